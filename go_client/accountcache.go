@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// defaultAccountCacheTTL is how long fetched accounts are reused when config.json does not say otherwise
+const defaultAccountCacheTTL = 15 * time.Second
+
+// cachedAccount is a fetched account together with its decoded campaign, if it holds one
+type cachedAccount struct {
+	Owner     solana.PublicKey `json:"owner"`
+	Lamports  uint64           `json:"lamports"`
+	Data      []byte           `json:"data"`
+	Campaign  *Campaign        `json:"campaign,omitempty"`
+	FetchedAt time.Time        `json:"fetchedAt"`
+}
+
+// accountCache keeps recently fetched accounts in memory, and optionally on disk, so repeated
+// menu refreshes don't hit the RPC node. Entries for accounts we write to are invalidated after sending.
+type accountCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	path    string // "" keeps the cache in memory only
+	entries map[string]*cachedAccount
+	shared  *redisCache // shared with other server instances, if configured
+}
+
+// newAccountCache creates the cache, loading persisted entries when a file is configured
+func newAccountCache(ttl time.Duration, path string) *accountCache {
+	cache := &accountCache{ttl: ttl, path: path, entries: map[string]*cachedAccount{}}
+	if path == "" {
+		return cache
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache // No cache file yet
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		fmt.Printf("⚠️  Ignoring invalid account cache %s: %v\n", path, err)
+		cache.entries = map[string]*cachedAccount{}
+	}
+	return cache
+}
+
+func (c *accountCache) get(address solana.PublicKey) (*cachedAccount, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[address.String()]
+	c.mu.Unlock()
+	if ok && time.Since(entry.FetchedAt) <= c.ttl {
+		return entry, true
+	}
+	if c.shared == nil {
+		return nil, false
+	}
+
+	// Another instance may have fetched it; keep a local copy for the rest of its lifetime
+	if entry, ok = c.shared.getAccount(address); !ok {
+		return nil, false
+	}
+	c.mu.Lock()
+	c.entries[address.String()] = entry
+	c.mu.Unlock()
+	return entry, true
+}
+
+func (c *accountCache) put(address solana.PublicKey, entry *cachedAccount) {
+	if c.ttl <= 0 {
+		return
+	}
+	if c.shared != nil {
+		c.shared.putAccount(address, entry, c.ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[address.String()] = entry
+	c.persist()
+}
+
+// invalidate drops the given accounts, typically after a transaction that writes to them
+func (c *accountCache) invalidate(addresses ...solana.PublicKey) {
+	if c.shared != nil {
+		for _, address := range addresses {
+			c.shared.invalidate(address.String())
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, address := range addresses {
+		delete(c.entries, address.String())
+	}
+	c.persist()
+}
+
+// forget drops an account from memory only, when another instance reported it changed
+func (c *accountCache) forget(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, address)
+}
+
+// persist writes unexpired entries to disk; the caller holds the lock
+func (c *accountCache) persist() {
+	if c.path == "" {
+		return
+	}
+
+	for key, entry := range c.entries {
+		if time.Since(entry.FetchedAt) > c.ttl {
+			delete(c.entries, key)
+		}
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		fmt.Printf("⚠️  Failed to save account cache: %v\n", err)
+	}
+}
+
+// getAccount returns the account at address, from the cache when fresh.
+// It returns nil without an error when the account does not exist.
+func (app *SolanaDApp) getAccount(address solana.PublicKey) (*cachedAccount, error) {
+	if entry, ok := app.accounts.get(address); ok {
+		return entry, nil
+	}
+
+	accountInfo, err := app.client.GetAccountInfo(context.Background(), address)
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && accountInfo.Value == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cachedAccount{
+		Owner:     accountInfo.Value.Owner,
+		Lamports:  accountInfo.Value.Lamports,
+		Data:      accountInfo.Value.Data.GetBinary(),
+		FetchedAt: time.Now(),
+	}
+	if entry.Owner.Equals(app.programID) {
+		entry.Campaign, _ = crowdfund.DecodeCampaign(entry.Data)
+	}
+	app.accounts.put(address, entry)
+	return entry, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Alert rule types
+const (
+	AlertRaisedAbove  = "raised_above"  // the campaign's raised total exceeds sol
+	AlertNoDonations  = "no_donations"  // the campaign received nothing for the given duration
+	AlertBalanceBelow = "balance_below" // the address's balance drops below sol
+)
+
+// AlertRule is one alert condition from config.json
+type AlertRule struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Campaign string `json:"campaign,omitempty"` // for raised_above and no_donations
+	Address  string `json:"address,omitempty"`  // for balance_below: any wallet or campaign
+	SOL      string `json:"sol,omitempty"`      // threshold, e.g. "10" or "0.5"
+	For      string `json:"for,omitempty"`      // for no_donations, e.g. "48h"
+
+	account   solana.PublicKey
+	threshold uint64
+	window    time.Duration
+}
+
+// prepare validates the rule and parses its fields
+func (rule *AlertRule) prepare() error {
+	if rule.Name == "" {
+		return fmt.Errorf("alert rule without a name")
+	}
+
+	target := rule.Campaign
+	if rule.Type == AlertBalanceBelow {
+		target = rule.Address
+	}
+	account, err := solana.PublicKeyFromBase58(target)
+	if err != nil {
+		return fmt.Errorf("alert %q: invalid address %q: %w", rule.Name, target, err)
+	}
+	rule.account = account
+
+	switch rule.Type {
+	case AlertRaisedAbove, AlertBalanceBelow:
+		if rule.threshold, err = parseSOL(rule.SOL); err != nil {
+			return fmt.Errorf("alert %q: %w", rule.Name, err)
+		}
+	case AlertNoDonations:
+		if rule.window, err = time.ParseDuration(rule.For); err != nil || rule.window <= 0 {
+			return fmt.Errorf("alert %q: invalid duration %q", rule.Name, rule.For)
+		}
+	default:
+		return fmt.Errorf("alert %q: unknown type %q", rule.Name, rule.Type)
+	}
+	return nil
+}
+
+// evaluate reports whether the rule currently fires, with a description of the situation
+func (rule *AlertRule) evaluate(ctx context.Context, d *Daemon, now time.Time) (bool, string, error) {
+	switch rule.Type {
+	case AlertRaisedAbove:
+		latest, ok := d.store.SnapshotAt(rule.account, now)
+		if !ok {
+			return false, "", nil
+		}
+		return latest.AmountDonated > rule.threshold,
+			fmt.Sprintf("'%s' has raised %s SOL (threshold %s SOL)", latest.Name, lamportsToSOL(latest.AmountDonated), lamportsToSOL(rule.threshold)), nil
+
+	case AlertNoDonations:
+		latest, ok := d.store.SnapshotAt(rule.account, now)
+		if !ok {
+			return false, "", nil
+		}
+		earlier, ok := d.store.SnapshotAt(rule.account, now.Add(-rule.window))
+		if !ok {
+			return false, "", nil // Not enough history yet
+		}
+		return latest.AmountDonated == earlier.AmountDonated,
+			fmt.Sprintf("'%s' has received no donations in the last %s", latest.Name, rule.window), nil
+
+	case AlertBalanceBelow:
+		balance, err := d.app.client.GetBalance(ctx, rule.account, "")
+		if err != nil {
+			return false, "", fmt.Errorf("failed to get balance of %s: %w", rule.account, err)
+		}
+		return balance.Value < rule.threshold,
+			fmt.Sprintf("%s holds %s SOL (threshold %s SOL)", rule.account, lamportsToSOL(balance.Value), lamportsToSOL(rule.threshold)), nil
+	}
+	return false, "", nil
+}
+
+// evaluateAlerts checks every rule and notifies when one starts or stops firing
+func (d *Daemon) evaluateAlerts(ctx context.Context) {
+	now := time.Now().UTC()
+	for i := range d.alerts {
+		rule := &d.alerts[i]
+		firing, message, err := rule.evaluate(ctx, d, now)
+		if err != nil {
+			logf(ctx, "Alert %q could not be evaluated: %v", rule.Name, err)
+			continue
+		}
+
+		if firing == d.store.AlertFiring(rule.Name) {
+			continue // Only state changes are notified
+		}
+		if err := d.store.SetAlertFiring(rule.Name, firing); err != nil {
+			logf(ctx, "Failed to save alert state: %v", err)
+		}
+
+		notification := Notification{Title: rule.Name, Message: message, Severity: "warning", Thread: "alert:" + rule.Name}
+		if rule.Type != AlertBalanceBelow {
+			notification.Campaign = rule.account.String()
+		}
+		if !firing {
+			notification.Severity = "resolved"
+			notification.Message = "Resolved: " + message
+		}
+		d.notifiers.Notify(ctx, notification)
+	}
+}
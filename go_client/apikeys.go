@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiKeysFile holds the API keys of `serve`, hashed
+const apiKeysFile = "apikeys.json"
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to spot
+const apiKeyPrefix = "cfk_"
+
+// API key scopes: what a key lets its holder do on `serve`
+const (
+	scopeRead     = "read"     // the JSON API, when `serve -require-key` closes it
+	scopeDonate   = "donate"   // the gasless relay and, with -require-key, donation intents
+	scopeWithdraw = "withdraw" // building and submitting withdrawals on the admin dashboard
+)
+
+var apiKeyScopes = []string{scopeRead, scopeDonate, scopeWithdraw}
+
+// APIKey is an issued API key. Only the SHA-256 of the key is kept: it is shown once, at creation.
+type APIKey struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"` // who the key was given to
+	Scopes  []string   `json:"scopes"`
+	Hash    string     `json:"hash"` // hex SHA-256 of the whole key
+	Created time.Time  `json:"created"`
+	Revoked *time.Time `json:"revoked,omitempty"`
+}
+
+// allows reports whether the key is live and carries the scope
+func (k *APIKey) allows(scope string) bool {
+	return k.Revoked == nil && slices.Contains(k.Scopes, scope)
+}
+
+// loadAPIKeys reads the issued keys, none when the file doesn't exist
+func loadAPIKeys() ([]APIKey, error) {
+	data, err := os.ReadFile(apiKeysFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", apiKeysFile, err)
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", apiKeysFile, err)
+	}
+	return keys, nil
+}
+
+// saveAPIKeys writes the issued keys, readable only by the owner
+func saveAPIKeys(keys []APIKey) error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(apiKeysFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", apiKeysFile, err)
+	}
+	return nil
+}
+
+// hashAPIKey is how a key is stored and looked up
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseScopes checks a comma-separated scope list
+func parseScopes(list string) ([]string, error) {
+	var scopes []string
+	for _, scope := range strings.Split(list, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || slices.Contains(scopes, scope) {
+			continue
+		}
+		if !slices.Contains(apiKeyScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q (want %s)", scope, strings.Join(apiKeyScopes, ", "))
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// CreateAPIKey issues a key and records its hash; the returned key is not stored anywhere
+func CreateAPIKey(name string, scopes []string) (*APIKey, string, error) {
+	keys, err := loadAPIKeys()
+	if err != nil {
+		return nil, "", err
+	}
+	random := make([]byte, 20)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	id := hex.EncodeToString(random[:4])
+	secret := apiKeyPrefix + id + "_" + hex.EncodeToString(random[4:])
+	key := APIKey{ID: id, Name: name, Scopes: scopes, Hash: hashAPIKey(secret), Created: time.Now().UTC()}
+	if err := saveAPIKeys(append(keys, key)); err != nil {
+		return nil, "", err
+	}
+	return &key, secret, nil
+}
+
+// RevokeAPIKey marks a key revoked; running servers stop accepting it on their next request
+func RevokeAPIKey(id string) (*APIKey, error) {
+	keys, err := loadAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if keys[i].ID != id {
+			continue
+		}
+		if keys[i].Revoked == nil {
+			now := time.Now().UTC()
+			keys[i].Revoked = &now
+		}
+		return &keys[i], saveAPIKeys(keys)
+	}
+	return nil, fmt.Errorf("no API key with id %s", id)
+}
+
+// apiKeyStore is the server's view of apikeys.json, reread when the file changes so that
+// keys created or revoked while it runs take effect
+type apiKeyStore struct {
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	keys    []APIKey
+}
+
+// lookup finds the key a client presented, nil for an unknown one
+func (s *apiKeyStore) lookup(ctx context.Context, presented string) *APIKey {
+	if !strings.HasPrefix(presented, apiKeyPrefix) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info, err := os.Stat(apiKeysFile); err != nil {
+		s.keys, s.modTime, s.size = nil, time.Time{}, 0
+	} else if !info.ModTime().Equal(s.modTime) || info.Size() != s.size {
+		keys, err := loadAPIKeys()
+		if err != nil {
+			logf(ctx, "Keeping the previous API keys: %v", err)
+		} else {
+			s.keys, s.modTime, s.size = keys, info.ModTime(), info.Size()
+		}
+	}
+	hash := hashAPIKey(presented)
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(s.keys[i].Hash), []byte(hash)) == 1 {
+			key := s.keys[i]
+			return &key
+		}
+	}
+	return nil
+}
+
+// bearerToken is the credential of a request: an Authorization bearer token, or for GET requests
+// an access_token query parameter, since browsers can't set headers on EventSource and WebSocket
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if r.Method == http.MethodGet {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+// requireScope lets a request through only with an API key carrying the scope: a missing or
+// unknown key is answered with 401, a key without the scope with 403
+func (s *Server) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="crowdfunding"`)
+			writeRelayError(w, r, &apiError{http.StatusUnauthorized, "an API key with the " + scope + " scope is required"})
+			return
+		}
+		key := s.apiKeys.lookup(r.Context(), token)
+		if key == nil || key.Revoked != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="crowdfunding", error="invalid_token"`)
+			writeRelayError(w, r, &apiError{http.StatusUnauthorized, "invalid or revoked API key"})
+			return
+		}
+		if !key.allows(scope) {
+			writeRelayError(w, r, &apiError{http.StatusForbidden, "the API key lacks the " + scope + " scope"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// keyed requires the scope only when `serve -require-key` closes the public API
+func (s *Server) keyed(scope string, next http.HandlerFunc) http.HandlerFunc {
+	if !s.opts.RequireKey {
+		return next
+	}
+	return s.requireScope(scope, next)
+}
+
+// runAPIKeyCommand handles `apikey`
+func runAPIKeyCommand(args []string) error {
+	usage := fmt.Errorf("usage: apikey create [-scopes read,donate,withdraw] <name> | list | revoke <id>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		scopeList := fs.String("scopes", scopeRead, "comma-separated scopes: "+strings.Join(apiKeyScopes, ", "))
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" {
+			return usage
+		}
+		scopes, err := parseScopes(*scopeList)
+		if err != nil {
+			return err
+		}
+		key, secret, err := CreateAPIKey(strings.TrimSpace(fs.Arg(0)), scopes)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔑 API key %s for %s (%s):\n", key.ID, key.Name, strings.Join(key.Scopes, ", "))
+		fmt.Printf("   %s\n", secret)
+		fmt.Println("💡 It is shown only now. Clients send it as `Authorization: Bearer <key>`.")
+		return nil
+
+	case "list":
+		if len(args) != 1 {
+			return usage
+		}
+		keys, err := loadAPIKeys()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			fmt.Println("📭 No API keys; create one with `apikey create <name>`")
+			return nil
+		}
+		for _, key := range keys {
+			status := "active"
+			if key.Revoked != nil {
+				status = "revoked " + formatTime(*key.Revoked)
+			}
+			fmt.Printf("🔑 %s  %-20s %-22s created %s, %s\n", key.ID, key.Name, strings.Join(key.Scopes, ","), formatTime(key.Created), status)
+		}
+		return nil
+
+	case "revoke":
+		if len(args) != 2 {
+			return usage
+		}
+		key, err := RevokeAPIKey(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🚫 API key %s of %s revoked\n", key.ID, key.Name)
+		return nil
+	}
+	return usage
+}
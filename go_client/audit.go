@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// auditFile is the append-only log of every transaction the client signs
+const auditFile = "audit.jsonl"
+
+// auditGenesis is the previous hash of the first entry
+var auditGenesis = strings.Repeat("0", 64)
+
+// auditMu serializes appends within the process
+var auditMu sync.Mutex
+
+// AuditEntry is one line of the audit log. Each entry includes the hash of the one before it,
+// so removing or editing an entry breaks the chain. Signed entries keep the signed message,
+// so the signature itself proves what the key signed.
+type AuditEntry struct {
+	Seq          int                `json:"seq"`
+	Time         time.Time          `json:"time"`
+	Event        string             `json:"event"` // "signed", "result", "screened" or "blocked"
+	OperationID  string             `json:"operationId,omitempty"`
+	Signer       string             `json:"signer"`
+	Transaction  string             `json:"transaction,omitempty"` // transaction signature, as shown by explorers
+	Signature    string             `json:"signature,omitempty"`   // the signer's own signature
+	Message      string             `json:"message,omitempty"`     // base64 signed message
+	Instructions []AuditInstruction `json:"instructions,omitempty"`
+	Screening    []ScreeningResult  `json:"screening,omitempty"` // counterparty checks before signing
+	Result       string             `json:"result,omitempty"`    // "confirmed" or "failed"
+	Error        string             `json:"error,omitempty"`
+	Prev         string             `json:"prev"`
+	Hash         string             `json:"hash"`
+}
+
+// AuditInstruction is a decoded instruction of a signed transaction
+type AuditInstruction struct {
+	Program string     `json:"program"`
+	Name    string     `json:"name,omitempty"`
+	Args    []AuditArg `json:"args,omitempty"`
+}
+
+type AuditArg struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// computeHash hashes the entry without its own hash
+func (e AuditEntry) computeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readAuditLog reads every entry of the log
+func readAuditLog(path string) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("audit log line %d is corrupt: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// lastAuditEntry reads the last entry of the log from its end, nil for an empty log
+func lastAuditEntry(path string) (*AuditEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	// Read backwards in chunks until the line before the trailing newline is complete
+	end := info.Size()
+	var tail []byte
+	for end > 0 {
+		chunk := min(end, 64*1024)
+		buf := make([]byte, chunk)
+		if _, err := f.ReadAt(buf, end-chunk); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		tail = append(buf, tail...)
+		end -= chunk
+		if i := bytes.LastIndexByte(bytes.TrimRight(tail, "\n"), '\n'); i >= 0 {
+			tail = tail[i+1:]
+			break
+		}
+	}
+	tail = bytes.TrimSpace(tail)
+	if len(tail) == 0 {
+		return nil, nil
+	}
+	var entry AuditEntry
+	if err := json.Unmarshal(tail, &entry); err != nil {
+		return nil, fmt.Errorf("last audit log line is corrupt: %w", err)
+	}
+	return &entry, nil
+}
+
+// appendAudit chains entry to the last one and appends it to the log
+func appendAudit(entry AuditEntry) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	last, err := lastAuditEntry(auditFile)
+	if err != nil {
+		return err
+	}
+	entry.Seq, entry.Prev = 1, auditGenesis
+	if last != nil {
+		entry.Seq, entry.Prev = last.Seq+1, last.Hash
+	}
+	entry.Time = time.Now().UTC()
+	entry.OperationID = operationID
+	entry.Hash = entry.computeHash()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	f, err := os.OpenFile(auditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return f.Sync()
+}
+
+// auditSigned records that signer signed tx. Callers must not send tx if this fails.
+func (app *SolanaDApp) auditSigned(tx *solana.Transaction, signer solana.PublicKey) error {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	entry := AuditEntry{
+		Event:   "signed",
+		Signer:  signer.String(),
+		Message: base64.StdEncoding.EncodeToString(message),
+	}
+	if len(tx.Signatures) > 0 {
+		entry.Transaction = tx.Signatures[0].String()
+	}
+	for i, key := range tx.Message.AccountKeys[:tx.Message.Header.NumRequiredSignatures] {
+		if key.Equals(signer) && i < len(tx.Signatures) {
+			entry.Signature = tx.Signatures[i].String()
+		}
+	}
+
+	entry.Instructions = app.auditInstructions(tx)
+
+	if err := appendAudit(entry); err != nil {
+		return fmt.Errorf("failed to record transaction in the audit log: %w", err)
+	}
+	return nil
+}
+
+// auditInstructions decodes a transaction's instructions for the audit log
+func (app *SolanaDApp) auditInstructions(tx *solana.Transaction) []AuditInstruction {
+	var instructions []AuditInstruction
+	for _, compiled := range tx.Message.Instructions {
+		program, err := tx.Message.Program(compiled.ProgramIDIndex)
+		if err != nil {
+			continue
+		}
+		instruction := AuditInstruction{Program: program.String()}
+		if program.Equals(app.programID) {
+			if ix, ok := app.idl.MatchInstruction(compiled.Data); ok {
+				instruction.Name = ix.Name
+				args, _ := ix.DecodeArgs(compiled.Data)
+				for _, arg := range args {
+					instruction.Args = append(instruction.Args, AuditArg{Name: arg.Name, Value: formatArg(arg)})
+				}
+			}
+		}
+		instructions = append(instructions, instruction)
+	}
+
+	return instructions
+}
+
+// auditBlocked records a transaction the withdrawal policy refused to sign
+func (app *SolanaDApp) auditBlocked(tx *solana.Transaction, violations []string) {
+	app.auditBlockedFor(app.wallet.Address(), tx, violations)
+}
+
+// auditBlockedFor records a transaction the withdrawal policy refused to let signer sign
+func (app *SolanaDApp) auditBlockedFor(signer solana.PublicKey, tx *solana.Transaction, violations []string) {
+	entry := AuditEntry{
+		Event:        "blocked",
+		Signer:       signer.String(),
+		Instructions: app.auditInstructions(tx),
+		Error:        strings.Join(violations, "; "),
+	}
+	if err := appendAudit(entry); err != nil {
+		log.Printf("Audit log: %v", err)
+	}
+}
+
+// auditResult records how a signed transaction ended. Unlike signing, a failure here is only logged.
+func (app *SolanaDApp) auditResult(sig solana.Signature, err error) {
+	app.auditResultFor(app.wallet.Address(), sig, err)
+}
+
+// auditResultFor records how a transaction signed by signer ended
+func (app *SolanaDApp) auditResultFor(signer solana.PublicKey, sig solana.Signature, err error) {
+	if sig.IsZero() {
+		return
+	}
+	entry := AuditEntry{Event: "result", Signer: signer.String(), Transaction: sig.String(), Result: "confirmed"}
+	if err != nil {
+		entry.Result, entry.Error = "failed", err.Error()
+	}
+	if err := appendAudit(entry); err != nil {
+		log.Printf("Audit log: %v", err)
+	}
+}
+
+// VerifyAuditLog checks the hash chain and every recorded signature. It returns the number of
+// good entries and the hash of the last one, which can be noted elsewhere to detect a rewritten log.
+func VerifyAuditLog(path string) (count int, head string, err error) {
+	entries, err := readAuditLog(path)
+	if err != nil {
+		return 0, "", err
+	}
+	prev := auditGenesis
+	for i, entry := range entries {
+		if entry.Seq != i+1 {
+			return i, prev, fmt.Errorf("entry %d has sequence number %d: entries were removed or reordered", i+1, entry.Seq)
+		}
+		if entry.Prev != prev {
+			return i, prev, fmt.Errorf("entry %d does not follow entry %d: the chain is broken", entry.Seq, entry.Seq-1)
+		}
+		if entry.computeHash() != entry.Hash {
+			return i, prev, fmt.Errorf("entry %d was modified after it was written", entry.Seq)
+		}
+		if entry.Event == "signed" {
+			if err := verifyAuditSignature(entry); err != nil {
+				return i, prev, fmt.Errorf("entry %d: %w", entry.Seq, err)
+			}
+		}
+		prev = entry.Hash
+	}
+	return len(entries), prev, nil
+}
+
+// verifyAuditSignature checks that the recorded signature is the signer's over the recorded message
+func verifyAuditSignature(entry AuditEntry) error {
+	message, err := base64.StdEncoding.DecodeString(entry.Message)
+	if err != nil {
+		return fmt.Errorf("invalid message encoding")
+	}
+	signer, err := solana.PublicKeyFromBase58(entry.Signer)
+	if err != nil {
+		return fmt.Errorf("invalid signer")
+	}
+	sig, err := solana.SignatureFromBase58(entry.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature")
+	}
+	if !ed25519.Verify(signer[:], message, sig[:]) {
+		return fmt.Errorf("signature %s is not %s's signature of the recorded message", entry.Signature, entry.Signer)
+	}
+	var msg solana.Message
+	if err := msg.UnmarshalBase64(entry.Message); err != nil || !msg.IsSigner(signer) {
+		return fmt.Errorf("the recorded message does not require %s's signature", entry.Signer)
+	}
+	return nil
+}
+
+// describe summarizes an entry on one line
+func (e AuditEntry) describe() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d\t%s\t%s\t", e.Seq, formatTime(e.Time), e.Event)
+	if e.Event == "result" {
+		fmt.Fprintf(&b, "%s %s", e.Result, e.Error)
+	} else {
+		var names []string
+		for _, ix := range e.Instructions {
+			if ix.Name == "" {
+				continue
+			}
+			var args []string
+			for _, arg := range ix.Args {
+				args = append(args, arg.Name+"="+arg.Value)
+			}
+			names = append(names, fmt.Sprintf("%s(%s)", ix.Name, strings.Join(args, ", ")))
+		}
+		if len(names) == 0 {
+			names = append(names, fmt.Sprintf("%d non-crowdfunding instruction(s)", len(e.Instructions)))
+		}
+		fmt.Fprintf(&b, "%s by %s", strings.Join(names, ", "), e.Signer)
+		if e.Event == "blocked" {
+			fmt.Fprintf(&b, ": %s", e.Error)
+		}
+		if e.Event == "screened" {
+			fmt.Fprintf(&b, ": %d address(es) cleared", len(e.Screening))
+			if e.Error != "" {
+				fmt.Fprintf(&b, ", incomplete: %s", e.Error)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\t%s", e.Transaction)
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// auditTestLog writes an audit log through two donations on the mock cluster, then returns its
+// entries and the head hash verification reports for it
+func auditTestLog(t *testing.T) ([]AuditEntry, string) {
+	t.Helper()
+	app, mock := newTestDApp(t)
+	campaign := addCampaign(t, app, mock, "roof", solana.LAMPORTS_PER_SOL)
+	for _, amount := range []uint64{1000, 2000} {
+		if err := app.DonateToCampaign("roof", campaign.String(), amount, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := readAuditLog(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("audit log has %d entries, want a signed and a result entry per donation", len(entries))
+	}
+	count, head, err := VerifyAuditLog(auditFile)
+	if err != nil {
+		t.Fatalf("untouched log: %v", err)
+	}
+	if count != 4 || head != entries[3].Hash {
+		t.Fatalf("untouched log: %d entries, head %s; want 4, %s", count, head, entries[3].Hash)
+	}
+	return entries, head
+}
+
+// writeAuditTestLog replaces the audit log with entries
+func writeAuditTestLog(t *testing.T, entries []AuditEntry) {
+	t.Helper()
+	var b strings.Builder
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	if err := os.WriteFile(auditFile, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectAuditFailure checks that verification fails after good entries, with a message naming the problem
+func expectAuditFailure(t *testing.T, good int, contains string) {
+	t.Helper()
+	count, _, err := VerifyAuditLog(auditFile)
+	if err == nil {
+		t.Fatalf("tampered log verified")
+	}
+	if count != good || !strings.Contains(err.Error(), contains) {
+		t.Errorf("VerifyAuditLog = %d, %v; want %d good entries and an error about %q", count, err, good, contains)
+	}
+}
+
+func TestAuditLogChangedEntry(t *testing.T) {
+	entries, _ := auditTestLog(t)
+	entries[1].Result = "failed"
+	writeAuditTestLog(t, entries)
+	expectAuditFailure(t, 1, "entry 2 was modified")
+}
+
+func TestAuditLogChangedEntryRehashed(t *testing.T) {
+	entries, _ := auditTestLog(t)
+	// Fixing up the entry's own hash breaks the link from the next one
+	entries[1].Error = "rewritten"
+	entries[1].Hash = entries[1].computeHash()
+	writeAuditTestLog(t, entries)
+	expectAuditFailure(t, 2, "entry 3 does not follow entry 2")
+}
+
+func TestAuditLogChangedSignedMessage(t *testing.T) {
+	entries, _ := auditTestLog(t)
+	// Even rehashing the whole chain can't hide a changed message: the signature no longer matches
+	message, err := base64.StdEncoding.DecodeString(entries[2].Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message[len(message)-1] ^= 0x01
+	entries[2].Message = base64.StdEncoding.EncodeToString(message)
+	for i := 2; i < len(entries); i++ {
+		entries[i].Prev = entries[i-1].Hash
+		entries[i].Hash = entries[i].computeHash()
+	}
+	writeAuditTestLog(t, entries)
+	expectAuditFailure(t, 2, "entry 3: signature")
+}
+
+func TestAuditLogRemovedEntry(t *testing.T) {
+	entries, _ := auditTestLog(t)
+	writeAuditTestLog(t, append(entries[:1:1], entries[2:]...))
+	expectAuditFailure(t, 1, "entries were removed or reordered")
+}
+
+func TestAuditLogRemovedEntryRenumbered(t *testing.T) {
+	entries, _ := auditTestLog(t)
+	kept := append(entries[:1:1], entries[2:]...)
+	for i := range kept {
+		kept[i].Seq = i + 1
+	}
+	writeAuditTestLog(t, kept)
+	expectAuditFailure(t, 1, "entry 2 does not follow entry 1")
+}
+
+func TestAuditLogRemovedLastEntry(t *testing.T) {
+	entries, head := auditTestLog(t)
+	// Truncation leaves a valid chain; only the head noted earlier shows it
+	writeAuditTestLog(t, entries[:3])
+	count, truncatedHead, err := VerifyAuditLog(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || truncatedHead == head {
+		t.Errorf("truncated log: %d entries, head %s; want 3 and a head other than %s", count, truncatedHead, head)
+	}
+}
+
+func TestAuditLogReorderedEntries(t *testing.T) {
+	entries, _ := auditTestLog(t)
+	entries[1], entries[2] = entries[2], entries[1]
+	writeAuditTestLog(t, entries)
+	expectAuditFailure(t, 1, "entries were removed or reordered")
+}
+
+func TestAuditLogReorderedEntriesRenumbered(t *testing.T) {
+	entries, _ := auditTestLog(t)
+	entries[1], entries[2] = entries[2], entries[1]
+	entries[1].Seq, entries[2].Seq = 2, 3
+	writeAuditTestLog(t, entries)
+	expectAuditFailure(t, 1, "entry 2 does not follow entry 1")
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// defaultAutoCloseGrace is how long a campaign keeps taking direct donations after reaching its goal
+const defaultAutoCloseGrace = 24 * time.Hour
+
+// AutoClosePolicy closes a campaign once it reaches its goal: scheduled donations to it stop at
+// once, and after the grace period it is closed and its balance above rent withdrawn
+type AutoClosePolicy struct {
+	Campaign    string `json:"campaign"`
+	Goal        string `json:"goal,omitempty"`        // SOL raised that completes the campaign; default its goal in lifecycle.json
+	GracePeriod string `json:"gracePeriod,omitempty"` // e.g. "24h", the default
+	Treasury    string `json:"treasury,omitempty"`    // forward the withdrawn balance here; default the admin wallet keeps it
+	DryRun      bool   `json:"dryRun,omitempty"`      // log what would be done without signing or closing
+
+	campaign solana.PublicKey
+	goal     uint64
+	grace    time.Duration
+	treasury *solana.PublicKey
+}
+
+// prepare validates the policy and parses its fields
+func (p *AutoClosePolicy) prepare() error {
+	var err error
+	if p.campaign, err = solana.PublicKeyFromBase58(p.Campaign); err != nil {
+		return fmt.Errorf("auto-close: invalid campaign %q: %w", p.Campaign, err)
+	}
+	if p.Goal != "" {
+		if p.goal, err = parseSOL(p.Goal); err != nil {
+			return fmt.Errorf("auto-close %s: goal: %w", p.Campaign, err)
+		}
+	}
+	p.grace = defaultAutoCloseGrace
+	if p.GracePeriod != "" {
+		if p.grace, err = time.ParseDuration(p.GracePeriod); err != nil {
+			return fmt.Errorf("auto-close %s: invalid gracePeriod: %w", p.Campaign, err)
+		}
+	}
+	if p.Treasury != "" {
+		treasury, err := solana.PublicKeyFromBase58(p.Treasury)
+		if err != nil {
+			return fmt.Errorf("auto-close %s: invalid treasury %q: %w", p.Campaign, p.Treasury, err)
+		}
+		p.treasury = &treasury
+	}
+	return nil
+}
+
+// autoClosing reports whether an auto-close policy has seen the campaign reach its goal, after
+// which the daemon stops running scheduled donations to it
+func (d *Daemon) autoClosing(campaign solana.PublicKey) bool {
+	records, err := loadCampaignRecords()
+	if err != nil {
+		return false
+	}
+	for i := range d.closePolicies {
+		if d.closePolicies[i].campaign.Equals(campaign) {
+			record := records.get(campaign)
+			return record != nil && record.GoalReachedAt != nil
+		}
+	}
+	return false
+}
+
+// runAutoClose applies every auto-close policy
+func (d *Daemon) runAutoClose(ctx context.Context, now time.Time) {
+	for i := range d.closePolicies {
+		if err := d.autoClose(ctx, &d.closePolicies[i], now); err != nil {
+			logf(ctx, "Auto-close for %s failed: %v", d.closePolicies[i].campaign, err)
+		}
+	}
+}
+
+// autoClose notes when the campaign reaches its goal, then closes it and withdraws its balance
+// once the grace period is over
+func (d *Daemon) autoClose(ctx context.Context, policy *AutoClosePolicy, now time.Time) error {
+	records, err := loadCampaignRecords()
+	if err != nil {
+		return err
+	}
+	record := records.get(policy.campaign)
+	if record != nil && record.ClosedAt != nil {
+		return nil
+	}
+
+	info, err := d.app.client.GetAccountInfoWithOpts(ctx, policy.campaign, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return fmt.Errorf("failed to fetch campaign: %w", err)
+	}
+	campaign, err := crowdfund.DecodeCampaign(info.Value.Data.GetBinary())
+	if err != nil {
+		return err
+	}
+
+	goal := policy.goal
+	if goal == 0 && record != nil {
+		goal = record.Goal
+	}
+	if goal == 0 {
+		return fmt.Errorf("no goal: set one in the policy or with `campaign goal`")
+	}
+	if campaign.AmountDonated < goal {
+		return nil
+	}
+	dryRun := policy.DryRun || d.dryRun
+
+	if record == nil {
+		record = &CampaignRecord{Name: campaign.Name, Admin: campaign.Admin, Goal: goal}
+		records[policy.campaign.String()] = record
+	}
+	if record.GoalReachedAt == nil {
+		if dryRun {
+			logf(ctx, "Auto-close dry run: '%s' reached its goal of %s SOL and would close after %s", campaign.Name, lamportsToSOL(goal), policy.grace)
+			return nil
+		}
+		reached := now.UTC()
+		record.GoalReachedAt = &reached
+		if err := records.save(); err != nil {
+			return err
+		}
+		d.notifiers.Notify(ctx, Notification{
+			Title:    "Goal reached",
+			Message:  fmt.Sprintf("'%s' raised %s SOL, reaching its goal of %s SOL. Scheduled donations have stopped; it closes at %s", campaign.Name, lamportsToSOL(campaign.AmountDonated), lamportsToSOL(goal), formatTime(reached.Add(policy.grace))),
+			Campaign: policy.campaign.String(),
+			Severity: "info",
+			Thread:   "auto-close:" + policy.campaign.String(),
+		})
+		return nil
+	}
+	if now.Before(record.GoalReachedAt.Add(policy.grace)) {
+		return nil
+	}
+
+	if d.app.wallet == nil || !campaign.Admin.Equals(d.app.wallet.Address()) {
+		return fmt.Errorf("the daemon wallet is not the campaign admin")
+	}
+	rent, err := d.app.client.GetMinimumBalanceForRentExemption(ctx, uint64(len(info.Value.Data.GetBinary())), rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get rent-exempt minimum: %w", err)
+	}
+
+	notification := Notification{Title: "Campaign completed", Campaign: policy.campaign.String(), Severity: "info", Thread: "auto-close:" + policy.campaign.String()}
+	notification.Message = fmt.Sprintf("'%s' is closed after raising %s SOL", campaign.Name, lamportsToSOL(campaign.AmountDonated))
+	if info.Value.Lamports > rent {
+		amount := info.Value.Lamports - rent
+		instructions := []solana.Instruction{}
+		withdraw, err := d.app.BuildInstruction("withdraw",
+			map[string]solana.PublicKey{"campaign": policy.campaign, "user": d.app.wallet.Address()},
+			map[string]interface{}{"name": campaign.Name, "amount": amount},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to build withdraw instruction: %w", err)
+		}
+		instructions = append(instructions, withdraw)
+		destination := d.app.wallet.Address()
+		if policy.treasury != nil {
+			destination = *policy.treasury
+			instructions = append(instructions, system.NewTransferInstruction(amount, d.app.wallet.Address(), destination).Build())
+		}
+
+		sig, err := d.app.submitTransaction("auto-close", instructions)
+		if err != nil {
+			d.notifiers.Notify(ctx, Notification{
+				Title:    "Campaign completed",
+				Message:  fmt.Sprintf("Failed to withdraw %s SOL from '%s'; it stays open and is retried: %v", lamportsToSOL(amount), campaign.Name, err),
+				Campaign: policy.campaign.String(),
+				Severity: "warning",
+				Thread:   notification.Thread,
+			})
+			return err
+		}
+		notification.Message += fmt.Sprintf("; %s SOL withdrawn to %s: %s", lamportsToSOL(amount), destination, d.app.TxURL(sig.String()))
+	}
+
+	closed := now.UTC()
+	record.ClosedAt = &closed
+	if err := records.save(); err != nil {
+		return err
+	}
+	logf(ctx, "%s", notification.Message)
+	d.notifiers.Notify(ctx, notification)
+	return nil
+}
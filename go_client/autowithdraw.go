@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// AutoWithdrawPolicy sweeps a campaign's balance to a treasury once it exceeds a threshold.
+// Only rent and the buffer are left on the campaign account.
+type AutoWithdrawPolicy struct {
+	Campaign  string `json:"campaign"`
+	Treasury  string `json:"treasury"`
+	Threshold string `json:"threshold"`        // SOL above rent that triggers a sweep, e.g. "5"
+	Buffer    string `json:"buffer,omitempty"` // SOL kept on the campaign on top of rent
+	DryRun    bool   `json:"dryRun,omitempty"` // log what would be withdrawn without signing
+
+	campaign  solana.PublicKey
+	treasury  solana.PublicKey
+	threshold uint64
+	buffer    uint64
+}
+
+// prepare validates the policy and parses its fields
+func (p *AutoWithdrawPolicy) prepare() error {
+	var err error
+	if p.campaign, err = solana.PublicKeyFromBase58(p.Campaign); err != nil {
+		return fmt.Errorf("auto-withdraw: invalid campaign %q: %w", p.Campaign, err)
+	}
+	if p.treasury, err = solana.PublicKeyFromBase58(p.Treasury); err != nil {
+		return fmt.Errorf("auto-withdraw %s: invalid treasury %q: %w", p.Campaign, p.Treasury, err)
+	}
+	if p.threshold, err = parseSOL(p.Threshold); err != nil {
+		return fmt.Errorf("auto-withdraw %s: threshold: %w", p.Campaign, err)
+	}
+	if p.Buffer != "" {
+		if p.buffer, err = parseSOL(p.Buffer); err != nil {
+			return fmt.Errorf("auto-withdraw %s: buffer: %w", p.Campaign, err)
+		}
+	}
+	return nil
+}
+
+// AutoWithdrawal is the audit record of one auto-withdraw decision
+type AutoWithdrawal struct {
+	Time        time.Time        `json:"time"`
+	Campaign    solana.PublicKey `json:"campaign"`
+	Treasury    solana.PublicKey `json:"treasury"`
+	Balance     uint64           `json:"balance"`
+	Rent        uint64           `json:"rent"`
+	Amount      uint64           `json:"amount"`
+	DryRun      bool             `json:"dryRun,omitempty"`
+	Signature   string           `json:"signature,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	OperationID string           `json:"operationId"`
+}
+
+// runAutoWithdrawals applies every policy whose campaign is over its threshold
+func (d *Daemon) runAutoWithdrawals(ctx context.Context) {
+	for i := range d.policies {
+		if err := d.autoWithdraw(ctx, &d.policies[i]); err != nil {
+			logf(ctx, "Auto-withdraw for %s failed: %v", d.policies[i].campaign, err)
+		}
+	}
+}
+
+// autoWithdraw withdraws the campaign's excess balance and forwards it to the treasury in one transaction
+func (d *Daemon) autoWithdraw(ctx context.Context, policy *AutoWithdrawPolicy) error {
+	info, err := d.app.client.GetAccountInfoWithOpts(ctx, policy.campaign, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return fmt.Errorf("failed to fetch campaign: %w", err)
+	}
+	campaign, err := crowdfund.DecodeCampaign(info.Value.Data.GetBinary())
+	if err != nil {
+		return err
+	}
+	rent, err := d.app.client.GetMinimumBalanceForRentExemption(ctx, uint64(len(info.Value.Data.GetBinary())), rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get rent-exempt minimum: %w", err)
+	}
+
+	balance := info.Value.Lamports
+	if balance < rent+policy.threshold || balance-rent <= policy.buffer {
+		return nil
+	}
+	amount := balance - rent - policy.buffer
+
+	record := AutoWithdrawal{
+		Time:        time.Now().UTC(),
+		Campaign:    policy.campaign,
+		Treasury:    policy.treasury,
+		Balance:     balance,
+		Rent:        rent,
+		Amount:      amount,
+		DryRun:      policy.DryRun || d.dryRun,
+		OperationID: beginOperation("auto-withdraw " + policy.campaign.String()),
+	}
+
+	if record.DryRun {
+		logf(ctx, "Auto-withdraw dry run: would move %s SOL from '%s' to %s", lamportsToSOL(amount), campaign.Name, policy.treasury)
+		return d.store.AddAutoWithdrawal(record)
+	}
+
+	if d.app.wallet == nil || !campaign.Admin.Equals(d.app.wallet.Address()) {
+		record.Error = "the daemon wallet is not the campaign admin"
+		if err := d.store.AddAutoWithdrawal(record); err != nil {
+			logf(ctx, "Failed to record auto-withdraw: %v", err)
+		}
+		return fmt.Errorf("%s", record.Error)
+	}
+
+	withdraw, err := d.app.BuildInstruction("withdraw",
+		map[string]solana.PublicKey{"campaign": policy.campaign, "user": d.app.wallet.Address()},
+		map[string]interface{}{"name": campaign.Name, "amount": amount},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to build withdraw instruction: %w", err)
+	}
+	transfer := system.NewTransferInstruction(amount, d.app.wallet.Address(), policy.treasury).Build()
+
+	sig, err := d.app.submitTransaction("auto-withdraw", []solana.Instruction{withdraw, transfer})
+	notification := Notification{Title: "Auto-withdraw", Campaign: policy.campaign.String(), Severity: "info"}
+	if err != nil {
+		record.Error = err.Error()
+		notification.Severity = "warning"
+		notification.Message = fmt.Sprintf("Failed to sweep %s SOL from '%s' to the treasury: %v", lamportsToSOL(amount), campaign.Name, err)
+	} else {
+		record.Signature = sig.String()
+		notification.Message = fmt.Sprintf("Swept %s SOL from '%s' to %s: %s", lamportsToSOL(amount), campaign.Name, policy.treasury, d.app.TxURL(sig.String()))
+	}
+
+	logf(ctx, "%s", notification.Message)
+	d.notifiers.Notify(ctx, notification)
+	return d.store.AddAutoWithdrawal(record)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/robfig/cron/v3"
+)
+
+// backupPassphraseEnv encrypts and decrypts backups with a passphrase when no age recipients are configured
+const backupPassphraseEnv = "CROWDFUNDING_BACKUP_PASSPHRASE"
+
+// backupSuffix ends every backup object name
+const backupSuffix = ".tar.gz.age"
+
+// backupStateFiles are the local state files backed up when they exist
+var backupStateFiles = []string{"campaign.txt", configFile, storeFile, nonceFile, idlCacheFile, idlCacheMetaFile, auditFile, lifecycleFile, apiKeysFile}
+
+// BackupConfig configures encrypted backups of the local state in config.json
+type BackupConfig struct {
+	To          string   `json:"to"`                    // s3://bucket/prefix or a local directory
+	Spec        string   `json:"spec,omitempty"`        // cron schedule of daemon backups, default "0 3 * * *"
+	Recipients  []string `json:"recipients,omitempty"`  // age public keys; without them CROWDFUNDING_BACKUP_PASSPHRASE is used
+	IncludeKeys bool     `json:"includeKeys,omitempty"` // also back up wallet.json, the fee payer key and the TOTP secret
+	Endpoint    string   `json:"endpoint,omitempty"`    // S3-compatible endpoint, default s3.amazonaws.com
+	Region      string   `json:"region,omitempty"`
+
+	schedule cron.Schedule
+}
+
+// prepare validates the settings and parses the daemon schedule
+func (c *BackupConfig) prepare() error {
+	if c.To == "" {
+		return fmt.Errorf("backup: to is required")
+	}
+	spec := c.Spec
+	if spec == "" {
+		spec = "0 3 * * *"
+	}
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("backup: invalid spec %q: %w", spec, err)
+	}
+	c.schedule = schedule
+	if _, err := c.recipients(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// recipients returns who backups are encrypted to
+func (c *BackupConfig) recipients() ([]age.Recipient, error) {
+	if len(c.Recipients) > 0 {
+		recipients, err := age.ParseRecipients(strings.NewReader(strings.Join(c.Recipients, "\n")))
+		if err != nil {
+			return nil, fmt.Errorf("backup: invalid recipients: %w", err)
+		}
+		return recipients, nil
+	}
+	passphrase := os.Getenv(backupPassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("backup: configure age recipients or set %s", backupPassphraseEnv)
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return []age.Recipient{recipient}, nil
+}
+
+// backupTarget stores backup objects by name
+type backupTarget interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, error)
+	List() ([]string, error) // backup names, oldest first
+	String() string
+}
+
+// openBackupTarget parses s3://bucket/prefix or a local directory
+func openBackupTarget(location string, config *BackupConfig) (backupTarget, error) {
+	rest, ok := strings.CutPrefix(location, "s3://")
+	if !ok {
+		return dirTarget(location), nil
+	}
+
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid backup location %q: missing bucket", location)
+	}
+	endpoint, region := "s3.amazonaws.com", ""
+	if config != nil {
+		if config.Endpoint != "" {
+			endpoint = config.Endpoint
+		}
+		region = config.Region
+	}
+	secure := true
+	if host, ok := strings.CutPrefix(endpoint, "http://"); ok {
+		endpoint, secure = host, false
+	}
+	endpoint = strings.TrimPrefix(endpoint, "https://")
+
+	// Credentials come from the usual AWS environment variables or ~/.aws/credentials
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{Client: &http.Client{Timeout: 5 * time.Second}},
+		}),
+		Secure: secure,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return &s3Target{client: client, bucket: bucket, prefix: strings.Trim(prefix, "/")}, nil
+}
+
+// s3Target keeps backups in an S3 bucket
+type s3Target struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func (t *s3Target) key(name string) string {
+	if t.prefix == "" {
+		return name
+	}
+	return t.prefix + "/" + name
+}
+
+func (t *s3Target) Put(name string, data []byte) error {
+	_, err := t.client.PutObject(context.Background(), t.bucket, t.key(name), bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: "application/octet-stream"})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", t.key(name), err)
+	}
+	return nil
+}
+
+func (t *s3Target) Get(name string) ([]byte, error) {
+	object, err := t.client.GetObject(context.Background(), t.bucket, t.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", t.key(name), err)
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", t.key(name), err)
+	}
+	return data, nil
+}
+
+func (t *s3Target) List() ([]string, error) {
+	prefix := ""
+	if t.prefix != "" {
+		prefix = t.prefix + "/"
+	}
+	var names []string
+	for object := range t.client.ListObjects(context.Background(), t.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list backups: %w", object.Err)
+		}
+		if name := path.Base(object.Key); strings.HasSuffix(name, backupSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (t *s3Target) String() string { return "s3://" + t.bucket + "/" + t.prefix }
+
+// dirTarget keeps backups in a local or mounted directory
+type dirTarget string
+
+func (t dirTarget) Put(name string, data []byte) error {
+	if err := os.MkdirAll(string(t), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", t, err)
+	}
+	return os.WriteFile(filepath.Join(string(t), name), data, 0600)
+}
+
+func (t dirTarget) Get(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(t), name))
+}
+
+func (t dirTarget) List() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(string(t), "*"+backupSuffix))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, match := range matches {
+		names = append(names, filepath.Base(match))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (t dirTarget) String() string { return string(t) }
+
+// backupFiles lists the existing files to back up, with the key files when asked
+func backupFiles(config *Config, includeKeys bool) []string {
+	candidates := append([]string(nil), backupStateFiles...)
+	if includeKeys {
+		candidates = append(candidates, "wallet.json", totpFile, fido2File)
+		if config.FeePayer != "" {
+			candidates = append(candidates, config.FeePayer)
+		}
+	}
+
+	var files []string
+	for _, file := range candidates {
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// CreateBackup archives, encrypts and uploads the local state, returning the backup's name
+func CreateBackup(config *Config, backup *BackupConfig, to string, includeKeys bool) (string, []string, error) {
+	recipients, err := backup.recipients()
+	if err != nil {
+		return "", nil, err
+	}
+	target, err := openBackupTarget(to, backup)
+	if err != nil {
+		return "", nil, err
+	}
+	files := backupFiles(config, includeKeys)
+	if len(files) == 0 {
+		return "", nil, fmt.Errorf("no local state to back up in this directory")
+	}
+
+	var buf bytes.Buffer
+	encrypted, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	if err := writeBackupArchive(encrypted, files); err != nil {
+		return "", nil, err
+	}
+	if err := encrypted.Close(); err != nil {
+		return "", nil, fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+
+	name := "crowdfunding-" + time.Now().UTC().Format("20060102T150405Z") + backupSuffix
+	if err := target.Put(name, buf.Bytes()); err != nil {
+		return "", nil, err
+	}
+	return name, files, nil
+}
+
+// writeBackupArchive writes the files as a gzipped tar. Each file is stored under its base
+// name, as it is restored into the working directory.
+func writeBackupArchive(w io.Writer, files []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		header := &tar.Header{Name: filepath.Base(file), Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", file, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", file, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// RestoreBackup downloads and decrypts a backup into the working directory. from is a backup
+// object or directory file, or a location whose latest backup is used. Existing files are only
+// replaced with force.
+func RestoreBackup(backup *BackupConfig, from, identityFile string, force bool) ([]string, error) {
+	location, name := from, ""
+	if strings.HasSuffix(from, backupSuffix) {
+		if i := strings.LastIndexAny(from, `/\`); i >= 0 {
+			location, name = from[:i], from[i+1:]
+		} else {
+			location, name = ".", from
+		}
+	}
+	target, err := openBackupTarget(location, backup)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		names, err := target.List()
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no backups in %s", target)
+		}
+		name = names[len(names)-1]
+	}
+
+	data, err := target.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := backupIdentities(identityFile)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := age.Decrypt(bytes.NewReader(data), identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", name, err)
+	}
+	return extractBackupArchive(decrypted, force)
+}
+
+// backupIdentities loads the age identity file, or uses the passphrase from the environment
+func backupIdentities(identityFile string) ([]age.Identity, error) {
+	if identityFile != "" {
+		file, err := os.Open(identityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file: %w", err)
+		}
+		defer file.Close()
+		identities, err := age.ParseIdentities(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse identity file: %w", err)
+		}
+		return identities, nil
+	}
+	passphrase := os.Getenv(backupPassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("pass -identity or set %s", backupPassphraseEnv)
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return []age.Identity{identity}, nil
+}
+
+// extractBackupArchive writes the archived files into the working directory, checking every
+// name first so a refused overwrite leaves nothing half-restored
+func extractBackupArchive(r io.Reader, force bool) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	contents := map[string][]byte{}
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup archive: %w", err)
+		}
+		if header.Name != filepath.Base(header.Name) || header.Name == ".." || header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unexpected entry %q in backup archive", header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup archive: %w", err)
+		}
+		if _, err := os.Stat(header.Name); err == nil && !force {
+			return nil, fmt.Errorf("%s already exists; pass -force to replace it", header.Name)
+		}
+		contents[header.Name] = data
+		names = append(names, header.Name)
+	}
+
+	for _, name := range names {
+		if err := os.WriteFile(name, contents[name], 0600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return names, nil
+}
+
+// runBackup uploads a backup when the backup schedule is due
+func (d *Daemon) runBackup(ctx context.Context, now time.Time) {
+	if d.backup == nil || d.backup.schedule.Next(d.lastBackup.In(displayLocation)).After(now) {
+		return
+	}
+	d.lastBackup = now
+
+	name, files, err := CreateBackup(d.app.config, d.backup, d.backup.To, d.backup.IncludeKeys)
+	if err != nil {
+		logf(ctx, "Backup failed: %v", err)
+		d.notifiers.Notify(ctx, Notification{Title: "Backup", Severity: "warning", Message: fmt.Sprintf("Scheduled backup to %s failed: %v", d.backup.To, err), Thread: "backup"})
+		return
+	}
+	logf(ctx, "Backed up %s to %s/%s", strings.Join(files, ", "), strings.TrimSuffix(d.backup.To, "/"), name)
+}
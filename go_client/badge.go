@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// badgeCacheTTL keeps embedded badges fresh: README renderers and CDNs re-fetch them at most this often
+const badgeCacheTTL = time.Minute
+
+// Badge colours, as used by shields.io
+const (
+	badgeBlue        = "#007ec6"
+	badgeYellow      = "#dfb317"
+	badgeGreen       = "#97ca00"
+	badgeBrightGreen = "#4c1"
+	badgeGrey        = "#9f9f9f"
+)
+
+// renderBadge draws a flat shields.io-style badge with a grey label and a coloured message
+func renderBadge(label, message, color string) ([]byte, error) {
+	fonts, err := newPosterFonts()
+	if err != nil {
+		return nil, err
+	}
+	labelWidth, err := fonts.width(label, 11, false)
+	if err != nil {
+		return nil, err
+	}
+	messageWidth, err := fonts.width(message, 11, false)
+	if err != nil {
+		return nil, err
+	}
+	labelWidth += 10
+	messageWidth += 10
+	width := labelWidth + messageWidth
+
+	var buf bytes.Buffer
+	label, message = html.EscapeString(label), html.EscapeString(message)
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(&buf, `<title>%s: %s</title>`, label, message)
+	fmt.Fprintf(&buf, `<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&buf, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(&buf, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		labelWidth, labelWidth, messageWidth, color, width)
+	fmt.Fprintf(&buf, `<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	// textLength squeezes the text into the measured width should the viewer's font be wider
+	for _, part := range []struct {
+		x, width int
+		text     string
+	}{{labelWidth / 2, labelWidth - 10, label}, {labelWidth + messageWidth/2, messageWidth - 10, message}} {
+		fmt.Fprintf(&buf, `<text x="%d" y="15" fill="#010101" fill-opacity=".3" textLength="%d">%s</text><text x="%d" y="14" textLength="%d">%s</text>`,
+			part.x, part.width, part.text, part.x, part.width, part.text)
+	}
+	fmt.Fprintf(&buf, `</g></svg>`)
+	return buf.Bytes(), nil
+}
+
+// badgeSOL formats lamports for a badge, truncated to two decimals
+func badgeSOL(lamports uint64) string {
+	return lamportsToSOL(lamports-lamports%(solana.LAMPORTS_PER_SOL/100)) + " SOL"
+}
+
+// campaignBadge builds the label, message and colour of a campaign's progress badge
+func (s *Server) campaignBadge(r *http.Request) (label, message, color string) {
+	label = r.URL.Query().Get("label")
+	if label == "" {
+		label = "raised"
+	}
+
+	name, ok := strings.CutSuffix(r.PathValue("file"), ".svg")
+	if !ok {
+		return label, "not found", badgeGrey
+	}
+	address, err := solana.PublicKeyFromBase58(name)
+	if err != nil {
+		return label, "invalid campaign", badgeGrey
+	}
+	var goal uint64
+	if value := r.URL.Query().Get("goal"); value != "" {
+		if goal, err = parseSOL(value); err != nil {
+			return label, "invalid goal", badgeGrey
+		}
+	}
+
+	campaign, err := s.app.FetchCampaign(address)
+	if err != nil {
+		if errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+			return label, "not found", badgeGrey
+		}
+		logf(r.Context(), "Badge error on %s: %v", r.URL.Path, err)
+		return label, "unavailable", badgeGrey
+	}
+
+	if goal == 0 {
+		return label, badgeSOL(campaign.AmountDonated), badgeBlue
+	}
+	message = badgeSOL(campaign.AmountDonated) + " / " + badgeSOL(goal)
+	switch {
+	case campaign.AmountDonated >= goal:
+		return label, message, badgeBrightGreen
+	case campaign.AmountDonated >= goal/2:
+		return label, message, badgeGreen
+	default:
+		return label, message, badgeYellow
+	}
+}
+
+// handleBadge serves an embeddable SVG progress badge. Problems are drawn on the badge rather than
+// returned as errors, so an embedding page never shows a broken image.
+func (s *Server) handleBadge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "image/svg+xml")
+	key := r.URL.RequestURI()
+
+	if body, ok := s.cache.get(key); ok {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(badgeCacheTTL.Seconds())))
+		w.Header().Set("X-Cache", "HIT")
+		w.Write(body)
+		return
+	}
+
+	label, message, color := s.campaignBadge(r)
+	body, err := renderBadge(label, message, color)
+	if err != nil {
+		logf(r.Context(), "Badge rendering failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if color == badgeGrey {
+		w.Header().Set("Cache-Control", "no-store")
+	} else {
+		s.cache.put(key, body, badgeCacheTTL)
+		s.watchForChanges(strings.TrimSuffix(r.PathValue("file"), ".svg"))
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(badgeCacheTTL.Seconds())))
+		w.Header().Set("X-Cache", "MISS")
+	}
+	w.Write(body)
+}
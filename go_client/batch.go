@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// nonceFile lists the durable nonce accounts created for each wallet
+const nonceFile = "nonces.json"
+
+// nonceAccountSize is the size of a system program nonce account
+const nonceAccountSize = 80
+
+// batchConfirmTimeout is how long the batch waits for one transaction before leaving it for a resumed run
+const batchConfirmTimeout = 90 * time.Second
+
+// Batch item states
+const (
+	BatchPending   = "pending"
+	BatchSent      = "sent"
+	BatchConfirmed = "confirmed"
+	BatchFailed    = "failed"
+)
+
+// BatchItem is one donation or withdrawal in a batch file
+type BatchItem struct {
+	Action   string           `json:"action"` // donate or withdraw
+	Campaign solana.PublicKey `json:"campaign"`
+	Amount   string           `json:"amount"` // SOL, e.g. "0.5"
+}
+
+// BatchItemProgress is the state of one batch item. The signed transaction is kept so an
+// interrupted run can rebroadcast it while its nonce is still unused.
+type BatchItemProgress struct {
+	Status       string `json:"status"`
+	Signature    string `json:"signature,omitempty"`
+	Transaction  string `json:"transaction,omitempty"` // base64
+	NonceAccount string `json:"nonceAccount,omitempty"`
+	Nonce        string `json:"nonce,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// BatchProgress is the resumable state of a batch run, saved next to the batch file
+type BatchProgress struct {
+	Wallet solana.PublicKey    `json:"wallet"`
+	Items  []BatchItemProgress `json:"items"`
+}
+
+// batchRun sends a batch of transactions in parallel, one durable nonce account per in-flight transaction.
+// Durable nonces do not expire like blockhashes, and each nonce value can be used only once, so a
+// transaction that is resent after an interruption can never land twice.
+type batchRun struct {
+	app      *SolanaDApp
+	items    []BatchItem
+	names    map[solana.PublicKey]string
+	amounts  []uint64
+	nonces   chan solana.PublicKey
+	path     string
+	mu       sync.Mutex
+	progress BatchProgress
+}
+
+// loadBatch reads a batch file and its progress, validating every item before anything is signed
+func (app *SolanaDApp) loadBatch(path string) (*batchRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch file: %w", err)
+	}
+	var items []BatchItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse batch file: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s has no items", path)
+	}
+
+	run := &batchRun{app: app, items: items, names: map[solana.PublicKey]string{}, path: path + ".progress.json"}
+	for i, item := range items {
+		if item.Action != "donate" && item.Action != "withdraw" {
+			return nil, fmt.Errorf("item %d: unsupported action %q: use donate or withdraw", i+1, item.Action)
+		}
+		amount, err := parseSOL(item.Amount)
+		if err != nil || amount == 0 {
+			return nil, fmt.Errorf("item %d: invalid amount %q", i+1, item.Amount)
+		}
+		run.amounts = append(run.amounts, amount)
+
+		if _, ok := run.names[item.Campaign]; !ok {
+			campaign, err := app.FetchCampaign(item.Campaign)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i+1, err)
+			}
+			run.names[item.Campaign] = campaign.Name
+		}
+		if item.Action == "donate" {
+			if err := app.checkDonationsOpen(item.Campaign); err != nil {
+				return nil, fmt.Errorf("item %d: %w", i+1, err)
+			}
+		}
+	}
+
+	run.progress = BatchProgress{Wallet: app.wallet.Address(), Items: make([]BatchItemProgress, len(items))}
+	for i := range run.progress.Items {
+		run.progress.Items[i].Status = BatchPending
+	}
+	if data, err := os.ReadFile(run.path); err == nil {
+		var saved BatchProgress
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", run.path, err)
+		}
+		if !saved.Wallet.Equals(app.wallet.Address()) || len(saved.Items) != len(items) {
+			return nil, fmt.Errorf("%s belongs to a different wallet or batch: remove it to start over", run.path)
+		}
+		run.progress = saved
+	}
+	return run, nil
+}
+
+// save writes the progress file atomically
+func (r *batchRun) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.progress, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode batch progress: %w", err)
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", r.path, err)
+	}
+	return nil
+}
+
+// item returns a copy of an item's progress
+func (r *batchRun) item(i int) BatchItemProgress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress.Items[i]
+}
+
+// update records an item's progress and persists it
+func (r *batchRun) update(i int, item BatchItemProgress) {
+	r.mu.Lock()
+	r.progress.Items[i] = item
+	r.mu.Unlock()
+	if err := r.save(); err != nil {
+		logf(context.Background(), "Failed to save batch progress: %v", err)
+	}
+}
+
+// Run sends every unfinished item using the given nonce accounts, resuming from saved progress
+func (r *batchRun) Run(ctx context.Context, nonceAccounts []solana.PublicKey) error {
+	if err := r.reconcile(ctx); err != nil {
+		return err
+	}
+
+	r.nonces = make(chan solana.PublicKey, len(nonceAccounts))
+	for _, account := range nonceAccounts {
+		r.nonces <- account
+	}
+
+	return forEachParallel(len(r.items), len(nonceAccounts), func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r.process(ctx, i)
+		return nil
+	})
+}
+
+// reconcile settles items left in flight by an interrupted run: confirmed or failed ones are recorded,
+// ones whose nonce is still unused are rebroadcast, and ones whose nonce has moved on are signed again
+func (r *batchRun) reconcile(ctx context.Context) error {
+	for i := range r.items {
+		item := r.item(i)
+		if item.Status != BatchSent {
+			continue
+		}
+
+		// Read the nonce before the signature status: if the nonce has moved on, the status
+		// fetched afterwards is guaranteed to show whether this transaction was what moved it
+		nonce, err := r.app.nonceValue(ctx, solana.MustPublicKeyFromBase58(item.NonceAccount), r.app.wallet.Address())
+		if err != nil {
+			return err
+		}
+
+		sig := solana.MustSignatureFromBase58(item.Signature)
+		statuses, err := r.app.client.GetSignatureStatuses(ctx, true, sig)
+		if err != nil {
+			return fmt.Errorf("failed to get signature statuses: %w", err)
+		}
+		if status := statuses.Value[0]; status != nil {
+			switch {
+			case status.Err != nil:
+				item.Status, item.Error = BatchFailed, fmt.Sprintf("transaction failed: %v", status.Err)
+			case status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized:
+				item.Status, item.Error = BatchConfirmed, ""
+			}
+			r.update(i, item) // Still processing otherwise; it is awaited below
+			continue
+		}
+		if nonce.String() != item.Nonce {
+			r.update(i, BatchItemProgress{Status: BatchPending})
+			continue
+		}
+
+		tx, err := solana.TransactionFromBase64(item.Transaction)
+		if err != nil {
+			return fmt.Errorf("item %d: failed to decode saved transaction: %w", i+1, err)
+		}
+		if _, err := r.app.send(ctx, r.items[i].Action, tx); err != nil {
+			logf(ctx, "Item %d: rebroadcast failed: %v", i+1, r.app.idl.DecodeError(err))
+		}
+	}
+	return nil
+}
+
+// process moves one item forward: pending items are signed against a free nonce account and sent,
+// and sent items are awaited
+func (r *batchRun) process(ctx context.Context, i int) {
+	item := r.item(i)
+	switch item.Status {
+	case BatchConfirmed, BatchFailed:
+		return
+	case BatchPending:
+		nonceAccount := <-r.nonces
+		defer func() { r.nonces <- nonceAccount }()
+
+		var err error
+		if item, err = r.send(ctx, i, nonceAccount); err != nil {
+			item.Status, item.Error = BatchFailed, err.Error()
+			r.update(i, item)
+			fmt.Printf("❌ Item %d: %v\n", i+1, err)
+			return
+		}
+	}
+
+	sig := solana.MustSignatureFromBase58(item.Signature)
+	confirmed, err := r.app.awaitSignature(ctx, sig, batchConfirmTimeout)
+	switch {
+	case err != nil:
+		item.Status, item.Error = BatchFailed, err.Error()
+		fmt.Printf("❌ Item %d: %v\n", i+1, err)
+	case confirmed:
+		item.Status, item.Error = BatchConfirmed, ""
+		fmt.Printf("✅ Item %d: %s %s SOL: %s\n", i+1, r.items[i].Action, r.items[i].Amount, sig)
+	default:
+		item.Error = "not confirmed yet"
+		fmt.Printf("⏳ Item %d: %s not confirmed yet, rerun the batch to resume\n", i+1, sig)
+	}
+	r.update(i, item)
+	if item.Status == BatchConfirmed || item.Status == BatchFailed {
+		r.app.auditResult(sig, err)
+	}
+}
+
+// send signs an item against the nonce account's current nonce, records it and broadcasts it
+func (r *batchRun) send(ctx context.Context, i int, nonceAccount solana.PublicKey) (BatchItemProgress, error) {
+	batchItem := r.items[i]
+	nonce, err := r.app.nonceValue(ctx, nonceAccount, r.app.wallet.Address())
+	if err != nil {
+		return BatchItemProgress{}, err
+	}
+
+	instruction, err := r.app.BuildInstruction(batchItem.Action,
+		map[string]solana.PublicKey{"campaign": batchItem.Campaign, "user": r.app.wallet.Address()},
+		map[string]interface{}{"name": r.names[batchItem.Campaign], "amount": r.amounts[i]},
+	)
+	if err != nil {
+		return BatchItemProgress{}, fmt.Errorf("failed to build %s instruction: %w", batchItem.Action, err)
+	}
+
+	// Advancing the nonce must be the first instruction of a durable nonce transaction
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{
+			system.NewAdvanceNonceAccountInstruction(nonceAccount, solana.SysVarRecentBlockHashesPubkey, r.app.wallet.Address()).Build(),
+			instruction,
+		},
+		nonce,
+		solana.TransactionPayer(r.app.payer()),
+	)
+	if err != nil {
+		return BatchItemProgress{}, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if err := r.app.signTransaction(tx); err != nil {
+		return BatchItemProgress{}, err
+	}
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		return BatchItemProgress{}, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	// Record the transaction before broadcasting it, so a crash mid-send is recoverable
+	item := BatchItemProgress{
+		Status:       BatchSent,
+		Signature:    tx.Signatures[0].String(),
+		Transaction:  encoded,
+		NonceAccount: nonceAccount.String(),
+		Nonce:        nonce.String(),
+	}
+	r.update(i, item)
+
+	defer r.app.invalidateWrittenAccounts(tx)
+	if _, err := r.app.send(ctx, batchItem.Action, tx); err != nil {
+		if !isRetryable(err) {
+			return item, fmt.Errorf("failed to send transaction: %w", r.app.idl.DecodeError(err))
+		}
+		// Transient failures leave the item sent: it may still land, and a rerun will find out
+		logf(ctx, "Item %d: send failed, will check for it later: %v", i+1, err)
+	}
+	return item, nil
+}
+
+// awaitSignature polls a signature until it is confirmed, fails, or the timeout passes
+func (app *SolanaDApp) awaitSignature(ctx context.Context, sig solana.Signature, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		statuses, err := app.client.GetSignatureStatuses(ctx, false, sig)
+		if err == nil && len(statuses.Value) > 0 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			if status.Err != nil {
+				return false, fmt.Errorf("transaction %s failed: %v", sig, status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return true, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case <-time.After(time.Second):
+		}
+	}
+	return false, nil
+}
+
+// nonceValue reads the current nonce stored in a durable nonce account controlled by authority
+func (app *SolanaDApp) nonceValue(ctx context.Context, account, authority solana.PublicKey) (solana.Hash, error) {
+	info, err := app.client.GetAccountInfoWithOpts(ctx, account, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return solana.Hash{}, fmt.Errorf("failed to fetch nonce account %s: %w", account, err)
+	}
+
+	// Layout: version u32, state u32, authority, nonce, fee calculator
+	data := info.Value.Data.GetBinary()
+	if len(data) < 72 || binary.LittleEndian.Uint32(data[4:8]) != 1 {
+		return solana.Hash{}, fmt.Errorf("%s is not an initialized nonce account", account)
+	}
+	if controller := solana.PublicKeyFromBytes(data[8:40]); !controller.Equals(authority) {
+		return solana.Hash{}, fmt.Errorf("nonce account %s is controlled by %s, not %s", account, controller, authority)
+	}
+	return solana.HashFromBytes(data[40:72]), nil
+}
+
+// ensureNonceAccounts returns n durable nonce accounts controlled by the wallet, creating any that are missing
+func (app *SolanaDApp) ensureNonceAccounts(ctx context.Context, n int) ([]solana.PublicKey, error) {
+	all := map[string][]solana.PublicKey{}
+	if data, err := os.ReadFile(nonceFile); err == nil {
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", nonceFile, err)
+		}
+	}
+	accounts := all[app.wallet.Address().String()]
+	if len(accounts) >= n {
+		return accounts[:n], nil
+	}
+
+	rent, err := app.client.GetMinimumBalanceForRentExemption(ctx, nonceAccountSize, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rent-exempt minimum: %w", err)
+	}
+	fmt.Printf("🔑 Creating %d nonce account(s), %s SOL rent each\n", n-len(accounts), lamportsToSOL(rent))
+
+	for len(accounts) < n {
+		key, err := solana.NewRandomPrivateKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate nonce account key: %w", err)
+		}
+		_, err = app.submitTransaction("create nonce account", []solana.Instruction{
+			system.NewCreateAccountInstruction(rent, nonceAccountSize, solana.SystemProgramID, app.wallet.Address(), key.PublicKey()).Build(),
+			system.NewInitializeNonceAccountInstruction(app.wallet.Address(), key.PublicKey(), solana.SysVarRecentBlockHashesPubkey, solana.SysVarRentPubkey).Build(),
+		}, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create nonce account: %w", err)
+		}
+		accounts = append(accounts, key.PublicKey())
+
+		all[app.wallet.Address().String()] = accounts
+		data, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode nonce accounts: %w", err)
+		}
+		if err := os.WriteFile(nonceFile, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to save %s: %w", nonceFile, err)
+		}
+	}
+	return accounts, nil
+}
+
+// Summary counts the items in each state
+func (r *batchRun) Summary() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := map[string]int{}
+	for _, item := range r.progress.Items {
+		counts[item.Status]++
+	}
+	return counts
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// slotDuration is the target time per slot, used to weigh slot lag against latency
+const slotDuration = 400 * time.Millisecond
+
+// EndpointStats is what bench-rpc measured for one endpoint
+type EndpointStats struct {
+	Endpoint   string
+	Samples    int
+	Errors     int
+	LatencyP50 time.Duration
+	LatencyP90 time.Duration
+	SlotLag    float64 // average slots behind the most advanced endpoint
+	LastError  string
+	latencies  []time.Duration
+	lagTotal   uint64
+	lagSamples int
+}
+
+// ErrorRate is the share of failed requests
+func (s *EndpointStats) ErrorRate() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Samples)
+}
+
+// score ranks endpoints, lower being better: the slow tail plus the time it takes to make up
+// the slot lag. Endpoints failing more than one request in ten rank after all the others.
+func (s *EndpointStats) score() time.Duration {
+	score := s.LatencyP90 + time.Duration(s.SlotLag*float64(slotDuration))
+	if s.ErrorRate() > 0.1 || s.Samples == s.Errors {
+		score += time.Hour
+	}
+	return score
+}
+
+// benchEndpoints polls each endpoint's slot every interval until the window ends, all endpoints
+// at once so their slots can be compared, and returns them best first
+func benchEndpoints(ctx context.Context, endpoints []string, window, interval time.Duration) []*EndpointStats {
+	stats := make([]*EndpointStats, len(endpoints))
+	clients := make([]*rpc.Client, len(endpoints))
+	for i, endpoint := range endpoints {
+		stats[i] = &EndpointStats{Endpoint: endpoint}
+		clients[i] = rpc.New(endpoint)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		slots := make([]uint64, len(endpoints))
+		var wg sync.WaitGroup
+		for i := range endpoints {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				requestCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				start := time.Now()
+				slot, err := clients[i].GetSlot(requestCtx, rpc.CommitmentProcessed)
+				stats[i].Samples++
+				if err != nil {
+					stats[i].Errors++
+					stats[i].LastError = err.Error()
+					return
+				}
+				stats[i].latencies = append(stats[i].latencies, time.Since(start))
+				slots[i] = slot
+			}(i)
+		}
+		wg.Wait()
+
+		highest := uint64(0)
+		for _, slot := range slots {
+			if slot > highest {
+				highest = slot
+			}
+		}
+		for i, slot := range slots {
+			if slot > 0 {
+				stats[i].lagTotal += highest - slot
+				stats[i].lagSamples++
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			for _, s := range stats {
+				s.LatencyP50 = latencyPercentile(s.latencies, 50)
+				s.LatencyP90 = latencyPercentile(s.latencies, 90)
+				if s.lagSamples > 0 {
+					s.SlotLag = float64(s.lagTotal) / float64(s.lagSamples)
+				}
+			}
+			sort.SliceStable(stats, func(i, j int) bool { return stats[i].score() < stats[j].score() })
+			return stats
+		case <-ticker.C:
+		}
+	}
+}
+
+// latencyPercentile returns the latency below which the given percentage of samples lie
+func latencyPercentile(latencies []time.Duration, percentile int) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)*percentile/100]
+}
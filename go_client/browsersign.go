@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/mr-tron/base58"
+)
+
+// BrowserSignRequest describes the operation a browser wallet is asked to sign
+type BrowserSignRequest struct {
+	Action   string // create, donate or withdraw
+	Campaign solana.PublicKey
+	Name     string
+	Text     string // description for create
+	Amount   uint64 // lamports for donate/withdraw
+}
+
+// Summary describes the request for the signing page
+func (req BrowserSignRequest) Summary() string {
+	switch req.Action {
+	case "create":
+		return fmt.Sprintf("Create campaign '%s'", req.Name)
+	case "donate":
+		return fmt.Sprintf("Donate %s SOL to '%s' (%s)", lamportsToSOL(req.Amount), req.Name, req.Campaign.String())
+	default:
+		return fmt.Sprintf("Withdraw %s SOL from '%s' (%s)", lamportsToSOL(req.Amount), req.Name, req.Campaign.String())
+	}
+}
+
+// buildForSigner builds the unsigned transaction with the browser wallet as fee payer and user,
+// and returns the block height after which its blockhash expires
+func (app *SolanaDApp) buildForSigner(req BrowserSignRequest, signer solana.PublicKey) (*solana.Transaction, uint64, error) {
+	campaign := req.Campaign
+	args := map[string]interface{}{"name": req.Name}
+	switch req.Action {
+	case "create":
+		pda, _, err := app.CampaignPDAFor(signer, req.Name)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create campaign PDA: %w", err)
+		}
+		campaign = pda
+		args["description"] = req.Text
+	default:
+		args["amount"] = req.Amount
+	}
+
+	instruction, err := app.BuildInstruction(req.Action,
+		map[string]solana.PublicKey{"campaign": campaign, "user": signer},
+		args,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build %s instruction: %w", req.Action, err)
+	}
+
+	recent, err := app.sender.GetLatestBlockhash(context.Background(), rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{instruction},
+		recent.Value.Blockhash,
+		solana.TransactionPayer(signer),
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return tx, recent.Value.LastValidBlockHeight, nil
+}
+
+// SignInBrowser serves a short-lived local page that lets a browser wallet sign the request,
+// then broadcasts the signed transaction and waits for its confirmation. A failed send or
+// confirmation is reported to the page, which can sign again until the timeout. The private
+// key never leaves the wallet.
+func (app *SolanaDApp) SignInBrowser(req BrowserSignRequest, addr string, timeout time.Duration) error {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	var (
+		mu          sync.Mutex
+		pending     *solana.Transaction
+		lastValid   uint64 // block height after which pending expires
+		sent        bool   // a transaction was confirmed, so no other may be sent
+		lastSendErr error  // the last failed attempt, reported if the page gives up
+		done        = make(chan error, 1)
+	)
+
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Query().Get("token") != token {
+			http.Error(w, "invalid session token", http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		browserSignPage.Execute(w, map[string]string{"Token": token, "Summary": req.Summary()})
+	})
+	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !authorized(w, r) {
+			return
+		}
+		var body struct {
+			PublicKey string `json:"publicKey"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+			return
+		}
+		signer, err := solana.PublicKeyFromBase58(body.PublicKey)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid public key"})
+			return
+		}
+
+		tx, validUntil, err := app.buildForSigner(req, signer)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		message, err := tx.Message.MarshalBinary()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+
+		mu.Lock()
+		pending, lastValid = tx, validUntil
+		mu.Unlock()
+
+		fmt.Printf("✍️  Transaction built for browser wallet %s, waiting for signature...\n", signer.String())
+		writeJSON(w, http.StatusOK, map[string]string{"message": base58.Encode(message)})
+	})
+	mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !authorized(w, r) {
+			return
+		}
+		var body struct {
+			Signature string `json:"signature"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+			return
+		}
+		signature, err := solana.SignatureFromBase58(body.Signature)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid signature"})
+			return
+		}
+
+		// A copy, so a concurrent /build or /submit cannot change the transaction being sent
+		mu.Lock()
+		if sent {
+			mu.Unlock()
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "the transaction has already been sent"})
+			return
+		}
+		if pending == nil {
+			mu.Unlock()
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "no transaction has been built yet"})
+			return
+		}
+		tx, validUntil := *pending, lastValid
+		mu.Unlock()
+
+		// The fee payer is the only signer
+		tx.Signatures = []solana.Signature{signature}
+		if err := tx.VerifySignatures(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "signature does not match the transaction"})
+			return
+		}
+
+		sig, err := app.send(context.Background(), req.Action, &tx)
+		if err != nil {
+			err = fmt.Errorf("failed to send transaction: %w", app.idl.DecodeError(err))
+		} else {
+			fmt.Printf("⏳ Transaction %s sent, waiting for confirmation...\n", sig)
+			if err = app.idl.DecodeError(crowdfund.WaitForConfirmation(context.Background(), app.client, sig, validUntil)); err != nil {
+				err = fmt.Errorf("transaction %s failed: %w", sig, err)
+			}
+		}
+		if err != nil {
+			// The page can sign a fresh transaction and try again
+			fmt.Printf("❌ %v\n", err)
+			mu.Lock()
+			lastSendErr = err
+			mu.Unlock()
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+
+		mu.Lock()
+		sent = true
+		mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]string{"signature": sig.String(), "url": app.TxURL(sig.String())})
+		fmt.Printf("✅ Transaction confirmed: %s\n", sig)
+		fmt.Printf("🔗 %s\n", app.TxURL(sig.String()))
+		select {
+		case done <- nil:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer func() {
+		// A submit still waiting for confirmation is not waited for
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	fmt.Printf("\n🌐 Open this page in a browser with Phantom installed to sign:\n")
+	fmt.Printf("   http://%s/?token=%s\n", listener.Addr().String(), token)
+	fmt.Printf("   %s\n", req.Summary())
+	fmt.Printf("⏳ The page expires in %s\n", timeout)
+
+	select {
+	case err := <-done:
+		// Give the page a moment to receive the response before shutting down
+		time.Sleep(500 * time.Millisecond)
+		return err
+	case <-time.After(timeout):
+		mu.Lock()
+		defer mu.Unlock()
+		if lastSendErr != nil {
+			return fmt.Errorf("no transaction confirmed within %s: %w", timeout, lastSendErr)
+		}
+		return fmt.Errorf("no signed transaction received within %s", timeout)
+	}
+}
+
+var browserSignPage = template.Must(template.New("sign").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Sign crowdfunding transaction</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 3em auto; }
+button { font-size: 1.1em; padding: 0.5em 1.5em; }
+#status { margin-top: 1.5em; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h2>Crowdfunding CLI signing request</h2>
+<p><strong>{{.Summary}}</strong></p>
+<p>The transaction is built by the CLI on this machine and signed by your wallet. Your private key never leaves the wallet.</p>
+<button id="sign">Connect wallet &amp; sign</button>
+<div id="status"></div>
+<script>
+const token = {{.Token}};
+const statusEl = document.getElementById("status");
+const setStatus = (text) => { statusEl.textContent = text; };
+
+async function post(path, body) {
+  const res = await fetch(path + "?token=" + encodeURIComponent(token), {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(body),
+  });
+  const data = await res.json();
+  if (!res.ok) throw new Error(data.error || res.statusText);
+  return data;
+}
+
+document.getElementById("sign").onclick = async () => {
+  const provider = (window.phantom && window.phantom.solana) || window.solana;
+  if (!provider) {
+    setStatus("No Phantom wallet found in this browser.");
+    return;
+  }
+  try {
+    setStatus("Connecting to wallet...");
+    const { publicKey } = await provider.connect();
+    setStatus("Building transaction...");
+    const built = await post("/build", { publicKey: publicKey.toString() });
+    setStatus("Waiting for wallet approval...");
+    const signed = await provider.request({ method: "signTransaction", params: { message: built.message } });
+    setStatus("Broadcasting and waiting for confirmation...");
+    const result = await post("/submit", { signature: signed.signature });
+    statusEl.innerHTML = "";
+    const link = document.createElement("a");
+    link.href = result.url;
+    link.textContent = result.signature;
+    statusEl.append("✅ Confirmed! ", link, "\nYou can close this page.");
+  } catch (err) {
+    setStatus("❌ " + err.message + "\nClick the button to sign again.");
+  }
+};
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxCalendarOccurrences bounds how many runs of one schedule are listed, for specs like "* * * * *"
+const maxCalendarOccurrences = 500
+
+// icsTime is the UTC date-time format of iCalendar
+const icsTime = "20060102T150405Z"
+
+// icsEvent is one VEVENT of an iCalendar file
+type icsEvent struct {
+	UID         string
+	Start       time.Time
+	Duration    time.Duration
+	Summary     string
+	Description string
+}
+
+// ScheduleCalendar lists the upcoming runs of every schedule within the window, and the runs recorded
+// during the same length of time in the past, as calendar events
+func ScheduleCalendar(store Store, now time.Time, window time.Duration) []icsEvent {
+	var events []icsEvent
+	schedules := map[string]Schedule{}
+
+	for _, schedule := range store.Schedules() {
+		schedules[schedule.ID] = schedule
+		spec, err := cronParser.Parse(schedule.Spec)
+		if err != nil {
+			continue
+		}
+		next, err := schedule.Next()
+		if err != nil {
+			continue
+		}
+		// A run that is already due is made by the daemon's next tick
+		if next.Before(now) {
+			next = now
+		}
+		for i := 0; i < maxCalendarOccurrences && !next.After(now.Add(window)); i++ {
+			events = append(events, icsEvent{
+				UID:         fmt.Sprintf("schedule-%s-%d@crowdfunding-client", schedule.ID, next.Unix()),
+				Start:       next,
+				Duration:    15 * time.Minute,
+				Summary:     "Scheduled " + schedule.Describe(),
+				Description: fmt.Sprintf("Schedule %s (%s), run by the daemon with -wallet", schedule.ID, schedule.Spec),
+			})
+			next = spec.Next(next)
+		}
+	}
+
+	for _, run := range store.ScheduleRuns() {
+		if run.Time.Before(now.Add(-window)) {
+			continue
+		}
+		summary := "Ran schedule " + run.ScheduleID
+		if schedule, ok := schedules[run.ScheduleID]; ok {
+			summary = "Ran " + schedule.Describe()
+		}
+		description := "Transaction " + run.Signature
+		if run.Error != "" {
+			summary = "Failed: " + strings.TrimPrefix(summary, "Ran ")
+			description = "Error: " + run.Error
+		}
+		if run.OperationID != "" {
+			description += "\nOperation " + run.OperationID
+		}
+		events = append(events, icsEvent{
+			UID:         fmt.Sprintf("run-%s-%d@crowdfunding-client", run.ScheduleID, run.Time.Unix()),
+			Start:       run.Time,
+			Duration:    15 * time.Minute,
+			Summary:     summary,
+			Description: description,
+		})
+	}
+	return events
+}
+
+// WriteICS writes events as an iCalendar (RFC 5545) file
+func WriteICS(w io.Writer, name string, events []icsEvent, now time.Time) error {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		b.WriteString(foldICSLine(fmt.Sprintf(format, args...)))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//crowdfunding-client//schedules//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", escapeICS(name))
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line("UID:%s", event.UID)
+		line("DTSTAMP:%s", now.UTC().Format(icsTime))
+		line("DTSTART:%s", event.Start.UTC().Format(icsTime))
+		line("DTEND:%s", event.Start.Add(event.Duration).UTC().Format(icsTime))
+		line("SUMMARY:%s", escapeICS(event.Summary))
+		line("DESCRIPTION:%s", escapeICS(event.Description))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeICS escapes text property values
+func escapeICS(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// foldICSLine splits lines longer than 75 octets, continuing them with a leading space,
+// without cutting a UTF-8 sequence in two
+func foldICSLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
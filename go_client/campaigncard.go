@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Link preview card size in pixels, the 1.91:1 image Open Graph and Twitter cards display
+const (
+	cardWidth  = 1200
+	cardHeight = 630
+	cardMargin = 72
+)
+
+// cardDescriptionLength is how much of the description link previews show
+const cardDescriptionLength = 200
+
+// CampaignCard is the content of a campaign's link preview image
+type CampaignCard struct {
+	Name        string
+	Description string // plain text
+	Raised      uint64 // lamports
+	Goal        uint64 // lamports, 0 for no progress bar
+}
+
+// WritePNG renders the card as a PNG image
+func (c CampaignCard) WritePNG(w io.Writer) error {
+	fonts, err := newPosterFonts()
+	if err != nil {
+		return err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 16, cardHeight), image.NewUniform(posterAccent), image.Point{}, draw.Src)
+
+	y := cardMargin
+	text := func(s string, size float64, bold bool, c color.RGBA, maxLines int) error {
+		lines, err := fonts.wrap(s, size, bold, maxLines, cardWidth-2*cardMargin)
+		if err != nil {
+			return err
+		}
+		face, err := fonts.face(size, bold)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			y += int(size * 1.2)
+			drawer := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(cardMargin, y)}
+			drawer.DrawString(line)
+		}
+		return nil
+	}
+
+	if err := text(c.Name, 64, true, posterInk, 2); err != nil {
+		return err
+	}
+	y += 12
+	if err := text(c.Description, 30, false, posterMuted, 3); err != nil {
+		return err
+	}
+
+	// The progress sits at the bottom, however long the name and description are
+	y = cardHeight - cardMargin - 150
+	progress := lamportsToSOL(c.Raised) + " SOL raised"
+	if c.Goal > 0 {
+		progress += " of " + lamportsToSOL(c.Goal) + " SOL"
+	}
+	if err := text(progress, 52, true, posterInk, 1); err != nil {
+		return err
+	}
+	if c.Goal > 0 {
+		bar := image.Rect(cardMargin, y+24, cardWidth-cardMargin, y+52)
+		draw.Draw(img, bar, image.NewUniform(posterTrack), image.Point{}, draw.Src)
+		filled := bar
+		filled.Max.X = filled.Min.X + int(float64(bar.Dx())*min(float64(c.Raised)/float64(c.Goal), 1))
+		draw.Draw(img, filled, image.NewUniform(posterAccent), image.Point{}, draw.Src)
+	}
+	y = cardHeight - cardMargin
+	face, err := fonts.face(26, false)
+	if err != nil {
+		return err
+	}
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(posterMuted), Face: face, Dot: fixed.P(cardMargin, y)}
+	drawer.DrawString("Donate with Phantom, Backpack or Solflare")
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return nil
+}
+
+// plainDescription flattens a Markdown description to one line of at most max characters
+func plainDescription(description string, max int) string {
+	text := strings.Join(strings.Fields(MarkdownToTerminal(description, "", false)), " ")
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)[:max-1]
+	return strings.TrimRight(string(runes), " ") + "…"
+}
+
+// campaignMeta builds the link preview metadata of a campaign's shareable page. The image URL
+// carries the amount raised, so networks that cache previews fetch a new card once it changes.
+func (s *Server) campaignMeta(r *http.Request, address solana.PublicKey, campaign *crowdfund.Campaign, page campaignPage) *campaignMeta {
+	base := s.baseURL(r)
+	raised := page.Raised + " SOL raised"
+	if page.Goal != "" {
+		raised += " of " + page.Goal + " SOL"
+	}
+	description := raised
+	if text := plainDescription(campaign.Description, cardDescriptionLength); text != "" {
+		description += " · " + text
+	}
+	return &campaignMeta{
+		URL:         base + "/c/" + address.String(),
+		Title:       campaign.Name,
+		Description: description,
+		Raised:      raised,
+		Image:       base + "/c/" + address.String() + "/card.png?v=" + strconv.FormatUint(campaign.AmountDonated, 10),
+		ImageWidth:  cardWidth,
+		ImageHeight: cardHeight,
+	}
+}
+
+// handleCampaignCard serves the link preview image of a campaign
+func (s *Server) handleCampaignCard(w http.ResponseWriter, r *http.Request) {
+	address, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		http.Error(w, "invalid campaign address", http.StatusBadRequest)
+		return
+	}
+
+	maxAge := int(s.opts.CacheTTL.Seconds())
+	if body, ok := s.cache.get(r.URL.Path); ok {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+		w.Header().Set("X-Cache", "HIT")
+		w.Write(body)
+		return
+	}
+
+	campaign, err := s.app.FetchCampaign(address)
+	if err != nil {
+		if errors.Is(err, crowdfund.ErrCampaignNotFound) || errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+			http.Error(w, "campaign not found", http.StatusNotFound)
+			return
+		}
+		logf(r.Context(), "Campaign card error on %s: %v", r.URL.Path, err)
+		http.Error(w, "upstream RPC request failed", http.StatusBadGateway)
+		return
+	}
+	card := CampaignCard{
+		Name:        campaign.Name,
+		Description: plainDescription(campaign.Description, cardDescriptionLength),
+		Raised:      campaign.AmountDonated,
+	}
+	records, _ := loadCampaignRecords()
+	if record := records.get(address); record != nil {
+		card.Goal = record.Goal
+	}
+
+	var body bytes.Buffer
+	if err := card.WritePNG(&body); err != nil {
+		logf(r.Context(), "Campaign card rendering failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.cache.put(r.URL.Path, body.Bytes(), s.opts.CacheTTL)
+	s.watchForChanges(r.PathValue("address"))
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	w.Header().Set("X-Cache", "MISS")
+	w.Write(body.Bytes())
+}
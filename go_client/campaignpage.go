@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// campaignPageTemplate is the HTML page of one campaign, with its description rendered from Markdown
+var campaignPageTemplate = template.Must(template.New("campaign").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+{{with .Meta}}<meta name="description" content="{{.Description}}">
+<link rel="canonical" href="{{.URL}}">
+<meta property="og:type" content="website">
+<meta property="og:site_name" content="Solana Crowdfunding">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+<meta property="og:image" content="{{.Image}}">
+<meta property="og:image:width" content="{{.ImageWidth}}">
+<meta property="og:image:height" content="{{.ImageHeight}}">
+<meta property="og:image:alt" content="{{.Title}}: {{.Raised}}">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+<meta name="twitter:image" content="{{.Image}}">
+{{end}}<link rel="alternate" type="application/atom+xml" title="Donations to {{.Name}}" href="{{.FeedURL}}">
+<style>
+body { font-family: system-ui, sans-serif; max-width: 42rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #222; }
+.meta { color: #666; font-size: .9rem; }
+.raised { font-size: 1.4rem; font-weight: 600; }
+.description { border-top: 1px solid #ddd; margin-top: 1.5rem; padding-top: 1rem; }
+pre { background: #f4f4f4; padding: .75rem; overflow-x: auto; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1rem; color: #555; }
+code { word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p class="raised">{{.Raised}} SOL raised{{if .Goal}} of {{.Goal}} SOL{{end}}</p>
+<p class="meta">State: {{.State}}{{if .Deadline}} · Deadline {{.Deadline}}{{end}}<br>
+Address <code>{{.Address}}</code> · <a href="{{.ExplorerURL}}">explorer</a><br>
+Admin <code>{{.Admin}}</code></p>
+<div class="description">
+{{.Description}}
+</div>
+</body>
+</html>
+`))
+
+// campaignPage is the data of the campaign page
+type campaignPage struct {
+	Name, Address, Admin, ExplorerURL string
+	Raised, Goal, Deadline            string
+	FeedURL                           string
+	State                             CampaignState
+	Description                       template.HTML
+	Meta                              *campaignMeta // link preview tags of the shareable page, nil on /page
+}
+
+// campaignMeta is the Open Graph and Twitter card metadata of a shareable campaign page
+type campaignMeta struct {
+	URL, Title, Description, Raised string
+	Image                           string
+	ImageWidth, ImageHeight         int
+}
+
+// handleCampaignPage serves a campaign as an HTML page
+func (s *Server) handleCampaignPage(w http.ResponseWriter, r *http.Request) {
+	s.serveCampaignPage(w, r, false)
+}
+
+// handleSharePage serves the campaign page at a short address, with the metadata social networks
+// and chat apps read to preview shared links
+func (s *Server) handleSharePage(w http.ResponseWriter, r *http.Request) {
+	s.serveCampaignPage(w, r, true)
+}
+
+// serveCampaignPage renders a campaign's HTML page, with link preview metadata when share is set
+func (s *Server) serveCampaignPage(w http.ResponseWriter, r *http.Request, share bool) {
+	address, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		http.Error(w, "invalid campaign address", http.StatusBadRequest)
+		return
+	}
+
+	maxAge := int(s.opts.CacheTTL.Seconds())
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if body, ok := s.cache.get(r.URL.Path); ok {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+		w.Header().Set("X-Cache", "HIT")
+		w.Write(body)
+		return
+	}
+
+	campaign, err := s.app.FetchCampaign(address)
+	if err != nil {
+		if errors.Is(err, crowdfund.ErrCampaignNotFound) || errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+			http.Error(w, "campaign not found", http.StatusNotFound)
+			return
+		}
+		logf(r.Context(), "Campaign page error on %s: %v", r.URL.Path, err)
+		http.Error(w, "upstream RPC request failed", http.StatusBadGateway)
+		return
+	}
+
+	records, _ := loadCampaignRecords()
+	record := records.get(address)
+	page := campaignPage{
+		Name:        campaign.Name,
+		Address:     address.String(),
+		Admin:       campaign.Admin.String(),
+		ExplorerURL: s.app.AddressURL(address.String()),
+		Raised:      lamportsToSOL(campaign.AmountDonated),
+		State:       campaignState(record, campaign),
+		Description: template.HTML(MarkdownToHTML(campaign.Description)),
+	}
+	if record != nil && record.Goal > 0 {
+		page.Goal = lamportsToSOL(record.Goal)
+	}
+	if record != nil && record.Deadline != nil {
+		page.Deadline = formatTime(*record.Deadline)
+	}
+	page.FeedURL = "feed.atom"
+	if share {
+		page.FeedURL = "/campaigns/" + address.String() + "/feed.atom"
+		page.Meta = s.campaignMeta(r, address, campaign, page)
+	}
+
+	var body bytes.Buffer
+	if err := campaignPageTemplate.Execute(&body, page); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.cache.put(r.URL.Path, body.Bytes(), s.opts.CacheTTL)
+	s.watchForChanges(r.PathValue("address"))
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	w.Write(body.Bytes())
+}
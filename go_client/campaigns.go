@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// CampaignAccount is a decoded campaign together with its address and balance
+type CampaignAccount struct {
+	Address  solana.PublicKey `json:"address"`
+	Lamports uint64           `json:"lamports"`
+	Verified bool             `json:"verified,omitempty"` // listed in the configured registry
+	State    CampaignState    `json:"state,omitempty"`    // lifecycle state from the local registry
+	Deadline *time.Time       `json:"deadline,omitempty"` // when donations stop, from the local registry
+	*Campaign
+}
+
+// CampaignActivity is a donation or withdrawal found in a campaign's transaction history
+type CampaignActivity struct {
+	Signature string     `json:"signature"`
+	Kind      string     `json:"kind"` // donate or withdraw
+	Wallet    string     `json:"wallet"`
+	Amount    uint64     `json:"amount"`
+	Slot      uint64     `json:"slot"`
+	BlockTime *time.Time `json:"blockTime,omitempty"`
+	Failed    bool       `json:"failed,omitempty"`
+	Memo      string     `json:"memo,omitempty"` // message the donor attached, if any
+
+	campaign solana.PublicKey // set when decoded from a transaction
+}
+
+// CampaignStats aggregates figures across all campaigns
+type CampaignStats struct {
+	Campaigns     int    `json:"campaigns"`
+	TotalDonated  uint64 `json:"totalDonated"`
+	TotalBalance  uint64 `json:"totalBalance"`
+	LargestRaised uint64 `json:"largestRaised"`
+}
+
+// maxAccountsPerRequest is the getMultipleAccounts limit
+const maxAccountsPerRequest = 100
+
+// ListCampaigns returns every campaign account owned by the program.
+// Addresses are listed first, then accounts are fetched in batches by a bounded worker pool.
+func (app *SolanaDApp) ListCampaigns() ([]CampaignAccount, error) {
+	var zero uint64
+	keyed, err := app.client.GetProgramAccountsWithOpts(context.Background(), app.programID, &rpc.GetProgramAccountsOpts{
+		Encoding:  solana.EncodingBase64,
+		DataSlice: &rpc.DataSlice{Offset: &zero, Length: &zero},
+		Filters: []rpc.RPCFilter{
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: crowdfund.Discriminator("account", "Campaign")}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list program accounts: %w", err)
+	}
+
+	addresses := make([]solana.PublicKey, len(keyed))
+	for i, account := range keyed {
+		addresses[i] = account.Pubkey
+	}
+
+	batches := (len(addresses) + maxAccountsPerRequest - 1) / maxAccountsPerRequest
+	results := make([][]CampaignAccount, batches)
+	err = forEachParallel(batches, defaultWorkers, func(i int) error {
+		end := (i + 1) * maxAccountsPerRequest
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		batch := addresses[i*maxAccountsPerRequest : end]
+
+		accounts, err := app.client.GetMultipleAccountsWithOpts(context.Background(), batch, &rpc.GetMultipleAccountsOpts{
+			Encoding: solana.EncodingBase64,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch campaign accounts: %w", err)
+		}
+
+		for j, account := range accounts.Value {
+			if account == nil {
+				continue // Closed since it was listed
+			}
+			campaign, err := crowdfund.DecodeCampaign(account.Data.GetBinary())
+			if err != nil {
+				continue // Skip accounts that merely share the discriminator prefix
+			}
+			results[i] = append(results[i], CampaignAccount{
+				Address:  batch[j],
+				Lamports: account.Lamports,
+				Campaign: campaign,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := make([]CampaignAccount, 0, len(addresses))
+	for _, batch := range results {
+		campaigns = append(campaigns, batch...)
+	}
+	return campaigns, nil
+}
+
+// Campaign accounts are allocated 9000 bytes, almost all of it padding. List views only need the
+// fields around the description, so they fetch two small slices instead of whole accounts:
+// the header up to the description length, then amount_donated and bump at their per-account offset.
+const (
+	maxCampaignNameLen  = 32 // The name is a PDA seed, which Solana caps at 32 bytes
+	campaignHeaderSlice = 8 + 32 + 4 + maxCampaignNameLen + 4
+)
+
+// campaignHeader is the part of a campaign account before the description
+type campaignHeader struct {
+	admin          solana.PublicKey
+	name           string
+	descriptionLen uint32
+}
+
+// amountOffset is where amount_donated starts in the full account
+func (h campaignHeader) amountOffset() uint64 {
+	return uint64(8 + 32 + 4 + len(h.name) + 4 + int(h.descriptionLen))
+}
+
+// decodeCampaignHeader decodes a header slice fetched with campaignHeaderSlice
+func decodeCampaignHeader(data []byte) (campaignHeader, error) {
+	if len(data) < 8 || !bytes.Equal(data[:8], crowdfund.Discriminator("account", "Campaign")) {
+		return campaignHeader{}, crowdfund.ErrNotACampaignAccount
+	}
+
+	r := crowdfund.NewBorshReader(data, 8)
+	var header campaignHeader
+	var err error
+	if header.admin, err = r.ReadPublicKey(); err != nil {
+		return header, fmt.Errorf("%w: admin: %v", crowdfund.ErrNotACampaignAccount, err)
+	}
+	if header.name, err = r.ReadString(); err != nil {
+		return header, fmt.Errorf("%w: name: %v", crowdfund.ErrNotACampaignAccount, err)
+	}
+	if header.descriptionLen, err = r.ReadU32(); err != nil {
+		return header, fmt.Errorf("%w: description length: %v", crowdfund.ErrNotACampaignAccount, err)
+	}
+	return header, nil
+}
+
+// ListCampaignSummaries returns every campaign without its description, downloading only the
+// byte ranges it needs. Use ListCampaigns when descriptions are required.
+func (app *SolanaDApp) ListCampaignSummaries() ([]CampaignAccount, error) {
+	var zero uint64
+	headerLen := uint64(campaignHeaderSlice)
+	keyed, err := app.client.GetProgramAccountsWithOpts(context.Background(), app.programID, &rpc.GetProgramAccountsOpts{
+		Encoding:  solana.EncodingBase64,
+		DataSlice: &rpc.DataSlice{Offset: &zero, Length: &headerLen},
+		Filters: []rpc.RPCFilter{
+			{Memcmp: &rpc.RPCFilterMemcmp{Offset: 0, Bytes: crowdfund.Discriminator("account", "Campaign")}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list program accounts: %w", err)
+	}
+
+	// Accounts whose amount sits at the same offset can share a sliced getMultipleAccounts call
+	type chunk struct {
+		offset    uint64
+		addresses []solana.PublicKey
+		headers   []campaignHeader
+	}
+	byOffset := map[uint64]*chunk{}
+	var chunks []*chunk
+	for _, account := range keyed {
+		header, err := decodeCampaignHeader(account.Account.Data.GetBinary())
+		if err != nil {
+			continue // Skip accounts that merely share the discriminator prefix
+		}
+		offset := header.amountOffset()
+		c, ok := byOffset[offset]
+		if !ok || len(c.addresses) == maxAccountsPerRequest {
+			c = &chunk{offset: offset}
+			byOffset[offset] = c
+			chunks = append(chunks, c)
+		}
+		c.addresses = append(c.addresses, account.Pubkey)
+		c.headers = append(c.headers, header)
+	}
+
+	results := make([][]CampaignAccount, len(chunks))
+	err = forEachParallel(len(chunks), defaultWorkers, func(i int) error {
+		c := chunks[i]
+		offset, length := c.offset, uint64(8+1)
+		accounts, err := app.client.GetMultipleAccountsWithOpts(context.Background(), c.addresses, &rpc.GetMultipleAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			DataSlice: &rpc.DataSlice{Offset: &offset, Length: &length},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch campaign totals: %w", err)
+		}
+
+		for j, account := range accounts.Value {
+			if account == nil {
+				continue // Closed since it was listed
+			}
+			data := account.Data.GetBinary()
+			if len(data) < 9 {
+				continue
+			}
+			results[i] = append(results[i], CampaignAccount{
+				Address:  c.addresses[j],
+				Lamports: account.Lamports,
+				Campaign: &Campaign{
+					Admin:         c.headers[j].admin,
+					Name:          c.headers[j].name,
+					AmountDonated: binary.LittleEndian.Uint64(data[:8]),
+					Bump:          data[8],
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := make([]CampaignAccount, 0, len(keyed))
+	for _, batch := range results {
+		campaigns = append(campaigns, batch...)
+	}
+	return campaigns, nil
+}
+
+// SummarizeCampaigns computes aggregate statistics for a set of campaigns
+func SummarizeCampaigns(campaigns []CampaignAccount) CampaignStats {
+	stats := CampaignStats{Campaigns: len(campaigns)}
+	for _, c := range campaigns {
+		stats.TotalDonated += c.AmountDonated
+		stats.TotalBalance += c.Lamports
+		if c.AmountDonated > stats.LargestRaised {
+			stats.LargestRaised = c.AmountDonated
+		}
+	}
+	return stats
+}
+
+// GetCampaignActivity returns the most recent donations and withdrawals for a campaign, newest first
+func (app *SolanaDApp) GetCampaignActivity(campaignAddress solana.PublicKey, limit int) ([]CampaignActivity, error) {
+	signatures, err := app.client.GetSignaturesForAddressWithOpts(context.Background(), campaignAddress, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch campaign signatures: %w", err)
+	}
+
+	results := make([][]CampaignActivity, len(signatures))
+	err = forEachParallel(len(signatures), defaultWorkers, func(i int) error {
+		entries, err := app.activityInTransaction(campaignAddress, signatures[i])
+		results[i] = entries
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	activity := []CampaignActivity{}
+	for _, entries := range results {
+		activity = append(activity, entries...)
+	}
+	app.loadCommentFilter().apply(campaignAddress, activity)
+	return activity, nil
+}
+
+// activityPageSize is how many signatures are requested per page when scanning a period
+const activityPageSize = 1000
+
+// GetCampaignActivityBetween returns every successful donation and withdrawal of a campaign with
+// a block time in [from, to), oldest first, paging back through the campaign's history as far as needed
+func (app *SolanaDApp) GetCampaignActivityBetween(campaignAddress solana.PublicKey, from, to time.Time) ([]CampaignActivity, error) {
+	inPeriod, err := app.signaturesBetween(campaignAddress, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch campaign signatures: %w", err)
+	}
+
+	results := make([][]CampaignActivity, len(inPeriod))
+	err = forEachParallel(len(inPeriod), defaultWorkers, func(i int) error {
+		entries, err := app.activityInTransaction(campaignAddress, inPeriod[i])
+		results[i] = entries
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	activity := []CampaignActivity{}
+	for i := len(results) - 1; i >= 0; i-- {
+		activity = append(activity, results[i]...)
+	}
+	return activity, nil
+}
+
+// signaturesBetween returns the successful transactions of an address with a block time in
+// [from, to), newest first
+func (app *SolanaDApp) signaturesBetween(address solana.PublicKey, from, to time.Time) ([]*rpc.TransactionSignature, error) {
+	var inPeriod []*rpc.TransactionSignature
+	var before solana.Signature
+	for {
+		limit := activityPageSize
+		page, err := app.client.GetSignaturesForAddressWithOpts(context.Background(), address, &rpc.GetSignaturesForAddressOpts{
+			Limit:      &limit,
+			Before:     before,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return nil, err
+		}
+		done := len(page) < limit
+		for _, sig := range page {
+			if sig.BlockTime == nil || sig.Err != nil {
+				continue
+			}
+			t := sig.BlockTime.Time()
+			if t.Before(from) {
+				done = true
+				break
+			}
+			if t.Before(to) {
+				inPeriod = append(inPeriod, sig)
+			}
+		}
+		if done || len(page) == 0 {
+			break
+		}
+		before = page[len(page)-1].Signature
+	}
+	return inPeriod, nil
+}
+
+// activityInTransaction decodes the donate/withdraw instructions touching a campaign in one transaction
+func (app *SolanaDApp) activityInTransaction(campaignAddress solana.PublicKey, sig *rpc.TransactionSignature) ([]CampaignActivity, error) {
+	all, err := app.transactionActivity(sig)
+	if err != nil {
+		return nil, err
+	}
+	var entries []CampaignActivity
+	for _, entry := range all {
+		if entry.campaign.Equals(campaignAddress) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// transactionActivity decodes every donate/withdraw instruction in one transaction, whatever
+// campaign it touches
+func (app *SolanaDApp) transactionActivity(sig *rpc.TransactionSignature) ([]CampaignActivity, error) {
+	result, tx, keys, err := app.fetchTransaction(sig.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockTime *time.Time
+	if result.BlockTime != nil {
+		t := result.BlockTime.Time().UTC()
+		blockTime = &t
+	} else if sig.BlockTime != nil {
+		t := sig.BlockTime.Time().UTC()
+		blockTime = &t
+	}
+
+	var memos []string
+	for _, compiled := range tx.Message.Instructions {
+		if int(compiled.ProgramIDIndex) < len(keys) && isMemoProgram(keys[compiled.ProgramIDIndex]) {
+			memos = append(memos, string(compiled.Data))
+		}
+	}
+
+	var entries []CampaignActivity
+	for _, compiled := range tx.Message.Instructions {
+		if int(compiled.ProgramIDIndex) >= len(keys) || !keys[compiled.ProgramIDIndex].Equals(app.programID) {
+			continue
+		}
+		ix, ok := app.idl.MatchInstruction(compiled.Data)
+		if !ok || (ix.Name != "donate" && ix.Name != "withdraw") {
+			continue
+		}
+
+		accounts := map[string]solana.PublicKey{}
+		for i, index := range compiled.Accounts {
+			if i < len(ix.Accounts) && int(index) < len(keys) {
+				accounts[crowdfund.NormalizeName(ix.Accounts[i].Name)] = keys[index]
+			}
+		}
+		args, err := ix.DecodeArgs(compiled.Data)
+		if err != nil {
+			continue
+		}
+		var amount uint64
+		for _, arg := range args {
+			if v, ok := arg.Value.(uint64); ok && crowdfund.NormalizeName(arg.Name) == "amount" {
+				amount = v
+			}
+		}
+
+		entries = append(entries, CampaignActivity{
+			campaign:  accounts["campaign"],
+			Signature: sig.Signature.String(),
+			Kind:      ix.Name,
+			Wallet:    accounts["user"].String(),
+			Amount:    amount,
+			Slot:      result.Slot,
+			BlockTime: blockTime,
+			Failed:    sig.Err != nil,
+		})
+		if ix.Name == "donate" {
+			entries[len(entries)-1].Memo = strings.Join(memos, " ")
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,2698 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/term"
+)
+
+// command is a non-interactive subcommand, run as `crowdfunding-client <name> [args]`
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{name: "setup", args: "", summary: "Choose a cluster, create or import a wallet and set the priority fee, saving them to config.json", run: runSetupCommand},
+	{name: "idl", args: "fetch", summary: "Download the program's on-chain IDL and cache it in idl.json", run: runIDLCommand},
+	{name: "donate-link", args: "[flags] <campaign> <lamports>", summary: "Print a Solana Pay link and QR code for mobile wallets and wait for the donation", run: runDonateLinkCommand},
+	{name: "poster", args: "[-o poster.png] [-goal SOL] [-server url [-amount SOL]] <campaign>", summary: "Render a printable PNG or SVG poster with a Solana Pay QR code and the campaign's progress", run: runPosterCommand},
+	{name: "site", args: "generate [-out ./public] [-url url] [-goal SOL] [-server url [-amount SOL]] [<campaign>]", summary: "Render a static campaign page with progress, recent donations, a Solana Pay QR code and share links, or regenerate those in config.json", run: runSiteCommand},
+	{name: "widget", args: "-server url [-goal SOL] [-amount SOL] [-self-contained] <campaign>", summary: "Print an HTML snippet embedding a campaign's live progress and a donate button in any website", run: runWidgetCommand},
+	{name: "browser-sign", args: "[flags] <create|donate|withdraw> <args...>", summary: "Build a transaction and have a browser wallet (Phantom) sign it on a local page", run: runBrowserSignCommand},
+	{name: "serve", args: "[flags]", summary: "Run the public read-only HTTP API (campaign list, stats, donation feed), with -ui an admin dashboard", run: runServeCommand},
+	{name: "apikey", args: "create [-scopes read,donate,withdraw] <name> | list | revoke <id>", summary: "Issue, list or revoke the API keys `serve` requires for the relay, dashboard withdrawals and, with -require-key, the JSON API", run: runAPIKeyCommand},
+	{name: "daemon", args: "[flags]", summary: "Run background jobs: campaign snapshots, alert rules and auto-withdrawals", run: runDaemonCommand},
+	{name: "diff", args: "[flags] [campaign...]", summary: "Show how campaigns changed between two points in time, from daemon snapshots", run: runDiffCommand},
+	{name: "schedule", args: "<add|list|remove|history> [args...]", summary: "Manage cron-scheduled withdrawals and donations run by the daemon", run: runScheduleCommand},
+	{name: "digest", args: "[-send] [-hours 24]", summary: "Preview the email digest of the tracked campaigns, or send it now", run: runDigestCommand},
+	{name: "calendar", args: "[-o schedules.ics] [-days 90]", summary: "Export scheduled withdrawals and donations, and their recent runs, as an iCalendar file", run: runCalendarCommand},
+	{name: "wallet", args: "shard [-threshold 3] [-shares 5] [-o dir] <key.json> | restore-from-shards [-o wallet.json] [shard...] | encrypt [-o file] [-fido2] <key.json>", summary: "Split a wallet key into Shamir shards for recovery, restore it from enough shards, or encrypt it with a passphrase or security key", run: runWalletCommand},
+	{name: "frost", args: "keygen [-threshold 2] [-shares 3] [-from key.json] [-addresses a,b,c] [-o dir] | participant -share <file> [-listen :7070]", summary: "Create a threshold (FROST) admin key, or run a participant that approves and co-signs its transactions", run: runFrostCommand},
+	{name: "policy", args: "check [-campaign addr] [-amount SOL] [-destination wallet] [-at time]", summary: "Validate the withdrawal policy, or test whether it would allow a withdrawal", run: runPolicyCommand},
+	{name: "totp", args: "enroll [-force] [-account name] [-qr file.png]", summary: "Enroll an authenticator app whose codes policy rules can require for withdrawals", run: runTOTPCommand},
+	{name: "fido2", args: "enroll [-force]", summary: "Enroll a FIDO2 security key whose touch policy rules can require for withdrawals, or that unlocks a keystore", run: runFIDO2Command},
+	{name: "audit", args: "[list|verify] [-file audit.jsonl]", summary: "Show the hash-chained log of every transaction this client signed, or verify it", run: runAuditCommand},
+	{name: "backup", args: "create [-to s3://bucket/path] [-include-keys] | restore [-from s3://bucket/path] [-identity key.txt] [-force]", summary: "Upload an encrypted backup of the local state, or restore one", run: runBackupCommand},
+	{name: "retry-queue", args: "[list|drop <id>]", summary: "Show or drop transactions queued for retry after a transient send failure", run: runRetryQueueCommand},
+	{name: "donate", args: "[-wallet key.json] [-from key.json] [-memo text] [-all] <campaign> [SOL]", summary: "Donate to a campaign, optionally signing with another keypair for this donation only; -all gives the whole balance less fees and rent", run: runDonateCommand},
+	{name: "withdraw", args: "-wallet key.json [-all] [-dry-run] <campaign> [SOL]", summary: "Withdraw from a campaign you administer; -all takes everything above its rent-exempt minimum, -dry-run simulates it and shows the resulting balances", run: runWithdrawCommand},
+	{name: "tx", args: "build|sign|add-signature|export|import|send [args...]", summary: "Build a transaction file, have it signed on other machines or by external wallets, merge the signatures and broadcast it", run: runTxCommand},
+	{name: "batch", args: "-wallet <key.json> [-nonces 4] <items.json>", summary: "Send many donations or withdrawals in parallel over durable nonce accounts, resumably", run: runBatchCommand},
+	{name: "create-batch", args: "-wallet <key.json> [-o results.csv] <campaigns.csv>", summary: "Create a campaign for every name/description/target row of a CSV file and write their addresses and signatures", run: runCreateBatchCommand},
+	{name: "donate-batch", args: "-wallet <key.json> [-per-tx 10] [-o report.csv] <payouts.csv>", summary: "Donate to every campaign/amount row of a CSV file, several donations per transaction, and write a reconciliation report", run: runDonateBatchCommand},
+	{name: "comments", args: "[campaign] | mute|unmute <campaign> <donor>", summary: "Show the messages donors attached to a campaign's donations, or mute a donor", run: runCommentsCommand},
+	{name: "registry", args: "show | sign -wallet <curator.json> [-name text] [-o registry.json] <campaigns.json>", summary: "Show the verified campaign registry, or sign one as its curator", run: runRegistryCommand},
+	{name: "report", args: "compliance -period 2024Q4 -wallet <key.json> [-campaigns a,b] [-o dir] | tax -wallet <pubkey> -year 2024 [-o dir] | verify <dir>", summary: "Export a signed compliance package of a period's fund movements, counterparties, screening results and policy exceptions, or a donor's tax-year summary with fiat values", run: runReportCommand},
+	{name: "export", args: "-format ledger|beancount [-period 2024Q4] [-campaigns a,b] [-o file]", summary: "Write every donation and withdrawal of the campaigns as Ledger or Beancount entries for the organization's books", run: runExportCommand},
+	{name: "ledger", args: "[balances] | sync [-campaigns a,b] | verify", summary: "Show the double-entry ledger of campaign funds, add transactions it is missing, or check it against on-chain balances", run: runLedgerCommand},
+	{name: "reconcile", args: "[-yes] <campaign>", summary: "Compare the campaign's donations and withdrawals in the local ledger with the chain, and offer to repair missing, duplicate or mismatched entries", run: runReconcileCommand},
+	{name: "campaign", args: "draft -wallet <key.json> [-goal SOL] [-edit] <name> [description] | publish -wallet <key.json> <name> | goal <campaign> <SOL> | close|reopen <campaign> | list", summary: "Track campaigns through draft, active, goal reached and closed; closed campaigns refuse donations", run: runCampaignCommand},
+	{name: "list", args: "[--json]", summary: "List every campaign with its raised total and balance", run: runListCommand},
+	{name: "pda", args: "--wallet <pubkey> --name <campaign> [--check address] [--json]", summary: "Print the campaign address and bump for any wallet and campaign name, without that wallet's key", run: runPDACommand},
+	{name: "inspect", args: "[-full] [--json] <address>", summary: "Show any account: owner, balance and rent, decoded campaign or IDL data for our program's accounts, else a hex dump", run: runInspectCommand},
+	{name: "disasm", args: "[-encoding auto|hex|base64|base58] <data>", summary: "Decode raw instruction data: match its discriminator to a program instruction and decode the Borsh arguments", run: runDisasmCommand},
+	{name: "decode-tx", args: "<signature>", summary: "Decode the crowdfunding instructions in any transaction", run: runDecodeTxCommand},
+	{name: "bench-rpc", args: "[-duration 30s] [-interval 1s] [--json] [endpoint...]", summary: "Measure latency, error rate and slot lag of the configured RPC endpoints and recommend which to use", run: runBenchRPCCommand},
+	{name: "version", args: "[--json]", summary: "Print the client version, commit, build date and target program", run: runVersionCommand},
+}
+
+// lookupCommand returns the subcommand with the given name, or nil
+func lookupCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// printUsage lists the interactive mode and all subcommands
+func printUsage() {
+	program := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage:\n")
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  %s [wallet.json]\t%s\n", program, "Start the interactive menu")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s %s %s\t%s\n", program, cmd.name, cmd.args, cmd.summary)
+	}
+	w.Flush()
+	fmt.Fprintf(os.Stderr, "\nAdd %s to any of them to dump each transaction and the raw RPC traffic to stderr.\n", debugTxFlag)
+}
+
+// runIDLCommand handles `idl fetch`
+func runIDLCommand(args []string) error {
+	if len(args) != 1 || args[0] != "fetch" {
+		return fmt.Errorf("usage: idl fetch")
+	}
+
+	app := NewReadOnlyDApp()
+	idl, err := app.FetchIDL()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Fetched IDL for '%s' (%d instructions, %d errors)\n", idl.ProgramName(), len(idl.Instructions), len(idl.Errors))
+	fmt.Printf("💾 Cached to %s; it will be used for instruction building and error decoding\n", idlCacheFile)
+	return nil
+}
+
+// runSetupCommand handles `setup`
+func runSetupCommand(args []string) error {
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	setup, err := RunSetupWizard(bufio.NewReader(os.Stdin))
+	if err != nil {
+		return err
+	}
+	if !setup.Airdrop {
+		return nil
+	}
+	app, err := NewSolanaDApp(setup.Wallet)
+	if err != nil {
+		return err
+	}
+	defer app.wsClient.Close()
+	return app.RequestAirdrop()
+}
+
+// runVersionCommand handles `version [--json]`
+func runVersionCommand(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	info := GetBuildInfo()
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+
+	fmt.Printf("crowdfunding-client %s\n", info)
+	if info.BuildDate != "" {
+		fmt.Printf("Built:   %s\n", info.BuildDate)
+	}
+	fmt.Printf("Go:      %s %s\n", info.GoVersion, info.Platform)
+	fmt.Printf("Program: %s\n", info.ProgramID)
+	fmt.Printf("Network: %s\n", info.Network)
+	return nil
+}
+
+// runBenchRPCCommand handles `bench-rpc [-duration 30s] [-interval 1s] [--json] [endpoint...]`
+func runBenchRPCCommand(args []string) error {
+	fs := flag.NewFlagSet("bench-rpc", flag.ContinueOnError)
+	duration := fs.Duration("duration", 30*time.Second, "sampling window")
+	interval := fs.Duration("interval", time.Second, "time between samples")
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 || *duration < *interval {
+		return fmt.Errorf("-interval must be positive and no longer than -duration")
+	}
+
+	// The configured endpoints, the cluster's public one for comparison, and any extra candidates
+	config := loadConfig()
+	var endpoints []string
+	seen := map[string]bool{}
+	for _, endpoint := range append([]string{config.readEndpoint(), config.sendEndpoint(), config.cluster().RPC}, fs.Args()...) {
+		if !seen[endpoint] {
+			seen[endpoint] = true
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	if !*asJSON {
+		fmt.Printf("⏱️  Sampling %d endpoints every %s for %s...\n", len(endpoints), *interval, *duration)
+	}
+	stats := benchEndpoints(context.Background(), endpoints, *duration, *interval)
+
+	if *asJSON {
+		results := make([]map[string]interface{}, len(stats))
+		for i, s := range stats {
+			results[i] = map[string]interface{}{
+				"endpoint":     s.Endpoint,
+				"rank":         i + 1,
+				"samples":      s.Samples,
+				"errors":       s.Errors,
+				"errorRate":    s.ErrorRate(),
+				"latencyP50Ms": s.LatencyP50.Milliseconds(),
+				"latencyP90Ms": s.LatencyP90.Milliseconds(),
+				"slotLag":      s.SlotLag,
+				"lastError":    s.LastError,
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nRANK\tENDPOINT\tP50\tP90\tERRORS\tSLOT LAG")
+	for i, s := range stats {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d/%d (%.0f%%)\t%.1f\n", i+1, s.Endpoint, s.LatencyP50.Round(time.Millisecond), s.LatencyP90.Round(time.Millisecond), s.Errors, s.Samples, 100*s.ErrorRate(), s.SlotLag)
+	}
+	w.Flush()
+	for _, s := range stats {
+		if s.LastError != "" {
+			fmt.Printf("⚠️  %s: %s\n", s.Endpoint, s.LastError)
+		}
+	}
+
+	best := stats[0]
+	if best.Samples == best.Errors {
+		return fmt.Errorf("no endpoint answered")
+	}
+	fmt.Println("\n🏆 Recommended order, best first:")
+	for i, s := range stats {
+		fmt.Printf("   %d. %s\n", i+1, s.Endpoint)
+	}
+	if best.Endpoint != config.readEndpoint() || best.Endpoint != config.sendEndpoint() {
+		fmt.Printf("💡 Set \"rpcUrl\" and \"sendRpcUrl\" in %s to %s\n", configFile, best.Endpoint)
+	} else {
+		fmt.Println("✅ Your configured endpoint is already the best one")
+	}
+	return nil
+}
+
+// runListCommand handles `list [--json]`
+func runListCommand(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	app := NewReadOnlyDApp()
+	campaigns, err := app.ListCampaignSummaries()
+	if err != nil {
+		return err
+	}
+	registry, err := app.Registry()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not check the campaign registry: %v\n", err)
+	}
+	if registry != nil {
+		for i := range campaigns {
+			campaigns[i].Verified = registry.Entry(campaigns[i].Address) != nil
+		}
+	}
+	records, err := loadCampaignRecords()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+	for i := range campaigns {
+		records.annotate(&campaigns[i])
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(campaigns)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tNAME\tADMIN\tRAISED (SOL)\tBALANCE (SOL)\tSTATE\tVERIFIED")
+	for _, c := range campaigns {
+		verified := ""
+		switch {
+		case registry == nil:
+		case c.Verified:
+			verified = "✅"
+		default:
+			verified = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Address, c.Name, c.Admin, lamportsToSOL(c.AmountDonated), lamportsToSOL(c.Lamports), c.State, verified)
+	}
+	w.Flush()
+
+	stats := SummarizeCampaigns(campaigns)
+	fmt.Printf("\n📊 %d campaigns, %s SOL raised, %s SOL held\n", stats.Campaigns, lamportsToSOL(stats.TotalDonated), lamportsToSOL(stats.TotalBalance))
+	return nil
+}
+
+// runInspectCommand handles `inspect <address>`
+func runInspectCommand(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	full := fs.Bool("full", false, "dump all account data, also for decoded accounts")
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: inspect [-full] [--json] <address>")
+	}
+	address, err := solana.PublicKeyFromBase58(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+
+	app := NewReadOnlyDApp()
+	info, err := app.InspectAccount(context.Background(), address)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+	info.Print(app, *full)
+	return nil
+}
+
+// runDisasmCommand handles `disasm <hex|base64>`
+func runDisasmCommand(args []string) error {
+	fs := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	encoding := fs.String("encoding", "auto", "hex, base64 or base58; auto tries them in that order")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: disasm [-encoding auto|hex|base64|base58] <instruction-data>")
+	}
+	data, used, err := parseInstructionData(strings.Join(fs.Args(), ""), *encoding)
+	if err != nil {
+		return err
+	}
+	if *encoding == "auto" {
+		fmt.Printf("Read as %s; pass -encoding if that is wrong\n", used)
+	}
+	return NewReadOnlyDApp().Disassemble(data)
+}
+
+// runDecodeTxCommand handles `decode-tx <signature>`
+func runDecodeTxCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: decode-tx <signature>")
+	}
+
+	signature, err := solana.SignatureFromBase58(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	return NewReadOnlyDApp().DecodeTransaction(signature)
+}
+
+// runPDACommand handles `pda --wallet <pubkey> --name <campaign>`
+func runPDACommand(args []string) error {
+	fs := flag.NewFlagSet("pda", flag.ContinueOnError)
+	wallet := fs.String("wallet", "", "admin wallet that created (or will create) the campaign")
+	name := fs.String("name", "", "campaign name")
+	check := fs.String("check", "", "compare the derived address with this one, e.g. an address a donor pasted")
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *wallet == "" || *name == "" || fs.NArg() != 0 {
+		return fmt.Errorf("usage: pda --wallet <pubkey> --name <campaign> [--check address] [--json]")
+	}
+
+	admin, err := solana.PublicKeyFromBase58(*wallet)
+	if err != nil {
+		return fmt.Errorf("invalid wallet address: %w", err)
+	}
+	app := NewReadOnlyDApp()
+	address, bump, err := app.CampaignPDAFor(admin, *name)
+	if err != nil {
+		return fmt.Errorf("failed to derive campaign address: %w", err)
+	}
+
+	var matches *bool
+	if *check != "" {
+		expected, err := solana.PublicKeyFromBase58(strings.TrimSpace(*check))
+		if err != nil {
+			return fmt.Errorf("invalid address to check: %w", err)
+		}
+		match := expected.Equals(address)
+		matches = &match
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(map[string]interface{}{
+			"wallet":  admin.String(),
+			"name":    *name,
+			"address": address.String(),
+			"bump":    bump,
+			"seed":    string(app.campaignSeed()),
+			"program": app.programID.String(),
+			"matches": matches,
+		}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("📍 Campaign address: %s\n", address)
+		fmt.Printf("   Bump: %d\n", bump)
+		fmt.Printf("   Seeds: %q, %s, %q\n", app.campaignSeed(), admin, *name)
+		fmt.Printf("   Program: %s\n", app.programID)
+		fmt.Printf("🔗 %s\n", app.AddressURL(address.String()))
+		if matches != nil && *matches {
+			fmt.Println("✅ Matches the address checked")
+		}
+	}
+
+	if matches != nil && !*matches {
+		return fmt.Errorf("%s is not the address of campaign %q of %s", strings.TrimSpace(*check), *name, admin)
+	}
+	return nil
+}
+
+// runDonateLinkCommand handles `donate-link <campaign> <lamports>`
+func runDonateLinkCommand(args []string) error {
+	fs := flag.NewFlagSet("donate-link", flag.ContinueOnError)
+	pngPath := fs.String("png", "", "also write the QR code to this PNG file")
+	timeout := fs.Duration("timeout", 10*time.Minute, "how long to wait for the donation to land")
+	server := fs.String("server", "", "public URL of a `serve` instance to build the donation, so it counts towards amount_donated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: donate-link [-png file] [-timeout 10m] [-server url] <campaign-address> <lamports>")
+	}
+	if *server != "" {
+		var err error
+		if *server, err = parseServerURL(*server); err != nil {
+			return err
+		}
+	}
+
+	campaignAddress, err := solana.PublicKeyFromBase58(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid campaign address: %w", err)
+	}
+	lamports, err := strconv.ParseUint(fs.Arg(1), 10, 64)
+	if err != nil || lamports == 0 {
+		return fmt.Errorf("amount must be a positive number of lamports")
+	}
+
+	return NewReadOnlyDApp().CreateDonationLink(campaignAddress, lamports, *server, *pngPath, *timeout)
+}
+
+// runPosterCommand handles `poster <campaign>`
+func runPosterCommand(args []string) error {
+	fs := flag.NewFlagSet("poster", flag.ContinueOnError)
+	output := fs.String("o", "poster.png", "output file, .png or .svg")
+	goal := fs.String("goal", "", "fundraising goal in SOL, shown as a progress bar")
+	server := fs.String("server", "", "public URL of a `serve` instance to build the donations, so they count towards amount_donated")
+	amount := fs.String("amount", "", "SOL the QR code asks for with -server (default 0.1)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: poster [-o poster.png|poster.svg] [-goal SOL] [-server url [-amount SOL]] <campaign-address>")
+	}
+
+	campaignAddress, err := solana.PublicKeyFromBase58(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid campaign address: %w", err)
+	}
+	var goalLamports uint64
+	if *goal != "" {
+		if goalLamports, err = parseSOL(*goal); err != nil {
+			return err
+		}
+	}
+
+	var amountLamports uint64
+	if *amount != "" {
+		if amountLamports, err = parseSOL(*amount); err != nil {
+			return err
+		}
+	}
+	if *server != "" {
+		if *server, err = parseServerURL(*server); err != nil {
+			return err
+		}
+	}
+
+	return NewReadOnlyDApp().CreatePoster(campaignAddress, goalLamports, *server, amountLamports, *output)
+}
+
+// runSiteCommand handles `site generate ...`
+func runSiteCommand(args []string) error {
+	usage := fmt.Errorf("usage: site generate [-out ./public] [-url url] [-goal SOL] [-server url [-amount SOL]] [<campaign-address>]")
+	if len(args) == 0 || args[0] != "generate" {
+		return usage
+	}
+	fs := flag.NewFlagSet("site generate", flag.ContinueOnError)
+	out := fs.String("out", "public", "directory to write index.html to")
+	pageURL := fs.String("url", "", "where the page will be published, used in share links (default: the address it is opened at)")
+	goal := fs.String("goal", "", "fundraising goal in SOL, shown as a progress bar (default: the goal in "+lifecycleFile+")")
+	server := fs.String("server", "", "public URL of a `serve` instance to build the donations, so they count towards amount_donated")
+	amount := fs.String("amount", "", "SOL the donate link asks for with -server (default 0.1)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usage
+	}
+
+	app := NewReadOnlyDApp()
+	// Without a campaign, regenerate the pages the daemon keeps up to date
+	sites := app.config.Sites
+	if fs.NArg() == 1 {
+		sites = []SiteConfig{{Campaign: fs.Arg(0), Out: *out, URL: *pageURL, Goal: *goal, Server: *server, Amount: *amount}}
+	} else if len(sites) == 0 {
+		return fmt.Errorf("no campaign given and no sites in %s", configFile)
+	}
+	for i := range sites {
+		if err := sites[i].prepare(); err != nil {
+			return err
+		}
+		path, err := app.GenerateSite(context.Background(), &sites[i])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🌐 Static page of %s written to %s\n", sites[i].Campaign, path)
+	}
+	return nil
+}
+
+// runWidgetCommand handles `widget ...`
+func runWidgetCommand(args []string) error {
+	fs := flag.NewFlagSet("widget", flag.ContinueOnError)
+	server := fs.String("server", "", "public URL of the `serve` instance the widget reads, e.g. https://donate.example.org")
+	goal := fs.String("goal", "", "fundraising goal in SOL, shown as a progress bar (default: the goal in "+lifecycleFile+")")
+	selfContained := fs.Bool("self-contained", false, "inline the widget script instead of loading it from the server")
+	amount := fs.String("amount", "", "SOL the donate button asks for (default 0.1)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *server == "" {
+		return fmt.Errorf("usage: widget -server url [-goal SOL] [-amount SOL] [-self-contained] <campaign-address>")
+	}
+	campaignAddress, err := solana.PublicKeyFromBase58(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid campaign address: %w", err)
+	}
+	var goalLamports uint64
+	if *goal != "" {
+		if goalLamports, err = parseSOL(*goal); err != nil {
+			return err
+		}
+	}
+
+	var amountLamports uint64
+	if *amount != "" {
+		if amountLamports, err = parseSOL(*amount); err != nil {
+			return err
+		}
+	}
+
+	snippet, err := NewReadOnlyDApp().WidgetSnippet(*server, campaignAddress, goalLamports, amountLamports, *selfContained)
+	if err != nil {
+		return err
+	}
+	fmt.Print(snippet)
+	return nil
+}
+
+// runBrowserSignCommand handles `browser-sign <action> ...`
+func runBrowserSignCommand(args []string) error {
+	fs := flag.NewFlagSet("browser-sign", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:0", "local address for the signing page")
+	timeout := fs.Duration("timeout", 5*time.Minute, "how long the signing page stays available")
+	applySendFlags := addSendFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	usage := fmt.Errorf("usage: browser-sign [flags] create <name> <description> | donate <campaign> <lamports> | withdraw <campaign> <lamports>")
+	if fs.NArg() != 3 {
+		return usage
+	}
+
+	app := NewReadOnlyDApp()
+	if err := applySendFlags(app.config); err != nil {
+		return err
+	}
+	req := BrowserSignRequest{Action: fs.Arg(0)}
+	switch req.Action {
+	case "create":
+		req.Name = fs.Arg(1)
+		req.Text = fs.Arg(2)
+	case "donate", "withdraw":
+		campaignAddress, err := solana.PublicKeyFromBase58(fs.Arg(1))
+		if err != nil {
+			return fmt.Errorf("invalid campaign address: %w", err)
+		}
+		amount, err := strconv.ParseUint(fs.Arg(2), 10, 64)
+		if err != nil || amount == 0 {
+			return fmt.Errorf("amount must be a positive number of lamports")
+		}
+		campaign, err := app.FetchCampaign(campaignAddress)
+		if err != nil {
+			return err
+		}
+		if req.Action == "donate" {
+			if err := app.checkDonationsOpen(campaignAddress); err != nil {
+				return err
+			}
+		}
+		req.Campaign = campaignAddress
+		req.Name = campaign.Name
+		req.Amount = amount
+	default:
+		return usage
+	}
+
+	return app.SignInBrowser(req, *listen, *timeout)
+}
+
+// runServeCommand handles `serve`
+func runServeCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	opts := ServerOptions{}
+	fs.StringVar(&opts.Listen, "listen", ":8080", "address to listen on")
+	fs.DurationVar(&opts.CacheTTL, "cache-ttl", 30*time.Second, "how long responses are cached (also sent as Cache-Control max-age)")
+	fs.Float64Var(&opts.RateLimit, "rate", 5, "requests per second allowed per client IP")
+	fs.IntVar(&opts.RateBurst, "burst", 20, "burst size allowed per client IP")
+	fs.BoolVar(&opts.TrustProxy, "trust-proxy", false, "use X-Forwarded-For for the client IP (only behind a trusted CDN or proxy)")
+	fs.IntVar(&opts.ProxyHops, "proxy-hops", 1, "with -trust-proxy, how many proxies in front of the server append to X-Forwarded-For")
+	fs.BoolVar(&opts.TLS, "tls", false, "serve HTTPS")
+	domains := fs.String("domain", "", "comma-separated domains to obtain Let's Encrypt certificates for (with -tls)")
+	fs.StringVar(&opts.CertFile, "cert", "", "TLS certificate file, instead of Let's Encrypt")
+	fs.StringVar(&opts.KeyFile, "key", "", "TLS private key file, instead of Let's Encrypt")
+	fs.StringVar(&opts.ACMECache, "acme-cache", "autocert-cache", "directory for Let's Encrypt certificates")
+	fs.StringVar(&opts.ACMEEmail, "acme-email", "", "contact email for the Let's Encrypt account")
+	fs.StringVar(&opts.Relay.Wallet, "relay-wallet", "", "enable the gasless donation relay, paying fees from this key file")
+	fs.Uint64Var(&opts.Relay.PriorityFee, "relay-priority-fee", 1000, "compute unit price of relayed donations, in micro-lamports")
+	relayUnits := fs.Uint("relay-compute-units", 50000, "compute unit limit of relayed donations")
+	fs.IntVar(&opts.Relay.Quota, "relay-quota", 10, "relayed donations allowed per donor per 24 hours")
+	fs.BoolVar(&opts.UI, "ui", false, "serve the admin dashboard under /ui/")
+	fs.StringVar(&opts.UIToken, "ui-token", "", "dashboard access token (default: generated at startup)")
+	fs.BoolVar(&opts.RequireKey, "require-key", false, "require an API key for the JSON API, event streams, WebSocket (read scope) and donation intents (donate scope)")
+	corsOrigins := fs.String("cors-origins", "*", "comma-separated origins whose pages may read the API, * for any, empty for none")
+	fs.BoolVar(&opts.Gzip, "gzip", true, "compress JSON, HTML, SVG and feed responses for clients that accept gzip")
+	fs.StringVar(&opts.AccessLog, "access-log", "", "append a JSON line per request to this file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: serve [flags]")
+	}
+	if opts.ProxyHops < 1 {
+		return fmt.Errorf("-proxy-hops must be at least 1")
+	}
+
+	for _, origin := range strings.Split(*corsOrigins, ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			opts.CORSOrigins = append(opts.CORSOrigins, origin)
+		}
+	}
+
+	if opts.TLS {
+		if *domains != "" {
+			opts.Domains = strings.Split(*domains, ",")
+		}
+		if (opts.CertFile == "") != (opts.KeyFile == "") {
+			return fmt.Errorf("-cert and -key must be used together")
+		}
+		if opts.CertFile == "" && len(opts.Domains) == 0 {
+			return fmt.Errorf("-tls needs either -domain for Let's Encrypt or -cert and -key")
+		}
+		listenSet := false
+		fs.Visit(func(f *flag.Flag) { listenSet = listenSet || f.Name == "listen" })
+		if !listenSet {
+			opts.Listen = ":443"
+		}
+	}
+
+	app := NewReadOnlyDApp()
+	wsClient, err := dialWS(context.Background(), app.config.wsEndpoint())
+	if err != nil {
+		return fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+	defer wsClient.Close()
+	app.wsClient = wsClient
+
+	if app.config.RateLimits != nil {
+		if err := app.config.RateLimits.prepare(); err != nil {
+			return err
+		}
+		opts.RateLimits = app.config.RateLimits
+	}
+
+	server := NewServer(app, opts)
+	if opts.Relay.Wallet != "" {
+		opts.Relay.ComputeUnits = uint32(*relayUnits)
+		if server.relay, err = newRelayer(app, opts.Relay); err != nil {
+			return err
+		}
+		fmt.Printf("⛽ Gasless donation relay enabled, fees paid by %s\n", server.relay.wallet.Address())
+	}
+	if opts.AccessLog != "" {
+		if server.accessLog, err = openAccessLog(opts.AccessLog); err != nil {
+			return err
+		}
+	}
+	if opts.UI {
+		if server.dashboard, err = newDashboard(app, opts.UIToken); err != nil {
+			return err
+		}
+		scheme, host := "http", opts.Listen
+		if opts.TLS {
+			scheme = "https"
+		}
+		if strings.HasPrefix(host, ":") {
+			host = "localhost" + host
+		}
+		fmt.Printf("🖥️  Admin dashboard: %s://%s/ui/?token=%s\n", scheme, host, server.dashboard.token)
+		fmt.Printf("💡 The token is read-only; withdraw from the dashboard by opening it with ?token=<API key> of a key with the withdraw scope\n")
+	}
+	if app.config.Redis != nil {
+		if err := server.useRedis(app.config.Redis); err != nil {
+			return err
+		}
+		fmt.Println("🧰 Caching responses and accounts in Redis, invalidated when campaigns change on-chain")
+	}
+	return server.ListenAndServe()
+}
+
+// runDaemonCommand handles `daemon`
+func runDaemonCommand(args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	interval := fs.Duration("interval", 0, "snapshot interval (default from config.json, else 15m)")
+	walletPath := fs.String("wallet", "", "wallet used to sign auto-withdrawals, schedules and queued retries")
+	dryRun := fs.Bool("dry-run", false, "record what auto-withdraw would do without signing anything")
+	rpcProxy := fs.String("rpc-proxy", "", "serve a caching JSON-RPC proxy on this address, e.g. 127.0.0.1:8898")
+	applySendFlags := addSendFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: daemon [flags]")
+	}
+
+	app := NewReadOnlyDApp()
+	if *walletPath != "" {
+		var err error
+		if app, err = NewSolanaDApp(*walletPath); err != nil {
+			return fmt.Errorf("failed to initialize dApp: %w", err)
+		}
+		defer app.wsClient.Close()
+	}
+	if err := applySendFlags(app.config); err != nil {
+		return err
+	}
+	if *rpcProxy != "" {
+		if app.config.Daemon == nil {
+			app.config.Daemon = &DaemonConfig{}
+		}
+		if app.config.Daemon.RPCProxy == nil {
+			app.config.Daemon.RPCProxy = &RPCProxyConfig{}
+		}
+		app.config.Daemon.RPCProxy.Listen = *rpcProxy
+	}
+
+	campaigns, err := app.trackedCampaigns()
+	if err != nil {
+		return err
+	}
+
+	if *interval == 0 {
+		*interval = defaultSnapshotInterval
+		if app.config.Daemon != nil && app.config.Daemon.SnapshotInterval != "" {
+			if *interval, err = time.ParseDuration(app.config.Daemon.SnapshotInterval); err != nil {
+				return fmt.Errorf("invalid daemon.snapshotInterval in %s: %w", configFile, err)
+			}
+		}
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("the snapshot interval must be positive")
+	}
+
+	store, err := OpenStore()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	daemon, err := NewDaemon(app, store, campaigns, *interval, *dryRun)
+	if err != nil {
+		return err
+	}
+	return daemon.Run(ctx)
+}
+
+// trackedCampaigns returns the campaigns listed under daemon.campaigns in config.json,
+// falling back to the saved campaign
+func (app *SolanaDApp) trackedCampaigns() ([]solana.PublicKey, error) {
+	var campaigns []solana.PublicKey
+	if app.config.Daemon != nil {
+		for _, address := range app.config.Daemon.Campaigns {
+			campaign, err := solana.PublicKeyFromBase58(address)
+			if err != nil {
+				return nil, fmt.Errorf("invalid campaign address %q in %s: %w", address, configFile, err)
+			}
+			campaigns = append(campaigns, campaign)
+		}
+	}
+
+	if len(campaigns) == 0 {
+		app.loadSavedCampaign()
+		if app.campaignAddress == nil {
+			return nil, fmt.Errorf("no campaigns to track: list them under daemon.campaigns in %s", configFile)
+		}
+		campaigns = append(campaigns, *app.campaignAddress)
+	}
+	return campaigns, nil
+}
+
+// runDiffCommand handles `diff [flags] [campaign...]`
+func runDiffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	since := fs.Duration("since", 24*time.Hour, "compare against this long ago")
+	fromFlag := fs.String("from", "", "start time (RFC 3339 or YYYY-MM-DD), instead of -since")
+	toFlag := fs.String("to", "", "end time (RFC 3339 or YYYY-MM-DD); default now")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	to := time.Now()
+	if *toFlag != "" {
+		t, err := parseTime(*toFlag)
+		if err != nil {
+			return err
+		}
+		to = t
+	}
+	from := to.Add(-*since)
+	if *fromFlag != "" {
+		t, err := parseTime(*fromFlag)
+		if err != nil {
+			return err
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("the start time must be before the end time")
+	}
+
+	store, err := OpenStore()
+	if err != nil {
+		return err
+	}
+
+	campaigns := store.SnapshotCampaigns()
+	if fs.NArg() > 0 {
+		campaigns = nil
+		for _, arg := range fs.Args() {
+			campaign, err := solana.PublicKeyFromBase58(arg)
+			if err != nil {
+				return fmt.Errorf("invalid campaign address %q: %w", arg, err)
+			}
+			campaigns = append(campaigns, campaign)
+		}
+	}
+	if len(campaigns) == 0 {
+		return fmt.Errorf("no snapshots yet: run `daemon` to start recording them")
+	}
+
+	for _, campaign := range campaigns {
+		diff, ok := DiffSnapshots(store, campaign, from, to)
+		if !ok {
+			fmt.Printf("❓ %s: no snapshots before %s\n", campaign, formatTime(to))
+			continue
+		}
+
+		fmt.Printf("\n📈 %s (%s)\n", diff.Name, campaign)
+		fmt.Printf("   %s → %s\n", formatTime(diff.From.Time), formatTime(diff.To.Time))
+		fmt.Printf("   Raised %s SOL (total %s SOL)\n", lamportsToSOL(diff.Raised()), lamportsToSOL(diff.To.AmountDonated))
+
+		change := diff.BalanceChange()
+		sign := "+"
+		if change < 0 {
+			sign, change = "-", -change
+		}
+		fmt.Printf("   Balance %s%s SOL (now %s SOL)\n", sign, lamportsToSOL(uint64(change)), lamportsToSOL(diff.To.Lamports))
+	}
+	return nil
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates in local time
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
+
+// runScheduleCommand handles `schedule add|list|remove|history`
+func runScheduleCommand(args []string) error {
+	usage := fmt.Errorf("usage: schedule add \"<cron spec>\" <withdraw|donate> -amount <SOL> [-campaign <address>] | list | remove <id> | history")
+	if len(args) == 0 {
+		return usage
+	}
+
+	store, err := OpenStore()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 3 {
+			return usage
+		}
+		spec, action := args[1], args[2]
+		if action != "withdraw" && action != "donate" {
+			return fmt.Errorf("unsupported scheduled action %q: use withdraw or donate", action)
+		}
+		if _, err := cronParser.Parse(spec); err != nil {
+			return fmt.Errorf("invalid cron spec %q: %w", spec, err)
+		}
+
+		fs := flag.NewFlagSet("schedule add", flag.ContinueOnError)
+		amount := fs.String("amount", "", "amount in SOL, e.g. 1SOL or 0.5")
+		campaignFlag := fs.String("campaign", "", "campaign address (default: the saved campaign)")
+		if err := fs.Parse(args[3:]); err != nil {
+			return err
+		}
+		if *amount == "" && fs.NArg() == 1 {
+			*amount = fs.Arg(0)
+		}
+		lamports, err := parseSOL(*amount)
+		if err != nil || lamports == 0 {
+			return fmt.Errorf("a positive -amount is required")
+		}
+
+		app := NewReadOnlyDApp()
+		var campaign solana.PublicKey
+		if *campaignFlag != "" {
+			if campaign, err = solana.PublicKeyFromBase58(*campaignFlag); err != nil {
+				return fmt.Errorf("invalid campaign address: %w", err)
+			}
+		} else {
+			app.loadSavedCampaign()
+			if app.campaignAddress == nil {
+				return fmt.Errorf("no saved campaign: pass -campaign")
+			}
+			campaign = *app.campaignAddress
+		}
+
+		schedule, err := store.AddSchedule(Schedule{Spec: spec, Action: action, Campaign: campaign, Amount: lamports, CreatedAt: time.Now().UTC()})
+		if err != nil {
+			return err
+		}
+		next, _ := schedule.Next()
+		fmt.Printf("✅ Schedule %s added: %s at \"%s\"\n", schedule.ID, schedule.Describe(), spec)
+		fmt.Printf("⏰ Next run: %s (the daemon must be running with -wallet)\n", formatTime(next))
+
+	case "list":
+		schedules := store.Schedules()
+		if len(schedules) == 0 {
+			fmt.Println("No schedules. Add one with `schedule add`.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSPEC\tOPERATION\tLAST RUN\tNEXT RUN")
+		for _, schedule := range schedules {
+			lastRun := "never"
+			if !schedule.LastRun.IsZero() {
+				lastRun = formatTime(schedule.LastRun)
+			}
+			nextRun := "invalid spec"
+			if next, err := schedule.Next(); err == nil {
+				nextRun = formatTime(next)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", schedule.ID, schedule.Spec, schedule.Describe(), lastRun, nextRun)
+		}
+		w.Flush()
+
+	case "remove":
+		if len(args) != 2 {
+			return usage
+		}
+		if err := store.RemoveSchedule(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️  Schedule %s removed\n", args[1])
+
+	case "history":
+		runs := store.ScheduleRuns()
+		if len(runs) == 0 {
+			fmt.Println("No scheduled operations have run yet.")
+			return nil
+		}
+		for _, run := range runs {
+			if run.Error != "" {
+				fmt.Printf("❌ %s schedule %s: %s (operation %s)\n", formatTime(run.Time), run.ScheduleID, run.Error, run.OperationID)
+			} else {
+				fmt.Printf("✅ %s schedule %s: %s\n", formatTime(run.Time), run.ScheduleID, run.Signature)
+			}
+		}
+
+	default:
+		return usage
+	}
+	return nil
+}
+
+// runWalletCommand handles `wallet shard` and `wallet restore-from-shards`
+func runWalletCommand(args []string) error {
+	usage := fmt.Errorf("usage: wallet shard [-threshold 3] [-shares 5] [-o dir] <key.json> | restore-from-shards [-o wallet.json] [shard or file...] | encrypt [-o wallet.keystore.json] [-fido2] <key.json>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "shard":
+		fs := flag.NewFlagSet("wallet shard", flag.ContinueOnError)
+		threshold := fs.Int("threshold", 3, "shards needed to restore the key")
+		shares := fs.Int("shares", 5, "shards to create")
+		dir := fs.String("o", "", "write shard-N.txt and a shard-N.png QR code per shard to this directory instead of printing them")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return usage
+		}
+
+		wallet, err := NewWallet(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		shards, err := ShardWallet(wallet, *shares, *threshold)
+		if err != nil {
+			return err
+		}
+		if *dir != "" {
+			if err := os.MkdirAll(*dir, 0700); err != nil {
+				return fmt.Errorf("failed to create %s: %w", *dir, err)
+			}
+		}
+
+		fmt.Printf("🧩 Split %s into %d shards; any %d of them restore it\n", wallet.PublicKey, *shares, *threshold)
+		for _, shard := range shards {
+			if *dir == "" {
+				fmt.Printf("\nShard %d of %d:\n%s\n", shard.Index, *shares, shard)
+				if err := PrintQRCode(shard.String(), ""); err != nil {
+					return err
+				}
+				continue
+			}
+			base := filepath.Join(*dir, fmt.Sprintf("shard-%d", shard.Index))
+			if err := shard.Save(base); err != nil {
+				return err
+			}
+			fmt.Printf("💾 %s.txt and %s.png\n", base, base)
+		}
+		fmt.Println("⚠️  Give each shard to a different person or place; fewer than the threshold reveal nothing about the key")
+		return nil
+
+	case "encrypt":
+		fs := flag.NewFlagSet("wallet encrypt", flag.ContinueOnError)
+		output := fs.String("o", "wallet.keystore.json", "encrypted keystore file to write")
+		withFIDO2 := fs.Bool("fido2", false, "unlock with a touch of the enrolled security key instead of a passphrase")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return usage
+		}
+		if _, err := os.Stat(*output); err == nil {
+			return fmt.Errorf("%s already exists; choose another file with -o", *output)
+		}
+
+		keyFile, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("failed to read key file: %w", err)
+		}
+		privateKey, err := parseKeyFile(keyFile)
+		if err != nil {
+			return err
+		}
+		wallet := &Wallet{PublicKey: solana.PublicKeyFromBytes(privateKey.Public().(ed25519.PublicKey)), PrivateKey: privateKey}
+		var data []byte
+		if *withFIDO2 {
+			credential, err := LoadFIDO2()
+			if err != nil {
+				return err
+			}
+			if credential == nil {
+				return fmt.Errorf("no security key is enrolled (run `fido2 enroll`)")
+			}
+			if data, err = EncryptKeystoreFIDO2(wallet, keyFile, credential); err != nil {
+				return err
+			}
+		} else {
+			passphrase, err := readNewPassphrase()
+			if err != nil {
+				return err
+			}
+			if data, err = EncryptKeystore(wallet, keyFile, passphrase); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(*output, data, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", *output, err)
+		}
+		fmt.Printf("🔐 Encrypted %s to %s\n", wallet.PublicKey, *output)
+		fmt.Printf("⚠️  %s still holds the key unencrypted: delete it once the keystore works\n", fs.Arg(0))
+		return nil
+
+	case "restore-from-shards":
+		fs := flag.NewFlagSet("wallet restore-from-shards", flag.ContinueOnError)
+		output := fs.String("o", "wallet.json", "key file to write")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if _, err := os.Stat(*output); err == nil {
+			return fmt.Errorf("%s already exists; choose another file with -o", *output)
+		}
+
+		var shards []WalletShard
+		for _, arg := range fs.Args() {
+			text := arg
+			if data, err := os.ReadFile(arg); err == nil {
+				text = string(data)
+			}
+			shard, err := ParseWalletShard(text)
+			if err != nil {
+				return fmt.Errorf("%s: %w", arg, err)
+			}
+			shards = append(shards, shard)
+		}
+		// Without arguments, or too few, the remaining shards are typed or pasted in
+		reader := bufio.NewReader(os.Stdin)
+		for len(shards) == 0 || len(shards) < shards[0].Threshold {
+			fmt.Printf("Shard %d: ", len(shards)+1)
+			line, err := reader.ReadString('\n')
+			if strings.TrimSpace(line) == "" && err != nil {
+				return fmt.Errorf("not enough shards given")
+			}
+			shard, parseErr := ParseWalletShard(line)
+			if parseErr != nil {
+				fmt.Printf("❌ %v\n", parseErr)
+				continue
+			}
+			shards = append(shards, shard)
+		}
+
+		wallet, err := RestoreWalletFromShards(shards)
+		if err != nil {
+			return err
+		}
+		keyBytes, _ := json.Marshal([]byte(wallet.PrivateKey))
+		if err := os.WriteFile(*output, keyBytes, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", *output, err)
+		}
+		fmt.Printf("✅ Restored wallet %s to %s\n", wallet.PublicKey, *output)
+		return nil
+
+	default:
+		return usage
+	}
+}
+
+// runFrostCommand handles `frost keygen` and `frost participant`
+func runFrostCommand(args []string) error {
+	usage := fmt.Errorf("usage: frost keygen [-threshold 2] [-shares 3] [-from key.json] [-addresses a,b,c] [-o dir] | participant -share <file> [-listen :7070]")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "keygen":
+		fs := flag.NewFlagSet("frost keygen", flag.ContinueOnError)
+		threshold := fs.Int("threshold", 2, "participants needed to sign")
+		shares := fs.Int("shares", 3, "participants to create shares for")
+		from := fs.String("from", "", "split this existing key instead of generating a fresh one")
+		addresses := fs.String("addresses", "", "comma-separated participant URLs, in share order, for the group file")
+		dir := fs.String("o", ".", "directory to write frost-group.json and frost-share-N.json to")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 0 {
+			return usage
+		}
+
+		var privateKey ed25519.PrivateKey
+		if *from != "" {
+			wallet, err := NewWallet(*from)
+			if err != nil {
+				return err
+			}
+			key, err := wallet.signingKey()
+			if err != nil {
+				return err
+			}
+			privateKey = ed25519.PrivateKey(key)
+		}
+		shareList, group, err := SplitFrostKey(privateKey, *shares, *threshold)
+		if err != nil {
+			return err
+		}
+		if *addresses != "" {
+			list := strings.Split(*addresses, ",")
+			if len(list) != len(group.Participants) {
+				return fmt.Errorf("%d addresses given for %d participants", len(list), len(group.Participants))
+			}
+			for i := range group.Participants {
+				group.Participants[i].Address = strings.TrimSpace(list[i])
+			}
+		}
+
+		if err := os.MkdirAll(*dir, 0700); err != nil {
+			return fmt.Errorf("failed to create %s: %w", *dir, err)
+		}
+		groupPath := filepath.Join(*dir, "frost-group.json")
+		data, _ := json.MarshalIndent(frostGroupFile{FrostGroup: group}, "", "  ")
+		if err := os.WriteFile(groupPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", groupPath, err)
+		}
+		fmt.Printf("🔑 Threshold key %s: any %d of %d participants sign\n", group.GroupPublicKey, *threshold, *shares)
+		fmt.Printf("   Group file (use it as the wallet): %s\n", groupPath)
+		for i := range shareList {
+			path := filepath.Join(*dir, fmt.Sprintf("frost-share-%d.json", shareList[i].Identifier))
+			data, _ := json.MarshalIndent(frostShareFile{FrostShare: &shareList[i]}, "", "  ")
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			fmt.Printf("   Share %d: %s\n", shareList[i].Identifier, path)
+		}
+		fmt.Println("⚠️  Move each share to its participant's machine and delete it here")
+		if *from != "" {
+			fmt.Printf("⚠️  %s still holds the whole key: store it offline or destroy it\n", *from)
+		}
+		return nil
+
+	case "participant":
+		fs := flag.NewFlagSet("frost participant", flag.ContinueOnError)
+		sharePath := fs.String("share", "", "this participant's frost-share-N.json")
+		listen := fs.String("listen", ":7070", "address to accept signing requests on")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *sharePath == "" || fs.NArg() != 0 {
+			return usage
+		}
+
+		share, err := LoadFrostShare(*sharePath)
+		if err != nil {
+			return err
+		}
+		server, err := newFrostParticipantServer(NewReadOnlyDApp(), share)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔏 FROST participant %d for %s listening on %s\n", share.Identifier, share.GroupPublicKey, *listen)
+		if server.token == "" {
+			fmt.Printf("⚠️  %s is not set: any host that can reach this port may request signatures\n", frostTokenEnv)
+		}
+		return http.ListenAndServe(*listen, server.Handler())
+
+	default:
+		return usage
+	}
+}
+
+// runPolicyCommand handles `policy check`
+func runPolicyCommand(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("usage: policy check [-campaign addr] [-amount SOL] [-destination wallet] [-at time]")
+	}
+	fs := flag.NewFlagSet("policy check", flag.ContinueOnError)
+	campaign := fs.String("campaign", "", "campaign to test a withdrawal from")
+	amount := fs.String("amount", "", "withdrawal amount in SOL to test")
+	destination := fs.String("destination", "", "wallet receiving the test withdrawal")
+	at := fs.String("at", "", "time of the test withdrawal (RFC 3339), default now")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	path := loadConfig().Policy
+	if path == "" {
+		path = defaultPolicyFile
+	}
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		fmt.Printf("No %s: withdrawals are not restricted.\n", path)
+		return nil
+	}
+	fmt.Printf("✅ %s is valid with %d rule(s)\n", path, len(policy.Rules))
+	if *amount == "" {
+		return nil
+	}
+
+	w := PolicyWithdrawal{Time: time.Now()}
+	if w.Lamports, err = parseSOL(*amount); err != nil {
+		return err
+	}
+	if *campaign != "" {
+		if w.Campaign, err = solana.PublicKeyFromBase58(*campaign); err != nil {
+			return fmt.Errorf("invalid campaign address: %w", err)
+		}
+	}
+	if *destination != "" {
+		if w.Destination, err = solana.PublicKeyFromBase58(*destination); err != nil {
+			return fmt.Errorf("invalid destination: %w", err)
+		}
+	}
+	if *at != "" {
+		if w.Time, err = time.Parse(time.RFC3339, *at); err != nil {
+			return fmt.Errorf("invalid -at: %w", err)
+		}
+	}
+
+	decision := policy.Check(w)
+	if len(decision.Violations) > 0 {
+		fmt.Println("🚫 This withdrawal would be blocked:")
+		for _, violation := range decision.Violations {
+			fmt.Printf("   • %s\n", violation)
+		}
+		return nil
+	}
+	if len(decision.Confirm) > 0 {
+		fmt.Printf("⚠️  Needs a second confirmation (%s)\n", strings.Join(decision.Confirm, ", "))
+	}
+	if len(decision.TOTP) > 0 {
+		fmt.Printf("🔑 Needs an authenticator code (%s)\n", strings.Join(decision.TOTP, ", "))
+	}
+	if len(decision.FIDO2) > 0 {
+		fmt.Printf("🔑 Needs a security key touch (%s)\n", strings.Join(decision.FIDO2, ", "))
+	}
+	if len(decision.Confirm) > 0 || len(decision.TOTP) > 0 || len(decision.FIDO2) > 0 {
+		return nil
+	}
+	fmt.Println("✅ This withdrawal would be allowed")
+	return nil
+}
+
+// runTOTPCommand handles `totp enroll`
+func runTOTPCommand(args []string) error {
+	if len(args) == 0 || args[0] != "enroll" {
+		return fmt.Errorf("usage: totp enroll [-force] [-account name] [-qr file.png]")
+	}
+	host, _ := os.Hostname()
+	fs := flag.NewFlagSet("totp enroll", flag.ContinueOnError)
+	force := fs.Bool("force", false, "replace the enrolled authenticator")
+	account := fs.String("account", host, "account name shown in the authenticator app")
+	qrPath := fs.String("qr", "", "also save the QR code as a PNG")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if existing, err := LoadTOTP(); err != nil {
+		return err
+	} else if existing != nil && !*force {
+		return fmt.Errorf("an authenticator is already enrolled since %s; use -force to replace it", formatDate(existing.Enrolled))
+	}
+	enrollment, err := NewTOTPEnrollment(*account)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("📱 Scan this QR code with an authenticator app (Google Authenticator, Aegis, 1Password...):")
+	if err := PrintQRCode(enrollment.URI(), *qrPath); err != nil {
+		return err
+	}
+	fmt.Printf("   Or enter the secret by hand: %s\n", enrollment.Secret)
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Enter the code the app shows to finish enrolling: ")
+		code, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("enrollment cancelled")
+		}
+		if enrollment.Verify(code, time.Now()) {
+			break
+		}
+		fmt.Println("❌ Wrong code, check the app and try again")
+	}
+	if err := enrollment.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Authenticator enrolled in %s. Add `totp: true` to policy rules to require its codes.\n", totpFile)
+	return nil
+}
+
+// runFIDO2Command handles `fido2 enroll`
+func runFIDO2Command(args []string) error {
+	if len(args) == 0 || args[0] != "enroll" {
+		return fmt.Errorf("usage: fido2 enroll [-force]")
+	}
+	fs := flag.NewFlagSet("fido2 enroll", flag.ContinueOnError)
+	force := fs.Bool("force", false, "replace the enrolled security key")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if existing, err := LoadFIDO2(); err != nil {
+		return err
+	} else if existing != nil && !*force {
+		return fmt.Errorf("a security key is already enrolled since %s; use -force to replace it", formatDate(existing.Enrolled))
+	}
+	device, err := fido2Device()
+	if err != nil {
+		return err
+	}
+	credential, err := EnrollFIDO2(device)
+	if err != nil {
+		return err
+	}
+	// Check the new credential works before relying on it
+	if _, err := credential.Assert([]byte(fido2RelyingParty), nil); err != nil {
+		return err
+	}
+	if err := credential.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Security key enrolled in %s. Add `fido2: true` to policy rules to require a touch, or encrypt the wallet with `wallet encrypt -fido2`.\n", fido2File)
+	return nil
+}
+
+// runAuditCommand handles `audit list` and `audit verify`
+func runAuditCommand(args []string) error {
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("audit "+action, flag.ContinueOnError)
+	file := fs.String("file", auditFile, "audit log to read")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "list":
+		entries, err := readAuditLog(*file)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("No signed transactions recorded yet.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEQ\tTIME\tEVENT\tDETAILS\tTRANSACTION")
+		for _, entry := range entries {
+			fmt.Fprintln(w, entry.describe())
+		}
+		return w.Flush()
+
+	case "verify":
+		count, head, err := VerifyAuditLog(*file)
+		if err != nil {
+			return fmt.Errorf("audit log verification failed after %d good entries: %w", count, err)
+		}
+		if count == 0 {
+			fmt.Println("No signed transactions recorded yet.")
+			return nil
+		}
+		fmt.Printf("✅ %d entries verified: the chain is intact and every signature matches its transaction\n", count)
+		fmt.Printf("🔗 Head: %s\n", head)
+		return nil
+
+	default:
+		return fmt.Errorf("usage: audit [list|verify] [-file audit.jsonl]")
+	}
+}
+
+// runBackupCommand handles `backup create` and `backup restore`
+func runBackupCommand(args []string) error {
+	usage := fmt.Errorf("usage: backup create [-to s3://bucket/path] [-include-keys] | restore [-from s3://bucket/path] [-identity key.txt] [-force]")
+	if len(args) == 0 {
+		return usage
+	}
+
+	config := loadConfig()
+	backup := config.Backup
+	if backup == nil {
+		backup = &BackupConfig{}
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("backup create", flag.ContinueOnError)
+		to := fs.String("to", backup.To, "s3://bucket/path or a directory (default from config.json)")
+		includeKeys := fs.Bool("include-keys", backup.IncludeKeys, "also back up wallet.json and the fee payer key")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *to == "" || fs.NArg() != 0 {
+			return usage
+		}
+
+		name, files, err := CreateBackup(config, backup, *to, *includeKeys)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔐 Encrypted %s\n", strings.Join(files, ", "))
+		fmt.Printf("☁️  Uploaded %s/%s\n", strings.TrimSuffix(*to, "/"), name)
+		return nil
+
+	case "restore":
+		fs := flag.NewFlagSet("backup restore", flag.ContinueOnError)
+		from := fs.String("from", backup.To, "a backup, or a location whose latest backup is restored (default from config.json)")
+		identity := fs.String("identity", "", "age identity file (default: the passphrase in "+backupPassphraseEnv+")")
+		force := fs.Bool("force", false, "replace existing files")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *from == "" || fs.NArg() != 0 {
+			return usage
+		}
+
+		files, err := RestoreBackup(backup, *from, *identity, *force)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Restored %s\n", strings.Join(files, ", "))
+		return nil
+
+	default:
+		return usage
+	}
+}
+
+// runDigestCommand handles `digest [-send]`
+func runDigestCommand(args []string) error {
+	fs := flag.NewFlagSet("digest", flag.ContinueOnError)
+	send := fs.Bool("send", false, "email the digest using the digest settings in config.json")
+	hours := fs.Int("hours", 24, "period covered by the digest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *hours <= 0 {
+		return fmt.Errorf("usage: digest [-send] [-hours 24]")
+	}
+
+	app := NewReadOnlyDApp()
+	if *send {
+		if app.config.Digest == nil {
+			return fmt.Errorf("no digest settings in %s", configFile)
+		}
+		if err := app.config.Digest.prepare(); err != nil {
+			return err
+		}
+	}
+	campaigns, err := app.trackedCampaigns()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, campaign := range campaigns {
+		digest, err := app.CampaignDigest(context.Background(), campaign, now.Add(-time.Duration(*hours)*time.Hour), now)
+		if err != nil {
+			return err
+		}
+		if *send {
+			if err := app.config.Digest.Send(digest); err != nil {
+				return err
+			}
+			fmt.Printf("📧 Digest for '%s' sent to %s\n", digest.Name, strings.Join(app.config.Digest.To, ", "))
+			continue
+		}
+		text, err := digest.Text()
+		if err != nil {
+			return err
+		}
+		fmt.Println(text)
+	}
+	return nil
+}
+
+// runCalendarCommand handles `calendar`
+func runCalendarCommand(args []string) error {
+	fs := flag.NewFlagSet("calendar", flag.ContinueOnError)
+	output := fs.String("o", "schedules.ics", "output file, or - for stdout")
+	days := fs.Int("days", 90, "how many days of upcoming (and past) runs to include")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *days <= 0 {
+		return fmt.Errorf("usage: calendar [-o schedules.ics] [-days 90]")
+	}
+
+	store, err := OpenStore()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	events := ScheduleCalendar(store, now, time.Duration(*days)*24*time.Hour)
+
+	if *output == "-" {
+		return WriteICS(os.Stdout, "Crowdfunding schedules", events, now)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create calendar file: %w", err)
+	}
+	if err := WriteICS(file, "Crowdfunding schedules", events, now); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write calendar file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write calendar file: %w", err)
+	}
+	fmt.Printf("📅 %d event(s) written to %s\n", len(events), *output)
+	return nil
+}
+
+// runRetryQueueCommand handles `retry-queue [list|drop <id>]`
+func runRetryQueueCommand(args []string) error {
+	usage := fmt.Errorf("usage: retry-queue [list|drop <id>]")
+
+	store, err := OpenStore()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 || args[0] == "list" {
+		if len(args) > 1 {
+			return usage
+		}
+		queue := store.QueuedTransactions()
+		if len(queue) == 0 {
+			fmt.Println("The retry queue is empty.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTATUS\tOPERATION\tATTEMPTS\tNEXT ATTEMPT\tLAST ERROR")
+		for _, queued := range queue {
+			next := "-"
+			if queued.Status == RetryPending {
+				next = formatTime(queued.NextAttempt)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", queued.ID, queued.Status, queued.Describe(), queued.Attempts, next, queued.LastError)
+		}
+		w.Flush()
+		return nil
+	}
+
+	if args[0] != "drop" || len(args) != 2 {
+		return usage
+	}
+	if err := store.RemoveQueuedTransaction(args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("🗑️  Queued transaction %s dropped\n", args[1])
+	return nil
+}
+
+// runDonateCommand handles `donate [-from key.json] [-all] <campaign> [SOL]`
+func runDonateCommand(args []string) error {
+	fs := flag.NewFlagSet("donate", flag.ContinueOnError)
+	walletPath := fs.String("wallet", "", "the app's wallet")
+	from := fs.String("from", "", "sign this donation with another keypair instead of the app's wallet")
+	memo := fs.String("memo", "", "message posted to the campaign's message board")
+	all := fs.Bool("all", false, "donate the wallet's whole balance, less the fee and the rent-exempt minimum it keeps")
+	applySendFlags := addSendFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	wantArgs := 2
+	if *all {
+		wantArgs = 1
+	}
+	if fs.NArg() != wantArgs || (*walletPath == "" && *from == "") {
+		return fmt.Errorf("usage: donate [-wallet key.json] [-from key.json] [-memo text] <campaign-address> <SOL> | -all <campaign-address>")
+	}
+
+	campaignAddress, err := solana.PublicKeyFromBase58(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid campaign address: %w", err)
+	}
+	var amount uint64
+	if !*all {
+		if amount, err = parseSOL(fs.Arg(1)); err != nil {
+			return err
+		}
+	}
+	if err := validateComment(*memo); err != nil {
+		return err
+	}
+
+	app := NewReadOnlyDApp()
+	if err := applySendFlags(app.config); err != nil {
+		return err
+	}
+	if *walletPath != "" {
+		if app.wallet, err = NewWallet(*walletPath); err != nil {
+			return fmt.Errorf("failed to create wallet: %w", err)
+		}
+	}
+	if app.config.FeePayer != "" {
+		if app.feePayer, err = NewWallet(app.config.FeePayer); err != nil {
+			return fmt.Errorf("failed to load fee payer: %w", err)
+		}
+	}
+	campaign, err := app.FetchCampaign(campaignAddress)
+	if err != nil {
+		return err
+	}
+
+	if !*all {
+		if *from != "" {
+			return app.DonateFrom(*from, campaign.Name, campaignAddress.String(), amount, *memo)
+		}
+		return app.DonateToCampaign(campaign.Name, campaignAddress.String(), amount, *memo)
+	}
+
+	// The whole balance is that of the wallet signing, so it is loaded before the amount is worked out
+	if *from != "" {
+		if app.wallet, err = NewWallet(*from); err != nil {
+			return fmt.Errorf("failed to load donor wallet: %w", err)
+		}
+		fmt.Printf("💳 Donating from %s\n", app.wallet.Address())
+	}
+	donation, err := app.DonateAllAmount(context.Background(), campaignAddress, campaign.Name, *memo)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("💰 Donating %s SOL of the wallet's %s SOL, keeping %s SOL for rent", lamportsToSOL(donation.Amount), lamportsToSOL(donation.Balance), lamportsToSOL(donation.Reserve))
+	if donation.Fee > 0 {
+		fmt.Printf(" and %s SOL for the fee", lamportsToSOL(donation.Fee))
+	}
+	fmt.Println()
+	return app.DonateToCampaign(campaign.Name, campaignAddress.String(), donation.Amount, *memo)
+}
+
+// runWithdrawCommand handles `withdraw -wallet key.json [-all] [-dry-run] <campaign> [SOL]`
+func runWithdrawCommand(args []string) error {
+	fs := flag.NewFlagSet("withdraw", flag.ContinueOnError)
+	walletPath := fs.String("wallet", "", "the campaign admin's wallet")
+	all := fs.Bool("all", false, "withdraw everything above the campaign's rent-exempt minimum")
+	dryRun := fs.Bool("dry-run", false, "simulate the withdrawal and show the balances it would leave, without sending it")
+	applySendFlags := addSendFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	wantArgs := 2
+	if *all {
+		wantArgs = 1
+	}
+	if fs.NArg() != wantArgs || *walletPath == "" {
+		return fmt.Errorf("usage: withdraw -wallet key.json [-dry-run] <campaign-address> <SOL> | -all <campaign-address>")
+	}
+
+	campaignAddress, err := solana.PublicKeyFromBase58(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid campaign address: %w", err)
+	}
+	var amount uint64
+	if !*all {
+		if amount, err = parseSOL(fs.Arg(1)); err != nil {
+			return err
+		}
+	}
+
+	app := NewReadOnlyDApp()
+	if err := applySendFlags(app.config); err != nil {
+		return err
+	}
+	if app.wallet, err = NewWallet(*walletPath); err != nil {
+		return fmt.Errorf("failed to create wallet: %w", err)
+	}
+	if app.config.FeePayer != "" {
+		if app.feePayer, err = NewWallet(app.config.FeePayer); err != nil {
+			return fmt.Errorf("failed to load fee payer: %w", err)
+		}
+	}
+	campaign, err := app.FetchCampaign(campaignAddress)
+	if err != nil {
+		return err
+	}
+	if !campaign.Admin.Equals(app.wallet.Address()) {
+		return fmt.Errorf("%w: %s is not the admin of '%s'", crowdfund.ErrUnauthorizedAdmin, app.wallet.Address(), campaign.Name)
+	}
+
+	if *all {
+		funds, err := app.CampaignFunds(context.Background(), campaignAddress)
+		if err != nil {
+			return err
+		}
+		if funds.Withdrawable == 0 {
+			return fmt.Errorf("%w: '%s' holds only its rent-exempt minimum of %s SOL", crowdfund.ErrInsufficientCampaignFunds, campaign.Name, lamportsToSOL(funds.Rent))
+		}
+		amount = funds.Withdrawable
+		if !*dryRun {
+			fmt.Printf("💰 Withdrawing %s SOL of the campaign's %s SOL, leaving its rent-exempt minimum of %s SOL\n", lamportsToSOL(amount), lamportsToSOL(funds.Balance), lamportsToSOL(funds.Rent))
+		}
+	}
+	if *dryRun {
+		preview, err := app.PreviewWithdrawal(context.Background(), campaignAddress, campaign.Name, amount)
+		if err != nil {
+			return err
+		}
+		printWithdrawalPreview(campaign.Name, preview)
+		return nil
+	}
+	return app.WithdrawFromCampaign(campaign.Name, campaignAddress.String(), amount)
+}
+
+// printWithdrawalPreview shows what a withdrawal would do
+func printWithdrawalPreview(campaignName string, p *WithdrawalPreview) {
+	fmt.Printf("🧪 Dry run: withdrawing %s SOL from '%s'; nothing was signed or sent\n\n", lamportsToSOL(p.Amount), campaignName)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if p.SimulationErr != nil {
+		fmt.Fprintf(w, "Simulation:\t❌ fails: %v\n", p.SimulationErr)
+	} else if p.UnitsConsumed > 0 {
+		fmt.Fprintf(w, "Simulation:\t✅ succeeds, %d compute units\n", p.UnitsConsumed)
+	} else {
+		fmt.Fprintf(w, "Simulation:\t✅ succeeds\n")
+	}
+	if p.BelowRent {
+		fmt.Fprintf(w, "Campaign balance:\t%s SOL, unchanged\n", lamportsToSOL(p.Funds.Balance))
+	} else {
+		fmt.Fprintf(w, "Campaign balance:\t%s SOL → %s SOL\n", lamportsToSOL(p.Funds.Balance), lamportsToSOL(p.CampaignAfter))
+	}
+	fmt.Fprintf(w, "Admin wallet:\t%s SOL → %s SOL\n", lamportsToSOL(p.AdminBefore), lamportsToSOL(p.AdminAfter))
+	fmt.Fprintf(w, "Fee:\t%s SOL, paid by %s\n", lamportsToSOL(p.Fee), p.FeePayer)
+	if p.BelowRent {
+		fmt.Fprintf(w, "Rent exemption:\t⚠️  violated: only %s SOL is above the %s SOL minimum, so the program refuses\n", lamportsToSOL(p.Funds.Withdrawable), lamportsToSOL(p.Funds.Rent))
+	} else {
+		fmt.Fprintf(w, "Rent exemption:\t✅ kept, %s SOL above the %s SOL minimum\n", lamportsToSOL(p.CampaignAfter-p.Funds.Rent), lamportsToSOL(p.Funds.Rent))
+	}
+	switch {
+	case p.PolicyErr != nil:
+		fmt.Fprintf(w, "Policy:\t🚫 every withdrawal is blocked: %v\n", p.PolicyErr)
+	case p.Policy == nil:
+		fmt.Fprintf(w, "Policy:\tnone, withdrawals are not restricted\n")
+	case len(p.Policy.Violations) > 0:
+		fmt.Fprintf(w, "Policy:\t🚫 blocked: %s\n", strings.Join(p.Policy.Violations, "; "))
+	default:
+		var asks []string
+		for _, ask := range []struct {
+			rules []string
+			what  string
+		}{{p.Policy.Confirm, "a second confirmation"}, {p.Policy.TOTP, "an authenticator code"}, {p.Policy.FIDO2, "a security key touch"}} {
+			if len(ask.rules) > 0 {
+				asks = append(asks, fmt.Sprintf("%s (%s)", ask.what, strings.Join(ask.rules, ", ")))
+			}
+		}
+		if len(asks) > 0 {
+			fmt.Fprintf(w, "Policy:\t✅ allowed after %s\n", strings.Join(asks, " and "))
+		} else {
+			fmt.Fprintf(w, "Policy:\t✅ allowed\n")
+		}
+	}
+	w.Flush()
+}
+
+// runTxCommand handles `tx build|sign|add-signature|export|import|send`
+func runTxCommand(args []string) error {
+	usage := fmt.Errorf("usage: tx build [-o tx.json] [-fee-payer pubkey] [-nonce account] <donate|withdraw> <signer> <campaign> <SOL> | sign -wallet <key.json> [-o sig.json] <tx.json> | add-signature <tx.json> <sig.json>... | export [-encoding base64|base58] <tx.json> | import [-send] <tx.json> <signed-tx|PUBKEY=SIGNATURE...|-> | send <tx.json>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "build":
+		fs := flag.NewFlagSet("tx build", flag.ContinueOnError)
+		output := fs.String("o", "tx.json", "transaction file to write")
+		feePayerFlag := fs.String("fee-payer", "", "wallet paying the fees, default the signer")
+		nonceFlag := fs.String("nonce", "", "durable nonce account controlled by the signer, so the transaction doesn't expire while signatures are collected")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 4 || (fs.Arg(0) != "donate" && fs.Arg(0) != "withdraw") {
+			return usage
+		}
+		signer, err := solana.PublicKeyFromBase58(fs.Arg(1))
+		if err != nil {
+			return fmt.Errorf("invalid signer: %w", err)
+		}
+		campaign, err := solana.PublicKeyFromBase58(fs.Arg(2))
+		if err != nil {
+			return fmt.Errorf("invalid campaign address: %w", err)
+		}
+		amount, err := parseSOL(fs.Arg(3))
+		if err != nil {
+			return err
+		}
+		feePayer := signer
+		if *feePayerFlag != "" {
+			if feePayer, err = solana.PublicKeyFromBase58(*feePayerFlag); err != nil {
+				return fmt.Errorf("invalid fee payer: %w", err)
+			}
+		}
+		var nonceAccount *solana.PublicKey
+		if *nonceFlag != "" {
+			account, err := solana.PublicKeyFromBase58(*nonceFlag)
+			if err != nil {
+				return fmt.Errorf("invalid nonce account: %w", err)
+			}
+			nonceAccount = &account
+		}
+
+		file, tx, err := NewReadOnlyDApp().BuildTxFile(context.Background(), fs.Arg(0), campaign, amount, signer, feePayer, nonceAccount)
+		if err != nil {
+			return err
+		}
+		if err := file.Save(*output, tx); err != nil {
+			return err
+		}
+		fmt.Printf("📝 Wrote %s, which needs signatures from:\n", *output)
+		for _, key := range missingSigners(tx) {
+			fmt.Printf("   %s\n", key)
+		}
+		if nonceAccount == nil {
+			fmt.Println("⏳ Its blockhash expires in about a minute; use -nonce to give signers more time")
+		}
+		return nil
+
+	case "sign":
+		fs := flag.NewFlagSet("tx sign", flag.ContinueOnError)
+		walletPath := fs.String("wallet", "", "key to sign with")
+		output := fs.String("o", "", "signature file to write, default <signer>.sig.json")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 || *walletPath == "" {
+			return usage
+		}
+		_, tx, err := LoadTxFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		app := NewReadOnlyDApp()
+		if app.wallet, err = NewWallet(*walletPath); err != nil {
+			return fmt.Errorf("failed to create wallet: %w", err)
+		}
+		sig, err := app.SignTxFile(tx)
+		if err != nil {
+			return err
+		}
+		if *output == "" {
+			*output = sig.Signer.String() + ".sig.json"
+		}
+		data, _ := json.MarshalIndent(sig, "", "  ")
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", *output, err)
+		}
+		fmt.Printf("✍️  Signed as %s; send %s back to be merged with `tx add-signature`\n", sig.Signer, *output)
+		return nil
+
+	case "add-signature":
+		if len(args) < 3 {
+			return usage
+		}
+		file, tx, err := LoadTxFile(args[1])
+		if err != nil {
+			return err
+		}
+		for _, path := range args[2:] {
+			sig, err := LoadSignatureFile(path)
+			if err != nil {
+				return err
+			}
+			if err := addSignature(tx, *sig); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			fmt.Printf("✅ Added the signature of %s\n", sig.Signer)
+		}
+		if err := file.Save(args[1], tx); err != nil {
+			return err
+		}
+		if missing := missingSigners(tx); len(missing) > 0 {
+			fmt.Printf("⏳ Still waiting for %d signature(s):\n", len(missing))
+			for _, key := range missing {
+				fmt.Printf("   %s\n", key)
+			}
+		} else {
+			fmt.Printf("🚀 Fully signed: broadcast it with `tx send %s`\n", args[1])
+		}
+		return nil
+
+	case "export":
+		fs := flag.NewFlagSet("tx export", flag.ContinueOnError)
+		encoding := fs.String("encoding", "base64", "base64, or base58 for wallets that want it")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return usage
+		}
+		_, tx, err := LoadTxFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		encoded, err := encodeTransaction(tx, *encoding)
+		if err != nil {
+			return err
+		}
+		// Only the transaction goes to stdout, so it can be piped into other tools
+		fmt.Println(encoded)
+		if missing := missingSigners(tx); len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "⏳ Needs signatures from %v; import the signed transaction with `tx import %s`\n", missing, fs.Arg(0))
+		}
+		return nil
+
+	case "import":
+		fs := flag.NewFlagSet("tx import", flag.ContinueOnError)
+		send := fs.Bool("send", false, "broadcast the transaction once it is fully signed")
+		applySendFlags := addSendFlags(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() < 2 {
+			return usage
+		}
+		file, tx, err := LoadTxFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		inputs := fs.Args()[1:]
+		if len(inputs) == 1 && inputs[0] == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read stdin: %w", err)
+			}
+			inputs = strings.Fields(string(data))
+		}
+		var added []solana.PublicKey
+		for _, input := range inputs {
+			// solana-cli --sign-only prints PUBKEY=SIGNATURE pairs, wallets the signed transaction
+			if pair, ok := parseSignerPair(input); ok {
+				if err := addSignature(tx, *pair); err != nil {
+					return err
+				}
+				added = append(added, pair.Signer)
+				continue
+			}
+			if len(input) < 100 {
+				continue // The rest of pasted solana-cli output, such as labels and absent signers
+			}
+			signed, err := decodeTransaction(input)
+			if err != nil {
+				return err
+			}
+			signers, err := importSignatures(tx, signed)
+			if err != nil {
+				return err
+			}
+			added = append(added, signers...)
+		}
+		if len(added) == 0 {
+			return fmt.Errorf("no new signatures found")
+		}
+		for _, signer := range added {
+			fmt.Printf("✅ Imported the signature of %s\n", signer)
+		}
+		if err := file.Save(fs.Arg(0), tx); err != nil {
+			return err
+		}
+
+		if missing := missingSigners(tx); len(missing) > 0 {
+			fmt.Printf("⏳ Still waiting for %d signature(s): %v\n", len(missing), missing)
+			return nil
+		}
+		if !*send {
+			fmt.Printf("🚀 Fully signed: broadcast it with `tx send %s`\n", fs.Arg(0))
+			return nil
+		}
+		app := NewReadOnlyDApp()
+		if err := applySendFlags(app.config); err != nil {
+			return err
+		}
+		if _, err := app.SendTxFile(context.Background(), file, tx); err != nil {
+			return err
+		}
+		fmt.Println("✅ Transaction confirmed!")
+		return nil
+
+	case "send":
+		fs := flag.NewFlagSet("tx send", flag.ContinueOnError)
+		applySendFlags := addSendFlags(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return usage
+		}
+		file, tx, err := LoadTxFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		app := NewReadOnlyDApp()
+		if err := applySendFlags(app.config); err != nil {
+			return err
+		}
+		if _, err := app.SendTxFile(context.Background(), file, tx); err != nil {
+			return err
+		}
+		fmt.Println("✅ Transaction confirmed!")
+		return nil
+	}
+	return usage
+}
+
+// runBatchCommand handles `batch -wallet <key.json> [-nonces N] <items.json>`
+func runBatchCommand(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	walletPath := fs.String("wallet", "", "wallet that signs and pays for every transaction")
+	nonces := fs.Int("nonces", 4, "durable nonce accounts to use, which is also the number of transactions in flight")
+	applySendFlags := addSendFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *walletPath == "" {
+		return fmt.Errorf("usage: batch -wallet <key.json> [-nonces N] <items.json>")
+	}
+	if *nonces <= 0 {
+		return fmt.Errorf("-nonces must be positive")
+	}
+
+	app, err := NewSolanaDApp(*walletPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize dApp: %w", err)
+	}
+	defer app.wsClient.Close()
+	if err := applySendFlags(app.config); err != nil {
+		return err
+	}
+
+	run, err := app.loadBatch(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	accounts, err := app.ensureNonceAccounts(ctx, *nonces)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📦 Sending %d item(s) over %d nonce account(s), progress in %s\n", len(run.items), len(accounts), run.path)
+	if err := run.Run(ctx, accounts); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	counts := run.Summary()
+	fmt.Printf("📊 %d confirmed, %d failed, %d in flight, %d pending\n", counts[BatchConfirmed], counts[BatchFailed], counts[BatchSent], counts[BatchPending])
+	if counts[BatchSent]+counts[BatchPending] > 0 {
+		return fmt.Errorf("batch incomplete: rerun the same command to resume")
+	}
+	if counts[BatchFailed] > 0 {
+		return fmt.Errorf("%d item(s) failed, see %s", counts[BatchFailed], run.path)
+	}
+	return nil
+}
+
+// runCreateBatchCommand handles `create-batch -wallet <key.json> [-o results.csv] <campaigns.csv>`
+func runCreateBatchCommand(args []string) error {
+	fs := flag.NewFlagSet("create-batch", flag.ContinueOnError)
+	walletPath := fs.String("wallet", "", "wallet that becomes the admin of every campaign and pays their rent")
+	output := fs.String("o", "", "results file (default <campaigns.csv>.results.csv)")
+	applySendFlags := addSendFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *walletPath == "" {
+		return fmt.Errorf("usage: create-batch -wallet <key.json> [-o results.csv] <campaigns.csv>")
+	}
+	if *output == "" {
+		*output = fs.Arg(0) + ".results.csv"
+	}
+
+	app, err := NewSolanaDApp(*walletPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize dApp: %w", err)
+	}
+	defer app.wsClient.Close()
+	if err := applySendFlags(app.config); err != nil {
+		return err
+	}
+
+	rows, err := app.loadCampaignRows(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	loadCreateResults(*output, rows)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.CreateCampaigns(ctx, rows, *output); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.Status]++
+	}
+	fmt.Printf("📊 %d created, %d already existed, %d failed, %d not attempted\n", counts[CreateRowCreated], counts[CreateRowExists], counts[CreateRowFailed], counts[""])
+	if counts[""] > 0 {
+		return fmt.Errorf("interrupted: rerun the same command to create the rest")
+	}
+	if counts[CreateRowFailed] > 0 {
+		return fmt.Errorf("%d campaign(s) failed, see %s", counts[CreateRowFailed], *output)
+	}
+	return nil
+}
+
+// runDonateBatchCommand handles `donate-batch -wallet <key.json> [-per-tx N] [-o report.csv] <payouts.csv>`
+func runDonateBatchCommand(args []string) error {
+	fs := flag.NewFlagSet("donate-batch", flag.ContinueOnError)
+	walletPath := fs.String("wallet", "", "wallet that makes every donation")
+	perTx := fs.Int("per-tx", 10, "most donations per transaction; fewer when they do not fit")
+	output := fs.String("o", "", "report file (default <payouts.csv>.report.csv)")
+	applySendFlags := addSendFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *walletPath == "" {
+		return fmt.Errorf("usage: donate-batch -wallet <key.json> [-per-tx N] [-o report.csv] <payouts.csv>")
+	}
+	if *perTx <= 0 {
+		return fmt.Errorf("-per-tx must be positive")
+	}
+	if *output == "" {
+		*output = fs.Arg(0) + ".report.csv"
+	}
+
+	app, err := NewSolanaDApp(*walletPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize dApp: %w", err)
+	}
+	defer app.wsClient.Close()
+	if err := applySendFlags(app.config); err != nil {
+		return err
+	}
+
+	rows, err := app.loadPayoutRows(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := loadPayoutReport(*output, rows); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.DonatePayouts(ctx, rows, *perTx, *output); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	counts := map[string]int{}
+	var donated uint64
+	for _, row := range rows {
+		counts[row.Status]++
+		if row.Status == PayoutConfirmed {
+			donated += row.Amount
+		}
+	}
+	fmt.Printf("📊 %d confirmed (%s SOL), %d failed, %d pending\n", counts[PayoutConfirmed], lamportsToSOL(donated), counts[PayoutFailed], counts[PayoutPending])
+	if counts[PayoutPending] > 0 {
+		return fmt.Errorf("interrupted: rerun the same command to send the rest")
+	}
+	if counts[PayoutFailed] > 0 {
+		return fmt.Errorf("%d donation(s) failed, see %s; rerun the same command to retry them", counts[PayoutFailed], *output)
+	}
+	return nil
+}
+
+// runRegistryCommand handles `registry show` and `registry sign`
+func runRegistryCommand(args []string) error {
+	usage := fmt.Errorf("usage: registry show | registry sign -wallet <curator.json> [-name text] [-o registry.json] <campaigns.json>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "show":
+		app := NewReadOnlyDApp()
+		registry, err := app.Registry()
+		if err != nil {
+			return err
+		}
+		if registry == nil {
+			return fmt.Errorf("no registry configured: set registry.url and registry.signer in %s", configFile)
+		}
+		fmt.Printf("✅ Registry %q signed by %s, updated %s\n", registry.Name, app.config.Registry.Signer, formatTime(registry.Updated))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ADDRESS\tNAME\tORGANIZATION\tVERIFIED AT")
+		for _, entry := range registry.Campaigns {
+			verifiedAt := ""
+			if !entry.VerifiedAt.IsZero() {
+				verifiedAt = formatDate(entry.VerifiedAt)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Address, entry.Name, entry.Organization, verifiedAt)
+		}
+		return w.Flush()
+
+	case "sign":
+		fs := flag.NewFlagSet("registry sign", flag.ContinueOnError)
+		walletPath := fs.String("wallet", "", "the curator's key")
+		name := fs.String("name", "", "registry name shown to donors")
+		output := fs.String("o", "registry.json", "signed registry to publish")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 || *walletPath == "" {
+			return usage
+		}
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("failed to read campaigns: %w", err)
+		}
+		registry := &Registry{Name: *name, Updated: time.Now().UTC()}
+		if err := json.Unmarshal(data, &registry.Campaigns); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", fs.Arg(0), err)
+		}
+		curator, err := NewWallet(*walletPath)
+		if err != nil {
+			return fmt.Errorf("failed to create wallet: %w", err)
+		}
+		key, err := curator.signingKey()
+		if err != nil {
+			return err
+		}
+		signed, err := SignRegistry(registry, key)
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(signed, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*output, out, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", *output, err)
+		}
+		fmt.Printf("✍️  Signed %d campaign(s) as %s into %s; publish it at the registry URL\n", len(registry.Campaigns), curator.PublicKey, *output)
+		return nil
+	}
+	return usage
+}
+
+// runCommentsCommand handles `comments [campaign]` and `comments mute|unmute <campaign> <donor>`
+func runCommentsCommand(args []string) error {
+	usage := fmt.Errorf("usage: comments [campaign] | comments mute|unmute <campaign> <donor>")
+
+	if len(args) > 0 && (args[0] == "mute" || args[0] == "unmute") {
+		if len(args) != 3 {
+			return usage
+		}
+		campaign, err := solana.PublicKeyFromBase58(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid campaign address: %w", err)
+		}
+		donor, err := solana.PublicKeyFromBase58(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid donor address: %w", err)
+		}
+		store, err := OpenStore()
+		if err != nil {
+			return err
+		}
+		if err := store.SetMuted(campaign.String(), donor.String(), args[0] == "mute"); err != nil {
+			return err
+		}
+		if args[0] == "mute" {
+			fmt.Printf("🔇 Comments from %s are hidden on %s\n", donor, campaign)
+		} else {
+			fmt.Printf("🔊 Comments from %s are shown again on %s\n", donor, campaign)
+		}
+		return nil
+	}
+	if len(args) > 1 {
+		return usage
+	}
+
+	app := NewReadOnlyDApp()
+	var campaign solana.PublicKey
+	if len(args) == 1 {
+		var err error
+		if campaign, err = solana.PublicKeyFromBase58(args[0]); err != nil {
+			return fmt.Errorf("invalid campaign address: %w", err)
+		}
+	} else {
+		app.loadSavedCampaign()
+		if app.campaignAddress == nil {
+			return fmt.Errorf("no saved campaign: pass a campaign address")
+		}
+		campaign = *app.campaignAddress
+	}
+
+	comments, err := app.GetCampaignComments(campaign, 100)
+	if err != nil {
+		return err
+	}
+	if len(comments) == 0 {
+		fmt.Println("No messages yet. Donors can attach one when donating.")
+		return nil
+	}
+	for _, comment := range comments {
+		when := fmt.Sprintf("slot %d", comment.Slot)
+		if comment.BlockTime != nil {
+			when = formatTime(*comment.BlockTime)
+		}
+		fmt.Printf("💬 %s  %s SOL from %s\n   %s\n", when, lamportsToSOL(comment.Amount), comment.Donor, comment.Message)
+	}
+	return nil
+}
+
+// runReportCommand handles `report compliance ...`, `report tax ...` and `report verify <dir>`
+func runReportCommand(args []string) error {
+	usage := fmt.Errorf("usage: report compliance -period <2024Q4|2024-11|2024> -wallet <key.json> [-campaigns a,b] [-o dir] | report tax -wallet <pubkey> -year <2024> [-o dir] | report verify <dir>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "compliance":
+		fs := flag.NewFlagSet("report compliance", flag.ContinueOnError)
+		periodFlag := fs.String("period", "", "year (2024), quarter (2024Q4) or month (2024-11) to report on")
+		walletPath := fs.String("wallet", "", "key that signs the report")
+		campaignsFlag := fs.String("campaigns", "", "comma-separated campaign addresses, default every campaign in "+lifecycleFile)
+		output := fs.String("o", "", "directory to write the report to, default compliance-<period>")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 0 || *periodFlag == "" || *walletPath == "" {
+			return usage
+		}
+		period, err := parsePeriod(*periodFlag)
+		if err != nil {
+			return err
+		}
+		if period.From.After(time.Now()) {
+			return fmt.Errorf("period %s has not started yet", period.Name)
+		}
+		if *output == "" {
+			*output = "compliance-" + period.Name
+		}
+		campaigns, err := reportCampaigns(*campaignsFlag)
+		if err != nil {
+			return err
+		}
+		signer, err := NewWallet(*walletPath)
+		if err != nil {
+			return fmt.Errorf("failed to create wallet: %w", err)
+		}
+
+		report, err := NewReadOnlyDApp().BuildComplianceReport(context.Background(), period, campaigns)
+		if err != nil {
+			return err
+		}
+		if err := report.Write(*output, signer); err != nil {
+			return err
+		}
+		if period.To.After(time.Now()) {
+			fmt.Printf("⚠️  Period %s is not over yet; the report only covers it up to now\n", period.Name)
+		}
+		fmt.Printf("📑 Compliance report for %s: %d campaign(s), %d movement(s), %d counterparties, %d exception(s)\n", period, len(campaigns), len(report.Movements), len(report.Counterparties), len(report.Exceptions))
+		fmt.Printf("✍️  Signed by %s; written to %s\n", signer.Address(), *output)
+		return nil
+
+	case "tax":
+		fs := flag.NewFlagSet("report tax", flag.ContinueOnError)
+		walletFlag := fs.String("wallet", "", "donor wallet address")
+		year := fs.Int("year", 0, "tax year")
+		output := fs.String("o", "", "directory to write the summary to, default tax-<year>-<wallet>")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 0 || *walletFlag == "" || *year == 0 {
+			return usage
+		}
+		wallet, err := solana.PublicKeyFromBase58(*walletFlag)
+		if err != nil {
+			return fmt.Errorf("invalid wallet address: %w", err)
+		}
+		period, err := parsePeriod(strconv.Itoa(*year))
+		if err != nil {
+			return err
+		}
+		if *output == "" {
+			*output = fmt.Sprintf("tax-%d-%s", *year, wallet.String()[:8])
+		}
+
+		summary, err := NewReadOnlyDApp().BuildTaxSummary(context.Background(), wallet, period)
+		if err != nil {
+			return err
+		}
+		if err := summary.Write(*output); err != nil {
+			return err
+		}
+		lamports, value, _ := summary.Totals()
+		if period.To.After(time.Now()) {
+			fmt.Printf("⚠️  %d is not over yet; the summary only covers it up to now\n", *year)
+		}
+		fmt.Printf("🧾 %d donation(s) in %d: %s SOL, worth %s when given; written to %s\n", len(summary.Gifts), *year, lamportsToSOL(lamports), formatFiat(value, summary.Currency), *output)
+		return nil
+
+	case "verify":
+		if len(args) != 2 {
+			return usage
+		}
+		manifest, signer, err := VerifyReport(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s report for %s signed by %s on %s; %d file(s) unchanged\n", manifest.Report, manifest.Period, signer, formatTime(manifest.Generated), len(manifest.Files))
+		return nil
+	}
+	return usage
+}
+
+// runExportCommand handles `export -format ledger|beancount [-period P] [-campaigns a,b] [-o file]`
+func runExportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "ledger or beancount")
+	periodFlag := fs.String("period", "", "year (2024), quarter (2024Q4) or month (2024-11) to export, default all history")
+	campaignsFlag := fs.String("campaigns", "", "comma-separated campaign addresses, default every campaign in "+lifecycleFile)
+	output := fs.String("o", "", "file to write, default crowdfunding.<format>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || (*format != ExportLedger && *format != ExportBeancount) {
+		return fmt.Errorf("usage: export -format ledger|beancount [-period 2024Q4] [-campaigns a,b] [-o file]")
+	}
+	if *output == "" {
+		*output = "crowdfunding." + *format
+	}
+	period := reportPeriod{Name: "all", To: time.Now()}
+	if *periodFlag != "" {
+		var err error
+		if period, err = parsePeriod(*periodFlag); err != nil {
+			return err
+		}
+	}
+	campaigns, err := reportCampaigns(*campaignsFlag)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", *output, err)
+	}
+	count, err := NewReadOnlyDApp().ExportMovements(context.Background(), file, *format, period, campaigns)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write %s: %w", *output, closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("📒 Wrote %d %s entries to %s\n", count, *format, *output)
+	return nil
+}
+
+// runLedgerCommand handles `ledger [balances]`, `ledger sync [-campaigns a,b]` and `ledger verify`
+func runLedgerCommand(args []string) error {
+	usage := fmt.Errorf("usage: ledger [balances] | ledger sync [-campaigns a,b] | ledger verify")
+	if len(args) == 0 {
+		args = []string{"balances"}
+	}
+
+	switch args[0] {
+	case "balances":
+		if len(args) != 1 {
+			return usage
+		}
+		store, err := OpenStore()
+		if err != nil {
+			return err
+		}
+		entries := store.LedgerEntries()
+		if len(entries) == 0 {
+			fmt.Println("The ledger is empty. Run `ledger sync` to record the campaigns' history.")
+			return nil
+		}
+		balances := LedgerBalances(entries)
+		var accounts []string
+		for account := range balances {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+		fmt.Printf("📒 %d transaction(s), last at slot %d\n", len(entries), entries[len(entries)-1].Slot)
+		for _, account := range accounts {
+			fmt.Printf("   %-54s %16s SOL\n", account, signedSOL(balances[account]))
+		}
+		return nil
+
+	case "sync":
+		fs := flag.NewFlagSet("ledger sync", flag.ContinueOnError)
+		campaignsFlag := fs.String("campaigns", "", "comma-separated campaign addresses, default every campaign in "+lifecycleFile)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 0 {
+			return usage
+		}
+		campaigns, err := reportCampaigns(*campaignsFlag)
+		if err != nil {
+			return err
+		}
+		added, err := NewReadOnlyDApp().SyncLedger(context.Background(), campaigns)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("📒 Added %d transaction(s) of %d campaign(s) to the ledger\n", added, len(campaigns))
+		return nil
+
+	case "verify":
+		if len(args) != 1 {
+			return usage
+		}
+		checked, problems, err := NewReadOnlyDApp().VerifyLedger(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			where := problem.Account
+			if problem.Signature != "" {
+				where += " at " + problem.Signature
+			}
+			fmt.Printf("❌ %s: %s\n", where, problem.Message)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("the ledger disagrees with the chain in %d place(s)", len(problems))
+		}
+		fmt.Printf("✅ Every entry balances and %d campaign balance(s) match the chain\n", checked)
+		return nil
+	}
+	return usage
+}
+
+// runReconcileCommand handles `reconcile [-yes] <campaign>`
+func runReconcileCommand(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "repair the local ledger without asking")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: reconcile [-yes] <campaign-address>")
+	}
+	campaign, err := solana.PublicKeyFromBase58(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid campaign address: %w", err)
+	}
+
+	r, err := NewReadOnlyDApp().Reconcile(context.Background(), campaign)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔎 %d transaction(s) in the local ledger, %d on chain\n", r.Local, r.OnChain)
+	if len(r.Issues) == 0 {
+		fmt.Println("✅ The local ledger matches the chain")
+		return nil
+	}
+	for _, issue := range r.Issues {
+		fmt.Printf("❌ %-9s %s\n   %s\n", issue.Kind, issue.Signature, issue.Detail)
+	}
+
+	if !*yes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("%d discrepancy(ies) found; run with -yes to repair the local ledger", len(r.Issues))
+		}
+		fmt.Print("\nRepair the local ledger from the chain? [y/N]: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return fmt.Errorf("%d discrepancy(ies) left unrepaired", len(r.Issues))
+		}
+	}
+	if err := r.Repair(); err != nil {
+		return fmt.Errorf("failed to repair the ledger: %w", err)
+	}
+	fmt.Printf("🔧 Repaired %d entry(ies)\n", len(r.Issues))
+	return nil
+}
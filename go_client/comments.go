@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// maxCommentLength bounds the memo attached to a donation
+const maxCommentLength = 280
+
+// memoV1ProgramID is the original SPL memo program, still used by some wallets
+var memoV1ProgramID = solana.MustPublicKeyFromBase58("Memo1UhkJRfHyvLMcVucJwxXeuD728EqVDDwQDxFMNo")
+
+// defaultBlockedWords is the built-in profanity list, extended with comments.blockedWords in config.json
+var defaultBlockedWords = []string{"fuck", "shit", "bitch", "cunt", "asshole", "bastard", "dick", "piss"}
+
+// CommentsConfig configures the campaign message board in config.json
+type CommentsConfig struct {
+	ProfanityFilter bool     `json:"profanityFilter,omitempty"`
+	BlockedWords    []string `json:"blockedWords,omitempty"`
+}
+
+// Comment is a message a donor attached to a donation as a memo
+type Comment struct {
+	Signature string     `json:"signature"`
+	Donor     string     `json:"donor"`
+	Amount    uint64     `json:"amount"`
+	Message   string     `json:"message"`
+	Slot      uint64     `json:"slot"`
+	BlockTime *time.Time `json:"blockTime,omitempty"`
+}
+
+// memoInstruction attaches a comment to a transaction, signed by the donor
+func memoInstruction(message string, signer solana.PublicKey) solana.Instruction {
+	return solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(signer).SIGNER()}, []byte(message))
+}
+
+// validateComment checks a comment before it is put on-chain
+func validateComment(message string) error {
+	if !utf8.ValidString(message) {
+		return fmt.Errorf("the message must be valid UTF-8")
+	}
+	if len(message) > maxCommentLength {
+		return fmt.Errorf("the message is %d bytes, the limit is %d", len(message), maxCommentLength)
+	}
+	return nil
+}
+
+// isMemoProgram reports whether a program is either version of the SPL memo program
+func isMemoProgram(program solana.PublicKey) bool {
+	return program.Equals(solana.MemoProgramID) || program.Equals(memoV1ProgramID)
+}
+
+// commentFilter hides comments from muted donors and masks blocked words
+type commentFilter struct {
+	muted   map[string]map[string]bool // campaign -> donor -> muted
+	blocked *regexp.Regexp
+}
+
+// loadCommentFilter builds the filter from config.json and the mute list in the local store
+func (app *SolanaDApp) loadCommentFilter() *commentFilter {
+	filter := &commentFilter{muted: map[string]map[string]bool{}}
+
+	if store, err := OpenStore(); err == nil {
+		for campaign, donors := range store.Mutes() {
+			filter.muted[campaign] = map[string]bool{}
+			for _, donor := range donors {
+				filter.muted[campaign][donor] = true
+			}
+		}
+	}
+
+	if app.config.Comments != nil && app.config.Comments.ProfanityFilter {
+		words := append(append([]string(nil), defaultBlockedWords...), app.config.Comments.BlockedWords...)
+		for i := range words {
+			words[i] = regexp.QuoteMeta(strings.ToLower(words[i]))
+		}
+		filter.blocked = regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)\w*`)
+	}
+	return filter
+}
+
+// apply filters the memos of a campaign's activity in place: muted donors' memos are dropped,
+// but their donations are still listed
+func (f *commentFilter) apply(campaign solana.PublicKey, activity []CampaignActivity) {
+	for i := range activity {
+		if activity[i].Memo == "" {
+			continue
+		}
+		if f.muted[campaign.String()][activity[i].Wallet] {
+			activity[i].Memo = ""
+			continue
+		}
+		if f.blocked != nil {
+			activity[i].Memo = f.blocked.ReplaceAllStringFunc(activity[i].Memo, func(word string) string {
+				return strings.Repeat("*", utf8.RuneCountInString(word))
+			})
+		}
+	}
+}
+
+// GetCampaignComments returns the filtered messages attached to a campaign's recent donations, newest first
+func (app *SolanaDApp) GetCampaignComments(campaign solana.PublicKey, limit int) ([]Comment, error) {
+	activity, err := app.GetCampaignActivity(campaign, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := []Comment{}
+	for _, entry := range activity {
+		if entry.Kind != "donate" || entry.Failed || entry.Memo == "" {
+			continue
+		}
+		comments = append(comments, Comment{
+			Signature: entry.Signature,
+			Donor:     entry.Wallet,
+			Amount:    entry.Amount,
+			Message:   entry.Memo,
+			Slot:      entry.Slot,
+			BlockTime: entry.BlockTime,
+		})
+	}
+	return comments, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const configFile = "config.json"
+
+// Config holds optional user preferences read from config.json
+type Config struct {
+	Explorer       string        `json:"explorer,omitempty"`       // solana-explorer, solscan, solanafm or xray
+	Cluster        string        `json:"cluster,omitempty"`        // devnet, testnet, mainnet-beta or localnet
+	Wallet         string        `json:"wallet,omitempty"`         // key file used when none is given on the command line
+	WSURL          string        `json:"wsUrl,omitempty"`          // WebSocket endpoint, default derived from rpcUrl
+	RPCURL         string        `json:"rpcUrl,omitempty"`         // RPC endpoint for queries, default devnet
+	SendRPCURL     string        `json:"sendRpcUrl,omitempty"`     // RPC endpoint for blockhashes and sending transactions, default rpcUrl
+	RPCConcurrency int           `json:"rpcConcurrency,omitempty"` // max in-flight requests per RPC endpoint
+	FeePayer       string        `json:"feePayer,omitempty"`       // key file of a sponsor wallet that pays transaction fees
+	Policy         string        `json:"policy,omitempty"`         // withdrawal rules, default policy.yaml
+	FIDO2Device    string        `json:"fido2Device,omitempty"`    // security key device path, default the first one found
+	SeedNamespace  string        `json:"seedNamespace,omitempty"`  // campaign address seed prefix of our program deployment, when its IDL doesn't say
+	Timezone       string        `json:"timezone,omitempty"`       // IANA time zone timestamps are shown in, default the system's
+	Log            *LogConfig    `json:"log,omitempty"`
+	Daemon         *DaemonConfig `json:"daemon,omitempty"`
+	Store          *StoreConfig  `json:"store,omitempty"`
+
+	Notifications []NotificationConfig `json:"notifications,omitempty"`
+	Alerts        []AlertRule          `json:"alerts,omitempty"`
+	AutoWithdraw  []AutoWithdrawPolicy `json:"autoWithdraw,omitempty"`
+	AutoClose     []AutoClosePolicy    `json:"autoClose,omitempty"`
+	Comments      *CommentsConfig      `json:"comments,omitempty"`
+	Digest        *DigestConfig        `json:"digest,omitempty"`
+	MQTT          *MQTTConfig          `json:"mqtt,omitempty"`
+	EventBus      *EventBusConfig      `json:"eventBus,omitempty"`
+	Redis         *RedisConfig         `json:"redis,omitempty"`
+	RateLimits    *RateLimitConfig     `json:"rateLimits,omitempty"`
+	Backup        *BackupConfig        `json:"backup,omitempty"`
+	Keystore      *KeystoreConfig      `json:"keystore,omitempty"`
+	Send          *SendConfig          `json:"send,omitempty"`
+	Faucets       []FaucetConfig       `json:"faucets,omitempty"`
+	Sites         []SiteConfig         `json:"sites,omitempty"`
+	PriorityFee   *PriorityFeeConfig   `json:"priorityFee,omitempty"`
+	Registry      *RegistryConfig      `json:"registry,omitempty"`
+	Screening     *ScreeningConfig     `json:"screening,omitempty"`
+	Prices        *PriceConfig         `json:"prices,omitempty"`
+	Export        *ExportConfig        `json:"export,omitempty"`
+
+	AccountCacheTTL  string `json:"accountCacheTTL,omitempty"`  // e.g. "30s"; "0" disables the account cache
+	AccountCacheFile string `json:"accountCacheFile,omitempty"` // persist the account cache across runs
+	IDLCacheTTL      string `json:"idlCacheTTL,omitempty"`      // e.g. "24h"; "0" only refreshes idl.json on redeploys
+
+	accountCacheTTL time.Duration
+	idlCacheTTL     time.Duration
+}
+
+// defaultConfig returns the settings used when config.json is missing or invalid
+func defaultConfig() *Config {
+	return &Config{Explorer: ExplorerSolana, RPCConcurrency: defaultRPCConcurrency, accountCacheTTL: defaultAccountCacheTTL, idlCacheTTL: defaultIDLCacheTTL}
+}
+
+// clusters are the clusters a config can name
+var clusters = map[string]rpc.Cluster{
+	rpc.DevNet.Name:      rpc.DevNet,
+	rpc.TestNet.Name:     rpc.TestNet,
+	rpc.MainNetBeta.Name: rpc.MainNetBeta,
+	rpc.LocalNet.Name:    rpc.LocalNet,
+}
+
+// cluster is the configured cluster, devnet by default
+func (c *Config) cluster() rpc.Cluster {
+	if cluster, ok := clusters[c.Cluster]; ok {
+		return cluster
+	}
+	return rpc.DevNet
+}
+
+// readEndpoint is the RPC endpoint queries go to
+func (c *Config) readEndpoint() string {
+	if c.RPCURL != "" {
+		return c.RPCURL
+	}
+	return c.cluster().RPC
+}
+
+// wsEndpoint is the WebSocket endpoint subscriptions go to: the configured one, else the one
+// serving the same host as rpcUrl, else the cluster's
+func (c *Config) wsEndpoint() string {
+	if c.WSURL != "" {
+		return c.WSURL
+	}
+	if c.RPCURL == "" {
+		return c.cluster().WS
+	}
+	u, err := url.Parse(c.RPCURL)
+	if err != nil {
+		return c.cluster().WS
+	}
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	// A local validator serves WebSockets on the port after its RPC port
+	if u.Port() == "8899" {
+		u.Host = u.Hostname() + ":8900"
+	}
+	return u.String()
+}
+
+// sendEndpoint is the RPC endpoint transactions are sent through, so that listing campaigns on a
+// free endpoint doesn't use up the quota of a paid one
+func (c *Config) sendEndpoint() string {
+	if c.SendRPCURL != "" {
+		return c.SendRPCURL
+	}
+	return c.readEndpoint()
+}
+
+// loadConfig reads config.json, returning defaults when it is missing or invalid
+func loadConfig() *Config {
+	config := defaultConfig()
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return config // No config file, which is fine
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		fmt.Printf("⚠️  Ignoring invalid %s: %v\n", configFile, err)
+		return defaultConfig()
+	}
+
+	if _, ok := explorers[config.Explorer]; !ok {
+		fmt.Printf("⚠️  Unknown explorer %q in %s, using %s\n", config.Explorer, configFile, ExplorerSolana)
+		config.Explorer = ExplorerSolana
+	}
+
+	if _, ok := clusters[config.Cluster]; config.Cluster != "" && !ok {
+		fmt.Printf("⚠️  Unknown cluster %q in %s, using %s\n", config.Cluster, configFile, rpc.DevNet.Name)
+		config.Cluster = ""
+	}
+
+	if config.Timezone != "" {
+		if err := setTimezone(config.Timezone); err != nil {
+			fmt.Printf("⚠️  Ignoring timezone in %s: %v\n", configFile, err)
+			config.Timezone = ""
+		}
+	}
+
+	if config.RPCConcurrency <= 0 {
+		config.RPCConcurrency = defaultRPCConcurrency
+	}
+
+	if len(config.SeedNamespace) > 32 {
+		fmt.Printf("⚠️  Ignoring seedNamespace in %s: seeds are at most 32 bytes\n", configFile)
+		config.SeedNamespace = ""
+	}
+
+	if config.Send != nil {
+		for _, opts := range []*SendOptions{&config.Send.SendOptions, config.Send.Donate, config.Send.Withdraw} {
+			if opts == nil {
+				continue
+			}
+			if err := opts.validate(); err != nil {
+				fmt.Printf("⚠️  Ignoring send option in %s: %v\n", configFile, err)
+				opts.PreflightCommitment = ""
+			}
+		}
+	}
+
+	if config.AccountCacheTTL != "" {
+		ttl, err := time.ParseDuration(config.AccountCacheTTL)
+		if err != nil {
+			fmt.Printf("⚠️  Invalid accountCacheTTL %q in %s, using %s\n", config.AccountCacheTTL, configFile, defaultAccountCacheTTL)
+		} else {
+			config.accountCacheTTL = ttl
+		}
+	}
+
+	if config.IDLCacheTTL != "" {
+		ttl, err := time.ParseDuration(config.IDLCacheTTL)
+		if err != nil {
+			fmt.Printf("⚠️  Invalid idlCacheTTL %q in %s, using %s\n", config.IDLCacheTTL, configFile, defaultIDLCacheTTL)
+		} else {
+			config.idlCacheTTL = ttl
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// sensitiveConfigKeys marks config values that are redacted from crash reports
+var sensitiveConfigKeys = []string{"password", "secret", "token", "key", "dsn", "webhook", "url"}
+
+// setupCrashReporting enables Sentry when SENTRY_DSN is set. The returned function flushes pending events.
+func setupCrashReporting() func() {
+	dsn := os.Getenv("SENTRY_DSN")
+	if dsn == "" {
+		return func() {}
+	}
+
+	if err := sentry.Init(sentry.ClientOptions{Dsn: dsn, Release: GetBuildInfo().String()}); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Crash reporting to Sentry disabled: %v\n", err)
+		return func() {}
+	}
+	return func() { sentry.Flush(2 * time.Second) }
+}
+
+// recoverCrash turns a panic in the CLI into a crash report instead of a raw stack trace. Use with defer.
+func recoverCrash() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	path := reportCrash(recovered, debug.Stack(), lastOperation)
+	fmt.Fprintf(os.Stderr, "\n💥 The client crashed unexpectedly: %v\n", recovered)
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "📄 A crash report was saved to %s. Please attach it when reporting the problem.\n", path)
+	}
+	os.Exit(2)
+}
+
+// recoverPanics answers 500 and writes a crash report when an API handler panics
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered) // Deliberate abort, let net/http handle it
+			}
+
+			operation := fmt.Sprintf("%s %s (request %s)", r.Method, r.URL.Path, requestIDFrom(r.Context()))
+			path := reportCrash(recovered, debug.Stack(), operation)
+			logf(r.Context(), "Panic serving %s: %v (crash report %s)", r.URL.Path, recovered, path)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "internal server error", "requestId": requestIDFrom(r.Context())})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reportCrash writes a crash report to a local file and forwards it to Sentry when enabled.
+// It returns the report's path, or "" if it could not be written.
+func reportCrash(recovered interface{}, stack []byte, operation string) string {
+	if hub := sentry.CurrentHub(); hub.Client() != nil {
+		hub.WithScope(func(scope *sentry.Scope) {
+			scope.SetTag("operation", operation)
+			hub.Recover(recovered)
+		})
+		sentry.Flush(2 * time.Second)
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Crash report %s\n\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&report, "Panic: %v\n", recovered)
+	info := GetBuildInfo()
+	fmt.Fprintf(&report, "Version: %s\n", info)
+	fmt.Fprintf(&report, "Built: %s\n", info.BuildDate)
+	fmt.Fprintf(&report, "Go: %s %s\n", info.GoVersion, info.Platform)
+	fmt.Fprintf(&report, "Program: %s\n", info.ProgramID)
+	fmt.Fprintf(&report, "Network: %s\n", info.Network)
+	fmt.Fprintf(&report, "Last operation: %s\n\n", operation)
+	fmt.Fprintf(&report, "Config (sanitized):\n%s\n\n", sanitizedConfig())
+	fmt.Fprintf(&report, "Stack:\n%s", stack)
+
+	path := fmt.Sprintf("crash-%s.log", time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(path, []byte(report.String()), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to write crash report: %v\n", err)
+		return ""
+	}
+	return path
+}
+
+// sanitizedConfig returns config.json with anything that looks like a credential or endpoint redacted
+func sanitizedConfig() string {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return "(no config file)"
+	}
+
+	var config interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "(invalid config file)"
+	}
+
+	pretty, _ := json.MarshalIndent(redactConfig(config), "", "  ")
+	return string(pretty)
+}
+
+func redactConfig(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isSensitiveConfigKey(key) {
+				v[key] = "[redacted]"
+			} else {
+				v[key] = redactConfig(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactConfig(v[i])
+		}
+	}
+	return value
+}
+
+func isSensitiveConfigKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveConfigKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
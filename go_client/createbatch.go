@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Campaign creation row states in a create-batch results file
+const (
+	CreateRowCreated = "created"
+	CreateRowExists  = "exists" // created by an earlier run, or by hand
+	CreateRowFailed  = "failed"
+)
+
+// csvRow is one data row of a CSV file, by lower-case column name
+type csvRow struct {
+	line   int // line in the file, for error messages
+	fields map[string]string
+}
+
+// readCSV reads a CSV file whose first row names the columns. Every name in required must be
+// present; other columns are kept too, so callers can accept optional ones.
+func readCSV(path string, required ...string) ([]csvRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header of %s: %w", path, err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+	}
+	for _, name := range required {
+		found := false
+		for _, column := range header {
+			found = found || column == name
+		}
+		if !found {
+			return nil, fmt.Errorf("%s has no %q column; the first row must name the columns (%s)", path, name, strings.Join(required, ", "))
+		}
+	}
+
+	var rows []csvRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		row := csvRow{line: line, fields: map[string]string{}}
+		empty := true
+		for i, value := range record {
+			if i < len(header) {
+				row.fields[header[i]] = strings.TrimSpace(value)
+				empty = empty && strings.TrimSpace(value) == ""
+			}
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s has no rows", path)
+	}
+	return rows, nil
+}
+
+// writeCSV writes a header and rows to path atomically
+func writeCSV(path string, header []string, rows [][]string) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	w := csv.NewWriter(file)
+	w.Write(header)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// CampaignRow is one campaign of a create-batch file and the outcome of creating it
+type CampaignRow struct {
+	Line        int
+	Name        string
+	Description string
+	Goal        uint64 // lamports; 0 when the row has no target
+
+	Address   solana.PublicKey
+	Status    string
+	Signature string
+	Error     string
+}
+
+// createBatchHeader is the header of a create-batch results file
+var createBatchHeader = []string{"line", "name", "target", "address", "status", "signature", "error"}
+
+// loadCampaignRows reads a create-batch CSV with name, description and optional target columns,
+// validating every row before anything is signed
+func (app *SolanaDApp) loadCampaignRows(path string) ([]*CampaignRow, error) {
+	records, err := readCSV(path, "name", "description")
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*CampaignRow
+	seen := map[string]int{}
+	for _, record := range records {
+		row := &CampaignRow{Line: record.line, Name: record.fields["name"], Description: record.fields["description"]}
+		if err := crowdfund.CheckCampaignCapacity(row.Name, row.Description); err != nil {
+			return nil, fmt.Errorf("line %d: %w", row.Line, err)
+		}
+		if line, ok := seen[row.Name]; ok {
+			return nil, fmt.Errorf("line %d: campaign %q is already on line %d", row.Line, row.Name, line)
+		}
+		seen[row.Name] = row.Line
+		if target := record.fields["target"]; target != "" {
+			if row.Goal, err = parseSOL(target); err != nil {
+				return nil, fmt.Errorf("line %d: invalid target %q: %w", row.Line, target, err)
+			}
+		}
+		if row.Address, _, err = app.CreateCampaignPDA(row.Name); err != nil {
+			return nil, fmt.Errorf("line %d: failed to derive campaign address: %w", row.Line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// loadCreateResults restores the campaigns a previous run created from its results file, so a
+// rerun still reports them as created, with their signatures
+func loadCreateResults(path string, rows []*CampaignRow) {
+	records, err := readCSV(path, "name", "status", "signature")
+	if err != nil {
+		return
+	}
+	created := map[string]string{}
+	for _, record := range records {
+		if record.fields["status"] == CreateRowCreated {
+			created[record.fields["name"]] = record.fields["signature"]
+		}
+	}
+	for _, row := range rows {
+		if sig, ok := created[row.Name]; ok {
+			row.Status, row.Signature = CreateRowCreated, sig
+		}
+	}
+}
+
+// saveCreateResults writes the results file
+func saveCreateResults(path string, rows []*CampaignRow) error {
+	var records [][]string
+	for _, row := range rows {
+		target := ""
+		if row.Goal > 0 {
+			target = lamportsToSOL(row.Goal)
+		}
+		records = append(records, []string{strconv.Itoa(row.Line), row.Name, target, row.Address.String(), row.Status, row.Signature, row.Error})
+	}
+	return writeCSV(path, createBatchHeader, records)
+}
+
+// CreateCampaigns creates every campaign in rows that does not exist yet, one transaction each,
+// reporting each row and saving the results after every row. Campaigns that already exist are
+// left alone, so an interrupted run can simply be repeated.
+func (app *SolanaDApp) CreateCampaigns(ctx context.Context, rows []*CampaignRow, resultsPath string) error {
+	// Check the rent for every missing campaign up front rather than failing halfway through
+	var missing int
+	for _, row := range rows {
+		account, err := app.getAccount(row.Address)
+		if err != nil {
+			return fmt.Errorf("line %d: failed to fetch campaign account: %w", row.Line, err)
+		}
+		switch {
+		case account != nil && account.Campaign != nil:
+			if row.Status != CreateRowCreated {
+				row.Status = CreateRowExists
+			}
+		case app.campaignAddressBlocked(account):
+			return fmt.Errorf("line %d: %w: %s", row.Line, ErrCampaignAddressTaken, row.Address)
+		default:
+			row.Status = ""
+			missing++
+		}
+	}
+	if missing > 0 {
+		rent, err := app.client.GetMinimumBalanceForRentExemption(ctx, crowdfund.CampaignAccountSpace, rpc.CommitmentConfirmed)
+		if err != nil {
+			return fmt.Errorf("failed to get rent exemption: %w", err)
+		}
+		balance, err := app.client.GetBalance(ctx, app.wallet.Address(), rpc.CommitmentConfirmed)
+		if err != nil {
+			return fmt.Errorf("failed to get balance: %w", err)
+		}
+		if need := rent * uint64(missing); balance.Value < need {
+			return fmt.Errorf("%w: %d campaign accounts need %s SOL of rent and the wallet holds %s SOL", crowdfund.ErrInsufficientBalance, missing, lamportsToSOL(need), lamportsToSOL(balance.Value))
+		}
+	}
+	fmt.Printf("📦 Creating %d campaign(s), %d already exist; results in %s\n", missing, len(rows)-missing, resultsPath)
+
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if row.Status == CreateRowCreated || row.Status == CreateRowExists {
+			fmt.Printf("⏭️  Line %d: '%s' already exists at %s\n", row.Line, row.Name, row.Address)
+		} else {
+			instruction, err := app.BuildInstruction("create",
+				map[string]solana.PublicKey{"campaign": row.Address, "user": app.wallet.Address()},
+				map[string]interface{}{"name": row.Name, "description": row.Description},
+			)
+			if err != nil {
+				return fmt.Errorf("failed to build create instruction: %w", err)
+			}
+			sig, err := app.submitTransaction("create", []solana.Instruction{instruction})
+			if err != nil {
+				row.Status, row.Signature, row.Error = CreateRowFailed, "", err.Error()
+				if !sig.IsZero() {
+					row.Signature = sig.String()
+				}
+				fmt.Printf("❌ Line %d: '%s': %v\n", row.Line, row.Name, err)
+			} else {
+				row.Status, row.Signature, row.Error = CreateRowCreated, sig.String(), ""
+				fmt.Printf("✅ Line %d: '%s' created at %s: %s\n", row.Line, row.Name, row.Address, sig)
+			}
+		}
+
+		if row.Status != CreateRowFailed {
+			if err := recordCampaign(row.Address, app.wallet.Address(), row.Name, "", row.Goal, nil); err != nil {
+				logf(ctx, "Failed to record campaign %s: %v", row.Name, err)
+			}
+		}
+		if err := saveCreateResults(resultsPath, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package crowdfund
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Campaign represents the campaign account structure
+type Campaign struct {
+	Admin         solana.PublicKey `json:"admin"`
+	Name          string           `json:"name"`
+	Description   string           `json:"description"`
+	AmountDonated uint64           `json:"amount_donated"`
+	Bump          uint8            `json:"bump"`
+}
+
+// BorshReader reads little-endian Borsh values from a byte slice
+type BorshReader struct {
+	data []byte
+	pos  int
+}
+
+// NewBorshReader reads data from offset on, e.g. 8 to skip an Anchor discriminator
+func NewBorshReader(data []byte, offset int) *BorshReader {
+	return &BorshReader{data: data, pos: offset}
+}
+
+// Offset is the position of the next value
+func (r *BorshReader) Offset() int {
+	return r.pos
+}
+
+func (r *BorshReader) next(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("unexpected end of data at offset %d (need %d bytes, have %d)", r.pos, n, len(r.data)-r.pos)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// ReadU8 reads a u8
+func (r *BorshReader) ReadU8() (uint8, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// ReadU32 reads a u32
+func (r *BorshReader) ReadU32() (uint32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// ReadU64 reads a u64
+func (r *BorshReader) ReadU64() (uint64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// ReadString reads a length-prefixed string
+func (r *BorshReader) ReadString() (string, error) {
+	n, err := r.ReadU32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.next(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ReadPublicKey reads a 32-byte public key
+func (r *BorshReader) ReadPublicKey() (solana.PublicKey, error) {
+	b, err := r.next(32)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	return solana.PublicKeyFromBytes(b), nil
+}
+
+// DecodeCampaign decodes an Anchor campaign account after verifying its discriminator
+func DecodeCampaign(data []byte) (*Campaign, error) {
+	if len(data) < 8 || !bytes.Equal(data[:8], Discriminator("account", "Campaign")) {
+		return nil, ErrNotACampaignAccount
+	}
+
+	r := NewBorshReader(data, 8)
+	var campaign Campaign
+	var err error
+	if campaign.Admin, err = r.ReadPublicKey(); err != nil {
+		return nil, fmt.Errorf("%w: admin: %v", ErrNotACampaignAccount, err)
+	}
+	if campaign.Name, err = r.ReadString(); err != nil {
+		return nil, fmt.Errorf("%w: name: %v", ErrNotACampaignAccount, err)
+	}
+	if campaign.Description, err = r.ReadString(); err != nil {
+		return nil, fmt.Errorf("%w: description: %v", ErrNotACampaignAccount, err)
+	}
+	if campaign.AmountDonated, err = r.ReadU64(); err != nil {
+		return nil, fmt.Errorf("%w: amount_donated: %v", ErrNotACampaignAccount, err)
+	}
+	if campaign.Bump, err = r.ReadU8(); err != nil {
+		return nil, fmt.Errorf("%w: bump: %v", ErrNotACampaignAccount, err)
+	}
+
+	return &campaign, nil
+}
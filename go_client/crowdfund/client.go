@@ -0,0 +1,133 @@
+package crowdfund
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Client runs campaign operations for one wallet: it builds the instructions, signs and sends
+// the transaction and waits for confirmation
+type Client struct {
+	RPC       RPC
+	Signer    Signer
+	ProgramID solana.PublicKey
+	IDL       *IDL   // instruction layouts and errors, default DefaultIDL
+	Seed      []byte // campaign address seed prefix, default the IDL's or LegacyCampaignSeed
+}
+
+// NewClient returns a Client for the deployed program. signer may be nil for read-only use.
+func NewClient(client RPC, signer Signer) *Client {
+	return &Client{
+		RPC:       client,
+		Signer:    signer,
+		ProgramID: solana.MustPublicKeyFromBase58(ProgramID),
+		IDL:       DefaultIDL(),
+	}
+}
+
+// seed is the campaign address seed prefix in use
+func (c *Client) seed() []byte {
+	if c.Seed != nil {
+		return c.Seed
+	}
+	if seed := c.IDL.CampaignSeed(); seed != nil {
+		return seed
+	}
+	return []byte(LegacyCampaignSeed)
+}
+
+// CampaignAddress derives the address of an admin's campaign
+func (c *Client) CampaignAddress(admin solana.PublicKey, name string) (solana.PublicKey, error) {
+	address, _, err := CampaignPDA(c.ProgramID, c.seed(), admin, name)
+	return address, err
+}
+
+// FetchCampaign loads and decodes a campaign account
+func (c *Client) FetchCampaign(ctx context.Context, address solana.PublicKey) (*Campaign, error) {
+	account, err := c.RPC.GetAccountInfo(ctx, address)
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && account.Value == nil) {
+		return nil, fmt.Errorf("%w: no account at %s", ErrCampaignNotFound, address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch campaign account: %w", err)
+	}
+	if !account.Value.Owner.Equals(c.ProgramID) {
+		return nil, fmt.Errorf("%w: owned by %s", ErrNotACampaignAccount, account.Value.Owner)
+	}
+	return DecodeCampaign(account.Value.Data.GetBinary())
+}
+
+// Create creates a campaign owned by the signer and returns its address
+func (c *Client) Create(ctx context.Context, name, description string) (solana.PublicKey, solana.Signature, error) {
+	if err := CheckCampaignCapacity(name, description); err != nil {
+		return solana.PublicKey{}, solana.Signature{}, err
+	}
+	address, err := c.CampaignAddress(c.Signer.Address(), name)
+	if err != nil {
+		return address, solana.Signature{}, fmt.Errorf("failed to derive campaign address: %w", err)
+	}
+	instruction, err := c.IDL.BuildInstruction(c.ProgramID, "create",
+		map[string]solana.PublicKey{"campaign": address, "user": c.Signer.Address()},
+		map[string]interface{}{"name": name, "description": description},
+	)
+	if err != nil {
+		return address, solana.Signature{}, fmt.Errorf("failed to build create instruction: %w", err)
+	}
+	sig, err := c.Send(ctx, instruction)
+	return address, sig, err
+}
+
+// Donate sends lamports from the signer to a campaign
+func (c *Client) Donate(ctx context.Context, campaign solana.PublicKey, amount uint64) (solana.Signature, error) {
+	return c.transfer(ctx, "donate", campaign, amount)
+}
+
+// Withdraw sends lamports from a campaign the signer administers to the signer
+func (c *Client) Withdraw(ctx context.Context, campaign solana.PublicKey, amount uint64) (solana.Signature, error) {
+	return c.transfer(ctx, "withdraw", campaign, amount)
+}
+
+// transfer runs donate or withdraw, which take the campaign's name along with the amount
+func (c *Client) transfer(ctx context.Context, action string, campaign solana.PublicKey, amount uint64) (solana.Signature, error) {
+	account, err := c.FetchCampaign(ctx, campaign)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	instruction, err := c.IDL.BuildInstruction(c.ProgramID, action,
+		map[string]solana.PublicKey{"campaign": campaign, "user": c.Signer.Address()},
+		map[string]interface{}{"name": account.Name, "amount": amount},
+	)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to build %s instruction: %w", action, err)
+	}
+	return c.Send(ctx, instruction)
+}
+
+// Send signs instructions into a transaction paid for by the signer, sends it and waits until it
+// is confirmed. Program errors are annotated from the IDL.
+func (c *Client) Send(ctx context.Context, instructions ...solana.Instruction) (solana.Signature, error) {
+	recent, err := c.RPC.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %w", err)
+	}
+	tx, err := solana.NewTransaction(instructions, recent.Value.Blockhash, solana.TransactionPayer(c.Signer.Address()))
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if err := SignTransaction(tx, c.Signer, nil); err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	sig, err := c.RPC.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{PreflightCommitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return sig, fmt.Errorf("failed to send transaction: %w", c.IDL.DecodeError(err))
+	}
+	if err := WaitForConfirmation(ctx, c.RPC, sig, recent.Value.LastValidBlockHeight); err != nil {
+		return sig, c.IDL.DecodeError(err)
+	}
+	return sig, nil
+}
@@ -0,0 +1,58 @@
+package crowdfund
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// StatusRPC is the part of the RPC API confirmation needs
+type StatusRPC interface {
+	GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, signatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error)
+	GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+}
+
+// WaitForConfirmation polls the signature status until the transaction is confirmed, fails, or
+// its blockhash expires, which is reported as ErrBlockhashExpired
+func WaitForConfirmation(ctx context.Context, client StatusRPC, sig solana.Signature, lastValidBlockHeight uint64) error {
+	for {
+		if done, err := SignatureOutcome(ctx, client, sig, false); done {
+			return err
+		}
+
+		height, err := client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+		if err == nil && height > lastValidBlockHeight {
+			// The transaction can no longer land, but it may have landed just before: look once more,
+			// through the whole history, so it is never sent twice
+			if done, err := SignatureOutcome(ctx, client, sig, true); done {
+				return err
+			}
+			return fmt.Errorf("transaction %s: %w", sig, ErrBlockhashExpired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// SignatureOutcome reports whether a transaction is confirmed or failed, and its error if it failed
+func SignatureOutcome(ctx context.Context, client StatusRPC, sig solana.Signature, searchHistory bool) (bool, error) {
+	statuses, err := client.GetSignatureStatuses(ctx, searchHistory, sig)
+	if err != nil || len(statuses.Value) == 0 || statuses.Value[0] == nil {
+		return false, nil
+	}
+	status := statuses.Value[0]
+	if status.Err != nil {
+		return true, fmt.Errorf("transaction %s failed: %v", sig, status.Err)
+	}
+	if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+		return true, nil
+	}
+	return false, nil
+}
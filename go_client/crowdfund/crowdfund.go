@@ -0,0 +1,82 @@
+// Package crowdfund is a client library for the crowdfunding Anchor program: campaign address
+// derivation, instruction encoding from the program's IDL, account decoding and transaction
+// confirmation. Client bundles them into campaign operations for services that embed them;
+// the crowdfunding-client CLI is built on the same pieces.
+package crowdfund
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ProgramID is the address of the deployed crowdfunding program
+const ProgramID = "3r5NUnG85XtVExb1234ZYYyUazjchqjfYknnQATyCDzp"
+
+// LegacyCampaignSeed prefixes the campaign addresses of the original deployment, and of any
+// program built before the seed became configurable
+const LegacyCampaignSeed = "CAMPAIGN_DEMO"
+
+// CampaignSeedConstant is the constant the program exports its campaign seed prefix as
+const CampaignSeedConstant = "CAMPAIGN_SEED"
+
+// CampaignAccountSpace is the size the program allocates for every campaign account
+const CampaignAccountSpace = 9000
+
+// CampaignFixedSpace is the part of a campaign account taken by everything but the name and
+// description: discriminator, admin, the two string lengths, amount donated and bump
+const CampaignFixedSpace = 8 + 32 + 4 + 4 + 8 + 1
+
+// ErrNotACampaignAccount is returned when account data does not start with the Campaign discriminator
+var ErrNotACampaignAccount = errors.New("account is not a crowdfunding campaign")
+
+// Errors returned by campaign operations, for callers to tell apart with errors.Is. Program
+// errors decoded by IDL.DecodeError wrap the matching one.
+var (
+	// ErrCampaignNotFound is returned when no account exists at a campaign address
+	ErrCampaignNotFound = errors.New("campaign not found")
+	// ErrUnauthorizedAdmin is returned when a wallet other than the campaign admin withdraws
+	ErrUnauthorizedAdmin = errors.New("wallet is not the campaign admin")
+	// ErrInsufficientCampaignFunds is returned when a withdrawal exceeds what the campaign can pay out
+	ErrInsufficientCampaignFunds = errors.New("campaign has insufficient funds")
+	// ErrInsufficientBalance is returned when the wallet cannot pay for a donation, account or fee
+	ErrInsufficientBalance = errors.New("wallet has insufficient SOL")
+	// ErrBlockhashExpired is returned when a transaction's blockhash expires before it is confirmed
+	ErrBlockhashExpired = errors.New("transaction expired before it was confirmed")
+)
+
+// Discriminator creates the 8-byte discriminator Anchor prefixes instructions ("global") and
+// accounts ("account") with
+func Discriminator(namespace, name string) []byte {
+	preimage := fmt.Sprintf("%s:%s", namespace, name)
+	hash := sha256.Sum256([]byte(preimage))
+	return hash[:8]
+}
+
+// CampaignPDA derives the address of an admin's campaign under a seed prefix
+func CampaignPDA(programID solana.PublicKey, seed []byte, admin solana.PublicKey, campaignName string) (solana.PublicKey, uint8, error) {
+	seeds := [][]byte{
+		seed,
+		admin.Bytes(),
+		[]byte(campaignName),
+	}
+
+	return solana.FindProgramAddress(seeds, programID)
+}
+
+// CheckCampaignCapacity checks that the name can seed the campaign address and that the name
+// and description fit the campaign account
+func CheckCampaignCapacity(name, description string) error {
+	if name == "" {
+		return fmt.Errorf("campaign name must not be empty")
+	}
+	if len(name) > solana.MaxSeedLength {
+		return fmt.Errorf("campaign name is %d bytes; names seed the campaign address and can be at most %d", len(name), solana.MaxSeedLength)
+	}
+	if size := CampaignFixedSpace + len(name) + len(description); size > CampaignAccountSpace {
+		return fmt.Errorf("campaign description is %d bytes too long: campaign accounts hold %d bytes", size-CampaignAccountSpace, CampaignAccountSpace)
+	}
+	return nil
+}
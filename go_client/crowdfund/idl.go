@@ -0,0 +1,533 @@
+package crowdfund
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// IDL is the subset of an Anchor IDL used by the client.
+// Both the 0.30+ spec and the legacy (isMut/isSigner) layout are accepted.
+type IDL struct {
+	Address      string           `json:"address,omitempty"`
+	Metadata     *IDLMetadata     `json:"metadata,omitempty"`
+	Name         string           `json:"name,omitempty"`
+	Version      string           `json:"version,omitempty"`
+	Instructions []IDLInstruction `json:"instructions"`
+	Errors       []IDLError       `json:"errors,omitempty"`
+	Constants    []IDLConstant    `json:"constants,omitempty"`
+}
+
+// IDLMetadata holds the program name and version in 0.30+ IDLs
+type IDLMetadata struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Spec    string `json:"spec,omitempty"`
+}
+
+// IDLInstruction describes a program instruction
+type IDLInstruction struct {
+	Name          string                  `json:"name"`
+	Discriminator []int                   `json:"discriminator,omitempty"`
+	Accounts      []IDLInstructionAccount `json:"accounts"`
+	Args          []IDLField              `json:"args"`
+}
+
+// IDLInstructionAccount describes an account passed to an instruction
+type IDLInstructionAccount struct {
+	Name     string  `json:"name"`
+	Writable bool    `json:"writable,omitempty"`
+	Signer   bool    `json:"signer,omitempty"`
+	IsMut    bool    `json:"isMut,omitempty"`
+	IsSigner bool    `json:"isSigner,omitempty"`
+	Address  string  `json:"address,omitempty"`
+	PDA      *IDLPDA `json:"pda,omitempty"`
+}
+
+// IDLPDA describes the seeds of an account that is a program derived address (0.30+ IDLs)
+type IDLPDA struct {
+	Seeds []IDLSeed `json:"seeds"`
+}
+
+// IDLSeed is one PDA seed: a constant, or an account or argument of the instruction
+type IDLSeed struct {
+	Kind  string `json:"kind"`            // const, account or arg
+	Value []int  `json:"value,omitempty"` // bytes of const seeds
+	Path  string `json:"path,omitempty"`
+}
+
+// IDLConstant is a constant the program exports with #[constant]
+type IDLConstant struct {
+	Name  string          `json:"name"`
+	Type  json.RawMessage `json:"type"`
+	Value string          `json:"value"`
+}
+
+// IDLField is a named, typed instruction argument
+type IDLField struct {
+	Name string          `json:"name"`
+	Type json.RawMessage `json:"type"`
+}
+
+// IDLError is a custom program error
+type IDLError struct {
+	Code uint32 `json:"code"`
+	Name string `json:"name"`
+	Msg  string `json:"msg,omitempty"`
+}
+
+// defaultIDL mirrors programs/crowdfunding
+var defaultIDL = IDL{
+	Address:  ProgramID,
+	Metadata: &IDLMetadata{Name: "crowdfunding", Version: "0.1.0"},
+	Instructions: []IDLInstruction{
+		{
+			Name: "create",
+			Accounts: []IDLInstructionAccount{
+				{Name: "campaign", Writable: true},
+				{Name: "user", Writable: true, Signer: true},
+				{Name: "system_program", Address: solana.SystemProgramID.String()},
+			},
+			Args: []IDLField{
+				{Name: "name", Type: json.RawMessage(`"string"`)},
+				{Name: "description", Type: json.RawMessage(`"string"`)},
+			},
+		},
+		{
+			Name: "withdraw",
+			Accounts: []IDLInstructionAccount{
+				{Name: "campaign", Writable: true},
+				{Name: "user", Writable: true, Signer: true},
+			},
+			Args: []IDLField{
+				{Name: "name", Type: json.RawMessage(`"string"`)},
+				{Name: "amount", Type: json.RawMessage(`"u64"`)},
+			},
+		},
+		{
+			Name: "donate",
+			Accounts: []IDLInstructionAccount{
+				{Name: "campaign", Writable: true},
+				{Name: "user", Writable: true, Signer: true},
+				{Name: "system_program", Address: solana.SystemProgramID.String()},
+			},
+			Args: []IDLField{
+				{Name: "name", Type: json.RawMessage(`"string"`)},
+				{Name: "amount", Type: json.RawMessage(`"u64"`)},
+			},
+		},
+	},
+	Errors: []IDLError{
+		{Code: 6000, Name: "Unauthorized", Msg: "You are not the admin of this campaign."},
+		{Code: 6001, Name: "InsufficientFunds", Msg: "Insufficient funds to perform this action."},
+	},
+}
+
+// anchorFrameworkErrors covers the Anchor errors users most commonly hit
+var anchorFrameworkErrors = []IDLError{
+	{Code: 2000, Name: "ConstraintMut", Msg: "A mut constraint was violated"},
+	{Code: 2006, Name: "ConstraintSeeds", Msg: "A seeds constraint was violated"},
+	{Code: 3007, Name: "AccountOwnedByWrongProgram", Msg: "The given account is owned by a different program than expected"},
+	{Code: 3012, Name: "AccountNotInitialized", Msg: "The program expected this account to be already initialized"},
+}
+
+// DefaultIDL returns the IDL of programs/crowdfunding as built into this package, for use
+// until the program's on-chain IDL has been fetched
+func DefaultIDL() *IDL {
+	idl := defaultIDL
+	return &idl
+}
+
+// NormalizeName lets snake_case (0.30+) and camelCase (legacy) names match each other
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// snakeCase converts a legacy camelCase instruction name to the form Anchor hashes
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ProgramName returns the program name from either IDL layout
+func (idl *IDL) ProgramName() string {
+	if idl.Metadata != nil && idl.Metadata.Name != "" {
+		return idl.Metadata.Name
+	}
+	return idl.Name
+}
+
+// Instruction looks up an instruction definition by name
+func (idl *IDL) Instruction(name string) (*IDLInstruction, error) {
+	for i := range idl.Instructions {
+		if NormalizeName(idl.Instructions[i].Name) == NormalizeName(name) {
+			return &idl.Instructions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("instruction %q not found in IDL", name)
+}
+
+// DiscriminatorBytes returns the instruction discriminator, deriving it when the IDL predates 0.30
+func (ix *IDLInstruction) DiscriminatorBytes() []byte {
+	if len(ix.Discriminator) == 8 {
+		out := make([]byte, 8)
+		for i, v := range ix.Discriminator {
+			out[i] = byte(v)
+		}
+		return out
+	}
+	return Discriminator("global", snakeCase(ix.Name))
+}
+
+// typeName returns the primitive type name of a field, or its raw JSON for complex types
+func (f IDLField) typeName() string {
+	var name string
+	if err := json.Unmarshal(f.Type, &name); err == nil {
+		return name
+	}
+	return string(f.Type)
+}
+
+// EncodeArgs Borsh-encodes instruction arguments in IDL order
+func (ix *IDLInstruction) EncodeArgs(args map[string]interface{}) ([]byte, error) {
+	data := ix.DiscriminatorBytes()
+
+	for _, field := range ix.Args {
+		value, ok := args[NormalizeName(field.Name)]
+		if !ok {
+			return nil, fmt.Errorf("missing argument %q for instruction %q", field.Name, ix.Name)
+		}
+
+		switch field.typeName() {
+		case "string":
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a string", field.Name)
+			}
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(s)))
+			data = append(data, []byte(s)...)
+		case "u64":
+			n, ok := value.(uint64)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a uint64", field.Name)
+			}
+			data = binary.LittleEndian.AppendUint64(data, n)
+		case "u32":
+			n, ok := value.(uint32)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a uint32", field.Name)
+			}
+			data = binary.LittleEndian.AppendUint32(data, n)
+		case "u8":
+			n, ok := value.(uint8)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a uint8", field.Name)
+			}
+			data = append(data, n)
+		case "bool":
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a bool", field.Name)
+			}
+			if b {
+				data = append(data, 1)
+			} else {
+				data = append(data, 0)
+			}
+		case "pubkey", "publicKey":
+			key, ok := value.(solana.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a public key", field.Name)
+			}
+			data = append(data, key.Bytes()...)
+		default:
+			return nil, fmt.Errorf("unsupported IDL type %s for argument %q", field.typeName(), field.Name)
+		}
+	}
+
+	return data, nil
+}
+
+// BuildInstruction builds an instruction for the program from the IDL, filling account metas by name
+func (idl *IDL) BuildInstruction(programID solana.PublicKey, name string, accounts map[string]solana.PublicKey, args map[string]interface{}) (*solana.GenericInstruction, error) {
+	ix, err := idl.Instruction(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ix.EncodeArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	metas := solana.AccountMetaSlice{}
+	for _, account := range ix.Accounts {
+		key, ok := accounts[NormalizeName(account.Name)]
+		if !ok {
+			switch {
+			case account.Address != "":
+				key, err = solana.PublicKeyFromBase58(account.Address)
+				if err != nil {
+					return nil, fmt.Errorf("invalid fixed address for account %q: %w", account.Name, err)
+				}
+			case NormalizeName(account.Name) == "systemprogram":
+				key = solana.SystemProgramID
+			default:
+				return nil, fmt.Errorf("missing account %q for instruction %q", account.Name, ix.Name)
+			}
+		}
+		metas = append(metas, &solana.AccountMeta{
+			PublicKey:  key,
+			IsWritable: account.Writable || account.IsMut,
+			IsSigner:   account.Signer || account.IsSigner,
+		})
+	}
+
+	return &solana.GenericInstruction{
+		ProgID:        programID,
+		AccountValues: metas,
+		DataBytes:     data,
+	}, nil
+}
+
+var (
+	customErrorHexPattern    = regexp.MustCompile(`custom program error: 0x([0-9a-fA-F]+)`)
+	anchorErrorNumberPattern = regexp.MustCompile(`Error Number: (\d+)`)
+	// A failed transaction's status error, as printed by SignatureOutcome
+	instructionErrorPattern = regexp.MustCompile(`Custom:(\d+)`)
+)
+
+// CustomErrorCode extracts a custom program error code from an RPC or simulation error
+func CustomErrorCode(err error) (uint32, bool) {
+	msg := err.Error()
+	if m := customErrorHexPattern.FindStringSubmatch(msg); m != nil {
+		code, parseErr := strconv.ParseUint(m[1], 16, 32)
+		if parseErr == nil {
+			return uint32(code), true
+		}
+	}
+	for _, pattern := range []*regexp.Regexp{anchorErrorNumberPattern, instructionErrorPattern} {
+		if m := pattern.FindStringSubmatch(msg); m != nil {
+			code, parseErr := strconv.ParseUint(m[1], 10, 32)
+			if parseErr == nil {
+				return uint32(code), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// LookupError finds a program or Anchor framework error by code
+func (idl *IDL) LookupError(code uint32) (*IDLError, bool) {
+	for _, list := range [][]IDLError{idl.Errors, anchorFrameworkErrors} {
+		for i := range list {
+			if list[i].Code == code {
+				return &list[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// programErrors maps the program's error names to the errors operations return for them
+var programErrors = map[string]error{
+	"Unauthorized":      ErrUnauthorizedAdmin,
+	"InsufficientFunds": ErrInsufficientCampaignFunds,
+}
+
+// DecodeError annotates a transaction error with the program error it carries, if any, and
+// wraps ErrUnauthorizedAdmin, ErrInsufficientCampaignFunds, ErrInsufficientBalance or
+// ErrBlockhashExpired when it is one of those
+func (idl *IDL) DecodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "Blockhash not found"):
+		return wrapError(ErrBlockhashExpired, err)
+	case strings.Contains(msg, "insufficient lamports"), strings.Contains(msg, "no record of a prior credit"),
+		strings.Contains(msg, "insufficient funds for fee"), strings.Contains(msg, "insufficient funds for rent"):
+		return wrapError(ErrInsufficientBalance, err)
+	}
+
+	code, ok := CustomErrorCode(err)
+	if !ok {
+		return err
+	}
+	programErr, ok := idl.LookupError(code)
+	if !ok {
+		return fmt.Errorf("program error %d: %w", code, err)
+	}
+	err = fmt.Errorf("%s (%d): %s: %w", programErr.Name, programErr.Code, programErr.Msg, err)
+	if sentinel, ok := programErrors[programErr.Name]; ok && code >= 6000 {
+		return wrapError(sentinel, err)
+	}
+	return err
+}
+
+// sentinelError lets errors.Is match a sentinel while keeping the original error's message
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+func wrapError(sentinel, err error) error {
+	if errors.Is(err, sentinel) {
+		return err
+	}
+	return &sentinelError{sentinel: sentinel, err: err}
+}
+
+func (e *sentinelError) Error() string   { return e.err.Error() }
+func (e *sentinelError) Unwrap() []error { return []error{e.sentinel, e.err} }
+
+// IDLAddress derives the account Anchor stores the program's IDL in
+func IDLAddress(programID solana.PublicKey) (solana.PublicKey, error) {
+	base, _, err := solana.FindProgramAddress([][]byte{}, programID)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	return solana.CreateWithSeed(base, "anchor:idl", programID)
+}
+
+// DecodeIDLAccount inflates the IDL JSON stored in an Anchor IDL account
+func DecodeIDLAccount(data []byte) ([]byte, error) {
+	// IdlAccount { discriminator: [u8; 8], authority: Pubkey, data_len: u32, data: zlib bytes }
+	if len(data) < 44 || !bytes.Equal(data[:8], Discriminator("account", "IdlAccount")) {
+		return nil, fmt.Errorf("not an Anchor IDL account")
+	}
+	dataLen := int(binary.LittleEndian.Uint32(data[40:44]))
+	if 44+dataLen > len(data) {
+		return nil, fmt.Errorf("IDL account reports %d bytes but holds %d", dataLen, len(data)-44)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data[44 : 44+dataLen]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open compressed IDL: %w", err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate IDL: %w", err)
+	}
+	return raw, nil
+}
+
+// CampaignSeed returns the seed prefix of campaign addresses the IDL announces, from the PDA seeds
+// of create's campaign account or the exported constant, or nil when the IDL doesn't say
+func (idl *IDL) CampaignSeed() []byte {
+	if ix, err := idl.Instruction("create"); err == nil {
+		for _, account := range ix.Accounts {
+			if NormalizeName(account.Name) != "campaign" || account.PDA == nil || len(account.PDA.Seeds) == 0 {
+				continue
+			}
+			if seed := account.PDA.Seeds[0]; seed.Kind == "const" {
+				value := make([]byte, len(seed.Value))
+				for i, b := range seed.Value {
+					value[i] = byte(b)
+				}
+				return value
+			}
+		}
+	}
+
+	for _, constant := range idl.Constants {
+		if constant.Name != CampaignSeedConstant {
+			continue
+		}
+		// Anchor writes byte string constants as "[67, 65, ...]" and string ones quoted
+		var bytesValue []int
+		if err := json.Unmarshal([]byte(constant.Value), &bytesValue); err == nil {
+			value := make([]byte, len(bytesValue))
+			for i, b := range bytesValue {
+				value[i] = byte(b)
+			}
+			return value
+		}
+		var text string
+		if err := json.Unmarshal([]byte(constant.Value), &text); err == nil {
+			return []byte(text)
+		}
+	}
+	return nil
+}
+
+// DecodedArg is a single decoded instruction argument
+type DecodedArg struct {
+	Name  string
+	Type  string
+	Value interface{}
+}
+
+// MatchInstruction finds the instruction whose discriminator prefixes the given data
+func (idl *IDL) MatchInstruction(data []byte) (*IDLInstruction, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	for i := range idl.Instructions {
+		if bytes.Equal(data[:8], idl.Instructions[i].DiscriminatorBytes()) {
+			return &idl.Instructions[i], true
+		}
+	}
+	return nil, false
+}
+
+// DecodeArgs decodes the Borsh arguments that follow the discriminator
+func (ix *IDLInstruction) DecodeArgs(data []byte) ([]DecodedArg, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("instruction data too short for a discriminator")
+	}
+
+	r := NewBorshReader(data, 8)
+	args := make([]DecodedArg, 0, len(ix.Args))
+	for _, field := range ix.Args {
+		var value interface{}
+		var err error
+		switch field.typeName() {
+		case "string":
+			value, err = r.ReadString()
+		case "u64":
+			value, err = r.ReadU64()
+		case "u32":
+			value, err = r.ReadU32()
+		case "u8":
+			value, err = r.ReadU8()
+		case "bool":
+			var b uint8
+			b, err = r.ReadU8()
+			value = b != 0
+		case "pubkey", "publicKey":
+			value, err = r.ReadPublicKey()
+		default:
+			return args, fmt.Errorf("unsupported IDL type %s for argument %q", field.typeName(), field.Name)
+		}
+		if err != nil {
+			return args, fmt.Errorf("argument %q: %w", field.Name, err)
+		}
+		args = append(args, DecodedArg{Name: field.Name, Type: field.typeName(), Value: value})
+	}
+
+	if r.Offset() != len(data) {
+		return args, fmt.Errorf("%d trailing bytes after arguments", len(data)-r.Offset())
+	}
+	return args, nil
+}
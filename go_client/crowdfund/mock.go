@@ -0,0 +1,381 @@
+package crowdfund
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MockRPC is an in-memory stand-in for a cluster, to run campaign flows in tests without
+// devnet. Accounts are set with SetAccount; sent transactions are recorded, confirmed at once
+// and applied by OnSend when set.
+type MockRPC struct {
+	// OnSend, when set, applies a sent transaction to the accounts. Its error fails the
+	// transaction on chain, as a program error would.
+	OnSend func(m *MockRPC, tx *solana.Transaction) error
+
+	mu       sync.Mutex
+	accounts map[solana.PublicKey]*rpc.Account
+	sent     []*solana.Transaction
+	statuses map[solana.Signature]*rpc.SignatureStatusesResult
+	failures map[string]error
+	slot     uint64
+}
+
+// NewMockRPC returns a MockRPC with no accounts
+func NewMockRPC() *MockRPC {
+	return &MockRPC{
+		accounts: map[solana.PublicKey]*rpc.Account{},
+		statuses: map[solana.Signature]*rpc.SignatureStatusesResult{},
+		failures: map[string]error{},
+		slot:     1000,
+	}
+}
+
+// SetAccount creates or replaces an account
+func (m *MockRPC) SetAccount(address, owner solana.PublicKey, lamports uint64, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accounts[address] = &rpc.Account{Lamports: lamports, Owner: owner, Data: rpc.DataBytesOrJSONFromBytes(data)}
+}
+
+// Fund gives a system account lamports, creating it if needed
+func (m *MockRPC) Fund(address solana.PublicKey, lamports uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if account, ok := m.accounts[address]; ok {
+		account.Lamports += lamports
+		return
+	}
+	m.accounts[address] = &rpc.Account{Lamports: lamports, Owner: solana.SystemProgramID, Data: rpc.DataBytesOrJSONFromBytes(nil)}
+}
+
+// Fail makes every call of a JSON-RPC method, e.g. "sendTransaction", return err; nil
+// restores it
+func (m *MockRPC) Fail(method string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err == nil {
+		delete(m.failures, method)
+	} else {
+		m.failures[method] = err
+	}
+}
+
+// Sent returns the transactions sent so far
+func (m *MockRPC) Sent() []*solana.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*solana.Transaction(nil), m.sent...)
+}
+
+// call advances the slot and returns the failure set for the method, with the lock held
+func (m *MockRPC) call(method string) error {
+	m.mu.Lock()
+	m.slot++
+	return m.failures[method]
+}
+
+// accountView returns a copy of an account, cut to the requested data slice
+func accountView(account *rpc.Account, slice *rpc.DataSlice) *rpc.Account {
+	if account == nil {
+		return nil
+	}
+	data := account.Data.GetBinary()
+	if slice != nil {
+		offset, length := uint64(0), uint64(len(data))
+		if slice.Offset != nil {
+			offset = *slice.Offset
+		}
+		if slice.Length != nil {
+			length = *slice.Length
+		}
+		if offset > uint64(len(data)) {
+			offset = uint64(len(data))
+		}
+		if offset+length > uint64(len(data)) {
+			length = uint64(len(data)) - offset
+		}
+		data = data[offset : offset+length]
+	}
+	view := *account
+	view.Data = rpc.DataBytesOrJSONFromBytes(append([]byte(nil), data...))
+	return &view
+}
+
+// GetAccountInfo implements RPC
+func (m *MockRPC) GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	return m.GetAccountInfoWithOpts(ctx, account, nil)
+}
+
+// GetAccountInfoWithOpts implements RPC
+func (m *MockRPC) GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+	err := m.call("getAccountInfo")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	found, ok := m.accounts[account]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	var slice *rpc.DataSlice
+	if opts != nil {
+		slice = opts.DataSlice
+	}
+	return &rpc.GetAccountInfoResult{RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: m.slot}}, Value: accountView(found, slice)}, nil
+}
+
+// GetMultipleAccountsWithOpts implements RPC
+func (m *MockRPC) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	err := m.call("getMultipleAccounts")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var slice *rpc.DataSlice
+	if opts != nil {
+		slice = opts.DataSlice
+	}
+	result := &rpc.GetMultipleAccountsResult{RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: m.slot}}}
+	for _, address := range accounts {
+		result.Value = append(result.Value, accountView(m.accounts[address], slice))
+	}
+	return result, nil
+}
+
+// GetProgramAccountsWithOpts implements RPC, applying dataSize and memcmp filters
+func (m *MockRPC) GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error) {
+	err := m.call("getProgramAccounts")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var result rpc.GetProgramAccountsResult
+accounts:
+	for address, account := range m.accounts {
+		if !account.Owner.Equals(program) {
+			continue
+		}
+		data := account.Data.GetBinary()
+		var slice *rpc.DataSlice
+		if opts != nil {
+			slice = opts.DataSlice
+			for _, filter := range opts.Filters {
+				if filter.DataSize != 0 && uint64(len(data)) != filter.DataSize {
+					continue accounts
+				}
+				if memcmp := filter.Memcmp; memcmp != nil {
+					end := memcmp.Offset + uint64(len(memcmp.Bytes))
+					if end > uint64(len(data)) || !bytes.Equal(data[memcmp.Offset:end], memcmp.Bytes) {
+						continue accounts
+					}
+				}
+			}
+		}
+		result = append(result, &rpc.KeyedAccount{Pubkey: address, Account: accountView(account, slice)})
+	}
+	return result, nil
+}
+
+// GetBalance implements RPC
+func (m *MockRPC) GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error) {
+	err := m.call("getBalance")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	result := &rpc.GetBalanceResult{RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: m.slot}}}
+	if found, ok := m.accounts[account]; ok {
+		result.Value = found.Lamports
+	}
+	return result, nil
+}
+
+// GetMinimumBalanceForRentExemption implements RPC with the mainnet rent rate
+func (m *MockRPC) GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error) {
+	err := m.call("getMinimumBalanceForRentExemption")
+	defer m.mu.Unlock()
+	return (dataSize + 128) * 6960, err
+}
+
+// GetSlot implements RPC
+func (m *MockRPC) GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	err := m.call("getSlot")
+	defer m.mu.Unlock()
+	return m.slot, err
+}
+
+// GetBlockHeight implements RPC; block height follows the slot
+func (m *MockRPC) GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	err := m.call("getBlockHeight")
+	defer m.mu.Unlock()
+	return m.slot, err
+}
+
+// GetLatestBlockhash implements RPC with a new blockhash per slot, valid for 150 blocks
+func (m *MockRPC) GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error) {
+	err := m.call("getLatestBlockhash")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var blockhash solana.Hash
+	binary.LittleEndian.PutUint64(blockhash[:], m.slot)
+	return &rpc.GetLatestBlockhashResult{
+		RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: m.slot}},
+		Value:      &rpc.LatestBlockhashResult{Blockhash: blockhash, LastValidBlockHeight: m.slot + 150},
+	}, nil
+}
+
+// GetRecentPrioritizationFees implements RPC; the mock network is never congested
+func (m *MockRPC) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	err := m.call("getRecentPrioritizationFees")
+	defer m.mu.Unlock()
+	return nil, err
+}
+
+// GetSignatureStatuses implements RPC: sent transactions are confirmed
+func (m *MockRPC) GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, signatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error) {
+	err := m.call("getSignatureStatuses")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	result := &rpc.GetSignatureStatusesResult{RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: m.slot}}}
+	for _, signature := range signatures {
+		result.Value = append(result.Value, m.statuses[signature])
+	}
+	return result, nil
+}
+
+// GetSignaturesForAddressWithOpts implements RPC, newest first
+func (m *MockRPC) GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	err := m.call("getSignaturesForAddress")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var result []*rpc.TransactionSignature
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		tx := m.sent[i]
+		if found, _ := tx.Message.HasAccount(account); !found {
+			continue
+		}
+		status := m.statuses[tx.Signatures[0]]
+		blockTime := solana.UnixTimeSeconds(time.Now().Unix())
+		result = append(result, &rpc.TransactionSignature{Signature: tx.Signatures[0], Slot: status.Slot, Err: status.Err, BlockTime: &blockTime, ConfirmationStatus: status.ConfirmationStatus})
+		if opts != nil && opts.Limit != nil && len(result) == *opts.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// GetTransaction implements RPC for sent transactions
+func (m *MockRPC) GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	err := m.call("getTransaction")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range m.sent {
+		if tx.Signatures[0] != signature {
+			continue
+		}
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		var envelope rpc.TransactionResultEnvelope
+		encoded, _ := json.Marshal([]string{base64.StdEncoding.EncodeToString(raw), "base64"})
+		if err := json.Unmarshal(encoded, &envelope); err != nil {
+			return nil, err
+		}
+		status := m.statuses[signature]
+		return &rpc.GetTransactionResult{
+			Slot:        status.Slot,
+			Transaction: &envelope,
+			Meta:        &rpc.TransactionMeta{Err: status.Err, Fee: 5000 * uint64(len(tx.Signatures))},
+		}, nil
+	}
+	return nil, rpc.ErrNotFound
+}
+
+// SendTransactionWithOpts implements RPC: the transaction must be fully signed, and lands
+// in the current slot
+func (m *MockRPC) SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error) {
+	if err := m.call("sendTransaction"); err != nil {
+		m.mu.Unlock()
+		return solana.Signature{}, err
+	}
+	slot := m.slot
+	m.mu.Unlock()
+
+	if err := tx.VerifySignatures(); err != nil {
+		return solana.Signature{}, fmt.Errorf("transaction signature verification failure: %w", err)
+	}
+	var failure interface{}
+	if m.OnSend != nil {
+		if err := m.OnSend(m, tx); err != nil {
+			failure = err.Error()
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, tx)
+	m.statuses[tx.Signatures[0]] = &rpc.SignatureStatusesResult{Slot: slot, Err: failure, ConfirmationStatus: rpc.ConfirmationStatusConfirmed}
+	return tx.Signatures[0], nil
+}
+
+// SimulateTransactionWithOpts implements RPC: the transaction succeeds, changing nothing
+func (m *MockRPC) SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error) {
+	err := m.call("simulateTransaction")
+	defer m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.SimulateTransactionResponse{RPCContext: rpc.RPCContext{Context: rpc.Context{Slot: m.slot}}, Value: &rpc.SimulateTransactionResult{}}, nil
+}
+
+// MockSigner is a Signer holding a throwaway key, counting its signatures
+type MockSigner struct {
+	Key solana.PrivateKey
+
+	mu     sync.Mutex
+	signed int
+}
+
+// NewMockSigner returns a MockSigner with a new random key
+func NewMockSigner() *MockSigner {
+	return &MockSigner{Key: solana.NewWallet().PrivateKey}
+}
+
+// Address implements Signer
+func (s *MockSigner) Address() solana.PublicKey {
+	return s.Key.PublicKey()
+}
+
+// SignMessage implements Signer
+func (s *MockSigner) SignMessage(message []byte) (solana.Signature, error) {
+	s.mu.Lock()
+	s.signed++
+	s.mu.Unlock()
+	return s.Key.Sign(message)
+}
+
+// Signed returns how many messages the signer has signed
+func (s *MockSigner) Signed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signed
+}
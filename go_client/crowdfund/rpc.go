@@ -0,0 +1,82 @@
+package crowdfund
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// RPC is the part of the Solana JSON-RPC API the package uses. *rpc.Client implements it;
+// MockRPC stands in for a cluster in tests.
+type RPC interface {
+	GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error)
+	GetAccountInfoWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error)
+	GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error)
+	GetProgramAccountsWithOpts(ctx context.Context, program solana.PublicKey, opts *rpc.GetProgramAccountsOpts) (rpc.GetProgramAccountsResult, error)
+	GetBalance(ctx context.Context, account solana.PublicKey, commitment rpc.CommitmentType) (*rpc.GetBalanceResult, error)
+	GetMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64, commitment rpc.CommitmentType) (uint64, error)
+	GetSlot(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	GetBlockHeight(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	GetLatestBlockhash(ctx context.Context, commitment rpc.CommitmentType) (*rpc.GetLatestBlockhashResult, error)
+	GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error)
+	GetSignatureStatuses(ctx context.Context, searchTransactionHistory bool, signatures ...solana.Signature) (*rpc.GetSignatureStatusesResult, error)
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+	SendTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts rpc.TransactionOpts) (solana.Signature, error)
+	SimulateTransactionWithOpts(ctx context.Context, tx *solana.Transaction, opts *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResponse, error)
+}
+
+// Signer holds the key of a wallet, or reaches whatever does: a hardware wallet, a threshold
+// group, a remote service. MockSigner is one for tests.
+type Signer interface {
+	// Address is the wallet's public key
+	Address() solana.PublicKey
+	// SignMessage signs a serialized transaction message
+	SignMessage(message []byte) (solana.Signature, error)
+}
+
+// SignerIndex returns the position of signer's signature in tx, or -1 if it needn't sign
+func SignerIndex(tx *solana.Transaction, signer solana.PublicKey) int {
+	for i, key := range tx.Message.AccountKeys[:tx.Message.Header.NumRequiredSignatures] {
+		if key.Equals(signer) {
+			return i
+		}
+	}
+	return -1
+}
+
+// SignTransaction fills in the signatures of signer and of keys, and fails unless that
+// completes the transaction. Signatures already present for other signers are kept.
+func SignTransaction(tx *solana.Transaction, signer Signer, keys []solana.PrivateKey) error {
+	if _, err := tx.PartialSign(func(key solana.PublicKey) *solana.PrivateKey {
+		for i := range keys {
+			if key.Equals(keys[i].PublicKey()) {
+				return &keys[i]
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	signerKeys := tx.Message.AccountKeys[:tx.Message.Header.NumRequiredSignatures]
+	if index := SignerIndex(tx, signer.Address()); index >= 0 {
+		message, err := tx.Message.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to encode message: %w", err)
+		}
+		signature, err := signer.SignMessage(message)
+		if err != nil {
+			return err
+		}
+		tx.Signatures[index] = signature
+	}
+	for i, key := range signerKeys {
+		if tx.Signatures[i].IsZero() {
+			return fmt.Errorf("signer key %q not found", key)
+		}
+	}
+	return nil
+}
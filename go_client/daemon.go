@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// defaultSnapshotInterval is how often the daemon snapshots tracked campaigns
+const defaultSnapshotInterval = 15 * time.Minute
+
+// DaemonConfig configures the long-running daemon in config.json
+type DaemonConfig struct {
+	Campaigns        []string `json:"campaigns,omitempty"`        // campaign addresses to track; defaults to the saved campaign
+	SnapshotInterval string   `json:"snapshotInterval,omitempty"` // e.g. "15m"
+
+	RPCProxy *RPCProxyConfig `json:"rpcProxy,omitempty"`
+}
+
+// Daemon runs periodic background jobs against the tracked campaigns
+type Daemon struct {
+	app           *SolanaDApp
+	store         Store
+	campaigns     []solana.PublicKey
+	interval      time.Duration
+	alerts        []AlertRule
+	policies      []AutoWithdrawPolicy
+	closePolicies []AutoClosePolicy
+	dryRun        bool // never sign, only record what auto-withdraw would do
+	notifiers     Notifiers
+	digest        *DigestConfig
+	mqtt          *MQTTConfig
+	backup        *BackupConfig
+	rpcProxy      *RPCProxy
+	sites         []SiteConfig
+	started       time.Time
+
+	lastBackup     time.Time   // the first backup runs at the first scheduled time after startup
+	sitesGenerated []time.Time // when each static page was last regenerated
+}
+
+// NewDaemon creates a daemon tracking the given campaigns, with alert rules and notification backends from config.json
+func NewDaemon(app *SolanaDApp, store Store, campaigns []solana.PublicKey, interval time.Duration, dryRun bool) (*Daemon, error) {
+	notifiers, err := NewNotifiers(app.config.Notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := append([]AlertRule(nil), app.config.Alerts...)
+	for i := range alerts {
+		if err := alerts[i].prepare(); err != nil {
+			return nil, err
+		}
+		// Campaign rules are evaluated against snapshots, so their campaigns must be tracked
+		if alerts[i].Type != AlertBalanceBelow && !containsKey(campaigns, alerts[i].account) {
+			campaigns = append(campaigns, alerts[i].account)
+		}
+	}
+
+	policies := append([]AutoWithdrawPolicy(nil), app.config.AutoWithdraw...)
+	for i := range policies {
+		if err := policies[i].prepare(); err != nil {
+			return nil, err
+		}
+		if app.wallet == nil && !policies[i].DryRun && !dryRun {
+			return nil, fmt.Errorf("auto-withdraw for %s needs the admin wallet: pass -wallet, or use dry-run", policies[i].Campaign)
+		}
+	}
+
+	closePolicies := append([]AutoClosePolicy(nil), app.config.AutoClose...)
+	for i := range closePolicies {
+		if err := closePolicies[i].prepare(); err != nil {
+			return nil, err
+		}
+		if app.wallet == nil && !closePolicies[i].DryRun && !dryRun {
+			return nil, fmt.Errorf("auto-close for %s needs the admin wallet: pass -wallet, or use dry-run", closePolicies[i].Campaign)
+		}
+	}
+
+	var digest *DigestConfig
+	if app.config.Digest != nil {
+		digest = app.config.Digest
+		if err := digest.prepare(); err != nil {
+			return nil, err
+		}
+	}
+
+	mqttConfig := app.config.MQTT
+	if mqttConfig != nil {
+		if err := mqttConfig.prepare(); err != nil {
+			return nil, err
+		}
+	}
+
+	backup := app.config.Backup
+	if backup != nil {
+		if err := backup.prepare(); err != nil {
+			return nil, err
+		}
+	}
+
+	var rpcProxy *RPCProxy
+	if app.config.Daemon != nil && app.config.Daemon.RPCProxy != nil {
+		if err := app.config.Daemon.RPCProxy.prepare(); err != nil {
+			return nil, err
+		}
+		rpcProxy = NewRPCProxy(app.config.Daemon.RPCProxy, app, campaigns)
+	}
+
+	sites := append([]SiteConfig(nil), app.config.Sites...)
+	for i := range sites {
+		if err := sites[i].prepare(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	return &Daemon{
+		app:           app,
+		store:         store,
+		campaigns:     campaigns,
+		interval:      interval,
+		alerts:        alerts,
+		policies:      policies,
+		closePolicies: closePolicies,
+		dryRun:        dryRun,
+		notifiers:     notifiers,
+		digest:        digest,
+		mqtt:          mqttConfig,
+		backup:        backup,
+		rpcProxy:      rpcProxy,
+		sites:         sites,
+		started:       now,
+
+		lastBackup:     now,
+		sitesGenerated: make([]time.Time, len(sites)),
+	}, nil
+}
+
+// Run snapshots the tracked campaigns immediately and then on every interval until ctx is cancelled.
+// Schedules, the retry queue, the email digest, backups, static pages and campaign deadlines are checked every minute.
+func (d *Daemon) Run(ctx context.Context) error {
+	fmt.Printf("🛰️  Daemon tracking %d campaign(s), snapshot every %s, %d alert rule(s), %d auto-withdraw and %d auto-close policies, %d schedule(s)\n",
+		len(d.campaigns), d.interval, len(d.alerts), len(d.policies), len(d.closePolicies), len(d.store.Schedules()))
+	if d.dryRun {
+		fmt.Println("🧪 Dry run: auto-withdrawals are recorded but not signed")
+	}
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	scheduler := time.NewTicker(time.Minute)
+	defer scheduler.Stop()
+
+	for _, notifier := range d.notifiers {
+		if background, ok := notifier.(backgroundNotifier); ok {
+			go background.Run(ctx, d)
+		}
+	}
+	if d.mqtt != nil || d.app.config.EventBus != nil {
+		go d.runEventStream(ctx)
+	}
+	if d.rpcProxy != nil {
+		go func() {
+			if err := d.rpcProxy.Run(ctx); err != nil {
+				log.Printf("RPC proxy stopped: %v", err)
+			}
+		}()
+	}
+
+	d.runJobs(ctx)
+	d.runDueSchedules(ctx, time.Now()) // Catch up on runs missed while stopped
+	d.runRetryQueue(ctx, time.Now())
+	d.runSites(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("👋 Daemon stopped")
+			return nil
+		case <-ticker.C:
+			d.runJobs(ctx)
+		case now := <-scheduler.C:
+			d.runDueSchedules(ctx, now)
+			d.runRetryQueue(ctx, now)
+			d.runDigest(ctx, now)
+			d.runBackup(ctx, now)
+			d.runSites(ctx, now)
+			d.notifyEndedCampaigns(ctx, now)
+		}
+	}
+}
+
+// runJobs runs the interval jobs: snapshots, then the alerts and policies that depend on them
+func (d *Daemon) runJobs(ctx context.Context) {
+	if err := d.snapshot(ctx); err != nil {
+		log.Printf("Snapshot failed: %v", err)
+	}
+	d.evaluateAlerts(ctx)
+	d.runAutoWithdrawals(ctx)
+	d.runAutoClose(ctx, time.Now())
+}
+
+// snapshot records the current balance and raised total of every tracked campaign
+func (d *Daemon) snapshot(ctx context.Context) error {
+	accounts, err := d.app.client.GetMultipleAccountsWithOpts(ctx, d.campaigns, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch campaign accounts: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var snapshots []Snapshot
+	for i, account := range accounts.Value {
+		if account == nil {
+			log.Printf("Campaign %s not found, skipping", d.campaigns[i])
+			continue
+		}
+		campaign, err := crowdfund.DecodeCampaign(account.Data.GetBinary())
+		if err != nil {
+			log.Printf("Campaign %s could not be decoded: %v", d.campaigns[i], err)
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			Campaign:      d.campaigns[i],
+			Name:          campaign.Name,
+			Time:          now,
+			Lamports:      account.Lamports,
+			AmountDonated: campaign.AmountDonated,
+		})
+	}
+
+	return d.store.AddSnapshots(snapshots...)
+}
+
+// CampaignDiff is how a campaign changed between two snapshots
+type CampaignDiff struct {
+	Campaign solana.PublicKey
+	Name     string
+	From, To Snapshot
+}
+
+// Raised is the amount donated between the two snapshots
+func (d CampaignDiff) Raised() uint64 {
+	if d.To.AmountDonated < d.From.AmountDonated {
+		return 0
+	}
+	return d.To.AmountDonated - d.From.AmountDonated
+}
+
+// BalanceChange is the signed change in the campaign's lamport balance
+func (d CampaignDiff) BalanceChange() int64 {
+	return int64(d.To.Lamports) - int64(d.From.Lamports)
+}
+
+// DiffSnapshots compares a campaign's snapshots closest to from and to. When there is no snapshot
+// as old as from, the oldest snapshot in the window is used instead.
+func DiffSnapshots(store Store, campaign solana.PublicKey, from, to time.Time) (CampaignDiff, bool) {
+	end, ok := store.SnapshotAt(campaign, to)
+	if !ok {
+		return CampaignDiff{}, false
+	}
+	start, ok := store.SnapshotAt(campaign, from)
+	if !ok {
+		window := store.Snapshots(campaign, from, to)
+		if len(window) == 0 {
+			return CampaignDiff{}, false
+		}
+		start = window[0]
+	}
+	return CampaignDiff{Campaign: campaign, Name: end.Name, From: start, To: end}, true
+}
+
+// containsKey reports whether keys contains key
+func containsKey(keys []solana.PublicKey, key solana.PublicKey) bool {
+	for _, k := range keys {
+		if k.Equals(key) {
+			return true
+		}
+	}
+	return false
+}
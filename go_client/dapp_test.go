@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// newTestDApp runs a dApp on a MockRPC in a scratch directory, with a funded wallet
+func newTestDApp(t *testing.T) (*SolanaDApp, *crowdfund.MockRPC) {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+
+	mock := crowdfund.NewMockRPC()
+	signer := crowdfund.NewMockSigner()
+	mock.Fund(signer.Address(), 10*solana.LAMPORTS_PER_SOL)
+	return NewDApp(defaultConfig(), mock, mock, signer), mock
+}
+
+// borshString encodes a string as Borsh does: a u32 length, then the bytes
+func borshString(s string) []byte {
+	out := binary.LittleEndian.AppendUint32(nil, uint32(len(s)))
+	return append(out, s...)
+}
+
+// borshU64 encodes a little-endian u64
+func borshU64(v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, v)
+}
+
+// campaignData is a campaign account as the program lays it out
+func campaignData(admin solana.PublicKey, name, description string, donated uint64) []byte {
+	data := crowdfund.Discriminator("account", "Campaign")
+	data = append(data, admin.Bytes()...)
+	data = append(data, borshString(name)...)
+	data = append(data, borshString(description)...)
+	data = append(data, borshU64(donated)...)
+	return append(data, 255)
+}
+
+// addCampaign puts a campaign of the app's wallet on the mock cluster
+func addCampaign(t *testing.T, app *SolanaDApp, mock *crowdfund.MockRPC, name string, lamports uint64) solana.PublicKey {
+	t.Helper()
+	address, _, err := app.CreateCampaignPDA(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.SetAccount(address, app.programID, lamports, campaignData(app.wallet.Address(), name, "a description", 0))
+	return address
+}
+
+// sentInstruction is the one program instruction of the only transaction sent
+func sentInstruction(t *testing.T, app *SolanaDApp, mock *crowdfund.MockRPC) ([]*solana.AccountMeta, []byte) {
+	t.Helper()
+	sent := mock.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d transactions, want 1", len(sent))
+	}
+	tx := sent[0]
+	var found []*solana.AccountMeta
+	var data []byte
+	for i := range tx.Message.Instructions {
+		ix := &tx.Message.Instructions[i]
+		program, err := tx.Message.ResolveProgramIDIndex(ix.ProgramIDIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !program.Equals(app.programID) {
+			continue
+		}
+		if found != nil {
+			t.Fatal("more than one crowdfunding instruction")
+		}
+		if found, err = ix.ResolveInstructionAccounts(&tx.Message); err != nil {
+			t.Fatal(err)
+		}
+		data = ix.Data
+	}
+	if found == nil {
+		t.Fatal("no crowdfunding instruction sent")
+	}
+	return found, data
+}
+
+// checkAccounts compares an instruction's accounts with the expected addresses and flags
+func checkAccounts(t *testing.T, got []*solana.AccountMeta, want []*solana.AccountMeta) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("instruction has %d accounts, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].PublicKey.Equals(want[i].PublicKey) || got[i].IsWritable != want[i].IsWritable || got[i].IsSigner != want[i].IsSigner {
+			t.Errorf("account %d = %s (writable %v, signer %v), want %s (writable %v, signer %v)", i,
+				got[i].PublicKey, got[i].IsWritable, got[i].IsSigner, want[i].PublicKey, want[i].IsWritable, want[i].IsSigner)
+		}
+	}
+}
+
+func TestCreateCampaignInstruction(t *testing.T) {
+	app, mock := newTestDApp(t)
+	if err := app.CreateCampaign("roof", "Fix the school roof"); err != nil {
+		t.Fatal(err)
+	}
+	address, _, err := app.CreateCampaignPDA("roof")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, data := sentInstruction(t, app, mock)
+	checkAccounts(t, accounts, []*solana.AccountMeta{
+		solana.Meta(address).WRITE(),
+		solana.Meta(app.wallet.Address()).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+	})
+	want := append(crowdfund.Discriminator("global", "create"), borshString("roof")...)
+	want = append(want, borshString("Fix the school roof")...)
+	if !bytes.Equal(data, want) {
+		t.Errorf("instruction data = %x, want %x", data, want)
+	}
+	if app.campaignAddress == nil || !app.campaignAddress.Equals(address) {
+		t.Errorf("saved campaign = %v, want %s", app.campaignAddress, address)
+	}
+}
+
+func TestDonateInstruction(t *testing.T) {
+	app, mock := newTestDApp(t)
+	campaign := addCampaign(t, app, mock, "roof", solana.LAMPORTS_PER_SOL)
+	if err := app.DonateToCampaign("roof", campaign.String(), 250_000_000, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, data := sentInstruction(t, app, mock)
+	checkAccounts(t, accounts, []*solana.AccountMeta{
+		solana.Meta(campaign).WRITE(),
+		solana.Meta(app.wallet.Address()).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+	})
+	want := append(crowdfund.Discriminator("global", "donate"), borshString("roof")...)
+	want = append(want, borshU64(250_000_000)...)
+	if !bytes.Equal(data, want) {
+		t.Errorf("instruction data = %x, want %x", data, want)
+	}
+}
+
+func TestWithdrawInstruction(t *testing.T) {
+	app, mock := newTestDApp(t)
+	campaign := addCampaign(t, app, mock, "roof", 2*solana.LAMPORTS_PER_SOL)
+	if err := app.WithdrawFromCampaign("roof", campaign.String(), solana.LAMPORTS_PER_SOL); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, data := sentInstruction(t, app, mock)
+	checkAccounts(t, accounts, []*solana.AccountMeta{
+		solana.Meta(campaign).WRITE(),
+		solana.Meta(app.wallet.Address()).WRITE().SIGNER(),
+	})
+	want := append(crowdfund.Discriminator("global", "withdraw"), borshString("roof")...)
+	want = append(want, borshU64(solana.LAMPORTS_PER_SOL)...)
+	if !bytes.Equal(data, want) {
+		t.Errorf("instruction data = %x, want %x", data, want)
+	}
+}
+
+func TestSendFailureIsReturned(t *testing.T) {
+	app, mock := newTestDApp(t)
+	campaign := addCampaign(t, app, mock, "roof", solana.LAMPORTS_PER_SOL)
+	refused := errors.New("node refused the transaction")
+	mock.Fail("sendTransaction", refused)
+	if err := app.DonateToCampaign("roof", campaign.String(), 1000, ""); !errors.Is(err, refused) {
+		t.Fatalf("DonateToCampaign error = %v, want %v", err, refused)
+	}
+	if sent := mock.Sent(); len(sent) != 0 {
+		t.Fatalf("sent %d transactions, want none", len(sent))
+	}
+}
+
+func TestFetchCampaign(t *testing.T) {
+	app, mock := newTestDApp(t)
+	campaign := addCampaign(t, app, mock, "roof", solana.LAMPORTS_PER_SOL)
+	got, err := app.FetchCampaign(campaign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "roof" || got.Description != "a description" || !got.Admin.Equals(app.wallet.Address()) {
+		t.Errorf("FetchCampaign = %+v", got)
+	}
+}
+
+func TestFetchCampaignNotFound(t *testing.T) {
+	app, _ := newTestDApp(t)
+	_, err := app.FetchCampaign(solana.NewWallet().PublicKey())
+	if !errors.Is(err, crowdfund.ErrCampaignNotFound) {
+		t.Fatalf("FetchCampaign error = %v, want ErrCampaignNotFound", err)
+	}
+}
+
+func TestFetchCampaignNotACampaignAccount(t *testing.T) {
+	app, mock := newTestDApp(t)
+	wallet := solana.NewWallet().PublicKey()
+	mock.Fund(wallet, solana.LAMPORTS_PER_SOL)
+	if _, err := app.FetchCampaign(wallet); !errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+		t.Fatalf("FetchCampaign of a wallet: error = %v, want ErrNotACampaignAccount", err)
+	}
+
+	// Owned by the program, but not holding a campaign
+	other := solana.NewWallet().PublicKey()
+	mock.SetAccount(other, app.programID, solana.LAMPORTS_PER_SOL, []byte("not a campaign at all"))
+	if _, err := app.FetchCampaign(other); !errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+		t.Fatalf("FetchCampaign of program data: error = %v, want ErrNotACampaignAccount", err)
+	}
+}
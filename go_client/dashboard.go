@@ -0,0 +1,417 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/mr-tron/base58"
+)
+
+// uiFiles holds the dashboard template and its static assets, compiled into the binary
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// dashboardTemplate is the admin dashboard page; its script and styles are served from ui/static
+var dashboardTemplate = template.Must(template.ParseFS(uiFiles, "ui/dashboard.html"))
+
+// dashboardCookie keeps the dashboard session after the token link is opened once
+const dashboardCookie = "crowdfunding_ui"
+
+// dashboardWithdrawalTTL is how long a built withdrawal waits for the browser wallet's signature
+const dashboardWithdrawalTTL = 2 * time.Minute
+
+// dashboard is the admin web UI of `serve -ui`. Like the rest of the server it holds no keys:
+// withdrawals are built here, checked against the withdrawal policy, and signed by the admin's
+// browser wallet.
+type dashboard struct {
+	app   *SolanaDApp
+	token string       // read-only access; withdrawals need an API key with the withdraw scope
+	keys  *apiKeyStore // set by register
+
+	mu      sync.Mutex
+	pending map[string]pendingWithdrawal // id -> transaction waiting for its signature
+}
+
+// pendingWithdrawal is a built withdrawal waiting for the browser wallet's signature
+type pendingWithdrawal struct {
+	tx      *solana.Transaction
+	expires time.Time
+}
+
+// policyApprovalNeeded asks the dashboard user for what the policy requires before a withdrawal
+type policyApprovalNeeded struct {
+	Rules   []string
+	Confirm bool // retype the amount
+	TOTP    bool // an authenticator code
+}
+
+func (e *policyApprovalNeeded) Error() string {
+	return "approval required by " + strings.Join(e.Rules, ", ")
+}
+
+// newDashboard creates the dashboard, generating an access token unless one is given
+func newDashboard(app *SolanaDApp, token string) (*dashboard, error) {
+	if token == "" {
+		tokenBytes := make([]byte, 16)
+		if _, err := rand.Read(tokenBytes); err != nil {
+			return nil, fmt.Errorf("failed to generate dashboard token: %w", err)
+		}
+		token = hex.EncodeToString(tokenBytes)
+	}
+	return &dashboard{app: app, token: token, pending: map[string]pendingWithdrawal{}}, nil
+}
+
+// register adds the dashboard routes under /ui/
+func (d *dashboard) register(mux *http.ServeMux, keys *apiKeyStore) {
+	d.keys = keys
+	mux.HandleFunc("GET /ui/{$}", d.handlePage)
+	mux.Handle("GET /ui/static/", http.FileServer(http.FS(uiFiles)))
+	mux.HandleFunc("GET /ui/api/campaigns", d.api(scopeRead, d.handleCampaigns))
+	mux.HandleFunc("GET /ui/api/campaigns/{address}/history", d.api(scopeRead, d.handleHistory))
+	mux.HandleFunc("POST /ui/api/withdrawals", d.api(scopeWithdraw, d.handleBuildWithdrawal))
+	mux.HandleFunc("POST /ui/api/withdrawals/{id}/submit", d.api(scopeWithdraw, d.handleSubmitWithdrawal))
+}
+
+// validToken compares a presented token with the dashboard's in constant time
+func (d *dashboard) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(d.token)) == 1
+}
+
+// authorize checks a presented credential: the dashboard token can only read, an API key can do
+// what its scopes allow. status is 0 when the scope is granted.
+func (d *dashboard) authorize(r *http.Request, token, scope string) (status int, msg string) {
+	if d.validToken(token) {
+		if scope == scopeRead {
+			return 0, ""
+		}
+		return http.StatusForbidden, "the dashboard token is read-only: open the dashboard with an API key that has the " + scope + " scope"
+	}
+	key := d.keys.lookup(r.Context(), token)
+	switch {
+	case key == nil || key.Revoked != nil:
+		return http.StatusForbidden, "invalid dashboard token"
+	case !key.allows(scope) && !(scope == scopeRead && key.allows(scopeWithdraw)):
+		return http.StatusForbidden, "the API key lacks the " + scope + " scope"
+	}
+	return 0, ""
+}
+
+// handlePage serves the dashboard. Opening the token link sets a session cookie and drops the
+// token from the address bar.
+func (d *dashboard) handlePage(w http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		if status, _ := d.authorize(r, token, scopeRead); status != 0 {
+			http.Error(w, "invalid dashboard token", http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: dashboardCookie, Value: token, Path: "/ui/", HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteStrictMode})
+		http.Redirect(w, r, "/ui/", http.StatusSeeOther)
+		return
+	}
+	cookie, err := r.Cookie(dashboardCookie)
+	if err != nil {
+		http.Error(w, "open the dashboard link printed by `serve -ui`", http.StatusForbidden)
+		return
+	}
+	if status, _ := d.authorize(r, cookie.Value, scopeRead); status != 0 {
+		http.Error(w, "open the dashboard link printed by `serve -ui`", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	dashboardTemplate.Execute(w, map[string]string{"Token": cookie.Value, "Cluster": d.app.config.Cluster})
+}
+
+// api wraps a dashboard JSON handler that needs the scope. API calls carry the token or API key
+// in a header, which other sites cannot make a browser send.
+func (d *dashboard) api(scope string, handler func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if status, msg := d.authorize(r, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), scope); status != 0 {
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": msg})
+			return
+		}
+
+		result, err := handler(r)
+		if err == nil {
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+		var approval *policyApprovalNeeded
+		var apiErr *apiError
+		switch {
+		case errors.As(err, &approval):
+			w.WriteHeader(http.StatusPreconditionRequired)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "confirm": approval.Confirm, "totp": approval.TOTP})
+			return
+		case errors.As(err, &apiErr):
+			w.WriteHeader(apiErr.status)
+		default:
+			logf(r.Context(), "Dashboard error on %s: %v", r.URL.Path, err)
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error(), "requestId": requestIDFrom(r.Context())})
+	}
+}
+
+// campaigns lists the tracked campaigns and those in the lifecycle registry
+func (d *dashboard) campaigns() []solana.PublicKey {
+	seen := map[solana.PublicKey]bool{}
+	var campaigns []solana.PublicKey
+	tracked, _ := d.app.trackedCampaigns()
+	for _, campaign := range tracked {
+		seen[campaign] = true
+		campaigns = append(campaigns, campaign)
+	}
+	records, _ := loadCampaignRecords()
+	var recorded []solana.PublicKey
+	for address := range records {
+		if campaign, err := solana.PublicKeyFromBase58(address); err == nil && !seen[campaign] {
+			recorded = append(recorded, campaign)
+		}
+	}
+	sort.Slice(recorded, func(i, j int) bool { return recorded[i].String() < recorded[j].String() })
+	return append(campaigns, recorded...)
+}
+
+// dashboardCampaign is one campaign's figures on the dashboard
+type dashboardCampaign struct {
+	CampaignAccount
+	Goal         uint64 `json:"goal,omitempty"`
+	Withdrawable uint64 `json:"withdrawable"` // balance above the rent-exempt minimum
+	ExplorerURL  string `json:"explorerUrl"`
+	Error        string `json:"error,omitempty"`
+}
+
+func (d *dashboard) handleCampaigns(r *http.Request) (interface{}, error) {
+	addresses := d.campaigns()
+	records, _ := loadCampaignRecords()
+	campaigns := make([]dashboardCampaign, len(addresses))
+	forEachParallel(len(addresses), defaultWorkers, func(i int) error {
+		c := &campaigns[i]
+		c.Address, c.ExplorerURL = addresses[i], d.app.AddressURL(addresses[i].String())
+		account, err := d.app.getAccount(addresses[i])
+		if err == nil && account == nil {
+			err = crowdfund.ErrCampaignNotFound
+		}
+		if err == nil {
+			c.Campaign, err = d.app.FetchCampaign(addresses[i])
+		}
+		var rent uint64
+		if err == nil {
+			rent, err = d.app.client.GetMinimumBalanceForRentExemption(r.Context(), uint64(len(account.Data)), rpc.CommitmentConfirmed)
+		}
+		if err != nil {
+			c.Error = err.Error()
+			return nil
+		}
+		c.Lamports = account.Lamports
+		if c.Lamports > rent {
+			c.Withdrawable = c.Lamports - rent
+		}
+		records.annotate(&c.CampaignAccount)
+		if record := records.get(addresses[i]); record != nil {
+			c.Goal = record.Goal
+		}
+		return nil
+	})
+	return campaigns, nil
+}
+
+func (d *dashboard) handleHistory(r *http.Request) (interface{}, error) {
+	address, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid campaign address"}
+	}
+	activity, err := d.app.GetCampaignActivity(address, 100)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]dashboardActivity, len(activity))
+	for i, a := range activity {
+		history[i] = dashboardActivity{CampaignActivity: a, ExplorerURL: d.app.TxURL(a.Signature)}
+	}
+	return history, nil
+}
+
+// dashboardActivity is a row of a campaign's history on the dashboard
+type dashboardActivity struct {
+	CampaignActivity
+	ExplorerURL string `json:"explorerUrl"`
+}
+
+// handleBuildWithdrawal builds a withdrawal for the admin's browser wallet once the withdrawal
+// policy allows it. Rules asking for a second confirmation or an authenticator code are answered
+// in the dashboard; rules asking for a security key touch need the CLI.
+func (d *dashboard) handleBuildWithdrawal(r *http.Request) (interface{}, error) {
+	var body struct {
+		Campaign      string `json:"campaign"`
+		Amount        string `json:"amount"` // SOL
+		PublicKey     string `json:"publicKey"`
+		ConfirmAmount string `json:"confirmAmount,omitempty"`
+		TOTPCode      string `json:"totpCode,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid request"}
+	}
+	campaign, err := solana.PublicKeyFromBase58(body.Campaign)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid campaign address"}
+	}
+	signer, err := solana.PublicKeyFromBase58(body.PublicKey)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid wallet public key"}
+	}
+	amount, err := parseSOL(body.Amount)
+	if err != nil || amount == 0 {
+		return nil, &apiError{http.StatusBadRequest, "invalid amount"}
+	}
+	fetched, err := d.app.FetchCampaign(campaign)
+	if err != nil {
+		return nil, &apiError{http.StatusNotFound, "campaign not found"}
+	}
+	if !fetched.Admin.Equals(signer) {
+		return nil, &apiError{http.StatusForbidden, fmt.Sprintf("only the campaign admin %s can withdraw", fetched.Admin)}
+	}
+
+	tx, _, err := d.app.buildForSigner(BrowserSignRequest{Action: "withdraw", Campaign: campaign, Name: fetched.Name, Amount: amount}, signer)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkPolicy(tx, body.ConfirmAmount, body.TOTPCode); err != nil {
+		return nil, err
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	id := hex.EncodeToString(idBytes)
+	d.mu.Lock()
+	now := time.Now()
+	for key, pending := range d.pending {
+		if now.After(pending.expires) {
+			delete(d.pending, key)
+		}
+	}
+	d.pending[id] = pendingWithdrawal{tx: tx, expires: now.Add(dashboardWithdrawalTTL)}
+	d.mu.Unlock()
+	return map[string]string{"id": id, "message": base58.Encode(message)}, nil
+}
+
+// checkPolicy evaluates the withdrawal policy against a built withdrawal with the approvals
+// given in the dashboard. Violations are recorded in the audit log like the CLI's.
+func (d *dashboard) checkPolicy(tx *solana.Transaction, confirmAmount, totpCode string) error {
+	policy, err := d.app.withdrawalPolicy()
+	if err != nil {
+		return &apiError{http.StatusForbidden, fmt.Sprintf("withdrawal blocked: %v", err)}
+	}
+	if policy == nil {
+		return nil
+	}
+	for _, w := range d.app.policyWithdrawals(tx) {
+		decision := policy.Check(w)
+		violations := decision.Violations
+		if len(violations) == 0 && len(decision.FIDO2) > 0 {
+			violations = append(violations, fmt.Sprintf("%s: a security key touch is required; withdraw from the CLI", strings.Join(decision.FIDO2, ", ")))
+		}
+		if len(violations) > 0 {
+			d.app.auditBlockedFor(tx.Message.AccountKeys[0], tx, violations)
+			return &apiError{http.StatusForbidden, "withdrawal blocked by policy: " + strings.Join(violations, "; ")}
+		}
+
+		needed := &policyApprovalNeeded{}
+		if len(decision.Confirm) > 0 {
+			if confirmAmount == "" {
+				needed.Rules, needed.Confirm = append(needed.Rules, decision.Confirm...), true
+			} else if typed, err := parseSOL(strings.TrimSpace(confirmAmount)); err != nil || typed != w.Lamports {
+				return &apiError{http.StatusForbidden, "the amount typed again does not match"}
+			}
+		}
+		if len(decision.TOTP) > 0 {
+			if totpCode == "" {
+				needed.Rules, needed.TOTP = append(needed.Rules, decision.TOTP...), true
+			} else if err := verifyTOTPCode(totpCode); err != nil {
+				d.app.auditBlockedFor(tx.Message.AccountKeys[0], tx, []string{fmt.Sprintf("%s: %v", strings.Join(decision.TOTP, ", "), err)})
+				return &apiError{http.StatusForbidden, err.Error()}
+			}
+		}
+		if needed.Confirm || needed.TOTP {
+			return needed
+		}
+	}
+	return nil
+}
+
+// verifyTOTPCode checks an authenticator code entered in the dashboard against the enrollment
+func verifyTOTPCode(code string) error {
+	enrollment, err := LoadTOTP()
+	if err != nil {
+		return err
+	}
+	if enrollment == nil {
+		return fmt.Errorf("a TOTP code is required but no authenticator is enrolled (run `totp enroll`)")
+	}
+	if !enrollment.Verify(code, time.Now()) {
+		return fmt.Errorf("wrong or already used TOTP code")
+	}
+	return enrollment.Save()
+}
+
+func (d *dashboard) handleSubmitWithdrawal(r *http.Request) (interface{}, error) {
+	var body struct {
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid request"}
+	}
+	signature, err := solana.SignatureFromBase58(body.Signature)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid signature"}
+	}
+
+	d.mu.Lock()
+	pending, ok := d.pending[r.PathValue("id")]
+	delete(d.pending, r.PathValue("id"))
+	d.mu.Unlock()
+	if !ok || time.Now().After(pending.expires) {
+		return nil, &apiError{http.StatusNotFound, "withdrawal expired; start it again"}
+	}
+
+	// The admin is the fee payer and only signer
+	tx := pending.tx
+	tx.Signatures = []solana.Signature{signature}
+	if err := tx.VerifySignatures(); err != nil {
+		return nil, &apiError{http.StatusBadRequest, "signature does not match the transaction"}
+	}
+	if err := d.app.auditSigned(tx, tx.Message.AccountKeys[0]); err != nil {
+		return nil, err
+	}
+	sig, err := d.app.send(r.Context(), "withdraw", tx)
+	d.app.auditResultFor(tx.Message.AccountKeys[0], sig, err)
+	if err != nil {
+		return nil, &apiError{http.StatusBadGateway, fmt.Sprintf("failed to send transaction: %v", d.app.idl.DecodeError(err))}
+	}
+	d.app.invalidateWrittenAccounts(tx)
+	logf(r.Context(), "Dashboard withdrawal sent: %s", sig)
+	return map[string]string{"signature": sig.String(), "url": d.app.TxURL(sig.String())}, nil
+}
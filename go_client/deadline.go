@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// formatCountdown describes the time left until a deadline, or since it passed
+func formatCountdown(deadline, now time.Time) string {
+	left := deadline.Sub(now)
+	if left <= 0 {
+		return "ended " + formatDuration(-left) + " ago"
+	}
+	return formatDuration(left) + " left"
+}
+
+// formatDuration rounds a duration to its two largest units, e.g. 3d 4h or 12m
+func formatDuration(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return "under a minute"
+	}
+}
+
+// printDeadline shows a campaign's deadline and countdown in the status view, if it has one
+func printDeadline(address solana.PublicKey) {
+	records, err := loadCampaignRecords()
+	if err != nil {
+		return
+	}
+	if record := records.get(address); record != nil && record.Deadline != nil {
+		fmt.Printf("   Deadline: %s (%s)\n", formatTime(*record.Deadline), formatCountdown(*record.Deadline, time.Now()))
+	}
+}
+
+// notifyEndedCampaigns announces, once, every campaign in the local registry whose deadline has
+// passed. Moving a deadline back into the future rearms the announcement.
+func (d *Daemon) notifyEndedCampaigns(ctx context.Context, now time.Time) {
+	records, err := loadCampaignRecords()
+	if err != nil {
+		logf(ctx, "Failed to check campaign deadlines: %v", err)
+		return
+	}
+	for address, record := range records {
+		if record.Deadline == nil {
+			continue
+		}
+		key := "ended:" + address
+		ended := record.ended(now)
+		if ended == d.store.AlertFiring(key) {
+			continue
+		}
+		if err := d.store.SetAlertFiring(key, ended); err != nil {
+			logf(ctx, "Failed to save campaign end state: %v", err)
+		}
+		if !ended {
+			continue
+		}
+
+		message := fmt.Sprintf("'%s' reached its deadline, %s; donations are no longer accepted", record.Name, formatTime(*record.Deadline))
+		if campaign, err := d.app.FetchCampaign(solana.MustPublicKeyFromBase58(address)); err == nil {
+			message += fmt.Sprintf(". It raised %s SOL", lamportsToSOL(campaign.AmountDonated))
+			if record.Goal > 0 {
+				message += fmt.Sprintf(" of its %s SOL goal", lamportsToSOL(record.Goal))
+			}
+		}
+		d.notifiers.Notify(ctx, Notification{Title: "Campaign ended", Message: message, Campaign: address, Severity: "info", Thread: key})
+	}
+}
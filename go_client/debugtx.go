@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// debugTxFlag turns on the transaction dumps, anywhere on the command line
+const debugTxFlag = "--debug-tx"
+
+// maxTransactionSize is the largest serialized transaction the network accepts
+const maxTransactionSize = 1232
+
+// debugTx dumps every transaction before it is sent, and the raw RPC traffic, to stderr
+var debugTx bool
+
+// parseDebugFlags removes --debug-tx from the arguments, enabling the dumps when it was there
+func parseDebugFlags(args []string) []string {
+	kept := args[:0:0]
+	for _, arg := range args {
+		if arg == debugTxFlag || arg == "-debug-tx" {
+			debugTx = true
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
+
+// dumpTransaction prints everything that goes on the wire for a transaction: each instruction's
+// account metas and data, split into the discriminator and Borsh-encoded arguments, and the
+// serialized transaction
+func (app *SolanaDApp) dumpTransaction(operation string, tx *solana.Transaction) {
+	out := os.Stderr
+	fmt.Fprintf(out, "\n🐞 Transaction for %s\n", operation)
+	fmt.Fprintf(out, "   Fee payer:  %s\n", tx.Message.AccountKeys[0])
+	fmt.Fprintf(out, "   Blockhash:  %s\n", tx.Message.RecentBlockhash)
+	fmt.Fprintf(out, "   Header:     %d signatures, %d read-only signed, %d read-only unsigned\n",
+		tx.Message.Header.NumRequiredSignatures, tx.Message.Header.NumReadonlySignedAccounts, tx.Message.Header.NumReadonlyUnsignedAccounts)
+
+	for i, ix := range tx.Message.Instructions {
+		program, err := tx.Message.Program(ix.ProgramIDIndex)
+		if err != nil {
+			fmt.Fprintf(out, "   ⚠️  Instruction #%d: %v\n", i+1, err)
+			continue
+		}
+		fmt.Fprintf(out, "\n   Instruction #%d: program %s\n", i+1, program)
+
+		metas, err := ix.ResolveInstructionAccounts(&tx.Message)
+		if err != nil {
+			fmt.Fprintf(out, "   ⚠️  Could not resolve accounts: %v\n", err)
+		}
+		var idlIx *IDLInstruction
+		if program.Equals(app.programID) {
+			idlIx, _ = app.idl.MatchInstruction(ix.Data)
+		}
+		fmt.Fprintln(out, "   Accounts:")
+		for j, meta := range metas {
+			role := fmt.Sprintf("#%d", j)
+			if idlIx != nil && j < len(idlIx.Accounts) {
+				role = idlIx.Accounts[j].Name
+			}
+			fmt.Fprintf(out, "     %-15s %s %s\n", role, meta.PublicKey, accountFlags(meta))
+		}
+
+		fmt.Fprintf(out, "   Data (%d bytes): %s\n", len(ix.Data), hex.EncodeToString(ix.Data))
+		if idlIx == nil {
+			continue
+		}
+		fmt.Fprintf(out, "   Discriminator:  %s (%s)\n", hex.EncodeToString(ix.Data[:8]), idlIx.Name)
+		fmt.Fprintf(out, "   Borsh args:     %s\n", hex.EncodeToString(ix.Data[8:]))
+		args, err := idlIx.DecodeArgs(ix.Data)
+		for _, arg := range args {
+			fmt.Fprintf(out, "     %-15s %s\n", arg.Name+":", formatArg(arg))
+		}
+		if err != nil {
+			fmt.Fprintf(out, "   ⚠️  Args don't match the IDL: %v\n", err)
+		}
+	}
+
+	if raw, err := tx.MarshalBinary(); err != nil {
+		fmt.Fprintf(out, "\n   ⚠️  Could not serialize: %v\n", err)
+	} else {
+		fmt.Fprintf(out, "\n   Serialized (%d bytes, limit %d):\n   %s\n", len(raw), maxTransactionSize, base64.StdEncoding.EncodeToString(raw))
+	}
+	fmt.Fprintln(out)
+}
+
+// accountFlags describes an account meta's permissions, like the explorers do
+func accountFlags(meta *solana.AccountMeta) string {
+	flags := ""
+	if meta.IsSigner {
+		flags += "[signer]"
+	}
+	if meta.IsWritable {
+		flags += "[writable]"
+	}
+	return flags
+}
+
+// debugHTTPClient prints the body of every JSON-RPC request and response it carries
+type debugHTTPClient struct {
+	next *http.Client
+}
+
+// Do implements jsonrpc.HTTPClient
+func (c *debugHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		fmt.Fprintf(os.Stderr, "🐞 → %s %s\n", req.URL.Host, body)
+	}
+
+	start := time.Now()
+	resp, err := c.next.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "🐞 ← %s error after %s: %v\n", req.URL.Host, time.Since(start).Round(time.Millisecond), err)
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	fmt.Fprintf(os.Stderr, "🐞 ← %s %s in %s: %s\n", req.URL.Host, resp.Status, time.Since(start).Round(time.Millisecond), bytes.TrimSpace(body))
+	return resp, nil
+}
+
+// CloseIdleConnections implements jsonrpc.HTTPClient
+func (c *debugHTTPClient) CloseIdleConnections() {
+	c.next.CloseIdleConnections()
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/robfig/cron/v3"
+)
+
+// digestActivityLimit is how many recent transactions a digest looks through for new donations
+const digestActivityLimit = 200
+
+// smtpPasswordEnv holds the SMTP password when it is not in config.json
+const smtpPasswordEnv = "CROWDFUNDING_SMTP_PASSWORD"
+
+// DigestConfig configures the daily email digest in config.json
+type DigestConfig struct {
+	Spec     string   `json:"spec,omitempty"` // cron expression, default "0 8 * * *"
+	SMTP     string   `json:"smtp"`           // host:port of the mail server
+	TLS      bool     `json:"tls,omitempty"`  // implicit TLS (port 465); otherwise STARTTLS is used when offered
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"` // or set CROWDFUNDING_SMTP_PASSWORD
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+
+	schedule cron.Schedule
+	from     *mail.Address
+	to       []*mail.Address
+}
+
+// prepare validates the digest settings and parses its schedule and addresses
+func (c *DigestConfig) prepare() error {
+	spec := c.Spec
+	if spec == "" {
+		spec = "0 8 * * *"
+	}
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("digest: invalid spec %q: %w", spec, err)
+	}
+	c.schedule = schedule
+
+	if _, _, err := net.SplitHostPort(c.SMTP); err != nil {
+		return fmt.Errorf("digest: smtp must be host:port: %w", err)
+	}
+	if c.from, err = mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("digest: invalid from address %q: %w", c.From, err)
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("digest: no recipients in to")
+	}
+	c.to = nil
+	for _, to := range c.To {
+		address, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("digest: invalid recipient %q: %w", to, err)
+		}
+		c.to = append(c.to, address)
+	}
+	return nil
+}
+
+// CampaignDigest summarises a campaign's donations over a period
+type CampaignDigest struct {
+	Campaign    solana.PublicKey
+	Name        string
+	URL         string
+	From, To    time.Time
+	TotalRaised uint64 // lamports, the on-chain amount_donated
+	Balance     uint64
+	Raised      uint64 // lamports donated during the period
+	Donations   []CampaignActivity
+	Largest     *CampaignActivity
+	Incomplete  bool              // more transactions in the period than digestActivityLimit
+	TxURLs      map[string]string // explorer link of each donation
+}
+
+// CampaignDigest gathers a campaign's totals and the donations made in [from, to]
+func (app *SolanaDApp) CampaignDigest(ctx context.Context, campaign solana.PublicKey, from, to time.Time) (*CampaignDigest, error) {
+	account, err := app.FetchCampaign(campaign)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := app.client.GetBalance(ctx, campaign, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign balance: %w", err)
+	}
+	activity, err := app.GetCampaignActivity(campaign, digestActivityLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &CampaignDigest{
+		Campaign:    campaign,
+		Name:        account.Name,
+		URL:         app.AddressURL(campaign.String()),
+		From:        from,
+		To:          to,
+		TotalRaised: account.AmountDonated,
+		Balance:     balance.Value,
+		TxURLs:      map[string]string{},
+	}
+	for i, entry := range activity {
+		if entry.BlockTime == nil || entry.BlockTime.After(to) {
+			continue
+		}
+		if entry.BlockTime.Before(from) {
+			break // Newest first, so the rest are older still
+		}
+		if i == len(activity)-1 && len(activity) == digestActivityLimit {
+			digest.Incomplete = true
+		}
+		if entry.Kind != "donate" || entry.Failed {
+			continue
+		}
+		digest.Donations = append(digest.Donations, entry)
+		digest.Raised += entry.Amount
+		digest.TxURLs[entry.Signature] = app.TxURL(entry.Signature)
+		if digest.Largest == nil || entry.Amount > digest.Largest.Amount {
+			digest.Largest = &activity[i]
+		}
+	}
+	return digest, nil
+}
+
+var digestFuncs = map[string]interface{}{
+	"sol":  lamportsToSOL,
+	"time": func(t *time.Time) string { return t.In(displayLocation).Format("Jan 2 15:04 MST") },
+	"date": func(t time.Time) string { return t.In(displayLocation).Format("Mon Jan 2 2006") },
+}
+
+var digestText = template.Must(template.New("digest").Funcs(digestFuncs).Parse(`Daily digest for {{.Name}}
+{{date .From}} to {{date .To}}
+
+Raised in this period: {{sol .Raised}} SOL from {{len .Donations}} donation(s)
+{{- if .Largest}}
+Largest gift:          {{sol .Largest.Amount}} SOL from {{.Largest.Wallet}}{{end}}
+Total raised:          {{sol .TotalRaised}} SOL
+Balance:               {{sol .Balance}} SOL
+Deadline:              none (campaigns stay open until withdrawn)
+{{if .Donations}}
+Donations:
+{{range .Donations}}  {{time .BlockTime}}  {{sol .Amount}} SOL from {{.Wallet}}{{if .Memo}}: "{{.Memo}}"{{end}}
+{{end}}{{end}}{{if .Incomplete}}
+Some earlier donations in this period may be missing: only the most recent transactions were checked.
+{{end}}
+{{.URL}}
+`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Funcs(digestFuncs).Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif; color: #111">
+<h2 style="margin-bottom: 0">{{.Name}}</h2>
+<p style="color: #666; margin-top: 4px">Daily digest, {{date .From}} to {{date .To}}</p>
+<table cellpadding="4">
+<tr><td>Raised in this period</td><td><b>{{sol .Raised}} SOL</b> from {{len .Donations}} donation(s)</td></tr>
+{{- if .Largest}}
+<tr><td>Largest gift</td><td>{{sol .Largest.Amount}} SOL from <code>{{.Largest.Wallet}}</code></td></tr>{{end}}
+<tr><td>Total raised</td><td>{{sol .TotalRaised}} SOL</td></tr>
+<tr><td>Balance</td><td>{{sol .Balance}} SOL</td></tr>
+<tr><td>Deadline</td><td>none (campaigns stay open until withdrawn)</td></tr>
+</table>
+{{- if .Donations}}
+<h3>Donations</h3>
+<table cellpadding="4">
+{{- range .Donations}}
+<tr><td>{{time .BlockTime}}</td><td><a href="{{index $.TxURLs .Signature}}">{{sol .Amount}} SOL</a></td><td><code>{{.Wallet}}</code></td><td>{{.Memo}}</td></tr>
+{{- end}}
+</table>{{end}}
+{{- if .Incomplete}}
+<p>Some earlier donations in this period may be missing: only the most recent transactions were checked.</p>{{end}}
+<p><a href="{{.URL}}">View the campaign in the explorer</a></p>
+</body></html>
+`))
+
+// Text renders the plaintext version of the digest
+func (d *CampaignDigest) Text() (string, error) {
+	var buf bytes.Buffer
+	if err := digestText.Execute(&buf, d); err != nil {
+		return "", fmt.Errorf("failed to render digest: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// message builds a multipart/alternative email with plaintext and HTML versions of the digest
+func (c *DigestConfig) message(d *CampaignDigest) ([]byte, error) {
+	text, err := d.Text()
+	if err != nil {
+		return nil, err
+	}
+	var html bytes.Buffer
+	if err := digestHTML.Execute(&html, d); err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html.String()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 12)
+	rand.Read(id)
+	domain := c.from.Address[strings.LastIndex(c.from.Address, "@")+1:]
+
+	var msg bytes.Buffer
+	recipients := make([]string, len(c.to))
+	for i, to := range c.to {
+		recipients[i] = to.String()
+	}
+	subject := fmt.Sprintf("%s: %s SOL raised from %d donation(s)", d.Name, lamportsToSOL(d.Raised), len(d.Donations))
+	fmt.Fprintf(&msg, "From: %s\r\n", c.from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// Send emails the digest to every recipient
+func (c *DigestConfig) Send(d *CampaignDigest) error {
+	msg, err := c.message(d)
+	if err != nil {
+		return err
+	}
+
+	host, _, _ := net.SplitHostPort(c.SMTP)
+	password := c.Password
+	if password == "" {
+		password = os.Getenv(smtpPasswordEnv)
+	}
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, password, host)
+	}
+	recipients := make([]string, len(c.to))
+	for i, to := range c.to {
+		recipients[i] = to.Address
+	}
+
+	if !c.TLS {
+		if err := smtp.SendMail(c.SMTP, auth, c.from.Address, recipients, msg); err != nil {
+			return fmt.Errorf("failed to send digest email: %w", err)
+		}
+		return nil
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", c.SMTP, &tls.Config{ServerName: host})
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", c.SMTP, err)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to %s: %w", c.SMTP, err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(c.from.Address); err != nil {
+		return fmt.Errorf("failed to send digest email: %w", err)
+	}
+	for _, to := range recipients {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to send digest email to %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send digest email: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to send digest email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send digest email: %w", err)
+	}
+	return client.Quit()
+}
+
+// runDigest emails each tracked campaign's digest when the digest schedule is due,
+// covering the time since the previous digest
+func (d *Daemon) runDigest(ctx context.Context, now time.Time) {
+	if d.digest == nil {
+		return
+	}
+	last := d.store.DigestSentAt()
+	base := last
+	if base.IsZero() {
+		base = d.started // The first digest goes out at the first scheduled time after startup
+	}
+	if d.digest.schedule.Next(base.In(displayLocation)).After(now) {
+		return
+	}
+	if last.IsZero() || now.Sub(last) > 7*24*time.Hour {
+		last = now.Add(-24 * time.Hour)
+	}
+
+	for _, campaign := range d.campaigns {
+		digest, err := d.app.CampaignDigest(ctx, campaign, last, now)
+		if err == nil {
+			err = d.digest.Send(digest)
+		}
+		if err != nil {
+			logf(ctx, "Digest for %s failed: %v", campaign, err)
+			d.notifiers.Notify(ctx, Notification{Title: "Digest", Campaign: campaign.String(), Severity: "warning", Message: fmt.Sprintf("Daily digest email failed: %v", err)})
+		}
+	}
+	if err := d.store.SetDigestSentAt(now.UTC()); err != nil {
+		logf(ctx, "Failed to record digest: %v", err)
+	}
+}
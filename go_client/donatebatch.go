@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Payout row states in a donate-batch report
+const (
+	PayoutPending   = "pending"
+	PayoutSending   = "sending" // its transaction was being sent when the run stopped
+	PayoutConfirmed = "confirmed"
+	PayoutFailed    = "failed"
+)
+
+// payoutSizeHeadroom leaves room in each transaction for the compute budget instructions the
+// priority fee adds when it is sent
+const payoutSizeHeadroom = 100
+
+// PayoutRow is one donation of a donate-batch file and its outcome
+type PayoutRow struct {
+	Line     int
+	Campaign solana.PublicKey
+	Name     string
+	Amount   uint64
+
+	Status    string
+	Signature string
+	Error     string
+}
+
+// payoutReportHeader is the header of a donate-batch report
+var payoutReportHeader = []string{"line", "campaign", "name", "amount", "status", "signature", "error"}
+
+// loadPayoutRows reads a donate-batch CSV with campaign and amount columns, validating every row
+// before anything is signed. The campaign is an address, or a name that exactly one campaign has.
+func (app *SolanaDApp) loadPayoutRows(path string) ([]*PayoutRow, error) {
+	records, err := readCSV(path, "campaign", "amount")
+	if err != nil {
+		return nil, err
+	}
+
+	var byName map[string][]CampaignAccount
+	var rows []*PayoutRow
+	for _, record := range records {
+		row := &PayoutRow{Line: record.line, Status: PayoutPending}
+		if row.Amount, err = parseSOL(record.fields["amount"]); err != nil || row.Amount == 0 {
+			return nil, fmt.Errorf("line %d: invalid amount %q", row.Line, record.fields["amount"])
+		}
+
+		campaign := record.fields["campaign"]
+		if address, err := solana.PublicKeyFromBase58(campaign); err == nil {
+			fetched, err := app.FetchCampaign(address)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", row.Line, err)
+			}
+			row.Campaign, row.Name = address, fetched.Name
+		} else {
+			if byName == nil {
+				campaigns, err := app.ListCampaigns()
+				if err != nil {
+					return nil, err
+				}
+				byName = map[string][]CampaignAccount{}
+				for _, c := range campaigns {
+					byName[c.Name] = append(byName[c.Name], c)
+				}
+			}
+			switch matches := byName[campaign]; len(matches) {
+			case 0:
+				return nil, fmt.Errorf("line %d: no campaign is named %q", row.Line, campaign)
+			case 1:
+				row.Campaign, row.Name = matches[0].Address, campaign
+			default:
+				var addresses []string
+				for _, c := range matches {
+					addresses = append(addresses, c.Address.String())
+				}
+				return nil, fmt.Errorf("line %d: %d campaigns are named %q, use an address: %s", row.Line, len(matches), campaign, strings.Join(addresses, ", "))
+			}
+		}
+
+		if err := app.checkDonationsOpen(row.Campaign); err != nil {
+			return nil, fmt.Errorf("line %d: %w", row.Line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// loadPayoutReport restores the outcome of a previous run from its report, matching rows by line,
+// campaign and amount. Rows left sending are refused: their transaction may or may not have landed.
+func loadPayoutReport(path string, rows []*PayoutRow) error {
+	records, err := readCSV(path, "line", "campaign", "amount", "status")
+	if err != nil {
+		return nil
+	}
+	saved := map[string]csvRow{}
+	for _, record := range records {
+		saved[record.fields["line"]+"/"+record.fields["campaign"]+"/"+record.fields["amount"]] = record
+	}
+
+	var unsettled []string
+	for _, row := range rows {
+		record, ok := saved[strconv.Itoa(row.Line)+"/"+row.Campaign.String()+"/"+lamportsToSOL(row.Amount)]
+		if !ok {
+			continue
+		}
+		switch record.fields["status"] {
+		case PayoutConfirmed:
+			row.Status, row.Signature = PayoutConfirmed, record.fields["signature"]
+		case PayoutSending:
+			unsettled = append(unsettled, strconv.Itoa(row.Line))
+		}
+	}
+	if len(unsettled) > 0 {
+		return fmt.Errorf("the donations on line(s) %s were being sent when the last run stopped and may have landed: check the wallet's recent transactions, then set their status in %s to confirmed or failed", strings.Join(unsettled, ", "), path)
+	}
+	return nil
+}
+
+// savePayoutReport writes the report
+func savePayoutReport(path string, rows []*PayoutRow) error {
+	var records [][]string
+	for _, row := range rows {
+		records = append(records, []string{strconv.Itoa(row.Line), row.Campaign.String(), row.Name, lamportsToSOL(row.Amount), row.Status, row.Signature, row.Error})
+	}
+	return writeCSV(path, payoutReportHeader, records)
+}
+
+// payoutInstruction builds the donate instruction of a row
+func (app *SolanaDApp) payoutInstruction(row *PayoutRow) (solana.Instruction, error) {
+	instruction, err := app.BuildInstruction("donate",
+		map[string]solana.PublicKey{"campaign": row.Campaign, "user": app.wallet.Address()},
+		map[string]interface{}{"name": row.Name, "amount": row.Amount},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build donate instruction: %w", err)
+	}
+	return instruction, nil
+}
+
+// groupPayouts packs consecutive pending rows into transactions of at most perTx donations that
+// fit the transaction size limit
+func (app *SolanaDApp) groupPayouts(rows []*PayoutRow, perTx int) ([][]*PayoutRow, error) {
+	var groups [][]*PayoutRow
+	var group []*PayoutRow
+	var instructions []solana.Instruction
+	for _, row := range rows {
+		if row.Status == PayoutConfirmed {
+			continue
+		}
+		instruction, err := app.payoutInstruction(row)
+		if err != nil {
+			return nil, err
+		}
+		if len(group) > 0 {
+			fits, err := app.fitsTransaction(append(instructions, instruction))
+			if err != nil {
+				return nil, err
+			}
+			if len(group) >= perTx || !fits {
+				groups = append(groups, group)
+				group, instructions = nil, nil
+			}
+		}
+		group = append(group, row)
+		instructions = append(instructions, instruction)
+	}
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// fitsTransaction reports whether the instructions fit one signed transaction, leaving room for
+// the priority fee instructions
+func (app *SolanaDApp) fitsTransaction(instructions []solana.Instruction) (bool, error) {
+	tx, err := solana.NewTransaction(instructions, solana.Hash{}, solana.TransactionPayer(app.payer()))
+	if err != nil {
+		return false, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize transaction: %w", err)
+	}
+	size := len(raw) + solana.SignatureLength*int(tx.Message.Header.NumRequiredSignatures)
+	return size+payoutSizeHeadroom <= maxTransactionSize, nil
+}
+
+// DonatePayouts sends the pending rows, several donations per transaction, saving the report
+// before and after every transaction. A failed transaction fails all of its rows, as none of its
+// donations landed.
+func (app *SolanaDApp) DonatePayouts(ctx context.Context, rows []*PayoutRow, perTx int, reportPath string) error {
+	groups, err := app.groupPayouts(rows, perTx)
+	if err != nil {
+		return err
+	}
+
+	var total uint64
+	for _, group := range groups {
+		for _, row := range group {
+			total += row.Amount
+		}
+	}
+	balance, err := app.client.GetBalance(ctx, app.wallet.Address(), rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get balance: %w", err)
+	}
+	if balance.Value < total {
+		return fmt.Errorf("the pending donations total %s SOL and the wallet holds %s SOL", lamportsToSOL(total), lamportsToSOL(balance.Value))
+	}
+	fmt.Printf("📦 Donating %s SOL in %d transaction(s); report in %s\n", lamportsToSOL(total), len(groups), reportPath)
+
+	for _, group := range groups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		instructions := make([]solana.Instruction, len(group))
+		lines := make([]string, len(group))
+		for i, row := range group {
+			if instructions[i], err = app.payoutInstruction(row); err != nil {
+				return err
+			}
+			lines[i] = strconv.Itoa(row.Line)
+			row.Status = PayoutSending
+		}
+		if err := savePayoutReport(reportPath, rows); err != nil {
+			return err
+		}
+
+		sig, err := app.submitTransaction("donate-batch", instructions)
+		for _, row := range group {
+			row.Signature, row.Error = "", ""
+			if !sig.IsZero() {
+				row.Signature = sig.String()
+			}
+			if err != nil {
+				row.Status, row.Error = PayoutFailed, err.Error()
+			} else {
+				row.Status = PayoutConfirmed
+			}
+		}
+		if err != nil {
+			fmt.Printf("❌ Line(s) %s: %v\n", strings.Join(lines, ", "), err)
+		} else {
+			fmt.Printf("✅ Line(s) %s: %d donation(s) confirmed: %s\n", strings.Join(lines, ", "), len(group), sig)
+		}
+		if err := savePayoutReport(reportPath, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// donationIntentRequest asks for a donation for the donor's wallet to sign and send
+type donationIntentRequest struct {
+	Donor     string `json:"donor"`
+	Campaign  string `json:"campaign"`
+	Amount    uint64 `json:"amount"`              // lamports
+	Message   string `json:"message,omitempty"`   // posted to the campaign's message board as a memo
+	Reference string `json:"reference,omitempty"` // Solana Pay reference, generated when empty
+}
+
+// donationIntent is an unsigned donation, paid for and signed by the donor
+type donationIntent struct {
+	Transaction          string `json:"transaction"` // base64
+	Campaign             string `json:"campaign"`
+	Donor                string `json:"donor"`
+	Amount               uint64 `json:"amount"`
+	Reference            string `json:"reference"`
+	Blockhash            string `json:"blockhash"`
+	LastValidBlockHeight uint64 `json:"lastValidBlockHeight"`
+}
+
+// handleDonationIntent builds an unsigned donation with the donor as fee payer. The server holds no
+// keys for it: the donor's wallet signs and sends the transaction, which carries a reference
+// account so the donation can be found once it lands.
+func (s *Server) handleDonationIntent(w http.ResponseWriter, r *http.Request) {
+	var req donationIntentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid request body"})
+		return
+	}
+	donor, err := solana.PublicKeyFromBase58(req.Donor)
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid donor address"})
+		return
+	}
+	campaignAddress, err := solana.PublicKeyFromBase58(req.Campaign)
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid campaign address"})
+		return
+	}
+	if req.Amount == 0 {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "amount must be positive"})
+		return
+	}
+	if err := validateComment(req.Message); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, err.Error()})
+		return
+	}
+	reference := solana.NewWallet().PublicKey()
+	if req.Reference != "" {
+		if reference, err = solana.PublicKeyFromBase58(req.Reference); err != nil {
+			writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid reference"})
+			return
+		}
+	}
+
+	tx, recent, err := s.buildDonation(r.Context(), donor, campaignAddress, reference, req.Amount, req.Message)
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+
+	writeRelayJSON(w, r, http.StatusCreated, donationIntent{
+		Transaction:          encoded,
+		Campaign:             campaignAddress.String(),
+		Donor:                donor.String(),
+		Amount:               req.Amount,
+		Reference:            reference.String(),
+		Blockhash:            recent.Value.Blockhash.String(),
+		LastValidBlockHeight: recent.Value.LastValidBlockHeight,
+	})
+}
+
+// buildDonation builds an unsigned donation of amount lamports from donor, who pays its fee. The
+// reference account lets Solana Pay find it once it lands, and message becomes a memo.
+func (s *Server) buildDonation(ctx context.Context, donor, campaignAddress, reference solana.PublicKey, amount uint64, message string) (*solana.Transaction, *rpc.GetLatestBlockhashResult, error) {
+	campaign, err := s.app.FetchCampaign(campaignAddress)
+	if err != nil {
+		if errors.Is(err, crowdfund.ErrCampaignNotFound) || errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+			return nil, nil, &apiError{http.StatusNotFound, "campaign not found"}
+		}
+		return nil, nil, err
+	}
+	if err := s.app.checkDonationsOpen(campaignAddress); err != nil {
+		if errors.Is(err, ErrCampaignClosed) || errors.Is(err, ErrCampaignEnded) {
+			return nil, nil, &apiError{http.StatusConflict, err.Error()}
+		}
+		return nil, nil, err
+	}
+
+	donate, err := s.app.BuildInstruction("donate",
+		map[string]solana.PublicKey{"campaign": campaignAddress, "user": donor},
+		map[string]interface{}{"name": campaign.Name, "amount": amount},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The program ignores extra accounts; Solana Pay finds transactions by this one
+	donate.AccountValues = append(donate.AccountValues, solana.Meta(reference))
+	instructions := []solana.Instruction{donate}
+	if message != "" {
+		instructions = append(instructions, memoInstruction(message, donor))
+	}
+
+	recent, err := s.app.sender.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := solana.NewTransaction(instructions, recent.Value.Blockhash, solana.TransactionPayer(donor))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return tx, recent, nil
+}
+
+// handlePayLabel answers the first request of a Solana Pay transaction request link with what
+// the wallet shows before asking for the donor's account
+func (s *Server) handlePayLabel(w http.ResponseWriter, r *http.Request) {
+	campaignAddress, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid campaign address"})
+		return
+	}
+	campaign, err := s.app.FetchCampaign(campaignAddress)
+	if err != nil {
+		if errors.Is(err, crowdfund.ErrCampaignNotFound) || errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+			writeRelayError(w, r, &apiError{http.StatusNotFound, "campaign not found"})
+			return
+		}
+		writeRelayError(w, r, err)
+		return
+	}
+	writeRelayJSON(w, r, http.StatusOK, map[string]string{
+		"label": campaign.Name,
+		"icon":  s.baseURL(r) + "/c/" + campaignAddress.String() + "/card.png",
+	})
+}
+
+// handlePayTransaction answers a wallet following a Solana Pay transaction request link with a
+// donation from its account, so the donation is counted like any other. The link carries the
+// amount in SOL, the reference and an optional memo.
+func (s *Server) handlePayTransaction(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Account string `json:"account"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid request body"})
+		return
+	}
+	donor, err := solana.PublicKeyFromBase58(req.Account)
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid account"})
+		return
+	}
+	campaignAddress, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid campaign address"})
+		return
+	}
+	query := r.URL.Query()
+	amount, err := parseSOL(query.Get("amount"))
+	if err != nil || amount == 0 {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "amount must be a positive SOL amount"})
+		return
+	}
+	reference, err := solana.PublicKeyFromBase58(query.Get("reference"))
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid reference"})
+		return
+	}
+	memo := query.Get("memo")
+	if err := validateComment(memo); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, err.Error()})
+		return
+	}
+
+	tx, _, err := s.buildDonation(r.Context(), donor, campaignAddress, reference, amount, memo)
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+	writeRelayJSON(w, r, http.StatusOK, map[string]string{
+		"transaction": encoded,
+		"message":     fmt.Sprintf("Donating %s SOL", lamportsToSOL(amount)),
+	})
+}
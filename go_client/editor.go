@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"crowdfunding-client/crowdfund"
+	"golang.org/x/term"
+)
+
+// editorScissors separates the text being edited from the instructions below it, as in
+// `git commit --cleanup=scissors`, so Markdown headings starting with # are kept
+const editorScissors = "# ------------------------ >8 ------------------------"
+
+// textEditor returns the user's editor command: $VISUAL, then $EDITOR, then the platform default
+func textEditor() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// editText opens initial in the user's editor, like `git commit`, and returns the saved text.
+// hint is shown below a scissors line, and everything from that line on is dropped.
+func editText(initial, hint string) (string, error) {
+	file, err := os.CreateTemp("", "crowdfund-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	var buf strings.Builder
+	buf.WriteString(initial)
+	if initial != "" && !strings.HasSuffix(initial, "\n") {
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(&buf, "\n%s\n# Do not modify or remove the line above; everything below it is ignored.\n", editorScissors)
+	for _, line := range strings.Split(hint, "\n") {
+		fmt.Fprintf(&buf, "# %s\n", line)
+	}
+	if _, err := file.WriteString(buf.String()); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	// Run through the shell, as git does, so editors with arguments such as "code --wait" work
+	editor := textEditor()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", editor+" "+file.Name())
+	} else {
+		cmd = exec.Command("sh", "-c", editor+` "$@"`, editor, file.Name())
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited text: %w", err)
+	}
+	text, _, _ := strings.Cut(strings.ReplaceAll(string(data), "\r\n", "\n"), editorScissors)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// editDescription writes a campaign description in the user's editor, reopening it with the
+// reason while the description does not fit the campaign account. Saving an empty description
+// aborts, as an empty message aborts `git commit`.
+func editDescription(name, initial string) (string, error) {
+	if err := crowdfund.CheckCampaignCapacity(name, ""); err != nil {
+		return "", err
+	}
+	hint := fmt.Sprintf("Description of '%s', in Markdown. At most %d bytes fit the campaign account.\nSave an empty description to cancel.",
+		name, crowdfund.CampaignAccountSpace-crowdfund.CampaignFixedSpace-len(name))
+	text := initial
+	for {
+		edited, err := editText(text, hint)
+		if err != nil {
+			return "", err
+		}
+		if edited == "" {
+			return "", fmt.Errorf("description is empty; cancelled")
+		}
+		text = edited
+		if err := crowdfund.CheckCampaignCapacity(name, text); err != nil {
+			if !term.IsTerminal(int(os.Stdin.Fd())) {
+				return "", err
+			}
+			hint = fmt.Sprintf("%v\n%s", err, hint)
+			continue
+		}
+		return text, nil
+	}
+}
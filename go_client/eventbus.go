@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/nats-io/nats.go"
+)
+
+// EventBusConfig configures streaming events to NATS JetStream in config.json
+type EventBusConfig struct {
+	URL         string `json:"url"`                   // e.g. nats://localhost:4222
+	Credentials string `json:"credentials,omitempty"` // NATS .creds file
+	Stream      string `json:"stream,omitempty"`      // JetStream stream, created if missing; default CROWDFUNDING
+	Subject     string `json:"subject,omitempty"`     // subject prefix, default "crowdfunding"
+}
+
+// OperationEvent is published for every transaction this client submits
+type OperationEvent struct {
+	Type        string    `json:"type"` // always operation
+	Operation   string    `json:"operation"`
+	OperationID string    `json:"operationId"`
+	Wallet      string    `json:"wallet"`
+	FeePayer    string    `json:"feePayer"`
+	Signature   string    `json:"signature,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// eventBus publishes structured messages to a JetStream stream
+type eventBus struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+// connectEventBus connects to NATS and makes sure the stream capturing our subjects exists
+func connectEventBus(config *EventBusConfig) (*eventBus, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("eventBus: url is required")
+	}
+	subject := config.Subject
+	if subject == "" {
+		subject = "crowdfunding"
+	}
+	stream := config.Stream
+	if stream == "" {
+		stream = "CROWDFUNDING"
+	}
+
+	opts := []nats.Option{nats.Name("crowdfunding-client"), nats.MaxReconnects(-1)}
+	if config.Credentials != "" {
+		opts = append(opts, nats.UserCredentials(config.Credentials))
+	}
+	conn, err := nats.Connect(config.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", config.URL, err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	if _, err := js.StreamInfo(stream); err == nats.ErrStreamNotFound {
+		_, err = js.AddStream(&nats.StreamConfig{Name: stream, Subjects: []string{subject + ".>"}})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to create stream %s: %w", stream, err)
+		}
+	} else if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to look up stream %s: %w", stream, err)
+	}
+
+	return &eventBus{conn: conn, js: js, subject: subject}, nil
+}
+
+// publish sends payload as JSON. JetStream drops a message whose ID it has already stored,
+// so daemons running side by side do not duplicate events.
+func (b *eventBus) publish(subject, msgID string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(b.subject + "." + subject)
+	msg.Data = data
+	if msgID != "" {
+		msg.Header.Set(nats.MsgIdHdr, msgID)
+	}
+	_, err = b.js.PublishMsg(msg)
+	return err
+}
+
+// publishCampaignEvent publishes a decoded on-chain event to <prefix>.events.<campaign>.<type>
+func (b *eventBus) publishCampaignEvent(event CampaignEvent) {
+	id := event.Activity.Signature + ":" + event.Type
+	if err := b.publish("events."+event.Campaign+"."+event.Type, id, event); err != nil {
+		log.Printf("Event bus publish failed: %v", err)
+	}
+}
+
+// Close flushes and closes the connection
+func (b *eventBus) Close() {
+	b.conn.Drain()
+}
+
+// eventBus returns the configured event bus, or nil when there is none or it is unreachable
+func (app *SolanaDApp) eventBus() *eventBus {
+	app.busOnce.Do(func() {
+		if app.config.EventBus == nil {
+			return
+		}
+		bus, err := connectEventBus(app.config.EventBus)
+		if err != nil {
+			log.Printf("Event bus disabled: %v", err)
+			return
+		}
+		app.bus = bus
+	})
+	return app.bus
+}
+
+// publishOperation reports a submitted transaction on the event bus, if one is configured
+func (app *SolanaDApp) publishOperation(operation string, sig solana.Signature, err error) {
+	bus := app.eventBus()
+	if bus == nil {
+		return
+	}
+
+	event := OperationEvent{
+		Type:        "operation",
+		Operation:   operation,
+		OperationID: operationID,
+		Wallet:      app.wallet.Address().String(),
+		FeePayer:    app.payer().String(),
+		Time:        time.Now().UTC(),
+	}
+	if !sig.IsZero() {
+		event.Signature = sig.String()
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	subject := "operations." + strings.NewReplacer(" ", "-", ".", "-").Replace(operation)
+	if err := bus.publish(subject, "", event); err != nil {
+		log.Printf("Event bus publish failed: %v", err)
+	}
+}
+
+// runEventStream forwards the tracked campaigns' live events to the MQTT broker and the event
+// bus, whichever are configured, over one WebSocket subscription until ctx is cancelled
+func (d *Daemon) runEventStream(ctx context.Context) {
+	var handlers []func(CampaignEvent)
+	if d.mqtt != nil {
+		publisher, err := d.startMQTT()
+		if err != nil {
+			logf(ctx, "MQTT publishing disabled: %v", err)
+		} else {
+			defer publisher.client.Disconnect(250)
+			handlers = append(handlers, publisher.handle)
+		}
+	}
+	if bus := d.app.eventBus(); bus != nil {
+		defer bus.Close()
+		fmt.Printf("📡 Streaming events of %d campaign(s) to %s\n", len(d.campaigns), d.app.config.EventBus.URL)
+		handlers = append(handlers, bus.publishCampaignEvent)
+	}
+	if len(handlers) == 0 {
+		return
+	}
+
+	if d.app.wsClient == nil {
+		wsClient, err := dialWS(ctx, d.app.config.wsEndpoint())
+		if err != nil {
+			logf(ctx, "Event streaming disabled: failed to connect to WebSocket: %v", err)
+			return
+		}
+		defer wsClient.Close()
+		d.app.wsClient = wsClient
+	}
+
+	NewEventHub(d.app).Stream(ctx, append([]solana.PublicKey(nil), d.campaigns...), func(event CampaignEvent) {
+		for _, handle := range handlers {
+			handle(event)
+		}
+	})
+}
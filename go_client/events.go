@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// CampaignEvent is a live update about a campaign
+type CampaignEvent struct {
+	Type          string            `json:"type"` // donate, withdraw or comment
+	Campaign      string            `json:"campaign"`
+	Activity      *CampaignActivity `json:"activity"`
+	AmountDonated uint64            `json:"amountDonated"`
+	Lamports      uint64            `json:"lamports"`
+}
+
+// EventHub fans out on-chain campaign activity to any number of subscribers,
+// holding a single WebSocket log subscription per watched campaign
+type EventHub struct {
+	app      *SolanaDApp
+	mu       sync.Mutex
+	watchers map[solana.PublicKey]*campaignWatcher
+}
+
+// campaignWatcher tracks the subscribers of one campaign
+type campaignWatcher struct {
+	subscribers map[chan CampaignEvent]struct{}
+	cancel      context.CancelFunc
+}
+
+// NewEventHub creates a hub using the app's WebSocket connection
+func NewEventHub(app *SolanaDApp) *EventHub {
+	return &EventHub{app: app, watchers: map[solana.PublicKey]*campaignWatcher{}}
+}
+
+// Subscribe streams events for a campaign until the returned cancel function is called.
+// The channel is closed if the underlying subscription fails.
+func (h *EventHub) Subscribe(campaign solana.PublicKey) (<-chan CampaignEvent, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	watcher, ok := h.watchers[campaign]
+	if !ok {
+		if h.app.wsClient == nil {
+			return nil, nil, fmt.Errorf("failed to subscribe to campaign logs: no WebSocket connection")
+		}
+		sub := h.app.wsClient.LogsSubscribeMentions(campaign, rpc.CommitmentConfirmed)
+		ctx, cancel := context.WithCancel(context.Background())
+		watcher = &campaignWatcher{subscribers: map[chan CampaignEvent]struct{}{}, cancel: cancel}
+		h.watchers[campaign] = watcher
+		go h.watch(ctx, campaign, sub)
+	}
+
+	ch := make(chan CampaignEvent, 16)
+	watcher.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := watcher.subscribers[ch]; !ok {
+			return // Already closed by the watcher
+		}
+		delete(watcher.subscribers, ch)
+		close(ch)
+		if len(watcher.subscribers) == 0 && h.watchers[campaign] == watcher {
+			watcher.cancel()
+			delete(h.watchers, campaign)
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+// Stream passes the events of several campaigns to handle until ctx is cancelled, subscribing again
+// with a backoff whenever a subscription fails. handle is called from one goroutine per campaign.
+func (h *EventHub) Stream(ctx context.Context, campaigns []solana.PublicKey, handle func(CampaignEvent)) {
+	var wg sync.WaitGroup
+	for _, campaign := range campaigns {
+		wg.Add(1)
+		go func(campaign solana.PublicKey) {
+			defer wg.Done()
+			backoff := time.Second
+			for ctx.Err() == nil {
+				events, unsubscribe, err := h.Subscribe(campaign)
+				if err != nil {
+					log.Printf("Subscribing to %s failed, retrying in %s: %v", campaign, backoff, err)
+					sleepContext(ctx, backoff)
+					backoff = min(2*backoff, time.Minute)
+					continue
+				}
+				backoff = time.Second
+
+			receive:
+				for {
+					select {
+					case <-ctx.Done():
+						break receive
+					case event, ok := <-events:
+						if !ok {
+							break receive // The subscription failed
+						}
+						handle(event)
+					}
+				}
+				unsubscribe()
+			}
+		}(campaign)
+	}
+	wg.Wait()
+}
+
+// watch turns log notifications for a campaign into decoded events. After the WebSocket
+// reconnects, transactions sent while it was down are fetched by polling.
+func (h *EventHub) watch(ctx context.Context, campaign solana.PublicKey, sub *wsSubscription) {
+	defer sub.Unsubscribe()
+
+	started := time.Now()
+	last := h.latestSignature(ctx, campaign)
+	seen := map[solana.Signature]bool{}
+	handle := func(signature solana.Signature) {
+		if seen[signature] {
+			return // Both notified and found while catching up
+		}
+		if len(seen) >= 1000 {
+			clear(seen)
+		}
+		seen[signature] = true
+		for _, event := range h.decodeEvents(campaign, signature) {
+			h.publish(campaign, event)
+		}
+	}
+
+	for {
+		value, err := sub.Recv(ctx)
+		if errors.Is(err, errWSResubscribed) {
+			for _, signature := range h.missedSignatures(ctx, campaign, last, started) {
+				last = signature
+				handle(signature)
+			}
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Log subscription for %s ended: %v", campaign.String(), err)
+				h.closeWatcher(campaign)
+			}
+			return
+		}
+		result := value.(*ws.LogResult)
+		last = result.Value.Signature
+		if result.Value.Err != nil {
+			continue // Failed transactions move no funds
+		}
+		handle(result.Value.Signature)
+	}
+}
+
+// latestSignature returns the campaign's newest transaction, from which missed ones are polled
+func (h *EventHub) latestSignature(ctx context.Context, campaign solana.PublicKey) solana.Signature {
+	limit := 1
+	signatures, err := h.app.client.GetSignaturesForAddressWithOpts(ctx, campaign, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil || len(signatures) == 0 {
+		return solana.Signature{}
+	}
+	return signatures[0].Signature
+}
+
+// missedSignatures polls the successful transactions of a campaign after last, oldest first.
+// Without a last signature, transactions since the watch started are returned.
+func (h *EventHub) missedSignatures(ctx context.Context, campaign solana.PublicKey, last solana.Signature, started time.Time) []solana.Signature {
+	opts := &rpc.GetSignaturesForAddressOpts{Commitment: rpc.CommitmentConfirmed}
+	if !last.IsZero() {
+		opts.Until = last
+	}
+	signatures, err := h.app.client.GetSignaturesForAddressWithOpts(ctx, campaign, opts)
+	if err != nil {
+		log.Printf("Failed to poll %s for transactions missed while reconnecting: %v", campaign, err)
+		return nil
+	}
+
+	var missed []solana.Signature
+	for i := len(signatures) - 1; i >= 0; i-- {
+		signature := signatures[i]
+		if signature.Err != nil {
+			continue
+		}
+		if last.IsZero() && (signature.BlockTime == nil || signature.BlockTime.Time().Before(started)) {
+			continue
+		}
+		missed = append(missed, signature.Signature)
+	}
+	if len(missed) > 0 {
+		log.Printf("Recovered %d transaction(s) of %s missed while reconnecting", len(missed), campaign)
+	}
+	return missed
+}
+
+// decodeEvents fetches a notified transaction and builds events for its campaign activity
+func (h *EventHub) decodeEvents(campaign solana.PublicKey, signature solana.Signature) []CampaignEvent {
+	var activity []CampaignActivity
+	var err error
+	// The notification can arrive before the transaction is queryable
+	for attempt := 0; attempt < 5; attempt++ {
+		activity, err = h.app.activityInTransaction(campaign, &rpc.TransactionSignature{Signature: signature})
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	if err != nil {
+		log.Printf("Failed to decode campaign transaction %s: %v", signature.String(), err)
+		return nil
+	}
+	if len(activity) == 0 {
+		return nil
+	}
+	h.app.loadCommentFilter().apply(campaign, activity)
+
+	var amountDonated, lamports uint64
+	if c, err := h.app.FetchCampaign(campaign); err == nil {
+		amountDonated = c.AmountDonated
+	}
+	if balance, err := h.app.client.GetBalance(context.Background(), campaign, rpc.CommitmentConfirmed); err == nil {
+		lamports = balance.Value
+	}
+
+	events := make([]CampaignEvent, 0, len(activity))
+	for i := range activity {
+		event := CampaignEvent{
+			Type:          activity[i].Kind,
+			Campaign:      campaign.String(),
+			Activity:      &activity[i],
+			AmountDonated: amountDonated,
+			Lamports:      lamports,
+		}
+		events = append(events, event)
+		// Donations carrying a message also appear on the campaign's message board
+		if activity[i].Memo != "" {
+			event.Type = "comment"
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// publish delivers an event to every subscriber, dropping it for subscribers that are not keeping up
+func (h *EventHub) publish(campaign solana.PublicKey, event CampaignEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	watcher, ok := h.watchers[campaign]
+	if !ok {
+		return
+	}
+	for ch := range watcher.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// closeWatcher drops a failed watcher and closes its subscriber channels
+func (h *EventHub) closeWatcher(campaign solana.PublicKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	watcher, ok := h.watchers[campaign]
+	if !ok {
+		return
+	}
+	for ch := range watcher.subscribers {
+		close(ch)
+	}
+	watcher.subscribers = map[chan CampaignEvent]struct{}{}
+	delete(h.watchers, campaign)
+}
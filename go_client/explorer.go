@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Supported block explorers
+const (
+	ExplorerSolana   = "solana-explorer"
+	ExplorerSolscan  = "solscan"
+	ExplorerSolanaFM = "solanafm"
+	ExplorerXRay     = "xray"
+)
+
+// explorerSpec describes how an explorer builds its URLs
+type explorerSpec struct {
+	addressURL string // format string taking the address
+	txURL      string // format string taking the signature
+	clusterArg func(cluster string) string
+}
+
+var explorers = map[string]explorerSpec{
+	ExplorerSolana: {
+		addressURL: "https://explorer.solana.com/address/%s",
+		txURL:      "https://explorer.solana.com/tx/%s",
+		clusterArg: func(cluster string) string {
+			if cluster == "mainnet-beta" {
+				return ""
+			}
+			return "cluster=" + cluster
+		},
+	},
+	ExplorerSolscan: {
+		addressURL: "https://solscan.io/account/%s",
+		txURL:      "https://solscan.io/tx/%s",
+		clusterArg: func(cluster string) string {
+			if cluster == "mainnet-beta" {
+				return ""
+			}
+			return "cluster=" + cluster
+		},
+	},
+	ExplorerSolanaFM: {
+		addressURL: "https://solana.fm/address/%s",
+		txURL:      "https://solana.fm/tx/%s",
+		clusterArg: func(cluster string) string {
+			switch cluster {
+			case "mainnet-beta":
+				return "cluster=mainnet-alpha"
+			default:
+				return "cluster=" + cluster + "-solana"
+			}
+		},
+	},
+	ExplorerXRay: {
+		addressURL: "https://xray.helius.xyz/account/%s",
+		txURL:      "https://xray.helius.xyz/tx/%s",
+		clusterArg: func(cluster string) string {
+			if cluster == "mainnet-beta" {
+				return "network=mainnet"
+			}
+			return "network=" + cluster
+		},
+	},
+}
+
+// clusterName maps the configured cluster, or failing that the RPC endpoint, to its cluster name
+func clusterName(config *Config) string {
+	if config.Cluster != "" {
+		return config.cluster().Name
+	}
+	switch config.readEndpoint() {
+	case rpc.MainNetBeta_RPC:
+		return "mainnet-beta"
+	case rpc.TestNet_RPC:
+		return "testnet"
+	default:
+		return "devnet"
+	}
+}
+
+// explorer returns the configured explorer, defaulting to Solana Explorer
+func (app *SolanaDApp) explorer() explorerSpec {
+	if spec, ok := explorers[app.config.Explorer]; ok {
+		return spec
+	}
+	return explorers[ExplorerSolana]
+}
+
+// withCluster appends the explorer's cluster query parameter to a URL
+func (spec explorerSpec) withCluster(url, cluster string) string {
+	if arg := spec.clusterArg(cluster); arg != "" {
+		return url + "?" + arg
+	}
+	return url
+}
+
+// AddressURL links to an account in the configured explorer
+func (app *SolanaDApp) AddressURL(address string) string {
+	spec := app.explorer()
+	return spec.withCluster(fmt.Sprintf(spec.addressURL, address), clusterName(app.config))
+}
+
+// TxURL links to a transaction in the configured explorer
+func (app *SolanaDApp) TxURL(signature string) string {
+	spec := app.explorer()
+	return spec.withCluster(fmt.Sprintf(spec.txURL, signature), clusterName(app.config))
+}
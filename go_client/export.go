@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Plaintext accounting formats export writes
+const (
+	ExportLedger    = "ledger"
+	ExportBeancount = "beancount"
+)
+
+// Default account names of an export; {name} is the campaign's name
+const (
+	defaultCampaignAccount = "Assets:Crowdfunding:{name}"
+	defaultDonorAccount    = "Income:Donations"
+	defaultWalletAccount   = "Assets:Treasury"
+)
+
+// ExportConfig maps campaigns and wallets to the account names of the organization's books
+type ExportConfig struct {
+	Accounts  map[string]string `json:"accounts,omitempty"`  // campaign or wallet address -> account name
+	Campaign  string            `json:"campaign,omitempty"`  // account of other campaigns, default Assets:Crowdfunding:{name}
+	Donor     string            `json:"donor,omitempty"`     // account donations come from, default Income:Donations
+	Wallet    string            `json:"wallet,omitempty"`    // account withdrawals go to, default Assets:Treasury
+	Commodity string            `json:"commodity,omitempty"` // default SOL
+}
+
+// accountComponent turns text into a valid account name component: letters, digits and dashes,
+// starting with a capital letter or digit
+func accountComponent(text string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range text {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	component := strings.TrimSuffix(b.String(), "-")
+	if component == "" {
+		return "Unnamed"
+	}
+	return component
+}
+
+// exportAccounts resolves account names from the config
+type exportAccounts struct {
+	config *ExportConfig
+}
+
+// campaign is the account of a campaign's funds
+func (a exportAccounts) campaign(address solana.PublicKey, name string) string {
+	if account, ok := a.config.Accounts[address.String()]; ok {
+		return account
+	}
+	template := a.config.Campaign
+	if template == "" {
+		template = defaultCampaignAccount
+	}
+	if name == "" {
+		name = address.String()
+	}
+	return strings.ReplaceAll(template, "{name}", accountComponent(name))
+}
+
+// wallet is the account of a donor or withdrawal destination
+func (a exportAccounts) wallet(address, fallback string) string {
+	if account, ok := a.config.Accounts[address]; ok {
+		return account
+	}
+	return fallback
+}
+
+// exportEntry is one balanced transaction of an export
+type exportEntry struct {
+	date      time.Time
+	narration string
+	payee     string
+	meta      [][2]string
+	debit     string // account receiving the SOL
+	credit    string // account the SOL leaves
+	amount    uint64
+}
+
+// exportEntries turns movements into entries, leaving out those without a block time
+func exportEntries(movements []CampaignMovement, config *ExportConfig) []exportEntry {
+	accounts := exportAccounts{config}
+	donor, wallet := config.Donor, config.Wallet
+	if donor == "" {
+		donor = defaultDonorAccount
+	}
+	if wallet == "" {
+		wallet = defaultWalletAccount
+	}
+
+	var entries []exportEntry
+	for _, m := range movements {
+		if m.BlockTime == nil {
+			fmt.Printf("⚠️  The node has no block time for %s; it is left out\n", m.Signature)
+			continue
+		}
+		name := m.CampaignName
+		if name == "" {
+			name = m.Campaign.String()
+		}
+		entry := exportEntry{date: *m.BlockTime, payee: m.Wallet, amount: m.Amount}
+		entry.meta = append(entry.meta, [2]string{"signature", m.Signature}, [2]string{"campaign", m.Campaign.String()})
+		if m.Kind == "donate" {
+			entry.narration = "Donation to " + name
+			entry.debit, entry.credit = accounts.campaign(m.Campaign, m.CampaignName), accounts.wallet(m.Wallet, donor)
+			entry.meta = append(entry.meta, [2]string{"donor", m.Wallet})
+			if m.Memo != "" {
+				entry.meta = append(entry.meta, [2]string{"memo", m.Memo})
+			}
+		} else {
+			entry.narration = "Withdrawal from " + name
+			entry.debit, entry.credit = accounts.wallet(m.Wallet, wallet), accounts.campaign(m.Campaign, m.CampaignName)
+			entry.meta = append(entry.meta, [2]string{"destination", m.Wallet})
+		}
+		if m.Valuation != nil {
+			entry.meta = append(entry.meta,
+				[2]string{"value", strconv.FormatFloat(m.Valuation.Of(m.Amount), 'f', 2, 64) + " " + strings.ToUpper(m.Valuation.Currency)},
+				[2]string{"price_source", m.Valuation.Source})
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// writeLedger writes entries in Ledger CLI format
+func writeLedger(w io.Writer, entries []exportEntry, commodity string) error {
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s * %s\n", e.date.UTC().Format("2006/01/02"), strings.ReplaceAll(e.narration, "\n", " "))
+		for _, kv := range e.meta {
+			fmt.Fprintf(w, "    ; %s: %s\n", kv[0], strings.ReplaceAll(kv[1], "\n", " "))
+		}
+		fmt.Fprintf(w, "    %-50s  %s %s\n", e.debit, lamportsToSOL(e.amount), commodity)
+		if _, err := fmt.Fprintf(w, "    %s\n\n", e.credit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// beancountString quotes a Beancount string
+func beancountString(s string) string {
+	return strconv.Quote(strings.ReplaceAll(s, "\n", " "))
+}
+
+// writeBeancount writes entries in Beancount format, opening every account on its first use
+func writeBeancount(w io.Writer, entries []exportEntry, commodity string) error {
+	opened := map[string]time.Time{}
+	for _, e := range entries {
+		for _, account := range []string{e.debit, e.credit} {
+			if first, ok := opened[account]; !ok || e.date.Before(first) {
+				opened[account] = e.date
+			}
+		}
+	}
+	var accounts []string
+	for account := range opened {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	for _, account := range accounts {
+		fmt.Fprintf(w, "%s open %s %s\n", opened[account].UTC().Format("2006-01-02"), account, commodity)
+	}
+	if len(accounts) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s * %s %s\n", e.date.UTC().Format("2006-01-02"), beancountString(e.payee), beancountString(e.narration))
+		for _, kv := range e.meta {
+			fmt.Fprintf(w, "  %s: %s\n", kv[0], beancountString(kv[1]))
+		}
+		fmt.Fprintf(w, "  %-50s  %s %s\n", e.debit, lamportsToSOL(e.amount), commodity)
+		if _, err := fmt.Fprintf(w, "  %-50s  -%s %s\n\n", e.credit, lamportsToSOL(e.amount), commodity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportMovements writes the campaigns' donations and withdrawals in the period as plaintext
+// accounting entries
+func (app *SolanaDApp) ExportMovements(ctx context.Context, w io.Writer, format string, period reportPeriod, campaigns []solana.PublicKey) (int, error) {
+	config := app.config.Export
+	if config == nil {
+		config = &ExportConfig{}
+	}
+	commodity := config.Commodity
+	if commodity == "" {
+		commodity = "SOL"
+	}
+	var write func(io.Writer, []exportEntry, string) error
+	switch format {
+	case ExportLedger:
+		write = writeLedger
+	case ExportBeancount:
+		write = writeBeancount
+	default:
+		return 0, fmt.Errorf("unknown export format %q: use %s or %s", format, ExportLedger, ExportBeancount)
+	}
+
+	store, err := OpenStore()
+	if err != nil {
+		return 0, err
+	}
+	valuer, err := NewValuer(app.config.Prices, store)
+	if err != nil {
+		return 0, err
+	}
+	movements, _, err := app.campaignMovements(ctx, period, campaigns, valuer)
+	if err != nil {
+		return 0, err
+	}
+	entries := exportEntries(movements, config)
+	if err := write(w, entries, commodity); err != nil {
+		return 0, fmt.Errorf("failed to write export: %w", err)
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// faucetStateFile remembers which faucets are cooling down after rate limiting us, across runs
+const faucetStateFile = "faucet.json"
+
+// defaultFaucetCooldown is how long a rate-limited faucet is skipped when it doesn't say
+const defaultFaucetCooldown = time.Hour
+
+// airdropLamports is how much each airdrop asks for
+const airdropLamports = 2 * solana.LAMPORTS_PER_SOL
+
+// errFaucetRateLimited marks a faucet refusing because we asked too often
+var errFaucetRateLimited = errors.New("rate limited")
+
+// Faucet hands out devnet SOL
+type Faucet interface {
+	Name() string
+	// Airdrop sends lamports to wallet. Rate limiting is reported as a faucetRateLimit error.
+	Airdrop(ctx context.Context, wallet solana.PublicKey, lamports uint64) (solana.Signature, error)
+}
+
+// faucetRateLimit is a rate limiting refusal, with how long the faucet asked us to wait if it did
+type faucetRateLimit struct {
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *faucetRateLimit) Error() string { return fmt.Sprintf("rate limited: %v", e.err) }
+func (e *faucetRateLimit) Unwrap() error { return errFaucetRateLimited }
+
+// FaucetConfig configures one faucet in config.json. Faucets are tried in order.
+type FaucetConfig struct {
+	Type     string `json:"type"`               // rpc or web
+	Name     string `json:"name,omitempty"`     // shown in messages, default the URL's host
+	URL      string `json:"url,omitempty"`      // RPC endpoint for rpc (default the cluster's), faucet API for web
+	Method   string `json:"method,omitempty"`   // web: GET or POST, default POST
+	Body     string `json:"body,omitempty"`     // web: request body, default {"address":"{address}","lamports":{lamports}}
+	Field    string `json:"field,omitempty"`    // web: JSON field of the response holding the signature, default signature
+	Cooldown string `json:"cooldown,omitempty"` // skip the faucet this long after it rate limits us, unless it says; default 1h
+}
+
+// faucetFactories builds each faucet type from its config
+var faucetFactories = map[string]func(FaucetConfig) (Faucet, error){
+	"rpc": newRPCFaucet,
+	"web": newWebFaucet,
+}
+
+// rpcFaucet airdrops through an RPC node's requestAirdrop
+type rpcFaucet struct {
+	name   string
+	client *rpc.Client
+}
+
+func newRPCFaucet(config FaucetConfig) (Faucet, error) {
+	endpoint := config.URL
+	if endpoint == "" {
+		endpoint = Network
+	}
+	return &rpcFaucet{name: faucetName(config, endpoint), client: newRPCClient(endpoint, 1)}, nil
+}
+
+func (f *rpcFaucet) Name() string { return f.name }
+
+func (f *rpcFaucet) Airdrop(ctx context.Context, wallet solana.PublicKey, lamports uint64) (solana.Signature, error) {
+	sig, err := f.client.RequestAirdrop(ctx, wallet, lamports, rpc.CommitmentConfirmed)
+	if err == nil {
+		return sig, nil
+	}
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code == http.StatusTooManyRequests {
+		return sig, &faucetRateLimit{err: err}
+	}
+	// Devnet answers an exhausted airdrop allowance with a JSON-RPC error rather than a 429
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "rate limit") || strings.Contains(message, "airdrop request limit") || strings.Contains(message, "faucet has run dry") {
+		return sig, &faucetRateLimit{err: err}
+	}
+	return sig, err
+}
+
+// webFaucet calls an HTTP faucet API. {address} and {lamports} in the URL and body are replaced.
+type webFaucet struct {
+	name   string
+	config FaucetConfig
+	client *http.Client
+}
+
+func newWebFaucet(config FaucetConfig) (Faucet, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("web faucets need a url")
+	}
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+	if config.Body == "" && config.Method == http.MethodPost {
+		config.Body = `{"address":"{address}","lamports":{lamports}}`
+	}
+	if config.Field == "" {
+		config.Field = "signature"
+	}
+	return &webFaucet{name: faucetName(config, config.URL), config: config, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (f *webFaucet) Name() string { return f.name }
+
+func (f *webFaucet) Airdrop(ctx context.Context, wallet solana.PublicKey, lamports uint64) (solana.Signature, error) {
+	expand := strings.NewReplacer("{address}", wallet.String(), "{lamports}", strconv.FormatUint(lamports, 10)).Replace
+	var body io.Reader
+	if f.config.Body != "" {
+		body = strings.NewReader(expand(f.config.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(f.config.Method), expand(f.config.URL), body)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		limit := &faucetRateLimit{err: fmt.Errorf("%s", resp.Status)}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			limit.retryAfter = time.Duration(seconds) * time.Second
+		}
+		return solana.Signature{}, limit
+	}
+	if resp.StatusCode >= 300 {
+		return solana.Signature{}, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to parse faucet response: %w", err)
+	}
+	text, _ := result[f.config.Field].(string)
+	sig, err := solana.SignatureFromBase58(text)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("faucet response has no signature in %q", f.config.Field)
+	}
+	return sig, nil
+}
+
+// faucetName is the configured name, else the endpoint's host
+func faucetName(config FaucetConfig, endpoint string) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return config.Type
+}
+
+// faucetCooldowns maps faucet names to when they may be asked again
+type faucetCooldowns map[string]time.Time
+
+// loadFaucetCooldowns reads the cooldowns, treating a missing or broken file as none
+func loadFaucetCooldowns() faucetCooldowns {
+	cooldowns := faucetCooldowns{}
+	if data, err := os.ReadFile(faucetStateFile); err == nil {
+		json.Unmarshal(data, &cooldowns)
+	}
+	return cooldowns
+}
+
+// save writes the cooldowns still running
+func (c faucetCooldowns) save() {
+	for name, until := range c {
+		if time.Now().After(until) {
+			delete(c, name)
+		}
+	}
+	data, _ := json.MarshalIndent(c, "", "  ")
+	if err := os.WriteFile(faucetStateFile, data, 0644); err != nil {
+		log.Printf("Failed to save faucet cooldowns: %v", err)
+	}
+}
+
+// faucets builds the configured faucets, defaulting to the devnet RPC airdrop
+func (app *SolanaDApp) faucets() ([]Faucet, map[string]time.Duration, error) {
+	configs := app.config.Faucets
+	if len(configs) == 0 {
+		configs = []FaucetConfig{{Type: "rpc", Name: clusterName(app.config) + " RPC", URL: app.config.readEndpoint()}}
+	}
+	var faucets []Faucet
+	cooldowns := map[string]time.Duration{}
+	for _, config := range configs {
+		factory, ok := faucetFactories[config.Type]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown faucet type %q", config.Type)
+		}
+		faucet, err := factory(config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s faucet: %w", config.Type, err)
+		}
+		cooldown := defaultFaucetCooldown
+		if config.Cooldown != "" {
+			if cooldown, err = time.ParseDuration(config.Cooldown); err != nil {
+				return nil, nil, fmt.Errorf("faucet %s: invalid cooldown: %w", faucet.Name(), err)
+			}
+		}
+		faucets = append(faucets, faucet)
+		cooldowns[faucet.Name()] = cooldown
+	}
+	return faucets, cooldowns, nil
+}
+
+// airdrop asks each faucet in turn for SOL, skipping those cooling down after rate limiting us
+// and retrying a faucet once after other failures, and returns the airdrop's signature
+func (app *SolanaDApp) airdrop(ctx context.Context, wallet solana.PublicKey, lamports uint64) (solana.Signature, error) {
+	faucets, cooldownFor, err := app.faucets()
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	cooldowns := loadFaucetCooldowns()
+	defer cooldowns.save()
+
+	var failures []string
+	for _, faucet := range faucets {
+		if until, ok := cooldowns[faucet.Name()]; ok && time.Now().Before(until) {
+			failures = append(failures, fmt.Sprintf("%s: cooling down until %s", faucet.Name(), until.In(displayLocation).Format("15:04 MST")))
+			continue
+		}
+		for attempt := 1; attempt <= 2; attempt++ {
+			fmt.Printf("🚰 Asking %s for %s SOL...\n", faucet.Name(), lamportsToSOL(lamports))
+			sig, err := faucet.Airdrop(ctx, wallet, lamports)
+			if err == nil {
+				return sig, nil
+			}
+			var limit *faucetRateLimit
+			if errors.As(err, &limit) {
+				wait := cooldownFor[faucet.Name()]
+				if limit.retryAfter > 0 {
+					wait = limit.retryAfter
+				}
+				cooldowns[faucet.Name()] = time.Now().Add(wait)
+				fmt.Printf("⏳ %s is rate limiting us; skipping it for %s\n", faucet.Name(), wait.Round(time.Minute))
+				failures = append(failures, fmt.Sprintf("%s: %v", faucet.Name(), err))
+				break
+			}
+			log.Printf("Airdrop from %s failed (attempt %d): %v", faucet.Name(), attempt, err)
+			if attempt == 2 {
+				failures = append(failures, fmt.Sprintf("%s: %v", faucet.Name(), err))
+			} else {
+				sleepContext(ctx, 2*time.Second)
+			}
+		}
+	}
+	return solana.Signature{}, fmt.Errorf("no faucet could airdrop (%s); try https://faucet.solana.com", strings.Join(failures, "; "))
+}
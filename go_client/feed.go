@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// feedEntries is how many recent donations an Atom feed carries
+const feedEntries = 50
+
+// atomFeed is an RFC 4287 Atom feed
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// atomLink, atomAuthor, atomEntry and atomContent are the Atom elements the feed uses
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated string       `xml:"updated"`
+	Link    atomLink     `xml:"link"`
+	Author  atomAuthor   `xml:"author"`
+	Content *atomContent `xml:"content,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// CampaignFeed builds an Atom feed of a campaign's latest donations, one entry per donation,
+// with the donor as author and the donor's message as content
+func (app *SolanaDApp) CampaignFeed(address solana.PublicKey, selfURL string) (*atomFeed, error) {
+	campaign, err := app.FetchCampaign(address)
+	if err != nil {
+		return nil, err
+	}
+	activity, err := app.GetCampaignActivity(address, feedEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &atomFeed{
+		ID:       "urn:solana:" + address.String(),
+		Title:    "Donations to " + campaign.Name,
+		Subtitle: campaign.Description,
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: selfURL},
+			{Rel: "alternate", Href: app.AddressURL(address.String())},
+		},
+		Author: atomAuthor{Name: campaign.Admin.String()},
+	}
+
+	var updated time.Time
+	for _, entry := range activity {
+		if entry.Kind != "donate" || entry.Failed {
+			continue
+		}
+		// Entries without a block time yet are dated now; they get their real time once finalized
+		when := time.Now().UTC()
+		if entry.BlockTime != nil {
+			when = entry.BlockTime.UTC()
+		}
+		if when.After(updated) {
+			updated = when
+		}
+
+		item := atomEntry{
+			ID:      "urn:solana:tx:" + entry.Signature,
+			Title:   fmt.Sprintf("%s SOL from %s", lamportsToSOL(entry.Amount), entry.Wallet),
+			Updated: when.Format(time.RFC3339),
+			Link:    atomLink{Rel: "alternate", Href: app.TxURL(entry.Signature)},
+			Author:  atomAuthor{Name: entry.Wallet},
+		}
+		if entry.Memo != "" {
+			item.Content = &atomContent{Type: "text", Body: entry.Memo}
+		}
+		feed.Entries = append(feed.Entries, item)
+	}
+
+	// Atom requires an updated time; a feed without donations uses the epoch so it stays stable
+	if updated.IsZero() {
+		updated = time.Unix(0, 0).UTC()
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+	return feed, nil
+}
+
+// handleFeed serves a campaign's donations as an Atom feed for feed readers and automation tools
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	address, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		http.Error(w, "invalid campaign address", http.StatusBadRequest)
+		return
+	}
+
+	maxAge := int(s.opts.CacheTTL.Seconds())
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if body, ok := s.cache.get(r.URL.Path); ok {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+		w.Header().Set("X-Cache", "HIT")
+		w.Write(body)
+		return
+	}
+
+	feed, err := s.app.CampaignFeed(address, s.baseURL(r)+r.URL.Path)
+	if err != nil {
+		if errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+			http.Error(w, "not a campaign account", http.StatusNotFound)
+			return
+		}
+		logf(r.Context(), "Feed error on %s: %v", r.URL.Path, err)
+		http.Error(w, "upstream RPC request failed", http.StatusBadGateway)
+		return
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+	s.cache.put(r.URL.Path, body, s.opts.CacheTTL)
+	s.watchForChanges(r.PathValue("address"))
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	w.Header().Set("X-Cache", "MISS")
+	w.Write(body)
+}
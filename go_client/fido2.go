@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// FIDO2 security keys (YubiKey, SoloKey, ...) are driven through the fido2-cred, fido2-assert and
+// fido2-token tools shipped with libfido2, so the client needs no cgo. The tools only relay
+// data: every assertion's signature is checked here against the key enrolled.
+
+// fido2File holds the enrolled security key credential
+const fido2File = "fido2.json"
+
+// fido2RelyingParty scopes credentials to this client
+const fido2RelyingParty = "crowdfunding-client"
+
+// FIDO2Credential is a credential created on a security key, with the hmac-secret extension
+type FIDO2Credential struct {
+	CredentialID string    `json:"credentialId"` // base64
+	PublicKey    string    `json:"publicKey"`    // PEM, verifies assertions
+	Enrolled     time.Time `json:"enrolled,omitempty"`
+}
+
+// fido2Device returns the configured security key, or the first one plugged in
+func fido2Device() (string, error) {
+	if device := loadConfig().FIDO2Device; device != "" {
+		return device, nil
+	}
+	out, err := exec.Command("fido2-token", "-L").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list security keys (is libfido2's fido2-token installed?): %w", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if device, _, ok := strings.Cut(line, ": "); ok && device != "" {
+			return device, nil
+		}
+	}
+	return "", fmt.Errorf("no FIDO2 security key found; plug one in or set fido2Device in %s", configFile)
+}
+
+// runFIDO2Tool runs a libfido2 tool with input lines on stdin and returns its output lines
+func runFIDO2Tool(name string, args []string, input []string) ([]string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(strings.Join(input, "\n") + "\n")
+	cmd.Stderr = os.Stderr // PIN prompts and errors
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return strings.Split(strings.TrimRight(string(out), "\n"), "\n"), nil
+}
+
+// EnrollFIDO2 creates a credential with the hmac-secret extension on the security key
+func EnrollFIDO2(device string) (*FIDO2Credential, error) {
+	clientDataHash := make([]byte, 32)
+	userID := make([]byte, 32)
+	if _, err := rand.Read(clientDataHash); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	if _, err := rand.Read(userID); err != nil {
+		return nil, fmt.Errorf("failed to generate user id: %w", err)
+	}
+
+	fmt.Println("👆 Touch your security key to create the credential...")
+	made, err := runFIDO2Tool("fido2-cred", []string{"-M", "-h", device, "es256"}, []string{
+		base64.StdEncoding.EncodeToString(clientDataHash),
+		fido2RelyingParty,
+		"crowdfunding admin",
+		base64.StdEncoding.EncodeToString(userID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Verifying the attestation yields the credential id and its public key
+	verified, err := runFIDO2Tool("fido2-cred", []string{"-V", "-h", "es256"}, made)
+	if err != nil {
+		return nil, err
+	}
+	if len(verified) < 2 {
+		return nil, fmt.Errorf("unexpected fido2-cred output")
+	}
+	credential := &FIDO2Credential{
+		CredentialID: verified[0],
+		PublicKey:    strings.Join(verified[1:], "\n") + "\n",
+		Enrolled:     time.Now().UTC(),
+	}
+	if _, err := credential.publicKey(); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// publicKey parses the credential's P-256 public key
+func (c *FIDO2Credential) publicKey() (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(c.PublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid credential public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("credential public key is not ES256")
+	}
+	return ecKey, nil
+}
+
+// rawAuthData strips the CBOR byte string header libfido2 wraps authenticator data in
+func rawAuthData(data []byte) []byte {
+	switch {
+	case len(data) > 2 && data[0] == 0x58 && int(data[1]) == len(data)-2:
+		return data[2:]
+	case len(data) > 3 && data[0] == 0x59 && int(binary.BigEndian.Uint16(data[1:3])) == len(data)-3:
+		return data[3:]
+	}
+	return data
+}
+
+// Assert asks for a touch of the security key over challenge and verifies the assertion. With
+// a salt, the hmac-secret extension's output for that salt is returned.
+func (c *FIDO2Credential) Assert(challenge, salt []byte) ([]byte, error) {
+	key, err := c.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	device, err := fido2Device()
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(challenge)
+	args := []string{"-G", "-p"}
+	input := []string{base64.StdEncoding.EncodeToString(clientDataHash[:]), fido2RelyingParty, c.CredentialID}
+	if salt != nil {
+		args = append(args, "-h")
+		input = append(input, base64.StdEncoding.EncodeToString(salt))
+	}
+	fmt.Println("👆 Touch your security key to approve...")
+	out, err := runFIDO2Tool("fido2-assert", append(args, device), input)
+	if err != nil {
+		return nil, err
+	}
+	// client data hash, relying party, authenticator data, signature[, hmac secret]
+	if len(out) < 4 || (salt != nil && len(out) < 5) {
+		return nil, fmt.Errorf("unexpected fido2-assert output")
+	}
+	authData, err := base64.StdEncoding.DecodeString(out[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid authenticator data")
+	}
+	signature, err := base64.StdEncoding.DecodeString(out[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid assertion signature")
+	}
+
+	authData = rawAuthData(authData)
+	rpIDHash := sha256.Sum256([]byte(fido2RelyingParty))
+	if len(authData) < 37 || !bytes.Equal(authData[:32], rpIDHash[:]) {
+		return nil, fmt.Errorf("assertion is for another relying party")
+	}
+	if authData[32]&0x01 == 0 {
+		return nil, fmt.Errorf("the security key was not touched")
+	}
+	signed := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	if !ecdsa.VerifyASN1(key, signed[:], signature) {
+		return nil, fmt.Errorf("assertion signature does not match the enrolled security key")
+	}
+
+	if salt == nil {
+		return nil, nil
+	}
+	// The hmac secret is not covered by the signature, but a wrong one simply fails to decrypt
+	secret, err := base64.StdEncoding.DecodeString(out[len(out)-1])
+	if err != nil || len(secret) != 32 {
+		return nil, fmt.Errorf("invalid hmac-secret output")
+	}
+	return secret, nil
+}
+
+// LoadFIDO2 reads the enrolled credential, returning nil when there is none
+func LoadFIDO2() (*FIDO2Credential, error) {
+	data, err := os.ReadFile(fido2File)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fido2File, err)
+	}
+	var credential FIDO2Credential
+	if err := json.Unmarshal(data, &credential); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", fido2File, err)
+	}
+	return &credential, nil
+}
+
+// Save writes the credential
+func (c *FIDO2Credential) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fido2File, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fido2File, err)
+	}
+	return nil
+}
+
+// approveFIDO2 has the operator touch the enrolled key over the transaction message
+func approveFIDO2(message []byte, w PolicyWithdrawal, rules []string) error {
+	credential, err := LoadFIDO2()
+	if err != nil {
+		return err
+	}
+	if credential == nil {
+		return fmt.Errorf("a security key touch is required but none is enrolled (run `fido2 enroll`)")
+	}
+	fmt.Printf("🔑 %s require a security key touch for withdrawing %s SOL from %s\n", strings.Join(rules, ", "), lamportsToSOL(w.Lamports), w.Campaign)
+	_, err = credential.Assert(message, nil)
+	return err
+}
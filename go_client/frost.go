@@ -0,0 +1,661 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/edwards25519"
+	"github.com/gagliardetto/solana-go"
+)
+
+// Threshold signing with FROST(Ed25519, SHA-512) as specified in RFC 9591. Each participant
+// holds a share of the admin key; any threshold of them produce an ordinary Ed25519
+// signature, so the program cannot tell it apart from a single-key one.
+
+const (
+	frostContext = "FROST-ED25519-SHA512-v1"
+	// frostTokenEnv holds an optional bearer token participants require from the coordinator
+	frostTokenEnv = "CROWDFUNDING_FROST_TOKEN"
+	// frostNonceTTL is how long a participant keeps the nonces of an unfinished signing session
+	frostNonceTTL = 10 * time.Minute
+	// frostMaxSessions caps the unfinished signing sessions a participant keeps nonces for, so
+	// commitment requests can't exhaust its memory, even when no token is required
+	frostMaxSessions = 64
+	// frostApprovalTimeout bounds how long the coordinator waits for participants to approve
+	frostApprovalTimeout = 5 * time.Minute
+)
+
+// FrostShare is one participant's share of a threshold key, kept in its own key file
+type FrostShare struct {
+	Identifier     uint16           `json:"identifier"`
+	SecretShare    string           `json:"secretShare"` // hex scalar
+	GroupPublicKey solana.PublicKey `json:"groupPublicKey"`
+	Threshold      int              `json:"threshold"`
+}
+
+// FrostParticipant is a participant as seen by the coordinator
+type FrostParticipant struct {
+	Identifier     uint16 `json:"identifier"`
+	VerifyingShare string `json:"verifyingShare"`    // hex point, the public half of its share
+	Address        string `json:"address,omitempty"` // URL of its `frost participant` server
+}
+
+// FrostGroup describes a threshold key. Its file can be used wherever a wallet key file is
+// expected: transactions are then signed by asking the participants.
+type FrostGroup struct {
+	GroupPublicKey solana.PublicKey   `json:"groupPublicKey"`
+	Threshold      int                `json:"threshold"`
+	Participants   []FrostParticipant `json:"participants"`
+}
+
+// frostShareFile and frostGroupFile are the key file formats
+type frostShareFile struct {
+	FrostShare *FrostShare `json:"frostShare"`
+}
+
+type frostGroupFile struct {
+	FrostGroup *FrostGroup `json:"frostGroup"`
+}
+
+// frostCommitment is a participant's pair of nonce commitments for one signing session
+type frostCommitment struct {
+	Identifier uint16 `json:"identifier"`
+	Hiding     string `json:"hiding"`
+	Binding    string `json:"binding"`
+}
+
+// frostCommit is a decoded frostCommitment
+type frostCommit struct {
+	id      uint16
+	hiding  *edwards25519.Point
+	binding *edwards25519.Point
+}
+
+// frostDigest is H(contextString || tag || m), the hash behind H1, H3, H4 and H5
+func frostDigest(tag string, parts ...[]byte) []byte {
+	h := sha512.New()
+	h.Write([]byte(frostContext + tag))
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+// frostHashToScalar reduces a 64-byte digest modulo the group order
+func frostHashToScalar(digest []byte) *edwards25519.Scalar {
+	s, err := edwards25519.NewScalar().SetUniformBytes(digest)
+	if err != nil {
+		panic(err) // digest is always 64 bytes
+	}
+	return s
+}
+
+// frostChallenge is H2, the Ed25519 challenge SHA-512(R || A || M)
+func frostChallenge(groupCommitment *edwards25519.Point, groupKey solana.PublicKey, message []byte) *edwards25519.Scalar {
+	h := sha512.New()
+	h.Write(groupCommitment.Bytes())
+	h.Write(groupKey[:])
+	h.Write(message)
+	return frostHashToScalar(h.Sum(nil))
+}
+
+// frostIdentifier encodes a participant identifier as a scalar
+func frostIdentifier(id uint16) *edwards25519.Scalar {
+	var b [32]byte
+	b[0], b[1] = byte(id), byte(id>>8)
+	s, _ := edwards25519.NewScalar().SetCanonicalBytes(b[:])
+	return s
+}
+
+// frostRandomScalar returns a uniformly random scalar
+func frostRandomScalar() (*edwards25519.Scalar, error) {
+	var b [64]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate randomness: %w", err)
+	}
+	return frostHashToScalar(b[:]), nil
+}
+
+// frostNonce derives a nonce from fresh randomness and the secret share, so a weak random
+// source alone does not leak the share
+func frostNonce(secret *edwards25519.Scalar) (*edwards25519.Scalar, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate randomness: %w", err)
+	}
+	return frostHashToScalar(frostDigest("nonce", b[:], secret.Bytes())), nil
+}
+
+func decodeScalar(s string) (*edwards25519.Scalar, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return edwards25519.NewScalar().SetCanonicalBytes(b)
+}
+
+func decodePoint(s string) (*edwards25519.Point, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(edwards25519.Point).SetBytes(b)
+}
+
+// decodeCommitments decodes and sorts a commitment list, rejecting duplicates
+func decodeCommitments(list []frostCommitment) ([]frostCommit, error) {
+	commits := make([]frostCommit, 0, len(list))
+	for _, c := range list {
+		hiding, err := decodePoint(c.Hiding)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hiding commitment of participant %d", c.Identifier)
+		}
+		binding, err := decodePoint(c.Binding)
+		if err != nil {
+			return nil, fmt.Errorf("invalid binding commitment of participant %d", c.Identifier)
+		}
+		commits = append(commits, frostCommit{id: c.Identifier, hiding: hiding, binding: binding})
+	}
+	sort.Slice(commits, func(i, j int) bool { return commits[i].id < commits[j].id })
+	for i := range commits {
+		if commits[i].id == 0 || (i > 0 && commits[i].id == commits[i-1].id) {
+			return nil, fmt.Errorf("invalid or duplicate participant identifier %d", commits[i].id)
+		}
+	}
+	return commits, nil
+}
+
+// frostBindingFactors computes every participant's binding factor for a sorted commitment list
+func frostBindingFactors(groupKey solana.PublicKey, commits []frostCommit, message []byte) map[uint16]*edwards25519.Scalar {
+	var encoded []byte
+	for _, c := range commits {
+		encoded = append(encoded, frostIdentifier(c.id).Bytes()...)
+		encoded = append(encoded, c.hiding.Bytes()...)
+		encoded = append(encoded, c.binding.Bytes()...)
+	}
+	prefix := append(append(append([]byte(nil), groupKey[:]...), frostDigest("msg", message)...), frostDigest("com", encoded)...)
+
+	factors := make(map[uint16]*edwards25519.Scalar, len(commits))
+	for _, c := range commits {
+		factors[c.id] = frostHashToScalar(frostDigest("rho", prefix, frostIdentifier(c.id).Bytes()))
+	}
+	return factors
+}
+
+// frostGroupCommitment is R, the sum of every hiding commitment plus its binding commitment
+// weighted by the binding factor
+func frostGroupCommitment(commits []frostCommit, factors map[uint16]*edwards25519.Scalar) *edwards25519.Point {
+	r := edwards25519.NewIdentityPoint()
+	for _, c := range commits {
+		r.Add(r, c.hiding)
+		r.Add(r, new(edwards25519.Point).ScalarMult(factors[c.id], c.binding))
+	}
+	return r
+}
+
+// frostLagrange is participant id's Lagrange coefficient at 0 among the signing participants
+func frostLagrange(id uint16, commits []frostCommit) *edwards25519.Scalar {
+	x := frostIdentifier(id)
+	numerator, denominator := frostIdentifier(1), frostIdentifier(1)
+	for _, c := range commits {
+		if c.id == id {
+			continue
+		}
+		xj := frostIdentifier(c.id)
+		numerator.Multiply(numerator, xj)
+		denominator.Multiply(denominator, edwards25519.NewScalar().Subtract(xj, x))
+	}
+	return numerator.Multiply(numerator, edwards25519.NewScalar().Invert(denominator))
+}
+
+// SplitFrostKey splits an Ed25519 key into shares, any threshold of which can sign for it.
+// With a nil privateKey a fresh key is generated, which exists only in memory while it is split.
+// The dealer briefly sees the whole key: this is not distributed key generation.
+func SplitFrostKey(privateKey ed25519.PrivateKey, shares, threshold int) ([]FrostShare, *FrostGroup, error) {
+	if threshold < 2 || threshold > shares || shares > 0xffff {
+		return nil, nil, fmt.Errorf("need 2 <= threshold <= shares")
+	}
+
+	var secret *edwards25519.Scalar
+	if privateKey != nil {
+		// An Ed25519 secret scalar is the clamped first half of SHA-512(seed)
+		digest := sha512.Sum512(privateKey.Seed())
+		s, err := edwards25519.NewScalar().SetBytesWithClamping(digest[:32])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive secret scalar: %w", err)
+		}
+		secret = s
+	} else {
+		s, err := frostRandomScalar()
+		if err != nil {
+			return nil, nil, err
+		}
+		secret = s
+	}
+	groupKey := solana.PublicKeyFromBytes(new(edwards25519.Point).ScalarBaseMult(secret).Bytes())
+	if privateKey != nil && !bytes.Equal(groupKey[:], privateKey.Public().(ed25519.PublicKey)) {
+		return nil, nil, fmt.Errorf("derived public key does not match the wallet")
+	}
+
+	coefficients := []*edwards25519.Scalar{secret}
+	for i := 1; i < threshold; i++ {
+		c, err := frostRandomScalar()
+		if err != nil {
+			return nil, nil, err
+		}
+		coefficients = append(coefficients, c)
+	}
+
+	group := &FrostGroup{GroupPublicKey: groupKey, Threshold: threshold}
+	result := make([]FrostShare, shares)
+	for i := range result {
+		id := uint16(i + 1)
+		x := frostIdentifier(id)
+		// Horner's rule
+		y := edwards25519.NewScalar()
+		for c := threshold - 1; c >= 0; c-- {
+			y.MultiplyAdd(y, x, coefficients[c])
+		}
+		result[i] = FrostShare{Identifier: id, SecretShare: hex.EncodeToString(y.Bytes()), GroupPublicKey: groupKey, Threshold: threshold}
+		group.Participants = append(group.Participants, FrostParticipant{
+			Identifier:     id,
+			VerifyingShare: hex.EncodeToString(new(edwards25519.Point).ScalarBaseMult(y).Bytes()),
+		})
+	}
+	return result, group, nil
+}
+
+// LoadFrostShare reads a participant's share file
+func LoadFrostShare(path string) (*FrostShare, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read share file: %w", err)
+	}
+	var file frostShareFile
+	if err := json.Unmarshal(data, &file); err != nil || file.FrostShare == nil {
+		return nil, fmt.Errorf("%s is not a FROST share file", path)
+	}
+	return file.FrostShare, nil
+}
+
+// loadFrostGroup parses a group file, returning nil if data is some other kind of key file
+func loadFrostGroup(data []byte) (*FrostGroup, error) {
+	var file frostGroupFile
+	if err := json.Unmarshal(data, &file); err != nil || file.FrostGroup == nil {
+		return nil, nil
+	}
+	group := file.FrostGroup
+	if group.Threshold < 2 || len(group.Participants) < group.Threshold {
+		return nil, fmt.Errorf("FROST group needs a threshold of at least 2 and as many participants")
+	}
+	return group, nil
+}
+
+// frostPost sends a JSON request to a participant and decodes its JSON response
+func frostPost(ctx context.Context, client *http.Client, address, path string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(address, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := os.Getenv(frostTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&failure)
+		return fmt.Errorf("participant answered %s: %s", resp.Status, failure.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+type frostCommitRequest struct {
+	Session string `json:"session"`
+}
+
+type frostSignRequest struct {
+	Session     string            `json:"session"`
+	Message     string            `json:"message"` // base64 serialized transaction message
+	Commitments []frostCommitment `json:"commitments"`
+}
+
+type frostSignResponse struct {
+	Identifier uint16 `json:"identifier"`
+	Share      string `json:"share"`
+}
+
+// Sign collects nonce commitments from the first threshold participants that answer, has them
+// sign message, checks every signature share and returns the aggregated Ed25519 signature
+func (g *FrostGroup) Sign(message []byte) ([]byte, error) {
+	var sessionBytes [16]byte
+	if _, err := rand.Read(sessionBytes[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate randomness: %w", err)
+	}
+	session := hex.EncodeToString(sessionBytes[:])
+
+	// Round one: commitments
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	client := &http.Client{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var commitments []frostCommitment
+	var failures []string
+	for _, p := range g.Participants {
+		if p.Address == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(p FrostParticipant) {
+			defer wg.Done()
+			var c frostCommitment
+			err := frostPost(ctx, client, p.Address, "/frost/commit", frostCommitRequest{Session: session}, &c)
+			if err == nil && c.Identifier != p.Identifier {
+				err = fmt.Errorf("answered as participant %d", c.Identifier)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("participant %d: %v", p.Identifier, err))
+				return
+			}
+			commitments = append(commitments, c)
+		}(p)
+	}
+	wg.Wait()
+	cancel()
+	if len(commitments) < g.Threshold {
+		return nil, fmt.Errorf("only %d of the %d participants needed are reachable (%s)", len(commitments), g.Threshold, strings.Join(failures, "; "))
+	}
+	sort.Slice(commitments, func(i, j int) bool { return commitments[i].Identifier < commitments[j].Identifier })
+	commitments = commitments[:g.Threshold]
+	commits, err := decodeCommitments(commitments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round two: signature shares, which each participant approves by hand
+	ctx, cancel = context.WithTimeout(context.Background(), frostApprovalTimeout)
+	defer cancel()
+	request := frostSignRequest{Session: session, Message: base64.StdEncoding.EncodeToString(message), Commitments: commitments}
+	shares := make([]*edwards25519.Scalar, len(commits))
+	errs := make([]error, len(commits))
+	for i := range commits {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := g.participant(commits[i].id)
+			var resp frostSignResponse
+			if err := frostPost(ctx, client, p.Address, "/frost/sign", request, &resp); err != nil {
+				errs[i] = fmt.Errorf("participant %d did not sign: %w", p.Identifier, err)
+				return
+			}
+			if shares[i], errs[i] = decodeScalar(resp.Share); errs[i] != nil {
+				errs[i] = fmt.Errorf("participant %d returned a malformed signature share", p.Identifier)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Check each share against the participant's verifying share, so a faulty or malicious
+	// participant is named instead of producing an invalid signature
+	factors := frostBindingFactors(g.GroupPublicKey, commits, message)
+	r := frostGroupCommitment(commits, factors)
+	challenge := frostChallenge(r, g.GroupPublicKey, message)
+	z := edwards25519.NewScalar()
+	for i, c := range commits {
+		verifying, err := decodePoint(g.participant(c.id).VerifyingShare)
+		if err != nil {
+			return nil, fmt.Errorf("invalid verifying share for participant %d in the group file", c.id)
+		}
+		expected := new(edwards25519.Point).ScalarMult(factors[c.id], c.binding)
+		expected.Add(expected, c.hiding)
+		weight := edwards25519.NewScalar().Multiply(challenge, frostLagrange(c.id, commits))
+		expected.Add(expected, new(edwards25519.Point).ScalarMult(weight, verifying))
+		if new(edwards25519.Point).ScalarBaseMult(shares[i]).Equal(expected) != 1 {
+			return nil, fmt.Errorf("participant %d returned an invalid signature share", c.id)
+		}
+		z.Add(z, shares[i])
+	}
+
+	signature := append(r.Bytes(), z.Bytes()...)
+	if !ed25519.Verify(g.GroupPublicKey[:], message, signature) {
+		return nil, fmt.Errorf("aggregated signature does not verify")
+	}
+	return signature, nil
+}
+
+// participant returns the participant with the given identifier
+func (g *FrostGroup) participant(id uint16) FrostParticipant {
+	for _, p := range g.Participants {
+		if p.Identifier == id {
+			return p
+		}
+	}
+	return FrostParticipant{Identifier: id}
+}
+
+// frostNonces are a participant's secret nonces for one session
+type frostNonces struct {
+	hiding, binding *edwards25519.Scalar
+	commitment      frostCommitment
+	created         time.Time
+}
+
+// frostParticipantServer answers a coordinator's commitment and signing requests with one share
+type frostParticipantServer struct {
+	app    *SolanaDApp
+	share  *FrostShare
+	secret *edwards25519.Scalar
+	token  string
+
+	mu     sync.Mutex
+	nonces map[string]*frostNonces
+
+	approveMu sync.Mutex // one approval prompt at a time
+	stdin     *bufio.Reader
+}
+
+func newFrostParticipantServer(app *SolanaDApp, share *FrostShare) (*frostParticipantServer, error) {
+	secret, err := decodeScalar(share.SecretShare)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret share: %w", err)
+	}
+	return &frostParticipantServer{
+		app:    app,
+		share:  share,
+		secret: secret,
+		token:  os.Getenv(frostTokenEnv),
+		nonces: map[string]*frostNonces{},
+		stdin:  bufio.NewReader(os.Stdin),
+	}, nil
+}
+
+func (s *frostParticipantServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/frost/commit", s.handleCommit)
+	mux.HandleFunc("/frost/sign", s.handleSign)
+	return mux
+}
+
+func (s *frostParticipantServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// decode checks the method and token and decodes the request body
+func (s *frostParticipantServer) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		s.writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+		return false
+	}
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		s.writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return false
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(v); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return false
+	}
+	return true
+}
+
+// handleCommit generates a session's nonces and returns their commitments
+func (s *frostParticipantServer) handleCommit(w http.ResponseWriter, r *http.Request) {
+	var req frostCommitRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	hiding, err := frostNonce(s.secret)
+	if err != nil {
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	binding, err := frostNonce(s.secret)
+	if err != nil {
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	commitment := frostCommitment{
+		Identifier: s.share.Identifier,
+		Hiding:     hex.EncodeToString(new(edwards25519.Point).ScalarBaseMult(hiding).Bytes()),
+		Binding:    hex.EncodeToString(new(edwards25519.Point).ScalarBaseMult(binding).Bytes()),
+	}
+
+	s.mu.Lock()
+	for session, n := range s.nonces {
+		if time.Since(n.created) > frostNonceTTL {
+			delete(s.nonces, session)
+		}
+	}
+	if _, exists := s.nonces[req.Session]; exists || req.Session == "" {
+		s.mu.Unlock()
+		s.writeJSON(w, http.StatusConflict, map[string]string{"error": "session already used"})
+		return
+	}
+	if len(s.nonces) >= frostMaxSessions {
+		s.mu.Unlock()
+		s.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "too many unfinished signing sessions, retry later"})
+		return
+	}
+	s.nonces[req.Session] = &frostNonces{hiding: hiding, binding: binding, commitment: commitment, created: time.Now()}
+	s.mu.Unlock()
+
+	s.writeJSON(w, http.StatusOK, commitment)
+}
+
+// handleSign shows the transaction, asks the operator to approve it and returns the signature share
+func (s *frostParticipantServer) handleSign(w http.ResponseWriter, r *http.Request) {
+	var req frostSignRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	// Nonces are single use: take them out before anything else can fail
+	s.mu.Lock()
+	nonces := s.nonces[req.Session]
+	delete(s.nonces, req.Session)
+	s.mu.Unlock()
+	if nonces == nil || time.Since(nonces.created) > frostNonceTTL {
+		s.writeJSON(w, http.StatusConflict, map[string]string{"error": "unknown or expired session"})
+		return
+	}
+
+	message, err := base64.StdEncoding.DecodeString(req.Message)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message encoding"})
+		return
+	}
+	var msg solana.Message
+	if err := msg.UnmarshalBase64(req.Message); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "not a transaction message"})
+		return
+	}
+	if !msg.IsSigner(s.share.GroupPublicKey) {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "the transaction does not need the group key's signature"})
+		return
+	}
+	commits, err := decodeCommitments(req.Commitments)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	ours := false
+	for _, c := range req.Commitments {
+		if c == nonces.commitment {
+			ours = true
+		}
+	}
+	if !ours {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "our commitment is missing from the list"})
+		return
+	}
+
+	if !s.approve(&msg, r.RemoteAddr) {
+		s.writeJSON(w, http.StatusForbidden, map[string]string{"error": "signing rejected by the operator"})
+		return
+	}
+
+	// z_i = d_i + e_i * rho_i + lambda_i * s_i * c
+	factors := frostBindingFactors(s.share.GroupPublicKey, commits, message)
+	challenge := frostChallenge(frostGroupCommitment(commits, factors), s.share.GroupPublicKey, message)
+	share := edwards25519.NewScalar().Multiply(frostLagrange(s.share.Identifier, commits), s.secret)
+	share.Multiply(share, challenge)
+	share.MultiplyAdd(nonces.binding, factors[s.share.Identifier], share)
+	share.Add(share, nonces.hiding)
+
+	fmt.Println("✅ Signature share sent")
+	s.writeJSON(w, http.StatusOK, frostSignResponse{Identifier: s.share.Identifier, Share: hex.EncodeToString(share.Bytes())})
+}
+
+// approve prints the transaction's instructions and asks the operator whether to sign it
+func (s *frostParticipantServer) approve(msg *solana.Message, from string) bool {
+	s.approveMu.Lock()
+	defer s.approveMu.Unlock()
+
+	fmt.Printf("\n🔏 Signing request from %s for %s\n", from, s.share.GroupPublicKey)
+	if len(msg.AccountKeys) > 0 {
+		fmt.Printf("   Fee payer: %s\n", msg.AccountKeys[0])
+	}
+	for i, ix := range msg.Instructions {
+		s.app.printInstruction(fmt.Sprintf("#%d", i+1), msg.AccountKeys, ix.ProgramIDIndex, ix.Accounts, ix.Data)
+	}
+	fmt.Print("\nSign this transaction? [y/N]: ")
+	answer, _ := s.stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"filippo.io/edwards25519"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+)
+
+// startFrostParticipants serves each share as a participant that approves every request, and
+// points the group's participants at them; only the shares in online get a server
+func startFrostParticipants(t *testing.T, group *FrostGroup, shares []FrostShare, online []int) {
+	t.Helper()
+	t.Setenv(frostTokenEnv, "")
+	app := NewDApp(defaultConfig(), crowdfund.NewMockRPC(), crowdfund.NewMockRPC(), nil)
+	for i := range group.Participants {
+		group.Participants[i].Address = ""
+	}
+	for _, i := range online {
+		server, err := newFrostParticipantServer(app, &shares[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.stdin = bufio.NewReader(strings.NewReader(strings.Repeat("y\n", 16)))
+		listener := httptest.NewServer(server.Handler())
+		t.Cleanup(listener.Close)
+		group.Participants[i].Address = listener.URL
+	}
+}
+
+// frostTestMessage is a transaction message the group key has to sign
+func frostTestMessage(t *testing.T, group *FrostGroup) []byte {
+	t.Helper()
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{system.NewTransferInstruction(1000, group.GroupPublicKey, solana.NewWallet().PublicKey()).Build()},
+		solana.Hash{1},
+		solana.TransactionPayer(group.GroupPublicKey),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestFrostSignEveryThresholdSubset(t *testing.T) {
+	shares, group, err := SplitFrostKey(nil, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := frostTestMessage(t, group)
+	subsets(len(shares), func(online []int) {
+		if len(online) != group.Threshold {
+			return
+		}
+		startFrostParticipants(t, group, shares, online)
+		signature, err := group.Sign(message)
+		if err != nil {
+			t.Fatalf("participants %v: %v", online, err)
+		}
+		if !ed25519.Verify(ed25519.PublicKey(group.GroupPublicKey[:]), message, signature) {
+			t.Errorf("participants %v: signature does not verify against the group key", online)
+		}
+	})
+}
+
+func TestFrostSignWithExistingKey(t *testing.T) {
+	key := solana.NewWallet().PrivateKey
+	shares, group, err := SplitFrostKey(ed25519.PrivateKey(key), 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !group.GroupPublicKey.Equals(key.PublicKey()) {
+		t.Fatalf("group key %s, want the wallet's %s", group.GroupPublicKey, key.PublicKey())
+	}
+	startFrostParticipants(t, group, shares, []int{0, 2, 4})
+	message := frostTestMessage(t, group)
+	signature, err := group.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key.PublicKey().Bytes()), message, signature) {
+		t.Error("signature does not verify against the wallet's key")
+	}
+}
+
+func TestFrostSignTooFewParticipants(t *testing.T) {
+	shares, group, err := SplitFrostKey(nil, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	startFrostParticipants(t, group, shares, []int{1})
+	if _, err := group.Sign(frostTestMessage(t, group)); err == nil || !strings.Contains(err.Error(), "reachable") {
+		t.Errorf("Sign with one of two participants: error = %v", err)
+	}
+}
+
+func TestFrostSignRejectsBadShare(t *testing.T) {
+	shares, group, err := SplitFrostKey(nil, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Participant 2 signs with a share that doesn't match its verifying share
+	secret, err := decodeScalar(shares[1].SecretShare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := edwards25519.NewScalar()
+	if _, err := one.SetCanonicalBytes(append([]byte{1}, make([]byte, 31)...)); err != nil {
+		t.Fatal(err)
+	}
+	shares[1].SecretShare = hex.EncodeToString(edwards25519.NewScalar().Add(secret, one).Bytes())
+
+	startFrostParticipants(t, group, shares, []int{0, 1})
+	_, err = group.Sign(frostTestMessage(t, group))
+	if err == nil || !strings.Contains(err.Error(), "participant 2 returned an invalid signature share") {
+		t.Errorf("Sign with a bad share: error = %v, want participant 2 named", err)
+	}
+}
+
+func TestFrostParticipantCapsSessions(t *testing.T) {
+	t.Setenv(frostTokenEnv, "")
+	shares, _, err := SplitFrostKey(nil, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := newFrostParticipantServer(nil, &shares[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.Handler()
+	commit := func(session string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/frost/commit", bytes.NewReader([]byte(`{"session":"`+session+`"}`))))
+		return w.Code
+	}
+
+	for i := 0; i < frostMaxSessions; i++ {
+		if code := commit(hex.EncodeToString([]byte{byte(i)})); code != http.StatusOK {
+			t.Fatalf("session %d: status %d", i, code)
+		}
+	}
+	if code := commit("one-too-many"); code != http.StatusServiceUnavailable {
+		t.Fatalf("session beyond the cap: status %d, want 503", code)
+	}
+
+	// Expired sessions make room again
+	server.mu.Lock()
+	for _, n := range server.nonces {
+		n.created = time.Now().Add(-frostNonceTTL - time.Second)
+	}
+	server.mu.Unlock()
+	if code := commit("after-expiry"); code != http.StatusOK {
+		t.Fatalf("session after the others expired: status %d", code)
+	}
+	if len(server.nonces) != 1 {
+		t.Errorf("%d sessions kept, want 1", len(server.nonces))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// wsStaleAfter marks the WebSocket as unhealthy for readiness
+	wsStaleAfter = 30 * time.Second
+	// wsWedgedAfter fails liveness so the orchestrator restarts the process
+	wsWedgedAfter = 2 * time.Minute
+)
+
+// wsHeartbeat records the last time a slot notification arrived over the WebSocket
+type wsHeartbeat struct {
+	lastSlotAt atomic.Int64 // unix nanoseconds
+	started    time.Time
+}
+
+// run keeps a slot subscription open; the subscription renews itself after reconnects.
+// started is set when the server is created, before run starts.
+func (hb *wsHeartbeat) run(app *SolanaDApp) {
+	sub := app.wsClient.SlotSubscribe()
+	for {
+		if _, err := sub.Recv(context.Background()); err != nil {
+			if errors.Is(err, errWSClosed) {
+				return
+			}
+			continue // Resubscribed after a reconnect: nothing to catch up on
+		}
+		hb.lastSlotAt.Store(time.Now().UnixNano())
+	}
+}
+
+// age reports how long ago the last slot notification arrived
+func (hb *wsHeartbeat) age() time.Duration {
+	last := hb.lastSlotAt.Load()
+	if last == 0 {
+		return time.Since(hb.started)
+	}
+	return time.Since(time.Unix(0, last))
+}
+
+// healthCheck is one entry in a readiness report
+type healthCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// handleHealthz is the liveness probe: it fails only when the WebSocket connection has wedged
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	age := s.heartbeat.age()
+	check := healthCheck{OK: age < wsWedgedAfter, Detail: "last slot notification " + age.Round(time.Second).String() + " ago"}
+	writeHealth(w, check.OK, map[string]healthCheck{"websocket": check})
+}
+
+// handleReadyz is the readiness probe: RPC, WebSocket and signer must all be usable
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]healthCheck{}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := s.app.client.GetSlot(ctx, ""); err != nil {
+		// The error can carry the RPC URL and its API key
+		logf(r.Context(), "Readiness: RPC check failed: %v", err)
+		checks["rpc"] = healthCheck{OK: false, Detail: "upstream RPC request failed"}
+	} else {
+		checks["rpc"] = healthCheck{OK: true}
+	}
+
+	age := s.heartbeat.age()
+	checks["websocket"] = healthCheck{OK: age < wsStaleAfter, Detail: "last slot notification " + age.Round(time.Second).String() + " ago"}
+
+	var signer Signer
+	detail := ""
+	if s.relay != nil {
+		signer, detail = s.relay.wallet, "relay fee payer "
+	} else if s.app.wallet != nil {
+		signer = s.app.wallet
+	}
+	if signer == nil {
+		checks["signer"] = healthCheck{OK: true, Detail: "not required (read-only server)"}
+	} else if err := probeSigner(signer); err != nil {
+		logf(r.Context(), "Readiness: signer %s cannot sign: %v", signer.Address(), err)
+		checks["signer"] = healthCheck{OK: false, Detail: detail + signer.Address().String() + " cannot sign"}
+	} else {
+		checks["signer"] = healthCheck{OK: true, Detail: detail + signer.Address().String()}
+	}
+
+	ready := true
+	for _, check := range checks {
+		ready = ready && check.OK
+	}
+	writeHealth(w, ready, checks)
+}
+
+// probeSigner signs a probe message and checks the signature. A locked keystore that would need
+// a passphrase at the terminal or a security key touch fails instead of prompting, and a FROST
+// group is not asked to sign, since that needs its participants' approval.
+func probeSigner(signer Signer) error {
+	if w, ok := signer.(*Wallet); ok {
+		if w.frost != nil {
+			return nil
+		}
+		if ks := w.keystore; ks != nil && !ks.Unlocked() && (ks.fido2 != nil || os.Getenv(keystorePassphraseEnv) == "") {
+			return fmt.Errorf("keystore is locked and cannot be unlocked unattended")
+		}
+	}
+	probe := []byte("crowdfunding readiness probe")
+	signature, err := signer.SignMessage(probe)
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(signer.Address(), probe) {
+		return fmt.Errorf("the key does not match the address")
+	}
+	return nil
+}
+
+// writeHealth reports probe results as JSON with 200 or 503
+func writeHealth(w http.ResponseWriter, ok bool, checks map[string]healthCheck) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": ok, "checks": checks})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	idlCacheFile     = "idl.json"
+	idlCacheMetaFile = "idl.meta.json"
+)
+
+// defaultIDLCacheTTL is how old the cached IDL may get before it is fetched again when config.json does not say otherwise
+const defaultIDLCacheTTL = 24 * time.Hour
+
+// idlCacheMeta records which deployment of the program the cached IDL was fetched for
+type idlCacheMeta struct {
+	Program    string    `json:"program"`
+	DeploySlot uint64    `json:"deploySlot"` // the program's last deploy slot at fetch time, 0 when unknown
+	Fetched    time.Time `json:"fetched"`
+}
+
+// loadIDLCacheMeta returns the metadata of the cached IDL, or nil when it is missing or for another program
+func loadIDLCacheMeta(programID solana.PublicKey) *idlCacheMeta {
+	data, err := os.ReadFile(idlCacheMetaFile)
+	if err != nil {
+		return nil
+	}
+	var meta idlCacheMeta
+	if err := json.Unmarshal(data, &meta); err != nil || meta.Program != programID.String() {
+		return nil
+	}
+	return &meta
+}
+
+// IDL is the subset of an Anchor IDL used by the client
+type IDL = crowdfund.IDL
+
+// IDLInstruction describes a program instruction
+type IDLInstruction = crowdfund.IDLInstruction
+
+// loadIDL returns the cached on-chain IDL when it matches the program, falling back to the built-in one
+func loadIDL(programID solana.PublicKey) *IDL {
+	data, err := os.ReadFile(idlCacheFile)
+	if err != nil {
+		return crowdfund.DefaultIDL()
+	}
+
+	var idl IDL
+	if err := json.Unmarshal(data, &idl); err != nil {
+		fmt.Printf("⚠️  Ignoring unreadable IDL cache %s: %v\n", idlCacheFile, err)
+		return crowdfund.DefaultIDL()
+	}
+	if idl.Address != "" && idl.Address != programID.String() {
+		fmt.Printf("⚠️  Ignoring IDL cache for a different program (%s)\n", idl.Address)
+		return crowdfund.DefaultIDL()
+	}
+
+	return &idl
+}
+
+// BuildInstruction builds a program instruction from the IDL, filling account metas by name
+func (app *SolanaDApp) BuildInstruction(name string, accounts map[string]solana.PublicKey, args map[string]interface{}) (*solana.GenericInstruction, error) {
+	return app.idl.BuildInstruction(app.programID, name, accounts, args)
+}
+
+// refreshIDL re-fetches the cached IDL when the program was redeployed since it was fetched, or
+// when it is older than idlCacheTTL. Without a cache it does nothing: the built-in IDL is used
+// until `idl fetch` is run. On failure the cached IDL is kept.
+func (app *SolanaDApp) refreshIDL() {
+	if _, err := os.Stat(idlCacheFile); err != nil {
+		return
+	}
+
+	var reason string
+	var deploySlot uint64
+	info, err := app.GetProgramUpgradeInfo()
+	if err == nil {
+		deploySlot = info.LastDeploySlot
+	}
+	meta := loadIDLCacheMeta(app.programID)
+	switch {
+	case meta == nil:
+		reason = "no record of the deployment it was fetched for"
+	case deploySlot > meta.DeploySlot:
+		reason = fmt.Sprintf("the program was redeployed at slot %d", deploySlot)
+	case app.config.idlCacheTTL > 0 && time.Since(meta.Fetched) > app.config.idlCacheTTL:
+		reason = fmt.Sprintf("older than %s", app.config.idlCacheTTL)
+	default:
+		return
+	}
+
+	if _, err := app.fetchIDL(deploySlot); err != nil {
+		fmt.Printf("⚠️  Could not refresh %s (%s), using the cached copy: %v\n", idlCacheFile, reason, err)
+		return
+	}
+	fmt.Printf("🔄 Refreshed %s: %s\n", idlCacheFile, reason)
+}
+
+// FetchIDL downloads and inflates the on-chain IDL, then caches it locally
+func (app *SolanaDApp) FetchIDL() (*IDL, error) {
+	var deploySlot uint64
+	if info, err := app.GetProgramUpgradeInfo(); err == nil {
+		deploySlot = info.LastDeploySlot
+	}
+	return app.fetchIDL(deploySlot)
+}
+
+// fetchIDL downloads the on-chain IDL and caches it, noting the program deployment it belongs to
+func (app *SolanaDApp) fetchIDL(deploySlot uint64) (*IDL, error) {
+	idlAddress, err := crowdfund.IDLAddress(app.programID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive IDL address: %w", err)
+	}
+
+	accountInfo, err := app.client.GetAccountInfo(context.Background(), idlAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IDL account %s: %w", idlAddress.String(), err)
+	}
+	if accountInfo.Value == nil {
+		return nil, fmt.Errorf("no IDL account found at %s", idlAddress.String())
+	}
+
+	raw, err := crowdfund.DecodeIDLAccount(accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("account %s: %w", idlAddress.String(), err)
+	}
+
+	var idl IDL
+	if err := json.Unmarshal(raw, &idl); err != nil {
+		return nil, fmt.Errorf("failed to parse IDL: %w", err)
+	}
+
+	if err := os.WriteFile(idlCacheFile, raw, 0644); err != nil {
+		return nil, fmt.Errorf("failed to cache IDL: %w", err)
+	}
+	meta, err := json.MarshalIndent(idlCacheMeta{Program: app.programID.String(), DeploySlot: deploySlot, Fetched: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode IDL cache metadata: %w", err)
+	}
+	if err := os.WriteFile(idlCacheMetaFile, meta, 0644); err != nil {
+		return nil, fmt.Errorf("failed to cache IDL metadata: %w", err)
+	}
+
+	app.idl = &idl
+	return &idl, nil
+}
+
+// formatArg renders a decoded argument, adding a SOL conversion for lamport amounts
+func formatArg(arg crowdfund.DecodedArg) string {
+	switch v := arg.Value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case uint64:
+		if crowdfund.NormalizeName(arg.Name) == "amount" {
+			return fmt.Sprintf("%d lamports (%.9f SOL)", v, float64(v)/float64(solana.LAMPORTS_PER_SOL))
+		}
+		return strconv.FormatUint(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// deployTestProgram puts the program on the mock cluster as an upgradeable program last deployed
+// at slot, with an on-chain IDL of the given version
+func deployTestProgram(t *testing.T, app *SolanaDApp, mock *crowdfund.MockRPC, slot uint64, version string) {
+	t.Helper()
+	programData := solana.NewWallet().PublicKey()
+	program := binary.LittleEndian.AppendUint32(nil, 2)
+	mock.SetAccount(app.programID, solana.BPFLoaderUpgradeableProgramID, solana.LAMPORTS_PER_SOL, append(program, programData.Bytes()...))
+	header := binary.LittleEndian.AppendUint32(nil, 3)
+	header = append(binary.LittleEndian.AppendUint64(header, slot), 0)
+	mock.SetAccount(programData, solana.BPFLoaderUpgradeableProgramID, solana.LAMPORTS_PER_SOL, header)
+
+	idl := crowdfund.DefaultIDL()
+	idl.Version = version
+	raw, err := json.Marshal(idl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(raw)
+	zw.Close()
+	data := crowdfund.Discriminator("account", "IdlAccount")
+	data = append(data, solana.NewWallet().PublicKey().Bytes()...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(compressed.Len()))
+	address, err := crowdfund.IDLAddress(app.programID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.SetAccount(address, app.programID, solana.LAMPORTS_PER_SOL, append(data, compressed.Bytes()...))
+}
+
+// cachedIDLVersion is the version of the IDL in idl.json
+func cachedIDLVersion(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(idlCacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idl IDL
+	if err := json.Unmarshal(data, &idl); err != nil {
+		t.Fatal(err)
+	}
+	return idl.Version
+}
+
+// ageIDLCache backdates the recorded fetch time of idl.json
+func ageIDLCache(t *testing.T, age time.Duration) {
+	t.Helper()
+	data, err := os.ReadFile(idlCacheMetaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta idlCacheMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	meta.Fetched = meta.Fetched.Add(-age)
+	if data, err = json.Marshal(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(idlCacheMetaFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshIDLAfterRedeploy(t *testing.T) {
+	app, mock := newTestDApp(t)
+	deployTestProgram(t, app, mock, 100, "0.1.0")
+	if _, err := app.FetchIDL(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged deployment and a fresh cache: nothing to do
+	deployTestProgram(t, app, mock, 100, "0.2.0")
+	app.refreshIDL()
+	if v := cachedIDLVersion(t); v != "0.1.0" {
+		t.Fatalf("cache refreshed without a redeploy: version %s", v)
+	}
+
+	deployTestProgram(t, app, mock, 200, "0.2.0")
+	app.refreshIDL()
+	if v := cachedIDLVersion(t); v != "0.2.0" || app.idl.Version != "0.2.0" {
+		t.Fatalf("after a redeploy: cached version %s, in use %s; want 0.2.0", v, app.idl.Version)
+	}
+	if meta := loadIDLCacheMeta(app.programID); meta == nil || meta.DeploySlot != 200 {
+		t.Errorf("cache metadata = %+v, want deploy slot 200", meta)
+	}
+}
+
+func TestRefreshIDLAfterTTL(t *testing.T) {
+	app, mock := newTestDApp(t)
+	deployTestProgram(t, app, mock, 100, "0.1.0")
+	if _, err := app.FetchIDL(); err != nil {
+		t.Fatal(err)
+	}
+
+	deployTestProgram(t, app, mock, 100, "0.2.0")
+	ageIDLCache(t, defaultIDLCacheTTL-time.Minute)
+	app.refreshIDL()
+	if v := cachedIDLVersion(t); v != "0.1.0" {
+		t.Fatalf("cache refreshed before its TTL: version %s", v)
+	}
+
+	ageIDLCache(t, 2*time.Minute)
+	app.refreshIDL()
+	if v := cachedIDLVersion(t); v != "0.2.0" {
+		t.Fatalf("cache past its TTL not refreshed: version %s", v)
+	}
+}
+
+func TestRefreshIDLKeepsCacheOnFailure(t *testing.T) {
+	app, mock := newTestDApp(t)
+	deployTestProgram(t, app, mock, 100, "0.1.0")
+	if _, err := app.FetchIDL(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Redeployed, but the IDL account can't be read
+	deployTestProgram(t, app, mock, 200, "0.2.0")
+	address, err := crowdfund.IDLAddress(app.programID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.SetAccount(address, app.programID, solana.LAMPORTS_PER_SOL, []byte("garbage"))
+	app.refreshIDL()
+	if v := cachedIDLVersion(t); v != "0.1.0" || app.idl.Version != "0.1.0" {
+		t.Fatalf("failed refresh: cached version %s, in use %s; want 0.1.0 kept", v, app.idl.Version)
+	}
+	if meta := loadIDLCacheMeta(app.programID); meta == nil || meta.DeploySlot != 100 {
+		t.Errorf("cache metadata = %+v, want deploy slot 100 kept", meta)
+	}
+}
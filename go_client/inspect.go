@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/mr-tron/base58"
+)
+
+// fetchTransaction loads a confirmed transaction and resolves its full account key list
+func (app *SolanaDApp) fetchTransaction(signature solana.Signature) (*rpc.GetTransactionResult, *solana.Transaction, solana.PublicKeySlice, error) {
+	maxVersion := uint64(0)
+	result, err := app.client.GetTransaction(context.Background(), signature, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to fetch transaction %s: %w", signature.String(), err)
+	}
+
+	tx, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode transaction %s: %w", signature.String(), err)
+	}
+	if result.Meta != nil && tx.Message.IsVersioned() {
+		if err := tx.Message.ResolveLookupsWith(result.Meta.LoadedAddresses.Writable, result.Meta.LoadedAddresses.ReadOnly); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to resolve address lookup tables: %w", err)
+		}
+	}
+	keys, err := tx.Message.GetAllKeys()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to resolve account keys: %w", err)
+	}
+
+	return result, tx, keys, nil
+}
+
+// DecodeTransaction fetches a transaction and prints a breakdown of every crowdfunding instruction in it
+func (app *SolanaDApp) DecodeTransaction(signature solana.Signature) error {
+	result, tx, keys, err := app.fetchTransaction(signature)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n🔎 Transaction %s\n", signature.String())
+	fmt.Printf("   🔗 %s\n", app.TxURL(signature.String()))
+	fmt.Printf("   Slot: %d\n", result.Slot)
+	if result.BlockTime != nil {
+		fmt.Printf("   Block Time: %s\n", formatTime(result.BlockTime.Time()))
+	}
+	if result.Meta != nil {
+		if result.Meta.Err != nil {
+			fmt.Printf("   Status: ❌ Failed (%v)\n", app.idl.DecodeError(fmt.Errorf("%v", result.Meta.Err)))
+		} else {
+			fmt.Println("   Status: ✅ Success")
+		}
+		fmt.Printf("   Fee: %d lamports\n", result.Meta.Fee)
+	}
+
+	found := 0
+	for i, ix := range tx.Message.Instructions {
+		if app.printInstruction(fmt.Sprintf("#%d", i+1), keys, ix.ProgramIDIndex, ix.Accounts, ix.Data) {
+			found++
+		}
+		if result.Meta == nil {
+			continue
+		}
+		for _, inner := range result.Meta.InnerInstructions {
+			if int(inner.Index) != i {
+				continue
+			}
+			for j, innerIx := range inner.Instructions {
+				if app.printInstruction(fmt.Sprintf("#%d.%d (inner)", i+1, j+1), keys, innerIx.ProgramIDIndex, innerIx.Accounts, innerIx.Data) {
+					found++
+				}
+			}
+		}
+	}
+	if found == 0 {
+		fmt.Println("\nℹ️  No crowdfunding program instructions in this transaction")
+	}
+
+	if result.Meta != nil && len(result.Meta.LogMessages) > 0 {
+		fmt.Println("\n📜 Logs:")
+		for _, line := range result.Meta.LogMessages {
+			fmt.Printf("   %s\n", line)
+		}
+	}
+
+	return nil
+}
+
+// printInstruction prints one compiled instruction and reports whether it belongs to our program
+func (app *SolanaDApp) printInstruction(label string, keys solana.PublicKeySlice, programIndex uint16, accounts []uint16, data []byte) bool {
+	if int(programIndex) >= len(keys) {
+		fmt.Printf("\n⚠️  Instruction %s references missing program index %d\n", label, programIndex)
+		return false
+	}
+	programID := keys[programIndex]
+	if !programID.Equals(app.programID) {
+		fmt.Printf("\n▫️  Instruction %s: %s program (not crowdfunding)\n", label, programID.String())
+		return false
+	}
+
+	ix, ok := app.idl.MatchInstruction(data)
+	if !ok {
+		fmt.Printf("\n📦 Instruction %s: unknown crowdfunding instruction\n", label)
+		fmt.Printf("   Data: %s\n", hex.EncodeToString(data))
+		return true
+	}
+
+	fmt.Printf("\n📦 Instruction %s: %s\n", label, ix.Name)
+	fmt.Println("   Accounts:")
+	for i, index := range accounts {
+		role := fmt.Sprintf("account %d", i)
+		if i < len(ix.Accounts) {
+			role = ix.Accounts[i].Name
+		}
+		key := "<missing>"
+		if int(index) < len(keys) {
+			key = keys[index].String()
+		}
+		fmt.Printf("     %-15s %s\n", role+":", key)
+	}
+
+	args, err := ix.DecodeArgs(data)
+	fmt.Println("   Args:")
+	for _, arg := range args {
+		fmt.Printf("     %-15s %s\n", arg.Name+":", formatArg(arg))
+	}
+	if err != nil {
+		fmt.Printf("   ⚠️  Could not fully decode arguments: %v\n", err)
+	}
+
+	return true
+}
+
+// inspectDumpLimit is how many bytes of account data inspect dumps unless asked for all of them
+const inspectDumpLimit = 512
+
+// knownPrograms names the owners inspect recognizes besides our program
+var knownPrograms = map[solana.PublicKey]string{
+	solana.SystemProgramID:                    "System Program",
+	solana.BPFLoaderUpgradeableProgramID:      "BPF Upgradeable Loader",
+	solana.BPFLoaderProgramID:                 "BPF Loader",
+	solana.TokenProgramID:                     "SPL Token",
+	solana.Token2022ProgramID:                 "SPL Token-2022",
+	solana.SPLAssociatedTokenAccountProgramID: "Associated Token Account",
+	solana.StakeProgramID:                     "Stake Program",
+	solana.VoteProgramID:                      "Vote Program",
+	solana.AddressLookupTableProgramID:        "Address Lookup Table",
+	solana.ConfigProgramID:                    "Config Program",
+	solana.FeatureProgramID:                   "Feature Program",
+}
+
+// InspectedAccount is what inspect found at an address
+type InspectedAccount struct {
+	Address    string              `json:"address"`
+	Exists     bool                `json:"exists"`
+	Owner      string              `json:"owner,omitempty"`
+	OwnerName  string              `json:"ownerName,omitempty"`
+	Lamports   uint64              `json:"lamports"`
+	RentExempt uint64              `json:"rentExemptMinimum"` // for the account's data size
+	Executable bool                `json:"executable"`
+	Type       string              `json:"type,omitempty"` // campaign, idl or unknown for our program's accounts
+	Campaign   *crowdfund.Campaign `json:"campaign,omitempty"`
+	PDAValid   *bool               `json:"pdaValid,omitempty"` // whether a campaign is at the address its admin and name derive
+	Authority  string              `json:"idlAuthority,omitempty"`
+	IDLName    string              `json:"idlName,omitempty"`
+	Data       []byte              `json:"data"`
+}
+
+// InspectAccount fetches any account and decodes it when it is one of our program's
+func (app *SolanaDApp) InspectAccount(ctx context.Context, address solana.PublicKey) (*InspectedAccount, error) {
+	info := &InspectedAccount{Address: address.String()}
+	result, err := app.client.GetAccountInfoWithOpts(ctx, address, &rpc.GetAccountInfoOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && result.Value == nil) {
+		return info, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account: %w", err)
+	}
+	account := result.Value
+	info.Exists = true
+	info.Owner, info.Lamports, info.Executable = account.Owner.String(), account.Lamports, account.Executable
+	info.Data = account.Data.GetBinary()
+	info.OwnerName = knownPrograms[account.Owner]
+	if info.RentExempt, err = app.client.GetMinimumBalanceForRentExemption(ctx, uint64(len(info.Data)), rpc.CommitmentConfirmed); err != nil {
+		return nil, fmt.Errorf("failed to fetch the rent-exempt minimum: %w", err)
+	}
+	if !account.Owner.Equals(app.programID) {
+		return info, nil
+	}
+
+	info.OwnerName = "crowdfunding program"
+	data := info.Data
+	switch {
+	case len(data) >= 8 && bytes.Equal(data[:8], crowdfund.Discriminator("account", "Campaign")):
+		info.Type = "campaign"
+		campaign, err := crowdfund.DecodeCampaign(data)
+		if err != nil {
+			return info, nil // Shown as unknown data with the hex dump
+		}
+		info.Campaign = campaign
+		if derived, _, err := app.CampaignPDAFor(campaign.Admin, campaign.Name); err == nil {
+			valid := derived.Equals(address)
+			info.PDAValid = &valid
+		}
+	case len(data) >= 44 && bytes.Equal(data[:8], crowdfund.Discriminator("account", "IdlAccount")):
+		info.Type = "idl"
+		info.Authority = solana.PublicKeyFromBytes(data[8:40]).String()
+		if raw, err := crowdfund.DecodeIDLAccount(data); err == nil {
+			var idl crowdfund.IDL
+			if json.Unmarshal(raw, &idl) == nil {
+				info.IDLName = idl.ProgramName()
+			}
+		}
+	default:
+		info.Type = "unknown"
+	}
+	return info, nil
+}
+
+// Print shows the account for a person, dumping at most inspectDumpLimit bytes of data unless full
+func (info *InspectedAccount) Print(app *SolanaDApp, full bool) {
+	fmt.Printf("\n🔎 Account %s\n", info.Address)
+	fmt.Printf("   🔗 %s\n", app.AddressURL(info.Address))
+	if !info.Exists {
+		fmt.Println("   ❌ No account at this address (never funded, or closed)")
+		return
+	}
+	owner := info.Owner
+	if info.OwnerName != "" {
+		owner += " (" + info.OwnerName + ")"
+	}
+	fmt.Printf("   Owner: %s\n", owner)
+	fmt.Printf("   Balance: %s SOL (%d lamports)\n", lamportsToSOL(info.Lamports), info.Lamports)
+	if info.Lamports >= info.RentExempt {
+		fmt.Printf("   Rent: ✅ exempt (minimum %s SOL)\n", lamportsToSOL(info.RentExempt))
+	} else {
+		fmt.Printf("   Rent: ⚠️  below the rent-exempt minimum of %s SOL\n", lamportsToSOL(info.RentExempt))
+	}
+	fmt.Printf("   Executable: %v\n", info.Executable)
+	fmt.Printf("   Data: %d bytes\n", len(info.Data))
+
+	decoded := false
+	switch info.Type {
+	case "campaign":
+		if info.Campaign == nil {
+			fmt.Println("\n⚠️  Campaign discriminator, but the data does not decode as a campaign")
+			break
+		}
+		decoded = true
+		c := info.Campaign
+		fmt.Println("\n📦 Campaign account")
+		fmt.Printf("   Name: %s\n", c.Name)
+		fmt.Printf("   Description: %s\n", c.Description)
+		fmt.Printf("   Admin: %s\n", c.Admin)
+		fmt.Printf("   Amount Donated: %s SOL\n", lamportsToSOL(c.AmountDonated))
+		fmt.Printf("   Bump: %d\n", c.Bump)
+		if info.Lamports > info.RentExempt {
+			fmt.Printf("   Withdrawable: %s SOL\n", lamportsToSOL(info.Lamports-info.RentExempt))
+		}
+		if info.PDAValid != nil && *info.PDAValid {
+			fmt.Println("   Address: ✅ derived from the admin and name")
+		} else if info.PDAValid != nil {
+			fmt.Printf("   Address: ⚠️  not the one derived from the admin and name with seed %q\n", app.campaignSeed())
+		}
+	case "idl":
+		decoded = true
+		fmt.Println("\n📦 Anchor IDL account")
+		fmt.Printf("   Authority: %s\n", info.Authority)
+		if len(info.Data) >= 44 {
+			fmt.Printf("   Compressed IDL: %d bytes\n", binary.LittleEndian.Uint32(info.Data[40:44]))
+		}
+		if info.IDLName != "" {
+			fmt.Printf("   Program: %s\n", info.IDLName)
+		}
+	case "unknown":
+		fmt.Printf("\n❓ Crowdfunding program account with unknown discriminator %s\n", hex.EncodeToString(info.Data[:min(8, len(info.Data))]))
+	}
+
+	if len(info.Data) == 0 || (decoded && !full) {
+		return
+	}
+	dump := info.Data
+	if !full && len(dump) > inspectDumpLimit {
+		dump = dump[:inspectDumpLimit]
+	}
+	fmt.Println("\n📄 Data:")
+	os.Stdout.WriteString(hex.Dump(dump))
+	if len(dump) < len(info.Data) {
+		fmt.Printf("   ... %d more bytes; use -full to dump them all\n", len(info.Data)-len(dump))
+	}
+}
+
+// parseInstructionData decodes instruction data in the given encoding. With "auto" it tries hex
+// (optionally 0x-prefixed), then base64, then base58 as explorers show it; base58 text can also
+// be valid base64, so the encoding used is returned.
+func parseInstructionData(text, encoding string) ([]byte, string, error) {
+	text = strings.Join(strings.Fields(text), "")
+	decoders := []struct {
+		name   string
+		decode func(string) ([]byte, error)
+	}{
+		{"hex", func(s string) ([]byte, error) { return hex.DecodeString(strings.TrimPrefix(strings.ToLower(s), "0x")) }},
+		{"base64", base64.StdEncoding.DecodeString},
+		{"base58", base58.Decode},
+	}
+	for _, d := range decoders {
+		if encoding != "auto" && encoding != d.name {
+			continue
+		}
+		data, err := d.decode(text)
+		if err == nil && len(data) > 0 {
+			return data, d.name, nil
+		}
+		if encoding != "auto" {
+			return nil, "", fmt.Errorf("invalid %s instruction data: %v", d.name, err)
+		}
+	}
+	if encoding != "auto" {
+		return nil, "", fmt.Errorf("unknown encoding %q: use hex, base64 or base58", encoding)
+	}
+	return nil, "", fmt.Errorf("not hex, base64 or base58 instruction data")
+}
+
+// Disassemble matches raw instruction data against the program's instructions and prints its
+// decoded Borsh arguments
+func (app *SolanaDApp) Disassemble(data []byte) error {
+	fmt.Printf("\n🔬 %d bytes: %s\n", len(data), hex.EncodeToString(data))
+	if len(data) < 8 {
+		return fmt.Errorf("instruction data is %d bytes, too short for an 8-byte discriminator", len(data))
+	}
+	fmt.Printf("   Discriminator: %s\n", hex.EncodeToString(data[:8]))
+
+	ix, ok := app.idl.MatchInstruction(data)
+	if !ok {
+		if bytes.Equal(data[:8], crowdfund.Discriminator("account", "Campaign")) {
+			fmt.Println("\nℹ️  This is campaign account data, not an instruction: use `inspect <address>`")
+		}
+		fmt.Printf("\n❓ No %s instruction has this discriminator. Known instructions:\n", app.idl.ProgramName())
+		for i := range app.idl.Instructions {
+			known := &app.idl.Instructions[i]
+			fmt.Printf("   %-15s %s\n", known.Name, hex.EncodeToString(known.DiscriminatorBytes()))
+		}
+		return fmt.Errorf("unknown instruction discriminator %s", hex.EncodeToString(data[:8]))
+	}
+
+	fmt.Printf("\n📦 %s\n", ix.Name)
+	if len(ix.Accounts) > 0 {
+		fmt.Println("   Accounts expected:")
+		for i, account := range ix.Accounts {
+			fmt.Printf("     %d. %s\n", i, account.Name)
+		}
+	}
+	args, err := ix.DecodeArgs(data)
+	fmt.Println("   Args:")
+	offset := 8
+	for _, arg := range args {
+		end := offset + encodedArgSize(arg)
+		fmt.Printf("     %-15s %s\n", arg.Name+":", formatArg(arg))
+		fmt.Printf("     %-15s %s (%s, bytes %d-%d)\n", "", hex.EncodeToString(data[offset:end]), arg.Type, offset, end-1)
+		offset = end
+	}
+	if err != nil {
+		fmt.Printf("   ⚠️  %v\n", err)
+		if offset < len(data) {
+			fmt.Printf("   Undecoded: %s (bytes %d-%d)\n", hex.EncodeToString(data[offset:]), offset, len(data)-1)
+		}
+		return fmt.Errorf("instruction data does not match the %s arguments", ix.Name)
+	}
+	return nil
+}
+
+// encodedArgSize is how many bytes a decoded argument took in Borsh
+func encodedArgSize(arg crowdfund.DecodedArg) int {
+	switch v := arg.Value.(type) {
+	case string:
+		return 4 + len(v)
+	case uint64:
+		return 8
+	case uint32:
+		return 4
+	case uint8, bool:
+		return 1
+	case solana.PublicKey:
+		return solana.PublicKeyLength
+	}
+	return 0
+}
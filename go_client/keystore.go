@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/term"
+)
+
+// keystorePassphraseEnv unlocks keystores without a prompt, for unattended use such as the daemon
+const keystorePassphraseEnv = "CROWDFUNDING_KEYSTORE_PASSPHRASE"
+
+const (
+	defaultKeystoreUnlockFor = 15 * time.Minute
+	defaultKeystoreIdleLock  = 5 * time.Minute
+)
+
+// KeystoreConfig configures how long an unlocked keystore key stays in memory, in config.json
+type KeystoreConfig struct {
+	UnlockFor string `json:"unlockFor,omitempty"` // e.g. "30m"; the passphrase is asked again after this, default 15m
+	IdleLock  string `json:"idleLock,omitempty"`  // lock after this long without signing, default 5m
+}
+
+// durations parses the settings, falling back to the defaults
+func (c *KeystoreConfig) durations() (unlockFor, idleLock time.Duration) {
+	unlockFor, idleLock = defaultKeystoreUnlockFor, defaultKeystoreIdleLock
+	if c == nil {
+		return
+	}
+	if d, err := time.ParseDuration(c.UnlockFor); err == nil {
+		unlockFor = d
+	} else if c.UnlockFor != "" {
+		fmt.Printf("⚠️  Invalid keystore unlockFor %q in %s, using %s\n", c.UnlockFor, configFile, unlockFor)
+	}
+	if d, err := time.ParseDuration(c.IdleLock); err == nil {
+		idleLock = d
+	} else if c.IdleLock != "" {
+		fmt.Printf("⚠️  Invalid keystore idleLock %q in %s, using %s\n", c.IdleLock, configFile, idleLock)
+	}
+	return
+}
+
+// keystoreFile is the encrypted key file format: the public key in the clear and the wallet
+// key file encrypted with a passphrase by age
+type keystoreFile struct {
+	Keystore *keystoreData `json:"keystore"`
+}
+
+type keystoreData struct {
+	PublicKey  solana.PublicKey `json:"publicKey"`
+	Ciphertext string           `json:"ciphertext"` // armored age file
+	FIDO2      *keystoreFIDO2   `json:"fido2,omitempty"`
+}
+
+// keystoreFIDO2 marks a keystore whose passphrase is a security key's hmac-secret for salt,
+// so unlocking it takes a touch of that key instead of typing a passphrase
+type keystoreFIDO2 struct {
+	FIDO2Credential
+	Salt string `json:"salt"` // base64
+}
+
+// keystore decrypts a wallet key on first use and keeps it in memory until it expires, goes
+// unused for too long or is locked explicitly
+type keystore struct {
+	path       string
+	publicKey  solana.PublicKey
+	ciphertext string
+	unlockFor  time.Duration
+	idleLock   time.Duration
+	fido2      *keystoreFIDO2
+
+	mu         sync.Mutex
+	key        ed25519.PrivateKey
+	unlockedAt time.Time
+	lastUsed   time.Time
+	timer      *time.Timer
+}
+
+// loadKeystore parses an encrypted key file, returning nil if data is some other kind of key file
+func loadKeystore(path string, data []byte) *keystore {
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Keystore == nil {
+		return nil
+	}
+	unlockFor, idleLock := loadConfig().Keystore.durations()
+	return &keystore{
+		path:       path,
+		publicKey:  file.Keystore.PublicKey,
+		ciphertext: file.Keystore.Ciphertext,
+		unlockFor:  unlockFor,
+		idleLock:   idleLock,
+		fido2:      file.Keystore.FIDO2,
+	}
+}
+
+// EncryptKeystore encrypts a wallet key file's contents with a passphrase
+func EncryptKeystore(wallet *Wallet, keyFile []byte, passphrase string) ([]byte, error) {
+	return encryptKeystore(wallet, keyFile, passphrase, nil)
+}
+
+// EncryptKeystoreFIDO2 encrypts a wallet key file's contents so that only a touch of the
+// enrolled security key unlocks it
+func EncryptKeystoreFIDO2(wallet *Wallet, keyFile []byte, credential *FIDO2Credential) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	fido2 := &keystoreFIDO2{FIDO2Credential: *credential, Salt: base64.StdEncoding.EncodeToString(salt)}
+	passphrase, err := fido2.passphrase(wallet.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return encryptKeystore(wallet, keyFile, passphrase, fido2)
+}
+
+// passphrase derives the keystore passphrase from the security key's hmac-secret
+func (f *keystoreFIDO2) passphrase(publicKey solana.PublicKey) (string, error) {
+	salt, err := base64.StdEncoding.DecodeString(f.Salt)
+	if err != nil || len(salt) != 32 {
+		return "", fmt.Errorf("invalid keystore salt")
+	}
+	fmt.Printf("🔐 Unlocking keystore of %s with your security key\n", publicKey)
+	secret, err := f.Assert(publicKey[:], salt)
+	if err != nil {
+		return "", fmt.Errorf("security key unlock failed: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+func encryptKeystore(wallet *Wallet, keyFile []byte, passphrase string, fido2 *keystoreFIDO2) ([]byte, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	if _, err := w.Write(keyFile); err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	if err := armored.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+
+	file := keystoreFile{Keystore: &keystoreData{PublicKey: wallet.PublicKey, Ciphertext: buf.String(), FIDO2: fido2}}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// decrypt returns the key file inside the keystore
+func (ks *keystore) decrypt(passphrase string) ([]byte, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(ks.ciphertext)), identity)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupt keystore: %w", err)
+	}
+	return io.ReadAll(r)
+}
+
+// readPassphrase prompts for the keystore passphrase on the terminal without echoing it
+func (ks *keystore) readPassphrase() (string, error) {
+	if passphrase := os.Getenv(keystorePassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("keystore %s is locked and there is no terminal to ask for its passphrase (set %s)", ks.path, keystorePassphraseEnv)
+	}
+	fmt.Printf("🔐 Passphrase for %s (%s): ", ks.path, ks.publicKey)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// readNewPassphrase asks for a new keystore passphrase twice
+func readNewPassphrase() (string, error) {
+	if passphrase := os.Getenv(keystorePassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no terminal to ask for a passphrase (set %s)", keystorePassphraseEnv)
+	}
+	fmt.Print("🔐 New passphrase: ")
+	first, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	fmt.Print("🔐 Repeat passphrase: ")
+	second, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if len(first) == 0 {
+		return "", fmt.Errorf("empty passphrase")
+	}
+	if !bytes.Equal(first, second) {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return string(first), nil
+}
+
+// privateKey returns the cached key, asking for the passphrase or a security key touch if the
+// keystore is locked
+func (ks *keystore) privateKey() (ed25519.PrivateKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := time.Now()
+	if ks.key != nil && (now.Sub(ks.unlockedAt) >= ks.unlockFor || now.Sub(ks.lastUsed) >= ks.idleLock) {
+		ks.lockLocked()
+	}
+	if ks.key == nil {
+		var passphrase string
+		var err error
+		if ks.fido2 != nil {
+			passphrase, err = ks.fido2.passphrase(ks.publicKey)
+		} else {
+			passphrase, err = ks.readPassphrase()
+		}
+		if err != nil {
+			return nil, err
+		}
+		keyFile, err := ks.decrypt(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseKeyFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(key.Public().(ed25519.PublicKey), ks.publicKey[:]) {
+			return nil, fmt.Errorf("keystore %s does not hold the key of %s", ks.path, ks.publicKey)
+		}
+		ks.key, ks.unlockedAt = key, now
+	}
+	ks.lastUsed = now
+
+	// Lock as soon as either limit is reached, rather than on the next use
+	expiry := ks.unlockedAt.Add(ks.unlockFor)
+	if idle := now.Add(ks.idleLock); idle.Before(expiry) {
+		expiry = idle
+	}
+	if ks.timer != nil {
+		ks.timer.Stop()
+	}
+	ks.timer = time.AfterFunc(time.Until(expiry), ks.Lock)
+
+	// A copy, so locking cannot wipe a key that is in use
+	return append(ed25519.PrivateKey(nil), ks.key...), nil
+}
+
+// Lock forgets the decrypted key
+func (ks *keystore) Lock() {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.lockLocked()
+}
+
+// lockLocked overwrites and drops the key; ks.mu must be held
+func (ks *keystore) lockLocked() {
+	for i := range ks.key {
+		ks.key[i] = 0
+	}
+	ks.key = nil
+	if ks.timer != nil {
+		ks.timer.Stop()
+		ks.timer = nil
+	}
+}
+
+// Unlocked reports whether the key is in memory
+func (ks *keystore) Unlocked() bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.key != nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Ledger account names. Campaign and wallet accounts are followed by the address.
+const (
+	ledgerCampaign = "campaign:"
+	ledgerWallet   = "wallet:"
+	ledgerFees     = "fees"
+)
+
+// LedgerPosting moves lamports into (positive) or out of (negative) one account
+type LedgerPosting struct {
+	Account string `json:"account"`
+	Amount  int64  `json:"amount"`
+}
+
+// LedgerAssertion is an account's on-chain balance right after the entry's transaction
+type LedgerAssertion struct {
+	Account string `json:"account"`
+	Balance uint64 `json:"balance"`
+}
+
+// LedgerEntry is the double-entry record of one confirmed transaction: its postings sum to zero
+type LedgerEntry struct {
+	Signature   string            `json:"signature"`
+	Slot        uint64            `json:"slot"`
+	Time        *time.Time        `json:"time,omitempty"`
+	Description string            `json:"description"`
+	Postings    []LedgerPosting   `json:"postings"`
+	Assertions  []LedgerAssertion `json:"assertions,omitempty"`
+}
+
+// balanced reports whether the postings sum to zero
+func (e LedgerEntry) balanced() bool {
+	var sum int64
+	for _, p := range e.Postings {
+		sum += p.Amount
+	}
+	return sum == 0
+}
+
+// post adds a balanced pair of postings moving amount from one account to another
+func (e *LedgerEntry) post(from, to string, amount int64) {
+	if amount == 0 {
+		return
+	}
+	e.Postings = append(e.Postings, LedgerPosting{Account: to, Amount: amount}, LedgerPosting{Account: from, Amount: -amount})
+}
+
+// ledgerEntry builds the ledger entry of a confirmed transaction from its crowdfunding
+// instructions and fee. It returns nil for failed transactions and those without any.
+func (app *SolanaDApp) ledgerEntry(signature solana.Signature) (*LedgerEntry, error) {
+	result, tx, keys, err := app.fetchTransaction(signature)
+	if err != nil {
+		return nil, err
+	}
+	if result.Meta == nil || result.Meta.Err != nil {
+		return nil, nil
+	}
+	balance := func(key solana.PublicKey, balances []uint64) int64 {
+		for i, k := range keys {
+			if k.Equals(key) && i < len(balances) {
+				return int64(balances[i])
+			}
+		}
+		return 0
+	}
+
+	entry := &LedgerEntry{Signature: signature.String(), Slot: result.Slot}
+	if result.BlockTime != nil {
+		t := result.BlockTime.Time().UTC()
+		entry.Time = &t
+	}
+	var descriptions []string
+	campaigns := map[solana.PublicKey]bool{}
+	for _, compiled := range tx.Message.Instructions {
+		if int(compiled.ProgramIDIndex) >= len(keys) || !keys[compiled.ProgramIDIndex].Equals(app.programID) {
+			continue
+		}
+		ix, ok := app.idl.MatchInstruction(compiled.Data)
+		if !ok {
+			continue
+		}
+		accounts := map[string]solana.PublicKey{}
+		for i, index := range compiled.Accounts {
+			if i < len(ix.Accounts) && int(index) < len(keys) {
+				accounts[crowdfund.NormalizeName(ix.Accounts[i].Name)] = keys[index]
+			}
+		}
+		var amount int64
+		if args, err := ix.DecodeArgs(compiled.Data); err == nil {
+			for _, arg := range args {
+				if v, ok := arg.Value.(uint64); ok && crowdfund.NormalizeName(arg.Name) == "amount" {
+					amount = int64(v)
+				}
+			}
+		}
+
+		campaign, user := ledgerCampaign+accounts["campaign"].String(), ledgerWallet+accounts["user"].String()
+		switch ix.Name {
+		case "create":
+			// The campaign account's opening balance is the rent its creator paid
+			rent := balance(accounts["campaign"], result.Meta.PostBalances) - balance(accounts["campaign"], result.Meta.PreBalances)
+			entry.post(user, campaign, rent)
+			descriptions = append(descriptions, "create "+accounts["campaign"].String())
+		case "donate":
+			entry.post(user, campaign, amount)
+			descriptions = append(descriptions, fmt.Sprintf("donate %s SOL to %s", lamportsToSOL(uint64(amount)), accounts["campaign"]))
+		case "withdraw":
+			entry.post(campaign, user, amount)
+			descriptions = append(descriptions, fmt.Sprintf("withdraw %s SOL from %s", lamportsToSOL(uint64(amount)), accounts["campaign"]))
+		default:
+			continue
+		}
+		campaigns[accounts["campaign"]] = true
+	}
+	if len(campaigns) == 0 {
+		return nil, nil
+	}
+
+	entry.post(ledgerWallet+keys[0].String(), ledgerFees, int64(result.Meta.Fee))
+	entry.Description = strings.Join(descriptions, "; ")
+	if len(result.Meta.PostBalances) > 0 {
+		for campaign := range campaigns {
+			entry.Assertions = append(entry.Assertions, LedgerAssertion{Account: ledgerCampaign + campaign.String(), Balance: uint64(balance(campaign, result.Meta.PostBalances))})
+		}
+		sort.Slice(entry.Assertions, func(i, j int) bool { return entry.Assertions[i].Account < entry.Assertions[j].Account })
+	}
+	return entry, nil
+}
+
+// recordLedger adds a transaction this client confirmed to the ledger. Failures are only logged:
+// `ledger sync` picks up what was missed.
+func (app *SolanaDApp) recordLedger(sig solana.Signature, err error) {
+	if err != nil || sig.IsZero() {
+		return
+	}
+	entry, err := app.ledgerEntry(sig)
+	if err == nil && entry != nil {
+		var store Store
+		if store, err = OpenStore(); err == nil {
+			err = store.AddLedgerEntries(*entry)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to record %s in the ledger: %v", sig, err)
+	}
+}
+
+// SyncLedger adds every confirmed transaction of the campaigns missing from the ledger, so
+// donations made by other clients are recorded too. It returns how many entries were added.
+func (app *SolanaDApp) SyncLedger(ctx context.Context, campaigns []solana.PublicKey) (int, error) {
+	store, err := OpenStore()
+	if err != nil {
+		return 0, err
+	}
+	recorded := map[string]bool{}
+	for _, entry := range store.LedgerEntries() {
+		recorded[entry.Signature] = true
+	}
+
+	var missing []solana.Signature
+	seen := map[solana.Signature]bool{}
+	for _, campaign := range campaigns {
+		signatures, err := app.signaturesBetween(campaign, time.Time{}, time.Now().Add(time.Hour))
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch signatures of %s: %w", campaign, err)
+		}
+		for _, sig := range signatures {
+			if !recorded[sig.Signature.String()] && !seen[sig.Signature] {
+				seen[sig.Signature] = true
+				missing = append(missing, sig.Signature)
+			}
+		}
+	}
+
+	entries := make([]*LedgerEntry, len(missing))
+	err = forEachParallel(len(missing), defaultWorkers, func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entry, err := app.ledgerEntry(missing[i])
+		entries[i] = entry
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	var added []LedgerEntry
+	for _, entry := range entries {
+		if entry != nil {
+			added = append(added, *entry)
+		}
+	}
+	if err := store.AddLedgerEntries(added...); err != nil {
+		return 0, err
+	}
+	return len(added), nil
+}
+
+// LedgerBalances sums the postings of every account
+func LedgerBalances(entries []LedgerEntry) map[string]int64 {
+	balances := map[string]int64{}
+	for _, entry := range entries {
+		for _, p := range entry.Postings {
+			balances[p.Account] += p.Amount
+		}
+	}
+	return balances
+}
+
+// LedgerProblem is a discrepancy found by VerifyLedger
+type LedgerProblem struct {
+	Account   string
+	Signature string // the entry it was found at, empty for the current balance
+	Message   string
+}
+
+// VerifyLedger checks that every entry balances, that each campaign's running balance matches the
+// on-chain balance recorded after every transaction, and that the final balances match the
+// campaigns' current on-chain balances. It returns the campaigns checked and the problems found.
+func (app *SolanaDApp) VerifyLedger(ctx context.Context) (int, []LedgerProblem, error) {
+	store, err := OpenStore()
+	if err != nil {
+		return 0, nil, err
+	}
+	entries := store.LedgerEntries()
+
+	var problems []LedgerProblem
+	running := map[string]int64{}
+	for _, entry := range entries {
+		if !entry.balanced() {
+			problems = append(problems, LedgerProblem{Signature: entry.Signature, Message: "postings do not sum to zero"})
+		}
+		for _, p := range entry.Postings {
+			running[p.Account] += p.Amount
+		}
+		for _, assertion := range entry.Assertions {
+			if running[assertion.Account] != int64(assertion.Balance) {
+				problems = append(problems, LedgerProblem{
+					Account:   assertion.Account,
+					Signature: entry.Signature,
+					Message:   fmt.Sprintf("ledger balance %s SOL, on-chain %s SOL after this transaction", signedSOL(running[assertion.Account]), lamportsToSOL(assertion.Balance)),
+				})
+				// Continue from the on-chain balance, so one gap is reported once
+				running[assertion.Account] = int64(assertion.Balance)
+			}
+		}
+	}
+
+	var campaigns []solana.PublicKey
+	for account := range LedgerBalances(entries) {
+		if address, ok := strings.CutPrefix(account, ledgerCampaign); ok {
+			campaigns = append(campaigns, solana.MustPublicKeyFromBase58(address))
+		}
+	}
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].String() < campaigns[j].String() })
+	for _, campaign := range campaigns {
+		account := ledgerCampaign + campaign.String()
+		onChain, err := app.client.GetBalance(ctx, campaign, rpc.CommitmentConfirmed)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get balance of %s: %w", campaign, err)
+		}
+		if running[account] != int64(onChain.Value) {
+			problems = append(problems, LedgerProblem{
+				Account: account,
+				Message: fmt.Sprintf("ledger balance %s SOL, on-chain %s SOL now; run `ledger sync` if transactions are missing", signedSOL(running[account]), lamportsToSOL(onChain.Value)),
+			})
+		}
+	}
+	return len(campaigns), problems, nil
+}
+
+// signedSOL formats a ledger balance, which can be negative, in SOL
+func signedSOL(lamports int64) string {
+	if lamports < 0 {
+		return "-" + lamportsToSOL(uint64(-lamports))
+	}
+	return lamportsToSOL(uint64(lamports))
+}
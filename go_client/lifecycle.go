@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// lifecycleFile is the local registry of campaign drafts, goals and closures
+const lifecycleFile = "lifecycle.json"
+
+// CampaignState is where a campaign is in its lifecycle. The program has no notion of one, so it is
+// derived from the campaign account and what the local registry records about the campaign.
+type CampaignState string
+
+const (
+	StateDraft       CampaignState = "draft"        // recorded locally but not created on chain yet
+	StateActive      CampaignState = "active"       // on chain and taking donations
+	StateGoalReached CampaignState = "goal-reached" // raised at least its goal; donations are still accepted
+	StateClosed      CampaignState = "closed"       // closed in the local registry or past its deadline; the client refuses donations
+)
+
+var (
+	// ErrCampaignClosed is returned when donating to a campaign closed in the local registry
+	ErrCampaignClosed = errors.New("campaign is closed")
+	// ErrCampaignEnded is returned when donating to a campaign whose deadline has passed
+	ErrCampaignEnded = errors.New("campaign has ended")
+)
+
+// CampaignRecord is what the local registry knows about a campaign beyond its account
+type CampaignRecord struct {
+	Name        string           `json:"name"`
+	Admin       solana.PublicKey `json:"admin"`
+	Description string           `json:"description,omitempty"` // kept for drafts until they are published
+	Goal        uint64           `json:"goal,omitempty"`        // lamports, 0 for no goal
+	Deadline    *time.Time       `json:"deadline,omitempty"`    // donations are refused after it
+	// When an auto-close policy saw the goal reached; the campaign closes after its grace period
+	GoalReachedAt *time.Time `json:"goalReachedAt,omitempty"`
+	ClosedAt      *time.Time `json:"closedAt,omitempty"`
+}
+
+// ended reports whether the campaign's deadline has passed
+func (r *CampaignRecord) ended(now time.Time) bool {
+	return r.Deadline != nil && !now.Before(*r.Deadline)
+}
+
+// campaignRecords maps campaign addresses to their records
+type campaignRecords map[string]*CampaignRecord
+
+// loadCampaignRecords reads the local registry, treating a missing file as empty. The file is
+// edited by hand, so its keys are checked to be addresses here rather than where they are used.
+func loadCampaignRecords() (campaignRecords, error) {
+	records := campaignRecords{}
+	data, err := os.ReadFile(lifecycleFile)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", lifecycleFile, err)
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", lifecycleFile, err)
+	}
+	for address, record := range records {
+		if _, err := solana.PublicKeyFromBase58(address); err != nil {
+			return nil, fmt.Errorf("%s: invalid campaign address %q: %w", lifecycleFile, address, err)
+		}
+		if record == nil {
+			return nil, fmt.Errorf("%s: campaign %s has no record", lifecycleFile, address)
+		}
+	}
+	return records, nil
+}
+
+// save writes the local registry
+func (r campaignRecords) save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(lifecycleFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", lifecycleFile, err)
+	}
+	return nil
+}
+
+// get returns the record of a campaign, or nil
+func (r campaignRecords) get(address solana.PublicKey) *CampaignRecord {
+	return r[address.String()]
+}
+
+// campaignState derives a campaign's state from its record, either of which may be nil
+func campaignState(record *CampaignRecord, campaign *Campaign) CampaignState {
+	switch {
+	case record != nil && (record.ClosedAt != nil || record.ended(time.Now())):
+		return StateClosed
+	case campaign == nil:
+		return StateDraft
+	case record != nil && record.Goal > 0 && campaign.AmountDonated >= record.Goal:
+		return StateGoalReached
+	default:
+		return StateActive
+	}
+}
+
+// annotate fills in a listed campaign's lifecycle state and deadline
+func (r campaignRecords) annotate(c *CampaignAccount) {
+	record := r.get(c.Address)
+	c.State = campaignState(record, c.Campaign)
+	if record != nil {
+		c.Deadline = record.Deadline
+	}
+}
+
+// lookupCampaignState derives a campaign's state with its record from the local registry
+func lookupCampaignState(address solana.PublicKey, campaign *Campaign) CampaignState {
+	records, err := loadCampaignRecords()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+	return campaignState(records.get(address), campaign)
+}
+
+// checkDonationsOpen refuses donations to campaigns closed in the local registry, and points out
+// that a campaign has already reached its goal
+func (app *SolanaDApp) checkDonationsOpen(address solana.PublicKey) error {
+	records, err := loadCampaignRecords()
+	if err != nil {
+		return err
+	}
+	record := records.get(address)
+	if record == nil {
+		return nil
+	}
+	if record.ClosedAt != nil {
+		return fmt.Errorf("%w: '%s' was closed on %s", ErrCampaignClosed, record.Name, formatTime(*record.ClosedAt))
+	}
+	if record.ended(time.Now()) {
+		return fmt.Errorf("%w: '%s' stopped taking donations at its deadline, %s", ErrCampaignEnded, record.Name, formatTime(*record.Deadline))
+	}
+	if record.Goal > 0 {
+		if campaign, err := app.FetchCampaign(address); err == nil && campaignState(record, campaign) == StateGoalReached {
+			fmt.Printf("🎯 '%s' has already reached its goal of %s SOL\n", record.Name, lamportsToSOL(record.Goal))
+		}
+	}
+	return nil
+}
+
+// recordCampaign adds a campaign to the local registry. A goal of 0 or a nil deadline keeps the
+// one already set.
+func recordCampaign(address, admin solana.PublicKey, name, description string, goal uint64, deadline *time.Time) error {
+	records, err := loadCampaignRecords()
+	if err != nil {
+		return err
+	}
+	record := records.get(address)
+	if record == nil {
+		record = &CampaignRecord{}
+		records[address.String()] = record
+	}
+	record.Name = name
+	record.Admin = admin
+	record.Description = description
+	if goal > 0 {
+		record.Goal = goal
+	}
+	if deadline != nil {
+		record.Deadline = deadline
+	}
+	return records.save()
+}
+
+// runCampaignCommand handles `campaign draft|publish|goal|close|reopen|list`
+func runCampaignCommand(args []string) error {
+	usage := fmt.Errorf("usage: campaign draft -wallet <key.json> [-goal SOL] [-deadline time] [-edit] <name> [description] | publish -wallet <key.json> <name> | goal <campaign> <SOL> | deadline <campaign> <time|none> | close|reopen <campaign> | list")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "draft", "publish":
+		fs := flag.NewFlagSet("campaign "+args[0], flag.ContinueOnError)
+		walletPath := fs.String("wallet", "", "the campaign admin's wallet")
+		goal := fs.String("goal", "", "fundraising goal in SOL")
+		deadline := fs.String("deadline", "", "when the campaign stops taking donations, RFC 3339 or YYYY-MM-DD")
+		edit := fs.Bool("edit", false, "write the description in $EDITOR, starting from the one given")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *walletPath == "" || fs.NArg() < 1 || fs.NArg() > 2 || (args[0] == "publish" && (fs.NArg() != 1 || *goal != "" || *deadline != "" || *edit)) {
+			return usage
+		}
+		wallet, err := NewWallet(*walletPath)
+		if err != nil {
+			return fmt.Errorf("failed to create wallet: %w", err)
+		}
+		app := NewReadOnlyDApp()
+		app.wallet = wallet
+		if args[0] == "publish" {
+			return app.PublishDraft(fs.Arg(0))
+		}
+		var goalLamports uint64
+		if *goal != "" {
+			if goalLamports, err = parseSOL(*goal); err != nil {
+				return err
+			}
+		}
+		var deadlineTime *time.Time
+		if *deadline != "" {
+			t, err := parseTime(*deadline)
+			if err != nil {
+				return err
+			}
+			deadlineTime = &t
+		}
+		description := fs.Arg(1)
+		if *edit {
+			if description, err = editDescription(fs.Arg(0), description); err != nil {
+				return err
+			}
+		}
+		return app.DraftCampaign(fs.Arg(0), description, goalLamports, deadlineTime)
+
+	case "goal", "deadline", "close", "reopen":
+		if ((args[0] == "goal" || args[0] == "deadline") && len(args) != 3) || (args[0] != "goal" && args[0] != "deadline" && len(args) != 2) {
+			return usage
+		}
+		address, err := solana.PublicKeyFromBase58(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid campaign address: %w", err)
+		}
+		app := NewReadOnlyDApp()
+		records, err := loadCampaignRecords()
+		if err != nil {
+			return err
+		}
+		record := records.get(address)
+		if record == nil {
+			campaign, err := app.FetchCampaign(address)
+			if err != nil {
+				return err
+			}
+			record = &CampaignRecord{Name: campaign.Name, Admin: campaign.Admin}
+			records[address.String()] = record
+		}
+
+		switch args[0] {
+		case "goal":
+			if record.Goal, err = parseSOL(args[2]); err != nil {
+				return err
+			}
+			fmt.Printf("🎯 Goal of '%s' set to %s SOL\n", record.Name, lamportsToSOL(record.Goal))
+		case "deadline":
+			if args[2] == "none" {
+				record.Deadline = nil
+				fmt.Printf("⏳ '%s' no longer has a deadline\n", record.Name)
+				break
+			}
+			t, err := parseTime(args[2])
+			if err != nil {
+				return err
+			}
+			record.Deadline = &t
+			fmt.Printf("⏳ '%s' takes donations until %s (%s)\n", record.Name, formatTime(t), formatCountdown(t, time.Now()))
+		case "close":
+			now := time.Now().UTC()
+			record.ClosedAt = &now
+			fmt.Printf("🔒 '%s' is closed: this client will refuse donations to it. Withdrawals still work.\n", record.Name)
+		case "reopen":
+			record.ClosedAt = nil
+			fmt.Printf("🔓 '%s' is open for donations again\n", record.Name)
+		}
+		return records.save()
+
+	case "list":
+		if len(args) != 1 {
+			return usage
+		}
+		return NewReadOnlyDApp().printCampaignRecords()
+	}
+	return usage
+}
+
+// DraftCampaign records a campaign of the app's wallet in the local registry without creating it
+func (app *SolanaDApp) DraftCampaign(name, description string, goal uint64, deadline *time.Time) error {
+	if err := crowdfund.CheckCampaignCapacity(name, description); err != nil {
+		return err
+	}
+	address, _, err := app.CreateCampaignPDA(name)
+	if err != nil {
+		return fmt.Errorf("failed to derive campaign address: %w", err)
+	}
+	if err := recordCampaign(address, app.wallet.Address(), name, description, goal, deadline); err != nil {
+		return err
+	}
+	fmt.Printf("📝 Draft '%s' saved; it will be created at %s\n", name, address)
+	fmt.Printf("💡 Run `campaign publish -wallet <key.json> %s` to create it on chain\n", name)
+	return nil
+}
+
+// PublishDraft creates a drafted campaign on chain with its recorded description
+func (app *SolanaDApp) PublishDraft(name string) error {
+	address, _, err := app.CreateCampaignPDA(name)
+	if err != nil {
+		return fmt.Errorf("failed to derive campaign address: %w", err)
+	}
+	records, err := loadCampaignRecords()
+	if err != nil {
+		return err
+	}
+	record := records.get(address)
+	if record == nil {
+		return fmt.Errorf("no draft named '%s' for wallet %s", name, app.wallet.Address())
+	}
+	return app.CreateCampaign(name, record.Description)
+}
+
+// printCampaignRecords lists the campaigns in the local registry with their current state
+func (app *SolanaDApp) printCampaignRecords() error {
+	records, err := loadCampaignRecords()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("No campaigns recorded yet. Create one, or add one with `campaign draft` or `campaign goal`.")
+		return nil
+	}
+
+	addresses := make([]string, 0, len(records))
+	for address := range records {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	now := time.Now()
+	fmt.Fprintln(w, "ADDRESS\tNAME\tSTATE\tRAISED (SOL)\tGOAL (SOL)\tDEADLINE")
+	for _, address := range addresses {
+		record := records[address]
+		raised := "-"
+		campaign, err := app.FetchCampaign(solana.MustPublicKeyFromBase58(address))
+		if err != nil && !errors.Is(err, crowdfund.ErrCampaignNotFound) {
+			return err
+		}
+		if campaign != nil {
+			raised = lamportsToSOL(campaign.AmountDonated)
+		}
+		goal := "-"
+		if record.Goal > 0 {
+			goal = lamportsToSOL(record.Goal)
+		}
+		deadline := "-"
+		if record.Deadline != nil {
+			deadline = fmt.Sprintf("%s (%s)", formatTime(*record.Deadline), formatCountdown(*record.Deadline, now))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", address, record.Name, campaignState(record, campaign), raised, goal, deadline)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// LogConfig enables writing log lines to a rotated file in addition to the console
+type LogConfig struct {
+	File       string `json:"file"`
+	MaxSizeMB  int    `json:"maxSizeMB,omitempty"`  // rotate once the file reaches this size (default 100)
+	MaxAgeDays int    `json:"maxAgeDays,omitempty"` // delete rotated files older than this (default: keep)
+	MaxBackups int    `json:"maxBackups,omitempty"` // keep at most this many rotated files (default: all)
+	Compress   bool   `json:"compress,omitempty"`   // gzip rotated files
+}
+
+// setupLogFile tees the standard logger into the configured log file. The returned function closes it.
+func setupLogFile(config *Config) func() {
+	if config.Log == nil || config.Log.File == "" {
+		return func() {}
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   config.Log.File,
+		MaxSize:    config.Log.MaxSizeMB,
+		MaxAge:     config.Log.MaxAgeDays,
+		MaxBackups: config.Log.MaxBackups,
+		Compress:   config.Log.Compress,
+		LocalTime:  true,
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, file))
+
+	return func() {
+		log.SetOutput(os.Stderr)
+		file.Close()
+	}
+}
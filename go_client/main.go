@@ -4,46 +4,49 @@ import (
 	"bufio"
 	"context"
 	"crypto/ed25519"
-	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"crowdfunding-client/crowdfund"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
-	"github.com/gagliardetto/solana-go/rpc/ws"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	ProgramID = "3r5NUnG85XtVExb1234ZYYyUazjchqjfYknnQATyCDzp"
+	ProgramID = crowdfund.ProgramID
 	Network   = rpc.DevNet_RPC
 )
 
-// generateDiscriminator creates an 8-byte discriminator for Anchor instructions
-func generateDiscriminator(namespace, name string) []byte {
-	preimage := fmt.Sprintf("%s:%s", namespace, name)
-	hash := sha256.Sum256([]byte(preimage))
-	return hash[:8]
-}
-
 // Campaign represents the campaign account structure
-type Campaign struct {
-	Admin         solana.PublicKey `json:"admin"`
-	Name          string           `json:"name"`
-	Description   string           `json:"description"`
-	AmountDonated uint64           `json:"amount_donated"`
-	Bump          uint8            `json:"bump"`
-}
+type Campaign = crowdfund.Campaign
+
+// ErrCampaignAddressTaken is returned when an account the program can't take over already sits at a new campaign's address
+var ErrCampaignAddressTaken = errors.New("campaign address is taken by another account")
 
 // SolanaDApp represents our dApp instance
 type SolanaDApp struct {
-	client          *rpc.Client
-	wsClient        *ws.Client
-	wallet          *Wallet
+	client          SolanaRPC // queries
+	sender          SolanaRPC // blockhashes and sends, on a separate endpoint when configured
+	wsClient        *wsConn
+	wallet          Signer
 	programID       solana.PublicKey
+	idl             *IDL
+	config          *Config
+	accounts        *accountCache
+	feePayer        *Wallet   // Sponsor paying transaction fees, if configured; the wallet still signs its transfers
+	bus             *eventBus // NATS event bus, connected on first use when configured
+	busOnce         sync.Once
 	campaignAddress *solana.PublicKey // Current campaign address
 	campaignName    string            // Current campaign name
 }
@@ -52,6 +55,8 @@ type SolanaDApp struct {
 type Wallet struct {
 	PublicKey  solana.PublicKey
 	PrivateKey ed25519.PrivateKey
+	frost      *FrostGroup // signs through threshold participants; PrivateKey is nil
+	keystore   *keystore   // holds PrivateKey encrypted until it is needed
 }
 
 // WalletData represents the wallet file format
@@ -60,6 +65,47 @@ type WalletData struct {
 	PrivateKey string `json:"privateKey,omitempty"`
 }
 
+// parseKeyFile parses a base58 wallet data file or a legacy byte array key file
+func parseKeyFile(keyData []byte) (ed25519.PrivateKey, error) {
+	// Try to parse as wallet data with base58 keys first
+	var walletData WalletData
+	if err := json.Unmarshal(keyData, &walletData); err == nil && walletData.PrivateKey != "" {
+		// Parse base58 private key
+		privKeyBytes, err := solana.PrivateKeyFromBase58(walletData.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse base58 private key: %w", err)
+		}
+		return ed25519.PrivateKey(privKeyBytes), nil
+	}
+
+	// Try to parse as byte array (legacy format)
+	var keyArray []byte
+	if err := json.Unmarshal(keyData, &keyArray); err != nil {
+		return nil, fmt.Errorf("failed to parse key file: %w", err)
+	}
+
+	if len(keyArray) != 64 {
+		return nil, fmt.Errorf("invalid key length: expected 64, got %d", len(keyArray))
+	}
+
+	return ed25519.PrivateKey(keyArray), nil
+}
+
+// signingKey returns the wallet's private key, unlocking its keystore if needed
+func (w *Wallet) signingKey() (solana.PrivateKey, error) {
+	switch {
+	case w.keystore != nil:
+		key, err := w.keystore.privateKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to unlock %s: %w", w.PublicKey, err)
+		}
+		return solana.PrivateKey(key), nil
+	case w.frost != nil:
+		return nil, fmt.Errorf("%s is a FROST group and has no private key", w.PublicKey)
+	}
+	return solana.PrivateKey(w.PrivateKey), nil
+}
+
 // NewWallet creates a new wallet from a private key file or generates one
 func NewWallet(keyPath string) (*Wallet, error) {
 	var privateKey ed25519.PrivateKey
@@ -71,27 +117,20 @@ func NewWallet(keyPath string) (*Wallet, error) {
 			return nil, fmt.Errorf("failed to read key file: %w", err)
 		}
 
-		// Try to parse as wallet data with base58 keys first
-		var walletData WalletData
-		if err := json.Unmarshal(keyData, &walletData); err == nil && walletData.PrivateKey != "" {
-			// Parse base58 private key
-			privKeyBytes, err := solana.PrivateKeyFromBase58(walletData.PrivateKey)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse base58 private key: %w", err)
-			}
-			privateKey = ed25519.PrivateKey(privKeyBytes)
-		} else {
-			// Try to parse as byte array (legacy format)
-			var keyArray []byte
-			if err := json.Unmarshal(keyData, &keyArray); err != nil {
-				return nil, fmt.Errorf("failed to parse key file: %w", err)
-			}
+		// A FROST group file holds no key: its participants sign together
+		if group, err := loadFrostGroup(keyData); err != nil {
+			return nil, err
+		} else if group != nil {
+			return &Wallet{PublicKey: group.GroupPublicKey, frost: group}, nil
+		}
 
-			if len(keyArray) != 64 {
-				return nil, fmt.Errorf("invalid key length: expected 64, got %d", len(keyArray))
-			}
+		// An encrypted keystore is decrypted when the key is first needed
+		if ks := loadKeystore(keyPath, keyData); ks != nil {
+			return &Wallet{PublicKey: ks.publicKey, keystore: ks}, nil
+		}
 
-			privateKey = ed25519.PrivateKey(keyArray)
+		if privateKey, err = parseKeyFile(keyData); err != nil {
+			return nil, err
 		}
 	} else {
 		// Generate new key
@@ -116,26 +155,35 @@ func NewWallet(keyPath string) (*Wallet, error) {
 
 // NewSolanaDApp creates a new instance of the Solana dApp
 func NewSolanaDApp(keyPath string) (*SolanaDApp, error) {
-	client := rpc.New(Network)
-	wsClient, err := ws.Connect(context.Background(), rpc.DevNet_WS)
+	config := loadConfig()
+	client := newRPCClient(config.readEndpoint(), config.RPCConcurrency)
+	wsClient, err := dialWS(context.Background(), config.wsEndpoint())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
+	if keyPath == "" {
+		keyPath = config.Wallet
+	}
 	wallet, err := NewWallet(keyPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create wallet: %w", err)
 	}
 
-	programID := solana.MustPublicKeyFromBase58(ProgramID)
-
-	app := &SolanaDApp{
-		client:    client,
-		wsClient:  wsClient,
-		wallet:    wallet,
-		programID: programID,
+	var feePayer *Wallet
+	if config.FeePayer != "" {
+		if feePayer, err = NewWallet(config.FeePayer); err != nil {
+			return nil, fmt.Errorf("failed to load fee payer: %w", err)
+		}
+		if feePayer.frost != nil {
+			return nil, fmt.Errorf("the fee payer cannot be a FROST group")
+		}
 	}
 
+	app := NewDApp(config, client, newRPCClient(config.sendEndpoint(), config.RPCConcurrency), wallet)
+	app.wsClient = wsClient
+	app.feePayer = feePayer
+
 	// Try to load saved campaign address
 	app.loadSavedCampaign()
 
@@ -145,6 +193,30 @@ func NewSolanaDApp(keyPath string) (*SolanaDApp, error) {
 	return app, nil
 }
 
+// NewReadOnlyDApp creates a dApp instance without a wallet or WebSocket for commands that only query the chain
+func NewReadOnlyDApp() *SolanaDApp {
+	config := loadConfig()
+	return NewDApp(config, newRPCClient(config.readEndpoint(), config.RPCConcurrency), newRPCClient(config.sendEndpoint(), config.RPCConcurrency), nil)
+}
+
+// NewDApp creates a dApp instance on the given RPC clients and wallet, without a WebSocket.
+// Queries go to client, blockhashes and sends to sender, which may be the same; wallet may be
+// nil for read-only use. Tests can pass a MockRPC and MockSigner to run campaign flows offline.
+func NewDApp(config *Config, client, sender SolanaRPC, wallet Signer) *SolanaDApp {
+	programID := solana.MustPublicKeyFromBase58(ProgramID)
+	app := &SolanaDApp{
+		client:    client,
+		sender:    sender,
+		wallet:    wallet,
+		programID: programID,
+		idl:       loadIDL(programID),
+		config:    config,
+		accounts:  newAccountCache(config.accountCacheTTL, config.AccountCacheFile),
+	}
+	app.refreshIDL()
+	return app
+}
+
 // SavedCampaign represents saved campaign data
 type SavedCampaign struct {
 	Address string `json:"address"`
@@ -215,7 +287,7 @@ func (app *SolanaDApp) saveCampaign() {
 func (app *SolanaDApp) GetBalance() (float64, error) {
 	balance, err := app.client.GetBalance(
 		context.Background(),
-		app.wallet.PublicKey,
+		app.wallet.Address(),
 		rpc.CommitmentFinalized,
 	)
 	if err != nil {
@@ -225,32 +297,24 @@ func (app *SolanaDApp) GetBalance() (float64, error) {
 	return float64(balance.Value) / float64(solana.LAMPORTS_PER_SOL), nil
 }
 
-// RequestAirdrop requests SOL from the devnet faucet
+// RequestAirdrop requests SOL from the configured faucets, rotating between them when one rate limits us
 func (app *SolanaDApp) RequestAirdrop() error {
-	fmt.Println("Requesting airdrop...")
-
-	sig, err := app.client.RequestAirdrop(
-		context.Background(),
-		app.wallet.PublicKey,
-		2*solana.LAMPORTS_PER_SOL, // 2 SOL
-		rpc.CommitmentFinalized,
-	)
+	ctx := context.Background()
+	sig, err := app.airdrop(ctx, app.wallet.Address(), airdropLamports)
 	if err != nil {
-		return fmt.Errorf("failed to request airdrop: %w", err)
+		return err
 	}
 
 	fmt.Printf("Airdrop requested. Transaction signature: %s\n", sig)
+	fmt.Printf("🔗 %s\n", app.TxURL(sig.String()))
 	fmt.Println("Waiting for confirmation...")
 
-	// Wait for confirmation
-	ctx := context.Background()
-	status, err := app.client.GetSignatureStatuses(ctx, true, sig)
+	confirmed, err := app.awaitSignature(ctx, sig, time.Minute)
 	if err != nil {
-		return fmt.Errorf("failed to confirm airdrop: %w", err)
+		return fmt.Errorf("airdrop transaction failed: %w", err)
 	}
-
-	if len(status.Value) > 0 && status.Value[0] != nil && status.Value[0].Err != nil {
-		return fmt.Errorf("airdrop transaction failed: %v", status.Value[0].Err)
+	if !confirmed {
+		return fmt.Errorf("airdrop %s was not confirmed within a minute; check your balance later", sig)
 	}
 
 	fmt.Println("✅ Airdrop confirmed!")
@@ -259,105 +323,219 @@ func (app *SolanaDApp) RequestAirdrop() error {
 
 // CreateCampaignPDA generates the Program Derived Address for a campaign
 func (app *SolanaDApp) CreateCampaignPDA(campaignName string) (solana.PublicKey, uint8, error) {
-	seeds := [][]byte{
-		[]byte("CAMPAIGN_DEMO"),
-		app.wallet.PublicKey.Bytes(),
-		[]byte(campaignName),
-	}
+	return app.CampaignPDAFor(app.wallet.Address(), campaignName)
+}
 
-	return solana.FindProgramAddress(seeds, app.programID)
+// CampaignPDAFor derives the campaign address for any admin wallet and campaign name
+func (app *SolanaDApp) CampaignPDAFor(admin solana.PublicKey, campaignName string) (solana.PublicKey, uint8, error) {
+	return app.campaignPDAWithSeed(app.campaignSeed(), admin, campaignName)
 }
 
-// CheckExistingCampaign checks if a properly initialized campaign already exists for this wallet and campaign name
+// CheckExistingCampaign checks if a properly initialized campaign already exists for this wallet and campaign name.
+// An account at the address that would stop the campaign being created there is reported as ErrCampaignAddressTaken.
 func (app *SolanaDApp) CheckExistingCampaign(campaignName string) (*solana.PublicKey, error) {
-	campaignPDA, _, err := app.CreateCampaignPDA(campaignName)
+	campaignPDA, account, err := app.findCampaignPDA(campaignName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create campaign PDA: %w", err)
+		return nil, fmt.Errorf("failed to fetch campaign account: %w", err)
 	}
-
-	// Check if the account exists and is properly initialized
-	accountInfo, err := app.client.GetAccountInfo(context.Background(), campaignPDA)
-	if err != nil {
-		return nil, nil // Account doesn't exist
-	}
-
-	if accountInfo.Value == nil {
+	if account == nil {
 		return nil, nil // Account doesn't exist
 	}
 
-	// Check if the account is owned by our program (not just allocated by system program)
-	if !accountInfo.Value.Owner.Equals(app.programID) {
-		fmt.Printf("⚠️  Found uninitialized account at %s (owned by %s, not %s)\n",
-			campaignPDA.String(), accountInfo.Value.Owner.String(), app.programID.String())
-		return nil, nil // Account exists but not initialized by our program
+	if app.campaignAddressBlocked(account) {
+		if account.Owner.Equals(app.programID) {
+			return nil, fmt.Errorf("%w: %s is owned by the program but holds no campaign data", ErrCampaignAddressTaken, campaignPDA)
+		}
+		return nil, fmt.Errorf("%w: %s is owned by %s and holds %d bytes", ErrCampaignAddressTaken, campaignPDA, account.Owner, len(account.Data))
 	}
-
-	// Check if the account has data (properly initialized campaign)
-	if len(accountInfo.Value.Data.GetBinary()) < 32 { // Minimum size for a campaign account
-		fmt.Printf("⚠️  Found account with insufficient data at %s\n", campaignPDA.String())
-		return nil, nil // Account exists but not properly initialized
+	if account.Campaign == nil {
+		// Only lamports sent to the address, which the program absorbs on creation
+		fmt.Printf("ℹ️  %s already holds %s SOL; it will be added to the campaign\n", campaignPDA, lamportsToSOL(account.Lamports))
+		return nil, nil
 	}
 
 	fmt.Printf("✅ Found properly initialized campaign at %s\n", campaignPDA.String())
 	return &campaignPDA, nil
 }
 
-// CheckCampaignStatus provides detailed status information about the campaign account
-func (app *SolanaDApp) CheckCampaignStatus(campaignName string) error {
-	campaignPDA, _, err := app.CreateCampaignPDA(campaignName)
+// FetchCampaign loads and decodes the campaign account at the given address
+func (app *SolanaDApp) FetchCampaign(address solana.PublicKey) (*Campaign, error) {
+	account, err := app.getAccount(address)
 	if err != nil {
-		return fmt.Errorf("failed to create campaign PDA: %w", err)
+		return nil, fmt.Errorf("failed to fetch campaign account: %w", err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: no account at %s", crowdfund.ErrCampaignNotFound, address.String())
+	}
+	if !account.Owner.Equals(app.programID) {
+		return nil, fmt.Errorf("%w: owned by %s", crowdfund.ErrNotACampaignAccount, account.Owner.String())
 	}
+	if account.Campaign != nil {
+		return account.Campaign, nil
+	}
+
+	return crowdfund.DecodeCampaign(account.Data)
+}
 
-	fmt.Printf("\n🔍 Campaign Status for Wallet: %s\n", app.wallet.PublicKey.String())
+// CheckCampaignStatus provides detailed status information about the campaign account
+func (app *SolanaDApp) CheckCampaignStatus(campaignName string) error {
+	campaignPDA, account, err := app.findCampaignPDA(campaignName)
+
+	fmt.Printf("\n🔍 Campaign Status for Wallet: %s\n", app.wallet.Address().String())
 	fmt.Printf("📍 Expected Campaign Address: %s\n", campaignPDA.String())
-	fmt.Printf("🔗 Explorer Link: https://explorer.solana.com/address/%s?cluster=devnet\n", campaignPDA.String())
+	fmt.Printf("🔗 Explorer Link: %s\n", app.AddressURL(campaignPDA.String()))
 
-	// Get account info
-	accountInfo, err := app.client.GetAccountInfo(context.Background(), campaignPDA)
 	if err != nil {
 		fmt.Printf("❌ Account does not exist or error fetching: %v\n", err)
 		fmt.Println("✅ You can create a new campaign!")
 		return nil
 	}
 
-	if accountInfo.Value == nil {
+	if account == nil {
 		fmt.Println("❌ Account does not exist")
 		fmt.Println("✅ You can create a new campaign!")
 		return nil
 	}
 
 	fmt.Printf("📊 Account Info:\n")
-	fmt.Printf("   Owner: %s\n", accountInfo.Value.Owner.String())
-	fmt.Printf("   Data Size: %d bytes\n", len(accountInfo.Value.Data.GetBinary()))
-	fmt.Printf("   Lamports: %d\n", accountInfo.Value.Lamports)
-
-	if accountInfo.Value.Owner.Equals(solana.SystemProgramID) {
-		fmt.Println("⚠️  Account is allocated but NOT initialized by the crowdfunding program")
-		fmt.Println("💡 This means a previous campaign creation failed partway through")
-		fmt.Println("🔧 The account exists but has no campaign data")
-		fmt.Println("❗ You'll need to use a different wallet or wait for the account to be reclaimed")
-	} else if accountInfo.Value.Owner.Equals(app.programID) {
+	fmt.Printf("   Owner: %s\n", account.Owner.String())
+	fmt.Printf("   Data Size: %d bytes\n", len(account.Data))
+	fmt.Printf("   Lamports: %d\n", account.Lamports)
+
+	if account.Owner.Equals(solana.SystemProgramID) {
+		if app.campaignAddressBlocked(account) {
+			fmt.Println("⚠️  Account is allocated but NOT initialized by the crowdfunding program")
+			fmt.Println("💡 This means a previous campaign creation failed partway through")
+			app.printCampaignRemedies(campaignName)
+		} else {
+			fmt.Println("ℹ️  Account only holds lamports sent to it; creating the campaign will absorb them")
+			fmt.Println("✅ You can create a new campaign!")
+		}
+	} else if account.Owner.Equals(app.programID) {
 		fmt.Println("✅ Account is properly owned by the crowdfunding program")
-		if len(accountInfo.Value.Data.GetBinary()) >= 32 {
-			fmt.Println("✅ Account appears to have campaign data")
+		campaign, err := crowdfund.DecodeCampaign(account.Data)
+		if err != nil {
+			fmt.Printf("⚠️  Account is owned by program but does not hold campaign data: %v\n", err)
+			app.printCampaignRemedies(campaignName)
+		} else {
+			fmt.Println("✅ Account holds valid campaign data")
+			fmt.Printf("   Name: %s\n", campaign.Name)
+			fmt.Printf("   Description:\n%s", MarkdownToTerminal(campaign.Description, "      ", colorOutput()))
+			fmt.Printf("   Admin: %s\n", campaign.Admin.String())
+			fmt.Printf("   Amount Donated: %d lamports\n", campaign.AmountDonated)
+			fmt.Printf("   State: %s\n", lookupCampaignState(campaignPDA, campaign))
+			printDeadline(campaignPDA)
+			if entry, registry, checked := app.CampaignVerification(campaignPDA); checked {
+				if entry != nil {
+					fmt.Printf("✅ Verified%s\n", registryAttribution(entry, registry))
+				} else {
+					fmt.Printf("⚠️  Not in the verified campaign registry%s\n", registryAttribution(nil, registry))
+				}
+			}
 			app.campaignAddress = &campaignPDA
 			app.campaignName = campaignName
 			app.saveCampaign()
-		} else {
-			fmt.Println("⚠️  Account is owned by program but has insufficient data")
 		}
 	} else {
-		fmt.Printf("❓ Account is owned by unknown program: %s\n", accountInfo.Value.Owner.String())
+		fmt.Printf("❓ Account is owned by unknown program: %s\n", account.Owner.String())
+		app.printCampaignRemedies(campaignName)
 	}
 
 	return nil
 }
 
+// ProgramUpgradeInfo describes whether the crowdfunding program can be replaced and by whom
+type ProgramUpgradeInfo struct {
+	Upgradeable      bool
+	ProgramData      solana.PublicKey
+	UpgradeAuthority *solana.PublicKey // nil when the program has been made immutable
+	LastDeploySlot   uint64
+}
+
+// GetProgramUpgradeInfo reads the upgradeable-loader accounts backing the program
+func (app *SolanaDApp) GetProgramUpgradeInfo() (*ProgramUpgradeInfo, error) {
+	programInfo, err := app.client.GetAccountInfo(context.Background(), app.programID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch program account: %w", err)
+	}
+	if programInfo.Value == nil {
+		return nil, fmt.Errorf("program account %s not found", app.programID.String())
+	}
+
+	// Programs owned by the legacy loaders can never be upgraded
+	if !programInfo.Value.Owner.Equals(solana.BPFLoaderUpgradeableProgramID) {
+		return &ProgramUpgradeInfo{Upgradeable: false}, nil
+	}
+
+	// UpgradeableLoaderState::Program { programdata_address: Pubkey }
+	data := programInfo.Value.Data.GetBinary()
+	if len(data) < 36 || binary.LittleEndian.Uint32(data[:4]) != 2 {
+		return nil, fmt.Errorf("unexpected program account layout")
+	}
+	programDataAddress := solana.PublicKeyFromBytes(data[4:36])
+
+	// Only the 45-byte header is needed, not the program bytecode
+	offset, length := uint64(0), uint64(45)
+	programDataInfo, err := app.client.GetAccountInfoWithOpts(context.Background(), programDataAddress, &rpc.GetAccountInfoOpts{
+		DataSlice: &rpc.DataSlice{Offset: &offset, Length: &length},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch program data account: %w", err)
+	}
+	if programDataInfo.Value == nil {
+		return nil, fmt.Errorf("program data account %s not found", programDataAddress.String())
+	}
+
+	// UpgradeableLoaderState::ProgramData { slot: u64, upgrade_authority_address: Option<Pubkey> }
+	data = programDataInfo.Value.Data.GetBinary()
+	if len(data) < 13 || binary.LittleEndian.Uint32(data[:4]) != 3 {
+		return nil, fmt.Errorf("unexpected program data account layout")
+	}
+
+	info := &ProgramUpgradeInfo{
+		ProgramData:    programDataAddress,
+		LastDeploySlot: binary.LittleEndian.Uint64(data[4:12]),
+	}
+	if data[12] == 1 && len(data) >= 45 {
+		authority := solana.PublicKeyFromBytes(data[13:45])
+		info.UpgradeAuthority = &authority
+		info.Upgradeable = true
+	}
+
+	return info, nil
+}
+
+// WarnIfProgramUpgradeable tells the donor who could swap out the program holding their funds
+func (app *SolanaDApp) WarnIfProgramUpgradeable() {
+	info, err := app.GetProgramUpgradeInfo()
+	if err != nil {
+		fmt.Printf("⚠️  Could not verify program upgrade authority: %v\n", err)
+		return
+	}
+
+	if !info.Upgradeable {
+		fmt.Println("🔒 Crowdfunding program is immutable (no upgrade authority)")
+		return
+	}
+
+	fmt.Println("⚠️  Crowdfunding program is UPGRADEABLE")
+	fmt.Printf("   Upgrade authority: %s\n", info.UpgradeAuthority.String())
+	fmt.Printf("   Last deployed at slot: %d\n", info.LastDeploySlot)
+	fmt.Println("   The holder of this key can replace the program code, including the logic guarding campaign funds.")
+}
+
 // CreateCampaign creates a new fundraising campaign
 func (app *SolanaDApp) CreateCampaign(name, description string) error {
 	// First, check if a campaign already exists
+	if err := crowdfund.CheckCampaignCapacity(name, description); err != nil {
+		return err
+	}
 	existingCampaign, err := app.CheckExistingCampaign(name)
+	if errors.Is(err, ErrCampaignAddressTaken) {
+		fmt.Printf("⚠️  Can't create %q: %v\n", name, err)
+		app.printCampaignRemedies(name)
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("failed to check existing campaign: %w", err)
 	}
@@ -371,6 +549,10 @@ func (app *SolanaDApp) CreateCampaign(name, description string) error {
 		return nil
 	}
 
+	if err := app.checkCampaignRent(context.Background()); err != nil {
+		return err
+	}
+
 	fmt.Printf("Creating campaign: %s\n", name)
 
 	campaignPDA, _, err := app.CreateCampaignPDA(name)
@@ -378,85 +560,22 @@ func (app *SolanaDApp) CreateCampaign(name, description string) error {
 		return fmt.Errorf("failed to create campaign PDA: %w", err)
 	}
 
-	// Build the instruction data for Anchor program
-	// Generate the correct discriminator for the "create" instruction
-	instructionData := generateDiscriminator("global", "create")
-
-	// Serialize name length and name (u32 + string)
-	nameLen := uint32(len(name))
-	nameLenBytes := make([]byte, 4)
-	for i := 0; i < 4; i++ {
-		nameLenBytes[i] = byte(nameLen >> (i * 8))
-	}
-	instructionData = append(instructionData, nameLenBytes...)
-	instructionData = append(instructionData, []byte(name)...)
-
-	// Serialize description length and description (u32 + string)
-	descLen := uint32(len(description))
-	descLenBytes := make([]byte, 4)
-	for i := 0; i < 4; i++ {
-		descLenBytes[i] = byte(descLen >> (i * 8))
-	}
-	instructionData = append(instructionData, descLenBytes...)
-	instructionData = append(instructionData, []byte(description)...)
-
-	instruction := &solana.GenericInstruction{
-		ProgID: app.programID,
-		AccountValues: solana.AccountMetaSlice{
-			{
-				PublicKey:  campaignPDA,
-				IsWritable: true,
-				IsSigner:   false,
-			},
-			{
-				PublicKey:  app.wallet.PublicKey,
-				IsWritable: true,
-				IsSigner:   true,
-			},
-			{
-				PublicKey:  solana.SystemProgramID,
-				IsWritable: false,
-				IsSigner:   false,
-			},
-		},
-		DataBytes: instructionData,
-	}
-
-	// Get latest blockhash
-	recent, err := app.client.GetLatestBlockhash(context.Background(), rpc.CommitmentFinalized)
-	if err != nil {
-		return fmt.Errorf("failed to get latest blockhash: %w", err)
-	}
-
-	// Create transaction
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(app.wallet.PublicKey),
+	// Build the instruction from the program IDL
+	instruction, err := app.BuildInstruction("create",
+		map[string]solana.PublicKey{"campaign": campaignPDA, "user": app.wallet.Address()},
+		map[string]interface{}{"name": name, "description": description},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+		return fmt.Errorf("failed to build create instruction: %w", err)
 	}
 
-	// Sign transaction
-	privKey := solana.PrivateKey(app.wallet.PrivateKey)
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(app.wallet.PublicKey) {
-			return &privKey
-		}
-		return nil
-	})
+	sig, err := app.submitTransaction("create", []solana.Instruction{instruction})
 	if err != nil {
-		return fmt.Errorf("failed to sign transaction: %w", err)
-	}
-
-	// Send transaction
-	sig, err := app.client.SendTransaction(context.Background(), tx)
-	if err != nil {
-		return fmt.Errorf("failed to send transaction: %w", err)
+		return err
 	}
 
 	fmt.Printf("Campaign created! Transaction: %s\n", sig)
+	fmt.Printf("🔗 %s\n", app.TxURL(sig.String()))
 	fmt.Printf("Campaign address: %s\n", campaignPDA.String())
 
 	// Store the campaign address and name for future use
@@ -464,57 +583,58 @@ func (app *SolanaDApp) CreateCampaign(name, description string) error {
 	app.campaignName = name
 	app.saveCampaign()
 	fmt.Printf("✅ Campaign address and name saved for quick access!\n")
+	if err := recordCampaign(campaignPDA, app.wallet.Address(), name, "", 0, nil); err != nil {
+		log.Printf("Warning: failed to record campaign: %v", err)
+	}
 
 	return nil
 }
 
-// DonateToCampaign donates SOL to a campaign
-func (app *SolanaDApp) DonateToCampaign(campaignName, campaignAddress string, amount uint64) error {
+// DonateToCampaign donates SOL to a campaign, optionally posting a message to its message board as a memo
+func (app *SolanaDApp) DonateToCampaign(campaignName, campaignAddress string, amount uint64, memo string) error {
+	campaignPubkey := solana.MustPublicKeyFromBase58(campaignAddress)
+	if err := app.checkDonationsOpen(campaignPubkey); err != nil {
+		return err
+	}
 	fmt.Printf("Donating %d lamports to campaign %s\n", amount, campaignAddress)
 
-	campaignPubkey := solana.MustPublicKeyFromBase58(campaignAddress)
+	// Donors should know whether the program can be changed under them, and who vetted the campaign
+	app.WarnIfProgramUpgradeable()
+	app.WarnIfUnverified(campaignPubkey)
 
-	// Build donate instruction with proper discriminator
-	instructionData := generateDiscriminator("global", "donate")
-	// Add name length and name (u32 + string)
-	nameLen := uint32(len(campaignName))
-	nameLenBytes := make([]byte, 4)
-	for i := 0; i < 4; i++ {
-		nameLenBytes[i] = byte(nameLen >> (i * 8))
-	}
-	instructionData = append(instructionData, nameLenBytes...)
-	instructionData = append(instructionData, []byte(campaignName)...)
-	// Add amount as 8 bytes (little endian)
-	amountBytes := make([]byte, 8)
-	for i := 0; i < 8; i++ {
-		amountBytes[i] = byte(amount >> (i * 8))
-	}
-	instructionData = append(instructionData, amountBytes...)
-
-	instruction := &solana.GenericInstruction{
-		ProgID: app.programID,
-		AccountValues: solana.AccountMetaSlice{
-			{
-				PublicKey:  campaignPubkey,
-				IsWritable: true,
-				IsSigner:   false,
-			},
-			{
-				PublicKey:  app.wallet.PublicKey,
-				IsWritable: true,
-				IsSigner:   true,
-			},
-			{
-				PublicKey:  solana.SystemProgramID,
-				IsWritable: false,
-				IsSigner:   false,
-			},
-		},
-		DataBytes: instructionData,
+	// Build donate instruction from the program IDL
+	instruction, err := app.BuildInstruction("donate",
+		map[string]solana.PublicKey{"campaign": campaignPubkey, "user": app.wallet.Address()},
+		map[string]interface{}{"name": campaignName, "amount": amount},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to build donate instruction: %w", err)
+	}
+
+	instructions := []solana.Instruction{instruction}
+	if memo != "" {
+		instructions = append(instructions, memoInstruction(memo, app.wallet.Address()))
 	}
 
 	// Get recent blockhash and send transaction
-	return app.sendTransaction([]solana.Instruction{instruction})
+	sig, err := app.sendTransaction("donate", instructions)
+	app.queueIfRetryable("donate", campaignPubkey, campaignName, amount, memo, sig, err)
+	return err
+}
+
+// DonateFrom donates signing with the keypair in keyPath instead of the app's wallet. The keypair
+// is loaded for this donation only; a configured fee payer still pays the fees.
+func (app *SolanaDApp) DonateFrom(keyPath, campaignName, campaignAddress string, amount uint64, memo string) error {
+	donor, err := NewWallet(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load donor wallet: %w", err)
+	}
+	fmt.Printf("💳 Donating from %s\n", donor.PublicKey.String())
+
+	wallet := app.wallet
+	app.wallet = donor
+	defer func() { app.wallet = wallet }()
+	return app.DonateToCampaign(campaignName, campaignAddress, amount, memo)
 }
 
 // WithdrawFromCampaign withdraws SOL from a campaign (only campaign admin can do this)
@@ -523,83 +643,167 @@ func (app *SolanaDApp) WithdrawFromCampaign(campaignName, campaignAddress string
 
 	campaignPubkey := solana.MustPublicKeyFromBase58(campaignAddress)
 
-	// Build withdraw instruction with proper discriminator
-	instructionData := generateDiscriminator("global", "withdraw")
-	// Add name length and name (u32 + string)
-	nameLen := uint32(len(campaignName))
-	nameLenBytes := make([]byte, 4)
-	for i := 0; i < 4; i++ {
-		nameLenBytes[i] = byte(nameLen >> (i * 8))
-	}
-	instructionData = append(instructionData, nameLenBytes...)
-	instructionData = append(instructionData, []byte(campaignName)...)
-	// Add amount as 8 bytes (little endian)
-	amountBytes := make([]byte, 8)
-	for i := 0; i < 8; i++ {
-		amountBytes[i] = byte(amount >> (i * 8))
-	}
-	instructionData = append(instructionData, amountBytes...)
-
-	instruction := &solana.GenericInstruction{
-		ProgID: app.programID,
-		AccountValues: solana.AccountMetaSlice{
-			{
-				PublicKey:  campaignPubkey,
-				IsWritable: true,
-				IsSigner:   false,
-			},
-			{
-				PublicKey:  app.wallet.PublicKey,
-				IsWritable: true,
-				IsSigner:   true,
-			},
-		},
-		DataBytes: instructionData,
-	}
-
-	return app.sendTransaction([]solana.Instruction{instruction})
+	// Build withdraw instruction from the program IDL
+	instruction, err := app.BuildInstruction("withdraw",
+		map[string]solana.PublicKey{"campaign": campaignPubkey, "user": app.wallet.Address()},
+		map[string]interface{}{"name": campaignName, "amount": amount},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to build withdraw instruction: %w", err)
+	}
+
+	sig, err := app.sendTransaction("withdraw", []solana.Instruction{instruction})
+	app.queueIfRetryable("withdraw", campaignPubkey, campaignName, amount, "", sig, err)
+	return err
 }
 
 // sendTransaction is a helper method to send transactions
-func (app *SolanaDApp) sendTransaction(instructions []solana.Instruction) error {
-	recent, err := app.client.GetLatestBlockhash(context.Background(), rpc.CommitmentFinalized)
+func (app *SolanaDApp) sendTransaction(operation string, instructions []solana.Instruction) (solana.Signature, error) {
+	sig, err := app.submitTransaction(operation, instructions)
+	if err != nil {
+		return sig, err
+	}
+
+	fmt.Printf("Transaction sent: %s\n", sig)
+	fmt.Printf("🔗 %s\n", app.TxURL(sig.String()))
+	return sig, nil
+}
+
+// submitTransaction builds, signs, sends and confirms a transaction, tracing each stage.
+// Keys the instructions require besides the wallet, such as a new account's, are passed as extraSigners.
+// If the blockhash expires before the transaction is confirmed, it is rebuilt with a fresh one and
+// sent again, up to maxSubmitAttempts times; the returned signature is the last attempt's.
+func (app *SolanaDApp) submitTransaction(operation string, instructions []solana.Instruction, extraSigners ...solana.PrivateKey) (sig solana.Signature, err error) {
+	ctx, span := tracer.Start(context.Background(), operation, trace.WithAttributes(
+		attribute.String("wallet", app.wallet.Address().String()),
+		attribute.String("fee_payer", app.payer().String()),
+		attribute.String("operation.id", operationID),
+	))
+	defer func() { endSpan(span, err) }()
+	defer func() { app.publishOperation(operation, sig, err) }()
+	defer func() { app.auditResult(sig, err) }()
+	defer func() { app.recordLedger(sig, err) }()
+
+	for attempt := 1; ; attempt++ {
+		sig, err = app.submitOnce(ctx, operation, instructions, extraSigners)
+		if !errors.Is(err, crowdfund.ErrBlockhashExpired) || attempt >= maxSubmitAttempts {
+			break
+		}
+		app.auditResult(sig, err)
+		log.Printf("Transaction %s expired unconfirmed, resubmitting (attempt %d of %d)", sig, attempt+1, maxSubmitAttempts)
+		fmt.Printf("🔁 Blockhash expired before %s was confirmed; resending with a fresh one (attempt %d of %d)\n", sig, attempt+1, maxSubmitAttempts)
+	}
+	if err == nil {
+		span.SetAttributes(attribute.String("signature", sig.String()))
+	}
+	return sig, err
+}
+
+// maxSubmitAttempts bounds how often submitTransaction sends a transaction whose blockhash expired
+const maxSubmitAttempts = 3
+
+// submitOnce builds, signs and sends the transaction with a fresh blockhash, then waits until it
+// is confirmed or its blockhash expires
+func (app *SolanaDApp) submitOnce(ctx context.Context, operation string, instructions []solana.Instruction, extraSigners []solana.PrivateKey) (sig solana.Signature, err error) {
+	buildCtx, buildSpan := tracer.Start(ctx, "build")
+	recent, err := app.sender.GetLatestBlockhash(buildCtx, rpc.CommitmentFinalized)
 	if err != nil {
-		return fmt.Errorf("failed to get latest blockhash: %w", err)
+		endSpan(buildSpan, err)
+		return sig, fmt.Errorf("failed to get latest blockhash: %w", err)
 	}
 
+	// Fees are looked up again on every attempt, as congestion is what makes transactions expire
 	tx, err := solana.NewTransaction(
-		instructions,
+		app.withPriorityFee(buildCtx, instructions),
 		recent.Value.Blockhash,
-		solana.TransactionPayer(app.wallet.PublicKey),
+		solana.TransactionPayer(app.payer()),
 	)
+	endSpan(buildSpan, err)
 	if err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+		return sig, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	privKey := solana.PrivateKey(app.wallet.PrivateKey)
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(app.wallet.PublicKey) {
-			return &privKey
-		}
-		return nil
-	})
+	_, signSpan := tracer.Start(ctx, "sign")
+	err = app.signTransaction(tx, extraSigners...)
+	endSpan(signSpan, err)
 	if err != nil {
-		return fmt.Errorf("failed to sign transaction: %w", err)
+		return sig, err
 	}
 
-	sig, err := app.client.SendTransaction(context.Background(), tx)
+	// Whatever the outcome, cached copies of the accounts we write may now be stale
+	defer app.invalidateWrittenAccounts(tx)
+
+	// The signature is known before sending, so callers can look the transaction up even when the send errors
+	sig = tx.Signatures[0]
+
+	sendCtx, sendSpan := tracer.Start(ctx, "send")
+	_, err = app.send(sendCtx, operation, tx)
+	endSpan(sendSpan, err)
 	if err != nil {
-		return fmt.Errorf("failed to send transaction: %w", err)
+		return sig, fmt.Errorf("failed to send transaction: %w", app.idl.DecodeError(err))
 	}
 
-	fmt.Printf("Transaction sent: %s\n", sig)
-	return nil
+	fmt.Println("⏳ Waiting for confirmation...")
+	confirmCtx, confirmSpan := tracer.Start(ctx, "confirm", trace.WithAttributes(
+		attribute.String("signature", sig.String()),
+		attribute.Int64("last_valid_block_height", int64(recent.Value.LastValidBlockHeight)),
+	))
+	err = app.idl.DecodeError(crowdfund.WaitForConfirmation(confirmCtx, app.client, sig, recent.Value.LastValidBlockHeight))
+	endSpan(confirmSpan, err)
+	return sig, err
+}
+
+// payer returns the wallet paying transaction fees: the sponsor when configured, else the wallet itself
+func (app *SolanaDApp) payer() solana.PublicKey {
+	if app.feePayer != nil {
+		return app.feePayer.PublicKey
+	}
+	return app.wallet.Address()
+}
+
+// signTransaction signs with every key the transaction requires: the wallet, the fee payer and extraSigners,
+// once screening and the withdrawal policy allow it, and records the transaction in the audit log
+func (app *SolanaDApp) signTransaction(tx *solana.Transaction, extraSigners ...solana.PrivateKey) error {
+	ours := []solana.PublicKey{app.payer()}
+	for _, key := range extraSigners {
+		ours = append(ours, key.PublicKey())
+	}
+	if err := app.screenTransaction(tx, app.wallet.Address(), ours...); err != nil {
+		return err
+	}
+	if err := app.checkPolicy(tx); err != nil {
+		return err
+	}
+
+	signers := append([]solana.PrivateKey{}, extraSigners...)
+	if app.feePayer != nil {
+		key, err := app.feePayer.signingKey()
+		if err != nil {
+			return fmt.Errorf("failed to sign transaction: %w", err)
+		}
+		signers = append(signers, key)
+	}
+	if err := crowdfund.SignTransaction(tx, app.wallet, signers); err != nil {
+		return fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return app.auditSigned(tx, app.wallet.Address())
+}
+
+// invalidateWrittenAccounts drops cached copies of the accounts a transaction may modify
+func (app *SolanaDApp) invalidateWrittenAccounts(tx *solana.Transaction) {
+	var written []solana.PublicKey
+	for _, key := range tx.Message.AccountKeys {
+		if writable, err := tx.Message.IsWritable(key); err == nil && writable {
+			written = append(written, key)
+		}
+	}
+	app.accounts.invalidate(written...)
 }
 
 // ShowMenu displays the interactive menu
 func (app *SolanaDApp) ShowMenu() {
 	fmt.Println("\n=== Solana dApp CLI ===")
-	fmt.Printf("Wallet: %s\n", app.wallet.PublicKey.String())
+	fmt.Printf("Wallet: %s\n", app.wallet.Address().String())
 
 	balance, err := app.GetBalance()
 	if err != nil {
@@ -632,6 +836,9 @@ func (app *SolanaDApp) ShowMenu() {
 	fmt.Println("5. Check Balance")
 	fmt.Println("6. Check Campaign Status")
 	fmt.Println("7. Exit")
+	if wallet, ok := app.wallet.(*Wallet); ok && wallet.keystore != nil && wallet.keystore.Unlocked() {
+		fmt.Println("🔓 Wallet unlocked; type 'lock' to lock it now")
+	}
 	fmt.Print("\nChoose an option (1-7): ")
 }
 
@@ -644,30 +851,34 @@ func (app *SolanaDApp) Run() {
 
 		input, _ := reader.ReadString('\n')
 		choice := strings.TrimSpace(input)
+		op := beginOperation("menu option " + choice)
 
 		switch choice {
 		case "1":
 			if err := app.RequestAirdrop(); err != nil {
-				if strings.Contains(err.Error(), "airdrop") {
-					fmt.Println("❌ Airdrop failed. You may have reached the rate limit. Try again later.")
-				} else {
-					fmt.Printf("❌ Error requesting airdrop: %v\n", err)
-				}
+				fmt.Printf("❌ Airdrop failed: %v (operation %s)\n", err, op)
 			}
 		case "2":
 			fmt.Print("Campaign name: ")
 			name, _ := reader.ReadString('\n')
 			name = strings.TrimSpace(name)
 
-			fmt.Print("Campaign description: ")
+			fmt.Printf("Campaign description (Enter to write it in %s): ", textEditor())
 			description, _ := reader.ReadString('\n')
 			description = strings.TrimSpace(description)
+			if description == "" {
+				var err error
+				if description, err = editDescription(name, ""); err != nil {
+					fmt.Printf("❌ %v (operation %s)\n", err, op)
+					break
+				}
+			}
 
 			if err := app.CreateCampaign(name, description); err != nil {
-				if strings.Contains(err.Error(), "insufficient") {
-					fmt.Println("❌ Insufficient SOL in your wallet. Please use option 1 to get SOL via airdrop.")
+				if errors.Is(err, crowdfund.ErrInsufficientBalance) {
+					fmt.Printf("❌ Insufficient SOL in your wallet. Please use option 1 to get SOL via airdrop. (operation %s)\n", op)
 				} else {
-					fmt.Printf("❌ Error creating campaign: %v\n", err)
+					fmt.Printf("❌ Error creating campaign: %v (operation %s)\n", err, op)
 				}
 			}
 		case "3":
@@ -718,11 +929,29 @@ func (app *SolanaDApp) Run() {
 				continue
 			}
 
-			if err := app.DonateToCampaign(campaignName, address, amount); err != nil {
-				if strings.Contains(err.Error(), "insufficient") {
-					fmt.Println("❌ Insufficient SOL for donation. Please check your balance or request an airdrop.")
+			fmt.Print("Message for the campaign (optional): ")
+			memo, _ := reader.ReadString('\n')
+			memo = strings.TrimSpace(memo)
+			if err := validateComment(memo); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				continue
+			}
+
+			fmt.Print("Sign with another keypair file (blank for this wallet): ")
+			from, _ := reader.ReadString('\n')
+			from = strings.TrimSpace(from)
+
+			donate := app.DonateToCampaign
+			if from != "" {
+				donate = func(campaignName, address string, amount uint64, memo string) error {
+					return app.DonateFrom(from, campaignName, address, amount, memo)
+				}
+			}
+			if err := donate(campaignName, address, amount, memo); err != nil {
+				if errors.Is(err, crowdfund.ErrInsufficientBalance) {
+					fmt.Printf("❌ Insufficient SOL for donation. Please check your balance or request an airdrop. (operation %s)\n", op)
 				} else {
-					fmt.Printf("❌ Error donating: %v\n", err)
+					fmt.Printf("❌ Error donating: %v (operation %s)\n", err, op)
 				}
 			} else {
 				fmt.Printf("✅ Successfully donated %d lamports!\n", amount)
@@ -776,12 +1005,12 @@ func (app *SolanaDApp) Run() {
 			}
 
 			if err := app.WithdrawFromCampaign(campaignName, address, amount); err != nil {
-				if strings.Contains(err.Error(), "Unauthorized") || strings.Contains(err.Error(), "6000") {
-					fmt.Println("❌ Unauthorized: You are not the admin of this campaign.")
-				} else if strings.Contains(err.Error(), "InsufficientFunds") || strings.Contains(err.Error(), "6001") {
-					fmt.Println("❌ Insufficient funds in the campaign to withdraw this amount.")
+				if errors.Is(err, crowdfund.ErrUnauthorizedAdmin) {
+					fmt.Printf("❌ Unauthorized: You are not the admin of this campaign. (operation %s)\n", op)
+				} else if errors.Is(err, crowdfund.ErrInsufficientCampaignFunds) {
+					fmt.Printf("❌ Insufficient funds in the campaign to withdraw this amount. (operation %s)\n", op)
 				} else {
-					fmt.Printf("❌ Error withdrawing: %v\n", err)
+					fmt.Printf("❌ Error withdrawing: %v (operation %s)\n", err, op)
 				}
 			} else {
 				fmt.Printf("✅ Successfully withdrew %d lamports!\n", amount)
@@ -789,7 +1018,7 @@ func (app *SolanaDApp) Run() {
 		case "5":
 			balance, err := app.GetBalance()
 			if err != nil {
-				fmt.Printf("Error getting balance: %v\n", err)
+				fmt.Printf("Error getting balance: %v (operation %s)\n", err, op)
 			} else {
 				fmt.Printf("Current balance: %.4f SOL\n", balance)
 			}
@@ -802,11 +1031,19 @@ func (app *SolanaDApp) Run() {
 				continue
 			}
 			if err := app.CheckCampaignStatus(campaignName); err != nil {
-				fmt.Printf("❌ Error checking campaign status: %v\n", err)
+				fmt.Printf("❌ Error checking campaign status: %v (operation %s)\n", err, op)
 			}
 		case "7":
 			fmt.Println("Goodbye!")
 			return
+		case "lock":
+			wallet, ok := app.wallet.(*Wallet)
+			if !ok || wallet.keystore == nil {
+				fmt.Println("❌ The wallet is not an encrypted keystore.")
+				continue
+			}
+			wallet.keystore.Lock()
+			fmt.Println("🔒 Wallet locked; the passphrase will be asked before the next signature.")
 		default:
 			fmt.Println("❌ Invalid choice. Please enter a number between 1-7.")
 		}
@@ -817,21 +1054,66 @@ func (app *SolanaDApp) Run() {
 }
 
 func main() {
+	shutdownTracing := setupTracing()
+	defer shutdownTracing()
+	closeLogFile := setupLogFile(loadConfig())
+	defer closeLogFile()
+	flushCrashReports := setupCrashReporting()
+	defer flushCrashReports()
+	defer recoverCrash()
+
+	os.Args = parseDebugFlags(os.Args)
+
 	var keyPath string
 	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "help", "-h", "--help":
+			printUsage()
+			return
+		}
+
+		if cmd := lookupCommand(os.Args[1]); cmd != nil {
+			op := beginOperation(cmd.name)
+			if err := cmd.run(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+				shutdownTracing()
+				log.Printf("%s: %v (operation %s)", cmd.name, err, op)
+				closeLogFile()
+				os.Exit(1)
+			}
+			return
+		}
+
 		keyPath = os.Args[1]
 	}
 
 	fmt.Println("🚀 Solana dApp CLI Starting...")
 
+	// First run: set up a cluster and wallet rather than generating a throwaway key
+	var setup *SetupResult
+	if keyPath == "" && needsSetup() {
+		var err error
+		if setup, err = RunSetupWizard(bufio.NewReader(os.Stdin)); err != nil {
+			log.Fatalf("Setup failed: %v", err)
+		}
+	}
+
 	app, err := NewSolanaDApp(keyPath)
 	if err != nil {
 		log.Fatalf("Failed to initialize dApp: %v", err)
 	}
 	defer app.wsClient.Close()
 
-	fmt.Printf("✅ Connected to Solana devnet\n")
-	fmt.Printf("💳 Wallet loaded: %s\n", app.wallet.PublicKey.String())
+	fmt.Printf("✅ Connected to Solana %s\n", clusterName(app.config))
+	fmt.Printf("💳 Wallet loaded: %s\n", app.wallet.Address().String())
+	if app.feePayer != nil {
+		fmt.Printf("⛽ Fees paid by sponsor: %s\n", app.feePayer.PublicKey.String())
+	}
+
+	if setup != nil && setup.Airdrop {
+		if err := app.RequestAirdrop(); err != nil {
+			fmt.Printf("❌ Airdrop failed: %v\n", err)
+		}
+	}
 
 	// Show initial balance
 	if balance, err := app.GetBalance(); err == nil {
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Campaign descriptions are Markdown. The client supports the subset that fits a description:
+// headings, paragraphs, bullet and numbered lists, block quotes, fenced code, and inline bold,
+// italics, code and links. Anything else is shown as written.
+
+// markdownBlock is one block-level element of a Markdown document
+type markdownBlock struct {
+	kind  string   // heading, paragraph, bullet, numbered, quote or code
+	level int      // heading level
+	lines []string // a paragraph's or quote's lines, a list's items, a code block's lines
+}
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	bulletPattern   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	numberedPattern = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	quotePattern    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+
+	codeSpanPattern = regexp.MustCompile("`[^`]+`")
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicPattern   = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+)
+
+// parseMarkdown splits a document into blocks
+func parseMarkdown(src string) []markdownBlock {
+	var blocks []markdownBlock
+	var current *markdownBlock
+	flush := func() {
+		if current != nil {
+			blocks = append(blocks, *current)
+			current = nil
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			flush()
+			code := markdownBlock{kind: "code"}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code.lines = append(code.lines, lines[i])
+			}
+			blocks = append(blocks, code)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, markdownBlock{kind: "heading", level: len(m[1]), lines: []string{m[2]}})
+			continue
+		}
+
+		kind, text := "paragraph", strings.TrimSpace(line)
+		if m := bulletPattern.FindStringSubmatch(line); m != nil {
+			kind, text = "bullet", m[1]
+		} else if m := numberedPattern.FindStringSubmatch(line); m != nil {
+			kind, text = "numbered", m[1]
+		} else if m := quotePattern.FindStringSubmatch(line); m != nil {
+			kind, text = "quote", m[1]
+		}
+
+		switch {
+		case current != nil && current.kind == kind:
+			current.lines = append(current.lines, text)
+		case current != nil && kind == "paragraph" && current.kind != "quote":
+			// A continuation line belongs to the paragraph or list item above it
+			last := len(current.lines) - 1
+			current.lines[last] += " " + text
+		default:
+			flush()
+			current = &markdownBlock{kind: kind, lines: []string{text}}
+		}
+	}
+	flush()
+	return blocks
+}
+
+// renderInline applies the inline styles to text outside code spans, after escape
+func renderInline(text string, escape, code func(string) string, link func(text, url string) string, bold, italic func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range codeSpanPattern.FindAllStringIndex(text, -1) {
+		b.WriteString(renderEmphasis(escape(text[last:span[0]]), link, bold, italic))
+		b.WriteString(code(escape(text[span[0]+1 : span[1]-1])))
+		last = span[1]
+	}
+	b.WriteString(renderEmphasis(escape(text[last:]), link, bold, italic))
+	return b.String()
+}
+
+func renderEmphasis(text string, link func(text, url string) string, bold, italic func(string) string) string {
+	text = linkPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := linkPattern.FindStringSubmatch(s)
+		return link(m[1], m[2])
+	})
+	text = boldPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := boldPattern.FindStringSubmatch(s)
+		return bold(m[1] + m[2])
+	})
+	return italicPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := italicPattern.FindStringSubmatch(s)
+		return italic(m[1] + m[2])
+	})
+}
+
+// safeLinkURL reports whether a link target may be followed from a rendered page
+func safeLinkURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "mailto:")
+}
+
+// MarkdownToHTML converts a description to HTML. Raw HTML in it is escaped, and only http(s) and
+// mailto links are kept, so the result is safe to embed in a page.
+func MarkdownToHTML(src string) string {
+	inline := func(text string) string {
+		return renderInline(text, html.EscapeString,
+			func(code string) string { return "<code>" + code + "</code>" },
+			func(text, url string) string {
+				// url is already escaped, as it was part of the escaped text
+				if !safeLinkURL(html.UnescapeString(url)) {
+					return text
+				}
+				return fmt.Sprintf(`<a href="%s" rel="nofollow noopener">%s</a>`, url, text)
+			},
+			func(s string) string { return "<strong>" + s + "</strong>" },
+			func(s string) string { return "<em>" + s + "</em>" },
+		)
+	}
+
+	var b strings.Builder
+	for _, block := range parseMarkdown(src) {
+		switch block.kind {
+		case "heading":
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", block.level, inline(block.lines[0]), block.level)
+		case "bullet", "numbered":
+			tag := "ul"
+			if block.kind == "numbered" {
+				tag = "ol"
+			}
+			fmt.Fprintf(&b, "<%s>\n", tag)
+			for _, item := range block.lines {
+				fmt.Fprintf(&b, "<li>%s</li>\n", inline(item))
+			}
+			fmt.Fprintf(&b, "</%s>\n", tag)
+		case "quote":
+			fmt.Fprintf(&b, "<blockquote><p>%s</p></blockquote>\n", inline(strings.Join(block.lines, " ")))
+		case "code":
+			fmt.Fprintf(&b, "<pre><code>%s</code></pre>\n", html.EscapeString(strings.Join(block.lines, "\n")))
+		default:
+			fmt.Fprintf(&b, "<p>%s</p>\n", inline(strings.Join(block.lines, " ")))
+		}
+	}
+	return b.String()
+}
+
+// ANSI styles for the terminal rendering
+const (
+	ansiBold      = "\x1b[1m"
+	ansiNoBold    = "\x1b[22m"
+	ansiItalic    = "\x1b[3m"
+	ansiNoItalic  = "\x1b[23m"
+	ansiUnderline = "\x1b[4m"
+	ansiNoUnder   = "\x1b[24m"
+	ansiCyan      = "\x1b[36m"
+	ansiDefault   = "\x1b[39m"
+	ansiDim       = "\x1b[2m"
+)
+
+// MarkdownToTerminal formats a description for the terminal, every line prefixed with indent.
+// With color it uses ANSI bold, italics and underlines; without, the markup is just removed.
+func MarkdownToTerminal(src, indent string, color bool) string {
+	style := func(on, off string) func(string) string {
+		if !color {
+			return func(s string) string { return s }
+		}
+		return func(s string) string { return on + s + off }
+	}
+	bold := style(ansiBold, ansiNoBold)
+	italic := style(ansiItalic, ansiNoItalic)
+	code := style(ansiCyan, ansiDefault)
+	underline := style(ansiUnderline, ansiNoUnder)
+	dim := style(ansiDim, ansiNoBold)
+
+	inline := func(text string) string {
+		return renderInline(stripControl(text), func(s string) string { return s }, code,
+			func(text, url string) string { return underline(text) + " (" + url + ")" },
+			bold, italic,
+		)
+	}
+
+	var b strings.Builder
+	for i, block := range parseMarkdown(src) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch block.kind {
+		case "heading":
+			fmt.Fprintf(&b, "%s%s\n", indent, bold(underline(inline(block.lines[0]))))
+		case "bullet":
+			for _, item := range block.lines {
+				fmt.Fprintf(&b, "%s• %s\n", indent, inline(item))
+			}
+		case "numbered":
+			for n, item := range block.lines {
+				fmt.Fprintf(&b, "%s%d. %s\n", indent, n+1, inline(item))
+			}
+		case "quote":
+			fmt.Fprintf(&b, "%s│ %s\n", indent, italic(inline(strings.Join(block.lines, " "))))
+		case "code":
+			for _, line := range block.lines {
+				fmt.Fprintf(&b, "%s    %s\n", indent, dim(stripControl(line)))
+			}
+		default:
+			fmt.Fprintf(&b, "%s%s\n", indent, inline(strings.Join(block.lines, " ")))
+		}
+	}
+	return b.String()
+}
+
+// stripControl removes control characters, with which a description could move the cursor or
+// restyle the terminal
+func stripControl(text string) string {
+	return strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t') || r == 0x7f {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// colorOutput reports whether stdout is a terminal that ANSI styles can be written to
+func colorOutput() bool {
+	return term.IsTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == ""
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// matrixTokenEnv holds the access token when it is not in config.json
+const matrixTokenEnv = "MATRIX_ACCESS_TOKEN"
+
+// matrixSyncTimeout is how long a /sync long poll waits for new events
+const matrixSyncTimeout = 30 * time.Second
+
+// matrixNotifier posts notifications to a Matrix room and, with commands enabled, answers
+// !balance and !campaign queries there while the daemon runs
+type matrixNotifier struct {
+	homeserver string
+	token      string
+	room       string
+	commands   bool
+	client     *http.Client
+	txn        atomic.Uint64
+}
+
+func newMatrixNotifier(config NotificationConfig) (Notifier, error) {
+	token := config.Token
+	if token == "" {
+		token = os.Getenv(matrixTokenEnv)
+	}
+	if config.URL == "" || config.Room == "" || token == "" {
+		return nil, fmt.Errorf("url (the homeserver), room and token (or %s) are required", matrixTokenEnv)
+	}
+	return &matrixNotifier{
+		homeserver: strings.TrimSuffix(config.URL, "/"),
+		token:      token,
+		room:       config.Room,
+		commands:   config.Commands,
+		client:     &http.Client{Timeout: matrixSyncTimeout + 15*time.Second},
+	}, nil
+}
+
+func (m *matrixNotifier) Notify(ctx context.Context, n Notification) error {
+	icon := "🔔"
+	switch n.Severity {
+	case "warning":
+		icon = "⚠️"
+	case "resolved":
+		icon = "✅"
+	}
+	return m.send(ctx, "m.text",
+		fmt.Sprintf("%s %s: %s", icon, n.Title, n.Message),
+		fmt.Sprintf("%s <b>%s</b>: %s", icon, html.EscapeString(n.Title), html.EscapeString(n.Message)))
+}
+
+// send posts a message to the room, with an HTML version for clients that render it
+func (m *matrixNotifier) send(ctx context.Context, msgtype, body, formatted string) error {
+	content := map[string]string{"msgtype": msgtype, "body": body}
+	if formatted != "" {
+		content["format"] = "org.matrix.custom.html"
+		content["formatted_body"] = formatted
+	}
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%d-%d", url.PathEscape(m.room), time.Now().UnixNano(), m.txn.Add(1))
+	return m.do(ctx, http.MethodPut, path, content, nil)
+}
+
+// do calls the client-server API, decoding the response into result when given
+func (m *matrixNotifier) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, m.homeserver+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var matrixErr struct {
+			Code  string `json:"errcode"`
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&matrixErr)
+		return fmt.Errorf("matrix answered %s: %s %s", resp.Status, matrixErr.Code, matrixErr.Error)
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode Matrix response: %w", err)
+		}
+	}
+	return nil
+}
+
+// matrixSync is the part of a /sync response the bot reads
+type matrixSync struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []struct {
+					Type    string `json:"type"`
+					Sender  string `json:"sender"`
+					Content struct {
+						MsgType string `json:"msgtype"`
+						Body    string `json:"body"`
+					} `json:"content"`
+				} `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// Run answers commands in the room until ctx is cancelled. Messages sent while the bot was
+// offline are skipped rather than answered late.
+func (m *matrixNotifier) Run(ctx context.Context, d *Daemon) {
+	if !m.commands {
+		return
+	}
+
+	var whoami struct {
+		UserID string `json:"user_id"`
+	}
+	if err := m.do(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &whoami); err != nil {
+		logf(ctx, "Matrix bot disabled: %v", err)
+		return
+	}
+
+	filter, _ := json.Marshal(map[string]interface{}{
+		"presence":     map[string]interface{}{"not_types": []string{"*"}},
+		"account_data": map[string]interface{}{"not_types": []string{"*"}},
+		"room": map[string]interface{}{
+			"rooms":        []string{m.room},
+			"timeline":     map[string]interface{}{"types": []string{"m.room.message"}},
+			"state":        map[string]interface{}{"not_types": []string{"*"}},
+			"ephemeral":    map[string]interface{}{"not_types": []string{"*"}},
+			"account_data": map[string]interface{}{"not_types": []string{"*"}},
+		},
+	})
+	since := ""
+	for ctx.Err() == nil {
+		query := url.Values{"filter": {string(filter)}}
+		if since != "" {
+			query.Set("since", since)
+			query.Set("timeout", fmt.Sprint(matrixSyncTimeout.Milliseconds()))
+		}
+
+		var sync matrixSync
+		if err := m.do(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, &sync); err != nil {
+			if ctx.Err() == nil {
+				logf(ctx, "Matrix sync failed, retrying: %v", err)
+				sleepContext(ctx, 10*time.Second)
+			}
+			continue
+		}
+		first := since == ""
+		since = sync.NextBatch
+		if first {
+			continue // The initial sync only marks where new messages start
+		}
+
+		for _, event := range sync.Rooms.Join[m.room].Timeline.Events {
+			if event.Type != "m.room.message" || event.Sender == whoami.UserID || event.Content.MsgType != "m.text" {
+				continue
+			}
+			if !strings.HasPrefix(event.Content.Body, "!") {
+				continue
+			}
+			reply := m.answer(ctx, d, strings.Fields(event.Content.Body))
+			if err := m.send(ctx, "m.notice", reply, ""); err != nil {
+				logf(ctx, "Matrix reply failed: %v", err)
+			}
+		}
+	}
+}
+
+// answer runs a bot command and returns the reply
+func (m *matrixNotifier) answer(ctx context.Context, d *Daemon, args []string) string {
+	const help = "Commands: !balance [address], !campaign status [campaign]"
+
+	var targets []solana.PublicKey
+	parseTarget := func(rest []string) error {
+		if len(rest) == 0 {
+			targets = d.campaigns
+			return nil
+		}
+		address, err := solana.PublicKeyFromBase58(rest[0])
+		if err != nil {
+			return fmt.Errorf("invalid address %q", rest[0])
+		}
+		targets = []solana.PublicKey{address}
+		return nil
+	}
+
+	switch {
+	case args[0] == "!balance":
+		if err := parseTarget(args[1:]); err != nil {
+			return err.Error()
+		}
+		var lines []string
+		for _, target := range targets {
+			balance, err := d.app.client.GetBalance(ctx, target, rpc.CommitmentConfirmed)
+			if err != nil {
+				return fmt.Sprintf("Could not fetch the balance of %s: %v", target, err)
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s SOL", target, lamportsToSOL(balance.Value)))
+		}
+		return strings.Join(lines, "\n")
+
+	case args[0] == "!campaign" && len(args) >= 2 && args[1] == "status":
+		if err := parseTarget(args[2:]); err != nil {
+			return err.Error()
+		}
+		var lines []string
+		for _, target := range targets {
+			campaign, err := d.app.FetchCampaign(target)
+			if err != nil {
+				return fmt.Sprintf("Could not fetch campaign %s: %v", target, err)
+			}
+			balance, err := d.app.client.GetBalance(ctx, target, rpc.CommitmentConfirmed)
+			if err != nil {
+				return fmt.Sprintf("Could not fetch the balance of %s: %v", target, err)
+			}
+			lines = append(lines, fmt.Sprintf("%s: raised %s SOL, balance %s SOL (%s)",
+				campaign.Name, lamportsToSOL(campaign.AmountDonated), lamportsToSOL(balance.Value), d.app.AddressURL(target.String())))
+		}
+		return strings.Join(lines, "\n")
+
+	default:
+		return help
+	}
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// corsMaxAge is how long browsers may reuse a preflight response
+const corsMaxAge = 10 * time.Minute
+
+// gzipMinSize is the smallest body worth compressing; a sniffed small body goes out as is
+const gzipMinSize = 512
+
+// originAllowed reports whether a browser page from origin may read the API. Requests without an
+// Origin header do not come from a cross-origin page and are always allowed.
+func (s *Server) originAllowed(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.opts.CORSOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// cors lets pages on the configured origins read the API and answers their preflight requests.
+// The admin dashboard is left out: only its own page may call it.
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || strings.HasPrefix(r.URL.Path, "/ui/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		if !s.originAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(s.opts.CORSOrigins) == 1 && s.opts.CORSOrigins[0] == "*" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Set("Access-Control-Expose-Headers", requestIDHeader+", Retry-After, X-Cache")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+apiKeyHeader+", "+requestIDHeader)
+			w.Header().Set("Access-Control-Max-Age", fmt.Sprint(int(corsMaxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// securityHeaders sets defaults that keep browsers from sniffing content types, framing our pages
+// or leaking full URLs to other sites
+func (s *Server) securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		if r.TLS != nil {
+			h.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// compressible reports whether responses of a content type are worth compressing
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "application/json", "application/atom+xml", "image/svg+xml", "text/html", "text/css", "text/javascript", "text/plain":
+		return true
+	}
+	return false
+}
+
+// gzipResponses compresses text responses for clients that accept gzip. Event streams and
+// WebSocket upgrades are passed through, as they must reach the client unbuffered.
+func (s *Server) gzipResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Header.Get("Upgrade") != "" || strings.HasSuffix(r.URL.Path, "/events") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipPool reuses gzip writers across responses
+var gzipPool = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+
+// gzipResponseWriter compresses the body once its content type turns out to be compressible
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte // start of the body, held until enough is known to decide
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	// Bodyless responses go out right away
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+// decide starts the response, compressed or not
+func (w *gzipResponseWriter) decide(compress bool) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.gz = gzipPool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		if !compressible(w.Header().Get("Content-Type")) || w.Header().Get("Content-Encoding") != "" {
+			w.decide(false)
+		} else if len(w.buf)+len(p) < gzipMinSize {
+			w.buf = append(w.buf, p...)
+			return len(p), nil
+		} else {
+			w.decide(true)
+		}
+		if len(w.buf) > 0 {
+			if _, err := w.write(w.buf); err != nil {
+				return 0, err
+			}
+			w.buf = nil
+		}
+	}
+	return w.write(p)
+}
+
+func (w *gzipResponseWriter) write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far, compressing a held-back start of the body if need be
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided && len(w.buf) > 0 {
+		buf := w.buf
+		w.buf = nil
+		w.decide(compressible(w.Header().Get("Content-Type")))
+		w.write(buf)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the response: a body too small to compress is written as is
+func (w *gzipResponseWriter) Close() {
+	if !w.decided {
+		if len(w.buf) > 0 {
+			w.Header().Set("Content-Length", fmt.Sprint(len(w.buf)))
+		}
+		w.decide(false)
+		if len(w.buf) > 0 {
+			w.ResponseWriter.Write(w.buf)
+		}
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipPool.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// accessLogEntry is one line of the access log
+type accessLogEntry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"requestId"`
+	RemoteIP   string    `json:"remoteIp"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"` // without the query string, which may carry the dashboard token
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMS float64   `json:"durationMs"`
+	Cache      string    `json:"cache,omitempty"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	Referer    string    `json:"referer,omitempty"`
+}
+
+// accessLog writes one JSON line per request
+type accessLog struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// openAccessLog opens the access log: "-" is standard output, anything else a file appended to
+func openAccessLog(path string) (*accessLog, error) {
+	if path == "-" {
+		return &accessLog{out: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	return &accessLog{out: f}, nil
+}
+
+func (l *accessLog) write(entry accessLogEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(append(line, '\n'))
+}
+
+// logAccess records every request in the access log once it has been answered
+func (s *Server) logAccess(next http.Handler) http.Handler {
+	if s.accessLog == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			s.accessLog.write(accessLogEntry{
+				Time:       start.UTC(),
+				RequestID:  requestIDFrom(r.Context()),
+				RemoteIP:   s.clientIP(r),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Status:     status,
+				Bytes:      rec.bytes,
+				DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+				Cache:      w.Header().Get("X-Cache"),
+				UserAgent:  r.UserAgent(),
+				Referer:    r.Referer(),
+			})
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// statusRecorder notes the status and size of a response, passing on streaming and upgrades
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection to the WebSocket API, which then answers with 101
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("connection cannot be hijacked")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
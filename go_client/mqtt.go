@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttPasswordEnv holds the broker password when it is not in config.json
+const mqttPasswordEnv = "CROWDFUNDING_MQTT_PASSWORD"
+
+// MQTTConfig configures publishing live campaign events to an MQTT broker in config.json
+type MQTTConfig struct {
+	Broker     string            `json:"broker"`             // e.g. tcp://localhost:1883 or ssl://broker:8883
+	ClientID   string            `json:"clientId,omitempty"` // default crowdfunding-<pid>
+	Username   string            `json:"username,omitempty"`
+	Password   string            `json:"password,omitempty"` // or set CROWDFUNDING_MQTT_PASSWORD
+	Topic      string            `json:"topic,omitempty"`    // default "crowdfunding/{campaign}"
+	Topics     map[string]string `json:"topics,omitempty"`   // campaign address -> topic
+	QoS        byte              `json:"qos,omitempty"`
+	Retain     bool              `json:"retain,omitempty"`     // keep the latest event for displays that connect later
+	Milestones []string          `json:"milestones,omitempty"` // raised totals in SOL, e.g. ["10", "50"]
+
+	milestones []uint64
+}
+
+// MilestoneEvent is published when a campaign's raised total passes a milestone
+type MilestoneEvent struct {
+	Type          string `json:"type"` // always milestone
+	Campaign      string `json:"campaign"`
+	Milestone     uint64 `json:"milestone"` // lamports
+	AmountDonated uint64 `json:"amountDonated"`
+	Signature     string `json:"signature"` // the donation that passed it
+}
+
+// prepare validates the settings and parses the milestones
+func (c *MQTTConfig) prepare() error {
+	if c.Broker == "" {
+		return fmt.Errorf("mqtt: broker is required")
+	}
+	if c.QoS > 2 {
+		return fmt.Errorf("mqtt: qos must be 0, 1 or 2")
+	}
+	c.milestones = nil
+	for _, milestone := range c.Milestones {
+		lamports, err := parseSOL(milestone)
+		if err != nil {
+			return fmt.Errorf("mqtt: invalid milestone: %w", err)
+		}
+		c.milestones = append(c.milestones, lamports)
+	}
+	return nil
+}
+
+// topic returns where a campaign's events are published
+func (c *MQTTConfig) topic(campaign string) string {
+	if topic, ok := c.Topics[campaign]; ok {
+		return topic
+	}
+	topic := c.Topic
+	if topic == "" {
+		topic = "crowdfunding/{campaign}"
+	}
+	return strings.ReplaceAll(topic, "{campaign}", campaign)
+}
+
+// mqttPublisher forwards campaign events and passed milestones to the broker
+type mqttPublisher struct {
+	config *MQTTConfig
+	client mqtt.Client
+
+	mu     sync.Mutex
+	raised map[string]uint64 // last known raised total per campaign, for milestone detection
+}
+
+// connectMQTT connects to the broker. The client reconnects by itself if the connection drops later.
+func connectMQTT(config *MQTTConfig) (*mqttPublisher, error) {
+	clientID := config.ClientID
+	if clientID == "" {
+		clientID = fmt.Sprintf("crowdfunding-%d", os.Getpid())
+	}
+	password := config.Password
+	if password == "" {
+		password = os.Getenv(mqttPasswordEnv)
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(clientID).
+		SetUsername(config.Username).
+		SetPassword(password).
+		SetConnectTimeout(10 * time.Second).
+		SetAutoReconnect(true)
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(15*time.Second) || token.Error() != nil {
+		err := token.Error()
+		if err == nil {
+			err = fmt.Errorf("timed out")
+		}
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: %w", config.Broker, err)
+	}
+	return &mqttPublisher{config: config, client: client, raised: map[string]uint64{}}, nil
+}
+
+// publish sends one JSON message to a campaign's topic
+func (p *mqttPublisher) publish(campaign string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	token := p.client.Publish(p.config.topic(campaign), p.config.QoS, p.config.Retain, data)
+	if !token.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("timed out publishing to %s", p.config.topic(campaign))
+	}
+	return token.Error()
+}
+
+// handle publishes an event, followed by any milestone the campaign's raised total passed
+func (p *mqttPublisher) handle(event CampaignEvent) {
+	if err := p.publish(event.Campaign, event); err != nil {
+		log.Printf("MQTT publish failed: %v", err)
+	}
+	if event.Type != "donate" {
+		return
+	}
+
+	p.mu.Lock()
+	previous, known := p.raised[event.Campaign]
+	if event.AmountDonated > previous {
+		p.raised[event.Campaign] = event.AmountDonated
+	}
+	p.mu.Unlock()
+	if !known {
+		return // Without an earlier total we cannot tell which milestones are new
+	}
+
+	for _, milestone := range p.config.milestones {
+		if previous < milestone && event.AmountDonated >= milestone {
+			err := p.publish(event.Campaign, MilestoneEvent{
+				Type:          "milestone",
+				Campaign:      event.Campaign,
+				Milestone:     milestone,
+				AmountDonated: event.AmountDonated,
+				Signature:     event.Activity.Signature,
+			})
+			if err != nil {
+				log.Printf("MQTT publish failed: %v", err)
+			}
+		}
+	}
+}
+
+// startMQTT connects to the broker and seeds the raised totals used for milestone detection
+func (d *Daemon) startMQTT() (*mqttPublisher, error) {
+	publisher, err := connectMQTT(d.mqtt)
+	if err != nil {
+		return nil, err
+	}
+	for _, campaign := range d.campaigns {
+		if account, err := d.app.FetchCampaign(campaign); err == nil {
+			publisher.raised[campaign.String()] = account.AmountDonated
+		}
+	}
+	fmt.Printf("📡 Publishing events of %d campaign(s) to %s\n", len(d.campaigns), d.mqtt.Broker)
+	return publisher, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Notification is a message for the people running a campaign
+type Notification struct {
+	Title       string    `json:"title"`
+	Message     string    `json:"message"`
+	Campaign    string    `json:"campaign,omitempty"`
+	Severity    string    `json:"severity"` // info, warning or resolved
+	Time        time.Time `json:"time"`
+	OperationID string    `json:"operationId,omitempty"`
+	Thread      string    `json:"thread,omitempty"` // groups follow-ups about the same thing, e.g. an alert and its resolution
+}
+
+// Notifier delivers notifications to one destination
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotificationConfig configures one notification backend in config.json
+type NotificationConfig struct {
+	Type string `json:"type"`          // console, webhook, slack or matrix
+	URL  string `json:"url,omitempty"` // webhook endpoint, a Slack incoming webhook or the Matrix homeserver
+
+	Token    string            `json:"token,omitempty"`    // Slack bot token or Matrix access token, also read from the environment
+	Channel  string            `json:"channel,omitempty"`  // Slack channel for campaigns without their own
+	Channels map[string]string `json:"channels,omitempty"` // campaign address -> Slack channel
+	Room     string            `json:"room,omitempty"`     // Matrix room ID
+	Commands bool              `json:"commands,omitempty"` // answer Matrix commands such as !balance while the daemon runs
+}
+
+// backgroundNotifier is a backend that also works while the daemon runs, such as a chat bot answering commands
+type backgroundNotifier interface {
+	Run(ctx context.Context, d *Daemon)
+}
+
+// notifierFactories builds each backend type from its config
+var notifierFactories = map[string]func(NotificationConfig) (Notifier, error){
+	"console": func(NotificationConfig) (Notifier, error) { return consoleNotifier{}, nil },
+	"webhook": newWebhookNotifier,
+	"slack":   newSlackNotifier,
+	"matrix":  newMatrixNotifier,
+}
+
+// NewNotifiers builds the configured backends, defaulting to the console
+func NewNotifiers(configs []NotificationConfig) (Notifiers, error) {
+	if len(configs) == 0 {
+		return Notifiers{consoleNotifier{}}, nil
+	}
+
+	var notifiers Notifiers
+	for _, config := range configs {
+		factory, ok := notifierFactories[config.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown notification type %q", config.Type)
+		}
+		notifier, err := factory(config)
+		if err != nil {
+			return nil, fmt.Errorf("%s notifications: %w", config.Type, err)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	return notifiers, nil
+}
+
+// Notifiers fans a notification out to every backend
+type Notifiers []Notifier
+
+// Notify sends n to every backend, logging failures instead of stopping at the first
+func (ns Notifiers) Notify(ctx context.Context, n Notification) {
+	if n.Time.IsZero() {
+		n.Time = time.Now().UTC()
+	}
+	if n.OperationID == "" {
+		n.OperationID = operationID
+	}
+
+	for _, notifier := range ns {
+		if err := notifier.Notify(ctx, n); err != nil {
+			log.Printf("Notification %q via %T failed: %v", n.Title, notifier, err)
+		}
+	}
+}
+
+// consoleNotifier prints notifications to stdout
+type consoleNotifier struct{}
+
+func (consoleNotifier) Notify(ctx context.Context, n Notification) error {
+	icon := "🔔"
+	switch n.Severity {
+	case "warning":
+		icon = "⚠️ "
+	case "resolved":
+		icon = "✅"
+	}
+	fmt.Printf("%s %s: %s\n", icon, n.Title, n.Message)
+	return nil
+}
+
+// webhookNotifier POSTs notifications as JSON
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookNotifier(config NotificationConfig) (Notifier, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	return &webhookNotifier{url: config.URL, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.OperationID != "" {
+		req.Header.Set(requestIDHeader, n.OperationID)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// checkCampaignRent checks the wallet can pay for the campaign account's rent
+func (app *SolanaDApp) checkCampaignRent(ctx context.Context) error {
+	rent, err := app.client.GetMinimumBalanceForRentExemption(ctx, crowdfund.CampaignAccountSpace, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get rent exemption: %w", err)
+	}
+	balance, err := app.client.GetBalance(ctx, app.wallet.Address(), rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get balance: %w", err)
+	}
+	if balance.Value < rent {
+		return fmt.Errorf("%w: the campaign account needs %s SOL of rent and the wallet holds %s SOL", crowdfund.ErrInsufficientBalance, lamportsToSOL(rent), lamportsToSOL(balance.Value))
+	}
+	return nil
+}
+
+// campaignAddressBlocked reports whether an account at a campaign's address stops the program
+// creating the campaign there. The program can take over an address that only holds lamports,
+// but not one already allocated or assigned to a program.
+func (app *SolanaDApp) campaignAddressBlocked(account *cachedAccount) bool {
+	if account == nil {
+		return false
+	}
+	if account.Owner.Equals(app.programID) {
+		return account.Campaign == nil
+	}
+	return !account.Owner.Equals(solana.SystemProgramID) || len(account.Data) > 0
+}
+
+// freeCampaignName suggests a variant of name whose address for this wallet is free
+func (app *SolanaDApp) freeCampaignName(name string) string {
+	for i := 2; i <= 20; i++ {
+		suffix := fmt.Sprintf("-%d", i)
+		candidate := name
+		if len(candidate)+len(suffix) > solana.MaxSeedLength {
+			candidate = strings.ToValidUTF8(candidate[:solana.MaxSeedLength-len(suffix)], "")
+		}
+		candidate += suffix
+		address, _, err := app.CreateCampaignPDA(candidate)
+		if err != nil {
+			continue
+		}
+		if account, err := app.getAccount(address); err == nil && account == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// printCampaignRemedies explains how to create a campaign whose address is taken
+func (app *SolanaDApp) printCampaignRemedies(name string) {
+	fmt.Println("🔧 The address is derived from the wallet and the campaign name, so either can be changed:")
+	if free := app.freeCampaignName(name); free != "" {
+		fmt.Printf("   • Rename the campaign, e.g. %q, which is free for this wallet\n", free)
+	} else {
+		fmt.Println("   • Rename the campaign")
+	}
+	fmt.Println("   • Use a different seed: the name is the only seed you choose, so add e.g. a year or edition to it")
+	fmt.Println("   • Create it from a different wallet; your existing wallet and its funds stay usable")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Page layout of text PDFs: US Letter in points, Courier at pdfFontSize
+const (
+	pdfPageWidth     = 612
+	pdfPageHeight    = 792
+	pdfMargin        = 54
+	pdfFontSize      = 9
+	pdfLeading       = 11
+	pdfLinesPerPage  = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+	pdfCharsPerLine  = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6) // Courier glyphs are 0.6 em wide
+	pdfContinuations = "  "
+)
+
+// pdfEscape makes a line safe inside a PDF string. The standard fonts only cover Latin-1, so
+// other characters are replaced.
+func pdfEscape(line string) string {
+	var b strings.Builder
+	for _, r := range line {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// pdfWrap breaks lines longer than a page is wide
+func pdfWrap(lines []string) []string {
+	var wrapped []string
+	for _, line := range lines {
+		runes := []rune(line)
+		for len(runes) > pdfCharsPerLine {
+			wrapped = append(wrapped, string(runes[:pdfCharsPerLine]))
+			runes = append([]rune(pdfContinuations), runes[pdfCharsPerLine:]...)
+		}
+		wrapped = append(wrapped, string(runes))
+	}
+	return wrapped
+}
+
+// writeTextPDF writes lines of monospaced text as a PDF document, numbering its pages
+func writeTextPDF(path, title string, lines []string) error {
+	lines = pdfWrap(lines)
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage-2 {
+		pages = append(pages, lines[:pdfLinesPerPage-2])
+		lines = lines[pdfLinesPerPage-2:]
+	}
+	pages = append(pages, lines)
+
+	// Objects: 1 catalog, 2 page tree, 3 font, 4 info, then a page and its content stream per page
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Producer (crowdfunding-client %s) >>", pdfEscape(title), pdfEscape(Version)),
+	)
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscape(line))
+		}
+		fmt.Fprintf(&content, "T* (%s) Tj\nET", pdfEscape(fmt.Sprintf("%s - page %d of %d", title, i+1, len(pages))))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
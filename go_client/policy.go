@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultPolicyFile holds the withdrawal rules when config.json does not name another file
+const defaultPolicyFile = "policy.yaml"
+
+// Policy is a set of rules every withdrawal must pass before the client signs it
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule restricts the withdrawals it applies to. Campaigns and Above narrow which
+// withdrawals that is; the other fields are the restrictions.
+type PolicyRule struct {
+	Name      string             `yaml:"name"`
+	Campaigns []solana.PublicKey `yaml:"campaigns,omitempty"` // applies to these campaigns only; all when empty
+	Above     string             `yaml:"above,omitempty"`     // applies to withdrawals above this many SOL only
+
+	Max          string             `yaml:"max,omitempty"`          // block withdrawals above this many SOL
+	Destinations []solana.PublicKey `yaml:"destinations,omitempty"` // block withdrawals paying any other wallet
+	Hours        string             `yaml:"hours,omitempty"`        // allowed time of day, e.g. "09:00-17:30"
+	Days         []string           `yaml:"days,omitempty"`         // allowed weekdays, e.g. [mon, tue, wed, thu, fri]
+	Timezone     string             `yaml:"timezone,omitempty"`     // for hours and days, default local time
+	Confirm      bool               `yaml:"confirm,omitempty"`      // require retyping the amount before signing
+	TOTP         bool               `yaml:"totp,omitempty"`         // require a code from the enrolled authenticator app
+	FIDO2        bool               `yaml:"fido2,omitempty"`        // require a touch of the enrolled security key
+
+	above, max uint64 // lamports
+	location   *time.Location
+	start, end int // minutes after midnight
+	days       map[time.Weekday]bool
+}
+
+// PolicyWithdrawal is a withdrawal about to be signed
+type PolicyWithdrawal struct {
+	Campaign    solana.PublicKey
+	Destination solana.PublicKey // the withdrawing wallet, which the program pays
+	Forwards    []PolicyTransfer // SOL the same transaction moves on out of Destination
+	Lamports    uint64
+	Time        time.Time
+}
+
+// PolicyTransfer is SOL a system program instruction moves out of the withdrawing wallet
+type PolicyTransfer struct {
+	To       solana.PublicKey
+	Lamports uint64
+}
+
+// destinations are the wallets the withdrawal ends up in: those it is forwarded to, and the
+// withdrawing wallet itself unless all of it is forwarded
+func (w PolicyWithdrawal) destinations() []solana.PublicKey {
+	var destinations []solana.PublicKey
+	var forwarded uint64
+	for _, forward := range w.Forwards {
+		destinations = append(destinations, forward.To)
+		forwarded += forward.Lamports
+	}
+	if forwarded < w.Lamports {
+		destinations = append(destinations, w.Destination)
+	}
+	return destinations
+}
+
+var policyWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// LoadPolicy reads and validates the policy file, returning nil when there is none
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for i := range policy.Rules {
+		if err := policy.Rules[i].prepare(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+	}
+	return &policy, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// prepare validates the rule and parses its time window
+func (r *PolicyRule) prepare() error {
+	if r.Name == "" {
+		r.Name = "unnamed rule"
+	}
+	var err error
+	if r.Above != "" {
+		if r.above, err = parseSOL(r.Above); err != nil {
+			return fmt.Errorf("invalid above: %w", err)
+		}
+	}
+	if r.Max != "" {
+		if r.max, err = parseSOL(r.Max); err != nil {
+			return fmt.Errorf("invalid max: %w", err)
+		}
+	}
+
+	r.location = displayLocation
+	if r.Timezone != "" {
+		location, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", r.Timezone, err)
+		}
+		r.location = location
+	}
+	if r.Hours != "" {
+		from, to, ok := strings.Cut(r.Hours, "-")
+		if !ok {
+			return fmt.Errorf("invalid hours %q, expected HH:MM-HH:MM", r.Hours)
+		}
+		if r.start, err = parseClock(from); err != nil {
+			return err
+		}
+		if r.end, err = parseClock(to); err != nil {
+			return err
+		}
+	}
+	if len(r.Days) > 0 {
+		r.days = map[time.Weekday]bool{}
+		for _, day := range r.Days {
+			weekday, ok := policyWeekdays[strings.ToLower(day)[:min(3, len(day))]]
+			if !ok {
+				return fmt.Errorf("invalid day %q", day)
+			}
+			r.days[weekday] = true
+		}
+	}
+	return nil
+}
+
+// applies reports whether the rule covers the withdrawal
+func (r *PolicyRule) applies(w PolicyWithdrawal) bool {
+	if len(r.Campaigns) > 0 && !containsKey(r.Campaigns, w.Campaign) {
+		return false
+	}
+	return w.Lamports > r.above
+}
+
+// violations returns why the rule blocks the withdrawal, if it does
+func (r *PolicyRule) violations(w PolicyWithdrawal) []string {
+	var reasons []string
+	if r.Max != "" && w.Lamports > r.max {
+		reasons = append(reasons, fmt.Sprintf("%s SOL exceeds the %s SOL ceiling", lamportsToSOL(w.Lamports), r.Max))
+	}
+	if len(r.Destinations) > 0 {
+		for _, destination := range w.destinations() {
+			if !containsKey(r.Destinations, destination) {
+				reasons = append(reasons, fmt.Sprintf("destination %s is not on the allowlist", destination))
+			}
+		}
+	}
+	local := w.Time.In(r.location)
+	if r.days != nil && !r.days[local.Weekday()] {
+		reasons = append(reasons, fmt.Sprintf("withdrawals are not allowed on %s", local.Weekday()))
+	}
+	if r.Hours != "" {
+		minute := local.Hour()*60 + local.Minute()
+		inside := minute >= r.start && minute < r.end
+		if r.start > r.end { // overnight window
+			inside = minute >= r.start || minute < r.end
+		}
+		if !inside {
+			reasons = append(reasons, fmt.Sprintf("%s is outside the allowed hours %s", local.Format("15:04 MST"), r.Hours))
+		}
+	}
+	return reasons
+}
+
+// PolicyDecision is the outcome of checking a withdrawal
+type PolicyDecision struct {
+	Violations []string // why the withdrawal is blocked
+	Confirm    []string // rules asking for a second confirmation
+	TOTP       []string // rules asking for an authenticator code
+	FIDO2      []string // rules asking for a security key touch
+}
+
+// Check evaluates every rule against a withdrawal
+func (p *Policy) Check(w PolicyWithdrawal) PolicyDecision {
+	var decision PolicyDecision
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.applies(w) {
+			continue
+		}
+		for _, reason := range rule.violations(w) {
+			decision.Violations = append(decision.Violations, fmt.Sprintf("%s: %s", rule.Name, reason))
+		}
+		if rule.Confirm {
+			decision.Confirm = append(decision.Confirm, rule.Name)
+		}
+		if rule.TOTP {
+			decision.TOTP = append(decision.TOTP, rule.Name)
+		}
+		if rule.FIDO2 {
+			decision.FIDO2 = append(decision.FIDO2, rule.Name)
+		}
+	}
+	return decision
+}
+
+// policyWithdrawals extracts the withdrawals a transaction makes, with the SOL it moves on out
+// of the withdrawing wallet, e.g. to a treasury, as where each withdrawal goes
+func (app *SolanaDApp) policyWithdrawals(tx *solana.Transaction) []PolicyWithdrawal {
+	var withdrawals []PolicyWithdrawal
+	forwards := map[solana.PublicKey][]PolicyTransfer{}
+	for _, compiled := range tx.Message.Instructions {
+		program, err := tx.Message.Program(compiled.ProgramIDIndex)
+		if err != nil || !program.Equals(solana.SystemProgramID) {
+			continue
+		}
+		accounts, err := compiled.ResolveInstructionAccounts(&tx.Message)
+		if err != nil {
+			continue
+		}
+		if from, transfer, ok := systemTransfer(accounts, compiled.Data); ok {
+			forwards[from] = append(forwards[from], transfer)
+		}
+	}
+
+	for _, compiled := range tx.Message.Instructions {
+		program, err := tx.Message.Program(compiled.ProgramIDIndex)
+		if err != nil || !program.Equals(app.programID) {
+			continue
+		}
+		ix, ok := app.idl.MatchInstruction(compiled.Data)
+		if !ok || ix.Name != "withdraw" {
+			continue
+		}
+		w := PolicyWithdrawal{Time: time.Now()}
+		accounts, err := compiled.ResolveInstructionAccounts(&tx.Message)
+		if err == nil {
+			for i, account := range ix.Accounts {
+				if i >= len(accounts) {
+					break
+				}
+				switch crowdfund.NormalizeName(account.Name) {
+				case "campaign":
+					w.Campaign = accounts[i].PublicKey
+				case "user":
+					w.Destination = accounts[i].PublicKey
+				}
+			}
+		}
+		args, _ := ix.DecodeArgs(compiled.Data)
+		for _, arg := range args {
+			if amount, ok := arg.Value.(uint64); ok && crowdfund.NormalizeName(arg.Name) == "amount" {
+				w.Lamports = amount
+			}
+		}
+		w.Forwards = forwards[w.Destination]
+		withdrawals = append(withdrawals, w)
+	}
+	return withdrawals
+}
+
+// systemTransfer decodes a system program instruction that moves SOL between wallets, including
+// into an account it creates
+func systemTransfer(accounts []*solana.AccountMeta, data []byte) (from solana.PublicKey, transfer PolicyTransfer, ok bool) {
+	decoded, err := system.DecodeInstruction(accounts, data)
+	if err != nil {
+		return from, transfer, false
+	}
+	var funding, recipient *solana.AccountMeta
+	var lamports *uint64
+	switch ix := decoded.Impl.(type) {
+	case *system.Transfer:
+		funding, recipient, lamports = ix.GetFundingAccount(), ix.GetRecipientAccount(), ix.Lamports
+	case *system.TransferWithSeed:
+		funding, recipient, lamports = ix.GetFundingAccount(), ix.GetRecipientAccount(), ix.Lamports
+	case *system.CreateAccount:
+		funding, recipient, lamports = ix.GetFundingAccount(), ix.GetNewAccount(), ix.Lamports
+	case *system.CreateAccountWithSeed:
+		funding, recipient, lamports = ix.GetFundingAccount(), ix.GetCreatedAccount(), ix.Lamports
+	}
+	if funding == nil || recipient == nil || lamports == nil {
+		return from, transfer, false
+	}
+	return funding.PublicKey, PolicyTransfer{To: recipient.PublicKey, Lamports: *lamports}, true
+}
+
+// withdrawalPolicy loads the configured policy file, nil when there is none
+func (app *SolanaDApp) withdrawalPolicy() (*Policy, error) {
+	path := app.config.Policy
+	if path == "" {
+		path = defaultPolicyFile
+	}
+	return LoadPolicy(path)
+}
+
+// checkPolicy evaluates the withdrawal policy against a transaction before it is signed.
+// Violations are logged and recorded in the audit log, and block the transaction.
+func (app *SolanaDApp) checkPolicy(tx *solana.Transaction) error {
+	withdrawals := app.policyWithdrawals(tx)
+	if len(withdrawals) == 0 {
+		return nil
+	}
+	policy, err := app.withdrawalPolicy()
+	if err != nil {
+		// A broken policy must not silently allow everything
+		return fmt.Errorf("withdrawal blocked: %w", err)
+	}
+	if policy == nil {
+		return nil
+	}
+
+	for _, w := range withdrawals {
+		decision := policy.Check(w)
+		violations := decision.Violations
+		if len(violations) == 0 && len(decision.Confirm) > 0 && !confirmWithdrawal(w, decision.Confirm) {
+			violations = append(violations, fmt.Sprintf("%s: second confirmation not given", strings.Join(decision.Confirm, ", ")))
+		}
+		if len(violations) == 0 && len(decision.TOTP) > 0 {
+			if err := promptTOTP(w, decision.TOTP); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %v", strings.Join(decision.TOTP, ", "), err))
+			}
+		}
+		if len(violations) == 0 && len(decision.FIDO2) > 0 {
+			// The key signs the transaction message itself, so the touch approves this transaction only
+			message, err := tx.Message.MarshalBinary()
+			if err == nil {
+				err = approveFIDO2(message, w, decision.FIDO2)
+			}
+			if err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %v", strings.Join(decision.FIDO2, ", "), err))
+			}
+		}
+		if len(violations) > 0 {
+			log.Printf("Policy blocked withdrawal of %d lamports from %s: %s", w.Lamports, w.Campaign, strings.Join(violations, "; "))
+			app.auditBlocked(tx, violations)
+			fmt.Println("🚫 Withdrawal blocked by policy:")
+			for _, violation := range violations {
+				fmt.Printf("   • %s\n", violation)
+			}
+			return fmt.Errorf("withdrawal blocked by policy: %s", strings.Join(violations, "; "))
+		}
+	}
+	return nil
+}
+
+// confirmWithdrawal asks the operator to retype the amount, which fails without a terminal
+func confirmWithdrawal(w PolicyWithdrawal, rules []string) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	fmt.Printf("⚠️  %s require a second confirmation of this withdrawal:\n", strings.Join(rules, ", "))
+	var destinations []string
+	for _, destination := range w.destinations() {
+		destinations = append(destinations, destination.String())
+	}
+	fmt.Printf("   %s SOL from %s to %s\n", lamportsToSOL(w.Lamports), w.Campaign, strings.Join(destinations, ", "))
+	fmt.Print("   Type the amount in SOL again to confirm: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	typed, err := parseSOL(strings.TrimSpace(answer))
+	return err == nil && typed == w.Lamports
+}
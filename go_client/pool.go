@@ -0,0 +1,50 @@
+package main
+
+import "sync"
+
+// defaultWorkers is the worker count for bulk fetching and decoding
+const defaultWorkers = 16
+
+// forEachParallel calls fn for every index in [0, n) using at most workers goroutines.
+// It returns the first error, after all started calls have finished.
+func forEachParallel(n, workers int, fn func(i int) error) error {
+	if workers > n {
+		workers = n
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		next     = make(chan int)
+		failed   = make(chan struct{})
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := fn(i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case next <- i:
+		case <-failed:
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	return firstErr
+}
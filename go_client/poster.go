@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Poster size in pixels: A-series proportions, 150 dpi when printed at A5
+const (
+	posterWidth  = 1240
+	posterHeight = 1754
+	posterMargin = 100
+	posterQRSize = 760
+)
+
+var (
+	posterInk    = color.RGBA{0x11, 0x11, 0x11, 0xff}
+	posterMuted  = color.RGBA{0x66, 0x66, 0x66, 0xff}
+	posterAccent = color.RGBA{0x99, 0x45, 0xff, 0xff} // Solana purple
+	posterTrack  = color.RGBA{0xe6, 0xe6, 0xe6, 0xff}
+)
+
+// Poster is the content of a printable campaign poster
+type Poster struct {
+	Name        string
+	Description string
+	Address     solana.PublicKey
+	Raised      uint64 // lamports, the on-chain amount_donated
+	Goal        uint64 // lamports, 0 to show the raised total without a progress bar
+	Link        string // Solana Pay URL encoded in the QR code
+	AsOf        time.Time
+}
+
+// posterText is one laid out line of text, centred horizontally with its baseline at y
+type posterText struct {
+	text  string
+	size  float64
+	bold  bool
+	color color.RGBA
+	y     int
+}
+
+// posterLayout positions everything on the poster, shared by the SVG and PNG renderers
+type posterLayout struct {
+	texts   []posterText
+	qr      [][]bool
+	qrRect  image.Rectangle
+	bar     image.Rectangle // empty without a goal
+	barFill float64
+}
+
+// posterFonts measures and draws text with the Go fonts, so wrapping matches across both formats
+type posterFonts struct {
+	regular, bold *opentype.Font
+	faces         map[posterFace]font.Face
+}
+
+// posterFace identifies a cached font face
+type posterFace struct {
+	size float64
+	bold bool
+}
+
+// newPosterFonts loads the embedded Go regular and bold fonts
+func newPosterFonts() (*posterFonts, error) {
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font: %w", err)
+	}
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font: %w", err)
+	}
+	return &posterFonts{regular: regular, bold: bold, faces: map[posterFace]font.Face{}}, nil
+}
+
+// face returns a font face of the given size, creating it on first use
+func (f *posterFonts) face(size float64, bold bool) (font.Face, error) {
+	key := posterFace{size, bold}
+	if face, ok := f.faces[key]; ok {
+		return face, nil
+	}
+	src := f.regular
+	if bold {
+		src = f.bold
+	}
+	face, err := opentype.NewFace(src, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font face: %w", err)
+	}
+	f.faces[key] = face
+	return face, nil
+}
+
+// width measures text in pixels
+func (f *posterFonts) width(text string, size float64, bold bool) (int, error) {
+	face, err := f.face(size, bold)
+	if err != nil {
+		return 0, err
+	}
+	return font.MeasureString(face, text).Ceil(), nil
+}
+
+// wrap breaks text into at most maxLines lines of maxWidth pixels, ending the last with an ellipsis if cut short
+func (f *posterFonts) wrap(text string, size float64, bold bool, maxLines, maxWidth int) ([]string, error) {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		width, err := f.width(candidate, size, bold)
+		if err != nil {
+			return nil, err
+		}
+		if width <= maxWidth || line == "" {
+			line = candidate
+			continue
+		}
+		lines = append(lines, line)
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] += " …"
+	}
+	return lines, nil
+}
+
+// layout positions the poster's text, QR code and progress bar from top to bottom
+func (p Poster) layout(fonts *posterFonts) (*posterLayout, error) {
+	qr, err := qrcode.New(p.Link, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	l := &posterLayout{qr: qr.Bitmap()}
+	y := posterMargin
+	addLines := func(text string, size float64, bold bool, c color.RGBA, maxLines int) error {
+		lines, err := fonts.wrap(text, size, bold, maxLines, posterWidth-2*posterMargin)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			y += int(size * 1.2)
+			l.texts = append(l.texts, posterText{text: line, size: size, bold: bold, color: c, y: y})
+		}
+		return nil
+	}
+
+	if err := addLines(p.Name, 84, true, posterInk, 2); err != nil {
+		return nil, err
+	}
+	y += 16
+	if err := addLines(p.Description, 36, false, posterMuted, 3); err != nil {
+		return nil, err
+	}
+
+	// Whole pixels per module keep the code sharp
+	module := posterQRSize / len(l.qr)
+	size := module * len(l.qr)
+	y += 40
+	l.qrRect = image.Rect((posterWidth-size)/2, y, (posterWidth+size)/2, y+size)
+	y += size + 20
+
+	if err := addLines("Scan to donate", 48, true, posterInk, 1); err != nil {
+		return nil, err
+	}
+	if err := addLines("with Phantom, Backpack or Solflare", 32, false, posterMuted, 1); err != nil {
+		return nil, err
+	}
+	y += 40
+
+	progress := lamportsToSOL(p.Raised) + " SOL raised"
+	if p.Goal > 0 {
+		progress += " of " + lamportsToSOL(p.Goal) + " SOL"
+	}
+	if err := addLines(progress, 52, true, posterInk, 1); err != nil {
+		return nil, err
+	}
+	if p.Goal > 0 {
+		y += 30
+		l.bar = image.Rect(posterMargin, y, posterWidth-posterMargin, y+28)
+		l.barFill = min(float64(p.Raised)/float64(p.Goal), 1)
+	}
+
+	footer := fmt.Sprintf("%s · as of %s", p.Address, p.AsOf.In(displayLocation).Format("2 Jan 2006"))
+	l.texts = append(l.texts, posterText{text: footer, size: 22, color: posterMuted, y: posterHeight - posterMargin/2})
+	return l, nil
+}
+
+// moduleSize is the side of one QR module in pixels
+func (l *posterLayout) moduleSize() int {
+	return l.qrRect.Dx() / len(l.qr)
+}
+
+// WriteSVG renders the poster as a scalable SVG
+func (p Poster) WriteSVG(w io.Writer) error {
+	fonts, err := newPosterFonts()
+	if err != nil {
+		return err
+	}
+	l, err := p.layout(fonts)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", posterWidth, posterHeight, posterWidth, posterHeight)
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="#fff"/>`+"\n")
+	for _, t := range l.texts {
+		weight := "normal"
+		if t.bold {
+			weight = "bold"
+		}
+		fmt.Fprintf(out, `<text x="%d" y="%d" font-family="Go, Helvetica, Arial, sans-serif" font-size="%g" font-weight="%s" fill="%s" text-anchor="middle">%s</text>`+"\n",
+			posterWidth/2, t.y, t.size, weight, hexColor(t.color), html.EscapeString(t.text))
+	}
+
+	module := l.moduleSize()
+	fmt.Fprintf(out, `<path fill="%s" shape-rendering="crispEdges" d="`, hexColor(posterInk))
+	for row, modules := range l.qr {
+		for col, dark := range modules {
+			if dark {
+				fmt.Fprintf(out, "M%d %dh%dv%dh-%dz", l.qrRect.Min.X+col*module, l.qrRect.Min.Y+row*module, module, module, module)
+			}
+		}
+	}
+	fmt.Fprintf(out, "\"/>\n")
+
+	if !l.bar.Empty() {
+		radius := l.bar.Dy() / 2
+		fmt.Fprintf(out, `<rect x="%d" y="%d" width="%d" height="%d" rx="%d" fill="%s"/>`+"\n", l.bar.Min.X, l.bar.Min.Y, l.bar.Dx(), l.bar.Dy(), radius, hexColor(posterTrack))
+		if fill := int(float64(l.bar.Dx()) * l.barFill); fill > 0 {
+			fmt.Fprintf(out, `<rect x="%d" y="%d" width="%d" height="%d" rx="%d" fill="%s"/>`+"\n", l.bar.Min.X, l.bar.Min.Y, fill, l.bar.Dy(), radius, hexColor(posterAccent))
+		}
+	}
+	fmt.Fprintf(out, "</svg>\n")
+	return out.Flush()
+}
+
+// WritePNG renders the poster as a PNG image
+func (p Poster) WritePNG(w io.Writer) error {
+	fonts, err := newPosterFonts()
+	if err != nil {
+		return err
+	}
+	l, err := p.layout(fonts)
+	if err != nil {
+		return err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, posterWidth, posterHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for _, t := range l.texts {
+		face, err := fonts.face(t.size, t.bold)
+		if err != nil {
+			return err
+		}
+		drawer := &font.Drawer{Dst: img, Src: image.NewUniform(t.color), Face: face}
+		width := drawer.MeasureString(t.text).Ceil()
+		drawer.Dot = fixed.P((posterWidth-width)/2, t.y)
+		drawer.DrawString(t.text)
+	}
+
+	module := l.moduleSize()
+	ink := image.NewUniform(posterInk)
+	for row, modules := range l.qr {
+		for col, dark := range modules {
+			if dark {
+				corner := l.qrRect.Min.Add(image.Pt(col*module, row*module))
+				draw.Draw(img, image.Rectangle{Min: corner, Max: corner.Add(image.Pt(module, module))}, ink, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	if !l.bar.Empty() {
+		draw.Draw(img, l.bar, image.NewUniform(posterTrack), image.Point{}, draw.Src)
+		filled := l.bar
+		filled.Max.X = filled.Min.X + int(float64(l.bar.Dx())*l.barFill)
+		draw.Draw(img, filled, image.NewUniform(posterAccent), image.Point{}, draw.Src)
+	}
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return nil
+}
+
+// hexColor formats a colour for SVG attributes
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// CreatePoster renders a printable poster for a campaign to an SVG or PNG file, chosen by extension.
+// With server, its QR code is a transaction request for amount lamports to that `serve` instance.
+func (app *SolanaDApp) CreatePoster(campaignAddress solana.PublicKey, goal uint64, server string, amount uint64, path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".svg" && ext != ".png" {
+		return fmt.Errorf("poster file must end in .svg or .png")
+	}
+
+	campaign, err := app.FetchCampaign(campaignAddress)
+	if err != nil {
+		return err
+	}
+
+	// One reference per poster lets its donations be told apart from other links
+	reference := solana.NewWallet().PublicKey()
+	poster := Poster{
+		Name:        campaign.Name,
+		Description: campaign.Description,
+		Address:     campaignAddress,
+		Raised:      campaign.AmountDonated,
+		Goal:        goal,
+		Link:        donationLink(server, campaignAddress, campaign.Name, amount, reference),
+		AsOf:        time.Now(),
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create poster file: %w", err)
+	}
+	if ext == ".svg" {
+		err = poster.WriteSVG(file)
+	} else {
+		err = poster.WritePNG(file)
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write poster file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🖼️  Poster for '%s' saved to %s\n", campaign.Name, path)
+	fmt.Printf("🔗 %s\n", poster.Link)
+	fmt.Printf("ℹ️  Donations from this poster carry the reference %s\n", reference)
+	return nil
+}
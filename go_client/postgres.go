@@ -0,0 +1,535 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	_ "github.com/lib/pq"
+)
+
+// postgresSchema creates the tables on first use. Records are kept as JSON, like in store.json,
+// with the columns queries filter on alongside.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS crowdfunding_snapshots (
+	id BIGSERIAL PRIMARY KEY,
+	campaign TEXT NOT NULL,
+	taken_at TIMESTAMPTZ NOT NULL,
+	data JSONB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS crowdfunding_snapshots_campaign_time ON crowdfunding_snapshots (campaign, taken_at);
+CREATE TABLE IF NOT EXISTS crowdfunding_alerts (
+	name TEXT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_auto_withdrawals (
+	id BIGSERIAL PRIMARY KEY,
+	data JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_schedules (
+	id BIGSERIAL PRIMARY KEY,
+	data JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_schedule_runs (
+	id BIGSERIAL PRIMARY KEY,
+	data JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_retry_queue (
+	id BIGSERIAL PRIMARY KEY,
+	data JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_mutes (
+	campaign TEXT NOT NULL,
+	donor TEXT NOT NULL,
+	PRIMARY KEY (campaign, donor)
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_settings (
+	key TEXT PRIMARY KEY,
+	value JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_threads (
+	key TEXT PRIMARY KEY,
+	id TEXT NOT NULL,
+	updated TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_ledger (
+	signature TEXT PRIMARY KEY,
+	slot BIGINT NOT NULL,
+	data JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS crowdfunding_valuations (
+	signature TEXT NOT NULL,
+	currency TEXT NOT NULL,
+	data JSONB NOT NULL,
+	PRIMARY KEY (signature, currency)
+);
+`
+
+// PostgresStore keeps daemon state and history in PostgreSQL, shared by every daemon and command
+// pointing at the same database
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// OpenPostgresStore connects to the database and creates the tables if needed
+func OpenPostgresStore(url string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL store: %w", err)
+	}
+	if _, err := db.Exec(postgresSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to prepare PostgreSQL store: %w", err)
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// queryJSON calls fn with the id and JSON data of every row the query selects
+func (s *PostgresStore) queryJSON(fn func(id int64, data []byte) error, query string, args ...interface{}) error {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return err
+		}
+		if err := fn(id, data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// logStoreError reports a failed read; readers fall back to empty results like LocalStore does
+func logStoreError(err error) {
+	if err != nil {
+		log.Printf("PostgreSQL store: %v", err)
+	}
+}
+
+// AddSnapshots appends snapshots and persists them
+func (s *PostgresStore) AddSnapshots(snapshots ...Snapshot) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, snapshot := range snapshots {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO crowdfunding_snapshots (campaign, taken_at, data) VALUES ($1, $2, $3)`,
+			snapshot.Campaign.String(), snapshot.Time, string(data)); err != nil {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Snapshots returns a campaign's snapshots taken in [from, to], oldest first
+func (s *PostgresStore) Snapshots(campaign solana.PublicKey, from, to time.Time) []Snapshot {
+	var snapshots []Snapshot
+	err := s.queryJSON(func(_ int64, data []byte) error {
+		var snapshot Snapshot
+		err := json.Unmarshal(data, &snapshot)
+		snapshots = append(snapshots, snapshot)
+		return err
+	}, `SELECT id, data FROM crowdfunding_snapshots WHERE campaign = $1 AND taken_at BETWEEN $2 AND $3 ORDER BY taken_at`,
+		campaign.String(), from, to)
+	logStoreError(err)
+	return snapshots
+}
+
+// SnapshotAt returns the latest snapshot of a campaign taken at or before t
+func (s *PostgresStore) SnapshotAt(campaign solana.PublicKey, t time.Time) (Snapshot, bool) {
+	var snapshot Snapshot
+	found := false
+	err := s.queryJSON(func(_ int64, data []byte) error {
+		found = true
+		return json.Unmarshal(data, &snapshot)
+	}, `SELECT id, data FROM crowdfunding_snapshots WHERE campaign = $1 AND taken_at <= $2 ORDER BY taken_at DESC LIMIT 1`,
+		campaign.String(), t)
+	logStoreError(err)
+	return snapshot, found && err == nil
+}
+
+// SnapshotCampaigns lists the campaigns that have snapshots
+func (s *PostgresStore) SnapshotCampaigns() []solana.PublicKey {
+	rows, err := s.db.Query(`SELECT campaign FROM crowdfunding_snapshots GROUP BY campaign ORDER BY MIN(taken_at)`)
+	if err != nil {
+		logStoreError(err)
+		return nil
+	}
+	defer rows.Close()
+
+	var campaigns []solana.PublicKey
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			logStoreError(err)
+			return campaigns
+		}
+		if campaign, err := solana.PublicKeyFromBase58(address); err == nil {
+			campaigns = append(campaigns, campaign)
+		}
+	}
+	return campaigns
+}
+
+// AlertFiring reports whether the named alert was firing at its last evaluation
+func (s *PostgresStore) AlertFiring(name string) bool {
+	var firing bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM crowdfunding_alerts WHERE name = $1)`, name).Scan(&firing)
+	logStoreError(err)
+	return firing
+}
+
+// SetAlertFiring records the named alert's state
+func (s *PostgresStore) SetAlertFiring(name string, firing bool) error {
+	var err error
+	if firing {
+		_, err = s.db.Exec(`INSERT INTO crowdfunding_alerts (name) VALUES ($1) ON CONFLICT DO NOTHING`, name)
+	} else {
+		_, err = s.db.Exec(`DELETE FROM crowdfunding_alerts WHERE name = $1`, name)
+	}
+	return err
+}
+
+// AddAutoWithdrawal appends an auto-withdraw audit record
+func (s *PostgresStore) AddAutoWithdrawal(record AutoWithdrawal) error {
+	return s.insertJSON(`INSERT INTO crowdfunding_auto_withdrawals (data) VALUES ($1)`, record)
+}
+
+// insertJSON runs an insert whose only argument is the record as JSON
+func (s *PostgresStore) insertJSON(query string, record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(query, string(data))
+	return err
+}
+
+// Schedules returns the persisted schedules
+func (s *PostgresStore) Schedules() []Schedule {
+	var schedules []Schedule
+	err := s.queryJSON(func(id int64, data []byte) error {
+		var schedule Schedule
+		err := json.Unmarshal(data, &schedule)
+		schedule.ID = strconv.FormatInt(id, 10)
+		schedules = append(schedules, schedule)
+		return err
+	}, `SELECT id, data FROM crowdfunding_schedules ORDER BY id`)
+	logStoreError(err)
+	return schedules
+}
+
+// AddSchedule persists a new schedule, assigning its ID
+func (s *PostgresStore) AddSchedule(schedule Schedule) (Schedule, error) {
+	schedule.ID = ""
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return schedule, err
+	}
+	var id int64
+	if err := s.db.QueryRow(`INSERT INTO crowdfunding_schedules (data) VALUES ($1) RETURNING id`, string(data)).Scan(&id); err != nil {
+		return schedule, fmt.Errorf("failed to save schedule: %w", err)
+	}
+	schedule.ID = strconv.FormatInt(id, 10)
+	return schedule, nil
+}
+
+// RemoveSchedule deletes a schedule by ID
+func (s *PostgresStore) RemoveSchedule(id string) error {
+	return s.deleteByID(`DELETE FROM crowdfunding_schedules WHERE id = $1`, id, "schedule")
+}
+
+// deleteByID runs a delete by numeric ID, failing when nothing matched
+func (s *PostgresStore) deleteByID(query, id, what string) error {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("no %s with ID %s", what, id)
+	}
+	result, err := s.db.Exec(query, n)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("no %s with ID %s", what, id)
+	}
+	return nil
+}
+
+// ClaimScheduleRun marks a schedule as run at now if it still last ran at lastRun, so that
+// only one daemon runs it
+func (s *PostgresStore) ClaimScheduleRun(id string, lastRun, now time.Time) (bool, error) {
+	return s.compareAndSet(`UPDATE crowdfunding_schedules SET data = jsonb_set(data, '{lastRun}', $3::jsonb)
+		WHERE id = $1 AND data->'lastRun' = $2::jsonb`, id, lastRun, now)
+}
+
+// compareAndSet runs an update of one JSON time field by numeric ID and reports whether it applied
+func (s *PostgresStore) compareAndSet(query, id string, old, new time.Time) (bool, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	oldJSON, _ := json.Marshal(old)
+	newJSON, _ := json.Marshal(new)
+	result, err := s.db.Exec(query, n, string(oldJSON), string(newJSON))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// RecordScheduleRun appends to the execution history and marks the schedule as run
+func (s *PostgresStore) RecordScheduleRun(run ScheduleRun) error {
+	if n, err := strconv.ParseInt(run.ScheduleID, 10, 64); err == nil {
+		lastRun, _ := json.Marshal(run.Time)
+		if _, err := s.db.Exec(`UPDATE crowdfunding_schedules SET data = jsonb_set(data, '{lastRun}', $2::jsonb) WHERE id = $1`, n, string(lastRun)); err != nil {
+			return err
+		}
+	}
+	return s.insertJSON(`INSERT INTO crowdfunding_schedule_runs (data) VALUES ($1)`, run)
+}
+
+// ScheduleRuns returns the execution history, oldest first
+func (s *PostgresStore) ScheduleRuns() []ScheduleRun {
+	var runs []ScheduleRun
+	err := s.queryJSON(func(_ int64, data []byte) error {
+		var run ScheduleRun
+		err := json.Unmarshal(data, &run)
+		runs = append(runs, run)
+		return err
+	}, `SELECT id, data FROM crowdfunding_schedule_runs ORDER BY id`)
+	logStoreError(err)
+	return runs
+}
+
+// QueuedTransactions returns the retry queue, including finished entries
+func (s *PostgresStore) QueuedTransactions() []QueuedTransaction {
+	var queue []QueuedTransaction
+	err := s.queryJSON(func(id int64, data []byte) error {
+		var queued QueuedTransaction
+		err := json.Unmarshal(data, &queued)
+		queued.ID = strconv.FormatInt(id, 10)
+		queue = append(queue, queued)
+		return err
+	}, `SELECT id, data FROM crowdfunding_retry_queue ORDER BY id`)
+	logStoreError(err)
+	return queue
+}
+
+// EnqueueTransaction persists a transaction to retry, assigning its ID
+func (s *PostgresStore) EnqueueTransaction(queued QueuedTransaction) (QueuedTransaction, error) {
+	queued.ID = ""
+	data, err := json.Marshal(queued)
+	if err != nil {
+		return queued, err
+	}
+	var id int64
+	if err := s.db.QueryRow(`INSERT INTO crowdfunding_retry_queue (data) VALUES ($1) RETURNING id`, string(data)).Scan(&id); err != nil {
+		return queued, fmt.Errorf("failed to queue transaction: %w", err)
+	}
+	queued.ID = strconv.FormatInt(id, 10)
+	return queued, nil
+}
+
+// ClaimQueuedTransaction postpones a pending entry's next attempt to until if it is still
+// nextAttempt, so that only one daemon retries it
+func (s *PostgresStore) ClaimQueuedTransaction(id string, nextAttempt, until time.Time) (bool, error) {
+	return s.compareAndSet(`UPDATE crowdfunding_retry_queue SET data = jsonb_set(data, '{nextAttempt}', $3::jsonb)
+		WHERE id = $1 AND data->'nextAttempt' = $2::jsonb AND data->>'status' = '`+RetryPending+`'`, id, nextAttempt, until)
+}
+
+// UpdateQueuedTransaction replaces a retry queue entry by ID
+func (s *PostgresStore) UpdateQueuedTransaction(queued QueuedTransaction) error {
+	n, err := strconv.ParseInt(queued.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("no queued transaction with ID %s", queued.ID)
+	}
+	data, err := json.Marshal(queued)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.Exec(`UPDATE crowdfunding_retry_queue SET data = $2 WHERE id = $1`, n, string(data))
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("no queued transaction with ID %s", queued.ID)
+	}
+	return nil
+}
+
+// RemoveQueuedTransaction deletes a retry queue entry by ID
+func (s *PostgresStore) RemoveQueuedTransaction(id string) error {
+	return s.deleteByID(`DELETE FROM crowdfunding_retry_queue WHERE id = $1`, id, "queued transaction")
+}
+
+// Mutes returns the muted donors of every campaign
+func (s *PostgresStore) Mutes() map[string][]string {
+	mutes := map[string][]string{}
+	rows, err := s.db.Query(`SELECT campaign, donor FROM crowdfunding_mutes ORDER BY campaign, donor`)
+	if err != nil {
+		logStoreError(err)
+		return mutes
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var campaign, donor string
+		if err := rows.Scan(&campaign, &donor); err != nil {
+			logStoreError(err)
+			return mutes
+		}
+		mutes[campaign] = append(mutes[campaign], donor)
+	}
+	return mutes
+}
+
+// SetMuted mutes or unmutes a donor's comments on a campaign
+func (s *PostgresStore) SetMuted(campaign, donor string, muted bool) error {
+	var err error
+	if muted {
+		_, err = s.db.Exec(`INSERT INTO crowdfunding_mutes (campaign, donor) VALUES ($1, $2) ON CONFLICT DO NOTHING`, campaign, donor)
+	} else {
+		_, err = s.db.Exec(`DELETE FROM crowdfunding_mutes WHERE campaign = $1 AND donor = $2`, campaign, donor)
+	}
+	return err
+}
+
+// DigestSentAt returns when a daemon last sent the email digest, or the zero time
+func (s *PostgresStore) DigestSentAt() time.Time {
+	var data []byte
+	var sentAt time.Time
+	err := s.db.QueryRow(`SELECT value FROM crowdfunding_settings WHERE key = 'digestSentAt'`).Scan(&data)
+	if err == sql.ErrNoRows {
+		return sentAt
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &sentAt)
+	}
+	logStoreError(err)
+	return sentAt
+}
+
+// SetDigestSentAt records when the email digest was sent
+func (s *PostgresStore) SetDigestSentAt(t time.Time) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO crowdfunding_settings (key, value) VALUES ('digestSentAt', $1)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value`, string(data))
+	return err
+}
+
+// NotificationThread returns the message a notification thread started with
+func (s *PostgresStore) NotificationThread(key string) (string, bool) {
+	var id string
+	err := s.db.QueryRow(`SELECT id FROM crowdfunding_threads WHERE key = $1`, key).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", false
+	}
+	logStoreError(err)
+	return id, err == nil
+}
+
+// SetNotificationThread remembers the message a notification thread started with, forgetting idle threads
+func (s *PostgresStore) SetNotificationThread(key, id string) error {
+	now := time.Now().UTC()
+	if _, err := s.db.Exec(`DELETE FROM crowdfunding_threads WHERE updated < $1`, now.Add(-threadRetention)); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`INSERT INTO crowdfunding_threads (key, id, updated) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET id = EXCLUDED.id, updated = EXCLUDED.updated`, key, id, now)
+	return err
+}
+
+// Valuation returns the stored valuation of a transaction in a currency
+func (s *PostgresStore) Valuation(signature, currency string) (Valuation, bool) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM crowdfunding_valuations WHERE signature = $1 AND currency = $2`, signature, currency).Scan(&data)
+	if err == sql.ErrNoRows {
+		return Valuation{}, false
+	}
+	if err != nil {
+		logStoreError(err)
+		return Valuation{}, false
+	}
+	var valuation Valuation
+	if err := json.Unmarshal(data, &valuation); err != nil {
+		logStoreError(err)
+		return Valuation{}, false
+	}
+	return valuation, true
+}
+
+// AddValuation stores a transaction's valuation, keeping the first one made
+func (s *PostgresStore) AddValuation(valuation Valuation) error {
+	data, err := json.Marshal(valuation)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO crowdfunding_valuations (signature, currency, data) VALUES ($1, $2, $3)
+		ON CONFLICT (signature, currency) DO NOTHING`, valuation.Signature, valuation.Currency, string(data))
+	return err
+}
+
+// LedgerEntries returns the ledger in slot order
+func (s *PostgresStore) LedgerEntries() []LedgerEntry {
+	var entries []LedgerEntry
+	err := s.queryJSON(func(_ int64, data []byte) error {
+		var entry LedgerEntry
+		err := json.Unmarshal(data, &entry)
+		entries = append(entries, entry)
+		return err
+	}, `SELECT slot, data FROM crowdfunding_ledger ORDER BY slot, signature`)
+	logStoreError(err)
+	return entries
+}
+
+// AddLedgerEntries adds entries for transactions not in the ledger yet
+func (s *PostgresStore) AddLedgerEntries(entries ...LedgerEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO crowdfunding_ledger (signature, slot, data) VALUES ($1, $2, $3)
+			ON CONFLICT (signature) DO NOTHING`, entry.Signature, int64(entry.Slot), string(data)); err != nil {
+			return fmt.Errorf("failed to save ledger entry: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveLedgerEntries removes the entries of the transactions
+func (s *PostgresStore) RemoveLedgerEntries(signatures ...string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, signature := range signatures {
+		if _, err := tx.Exec(`DELETE FROM crowdfunding_ledger WHERE signature = $1`, signature); err != nil {
+			return fmt.Errorf("failed to remove ledger entry: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// pricesFile caches historical SOL prices, which never change once the day is over
+const pricesFile = "prices.json"
+
+// coingeckoAPI serves daily SOL prices; a demo key from coingeckoKeyEnv raises its rate limit
+const (
+	coingeckoAPI    = "https://api.coingecko.com/api/v3"
+	coingeckoKeyEnv = "COINGECKO_API_KEY"
+)
+
+// defaultCurrency is the fiat currency reports value SOL in unless prices.currency says otherwise
+const defaultCurrency = "usd"
+
+// PriceConfig selects where historical SOL prices come from and the fiat currency reports use
+type PriceConfig struct {
+	Provider string `json:"provider,omitempty"` // coingecko (default) or csv
+	Currency string `json:"currency,omitempty"` // e.g. "eur", default "usd"
+	URL      string `json:"url,omitempty"`      // CoinGecko API base, e.g. the Pro API's
+	APIKey   string `json:"apiKey,omitempty"`   // CoinGecko key, also read from the environment
+	File     string `json:"file,omitempty"`     // csv provider: time,price rows in the configured currency
+}
+
+// PriceProvider looks up the price of one SOL in a fiat currency at a past time
+type PriceProvider interface {
+	PriceAt(ctx context.Context, t time.Time, currency string) (float64, error)
+	// Source names where prices come from, for the records that use them
+	Source() string
+}
+
+// priceProviderFactories builds each provider from its config
+var priceProviderFactories = map[string]func(*PriceConfig) (PriceProvider, error){
+	"coingecko": newCoinGeckoPrices,
+	"csv":       newCSVPrices,
+}
+
+// currency is the configured fiat currency, lower case
+func (c *PriceConfig) currency() string {
+	if c == nil || c.Currency == "" {
+		return defaultCurrency
+	}
+	return strings.ToLower(c.Currency)
+}
+
+// NewPriceProvider builds the configured provider, CoinGecko by default
+func NewPriceProvider(config *PriceConfig) (PriceProvider, error) {
+	if config == nil {
+		config = &PriceConfig{}
+	}
+	name := config.Provider
+	if name == "" {
+		name = "coingecko"
+	}
+	factory, ok := priceProviderFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown price provider %q", name)
+	}
+	provider, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("%s prices: %w", name, err)
+	}
+	return provider, nil
+}
+
+// Valuation is the price of SOL at a transaction's block time. Once made it is kept in the store,
+// so every later report and export values the transaction the same way.
+type Valuation struct {
+	Signature string    `json:"signature"`
+	Currency  string    `json:"currency"`
+	Time      time.Time `json:"time"`  // block time the price is for
+	Price     float64   `json:"price"` // of one SOL
+	Source    string    `json:"source"`
+	Recorded  time.Time `json:"recorded"`
+}
+
+// Of is the value of an amount of lamports at the valuation's price
+func (v Valuation) Of(lamports uint64) float64 {
+	return float64(lamports) / float64(solana.LAMPORTS_PER_SOL) * v.Price
+}
+
+// Valuer values transactions in the configured currency, reusing valuations already in the store
+type Valuer struct {
+	provider PriceProvider
+	store    Store
+	currency string
+}
+
+// NewValuer builds the configured price provider over the store
+func NewValuer(config *PriceConfig, store Store) (*Valuer, error) {
+	provider, err := NewPriceProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Valuer{provider: provider, store: store, currency: config.currency()}, nil
+}
+
+// Currency is the fiat currency values are in
+func (v *Valuer) Currency() string {
+	return v.currency
+}
+
+// Value returns the price of SOL at a transaction's block time, looking it up and storing it
+// the first time
+func (v *Valuer) Value(ctx context.Context, signature string, blockTime time.Time) (Valuation, error) {
+	if stored, ok := v.store.Valuation(signature, v.currency); ok {
+		return stored, nil
+	}
+	price, err := v.provider.PriceAt(ctx, blockTime, v.currency)
+	if err != nil {
+		return Valuation{}, err
+	}
+	valuation := Valuation{
+		Signature: signature,
+		Currency:  v.currency,
+		Time:      blockTime.UTC(),
+		Price:     price,
+		Source:    v.provider.Source(),
+		Recorded:  time.Now().UTC(),
+	}
+	if err := v.store.AddValuation(valuation); err != nil {
+		log.Printf("Failed to store valuation of %s: %v", signature, err)
+	}
+	return valuation, nil
+}
+
+// formatFiat formats an amount of a fiat currency with cents
+func formatFiat(v float64, currency string) string {
+	amount := strconv.FormatFloat(v, 'f', 2, 64)
+	if currency == "usd" {
+		return "$" + amount
+	}
+	return amount + " " + strings.ToUpper(currency)
+}
+
+// coingeckoPrices looks up daily prices from CoinGecko, caching past days in pricesFile
+type coingeckoPrices struct {
+	mu     sync.Mutex
+	url    string
+	key    string
+	client *http.Client
+	days   map[string]map[string]float64 // currency -> UTC date -> price of one SOL
+}
+
+// newCoinGeckoPrices loads the price cache, treating a missing or outdated file as empty
+func newCoinGeckoPrices(config *PriceConfig) (PriceProvider, error) {
+	p := &coingeckoPrices{url: coingeckoAPI, key: config.APIKey, client: &http.Client{Timeout: 15 * time.Second}, days: map[string]map[string]float64{}}
+	if config.URL != "" {
+		p.url = strings.TrimSuffix(config.URL, "/")
+	}
+	if p.key == "" {
+		p.key = os.Getenv(coingeckoKeyEnv)
+	}
+	data, err := os.ReadFile(pricesFile)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", pricesFile, err)
+	}
+	if err := json.Unmarshal(data, &p.days); err != nil {
+		log.Printf("Ignoring the price cache %s: %v", pricesFile, err)
+		p.days = map[string]map[string]float64{}
+	}
+	return p, nil
+}
+
+// Source implements PriceProvider
+func (p *coingeckoPrices) Source() string {
+	return "coingecko daily"
+}
+
+// PriceAt implements PriceProvider with the price of the UTC day of t, at 00:00 UTC
+func (p *coingeckoPrices) PriceAt(ctx context.Context, t time.Time, currency string) (float64, error) {
+	day := t.UTC().Format("2006-01-02")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if price, ok := p.days[currency][day]; ok {
+		return price, nil
+	}
+
+	price, err := p.fetch(ctx, t.UTC(), currency)
+	if err != nil {
+		return 0, err
+	}
+	// Today's price is still moving, so only finished days are cached
+	if day != time.Now().UTC().Format("2006-01-02") {
+		if p.days[currency] == nil {
+			p.days[currency] = map[string]float64{}
+		}
+		p.days[currency][day] = price
+		data, err := json.MarshalIndent(p.days, "", "  ")
+		if err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(pricesFile, data, 0644); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", pricesFile, err)
+		}
+	}
+	return price, nil
+}
+
+// fetch asks CoinGecko for the price of a day, waiting out its rate limit a few times
+func (p *coingeckoPrices) fetch(ctx context.Context, day time.Time, currency string) (float64, error) {
+	url := fmt.Sprintf("%s/coins/solana/history?date=%s&localization=false", p.url, day.Format("02-01-2006"))
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return 0, err
+		}
+		if p.key != "" {
+			if strings.Contains(p.url, "pro-api") {
+				req.Header.Set("x-cg-pro-api-key", p.key)
+			} else {
+				req.Header.Set("x-cg-demo-api-key", p.key)
+			}
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch SOL price for %s: %w", day.Format("2006-01-02"), err)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < 3 {
+			resp.Body.Close()
+			wait := 30 * time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		var decoded struct {
+			MarketData struct {
+				CurrentPrice map[string]float64 `json:"current_price"`
+			} `json:"market_data"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return 0, fmt.Errorf("failed to fetch SOL price for %s: %s", day.Format("2006-01-02"), resp.Status)
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&decoded)
+		resp.Body.Close()
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch SOL price for %s: %w", day.Format("2006-01-02"), err)
+		}
+		price, ok := decoded.MarketData.CurrentPrice[currency]
+		if !ok {
+			return 0, fmt.Errorf("no SOL/%s price for %s", strings.ToUpper(currency), day.Format("2006-01-02"))
+		}
+		return price, nil
+	}
+}
+
+// csvPricePoint is one row of a csv price file
+type csvPricePoint struct {
+	time  time.Time
+	price float64
+}
+
+// csvPrices serves prices from a file of time,price rows, such as an exchange's export, using the
+// latest row at or before the time asked for
+type csvPrices struct {
+	path     string
+	currency string
+	points   []csvPricePoint
+}
+
+// csvPriceMaxAge is how stale the latest row before a time may be
+const csvPriceMaxAge = 24 * time.Hour
+
+// newCSVPrices reads the price file. Times are RFC 3339 or dates, taken as 00:00 UTC.
+func newCSVPrices(config *PriceConfig) (PriceProvider, error) {
+	if config.File == "" {
+		return nil, fmt.Errorf("prices.file is required")
+	}
+	rows, err := readCSV(config.File, "time", "price")
+	if err != nil {
+		return nil, err
+	}
+	p := &csvPrices{path: config.File, currency: config.currency()}
+	for _, row := range rows {
+		t, err := time.Parse(time.RFC3339, row.fields["time"])
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", row.fields["time"]); err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid time %q", config.File, row.line, row.fields["time"])
+			}
+		}
+		price, err := strconv.ParseFloat(row.fields["price"], 64)
+		if err != nil || price <= 0 {
+			return nil, fmt.Errorf("%s line %d: invalid price %q", config.File, row.line, row.fields["price"])
+		}
+		p.points = append(p.points, csvPricePoint{time: t, price: price})
+	}
+	sort.Slice(p.points, func(i, j int) bool { return p.points[i].time.Before(p.points[j].time) })
+	return p, nil
+}
+
+// Source implements PriceProvider
+func (p *csvPrices) Source() string {
+	return "csv " + p.path
+}
+
+// PriceAt implements PriceProvider
+func (p *csvPrices) PriceAt(ctx context.Context, t time.Time, currency string) (float64, error) {
+	if currency != p.currency {
+		return 0, fmt.Errorf("%s has %s prices, not %s", p.path, strings.ToUpper(p.currency), strings.ToUpper(currency))
+	}
+	i := sort.Search(len(p.points), func(i int) bool { return p.points[i].time.After(t) })
+	if i == 0 || t.Sub(p.points[i-1].time) > csvPriceMaxAge {
+		return 0, fmt.Errorf("%s has no price within a day before %s", p.path, t.UTC().Format(time.RFC3339))
+	}
+	return p.points[i-1].price, nil
+}
+
+// valuationColumns formats the price, value and price source columns of a valued record for CSV,
+// leaving them empty when it could not be valued
+func valuationColumns(valuation *Valuation, lamports uint64) (price, value, source string) {
+	if valuation == nil {
+		return "", "", ""
+	}
+	return strconv.FormatFloat(valuation.Price, 'f', 4, 64), strconv.FormatFloat(valuation.Of(lamports), 'f', 2, 64), valuation.Source
+}
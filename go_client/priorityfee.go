@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+)
+
+// defaultPriorityFeePercentile is the percentile of recent fees paid when none is configured
+const defaultPriorityFeePercentile = 75
+
+// PriorityFeeConfig sets the compute unit price of transactions from the fees recently paid to
+// write the same accounts, in config.json. Without it no priority fee is paid.
+type PriorityFeeConfig struct {
+	Percentile   int    `json:"percentile,omitempty"`   // of recent fees, 0-100, default 75
+	Min          uint64 `json:"min,omitempty"`          // micro-lamports per compute unit
+	Max          uint64 `json:"max,omitempty"`          // micro-lamports per compute unit; 0 for no ceiling
+	ComputeUnits uint32 `json:"computeUnits,omitempty"` // compute unit limit, which with the price sets the fee; default the runtime's
+}
+
+// percentileFee returns the fee below which the given percentage of fees lie
+func percentileFee(fees []uint64, percentile int) uint64 {
+	if len(fees) == 0 {
+		return 0
+	}
+	sorted := append([]uint64(nil), fees...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile = max(0, min(percentile, 100))
+	index := (len(sorted) - 1) * percentile / 100
+	return sorted[index]
+}
+
+// writableAccounts lists the accounts the instructions write, whose recent fees matter
+func writableAccounts(instructions []solana.Instruction) solana.PublicKeySlice {
+	var accounts solana.PublicKeySlice
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts() {
+			if account.IsWritable && !accounts.Has(account.PublicKey) {
+				accounts = append(accounts, account.PublicKey)
+			}
+		}
+	}
+	return accounts
+}
+
+// priorityFee picks the compute unit price for a transaction with these instructions
+func (app *SolanaDApp) priorityFee(ctx context.Context, instructions []solana.Instruction) (uint64, error) {
+	config := app.config.PriorityFee
+	percentile := config.Percentile
+	if percentile == 0 {
+		percentile = defaultPriorityFeePercentile
+	}
+
+	recent, err := app.client.GetRecentPrioritizationFees(ctx, writableAccounts(instructions))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recent prioritization fees: %w", err)
+	}
+	fees := make([]uint64, 0, len(recent))
+	for _, fee := range recent {
+		fees = append(fees, fee.PrioritizationFee)
+	}
+
+	price := max(percentileFee(fees, percentile), config.Min)
+	if config.Max > 0 {
+		price = min(price, config.Max)
+	}
+	return price, nil
+}
+
+// withPriorityFee prepends the compute budget instructions when a priority fee is configured.
+// If recent fees can't be fetched, the configured minimum is paid.
+func (app *SolanaDApp) withPriorityFee(ctx context.Context, instructions []solana.Instruction) []solana.Instruction {
+	config := app.config.PriorityFee
+	if config == nil {
+		return instructions
+	}
+
+	price, err := app.priorityFee(ctx, instructions)
+	if err != nil {
+		log.Printf("Priority fee: %v; paying the minimum of %d micro-lamports", err, config.Min)
+		price = config.Min
+	}
+
+	var budget []solana.Instruction
+	if config.ComputeUnits > 0 {
+		budget = append(budget, computebudget.NewSetComputeUnitLimitInstruction(config.ComputeUnits).Build())
+	}
+	if price > 0 {
+		budget = append(budget, computebudget.NewSetComputeUnitPriceInstruction(price).Build())
+	}
+	return append(budget, instructions...)
+}
+
+// Base fee and the runtime's default compute budget, which price a transaction
+const (
+	lamportsPerSignature       = 5000
+	defaultInstructionUnits    = 200_000
+	maxTransactionComputeUnits = 1_400_000
+	microLamportsPerLamport    = 1_000_000
+)
+
+// estimateFee returns the fee of a transaction of these instructions: the base fee of each
+// signature plus the priority fee withPriorityFee would add at the current price
+func (app *SolanaDApp) estimateFee(ctx context.Context, instructions []solana.Instruction) uint64 {
+	signers := solana.PublicKeySlice{app.payer()}
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts() {
+			if account.IsSigner && !signers.Has(account.PublicKey) {
+				signers = append(signers, account.PublicKey)
+			}
+		}
+	}
+	fee := uint64(len(signers)) * lamportsPerSignature
+
+	config := app.config.PriorityFee
+	if config == nil {
+		return fee
+	}
+	price, err := app.priorityFee(ctx, instructions)
+	if err != nil {
+		price = config.Min
+	}
+	units := uint64(config.ComputeUnits)
+	if units == 0 {
+		units = min(uint64(len(instructions))*defaultInstructionUnits, maxTransactionComputeUnits)
+	}
+	return fee + (price*units+microLamportsPerLamport-1)/microLamportsPerLamport
+}
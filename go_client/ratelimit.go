@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// apiKeyHeader carries the API key of a client with its own rate limit
+const apiKeyHeader = "X-API-Key"
+
+// RateLimitConfig sets the API server's rate limits beyond the per-IP one of -rate and -burst
+type RateLimitConfig struct {
+	Global  *RateLimit       `json:"global,omitempty"`  // all clients together, to protect the RPC node
+	Routes  []RouteRateLimit `json:"routes,omitempty"`  // per client on one route, on top of its overall limit
+	APIKeys []APIKeyLimit    `json:"apiKeys,omitempty"` // clients sending X-API-Key get their own limit instead of the per-IP one
+}
+
+// RateLimit is a token bucket: rate requests per second, bursts of up to burst
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst,omitempty"` // default the rate rounded up
+}
+
+// RouteRateLimit limits each client on one route, e.g. "GET /campaigns/{address}/page"
+type RouteRateLimit struct {
+	Route string `json:"route"` // a pattern of the endpoint table, with or without the method
+	RateLimit
+}
+
+// APIKeyLimit is the rate limit of one API key; a rate of 0 leaves its client unlimited
+type APIKeyLimit struct {
+	Name string `json:"name"` // who the key was given to
+	Key  string `json:"key"`
+	RateLimit
+}
+
+// prepare validates the limits and fills in default bursts
+func (c *RateLimitConfig) prepare() error {
+	if c.Global != nil {
+		if err := c.Global.prepare("global"); err != nil {
+			return err
+		}
+	}
+	for i := range c.Routes {
+		route := &c.Routes[i]
+		if route.Route == "" {
+			return fmt.Errorf("rateLimits.routes: route is required")
+		}
+		if err := route.prepare(route.Route); err != nil {
+			return err
+		}
+	}
+	keys := map[string]bool{}
+	for i := range c.APIKeys {
+		key := &c.APIKeys[i]
+		if key.Name == "" || len(key.Key) < 16 {
+			return fmt.Errorf("rateLimits.apiKeys: each key needs a name and a key of at least 16 characters")
+		}
+		if keys[key.Key] || keys["name:"+key.Name] {
+			return fmt.Errorf("rateLimits.apiKeys: %s is listed twice or shares its key", key.Name)
+		}
+		keys[key.Key], keys["name:"+key.Name] = true, true
+		if key.Rate == 0 {
+			continue
+		}
+		if err := key.prepare(key.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *RateLimit) prepare(name string) error {
+	if l.Rate <= 0 || l.Burst < 0 {
+		return fmt.Errorf("rateLimits: %s needs a positive rate and burst", name)
+	}
+	if l.Burst == 0 {
+		l.Burst = int(math.Ceil(l.Rate))
+	}
+	return nil
+}
+
+// limit is the bucket's refill rate, unlimited for a rate of 0
+func (l RateLimit) limit() rate.Limit {
+	if l.Rate == 0 {
+		return rate.Inf
+	}
+	return rate.Limit(l.Rate)
+}
+
+// rateClient tracks one client's token bucket
+type rateClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// keyedRateLimiter keeps a token bucket per client and forgets idle clients
+type keyedRateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*rateClient
+	lastSweep time.Time
+}
+
+func newKeyedRateLimiter(limit rate.Limit, burst int) *keyedRateLimiter {
+	return &keyedRateLimiter{limit: limit, burst: burst, clients: map[string]*rateClient{}}
+}
+
+// reserve takes a token from the client's bucket, to be handed back if the request is rejected
+func (l *keyedRateLimiter) reserve(key string, now time.Time) *rate.Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > time.Minute {
+		for key, client := range l.clients {
+			if now.Sub(client.lastSeen) > 3*time.Minute {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	client, ok := l.clients[key]
+	if !ok {
+		client = &rateClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+	return client.limiter.ReserveN(now, 1)
+}
+
+// rateLimiters are the buckets a request is checked against
+type rateLimiters struct {
+	perIP   *keyedRateLimiter
+	perKey  map[string]*keyedRateLimiter // by API key
+	keys    []APIKeyLimit
+	routes  map[string]*keyedRateLimiter // by route pattern
+	global  *rate.Limiter
+	matcher *http.ServeMux // finds the pattern of a request's route
+}
+
+func newRateLimiters(opts ServerOptions) *rateLimiters {
+	limiters := &rateLimiters{
+		perIP:  newKeyedRateLimiter(rate.Limit(opts.RateLimit), opts.RateBurst),
+		perKey: map[string]*keyedRateLimiter{},
+		routes: map[string]*keyedRateLimiter{},
+	}
+	if opts.RateLimits == nil {
+		return limiters
+	}
+	if global := opts.RateLimits.Global; global != nil {
+		limiters.global = rate.NewLimiter(global.limit(), global.Burst)
+	}
+	for _, route := range opts.RateLimits.Routes {
+		limiters.routes[route.Route] = newKeyedRateLimiter(route.limit(), route.Burst)
+	}
+	limiters.keys = opts.RateLimits.APIKeys
+	for _, key := range limiters.keys {
+		limiters.perKey[key.Key] = newKeyedRateLimiter(key.limit(), key.Burst)
+	}
+	return limiters
+}
+
+// apiKey finds the configured key a request carries; ok is false for an unknown one
+func (l *rateLimiters) apiKey(r *http.Request) (key *APIKeyLimit, ok bool) {
+	sent := r.Header.Get(apiKeyHeader)
+	if sent == "" {
+		return nil, true
+	}
+	for i := range l.keys {
+		if subtle.ConstantTimeCompare([]byte(l.keys[i].Key), []byte(sent)) == 1 {
+			return &l.keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// routeLimiter returns the limiter of the route a request goes to, if one is configured
+func (l *rateLimiters) routeLimiter(r *http.Request) *keyedRateLimiter {
+	if len(l.routes) == 0 || l.matcher == nil {
+		return nil
+	}
+	_, pattern := l.matcher.Handler(r)
+	if limiter, ok := l.routes[pattern]; ok {
+		return limiter
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return l.routes[path]
+	}
+	return nil
+}
+
+// takeToken takes a token for r from its client's bucket, its route's and the server's, or from none
+// of them; wait is how long until the client may retry, 0 if the token was taken
+func (s *Server) takeToken(r *http.Request, key *APIKeyLimit) (wait time.Duration) {
+	client := "ip:" + s.clientIP(r)
+	clientLimiter := s.limiter.perIP
+	if key != nil {
+		client = "key:" + key.Name
+		clientLimiter = s.limiter.perKey[key.Key]
+	}
+
+	// Every bucket gives a token, or none does: a rejected request doesn't use up the others
+	now := time.Now()
+	reservations := []*rate.Reservation{clientLimiter.reserve(client, now)}
+	if route := s.limiter.routeLimiter(r); route != nil {
+		reservations = append(reservations, route.reserve(client, now))
+	}
+	if s.limiter.global != nil {
+		reservations = append(reservations, s.limiter.global.ReserveN(now, 1))
+	}
+	for _, reservation := range reservations {
+		if !reservation.OK() {
+			wait = max(wait, time.Minute)
+		} else {
+			wait = max(wait, reservation.DelayFrom(now))
+		}
+	}
+	if wait > 0 {
+		for _, reservation := range reservations {
+			reservation.CancelAt(now)
+		}
+	}
+	return wait
+}
+
+// rateLimited rejects requests over their client's budget, their route's budget or the server's
+// overall one, answering when to retry
+func (s *Server) rateLimited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := s.limiter.apiKey(r)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unknown API key", "requestId": requestIDFrom(r.Context())})
+			return
+		}
+		wait := s.takeToken(r, key)
+		if wait == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Retry-After", fmt.Sprint(retryAfterSeconds(wait)))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]string{"error": "rate limit exceeded", "requestId": requestIDFrom(r.Context())})
+	})
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds
+func retryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
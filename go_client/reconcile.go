@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Kinds of discrepancy between the local ledger and the chain
+const (
+	ReconcileMissing   = "missing"   // on chain, not in the ledger
+	ReconcileDuplicate = "duplicate" // in the ledger more than once
+	ReconcileMismatch  = "mismatch"  // in both, recorded differently
+	ReconcileUnknown   = "unknown"   // in the ledger, not a confirmed crowdfunding transaction on chain
+)
+
+// ReconcileIssue is one transaction the local ledger records differently from the chain
+type ReconcileIssue struct {
+	Kind      string
+	Signature string
+	Detail    string
+	entry     *LedgerEntry // what the chain says the entry is, nil when it should not be in the ledger
+}
+
+// Reconciliation compares a campaign's transactions in the local ledger with a fresh scan of the chain
+type Reconciliation struct {
+	Campaign solana.PublicKey
+	Local    int // ledger entries of the campaign
+	OnChain  int // confirmed crowdfunding transactions of the campaign
+	Issues   []ReconcileIssue
+}
+
+// ledgerEntryDiff describes how a recorded entry differs from the one rebuilt from the chain,
+// empty when they agree
+func ledgerEntryDiff(local, chain LedgerEntry) string {
+	var diffs []string
+	if local.Slot != chain.Slot {
+		diffs = append(diffs, fmt.Sprintf("slot %d, on chain %d", local.Slot, chain.Slot))
+	}
+	localNet, chainNet := LedgerBalances([]LedgerEntry{local}), LedgerBalances([]LedgerEntry{chain})
+	accounts := map[string]bool{}
+	for account := range localNet {
+		accounts[account] = true
+	}
+	for account := range chainNet {
+		accounts[account] = true
+	}
+	var sorted []string
+	for account := range accounts {
+		sorted = append(sorted, account)
+	}
+	sort.Strings(sorted)
+	for _, account := range sorted {
+		if localNet[account] != chainNet[account] {
+			diffs = append(diffs, fmt.Sprintf("%s %s SOL, on chain %s SOL", account, signedSOL(localNet[account]), signedSOL(chainNet[account])))
+		}
+	}
+	localAsserted := map[string]uint64{}
+	for _, assertion := range local.Assertions {
+		localAsserted[assertion.Account] = assertion.Balance
+	}
+	for _, assertion := range chain.Assertions {
+		if balance, ok := localAsserted[assertion.Account]; !ok || balance != assertion.Balance {
+			diffs = append(diffs, fmt.Sprintf("%s balance after it %s SOL, on chain %s SOL", assertion.Account, lamportsToSOL(balance), lamportsToSOL(assertion.Balance)))
+		}
+	}
+	return strings.Join(diffs, "; ")
+}
+
+// Reconcile scans the campaign's confirmed transactions on chain and compares them with the
+// local ledger, reporting transactions missing from it, recorded twice, recorded differently,
+// or recorded without being on chain
+func (app *SolanaDApp) Reconcile(ctx context.Context, campaign solana.PublicKey) (*Reconciliation, error) {
+	store, err := OpenStore()
+	if err != nil {
+		return nil, err
+	}
+	account := ledgerCampaign + campaign.String()
+	local := map[string][]LedgerEntry{}
+	var order []string
+	for _, entry := range store.LedgerEntries() {
+		for _, p := range entry.Postings {
+			if p.Account == account {
+				if local[entry.Signature] == nil {
+					order = append(order, entry.Signature)
+				}
+				local[entry.Signature] = append(local[entry.Signature], entry)
+				break
+			}
+		}
+	}
+
+	signatures, err := app.signaturesBetween(campaign, time.Time{}, time.Now().Add(time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signatures of %s: %w", campaign, err)
+	}
+	// Entries the node did not list are fetched on their own, as it may not keep the full history
+	scanned := map[string]bool{}
+	var fetch []solana.Signature
+	for _, sig := range signatures {
+		scanned[sig.Signature.String()] = true
+		fetch = append(fetch, sig.Signature)
+	}
+	for _, signature := range order {
+		if !scanned[signature] {
+			sig, err := solana.SignatureFromBase58(signature)
+			if err != nil {
+				return nil, fmt.Errorf("invalid signature %q in the ledger: %w", signature, err)
+			}
+			fetch = append(fetch, sig)
+		}
+	}
+	chain := make([]*LedgerEntry, len(fetch))
+	err = forEachParallel(len(fetch), defaultWorkers, func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entry, err := app.ledgerEntry(fetch[i])
+		if errors.Is(err, rpc.ErrNotFound) {
+			return nil
+		}
+		chain[i] = entry
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reconciliation{Campaign: campaign, Local: len(order)}
+	onChain := map[string]*LedgerEntry{}
+	for i, entry := range chain {
+		if entry != nil {
+			onChain[fetch[i].String()] = entry
+		}
+	}
+	// Oldest first, like the ledger
+	for i := len(signatures) - 1; i >= 0; i-- {
+		signature := signatures[i].Signature.String()
+		entry := onChain[signature]
+		if entry == nil {
+			continue
+		}
+		r.OnChain++
+		if _, ok := local[signature]; !ok {
+			r.Issues = append(r.Issues, ReconcileIssue{Kind: ReconcileMissing, Signature: signature, Detail: entry.Description, entry: entry})
+		}
+	}
+	for _, signature := range order {
+		entries, entry := local[signature], onChain[signature]
+		switch {
+		case entry == nil:
+			r.Issues = append(r.Issues, ReconcileIssue{Kind: ReconcileUnknown, Signature: signature, Detail: "not found on chain, failed, or not a crowdfunding transaction"})
+		case len(entries) > 1:
+			r.Issues = append(r.Issues, ReconcileIssue{Kind: ReconcileDuplicate, Signature: signature, Detail: fmt.Sprintf("recorded %d times", len(entries)), entry: entry})
+		default:
+			if diff := ledgerEntryDiff(entries[0], *entry); diff != "" {
+				r.Issues = append(r.Issues, ReconcileIssue{Kind: ReconcileMismatch, Signature: signature, Detail: diff, entry: entry})
+			}
+		}
+	}
+	return r, nil
+}
+
+// Repair makes the local ledger agree with the chain: every entry with an issue is replaced by
+// the one rebuilt from the chain, or removed when the transaction is not on chain
+func (r *Reconciliation) Repair() error {
+	store, err := OpenStore()
+	if err != nil {
+		return err
+	}
+	var remove []string
+	var add []LedgerEntry
+	for _, issue := range r.Issues {
+		if issue.Kind != ReconcileMissing {
+			remove = append(remove, issue.Signature)
+		}
+		if issue.entry != nil {
+			add = append(add, *issue.entry)
+		}
+	}
+	if err := store.RemoveLedgerEntries(remove...); err != nil {
+		return err
+	}
+	return store.AddLedgerEntries(add...)
+}
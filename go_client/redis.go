@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds every cache call: a slow cache falls through to the RPC node rather
+// than slowing the API down
+const redisTimeout = 500 * time.Millisecond
+
+// RedisConfig configures the cache shared by `serve` instances in config.json
+type RedisConfig struct {
+	URL    string `json:"url"`              // e.g. redis://:password@localhost:6379/0
+	Prefix string `json:"prefix,omitempty"` // key prefix, default "crowdfunding:"
+}
+
+// redisCache shares API responses and fetched accounts between server instances. When a watched
+// campaign changes on-chain its entries are deleted, and the other instances are told over
+// pub/sub to drop their in-memory copies.
+type redisCache struct {
+	client *redis.Client
+	prefix string
+	forget func(address string) // drops a local copy of a changed account
+}
+
+// connectRedis connects to Redis and checks that it answers
+func connectRedis(config *RedisConfig) (*redisCache, error) {
+	opts, err := redis.ParseURL(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	prefix := config.Prefix
+	if prefix == "" {
+		prefix = "crowdfunding:"
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return &redisCache{client: client, prefix: prefix}, nil
+}
+
+// fetch reads a key, treating errors like a miss
+func (c *redisCache) fetch(key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	body, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("Redis get failed: %v", err)
+		}
+		return nil, false
+	}
+	return body, true
+}
+
+// store writes a key that expires after ttl
+func (c *redisCache) store(key string, value []byte, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := c.client.Set(ctx, c.prefix+key, value, ttl).Err(); err != nil {
+		log.Printf("Redis set failed: %v", err)
+	}
+}
+
+func (c *redisCache) get(key string) ([]byte, bool) {
+	return c.fetch("response:" + key)
+}
+
+func (c *redisCache) put(key string, body []byte, ttl time.Duration) {
+	c.store("response:"+key, body, ttl)
+}
+
+// getAccount returns an account another instance fetched
+func (c *redisCache) getAccount(address solana.PublicKey) (*cachedAccount, bool) {
+	data, ok := c.fetch("account:" + address.String())
+	if !ok {
+		return nil, false
+	}
+	var entry cachedAccount
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// putAccount shares a fetched account for ttl
+func (c *redisCache) putAccount(address solana.PublicKey, entry *cachedAccount, ttl time.Duration) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.store("account:"+address.String(), data, ttl)
+}
+
+// invalidate deletes the cached account and every response mentioning address, along with the
+// campaign list and stats, and tells the other instances
+func (c *redisCache) invalidate(address string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keys := []string{c.prefix + "account:" + address, c.prefix + "response:/campaigns", c.prefix + "response:/stats"}
+	iter := c.client.Scan(ctx, 0, c.prefix+"response:*"+address+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("Redis scan failed: %v", err)
+	}
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("Redis invalidation of %s failed: %v", address, err)
+	}
+	if err := c.client.Publish(ctx, c.prefix+"invalidate", address).Err(); err != nil {
+		log.Printf("Redis publish failed: %v", err)
+	}
+}
+
+// listen drops local copies of the accounts other instances report as changed, until ctx is cancelled
+func (c *redisCache) listen(ctx context.Context) {
+	sub := c.client.Subscribe(ctx, c.prefix+"invalidate")
+	defer sub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			if c.forget != nil {
+				c.forget(msg.Payload)
+			}
+		}
+	}
+}
+
+// useRedis makes the server cache responses and accounts in Redis, shared with other instances
+func (s *Server) useRedis(config *RedisConfig) error {
+	cache, err := connectRedis(config)
+	if err != nil {
+		return err
+	}
+	cache.forget = s.app.accounts.forget
+	s.redis = cache
+	s.cache = cache
+	s.app.accounts.shared = cache
+	s.watched = map[string]bool{}
+	go cache.listen(context.Background())
+	return nil
+}
+
+// watchForChanges invalidates a campaign's cached entries whenever its on-chain state changes,
+// starting the first time one of its responses is cached. Without Redis, responses simply expire.
+func (s *Server) watchForChanges(address string) {
+	if s.redis == nil || address == "" {
+		return
+	}
+	campaign, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return
+	}
+
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.watched[address] {
+		return
+	}
+	s.watched[address] = true
+	go s.events.Stream(context.Background(), []solana.PublicKey{campaign}, func(event CampaignEvent) {
+		s.redis.invalidate(event.Campaign)
+	})
+}
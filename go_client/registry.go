@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// registryCacheFile keeps the last registry fetched, so it still applies when the URL is down
+const registryCacheFile = "registry-cache.json"
+
+// defaultRegistryTTL is how long a fetched registry is used before it is fetched again
+const defaultRegistryTTL = time.Hour
+
+// RegistryConfig points the client at a curated registry of vetted campaigns, in config.json
+type RegistryConfig struct {
+	URL    string `json:"url"`           // where the signed registry is published
+	Signer string `json:"signer"`        // public key of the curator, which must have signed it
+	TTL    string `json:"ttl,omitempty"` // how long a fetched registry is used, default 1h
+}
+
+// Registry lists the campaigns a curator has vetted
+type Registry struct {
+	Name      string          `json:"name,omitempty"`
+	Updated   time.Time       `json:"updated"`
+	Campaigns []RegistryEntry `json:"campaigns"`
+}
+
+// RegistryEntry is one vetted campaign
+type RegistryEntry struct {
+	Address      solana.PublicKey `json:"address"`
+	Name         string           `json:"name,omitempty"`
+	Organization string           `json:"organization,omitempty"`
+	VerifiedAt   time.Time        `json:"verifiedAt,omitempty"`
+}
+
+// SignedRegistry is a registry with the curator's attestation: an ed25519 signature over the
+// registry field's JSON with insignificant whitespace removed, so reindenting the file keeps it valid
+type SignedRegistry struct {
+	Registry  json.RawMessage  `json:"registry"`
+	Signer    solana.PublicKey `json:"signer"`
+	Signature solana.Signature `json:"signature"`
+}
+
+// registryCache is the registry file kept locally
+type registryCache struct {
+	URL     string         `json:"url"`
+	Fetched time.Time      `json:"fetched"`
+	Signed  SignedRegistry `json:"signed"`
+}
+
+// SignRegistry signs a registry with the curator's key
+func SignRegistry(registry *Registry, curator solana.PrivateKey) (*SignedRegistry, error) {
+	data, err := json.Marshal(registry)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := curator.Sign(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign registry: %w", err)
+	}
+	return &SignedRegistry{Registry: data, Signer: curator.PublicKey(), Signature: signature}, nil
+}
+
+// Verify checks the registry was signed by signer and decodes it
+func (s *SignedRegistry) Verify(signer solana.PublicKey) (*Registry, error) {
+	if !s.Signer.Equals(signer) {
+		return nil, fmt.Errorf("registry is signed by %s, not the configured curator %s", s.Signer, signer)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, s.Registry); err != nil {
+		return nil, fmt.Errorf("failed to parse registry: %w", err)
+	}
+	if !s.Signature.Verify(signer, compact.Bytes()) {
+		return nil, fmt.Errorf("registry signature is invalid: it was altered after signing")
+	}
+	var registry Registry
+	if err := json.Unmarshal(s.Registry, &registry); err != nil {
+		return nil, fmt.Errorf("failed to parse registry: %w", err)
+	}
+	return &registry, nil
+}
+
+// Entry returns the registry's entry for a campaign, or nil when it is not vetted
+func (r *Registry) Entry(campaign solana.PublicKey) *RegistryEntry {
+	for i := range r.Campaigns {
+		if r.Campaigns[i].Address.Equals(campaign) {
+			return &r.Campaigns[i]
+		}
+	}
+	return nil
+}
+
+// fetchRegistry downloads the signed registry
+func fetchRegistry(ctx context.Context, url string) (*SignedRegistry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Timeout: 15 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch registry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch registry: %s", resp.Status)
+	}
+	var signed SignedRegistry
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&signed); err != nil {
+		return nil, fmt.Errorf("failed to parse registry: %w", err)
+	}
+	return &signed, nil
+}
+
+// Registry returns the verified registry, fetching it again once the cached copy is older than
+// the TTL. It returns nil when no registry is configured. If the registry can't be fetched, the
+// cached copy is used however old.
+func (app *SolanaDApp) Registry() (*Registry, error) {
+	config := app.config.Registry
+	if config == nil || config.URL == "" {
+		return nil, nil
+	}
+	signer, err := solana.PublicKeyFromBase58(config.Signer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry.signer in %s: %w", configFile, err)
+	}
+	ttl := defaultRegistryTTL
+	if config.TTL != "" {
+		if ttl, err = time.ParseDuration(config.TTL); err != nil {
+			return nil, fmt.Errorf("invalid registry.ttl in %s: %w", configFile, err)
+		}
+	}
+
+	var cache *registryCache
+	if data, err := os.ReadFile(registryCacheFile); err == nil {
+		var cached registryCache
+		if json.Unmarshal(data, &cached) == nil && cached.URL == config.URL {
+			cache = &cached
+		}
+	}
+	if cache != nil && time.Since(cache.Fetched) < ttl {
+		return cache.Signed.Verify(signer)
+	}
+
+	signed, err := fetchRegistry(context.Background(), config.URL)
+	if err == nil {
+		var registry *Registry
+		if registry, err = signed.Verify(signer); err == nil {
+			cache = &registryCache{URL: config.URL, Fetched: time.Now().UTC(), Signed: *signed}
+			if data, err := json.MarshalIndent(cache, "", "  "); err == nil {
+				if err := os.WriteFile(registryCacheFile, data, 0644); err != nil {
+					log.Printf("Failed to cache registry: %v", err)
+				}
+			}
+			return registry, nil
+		}
+	}
+	if cache == nil {
+		return nil, err
+	}
+	log.Printf("Registry: %v; using the copy fetched %s", err, formatTime(cache.Fetched))
+	return cache.Signed.Verify(signer)
+}
+
+// CampaignVerification reports whether a campaign is in the registry. checked is false when no
+// registry is configured or it couldn't be loaded.
+func (app *SolanaDApp) CampaignVerification(campaign solana.PublicKey) (entry *RegistryEntry, registry *Registry, checked bool) {
+	registry, err := app.Registry()
+	if err != nil {
+		fmt.Printf("⚠️  Could not check the campaign registry: %v\n", err)
+		return nil, nil, false
+	}
+	if registry == nil {
+		return nil, nil, false
+	}
+	return registry.Entry(campaign), registry, true
+}
+
+// WarnIfUnverified warns before donating to a campaign missing from the registry
+func (app *SolanaDApp) WarnIfUnverified(campaign solana.PublicKey) {
+	entry, registry, checked := app.CampaignVerification(campaign)
+	if !checked {
+		return
+	}
+	if entry != nil {
+		fmt.Printf("✅ Verified campaign%s\n", registryAttribution(entry, registry))
+		return
+	}
+	fmt.Printf("⚠️  %s is NOT in the verified campaign registry%s\n", campaign, registryAttribution(nil, registry))
+	fmt.Println("   Make sure you know who runs it before donating.")
+}
+
+// registryAttribution describes who vetted a campaign, for messages
+func registryAttribution(entry *RegistryEntry, registry *Registry) string {
+	text := ""
+	if entry != nil && entry.Organization != "" {
+		text += " run by " + entry.Organization
+	}
+	if registry.Name != "" {
+		text += " (" + registry.Name + ")"
+	}
+	return text
+}
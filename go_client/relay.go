@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// RelayOptions configures the gasless donation relay
+type RelayOptions struct {
+	Wallet       string // key file of the wallet paying donors' fees; the relay is off without it
+	PriorityFee  uint64 // compute unit price in micro-lamports
+	ComputeUnits uint32 // compute unit limit of relayed transactions
+	Quota        int    // relayed donations allowed per donor per 24 hours
+}
+
+// relayer pays the fees of donations signed by donors. Donors ask it to prepare a transaction,
+// sign it as the donating account and hand it back; the relayer checks that it is still nothing
+// but a donation before adding its own signature, so its wallet only ever pays fees.
+type relayer struct {
+	app    *SolanaDApp
+	wallet *Wallet
+	opts   RelayOptions
+
+	mu    sync.Mutex
+	usage map[solana.PublicKey][]time.Time // relayed donations per donor in the last 24 hours
+}
+
+// newRelayer loads the relay wallet
+func newRelayer(app *SolanaDApp, opts RelayOptions) (*relayer, error) {
+	wallet, err := NewWallet(opts.Wallet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load relay wallet: %w", err)
+	}
+	if wallet.frost != nil {
+		return nil, fmt.Errorf("the relay wallet cannot be a FROST group")
+	}
+	return &relayer{app: app, wallet: wallet, opts: opts, usage: map[solana.PublicKey][]time.Time{}}, nil
+}
+
+// relayInfo is returned by GET /relay
+type relayInfo struct {
+	FeePayer     string `json:"feePayer"`
+	PriorityFee  uint64 `json:"priorityFee"`
+	ComputeUnits uint32 `json:"computeUnits"`
+	Quota        int    `json:"quota"`
+}
+
+// relayPrepareRequest asks the relay to build a donation for the donor to sign
+type relayPrepareRequest struct {
+	Donor    string `json:"donor"`
+	Campaign string `json:"campaign"`
+	Amount   uint64 `json:"amount"` // lamports
+}
+
+// relaySubmitRequest carries a donation signed by the donor
+type relaySubmitRequest struct {
+	Transaction string `json:"transaction"` // base64
+}
+
+func (rl *relayer) handleInfo(w http.ResponseWriter, r *http.Request) {
+	writeRelayJSON(w, r, http.StatusOK, relayInfo{
+		FeePayer:     rl.wallet.Address().String(),
+		PriorityFee:  rl.opts.PriorityFee,
+		ComputeUnits: rl.opts.ComputeUnits,
+		Quota:        rl.opts.Quota,
+	})
+}
+
+// handlePrepare builds an unsigned donation with the relay as fee payer and its priority fee applied
+func (rl *relayer) handlePrepare(w http.ResponseWriter, r *http.Request) {
+	var req relayPrepareRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid request body"})
+		return
+	}
+	donor, err := solana.PublicKeyFromBase58(req.Donor)
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid donor address"})
+		return
+	}
+	campaignAddress, err := solana.PublicKeyFromBase58(req.Campaign)
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid campaign address"})
+		return
+	}
+	if req.Amount == 0 {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "amount must be positive"})
+		return
+	}
+	if donor.Equals(rl.wallet.Address()) {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "the relay cannot donate"})
+		return
+	}
+	if rl.remaining(donor) <= 0 {
+		writeRelayError(w, r, &apiError{http.StatusTooManyRequests, "relay quota exhausted for this donor"})
+		return
+	}
+
+	campaign, err := rl.app.FetchCampaign(campaignAddress)
+	if err != nil {
+		if errors.Is(err, crowdfund.ErrCampaignNotFound) {
+			writeRelayError(w, r, &apiError{http.StatusNotFound, "campaign not found"})
+			return
+		}
+		if errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+			writeRelayError(w, r, &apiError{http.StatusNotFound, "not a campaign account"})
+			return
+		}
+		writeRelayError(w, r, err)
+		return
+	}
+	if err := rl.app.checkDonationsOpen(campaignAddress); err != nil {
+		if errors.Is(err, ErrCampaignClosed) || errors.Is(err, ErrCampaignEnded) {
+			writeRelayError(w, r, &apiError{http.StatusConflict, err.Error()})
+			return
+		}
+		writeRelayError(w, r, err)
+		return
+	}
+
+	donate, err := rl.app.BuildInstruction("donate",
+		map[string]solana.PublicKey{"campaign": campaignAddress, "user": donor},
+		map[string]interface{}{"name": campaign.Name, "amount": req.Amount},
+	)
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+
+	recent, err := rl.app.sender.GetLatestBlockhash(r.Context(), rpc.CommitmentFinalized)
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{
+			computebudget.NewSetComputeUnitLimitInstruction(rl.opts.ComputeUnits).Build(),
+			computebudget.NewSetComputeUnitPriceInstruction(rl.opts.PriorityFee).Build(),
+			donate,
+		},
+		recent.Value.Blockhash,
+		solana.TransactionPayer(rl.wallet.Address()),
+	)
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+
+	writeRelayJSON(w, r, http.StatusOK, map[string]interface{}{
+		"transaction":          encoded,
+		"lastValidBlockHeight": recent.Value.LastValidBlockHeight,
+	})
+}
+
+// handleSubmit countersigns a donor-signed donation as fee payer and broadcasts it
+func (rl *relayer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req relaySubmitRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid request body"})
+		return
+	}
+	tx, err := solana.TransactionFromBase64(req.Transaction)
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid transaction"})
+		return
+	}
+
+	donor, err := rl.validate(tx)
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, err.Error()})
+		return
+	}
+	// Checked before the quota or the relay key is touched, so unsigned submissions cost nothing
+	if err := rl.verifyDonorSignatures(tx); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "transaction is not signed by the donor"})
+		return
+	}
+	if !rl.consume(donor) {
+		writeRelayError(w, r, &apiError{http.StatusTooManyRequests, "relay quota exhausted for this donor"})
+		return
+	}
+
+	if err := rl.app.screenTransaction(tx, rl.wallet.Address()); err != nil {
+		rl.refund(donor)
+		writeRelayError(w, r, &apiError{http.StatusForbidden, "transaction refused by screening"})
+		return
+	}
+	relayKey, err := rl.wallet.signingKey()
+	if err != nil {
+		rl.refund(donor)
+		writeRelayError(w, r, err)
+		return
+	}
+	if _, err := tx.PartialSign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(rl.wallet.Address()) {
+			return &relayKey
+		}
+		return nil
+	}); err != nil {
+		rl.refund(donor)
+		writeRelayError(w, r, err)
+		return
+	}
+	if err := rl.app.auditSigned(tx, rl.wallet.Address()); err != nil {
+		rl.refund(donor)
+		writeRelayError(w, r, err)
+		return
+	}
+
+	sig, err := rl.app.send(r.Context(), "relay donate", tx)
+	if err != nil {
+		rl.refund(donor)
+		if _, ok := crowdfund.CustomErrorCode(err); ok {
+			writeRelayError(w, r, &apiError{http.StatusUnprocessableEntity, rl.app.idl.DecodeError(err).Error()})
+			return
+		}
+		writeRelayError(w, r, err)
+		return
+	}
+
+	logf(r.Context(), "Relayed donation from %s: %s", donor, sig)
+	writeRelayJSON(w, r, http.StatusOK, map[string]interface{}{
+		"signature": sig.String(),
+		"remaining": rl.remaining(donor),
+	})
+}
+
+// validate checks that a submitted transaction is a single donation the relay is willing to pay for,
+// and returns the donor. The relay must appear only as fee payer so no instruction can spend from it.
+func (rl *relayer) validate(tx *solana.Transaction) (solana.PublicKey, error) {
+	message := tx.Message
+	if message.IsVersioned() {
+		return solana.PublicKey{}, fmt.Errorf("versioned transactions are not relayed")
+	}
+	if len(message.AccountKeys) == 0 || !message.AccountKeys[0].Equals(rl.wallet.Address()) {
+		return solana.PublicKey{}, fmt.Errorf("the fee payer must be the relay %s", rl.wallet.Address())
+	}
+
+	var donor solana.PublicKey
+	donations := 0
+	for _, compiled := range message.Instructions {
+		program, err := message.Program(compiled.ProgramIDIndex)
+		if err != nil {
+			return solana.PublicKey{}, fmt.Errorf("invalid instruction: %w", err)
+		}
+		for _, index := range compiled.Accounts {
+			if index == 0 {
+				return solana.PublicKey{}, fmt.Errorf("instructions may not use the relay's account")
+			}
+		}
+
+		switch {
+		case program.Equals(computebudget.ProgramID):
+			if err := rl.checkComputeBudget(compiled, message); err != nil {
+				return solana.PublicKey{}, err
+			}
+		case program.Equals(rl.app.programID):
+			ix, ok := rl.app.idl.MatchInstruction(compiled.Data)
+			if !ok || ix.Name != "donate" {
+				return solana.PublicKey{}, fmt.Errorf("only donations are relayed")
+			}
+			accounts, err := compiled.ResolveInstructionAccounts(&message)
+			if err != nil || len(accounts) != len(ix.Accounts) {
+				return solana.PublicKey{}, fmt.Errorf("invalid donate instruction")
+			}
+			for i, account := range ix.Accounts {
+				if crowdfund.NormalizeName(account.Name) == "user" {
+					donor = accounts[i].PublicKey
+				}
+			}
+			donations++
+		default:
+			return solana.PublicKey{}, fmt.Errorf("program %s is not allowed in relayed transactions", program)
+		}
+	}
+	if donations != 1 {
+		return solana.PublicKey{}, fmt.Errorf("a relayed transaction must contain exactly one donation")
+	}
+	return donor, nil
+}
+
+// verifyDonorSignatures checks every signature the transaction needs besides the relay's,
+// which comes first as fee payer and is still missing
+func (rl *relayer) verifyDonorSignatures(tx *solana.Transaction) error {
+	signers := int(tx.Message.Header.NumRequiredSignatures)
+	if signers < 2 || len(tx.Signatures) != signers || len(tx.Message.AccountKeys) < signers {
+		return fmt.Errorf("missing signatures")
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	for i := 1; i < signers; i++ {
+		if !tx.Signatures[i].Verify(tx.Message.AccountKeys[i], message) {
+			return fmt.Errorf("invalid signature of %s", tx.Message.AccountKeys[i])
+		}
+	}
+	return nil
+}
+
+// checkComputeBudget rejects compute budget instructions asking for more than the relay offers
+func (rl *relayer) checkComputeBudget(compiled solana.CompiledInstruction, message solana.Message) error {
+	accounts, err := compiled.ResolveInstructionAccounts(&message)
+	if err != nil {
+		return fmt.Errorf("invalid compute budget instruction: %w", err)
+	}
+	decoded, err := computebudget.DecodeInstruction(accounts, compiled.Data)
+	if err != nil {
+		return fmt.Errorf("invalid compute budget instruction: %w", err)
+	}
+
+	switch inst := decoded.Impl.(type) {
+	case *computebudget.SetComputeUnitPrice:
+		if inst.MicroLamports > rl.opts.PriorityFee {
+			return fmt.Errorf("priority fee above the relay's %d micro-lamports", rl.opts.PriorityFee)
+		}
+	case *computebudget.SetComputeUnitLimit:
+		if inst.Units > rl.opts.ComputeUnits {
+			return fmt.Errorf("compute unit limit above the relay's %d", rl.opts.ComputeUnits)
+		}
+	default:
+		return fmt.Errorf("unsupported compute budget instruction")
+	}
+	return nil
+}
+
+// remaining returns how many more donations the relay will pay for today for the donor
+func (rl *relayer) remaining(donor solana.PublicKey) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.opts.Quota - len(rl.recent(donor))
+}
+
+// consume uses one of the donor's relayed donations, reporting false when the quota is exhausted
+func (rl *relayer) consume(donor solana.PublicKey) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	used := rl.recent(donor)
+	if len(used) >= rl.opts.Quota {
+		return false
+	}
+	rl.usage[donor] = append(used, time.Now())
+	return true
+}
+
+// refund returns a donation to the donor's quota when it was never broadcast
+func (rl *relayer) refund(donor solana.PublicKey) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if used := rl.usage[donor]; len(used) > 0 {
+		rl.usage[donor] = used[:len(used)-1]
+	}
+}
+
+// recent prunes and returns the donor's relayed donations in the last 24 hours; the caller holds the lock
+func (rl *relayer) recent(donor solana.PublicKey) []time.Time {
+	cutoff := time.Now().Add(-24 * time.Hour)
+	used := rl.usage[donor]
+	for len(used) > 0 && used[0].Before(cutoff) {
+		used = used[1:]
+	}
+	if len(used) == 0 {
+		delete(rl.usage, donor)
+		return nil
+	}
+	rl.usage[donor] = used
+	return used
+}
+
+// writeRelayJSON writes a relay response; relay responses are never cached
+func writeRelayJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeRelayError reports an error like the read-only API does, hiding upstream details
+func writeRelayError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadGateway
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		status = apiErr.status
+	} else {
+		logf(r.Context(), "Relay error on %s: %v", r.URL.Path, err)
+		err = errors.New("upstream RPC request failed")
+	}
+	writeRelayJSON(w, r, status, map[string]string{"error": err.Error(), "requestId": requestIDFrom(r.Context())})
+}
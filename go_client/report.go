@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// manifestFile lists a report package's files with their hashes, signed by the wallet that made it
+const manifestFile = "manifest.json"
+
+// reportPeriod is the span of time a report covers, [From, To)
+type reportPeriod struct {
+	Name     string
+	From, To time.Time
+}
+
+var quarterPattern = regexp.MustCompile(`^(\d{4})[Qq]([1-4])$`)
+
+// parsePeriod reads a year ("2024"), quarter ("2024Q4") or month ("2024-11") in the display time zone
+func parsePeriod(s string) (reportPeriod, error) {
+	if m := quarterPattern.FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		quarter, _ := strconv.Atoi(m[2])
+		from := time.Date(year, time.Month(3*quarter-2), 1, 0, 0, 0, 0, displayLocation)
+		return reportPeriod{Name: strings.ToUpper(s), From: from, To: from.AddDate(0, 3, 0)}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01", s, displayLocation); err == nil {
+		return reportPeriod{Name: s, From: t, To: t.AddDate(0, 1, 0)}, nil
+	}
+	if t, err := time.ParseInLocation("2006", s, displayLocation); err == nil {
+		return reportPeriod{Name: s, From: t, To: t.AddDate(1, 0, 0)}, nil
+	}
+	return reportPeriod{}, fmt.Errorf("invalid period %q: use a year (2024), quarter (2024Q4) or month (2024-11)", s)
+}
+
+// contains reports whether t falls in the period
+func (p reportPeriod) contains(t time.Time) bool {
+	return !t.Before(p.From) && t.Before(p.To)
+}
+
+// String describes the period with its dates
+func (p reportPeriod) String() string {
+	return fmt.Sprintf("%s (%s to %s)", p.Name, formatDate(p.From), formatDate(p.To.Add(-time.Second)))
+}
+
+// ReportManifest describes a report package: what it covers and the SHA-256 of every file in it
+type ReportManifest struct {
+	Report    string            `json:"report"`
+	Period    string            `json:"period"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Generated time.Time         `json:"generated"`
+	Campaigns []string          `json:"campaigns,omitempty"`
+	Files     map[string]string `json:"files"`
+}
+
+// SignedManifest is a manifest with the ed25519 signature of the wallet that made the report
+type SignedManifest struct {
+	Manifest  json.RawMessage  `json:"manifest"`
+	Signer    solana.PublicKey `json:"signer"`
+	Signature solana.Signature `json:"signature"`
+}
+
+// signReport hashes the files of a report package and writes its signed manifest
+func signReport(dir string, manifest *ReportManifest, files []string, signer crowdfund.Signer) error {
+	manifest.Files = map[string]string{}
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", name, err)
+		}
+		sum := sha256.Sum256(data)
+		manifest.Files[name] = hex.EncodeToString(sum[:])
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	signature, err := signer.SignMessage(data)
+	if err != nil {
+		return fmt.Errorf("failed to sign report: %w", err)
+	}
+	signed, err := json.MarshalIndent(SignedManifest{Manifest: data, Signer: signer.Address(), Signature: signature}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), signed, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// VerifyReport checks a report package's signature and that none of its files changed
+func VerifyReport(dir string) (*ReportManifest, solana.PublicKey, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, solana.PublicKey{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var signed SignedManifest
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, solana.PublicKey{}, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, signed.Manifest); err != nil {
+		return nil, signed.Signer, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if !signed.Signature.Verify(signed.Signer, compact.Bytes()) {
+		return nil, signed.Signer, fmt.Errorf("manifest signature is invalid: it was altered after signing")
+	}
+	var manifest ReportManifest
+	if err := json.Unmarshal(signed.Manifest, &manifest); err != nil {
+		return nil, signed.Signer, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	for name, want := range manifest.Files {
+		file, err := os.ReadFile(filepath.Join(dir, filepath.Base(name)))
+		if err != nil {
+			return &manifest, signed.Signer, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if sum := sha256.Sum256(file); hex.EncodeToString(sum[:]) != want {
+			return &manifest, signed.Signer, fmt.Errorf("%s was modified after the report was signed", name)
+		}
+	}
+	return &manifest, signed.Signer, nil
+}
+
+// reportCampaigns resolves the campaigns a report covers: the given addresses, or every campaign
+// in the local lifecycle registry
+func reportCampaigns(list string) ([]solana.PublicKey, error) {
+	var campaigns []solana.PublicKey
+	if list != "" {
+		for _, field := range strings.Split(list, ",") {
+			address, err := solana.PublicKeyFromBase58(strings.TrimSpace(field))
+			if err != nil {
+				return nil, fmt.Errorf("invalid campaign address %q: %w", field, err)
+			}
+			campaigns = append(campaigns, address)
+		}
+		return campaigns, nil
+	}
+
+	records, err := loadCampaignRecords()
+	if err != nil {
+		return nil, err
+	}
+	for address := range records {
+		campaigns = append(campaigns, solana.MustPublicKeyFromBase58(address))
+	}
+	if len(campaigns) == 0 {
+		return nil, fmt.Errorf("no campaigns recorded in %s; list them with -campaigns", lifecycleFile)
+	}
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].String() < campaigns[j].String() })
+	return campaigns, nil
+}
+
+// CampaignMovement is a donation into or withdrawal out of a campaign
+type CampaignMovement struct {
+	Campaign     solana.PublicKey
+	CampaignName string
+	CampaignActivity
+	Valuation *Valuation // nil when no price could be found
+}
+
+// campaignMovements collects the campaigns' donations and withdrawals in the period, oldest first,
+// valued at the SOL price of their block time. It also returns the campaigns' names.
+func (app *SolanaDApp) campaignMovements(ctx context.Context, period reportPeriod, campaigns []solana.PublicKey, valuer *Valuer) ([]CampaignMovement, map[solana.PublicKey]string, error) {
+	var movements []CampaignMovement
+	names := map[solana.PublicKey]string{}
+	records, _ := loadCampaignRecords()
+	for _, campaign := range campaigns {
+		if fetched, err := app.FetchCampaign(campaign); err == nil {
+			names[campaign] = fetched.Name
+		} else if record := records.get(campaign); record != nil {
+			names[campaign] = record.Name // closed since
+		}
+		activity, err := app.GetCampaignActivityBetween(campaign, period.From, period.To)
+		if err != nil {
+			return nil, nil, fmt.Errorf("campaign %s: %w", campaign, err)
+		}
+		for _, a := range activity {
+			movements = append(movements, CampaignMovement{Campaign: campaign, CampaignName: names[campaign], CampaignActivity: a})
+		}
+	}
+	sort.SliceStable(movements, func(i, j int) bool { return movements[i].Slot < movements[j].Slot })
+	for i, m := range movements {
+		if m.BlockTime == nil {
+			continue
+		}
+		valuation, err := valuer.Value(ctx, m.Signature, *m.BlockTime)
+		if err != nil {
+			fmt.Printf("⚠️  %v; %s is left unvalued\n", err, m.Signature)
+			continue
+		}
+		movements[i].Valuation = &valuation
+	}
+	return movements, names, nil
+}
+
+// ComplianceCounterparty totals what one wallet moved in and out of the campaigns, and the last
+// screening decision on it
+type ComplianceCounterparty struct {
+	Address   string
+	Inflow    uint64
+	Outflow   uint64
+	Movements int
+	Screening string // "cleared", "blocked" or "not screened"
+}
+
+// ComplianceReport gathers what a compliance review of a period asks for: every movement of
+// campaign funds, the counterparties, the screening decisions and the transactions that screening
+// or the withdrawal policy blocked
+type ComplianceReport struct {
+	Period         reportPeriod
+	Currency       string
+	Campaigns      []solana.PublicKey
+	Names          map[solana.PublicKey]string
+	Movements      []CampaignMovement
+	Counterparties []ComplianceCounterparty
+	Screening      []AuditEntry // entries with screening decisions
+	Exceptions     []AuditEntry // blocked transactions
+}
+
+// BuildComplianceReport scans the campaigns' on-chain history and the audit log for the period,
+// valuing every movement at the SOL price of its block time
+func (app *SolanaDApp) BuildComplianceReport(ctx context.Context, period reportPeriod, campaigns []solana.PublicKey) (*ComplianceReport, error) {
+	store, err := OpenStore()
+	if err != nil {
+		return nil, err
+	}
+	valuer, err := NewValuer(app.config.Prices, store)
+	if err != nil {
+		return nil, err
+	}
+	report := &ComplianceReport{Period: period, Currency: valuer.Currency(), Campaigns: campaigns}
+	if report.Movements, report.Names, err = app.campaignMovements(ctx, period, campaigns, valuer); err != nil {
+		return nil, err
+	}
+
+	entries, err := readAuditLog(auditFile)
+	if err != nil {
+		return nil, err
+	}
+	decisions := map[string]string{}
+	for _, entry := range entries {
+		if !period.contains(entry.Time) {
+			continue
+		}
+		if len(entry.Screening) > 0 {
+			report.Screening = append(report.Screening, entry)
+			for _, result := range entry.Screening {
+				if result.Blocked {
+					decisions[result.Address] = "blocked"
+				} else if decisions[result.Address] != "blocked" {
+					decisions[result.Address] = "cleared"
+				}
+			}
+		}
+		if entry.Event == "blocked" {
+			report.Exceptions = append(report.Exceptions, entry)
+		}
+	}
+
+	byAddress := map[string]*ComplianceCounterparty{}
+	for _, m := range report.Movements {
+		c := byAddress[m.Wallet]
+		if c == nil {
+			c = &ComplianceCounterparty{Address: m.Wallet, Screening: "not screened"}
+			if decision, ok := decisions[m.Wallet]; ok {
+				c.Screening = decision
+			}
+			byAddress[m.Wallet] = c
+		}
+		c.Movements++
+		if m.Kind == "donate" {
+			c.Inflow += m.Amount
+		} else {
+			c.Outflow += m.Amount
+		}
+	}
+	for _, c := range byAddress {
+		report.Counterparties = append(report.Counterparties, *c)
+	}
+	sort.Slice(report.Counterparties, func(i, j int) bool {
+		a, b := report.Counterparties[i], report.Counterparties[j]
+		if a.Inflow+a.Outflow != b.Inflow+b.Outflow {
+			return a.Inflow+a.Outflow > b.Inflow+b.Outflow
+		}
+		return a.Address < b.Address
+	})
+	return report, nil
+}
+
+// movementTime formats a movement's block time, which the RPC node may not know
+func movementTime(a CampaignActivity) string {
+	if a.BlockTime == nil {
+		return ""
+	}
+	return a.BlockTime.Format(time.RFC3339)
+}
+
+// auditInstructionNames summarizes an audit entry's crowdfunding instructions
+func auditInstructionNames(entry AuditEntry) string {
+	var names []string
+	for _, ix := range entry.Instructions {
+		if ix.Name != "" {
+			names = append(names, ix.Name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// Write saves the report as CSV files and a PDF summary in dir, and signs the package
+func (r *ComplianceReport) Write(dir string, signer crowdfund.Signer) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var movements [][]string
+	for _, m := range r.Movements {
+		direction := "inflow"
+		if m.Kind == "withdraw" {
+			direction = "outflow"
+		}
+		price, value, source := valuationColumns(m.Valuation, m.Amount)
+		movements = append(movements, []string{movementTime(m.CampaignActivity), m.Signature, m.Campaign.String(), m.CampaignName, direction, m.Wallet, lamportsToSOL(m.Amount), price, value, source, m.Memo})
+	}
+	var counterparties [][]string
+	for _, c := range r.Counterparties {
+		counterparties = append(counterparties, []string{c.Address, lamportsToSOL(c.Inflow), lamportsToSOL(c.Outflow), strconv.Itoa(c.Movements), c.Screening})
+	}
+	var screening [][]string
+	for _, entry := range r.Screening {
+		for _, result := range entry.Screening {
+			decision := "cleared"
+			if result.Blocked {
+				decision = "blocked"
+			}
+			screening = append(screening, []string{entry.Time.Format(time.RFC3339), strconv.Itoa(entry.Seq), entry.Signer, auditInstructionNames(entry), result.Address, result.Source, decision, result.Reason})
+		}
+	}
+	var exceptions [][]string
+	for _, entry := range r.Exceptions {
+		exceptions = append(exceptions, []string{entry.Time.Format(time.RFC3339), strconv.Itoa(entry.Seq), entry.Signer, auditInstructionNames(entry), entry.Error})
+	}
+
+	files := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{"movements.csv", []string{"time", "signature", "campaign", "campaign_name", "direction", "counterparty", "amount_sol", "sol_" + r.Currency, "value_" + r.Currency, "price_source", "memo"}, movements},
+		{"counterparties.csv", []string{"address", "inflow_sol", "outflow_sol", "movements", "screening"}, counterparties},
+		{"screening.csv", []string{"time", "audit_seq", "signer", "instructions", "address", "source", "decision", "reason"}, screening},
+		{"exceptions.csv", []string{"time", "audit_seq", "signer", "instructions", "reason"}, exceptions},
+	}
+	var names []string
+	for _, f := range files {
+		if err := writeCSV(filepath.Join(dir, f.name), f.header, f.rows); err != nil {
+			return err
+		}
+		names = append(names, f.name)
+	}
+	if err := writeTextPDF(filepath.Join(dir, "report.pdf"), "Compliance report "+r.Period.Name, r.summary(signer.Address())); err != nil {
+		return err
+	}
+	names = append(names, "report.pdf")
+
+	manifest := &ReportManifest{Report: "compliance", Period: r.Period.Name, From: r.Period.From.UTC(), To: r.Period.To.UTC(), Generated: time.Now().UTC()}
+	for _, campaign := range r.Campaigns {
+		manifest.Campaigns = append(manifest.Campaigns, campaign.String())
+	}
+	return signReport(dir, manifest, names, signer)
+}
+
+// summary is the text of the PDF report
+func (r *ComplianceReport) summary(signer solana.PublicKey) []string {
+	lines := []string{
+		"COMPLIANCE REPORT " + r.Period.String(),
+		fmt.Sprintf("Generated %s, signed by %s", formatTime(time.Now()), signer),
+		"Program " + ProgramID,
+		"",
+		"CAMPAIGNS",
+	}
+	var totalIn, totalOut uint64
+	var totalInValue, totalOutValue float64
+	unvalued := 0
+	for _, campaign := range r.Campaigns {
+		var in, out uint64
+		var inValue, outValue float64
+		var donations, withdrawals int
+		for _, m := range r.Movements {
+			if !m.Campaign.Equals(campaign) {
+				continue
+			}
+			var value float64
+			if m.Valuation != nil {
+				value = m.Valuation.Of(m.Amount)
+			} else {
+				unvalued++
+			}
+			if m.Kind == "donate" {
+				in, inValue, donations = in+m.Amount, inValue+value, donations+1
+			} else {
+				out, outValue, withdrawals = out+m.Amount, outValue+value, withdrawals+1
+			}
+		}
+		totalIn, totalOut = totalIn+in, totalOut+out
+		totalInValue, totalOutValue = totalInValue+inValue, totalOutValue+outValue
+		lines = append(lines,
+			fmt.Sprintf("  %s  %s", r.Names[campaign], campaign),
+			fmt.Sprintf("    inflows  %14s SOL in %d donation(s), %s", lamportsToSOL(in), donations, formatFiat(inValue, r.Currency)),
+			fmt.Sprintf("    outflows %14s SOL in %d withdrawal(s), %s", lamportsToSOL(out), withdrawals, formatFiat(outValue, r.Currency)),
+		)
+	}
+	lines = append(lines, fmt.Sprintf("  Total: %s SOL (%s) in, %s SOL (%s) out", lamportsToSOL(totalIn), formatFiat(totalInValue, r.Currency), lamportsToSOL(totalOut), formatFiat(totalOutValue, r.Currency)))
+	if unvalued > 0 {
+		lines = append(lines, fmt.Sprintf("  %d movement(s) could not be valued and are not in the %s totals", unvalued, strings.ToUpper(r.Currency)))
+	}
+	lines = append(lines, "")
+
+	counts := map[string]int{}
+	for _, c := range r.Counterparties {
+		counts[c.Screening]++
+	}
+	lines = append(lines, fmt.Sprintf("COUNTERPARTIES: %d (%d cleared, %d blocked, %d not screened)", len(r.Counterparties), counts["cleared"], counts["blocked"], counts["not screened"]))
+	for _, c := range r.Counterparties {
+		lines = append(lines, fmt.Sprintf("  %-44s in %12s  out %12s  %s", c.Address, lamportsToSOL(c.Inflow), lamportsToSOL(c.Outflow), c.Screening))
+	}
+
+	var decisions, blocked int
+	for _, entry := range r.Screening {
+		for _, result := range entry.Screening {
+			decisions++
+			if result.Blocked {
+				blocked++
+			}
+		}
+	}
+	lines = append(lines, "", fmt.Sprintf("SCREENING: %d decision(s) on %d transaction(s), %d blocked", decisions, len(r.Screening), blocked))
+	lines = append(lines, "", fmt.Sprintf("EXCEPTIONS: %d transaction(s) blocked by screening or the withdrawal policy", len(r.Exceptions)))
+	for _, entry := range r.Exceptions {
+		lines = append(lines, fmt.Sprintf("  #%d %s %s: %s", entry.Seq, formatTime(entry.Time), auditInstructionNames(entry), entry.Error))
+	}
+
+	lines = append(lines, "", "MOVEMENTS")
+	for _, m := range r.Movements {
+		direction := "in "
+		if m.Kind == "withdraw" {
+			direction = "out"
+		}
+		when := ""
+		if m.BlockTime != nil {
+			when = formatTime(*m.BlockTime)
+		}
+		value := "not valued"
+		if m.Valuation != nil {
+			value = formatFiat(m.Valuation.Of(m.Amount), r.Currency)
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %12s SOL %s %s %s", when, direction, lamportsToSOL(m.Amount), value, m.CampaignName, m.Wallet))
+	}
+	lines = append(lines, "", "Values use the SOL price at each movement's block time; movements.csv names the price source.",
+		"The CSV files hold the full records. manifest.json lists their SHA-256 hashes, signed by the wallet above;", "check it with `report verify <dir>`.")
+	return lines
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+)
+
+// requestIDHeader carries the request ID in API requests and responses
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limits caller-supplied IDs to something safe to echo and log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// operationID identifies the CLI operation in progress and lastOperation describes it
+var (
+	operationID   string
+	lastOperation string
+)
+
+// newOperationID returns a short random ID for a command, menu action or API request
+func newOperationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// withRequestID stores the request ID in the context
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestIDFrom returns the request ID stored in the context, if any
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logf logs a line tagged with the context's request ID
+func logf(ctx context.Context, format string, args ...interface{}) {
+	if id := requestIDFrom(ctx); id != "" {
+		format = fmt.Sprintf("[req %s] %s", id, format)
+	}
+	log.Printf(format, args...)
+}
+
+// beginOperation starts a new CLI operation: every log line and transaction error is tagged with its ID
+func beginOperation(name string) string {
+	operationID = newOperationID()
+	lastOperation = fmt.Sprintf("%s (operation %s)", name, operationID)
+	log.SetPrefix(fmt.Sprintf("[op %s] ", operationID))
+	return operationID
+}
+
+// requestIDs assigns every API request an ID, reusing a well-formed X-Request-ID from the caller
+// (such as a CDN or load balancer), and echoes it in the response headers
+func requestIDs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newOperationID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(withRequestID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// maxRetryAttempts is how many times a queued transaction is sent before it is given up on
+const maxRetryAttempts = 10
+
+// retryClaimTimeout is how long a daemon holds a queue entry it is retrying; if it stops
+// midway, another daemon picks the entry up after this
+const retryClaimTimeout = 5 * time.Minute
+
+// Retry queue entry states
+const (
+	RetryPending   = "pending"
+	RetrySucceeded = "succeeded"
+	RetryFailed    = "failed"
+)
+
+// QueuedTransaction is a donation or withdrawal whose send failed for a transient reason.
+// It stores the intent rather than the signed transaction, so each retry uses a fresh blockhash.
+type QueuedTransaction struct {
+	ID          string           `json:"id"`
+	Action      string           `json:"action"` // donate or withdraw
+	Campaign    solana.PublicKey `json:"campaign"`
+	Name        string           `json:"name"`
+	Amount      uint64           `json:"amount"` // lamports
+	Memo        string           `json:"memo,omitempty"`
+	Wallet      solana.PublicKey `json:"wallet"` // only this wallet may retry it
+	Status      string           `json:"status"`
+	Attempts    int              `json:"attempts"`
+	Signatures  []string         `json:"signatures,omitempty"` // every attempt, checked before sending again
+	LastError   string           `json:"lastError,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	NextAttempt time.Time        `json:"nextAttempt,omitempty"`
+	OperationID string           `json:"operationId"`
+}
+
+// Describe summarises the queued operation
+func (q QueuedTransaction) Describe() string {
+	if q.Action == "donate" {
+		return fmt.Sprintf("donate %s SOL to %s", lamportsToSOL(q.Amount), q.Campaign)
+	}
+	return fmt.Sprintf("withdraw %s SOL from %s", lamportsToSOL(q.Amount), q.Campaign)
+}
+
+// isRetryable reports whether a send failed for a reason a later attempt could avoid:
+// an expired blockhash or an unreachable or overloaded RPC node. Program errors are final.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, crowdfund.ErrBlockhashExpired) {
+		return true
+	}
+	if _, ok := crowdfund.CustomErrorCode(err); ok {
+		return false
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusTooManyRequests || httpErr.Code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryBackoff is the wait before the next attempt: 2, 4, 8... minutes, at most an hour.
+// The first wait outlasts a blockhash, so an earlier attempt still in flight cannot land alongside the retry.
+func retryBackoff(attempts int) time.Duration {
+	backoff := time.Minute << attempts
+	if attempts > 6 || backoff > time.Hour {
+		return time.Hour
+	}
+	return backoff
+}
+
+// queueIfRetryable adds a failed donation or withdrawal to the retry queue when the failure was transient
+func (app *SolanaDApp) queueIfRetryable(action string, campaign solana.PublicKey, name string, amount uint64, memo string, sig solana.Signature, err error) {
+	if !isRetryable(err) {
+		return
+	}
+
+	store, storeErr := OpenStore()
+	if storeErr != nil {
+		fmt.Printf("⚠️  Could not queue the %s for retry: %v\n", action, storeErr)
+		return
+	}
+
+	queued := QueuedTransaction{
+		Action:      action,
+		Campaign:    campaign,
+		Name:        name,
+		Amount:      amount,
+		Memo:        memo,
+		Wallet:      app.wallet.Address(),
+		Status:      RetryPending,
+		Attempts:    1,
+		LastError:   err.Error(),
+		CreatedAt:   time.Now().UTC(),
+		NextAttempt: time.Now().UTC().Add(retryBackoff(1)),
+		OperationID: operationID,
+	}
+	if !sig.IsZero() {
+		queued.Signatures = []string{sig.String()}
+	}
+
+	queued, storeErr = store.EnqueueTransaction(queued)
+	if storeErr != nil {
+		fmt.Printf("⚠️  Could not queue the %s for retry: %v\n", action, storeErr)
+		return
+	}
+	fmt.Printf("📥 Queued for retry as #%s: the daemon (with -wallet) resends it with a fresh blockhash. See `retry-queue list`.\n", queued.ID)
+}
+
+// runRetryQueue retries the queued transactions that are due and notifies their final outcome
+func (d *Daemon) runRetryQueue(ctx context.Context, now time.Time) {
+	for _, queued := range d.store.QueuedTransactions() {
+		if queued.Status != RetryPending || queued.NextAttempt.After(now) {
+			continue
+		}
+		if d.app.wallet == nil || !d.app.wallet.Address().Equals(queued.Wallet) {
+			continue // Left for a daemon running with the wallet that queued it
+		}
+		// Hold the entry while retrying so that other daemons sharing the store skip it
+		if claimed, err := d.store.ClaimQueuedTransaction(queued.ID, queued.NextAttempt, now.UTC().Add(retryClaimTimeout)); err != nil || !claimed {
+			if err != nil {
+				logf(ctx, "Failed to claim retry #%s: %v", queued.ID, err)
+			}
+			continue
+		}
+		d.retryTransaction(ctx, queued, now)
+	}
+}
+
+// retryTransaction makes one attempt at a queued transaction, first checking that no earlier attempt landed
+func (d *Daemon) retryTransaction(ctx context.Context, queued QueuedTransaction, now time.Time) {
+	beginOperation(fmt.Sprintf("retry #%s: %s", queued.ID, queued.Describe()))
+
+	landed, failed, err := d.earlierAttemptLanded(ctx, queued)
+	if err != nil {
+		logf(ctx, "Retry #%s: could not check earlier attempts, trying again later: %v", queued.ID, err)
+		queued.NextAttempt = now.UTC().Add(retryBackoff(1))
+		d.updateQueued(ctx, queued)
+		return
+	}
+	if failed != "" {
+		d.finishRetry(ctx, queued, "", errors.New(failed))
+		return
+	}
+	if landed != "" {
+		d.finishRetry(ctx, queued, landed, nil)
+		return
+	}
+
+	instruction, err := d.app.BuildInstruction(queued.Action,
+		map[string]solana.PublicKey{"campaign": queued.Campaign, "user": queued.Wallet},
+		map[string]interface{}{"name": queued.Name, "amount": queued.Amount},
+	)
+	if err != nil {
+		d.finishRetry(ctx, queued, "", fmt.Errorf("failed to build %s instruction: %w", queued.Action, err))
+		return
+	}
+
+	instructions := []solana.Instruction{instruction}
+	if queued.Memo != "" {
+		instructions = append(instructions, memoInstruction(queued.Memo, queued.Wallet))
+	}
+
+	queued.Attempts++
+	sig, err := d.app.submitTransaction("retry "+queued.Action, instructions)
+	if !sig.IsZero() {
+		queued.Signatures = append(queued.Signatures, sig.String())
+	}
+	if err == nil {
+		d.finishRetry(ctx, queued, sig.String(), nil)
+		return
+	}
+	if !isRetryable(err) || queued.Attempts >= maxRetryAttempts {
+		d.finishRetry(ctx, queued, "", err)
+		return
+	}
+
+	queued.LastError = err.Error()
+	queued.NextAttempt = now.UTC().Add(retryBackoff(queued.Attempts))
+	message := fmt.Sprintf("Retry #%s (%s) attempt %d failed, next attempt at %s: %v", queued.ID, queued.Describe(), queued.Attempts, formatTime(queued.NextAttempt), err)
+	logf(ctx, "%s", message)
+	d.notifiers.Notify(ctx, Notification{Title: "Queued " + queued.Action, Message: message, Campaign: queued.Campaign.String(), Severity: "info", Thread: "retry:" + queued.ID})
+	d.updateQueued(ctx, queued)
+}
+
+// earlierAttemptLanded returns the signature of an earlier attempt that was confirmed, or the
+// failure of one that was processed but failed on-chain
+func (d *Daemon) earlierAttemptLanded(ctx context.Context, queued QueuedTransaction) (landed, failed string, err error) {
+	if len(queued.Signatures) == 0 {
+		return "", "", nil
+	}
+
+	sigs := make([]solana.Signature, 0, len(queued.Signatures))
+	for _, s := range queued.Signatures {
+		if sig, err := solana.SignatureFromBase58(s); err == nil {
+			sigs = append(sigs, sig)
+		}
+	}
+	statuses, err := d.app.client.GetSignatureStatuses(ctx, true, sigs...)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get signature statuses: %w", err)
+	}
+
+	for i, status := range statuses.Value {
+		if status == nil {
+			continue
+		}
+		if status.Err != nil {
+			return "", fmt.Sprintf("transaction %s failed: %v", sigs[i], status.Err), nil
+		}
+		if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+			return sigs[i].String(), "", nil
+		}
+	}
+	return "", "", nil
+}
+
+// finishRetry records the final outcome of a queued transaction and notifies it
+func (d *Daemon) finishRetry(ctx context.Context, queued QueuedTransaction, sig string, err error) {
+	notification := Notification{Title: "Queued " + queued.Action, Campaign: queued.Campaign.String(), Thread: "retry:" + queued.ID}
+	if err != nil {
+		queued.Status = RetryFailed
+		queued.LastError = err.Error()
+		notification.Severity = "warning"
+		notification.Message = fmt.Sprintf("Gave up on #%s (%s) after %d attempt(s): %v", queued.ID, queued.Describe(), queued.Attempts, err)
+	} else {
+		queued.Status = RetrySucceeded
+		queued.LastError = ""
+		notification.Severity = "resolved"
+		notification.Message = fmt.Sprintf("#%s (%s) confirmed after %d attempt(s): %s", queued.ID, queued.Describe(), queued.Attempts, d.app.TxURL(sig))
+	}
+	queued.NextAttempt = time.Time{}
+
+	d.notifiers.Notify(ctx, notification)
+	d.updateQueued(ctx, queued)
+}
+
+// updateQueued persists a retry queue entry, logging failures
+func (d *Daemon) updateQueued(ctx context.Context, queued QueuedTransaction) {
+	if err := d.store.UpdateQueuedTransaction(queued); err != nil {
+		logf(ctx, "Failed to update retry queue: %v", err)
+	}
+}
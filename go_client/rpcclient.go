@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SolanaRPC is the part of the Solana JSON-RPC API the client uses
+type SolanaRPC = crowdfund.RPC
+
+// defaultRPCConcurrency is the number of requests allowed in flight per RPC endpoint
+const defaultRPCConcurrency = 8
+
+// endpointSlots bounds in-flight requests per endpoint, shared by every client using it
+var (
+	endpointSlotsMu sync.Mutex
+	endpointSlots   = map[string]chan struct{}{}
+)
+
+// slotsFor returns the concurrency semaphore for an endpoint, creating it on first use
+func slotsFor(endpoint string, limit int) chan struct{} {
+	endpointSlotsMu.Lock()
+	defer endpointSlotsMu.Unlock()
+
+	slots, ok := endpointSlots[endpoint]
+	if !ok {
+		if limit <= 0 {
+			limit = defaultRPCConcurrency
+		}
+		slots = make(chan struct{}, limit)
+		endpointSlots[endpoint] = slots
+	}
+	return slots
+}
+
+// newRPCClient creates an RPC client that limits concurrent requests to the endpoint
+// and records a span for every JSON-RPC call
+func newRPCClient(endpoint string, maxConcurrent int) *rpc.Client {
+	next := rpc.New(endpoint)
+	if debugTx {
+		next = rpc.NewWithCustomRPCClient(jsonrpc.NewClientWithOpts(endpoint, &jsonrpc.RPCClientOpts{
+			HTTPClient: &debugHTTPClient{next: &http.Client{Timeout: 2 * time.Minute}},
+		}))
+	}
+	return rpc.NewWithCustomRPCClient(&instrumentedRPCClient{
+		next:     next,
+		endpoint: endpoint,
+		slots:    slotsFor(endpoint, maxConcurrent),
+	})
+}
+
+// instrumentedRPCClient wraps the default JSON-RPC client with a concurrency limit and a span per call
+type instrumentedRPCClient struct {
+	next     *rpc.Client
+	endpoint string
+	slots    chan struct{}
+}
+
+// start waits for a free slot on the endpoint and opens the call's span.
+// The returned function releases the slot and ends the span.
+func (c *instrumentedRPCClient) start(ctx context.Context, method string) (context.Context, trace.Span, func(error), error) {
+	ctx, span := tracer.Start(ctx, "rpc "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemKey.String("jsonrpc"),
+			semconv.RPCMethod(method),
+			attribute.String("rpc.endpoint", c.endpoint),
+		),
+	)
+
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		endSpan(span, ctx.Err())
+		return ctx, span, nil, ctx.Err()
+	}
+
+	return ctx, span, func(err error) {
+		<-c.slots
+		endSpan(span, err)
+	}, nil
+}
+
+func (c *instrumentedRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	ctx, _, done, err := c.start(ctx, method)
+	if err != nil {
+		return err
+	}
+	err = c.next.RPCCallForInto(ctx, out, method, params)
+	done(err)
+	return err
+}
+
+func (c *instrumentedRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	ctx, _, done, err := c.start(ctx, method)
+	if err != nil {
+		return err
+	}
+	err = c.next.RPCCallWithCallback(ctx, method, params, callback)
+	done(err)
+	return err
+}
+
+func (c *instrumentedRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	ctx, span, done, err := c.start(ctx, "batch")
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("rpc.batch_size", len(requests)))
+	responses, err := c.next.RPCCallBatch(ctx, requests)
+	done(err)
+	return responses, err
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Defaults of the daemon's JSON-RPC proxy
+const (
+	defaultRPCProxyListen    = "127.0.0.1:8898"
+	defaultProxyAccountTTL   = 5 * time.Second
+	defaultProxyBlockhashTTL = 10 * time.Second
+	// proxyRentTTL caches getMinimumBalanceForRentExemption, which only changes with the rent rate
+	proxyRentTTL = time.Hour
+	// maxProxyRequest bounds a request body; a batch of a few hundred calls fits easily
+	maxProxyRequest = 4 << 20
+)
+
+// RPCProxyConfig enables a local Solana JSON-RPC endpoint in the daemon, so tools on the same host
+// share its upstream endpoint and cache
+type RPCProxyConfig struct {
+	Listen       string `json:"listen,omitempty"`       // default 127.0.0.1:8898
+	AccountTTL   string `json:"accountTTL,omitempty"`   // how long tracked campaigns' accounts are cached, default 5s
+	BlockhashTTL string `json:"blockhashTTL,omitempty"` // how long the latest blockhash is cached, default 10s
+
+	accountTTL, blockhashTTL time.Duration
+}
+
+// prepare applies defaults and parses the durations
+func (c *RPCProxyConfig) prepare() error {
+	if c.Listen == "" {
+		c.Listen = defaultRPCProxyListen
+	}
+	c.accountTTL, c.blockhashTTL = defaultProxyAccountTTL, defaultProxyBlockhashTTL
+	var err error
+	if c.AccountTTL != "" {
+		if c.accountTTL, err = time.ParseDuration(c.AccountTTL); err != nil {
+			return fmt.Errorf("invalid daemon.rpcProxy.accountTTL: %w", err)
+		}
+	}
+	if c.BlockhashTTL != "" {
+		if c.blockhashTTL, err = time.ParseDuration(c.BlockhashTTL); err != nil {
+			return fmt.Errorf("invalid daemon.rpcProxy.blockhashTTL: %w", err)
+		}
+	}
+	return nil
+}
+
+// proxyRequest is one JSON-RPC call
+type proxyRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// proxyResponse is one JSON-RPC result or error
+type proxyResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// proxyCacheEntry is a cached result
+type proxyCacheEntry struct {
+	result  json.RawMessage
+	expires time.Time
+	account bool // an account read, dropped when a transaction is sent
+}
+
+// RPCProxy forwards JSON-RPC calls upstream, answering hot reads from a short-lived cache:
+// tracked campaigns' accounts and balances, the latest blockhash and rent-exempt minimums.
+// Upstream calls share the client's per-endpoint concurrency limit.
+type RPCProxy struct {
+	config     *RPCProxyConfig
+	read, send string // upstream endpoints; transactions go to send
+	readSlots  chan struct{}
+	sendSlots  chan struct{}
+	client     *http.Client
+	tracked    map[string]bool
+
+	mu    sync.Mutex
+	cache map[string]proxyCacheEntry // method and params -> result
+
+	hits, misses atomic.Int64
+}
+
+// NewRPCProxy creates a proxy in front of the configured RPC endpoints, caching the accounts of
+// the tracked campaigns
+func NewRPCProxy(config *RPCProxyConfig, app *SolanaDApp, campaigns []solana.PublicKey) *RPCProxy {
+	p := &RPCProxy{
+		config:    config,
+		read:      app.config.readEndpoint(),
+		send:      app.config.sendEndpoint(),
+		readSlots: slotsFor(app.config.readEndpoint(), app.config.RPCConcurrency),
+		sendSlots: slotsFor(app.config.sendEndpoint(), app.config.RPCConcurrency),
+		client:    &http.Client{Timeout: 2 * time.Minute},
+		tracked:   map[string]bool{},
+		cache:     map[string]proxyCacheEntry{},
+	}
+	for _, campaign := range campaigns {
+		p.tracked[campaign.String()] = true
+	}
+	return p
+}
+
+// Run serves the proxy until ctx is cancelled
+func (p *RPCProxy) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", p.config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to start the RPC proxy: %w", err)
+	}
+	server := &http.Server{Handler: p}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	fmt.Printf("🔀 RPC proxy on http://%s in front of %s\n", listener.Addr(), p.read)
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// cacheTTL is how long a call's result may be served from the cache, zero when it must not be
+func (p *RPCProxy) cacheTTL(req proxyRequest) (ttl time.Duration, account bool) {
+	var params []json.RawMessage
+	if len(req.Params) > 0 && json.Unmarshal(req.Params, &params) != nil {
+		return 0, false
+	}
+	tracked := func(raw json.RawMessage) bool {
+		var address string
+		return json.Unmarshal(raw, &address) == nil && p.tracked[address]
+	}
+	switch req.Method {
+	case "getAccountInfo", "getBalance":
+		if len(params) > 0 && tracked(params[0]) {
+			return p.config.accountTTL, true
+		}
+	case "getMultipleAccounts":
+		var addresses []json.RawMessage
+		if len(params) == 0 || json.Unmarshal(params[0], &addresses) != nil || len(addresses) == 0 {
+			return 0, false
+		}
+		for _, address := range addresses {
+			if !tracked(address) {
+				return 0, false
+			}
+		}
+		return p.config.accountTTL, true
+	case "getLatestBlockhash":
+		return p.config.blockhashTTL, false
+	case "getMinimumBalanceForRentExemption":
+		return proxyRentTTL, false
+	}
+	return 0, false
+}
+
+// cacheKey identifies a call by its method and parameters
+func cacheKey(req proxyRequest) string {
+	var params bytes.Buffer
+	if json.Compact(&params, req.Params) != nil {
+		params.Write(req.Params)
+	}
+	return req.Method + " " + params.String()
+}
+
+// cached returns a call's result if it is in the cache and fresh
+func (p *RPCProxy) cached(req proxyRequest) (json.RawMessage, bool) {
+	if ttl, _ := p.cacheTTL(req); ttl <= 0 {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.cache[cacheKey(req)]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// store caches a successful result of a cacheable call
+func (p *RPCProxy) store(req proxyRequest, resp proxyResponse) {
+	ttl, account := p.cacheTTL(req)
+	if ttl <= 0 || len(resp.Error) > 0 || len(resp.Result) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for key, entry := range p.cache {
+		if now.After(entry.expires) {
+			delete(p.cache, key)
+		}
+	}
+	p.cache[cacheKey(req)] = proxyCacheEntry{result: resp.Result, expires: now.Add(ttl), account: account}
+}
+
+// invalidateAccounts drops cached account reads, which a sent transaction may have changed
+func (p *RPCProxy) invalidateAccounts() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, entry := range p.cache {
+		if entry.account {
+			delete(p.cache, key)
+		}
+	}
+}
+
+// ServeHTTP answers single and batch JSON-RPC calls; GET reports cache statistics
+func (p *RPCProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		p.mu.Lock()
+		entries := len(p.cache)
+		p.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"upstream": p.read,
+			"cached":   entries,
+			"hits":     p.hits.Load(),
+			"misses":   p.misses.Load(),
+		})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxProxyRequest+1))
+	if err != nil || len(body) > maxProxyRequest {
+		http.Error(w, "request too large or unreadable", http.StatusBadRequest)
+		return
+	}
+
+	batch := strings.HasPrefix(strings.TrimSpace(string(body)), "[")
+	var requests []proxyRequest
+	if batch {
+		err = json.Unmarshal(body, &requests)
+	} else {
+		var req proxyRequest
+		err = json.Unmarshal(body, &req)
+		requests = []proxyRequest{req}
+	}
+	if err != nil || len(requests) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`))
+		return
+	}
+
+	responses := make([]*proxyResponse, len(requests))
+	var forward []proxyRequest
+	send := false
+	for i, req := range requests {
+		if result, ok := p.cached(req); ok {
+			p.hits.Add(1)
+			responses[i] = &proxyResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+			continue
+		}
+		p.misses.Add(1)
+		forward = append(forward, req)
+		send = send || req.Method == "sendTransaction"
+	}
+
+	if len(forward) > 0 {
+		endpoint, slots := p.read, p.readSlots
+		if send {
+			endpoint, slots = p.send, p.sendSlots
+		}
+		// A single call is relayed verbatim, so upstream errors such as 429 reach the caller as they are
+		var payload interface{} = forward
+		if !batch {
+			payload = forward[0]
+		}
+		status, upstream, err := p.forward(r.Context(), endpoint, slots, payload)
+		if err != nil {
+			log.Printf("RPC proxy: %v", err)
+			http.Error(w, "upstream RPC unavailable", http.StatusBadGateway)
+			return
+		}
+		if send {
+			p.invalidateAccounts()
+		}
+		if !batch || status != http.StatusOK {
+			if status == http.StatusOK {
+				var resp proxyResponse
+				if json.Unmarshal(upstream, &resp) == nil {
+					p.store(forward[0], resp)
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write(upstream)
+			return
+		}
+
+		var answered []proxyResponse
+		if err := json.Unmarshal(upstream, &answered); err != nil {
+			log.Printf("RPC proxy: invalid batch response from %s: %v", endpoint, err)
+			http.Error(w, "invalid upstream response", http.StatusBadGateway)
+			return
+		}
+		byID := map[string]proxyResponse{}
+		for _, resp := range answered {
+			byID[string(resp.ID)] = resp
+		}
+		for i, req := range requests {
+			if responses[i] != nil {
+				continue
+			}
+			resp, ok := byID[string(req.ID)]
+			if !ok {
+				resp = proxyResponse{JSONRPC: "2.0", ID: req.ID, Error: json.RawMessage(`{"code":-32603,"message":"no response from upstream"}`)}
+			}
+			p.store(req, resp)
+			responses[i] = &resp
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if batch {
+		json.NewEncoder(w).Encode(responses)
+	} else {
+		json.NewEncoder(w).Encode(responses[0])
+	}
+}
+
+// forward posts a call or batch upstream, holding one of the endpoint's concurrency slots
+func (p *RPCProxy) forward(ctx context.Context, endpoint string, slots chan struct{}, payload interface{}) (int, []byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to reach %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read the response of %s: %w", endpoint, err)
+	}
+	return resp.StatusCode, data, nil
+}
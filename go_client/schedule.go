@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser accepts standard five-field cron expressions and descriptors such as @daily
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule is a recurring operation run by the daemon
+type Schedule struct {
+	ID        string           `json:"id"`
+	Spec      string           `json:"spec"`   // cron expression, e.g. "0 9 * * MON"
+	Action    string           `json:"action"` // withdraw or donate
+	Campaign  solana.PublicKey `json:"campaign"`
+	Amount    uint64           `json:"amount"` // lamports
+	CreatedAt time.Time        `json:"createdAt"`
+	LastRun   time.Time        `json:"lastRun,omitempty"`
+}
+
+// Next returns the first run time after the schedule last ran (or was created)
+func (s Schedule) Next() (time.Time, error) {
+	spec, err := cronParser.Parse(s.Spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	after := s.CreatedAt
+	if s.LastRun.After(after) {
+		after = s.LastRun
+	}
+	return spec.Next(after.In(displayLocation)), nil
+}
+
+// Describe summarises the schedule's operation
+func (s Schedule) Describe() string {
+	if s.Action == "donate" {
+		return fmt.Sprintf("donate %s SOL to %s", lamportsToSOL(s.Amount), s.Campaign)
+	}
+	return fmt.Sprintf("withdraw %s SOL from %s", lamportsToSOL(s.Amount), s.Campaign)
+}
+
+// ScheduleRun is the execution history entry of one scheduled operation
+type ScheduleRun struct {
+	ScheduleID  string    `json:"scheduleId"`
+	Time        time.Time `json:"time"`
+	Signature   string    `json:"signature,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	OperationID string    `json:"operationId"`
+}
+
+// runDueSchedules runs every schedule whose next run time has passed. Missed runs
+// (for example while the daemon was stopped) are run once, not once per missed slot.
+func (d *Daemon) runDueSchedules(ctx context.Context, now time.Time) {
+	for _, schedule := range d.store.Schedules() {
+		next, err := schedule.Next()
+		if err != nil {
+			logf(ctx, "Schedule %s has an invalid spec %q: %v", schedule.ID, schedule.Spec, err)
+			continue
+		}
+		if next.After(now) {
+			continue
+		}
+		// With a shared store another daemon may have taken this run already
+		if claimed, err := d.store.ClaimScheduleRun(schedule.ID, schedule.LastRun, now.UTC()); err != nil || !claimed {
+			if err != nil {
+				logf(ctx, "Failed to claim schedule %s: %v", schedule.ID, err)
+			}
+			continue
+		}
+		d.runSchedule(ctx, schedule, now)
+	}
+}
+
+// runSchedule executes one scheduled operation and records the outcome
+func (d *Daemon) runSchedule(ctx context.Context, schedule Schedule, now time.Time) {
+	run := ScheduleRun{
+		ScheduleID:  schedule.ID,
+		Time:        now.UTC(),
+		OperationID: beginOperation(fmt.Sprintf("schedule %s: %s", schedule.ID, schedule.Describe())),
+	}
+
+	sig, err := d.executeSchedule(schedule)
+	notification := Notification{Title: "Scheduled " + schedule.Action, Campaign: schedule.Campaign.String(), Severity: "info"}
+	if err != nil {
+		run.Error = err.Error()
+		notification.Severity = "warning"
+		notification.Message = fmt.Sprintf("Schedule %s (%s) failed: %v", schedule.ID, schedule.Describe(), err)
+		d.notifiers.Notify(ctx, notification)
+	} else {
+		run.Signature = sig.String()
+		logf(ctx, "Schedule %s: %s: %s", schedule.ID, schedule.Describe(), d.app.TxURL(sig.String()))
+	}
+
+	if err := d.store.RecordScheduleRun(run); err != nil {
+		logf(ctx, "Failed to record schedule run: %v", err)
+	}
+}
+
+// executeSchedule signs and sends the scheduled operation
+func (d *Daemon) executeSchedule(schedule Schedule) (solana.Signature, error) {
+	if d.app.wallet == nil {
+		return solana.Signature{}, fmt.Errorf("the daemon has no wallet: pass -wallet to run schedules")
+	}
+
+	campaign, err := d.app.FetchCampaign(schedule.Campaign)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	if schedule.Action == "donate" {
+		if d.autoClosing(schedule.Campaign) {
+			return solana.Signature{}, fmt.Errorf("'%s' reached its goal and is being closed by its auto-close policy", campaign.Name)
+		}
+		if err := d.app.checkDonationsOpen(schedule.Campaign); err != nil {
+			return solana.Signature{}, err
+		}
+	}
+
+	instruction, err := d.app.BuildInstruction(schedule.Action,
+		map[string]solana.PublicKey{"campaign": schedule.Campaign, "user": d.app.wallet.Address()},
+		map[string]interface{}{"name": campaign.Name, "amount": schedule.Amount},
+	)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to build %s instruction: %w", schedule.Action, err)
+	}
+	return d.app.submitTransaction("schedule "+schedule.Action, []solana.Instruction{instruction})
+}
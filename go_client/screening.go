@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// screeningTokenEnv holds the screening API token when it is not in config.json
+const screeningTokenEnv = "SCREENING_API_TOKEN"
+
+// defaultScreeningTimeout bounds a screening API call
+const defaultScreeningTimeout = 10 * time.Second
+
+// ErrScreeningBlocked is returned when screening refuses to sign a transaction
+var ErrScreeningBlocked = errors.New("transaction blocked by screening")
+
+// ScreeningConfig checks the counterparties of every transaction against a denylist and/or a
+// screening API before the client signs it
+type ScreeningConfig struct {
+	Denylist string `json:"denylist,omitempty"` // file of blocked addresses, one per line with an optional reason
+	URL      string `json:"url,omitempty"`      // screening API, see the README for its contract
+	Token    string `json:"token,omitempty"`    // bearer token for the API, also read from the environment
+	Timeout  string `json:"timeout,omitempty"`  // e.g. "10s", the default
+	FailOpen bool   `json:"failOpen,omitempty"` // sign when the denylist or API can't be checked; by default that blocks
+}
+
+// ScreeningResult is the decision on one address, as recorded in the audit log
+type ScreeningResult struct {
+	Address string `json:"address"`
+	Source  string `json:"source"` // "denylist" or the API host
+	Blocked bool   `json:"blocked,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// screeningExempt are the system program and sysvars, which instructions reference without them
+// being counterparties. The programs a transaction calls are skipped as well.
+var screeningExempt = []solana.PublicKey{
+	solana.SystemProgramID,
+	solana.SysVarRentPubkey,
+	solana.SysVarClockPubkey,
+	solana.SysVarRecentBlockHashesPubkey,
+	solana.SysVarInstructionsPubkey,
+}
+
+// loadDenylist reads a denylist file: one address per line, optionally followed by a reason.
+// Blank lines and lines starting with # are ignored.
+func loadDenylist(path string) (map[solana.PublicKey]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open denylist: %w", err)
+	}
+	defer f.Close()
+
+	denied := map[solana.PublicKey]string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		field, reason, _ := strings.Cut(text, " ")
+		address, err := solana.PublicKeyFromBase58(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid address %q", path, line, field)
+		}
+		denied[address] = strings.TrimSpace(reason)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read denylist: %w", err)
+	}
+	return denied, nil
+}
+
+// screeningRequest and screeningResponse are the screening API's request and response bodies
+type screeningRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+type screeningResponse struct {
+	Results []struct {
+		Address string `json:"address"`
+		Blocked bool   `json:"blocked"`
+		Reason  string `json:"reason,omitempty"`
+	} `json:"results"`
+}
+
+// screenWithAPI asks the screening API about every address. An address the API does not
+// answer for is an error, not a pass.
+func (c *ScreeningConfig) screenWithAPI(ctx context.Context, addresses []solana.PublicKey) ([]ScreeningResult, error) {
+	timeout := defaultScreeningTimeout
+	if c.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(c.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid screening timeout: %w", err)
+		}
+	}
+	source := c.URL
+	if u, err := url.Parse(c.URL); err == nil && u.Host != "" {
+		source = u.Host
+	}
+
+	req := screeningRequest{}
+	for _, address := range addresses {
+		req.Addresses = append(req.Addresses, address.String())
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	token := c.Token
+	if token == "" {
+		token = os.Getenv(screeningTokenEnv)
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("screening API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("screening API request failed: %s", resp.Status)
+	}
+	var decoded screeningResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to parse screening API response: %w", err)
+	}
+
+	answered := map[string]ScreeningResult{}
+	for _, r := range decoded.Results {
+		answered[r.Address] = ScreeningResult{Address: r.Address, Source: source, Blocked: r.Blocked, Reason: r.Reason}
+	}
+	results := make([]ScreeningResult, len(addresses))
+	for i, address := range addresses {
+		result, ok := answered[address.String()]
+		if !ok {
+			return nil, fmt.Errorf("screening API did not answer for %s", address)
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// screeningAddresses returns the counterparties of a transaction: every account it references
+// except ours, the programs it calls and sysvars. For campaign accounts the campaign admin is
+// screened as well, since they are who ultimately receives a donation.
+func (app *SolanaDApp) screeningAddresses(tx *solana.Transaction, ours []solana.PublicKey) []solana.PublicKey {
+	skip := map[solana.PublicKey]bool{app.programID: true}
+	for _, key := range append(append([]solana.PublicKey{}, screeningExempt...), ours...) {
+		skip[key] = true
+	}
+	for _, compiled := range tx.Message.Instructions {
+		if program, err := tx.Message.Program(compiled.ProgramIDIndex); err == nil {
+			skip[program] = true
+		}
+	}
+
+	var addresses []solana.PublicKey
+	add := func(key solana.PublicKey) {
+		if !skip[key] {
+			skip[key] = true
+			addresses = append(addresses, key)
+		}
+	}
+	for _, key := range tx.Message.AccountKeys {
+		if skip[key] {
+			continue
+		}
+		add(key)
+		if account, err := app.getAccount(key); err == nil && account != nil && account.Campaign != nil {
+			add(account.Campaign.Admin)
+		}
+	}
+	return addresses
+}
+
+// screenTransaction checks a transaction's counterparties before signer signs it. Every decision
+// is recorded in the audit log; a blocked address, or a check that can't be made without
+// failOpen, blocks the transaction.
+func (app *SolanaDApp) screenTransaction(tx *solana.Transaction, signer solana.PublicKey, ours ...solana.PublicKey) error {
+	config := app.config.Screening
+	if config == nil || (config.Denylist == "" && config.URL == "") {
+		return nil
+	}
+	addresses := app.screeningAddresses(tx, append(ours, signer))
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	var results []ScreeningResult
+	var failures []string
+	if config.Denylist != "" {
+		denied, err := loadDenylist(config.Denylist)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+		for _, address := range addresses {
+			if reason, ok := denied[address]; ok {
+				results = append(results, ScreeningResult{Address: address.String(), Source: "denylist", Blocked: true, Reason: reason})
+			} else if err == nil {
+				results = append(results, ScreeningResult{Address: address.String(), Source: "denylist"})
+			}
+		}
+	}
+	if config.URL != "" {
+		checked, err := config.screenWithAPI(context.Background(), addresses)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+		results = append(results, checked...)
+	}
+
+	var violations []string
+	for _, result := range results {
+		if result.Blocked {
+			violation := fmt.Sprintf("%s is blocked by %s", result.Address, result.Source)
+			if result.Reason != "" {
+				violation += ": " + result.Reason
+			}
+			violations = append(violations, violation)
+		}
+	}
+	if len(failures) > 0 && !config.FailOpen {
+		violations = append(violations, failures...)
+	}
+
+	entry := AuditEntry{
+		Event:        "screened",
+		Signer:       signer.String(),
+		Instructions: app.auditInstructions(tx),
+		Screening:    results,
+		Error:        strings.Join(failures, "; "),
+	}
+	if len(violations) > 0 {
+		entry.Event, entry.Error = "blocked", strings.Join(violations, "; ")
+	}
+	if err := appendAudit(entry); err != nil {
+		// Compliance needs the record, so an unrecorded decision must not lead to a signature
+		return fmt.Errorf("failed to record screening in the audit log: %w", err)
+	}
+
+	if len(violations) > 0 {
+		log.Printf("Screening blocked a transaction: %s", strings.Join(violations, "; "))
+		fmt.Println("🚫 Transaction blocked by screening:")
+		for _, violation := range violations {
+			fmt.Printf("   • %s\n", violation)
+		}
+		return fmt.Errorf("%w: %s", ErrScreeningBlocked, strings.Join(violations, "; "))
+	}
+	if len(failures) > 0 {
+		log.Printf("Screening incomplete, signing anyway as failOpen is set: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// seedMismatchWarning makes sure a seedNamespace contradicting the program is reported once
+var seedMismatchWarning sync.Once
+
+// campaignSeed is the seed prefix of campaign addresses. The program's IDL decides when it
+// announces one; otherwise seedNamespace from config.json applies, else the legacy prefix.
+func (app *SolanaDApp) campaignSeed() []byte {
+	namespace := app.config.SeedNamespace
+	if seed := app.idl.CampaignSeed(); seed != nil {
+		if namespace != "" && namespace != string(seed) {
+			seedMismatchWarning.Do(func() {
+				fmt.Printf("⚠️  seedNamespace %q in %s doesn't match the program, which uses %q; using the program's\n", namespace, configFile, seed)
+			})
+		}
+		return seed
+	}
+	if namespace != "" {
+		return []byte(namespace)
+	}
+	return []byte(crowdfund.LegacyCampaignSeed)
+}
+
+// campaignPDAWithSeed derives a campaign address under a seed prefix
+func (app *SolanaDApp) campaignPDAWithSeed(seed []byte, admin solana.PublicKey, campaignName string) (solana.PublicKey, uint8, error) {
+	return crowdfund.CampaignPDA(app.programID, seed, admin, campaignName)
+}
+
+// findCampaignPDA returns where the wallet's campaign of that name lives, and its account if
+// any. New campaigns are created under the current seed prefix, but a campaign created under
+// the legacy prefix is found there when the current address is empty.
+func (app *SolanaDApp) findCampaignPDA(campaignName string) (solana.PublicKey, *cachedAccount, error) {
+	address, _, err := app.CreateCampaignPDA(campaignName)
+	if err != nil {
+		return address, nil, fmt.Errorf("failed to create campaign PDA: %w", err)
+	}
+	account, err := app.getAccount(address)
+	if err != nil || account != nil || string(app.campaignSeed()) == crowdfund.LegacyCampaignSeed {
+		return address, account, err
+	}
+
+	legacy, _, err := app.campaignPDAWithSeed([]byte(crowdfund.LegacyCampaignSeed), app.wallet.Address(), campaignName)
+	if err != nil {
+		return address, nil, nil
+	}
+	if legacyAccount, err := app.getAccount(legacy); err == nil && legacyAccount != nil && legacyAccount.Campaign != nil {
+		fmt.Printf("ℹ️  Found %q under the legacy %q seed prefix\n", campaignName, crowdfund.LegacyCampaignSeed)
+		return legacy, legacyAccount, nil
+	}
+	return address, nil, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// CampaignFunds is what a campaign holds and how much of it can be withdrawn
+type CampaignFunds struct {
+	Balance      uint64 // lamports
+	Rent         uint64 // rent-exempt minimum the program keeps in the account
+	Withdrawable uint64
+}
+
+// CampaignFunds looks up a campaign's balance and the part above its rent-exempt minimum,
+// which is the most a withdrawal can take
+func (app *SolanaDApp) CampaignFunds(ctx context.Context, campaignAddress solana.PublicKey) (*CampaignFunds, error) {
+	info, err := app.client.GetAccountInfoWithOpts(ctx, campaignAddress, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch campaign: %w", err)
+	}
+	rent, err := app.client.GetMinimumBalanceForRentExemption(ctx, uint64(len(info.Value.Data.GetBinary())), rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rent-exempt minimum: %w", err)
+	}
+	funds := &CampaignFunds{Balance: info.Value.Lamports, Rent: rent}
+	if funds.Balance > rent {
+		funds.Withdrawable = funds.Balance - rent
+	}
+	return funds, nil
+}
+
+// DonationAll is the most a wallet can give in one donation and what it keeps back
+type DonationAll struct {
+	Balance uint64 // lamports
+	Fee     uint64 // estimated fee, 0 when a sponsor pays it
+	Reserve uint64 // rent-exempt minimum of a wallet
+	Amount  uint64
+}
+
+// DonateAllAmount works out the most the wallet can donate to a campaign: its balance less the
+// donation's fee and the rent-exempt minimum of a wallet. Keeping that minimum leaves the wallet
+// open, and absorbs a rise in the priority fee before the donation lands, where a balance left
+// between zero and the minimum would fail the transaction.
+func (app *SolanaDApp) DonateAllAmount(ctx context.Context, campaignAddress solana.PublicKey, campaignName, memo string) (*DonationAll, error) {
+	donor := app.wallet.Address()
+	balance, err := app.client.GetBalance(ctx, donor, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	reserve, err := app.client.GetMinimumBalanceForRentExemption(ctx, 0, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rent-exempt minimum: %w", err)
+	}
+	all := &DonationAll{Balance: balance.Value, Reserve: reserve}
+
+	if app.payer().Equals(donor) {
+		donate, err := app.BuildInstruction("donate",
+			map[string]solana.PublicKey{"campaign": campaignAddress, "user": donor},
+			map[string]interface{}{"name": campaignName, "amount": uint64(0)},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build donate instruction: %w", err)
+		}
+		instructions := []solana.Instruction{donate}
+		if memo != "" {
+			instructions = append(instructions, memoInstruction(memo, donor))
+		}
+		all.Fee = app.estimateFee(ctx, instructions)
+	}
+
+	if all.Balance <= all.Fee+all.Reserve {
+		return nil, fmt.Errorf("%w: %s SOL doesn't cover the %s SOL fee and the %s SOL the wallet keeps for rent",
+			crowdfund.ErrInsufficientBalance, lamportsToSOL(all.Balance), lamportsToSOL(all.Fee), lamportsToSOL(all.Reserve))
+	}
+	all.Amount = all.Balance - all.Fee - all.Reserve
+	return all, nil
+}
+
+// WithdrawalPreview is what a withdrawal would do, found without sending it
+type WithdrawalPreview struct {
+	Amount        uint64 // lamports
+	Funds         CampaignFunds
+	CampaignAfter uint64 // unchanged when the withdrawal would be refused
+	AdminBefore   uint64
+	AdminAfter    uint64
+	Fee           uint64
+	FeePayer      solana.PublicKey
+	BelowRent     bool   // the campaign would be left under its rent-exempt minimum, so the program refuses
+	SimulationErr error  // why the simulated transaction failed, nil if it succeeded
+	UnitsConsumed uint64 // compute units the simulation used
+	Policy        *PolicyDecision
+	PolicyErr     error // the policy could not be read, which blocks every withdrawal
+}
+
+// PreviewWithdrawal simulates a withdrawal by the wallet and projects the balances it leaves,
+// without signing or sending anything
+func (app *SolanaDApp) PreviewWithdrawal(ctx context.Context, campaignAddress solana.PublicKey, campaignName string, amount uint64) (*WithdrawalPreview, error) {
+	admin := app.wallet.Address()
+	funds, err := app.CampaignFunds(ctx, campaignAddress)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := app.client.GetBalance(ctx, admin, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	preview := &WithdrawalPreview{Amount: amount, Funds: *funds, AdminBefore: balance.Value, FeePayer: app.payer()}
+
+	withdraw, err := app.BuildInstruction("withdraw",
+		map[string]solana.PublicKey{"campaign": campaignAddress, "user": admin},
+		map[string]interface{}{"name": campaignName, "amount": amount},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build withdraw instruction: %w", err)
+	}
+	instructions := []solana.Instruction{withdraw}
+	preview.Fee = app.estimateFee(ctx, instructions)
+
+	// A refused withdrawal still costs its fee
+	preview.BelowRent = amount > funds.Withdrawable
+	preview.CampaignAfter, preview.AdminAfter = funds.Balance, preview.AdminBefore
+	if !preview.BelowRent {
+		preview.CampaignAfter -= amount
+		preview.AdminAfter += amount
+	}
+	if preview.FeePayer.Equals(admin) {
+		preview.AdminAfter -= min(preview.Fee, preview.AdminAfter)
+	}
+
+	// The simulation runs unsigned, against the latest blockhash
+	recent, err := app.sender.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+	tx, err := solana.NewTransaction(app.withPriorityFee(ctx, instructions), recent.Value.Blockhash, solana.TransactionPayer(preview.FeePayer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	simulation, err := app.client.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		Commitment:             rpc.CommitmentConfirmed,
+		ReplaceRecentBlockhash: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate withdrawal: %w", err)
+	}
+	if simulation.Value.Err != nil {
+		preview.SimulationErr = app.idl.DecodeError(fmt.Errorf("%v", simulation.Value.Err))
+	}
+	if simulation.Value.UnitsConsumed != nil {
+		preview.UnitsConsumed = *simulation.Value.UnitsConsumed
+	}
+
+	policy, err := app.withdrawalPolicy()
+	if err != nil {
+		preview.PolicyErr = err
+	} else if policy != nil {
+		decision := policy.Check(PolicyWithdrawal{Campaign: campaignAddress, Destination: admin, Lamports: amount, Time: time.Now()})
+		preview.Policy = &decision
+	}
+	return preview, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SendOptions are the sendTransaction options. Unset fields fall back to the general
+// settings, then to the RPC node's defaults.
+type SendOptions struct {
+	SkipPreflight       *bool  `json:"skipPreflight,omitempty"`       // send without simulating first
+	MaxRetries          *uint  `json:"maxRetries,omitempty"`          // how often the node rebroadcasts; 0 leaves it to the client
+	PreflightCommitment string `json:"preflightCommitment,omitempty"` // processed, confirmed or finalized
+}
+
+// SendConfig configures how transactions are sent, in config.json, with overrides for
+// donations and withdrawals
+type SendConfig struct {
+	SendOptions
+	Donate   *SendOptions `json:"donate,omitempty"`
+	Withdraw *SendOptions `json:"withdraw,omitempty"`
+}
+
+// merge fills the unset options from fallback
+func (o SendOptions) merge(fallback SendOptions) SendOptions {
+	if o.SkipPreflight == nil {
+		o.SkipPreflight = fallback.SkipPreflight
+	}
+	if o.MaxRetries == nil {
+		o.MaxRetries = fallback.MaxRetries
+	}
+	if o.PreflightCommitment == "" {
+		o.PreflightCommitment = fallback.PreflightCommitment
+	}
+	return o
+}
+
+// validate checks the preflight commitment
+func (o SendOptions) validate() error {
+	switch rpc.CommitmentType(o.PreflightCommitment) {
+	case "", rpc.CommitmentProcessed, rpc.CommitmentConfirmed, rpc.CommitmentFinalized:
+		return nil
+	}
+	return fmt.Errorf("invalid preflight commitment %q: use processed, confirmed or finalized", o.PreflightCommitment)
+}
+
+// sendAction maps an operation name such as "schedule withdraw" to the action whose overrides apply
+func sendAction(operation string) string {
+	switch {
+	case strings.HasSuffix(operation, "donate"):
+		return "donate"
+	case strings.HasSuffix(operation, "withdraw"):
+		return "withdraw"
+	}
+	return operation
+}
+
+// sendOptions returns the options for sending a transaction of the operation
+func (app *SolanaDApp) sendOptions(operation string) rpc.TransactionOpts {
+	var opts SendOptions
+	if config := app.config.Send; config != nil {
+		opts = config.SendOptions
+		switch {
+		case sendAction(operation) == "donate" && config.Donate != nil:
+			opts = config.Donate.merge(opts)
+		case sendAction(operation) == "withdraw" && config.Withdraw != nil:
+			opts = config.Withdraw.merge(opts)
+		}
+	}
+
+	txOpts := rpc.TransactionOpts{
+		PreflightCommitment: rpc.CommitmentType(opts.PreflightCommitment),
+		MaxRetries:          opts.MaxRetries,
+	}
+	if opts.SkipPreflight != nil {
+		txOpts.SkipPreflight = *opts.SkipPreflight
+	}
+	return txOpts
+}
+
+// send sends a signed transaction of the operation through the send endpoint, with its options
+func (app *SolanaDApp) send(ctx context.Context, operation string, tx *solana.Transaction) (solana.Signature, error) {
+	if debugTx {
+		app.dumpTransaction(operation, tx)
+	}
+	return app.sender.SendTransactionWithOpts(ctx, tx, app.sendOptions(operation))
+}
+
+// addSendFlags registers flags that override the send options of config.json for one run.
+// The returned function applies them once the flags are parsed.
+func addSendFlags(fs *flag.FlagSet) func(*Config) error {
+	skipPreflight := fs.Bool("skip-preflight", false, "send without simulating first, for time-critical sends")
+	maxRetries := fs.Uint("max-retries", 0, "how often the RPC node rebroadcasts each transaction")
+	commitment := fs.String("preflight-commitment", "", "commitment preflight simulations run at: processed, confirmed or finalized")
+
+	return func(config *Config) error {
+		var flags SendOptions
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "skip-preflight":
+				flags.SkipPreflight = skipPreflight
+			case "max-retries":
+				flags.MaxRetries = maxRetries
+			case "preflight-commitment":
+				flags.PreflightCommitment = *commitment
+			}
+		})
+		if err := flags.validate(); err != nil {
+			return err
+		}
+
+		// Flags win over every setting of config.json, including the per-action ones
+		if config.Send == nil {
+			config.Send = &SendConfig{}
+		}
+		config.Send.SendOptions = flags.merge(config.Send.SendOptions)
+		for _, override := range []*SendOptions{config.Send.Donate, config.Send.Withdraw} {
+			if override != nil {
+				*override = flags.merge(*override)
+			}
+		}
+		return nil
+	}
+}
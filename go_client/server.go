@@ -0,0 +1,412 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ServerOptions configures the public read-only API
+type ServerOptions struct {
+	Listen     string
+	CacheTTL   time.Duration
+	RateLimit  float64 // requests per second per client IP
+	RateBurst  int
+	RateLimits *RateLimitConfig // global, per-route and per-API-key limits from config.json
+	TrustProxy bool             // take the client IP from X-Forwarded-For (only behind a trusted CDN/proxy)
+	ProxyHops  int              // trusted proxies in front of the server, each appending to X-Forwarded-For
+
+	TLS       bool
+	Domains   []string // Let's Encrypt certificates are obtained for these hosts
+	CertFile  string   // manual certificate instead of Let's Encrypt
+	KeyFile   string
+	ACMECache string // directory where issued certificates are kept across restarts
+	ACMEEmail string
+
+	Relay      RelayOptions // gasless donations, enabled by a relay wallet
+	RequireKey bool         // the JSON API and donation intents need an API key too, not just the relay and withdrawals
+
+	UI      bool   // serve the admin dashboard under /ui/
+	UIToken string // dashboard access token, generated when empty
+
+	CORSOrigins []string // origins whose pages may read the API, "*" for any
+	Gzip        bool     // compress text responses
+	AccessLog   string   // file JSON access log lines are appended to, "-" for stdout, empty for none
+}
+
+// Server exposes read-only campaign data over HTTP. It never loads a wallet, except the
+// relay's fee-paying one when the gasless donation relay is enabled.
+type Server struct {
+	app       *SolanaDApp
+	opts      ServerOptions
+	cache     responseCache
+	limiter   *rateLimiters
+	events    *EventHub
+	heartbeat *wsHeartbeat
+	relay     *relayer
+	dashboard *dashboard
+	accessLog *accessLog
+	redis     *redisCache // shared cache, invalidated when watched campaigns change
+	apiKeys   *apiKeyStore
+	siws      *siwsAuth // donors signed in with their wallet
+
+	watchMu sync.Mutex
+	watched map[string]bool // campaigns whose changes invalidate the Redis cache
+}
+
+// NewServer creates the public API server
+func NewServer(app *SolanaDApp, opts ServerOptions) *Server {
+	return &Server{
+		app:       app,
+		opts:      opts,
+		cache:     &memoryCache{entries: map[string]cachedResponse{}},
+		limiter:   newRateLimiters(opts),
+		events:    NewEventHub(app),
+		heartbeat: &wsHeartbeat{started: time.Now()},
+		apiKeys:   &apiKeyStore{},
+		siws:      newSIWSAuth(),
+	}
+}
+
+// Handler returns the HTTP handler with rate limiting and caching applied
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /campaigns", s.keyed(scopeRead, s.cached(s.handleCampaigns)))
+	mux.HandleFunc("GET /campaigns/{address}", s.keyed(scopeRead, s.cached(s.handleCampaign)))
+	mux.HandleFunc("GET /campaigns/{address}/donations", s.keyed(scopeRead, s.cached(s.handleDonations)))
+	mux.HandleFunc("GET /campaigns/{address}/comments", s.keyed(scopeRead, s.cached(s.handleComments)))
+	mux.HandleFunc("GET /campaigns/{address}/events", s.keyed(scopeRead, s.handleEvents))
+	mux.HandleFunc("GET /campaigns/{address}/feed.atom", s.handleFeed)
+	mux.HandleFunc("GET /campaigns/{address}/page", s.handleCampaignPage)
+	mux.HandleFunc("GET /c/{address}", s.handleSharePage)
+	mux.HandleFunc("GET /c/{address}/card.png", s.handleCampaignCard)
+	mux.HandleFunc("GET /stats", s.keyed(scopeRead, s.cached(s.handleStats)))
+	mux.HandleFunc("GET /badge/{file}", s.handleBadge)
+	mux.HandleFunc("GET /widget.js", s.handleWidgetScript)
+	mux.HandleFunc("POST /donation-intents", s.keyed(scopeDonate, s.handleDonationIntent))
+	mux.HandleFunc("GET /pay/{address}", s.handlePayLabel)
+	mux.HandleFunc("POST /pay/{address}", s.handlePayTransaction)
+	mux.HandleFunc("GET /ws", s.keyed(scopeRead, s.handleWebSocket))
+	mux.HandleFunc("POST /auth/siws/challenge", s.handleSIWSChallenge)
+	mux.HandleFunc("POST /auth/siws/verify", s.handleSIWSVerify)
+	mux.HandleFunc("GET /me/donations", s.handleMeDonations)
+	if s.relay != nil {
+		mux.HandleFunc("GET /relay", s.relay.handleInfo)
+		mux.HandleFunc("POST /relay/donations", s.requireScope(scopeDonate, s.relay.handlePrepare))
+		mux.HandleFunc("POST /relay/submit", s.requireScope(scopeDonate, s.relay.handleSubmit))
+	}
+	if s.dashboard != nil {
+		s.dashboard.register(mux, s.apiKeys)
+	}
+
+	// Probes bypass rate limiting so the orchestrator is never throttled
+	root := http.NewServeMux()
+	root.HandleFunc("GET /healthz", s.handleHealthz)
+	root.HandleFunc("GET /readyz", s.handleReadyz)
+	s.limiter.matcher = mux
+	root.Handle("/", s.rateLimited(mux))
+	var handler http.Handler = root
+	if s.opts.Gzip {
+		handler = s.gzipResponses(handler)
+	}
+	return requestIDs(s.logAccess(recoverPanics(s.securityHeaders(s.cors(handler)))))
+}
+
+// ListenAndServe runs the server until it fails, over HTTPS when TLS is enabled
+func (s *Server) ListenAndServe() error {
+	go s.heartbeat.run(s.app)
+
+	server := &http.Server{Addr: s.opts.Listen, Handler: s.Handler()}
+
+	if !s.opts.TLS {
+		fmt.Printf("🌐 Public read-only API listening on %s\n", s.opts.Listen)
+		return server.ListenAndServe()
+	}
+
+	if s.opts.CertFile != "" {
+		fmt.Printf("🔒 Public read-only API listening on %s (certificate %s)\n", s.opts.Listen, s.opts.CertFile)
+		return server.ListenAndServeTLS(s.opts.CertFile, s.opts.KeyFile)
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(s.opts.Domains...),
+		Cache:      autocert.DirCache(s.opts.ACMECache),
+		Email:      s.opts.ACMEEmail,
+	}
+	server.TLSConfig = manager.TLSConfig()
+
+	// Port 80 answers HTTP-01 challenges and redirects everything else to HTTPS
+	go func() {
+		if err := http.ListenAndServe(":80", manager.HTTPHandler(nil)); err != nil {
+			log.Printf("ACME HTTP challenge listener stopped: %v", err)
+		}
+	}()
+
+	fmt.Printf("🔒 Public read-only API listening on %s for %s (Let's Encrypt)\n", s.opts.Listen, strings.Join(s.opts.Domains, ", "))
+	return server.ListenAndServeTLS("", "")
+}
+
+// baseURL is the scheme and host the request was made to, for absolute links in responses
+func (s *Server) baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || (s.opts.TrustProxy && r.Header.Get("X-Forwarded-Proto") == "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// apiError is an error carrying the HTTP status to report
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func (s *Server) handleCampaigns(r *http.Request) (interface{}, error) {
+	campaigns, err := s.app.ListCampaigns()
+	if err != nil {
+		return nil, err
+	}
+	records, _ := loadCampaignRecords()
+	for i := range campaigns {
+		records.annotate(&campaigns[i])
+	}
+	return campaigns, nil
+}
+
+func (s *Server) handleCampaign(r *http.Request) (interface{}, error) {
+	address, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid campaign address"}
+	}
+
+	campaign, err := s.app.FetchCampaign(address)
+	if err != nil {
+		if errors.Is(err, crowdfund.ErrCampaignNotFound) {
+			return nil, &apiError{http.StatusNotFound, "campaign not found"}
+		}
+		if errors.Is(err, crowdfund.ErrNotACampaignAccount) {
+			return nil, &apiError{http.StatusNotFound, "not a campaign account"}
+		}
+		return nil, err
+	}
+	balance, err := s.app.client.GetBalance(r.Context(), address, "")
+	if err != nil {
+		return nil, err
+	}
+
+	account := CampaignAccount{Address: address, Lamports: balance.Value, Campaign: campaign}
+	records, _ := loadCampaignRecords()
+	records.annotate(&account)
+	return account, nil
+}
+
+func (s *Server) handleDonations(r *http.Request) (interface{}, error) {
+	address, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid campaign address"}
+	}
+
+	activity, err := s.app.GetCampaignActivity(address, 25)
+	if err != nil {
+		return nil, err
+	}
+
+	donations := []CampaignActivity{}
+	for _, entry := range activity {
+		if entry.Kind == "donate" && !entry.Failed {
+			donations = append(donations, entry)
+		}
+	}
+	return donations, nil
+}
+
+func (s *Server) handleComments(r *http.Request) (interface{}, error) {
+	address, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "invalid campaign address"}
+	}
+	return s.app.GetCampaignComments(address, 100)
+}
+
+func (s *Server) handleStats(r *http.Request) (interface{}, error) {
+	campaigns, err := s.app.ListCampaignSummaries()
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeCampaigns(campaigns), nil
+}
+
+// handleEvents streams live campaign activity as server-sent events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	address, err := solana.PublicKeyFromBase58(r.PathValue("address"))
+	if err != nil {
+		http.Error(w, "invalid campaign address", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.app.FetchCampaign(address); err != nil {
+		http.Error(w, "not a campaign account", http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe, err := s.events.Subscribe(address)
+	if err != nil {
+		logf(r.Context(), "SSE subscribe failed for %s: %v", address.String(), err)
+		http.Error(w, "live updates unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx-style proxies from buffering the stream
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(25 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return // Subscription failed; the client will reconnect
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Activity.Signature, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// cached wraps a JSON handler with an in-memory response cache and CDN-friendly Cache-Control headers
+func (s *Server) cached(handler func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(s.opts.CacheTTL.Seconds()))
+	if s.opts.RequireKey {
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(s.opts.CacheTTL.Seconds())) // a CDN must not hand keyed responses to anyone
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if body, ok := s.cache.get(r.URL.Path); ok {
+			w.Header().Set("Cache-Control", cacheControl)
+			w.Header().Set("X-Cache", "HIT")
+			w.Write(body)
+			return
+		}
+
+		result, err := handler(r)
+		if err != nil {
+			status := http.StatusBadGateway
+			var apiErr *apiError
+			if errors.As(err, &apiErr) {
+				status = apiErr.status
+			} else {
+				logf(r.Context(), "API error on %s: %v", r.URL.Path, err)
+				err = errors.New("upstream RPC request failed")
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error(), "requestId": requestIDFrom(r.Context())})
+			return
+		}
+
+		body, err := json.Marshal(result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.cache.put(r.URL.Path, body, s.opts.CacheTTL)
+		s.watchForChanges(r.PathValue("address"))
+
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("X-Cache", "MISS")
+		w.Write(body)
+	}
+}
+
+// clientIP returns the caller's IP, honouring X-Forwarded-For only when configured to
+func (s *Server) clientIP(r *http.Request) string {
+	if s.opts.TrustProxy {
+		// Clients can send X-Forwarded-For themselves, so only the addresses appended by our own
+		// proxies count: the one the outermost proxy saw sits ProxyHops from the right
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+		if len(hops) > 0 {
+			return hops[max(len(hops)-max(s.opts.ProxyHops, 1), 0)]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// cachedResponse is a serialized response and its expiry
+type cachedResponse struct {
+	body    []byte
+	expires time.Time
+}
+
+// responseCache holds serialized responses keyed by path
+type responseCache interface {
+	get(key string) ([]byte, bool)
+	put(key string, body []byte, ttl time.Duration)
+}
+
+// memoryCache is the default responseCache, private to this process
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+func (c *memoryCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.body, true
+}
+
+func (c *memoryCache) put(key string, body []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedResponse{body: body, expires: time.Now().Add(ttl)}
+}
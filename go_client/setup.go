@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"golang.org/x/term"
+)
+
+// SetupResult is what the setup wizard decided
+type SetupResult struct {
+	Wallet  string // key file to load
+	Airdrop bool   // request an airdrop once connected
+}
+
+// needsSetup reports whether this is a first run the wizard should greet: no config.json, and
+// someone at a terminal to answer
+func needsSetup() bool {
+	if _, err := os.Stat(configFile); !os.IsNotExist(err) {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// setupPrompt asks a question, returning the default for an empty answer
+func setupPrompt(reader *bufio.Reader, question, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, _ := reader.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return defaultValue
+	}
+	return answer
+}
+
+// setupConfirm asks a yes/no question
+func setupConfirm(reader *bufio.Reader, question string, defaultYes bool) bool {
+	defaultValue := "y/N"
+	if defaultYes {
+		defaultValue = "Y/n"
+	}
+	switch strings.ToLower(setupPrompt(reader, question, defaultValue)) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return defaultYes
+}
+
+// RunSetupWizard walks through choosing a cluster, creating or importing a wallet, and the
+// priority fee, then writes config.json. Settings already in config.json are kept.
+func RunSetupWizard(reader *bufio.Reader) (*SetupResult, error) {
+	fmt.Println("👋 Welcome! Let's set up the client. Press Enter to accept the [default].")
+
+	settings := map[string]interface{}{}
+	if data, err := os.ReadFile(configFile); err == nil {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", configFile, err)
+		}
+	}
+
+	// Cluster
+	fmt.Println("\n🌐 Cluster:")
+	fmt.Println("1. devnet (free test SOL from airdrops)")
+	fmt.Println("2. testnet")
+	fmt.Println("3. mainnet-beta (real SOL)")
+	fmt.Println("4. localnet (solana-test-validator)")
+	fmt.Println("5. custom RPC endpoint")
+	cluster := rpc.DevNet.Name
+	delete(settings, "rpcUrl")
+	switch setupPrompt(reader, "Choose", "1") {
+	case "2":
+		cluster = rpc.TestNet.Name
+	case "3":
+		cluster = rpc.MainNetBeta.Name
+	case "4":
+		cluster = rpc.LocalNet.Name
+	case "5":
+		cluster = setupPrompt(reader, "Cluster it belongs to (devnet, testnet, mainnet-beta, localnet)", rpc.DevNet.Name)
+		if _, ok := clusters[cluster]; !ok {
+			return nil, fmt.Errorf("unknown cluster %q", cluster)
+		}
+		endpoint := setupPrompt(reader, "RPC URL", "")
+		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			return nil, fmt.Errorf("the RPC URL must start with http:// or https://")
+		}
+		settings["rpcUrl"] = endpoint
+	}
+	settings["cluster"] = cluster
+
+	// Wallet
+	fmt.Println("\n💳 Wallet:")
+	fmt.Println("1. Create a new wallet")
+	fmt.Println("2. Import a key file (solana-keygen's id.json or this client's wallet.json)")
+	fmt.Println("3. Import a base58 secret key (e.g. exported from Phantom)")
+	var privateKey ed25519.PrivateKey
+	var keyFile []byte
+	var importedPath string
+	switch setupPrompt(reader, "Choose", "1") {
+	case "1":
+		_, privateKey, _ = ed25519.GenerateKey(nil)
+	case "2":
+		importedPath = setupPrompt(reader, "Key file", "")
+		data, err := os.ReadFile(importedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		if privateKey, err = parseKeyFile(data); err != nil {
+			return nil, err
+		}
+		keyFile = data
+	case "3":
+		fmt.Print("Secret key (hidden): ")
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret key: %w", err)
+		}
+		key, err := solana.PrivateKeyFromBase58(strings.TrimSpace(string(secret)))
+		if err != nil || len(key) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid base58 secret key")
+		}
+		privateKey = ed25519.PrivateKey(key)
+	default:
+		return nil, fmt.Errorf("unknown choice")
+	}
+	wallet := &Wallet{PublicKey: solana.PublicKeyFromBytes(privateKey.Public().(ed25519.PublicKey)), PrivateKey: privateKey}
+	if keyFile == nil {
+		keyFile, _ = json.Marshal(WalletData{PublicKey: wallet.PublicKey.String(), PrivateKey: solana.PrivateKey(privateKey).String()})
+	}
+
+	walletPath := importedPath
+	if setupConfirm(reader, "🔐 Encrypt the wallet with a passphrase?", true) {
+		passphrase, err := readNewPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		data, err := EncryptKeystore(wallet, keyFile, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		walletPath = setupFreeFile("wallet.keystore.json")
+		if err := os.WriteFile(walletPath, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", walletPath, err)
+		}
+		if importedPath != "" {
+			fmt.Printf("⚠️  %s still holds the key unencrypted: delete it once the keystore works\n", importedPath)
+		}
+	} else if walletPath == "" {
+		walletPath = setupFreeFile("wallet.json")
+		if err := os.WriteFile(walletPath, keyFile, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", walletPath, err)
+		}
+	}
+	settings["wallet"] = walletPath
+	fmt.Printf("✅ Wallet %s saved to %s\n", wallet.PublicKey, walletPath)
+
+	// Priority fee
+	fmt.Println("\n⛽ Priority fee, which speeds up transactions when the network is busy:")
+	fmt.Println("1. None")
+	fmt.Println("2. Match recent fees (75th percentile)")
+	fmt.Println("3. A fixed price")
+	switch setupPrompt(reader, "Choose", "1") {
+	case "1":
+		delete(settings, "priorityFee")
+	case "2":
+		settings["priorityFee"] = PriorityFeeConfig{Percentile: defaultPriorityFeePercentile}
+	case "3":
+		price, err := strconv.ParseUint(setupPrompt(reader, "Micro-lamports per compute unit", "1000"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price: %w", err)
+		}
+		settings["priorityFee"] = PriorityFeeConfig{Min: price, Max: price}
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", configFile, err)
+	}
+	fmt.Printf("\n💾 Settings saved to %s; rerun `setup` or edit it to change them\n", configFile)
+
+	result := &SetupResult{Wallet: walletPath}
+	if cluster != rpc.MainNetBeta.Name {
+		result.Airdrop = setupConfirm(reader, "🚰 Request an airdrop of test SOL now?", true)
+	}
+	return result, nil
+}
+
+// setupFreeFile returns name, or a numbered variant when name already exists, so no key is overwritten
+func setupFreeFile(name string) string {
+	base := strings.TrimSuffix(name, ".json")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d.json", base, i)
+	}
+}
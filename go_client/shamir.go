@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/mr-tron/base58"
+	"github.com/skip2/go-qrcode"
+)
+
+// shardPrefix starts every printed wallet shard
+const shardPrefix = "crowdfunding-shard"
+
+// gf256Exp and gf256Log are exponent and logarithm tables of GF(2^8) with the AES polynomial,
+// the field Shamir's scheme is evaluated in byte by byte
+var gf256Exp, gf256Log = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		// Multiply by the generator 3
+		x ^= x<<1 ^ byte(int8(x)>>7)&0x1b
+	}
+	return exp, log
+}()
+
+func gf256Mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gf256Exp[int(gf256Log[a])+int(gf256Log[b])]
+}
+
+func gf256Div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gf256Exp[int(gf256Log[a])+255-int(gf256Log[b])]
+}
+
+// splitSecret splits secret into shares of which any threshold recover it. Share i is the
+// secret's random polynomials evaluated at x = i+1.
+func splitSecret(secret []byte, shares, threshold int) ([][]byte, error) {
+	if threshold < 2 || threshold > shares || shares > 255 {
+		return nil, fmt.Errorf("need 2 <= threshold <= shares <= 255")
+	}
+
+	result := make([][]byte, shares)
+	for i := range result {
+		result[i] = make([]byte, len(secret))
+	}
+	coefficients := make([]byte, threshold)
+	for b, s := range secret {
+		coefficients[0] = s
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("failed to generate randomness: %w", err)
+		}
+		for i := range result {
+			x := byte(i + 1)
+			// Horner's rule
+			y := byte(0)
+			for c := threshold - 1; c >= 0; c-- {
+				y = gf256Mul(y, x) ^ coefficients[c]
+			}
+			result[i][b] = y
+		}
+	}
+	return result, nil
+}
+
+// combineShares recovers the secret from shares by Lagrange interpolation at x = 0
+func combineShares(xs []byte, shares [][]byte) []byte {
+	secret := make([]byte, len(shares[0]))
+	for i, xi := range xs {
+		// Lagrange basis polynomial of share i at 0; subtraction is XOR in GF(2^8)
+		basis := byte(1)
+		for j, xj := range xs {
+			if i != j {
+				basis = gf256Mul(basis, gf256Div(xj, xi^xj))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= gf256Mul(shares[i][b], basis)
+		}
+	}
+	return secret
+}
+
+// WalletShard is one Shamir share of a wallet's private key
+type WalletShard struct {
+	Threshold int
+	Index     byte
+	Wallet    solana.PublicKey
+	Share     []byte
+}
+
+// String encodes the shard for printing or a QR code, with a checksum against typos:
+// crowdfunding-shard:<threshold>:<index>:<wallet>:<share and checksum in base58>
+func (s WalletShard) String() string {
+	body := fmt.Sprintf("%s:%d:%d:%s:", shardPrefix, s.Threshold, s.Index, s.Wallet)
+	sum := sha256.Sum256(append([]byte(body), s.Share...))
+	return body + base58.Encode(append(append([]byte(nil), s.Share...), sum[:4]...))
+}
+
+// Save writes the shard to base.txt and as a QR code to base.png, readable only by the owner
+func (s WalletShard) Save(base string) error {
+	if err := os.WriteFile(base+".txt", []byte(s.String()+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write shard: %w", err)
+	}
+	qr, err := qrcode.New(s.String(), qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	png, err := qr.PNG(512)
+	if err != nil {
+		return fmt.Errorf("failed to render QR code: %w", err)
+	}
+	if err := os.WriteFile(base+".png", png, 0600); err != nil {
+		return fmt.Errorf("failed to write QR code image: %w", err)
+	}
+	return nil
+}
+
+// ParseWalletShard decodes a shard printed by String
+func ParseWalletShard(text string) (WalletShard, error) {
+	parts := strings.Split(strings.TrimSpace(text), ":")
+	if len(parts) != 5 || parts[0] != shardPrefix {
+		return WalletShard{}, fmt.Errorf("not a wallet shard")
+	}
+	threshold, err := strconv.Atoi(parts[1])
+	if err != nil || threshold < 2 {
+		return WalletShard{}, fmt.Errorf("invalid shard threshold %q", parts[1])
+	}
+	index, err := strconv.ParseUint(parts[2], 10, 8)
+	if err != nil || index == 0 {
+		return WalletShard{}, fmt.Errorf("invalid shard index %q", parts[2])
+	}
+	wallet, err := solana.PublicKeyFromBase58(parts[3])
+	if err != nil {
+		return WalletShard{}, fmt.Errorf("invalid shard wallet: %w", err)
+	}
+	data, err := base58.Decode(parts[4])
+	if err != nil || len(data) <= 4 {
+		return WalletShard{}, fmt.Errorf("invalid shard data")
+	}
+
+	shard := WalletShard{Threshold: threshold, Index: byte(index), Wallet: wallet, Share: data[:len(data)-4]}
+	body := strings.Join(parts[:4], ":") + ":"
+	sum := sha256.Sum256(append([]byte(body), shard.Share...))
+	if !bytes.Equal(sum[:4], data[len(data)-4:]) {
+		return WalletShard{}, fmt.Errorf("shard %d checksum mismatch: check for typos", index)
+	}
+	return shard, nil
+}
+
+// ShardWallet splits a wallet's private key seed into shares, any threshold of which restore it
+func ShardWallet(wallet *Wallet, shares, threshold int) ([]WalletShard, error) {
+	key, err := wallet.signingKey()
+	if err != nil {
+		return nil, err
+	}
+	parts, err := splitSecret(ed25519.PrivateKey(key).Seed(), shares, threshold)
+	if err != nil {
+		return nil, err
+	}
+	shards := make([]WalletShard, len(parts))
+	for i, part := range parts {
+		shards[i] = WalletShard{Threshold: threshold, Index: byte(i + 1), Wallet: wallet.PublicKey, Share: part}
+	}
+	return shards, nil
+}
+
+// RestoreWalletFromShards combines enough shards of one wallet and checks that the recovered
+// key belongs to it
+func RestoreWalletFromShards(shards []WalletShard) (*Wallet, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("no shards given")
+	}
+	first := shards[0]
+	seen := map[byte]bool{}
+	var xs []byte
+	var shares [][]byte
+	for _, shard := range shards {
+		if !shard.Wallet.Equals(first.Wallet) || shard.Threshold != first.Threshold || len(shard.Share) != ed25519.SeedSize {
+			return nil, fmt.Errorf("shard %d does not belong to the same set as shard %d", shard.Index, first.Index)
+		}
+		if seen[shard.Index] {
+			continue
+		}
+		seen[shard.Index] = true
+		xs = append(xs, shard.Index)
+		shares = append(shares, shard.Share)
+	}
+	if len(xs) < first.Threshold {
+		return nil, fmt.Errorf("%d distinct shard(s) given, %d needed", len(xs), first.Threshold)
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(combineShares(xs[:first.Threshold], shares[:first.Threshold]))
+	publicKey := solana.PublicKeyFromBytes(privateKey.Public().(ed25519.PublicKey))
+	if !publicKey.Equals(first.Wallet) {
+		return nil, fmt.Errorf("the shards do not restore wallet %s: one of them is corrupt", first.Wallet)
+	}
+	return &Wallet{PublicKey: publicKey, PrivateKey: privateKey}, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"math/bits"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// subsets calls f with the indexes of every non-empty subset of n items
+func subsets(n int, f func(indexes []int)) {
+	for mask := 1; mask < 1<<n; mask++ {
+		indexes := make([]int, 0, bits.OnesCount(uint(mask)))
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				indexes = append(indexes, i)
+			}
+		}
+		f(indexes)
+	}
+}
+
+// testWallet is a wallet with a fresh random key
+func testWallet() *Wallet {
+	key := solana.NewWallet().PrivateKey
+	return &Wallet{PublicKey: key.PublicKey(), PrivateKey: ed25519.PrivateKey(key)}
+}
+
+func TestSplitSecretEverySubset(t *testing.T) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	for _, scheme := range []struct{ threshold, shares int }{{2, 2}, {2, 3}, {3, 5}, {4, 6}, {5, 5}} {
+		shares, err := splitSecret(secret, scheme.shares, scheme.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subsets(scheme.shares, func(indexes []int) {
+			var xs []byte
+			var picked [][]byte
+			for _, i := range indexes {
+				xs = append(xs, byte(i+1))
+				picked = append(picked, shares[i])
+			}
+			recovered := bytes.Equal(combineShares(xs, picked), secret)
+			if len(indexes) >= scheme.threshold && !recovered {
+				t.Errorf("%d-of-%d: shares %v don't recover the secret", scheme.threshold, scheme.shares, xs)
+			}
+			if len(indexes) < scheme.threshold && recovered {
+				t.Errorf("%d-of-%d: only %d shares %v recover the secret", scheme.threshold, scheme.shares, len(xs), xs)
+			}
+		})
+	}
+}
+
+func TestSplitSecretBounds(t *testing.T) {
+	for _, scheme := range []struct{ threshold, shares int }{{1, 3}, {4, 3}, {2, 256}} {
+		if _, err := splitSecret([]byte("secret"), scheme.shares, scheme.threshold); err == nil {
+			t.Errorf("splitSecret(%d of %d) succeeded", scheme.threshold, scheme.shares)
+		}
+	}
+}
+
+func TestRestoreWalletFromEveryShardSubset(t *testing.T) {
+	wallet := testWallet()
+	shards, err := ShardWallet(wallet, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subsets(len(shards), func(indexes []int) {
+		var picked []WalletShard
+		for _, i := range indexes {
+			// Through the printed form, as shards are restored from paper or QR codes
+			parsed, err := ParseWalletShard(shards[i].String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			picked = append(picked, parsed)
+		}
+		restored, err := RestoreWalletFromShards(picked)
+		if len(indexes) < 3 {
+			if err == nil {
+				t.Errorf("%d shards restored a 3-of-5 wallet", len(indexes))
+			}
+			return
+		}
+		if err != nil {
+			t.Errorf("shards %v: %v", indexes, err)
+			return
+		}
+		if !restored.PublicKey.Equals(wallet.PublicKey) || !bytes.Equal(restored.PrivateKey, wallet.PrivateKey) {
+			t.Errorf("shards %v restored another key", indexes)
+		}
+	})
+}
+
+func TestParseWalletShardChecksum(t *testing.T) {
+	shards, err := ShardWallet(testWallet(), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := shards[0].String()
+
+	// A typo in the share data
+	data := text[strings.LastIndex(text, ":")+1:]
+	replacement := "2"
+	if data[len(data)/2] == '2' {
+		replacement = "3"
+	}
+	typo := text[:len(text)-len(data)] + data[:len(data)/2] + replacement + data[len(data)/2+1:]
+	if _, err := ParseWalletShard(typo); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("shard with a typo: error = %v, want a checksum mismatch", err)
+	}
+
+	// The checksum covers the header too: another index is caught
+	relabeled := strings.Replace(text, ":2:1:", ":2:2:", 1)
+	if _, err := ParseWalletShard(relabeled); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("relabeled shard: error = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestRestoreWalletFromCorruptShard(t *testing.T) {
+	wallet := testWallet()
+	shards, err := ShardWallet(wallet, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A share corrupted before it was printed passes its checksum; the key check still catches it
+	corrupt := shards[1]
+	corrupt.Share = append([]byte(nil), corrupt.Share...)
+	corrupt.Share[0] ^= 0x01
+	parsed, err := ParseWalletShard(corrupt.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestoreWalletFromShards([]WalletShard{shards[0], parsed}); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("corrupt shard: error = %v, want the wallet check to fail", err)
+	}
+}
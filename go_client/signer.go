@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+)
+
+// Signer is the wallet a SolanaDApp acts for. *Wallet implements it for key files, encrypted
+// keystores and FROST groups.
+type Signer = crowdfund.Signer
+
+// Address implements Signer
+func (w *Wallet) Address() solana.PublicKey {
+	return w.PublicKey
+}
+
+// SignMessage implements Signer, unlocking a keystore or asking the FROST participants as needed
+func (w *Wallet) SignMessage(message []byte) (solana.Signature, error) {
+	if w.frost != nil {
+		fmt.Printf("🔏 Requesting %d-of-%d threshold signature; approve it on the participants' machines\n", w.frost.Threshold, len(w.frost.Participants))
+		signature, err := w.frost.Sign(message)
+		if err != nil {
+			return solana.Signature{}, err
+		}
+		return solana.SignatureFromBytes(signature), nil
+	}
+	key, err := w.signingKey()
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	return key.Sign(message)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/skip2/go-qrcode"
+)
+
+// siteDonations is how many recent donations the static page lists
+const siteDonations = 10
+
+// SiteConfig is a static campaign page the daemon keeps up to date
+type SiteConfig struct {
+	Campaign string `json:"campaign"`
+	Out      string `json:"out,omitempty"`      // directory index.html is written to, default public
+	URL      string `json:"url,omitempty"`      // where the page is published, used in share links
+	Goal     string `json:"goal,omitempty"`     // SOL shown as a progress bar; default the goal in lifecycle.json
+	Interval string `json:"interval,omitempty"` // how often the daemon regenerates it, default 15m
+	Server   string `json:"server,omitempty"`   // `serve` instance building the donations of the page's Solana Pay link
+	Amount   string `json:"amount,omitempty"`   // SOL the link asks for with a server, default 0.1
+
+	campaign solana.PublicKey
+	goal     uint64
+	amount   uint64
+	interval time.Duration
+}
+
+// prepare validates the settings and fills in defaults
+func (c *SiteConfig) prepare() error {
+	campaign, err := solana.PublicKeyFromBase58(c.Campaign)
+	if err != nil {
+		return fmt.Errorf("invalid campaign address %q: %w", c.Campaign, err)
+	}
+	c.campaign = campaign
+	if c.Out == "" {
+		c.Out = "public"
+	}
+	if c.Goal != "" {
+		if c.goal, err = parseSOL(c.Goal); err != nil {
+			return fmt.Errorf("sites: %w", err)
+		}
+	}
+	if c.Server != "" {
+		if c.Server, err = parseServerURL(c.Server); err != nil {
+			return fmt.Errorf("sites: %w", err)
+		}
+	}
+	if c.Amount != "" {
+		if c.amount, err = parseSOL(c.Amount); err != nil {
+			return fmt.Errorf("sites: %w", err)
+		}
+	}
+	c.interval = 15 * time.Minute
+	if c.Interval != "" {
+		if c.interval, err = time.ParseDuration(c.Interval); err != nil || c.interval < time.Minute {
+			return fmt.Errorf("sites: interval must be a duration of at least 1m, got %q", c.Interval)
+		}
+	}
+	return nil
+}
+
+// siteTemplate is the self-contained static page of one campaign: styles and QR code are inline,
+// so the directory can be published as is on GitHub Pages or any static host
+var siteTemplate = template.Must(template.New("site").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<meta name="description" content="{{.Summary}}">
+<style>
+body { font-family: system-ui, sans-serif; max-width: 42rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #222; }
+.raised { font-size: 1.5rem; font-weight: 600; margin-bottom: .3rem; }
+.bar { height: 1rem; border-radius: .5rem; background: #e6e6e6; overflow: hidden; }
+.bar div { height: 100%; background: #9945ff; }
+.meta { color: #666; font-size: .9rem; }
+.donate { display: flex; gap: 1.5rem; align-items: center; flex-wrap: wrap; border: 1px solid #ddd; border-radius: .5rem; padding: 1rem; margin: 1.5rem 0; }
+.donate svg { width: 12rem; height: 12rem; }
+.button { display: inline-block; background: #9945ff; color: #fff; padding: .6rem 1.2rem; border-radius: .4rem; text-decoration: none; font-weight: 600; }
+.share a { margin-right: .8rem; }
+.description { border-top: 1px solid #ddd; margin-top: 1.5rem; padding-top: 1rem; }
+table { width: 100%; border-collapse: collapse; font-size: .9rem; }
+td { padding: .35rem .25rem; border-bottom: 1px solid #eee; vertical-align: top; }
+td.amount { text-align: right; white-space: nowrap; font-variant-numeric: tabular-nums; }
+pre { background: #f4f4f4; padding: .75rem; overflow-x: auto; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1rem; color: #555; }
+code { word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p class="raised">{{.Raised}} SOL raised{{if .Goal}} of {{.Goal}} SOL{{end}}</p>
+{{if .Goal}}<div class="bar"><div style="width: {{.Percent}}%"></div></div>{{end}}
+<p class="meta">{{if .Deadline}}Donations close {{.Deadline}} · {{end}}Updated {{.Updated}}</p>
+
+<div class="donate">
+{{.QR}}
+<div>
+<p><strong>Scan to donate</strong> with Phantom, Backpack or Solflare, or open the link on your phone.</p>
+<p><a class="button" href="{{.PayURL}}">Donate with a Solana wallet</a></p>
+<p class="share">Share:
+<a href="{{.Share.X}}" data-share="x">X</a>
+<a href="{{.Share.Facebook}}" data-share="facebook">Facebook</a>
+<a href="{{.Share.Telegram}}" data-share="telegram">Telegram</a>
+<a href="{{.Share.WhatsApp}}" data-share="whatsapp">WhatsApp</a>
+<a href="{{.Share.Email}}" data-share="email">Email</a></p>
+</div>
+</div>
+
+<h2>Recent donations</h2>
+{{if .Donations}}<table>
+{{range .Donations}}<tr><td>{{.Time}}</td><td><code>{{.Wallet}}</code>{{if .Memo}}<br>{{.Memo}}{{end}}</td><td class="amount">{{.Amount}} SOL</td></tr>
+{{end}}</table>{{else}}<p class="meta">No donations yet. Be the first!</p>{{end}}
+
+<div class="description">
+{{.Description}}
+</div>
+<p class="meta">Campaign <code>{{.Address}}</code> · <a href="{{.ExplorerURL}}">explorer</a></p>
+{{if not .PageURL}}<script>
+// Published without a URL: share the address the page is opened at
+document.querySelectorAll("[data-share]").forEach((a) => { a.href = a.href.split("__PAGE__").join(encodeURIComponent(location.href)); });
+</script>{{end}}
+</body>
+</html>
+`))
+
+// sitePage is the data of a static campaign page
+type sitePage struct {
+	Name, Summary, Address, ExplorerURL string
+	Raised, Goal, Deadline, Updated     string
+	Percent                             int
+	PayURL                              template.URL
+	PageURL                             string
+	Share                               siteShareLinks
+	QR                                  template.HTML
+	Donations                           []siteDonation
+	Description                         template.HTML
+}
+
+// siteDonation is a row of the recent donations table
+type siteDonation struct {
+	Time, Wallet, Amount, Memo string
+}
+
+// siteShareLinks are the social share links of a page
+type siteShareLinks struct {
+	X, Facebook, Telegram, WhatsApp, Email template.URL
+}
+
+// siteSharePlaceholder stands for the page URL in share links when it is only known in the browser
+const siteSharePlaceholder = "__PAGE__"
+
+// newSiteShareLinks builds share links for a page; pageURL may be the placeholder
+func newSiteShareLinks(pageURL, text string) siteShareLinks {
+	page := url.QueryEscape(pageURL)
+	if pageURL == siteSharePlaceholder {
+		page = siteSharePlaceholder
+	}
+	escaped := url.QueryEscape(text)
+	return siteShareLinks{
+		X:        template.URL("https://twitter.com/intent/tweet?text=" + escaped + "&url=" + page),
+		Facebook: template.URL("https://www.facebook.com/sharer/sharer.php?u=" + page),
+		Telegram: template.URL("https://t.me/share/url?url=" + page + "&text=" + escaped),
+		WhatsApp: template.URL("https://wa.me/?text=" + escaped + "%20" + page),
+		Email:    template.URL("mailto:?subject=" + url.PathEscape(text) + "&body=" + page),
+	}
+}
+
+// qrSVG renders content as an inline SVG QR code
+func qrSVG(content string) (template.HTML, error) {
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	bitmap := qr.Bitmap()
+	var out strings.Builder
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" role="img" aria-label="Solana Pay QR code"><rect width="100%%" height="100%%" fill="#fff"/><path fill="#111" shape-rendering="crispEdges" d="`, len(bitmap), len(bitmap))
+	for row, modules := range bitmap {
+		for col, dark := range modules {
+			if dark {
+				fmt.Fprintf(&out, "M%d %dh1v1h-1z", col, row)
+			}
+		}
+	}
+	out.WriteString(`"/></svg>`)
+	return template.HTML(out.String()), nil
+}
+
+// GenerateSite renders a campaign's static page to index.html in the site's directory and
+// returns the file written
+func (app *SolanaDApp) GenerateSite(ctx context.Context, site *SiteConfig) (string, error) {
+	campaign, err := app.FetchCampaign(site.campaign)
+	if err != nil {
+		return "", err
+	}
+	activity, err := app.GetCampaignActivity(site.campaign, 50)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch donations: %w", err)
+	}
+	records, _ := loadCampaignRecords()
+	record := records.get(site.campaign)
+
+	goal := site.goal
+	if goal == 0 && record != nil {
+		goal = record.Goal
+	}
+	summary := fmt.Sprintf("%s SOL raised for %s", lamportsToSOL(campaign.AmountDonated), campaign.Name)
+	if goal > 0 {
+		summary = fmt.Sprintf("%s SOL of %s SOL raised for %s", lamportsToSOL(campaign.AmountDonated), lamportsToSOL(goal), campaign.Name)
+	}
+
+	// One reference per generation lets donations from the page be told apart from other links
+	payURL := donationLink(site.Server, site.campaign, campaign.Name, site.amount, solana.NewWallet().PublicKey())
+	qr, err := qrSVG(payURL)
+	if err != nil {
+		return "", err
+	}
+	pageURL := site.URL
+	if pageURL == "" {
+		pageURL = siteSharePlaceholder
+	}
+
+	page := sitePage{
+		Name:        campaign.Name,
+		Summary:     summary,
+		Address:     site.campaign.String(),
+		ExplorerURL: app.AddressURL(site.campaign.String()),
+		Raised:      lamportsToSOL(campaign.AmountDonated),
+		Updated:     formatTime(time.Now()),
+		PayURL:      template.URL(payURL),
+		PageURL:     site.URL,
+		Share:       newSiteShareLinks(pageURL, "Support "+campaign.Name+" on Solana"),
+		QR:          qr,
+		Description: template.HTML(MarkdownToHTML(campaign.Description)),
+	}
+	if goal > 0 {
+		page.Goal = lamportsToSOL(goal)
+		page.Percent = int(min(100*float64(campaign.AmountDonated)/float64(goal), 100))
+	}
+	if record != nil && record.Deadline != nil {
+		page.Deadline = formatTime(*record.Deadline)
+	}
+	for _, a := range activity {
+		if a.Kind != "donate" || a.Failed {
+			continue
+		}
+		if len(page.Donations) == siteDonations {
+			break
+		}
+		donation := siteDonation{Wallet: shortenAddress(a.Wallet), Amount: lamportsToSOL(a.Amount), Memo: a.Memo}
+		if a.BlockTime != nil {
+			donation.Time = formatTime(*a.BlockTime)
+		}
+		page.Donations = append(page.Donations, donation)
+	}
+
+	var body bytes.Buffer
+	if err := siteTemplate.Execute(&body, page); err != nil {
+		return "", fmt.Errorf("failed to render page: %w", err)
+	}
+	if err := os.MkdirAll(site.Out, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", site.Out, err)
+	}
+	// Replace the page in one step, so a web server never serves half of it
+	path := filepath.Join(site.Out, "index.html")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// shortenAddress abbreviates a wallet address for display
+func shortenAddress(address string) string {
+	if len(address) <= 12 {
+		return address
+	}
+	return address[:4] + "…" + address[len(address)-4:]
+}
+
+// runSites regenerates the configured static pages that are due
+func (d *Daemon) runSites(ctx context.Context, now time.Time) {
+	for i := range d.sites {
+		site := &d.sites[i]
+		if !d.sitesGenerated[i].IsZero() && now.Sub(d.sitesGenerated[i]) < site.interval {
+			continue
+		}
+		d.sitesGenerated[i] = now
+		path, err := d.app.GenerateSite(ctx, site)
+		if err != nil {
+			logf(ctx, "Static page of %s failed: %v", site.Campaign, err)
+			continue
+		}
+		logf(ctx, "Regenerated static page %s", path)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/mr-tron/base58"
+)
+
+const (
+	siwsChallengeTTL = 5 * time.Minute // how long a sign-in message may take to be signed
+	siwsSessionTTL   = 12 * time.Hour  // how long a signed-in donor stays signed in
+	siwsMaxPending   = 10000           // challenges and sessions kept at most, each
+	siwsStatement    = "Sign in to see the donations made from this wallet."
+	siwsTokenPrefix  = "cfs_"           // session tokens, told apart from API keys
+	meDonationsCache = 10 * time.Minute // a donor's summary is costly to build, one RPC call per transaction
+)
+
+// siwsChallenge is a sign-in message waiting for the wallet's signature
+type siwsChallenge struct {
+	address solana.PublicKey
+	message string
+	expires time.Time
+}
+
+// siwsSession is a donor signed in with their wallet
+type siwsSession struct {
+	address solana.PublicKey
+	expires time.Time
+}
+
+// siwsAuth implements Sign In With Solana: the server issues a message with a single-use nonce,
+// the donor's wallet signs it, and the signature is exchanged for a session token. Both maps are
+// pruned of expired entries and capped, so unauthenticated callers can't grow them without bound.
+type siwsAuth struct {
+	mu         sync.Mutex
+	challenges map[string]siwsChallenge // nonce -> challenge
+	sessions   map[string]siwsSession   // token -> session
+}
+
+func newSIWSAuth() *siwsAuth {
+	return &siwsAuth{challenges: map[string]siwsChallenge{}, sessions: map[string]siwsSession{}}
+}
+
+// prune drops expired challenges and sessions; the caller holds mu
+func (a *siwsAuth) prune(now time.Time) {
+	for nonce, challenge := range a.challenges {
+		if now.After(challenge.expires) {
+			delete(a.challenges, nonce)
+		}
+	}
+	for token, session := range a.sessions {
+		if now.After(session.expires) {
+			delete(a.sessions, token)
+		}
+	}
+}
+
+// siwsMessage is the CAIP-122 sign-in message wallets display before signing
+func siwsMessage(domain, uri, chainID string, address solana.PublicKey, nonce string, issued, expires time.Time) string {
+	return fmt.Sprintf("%s wants you to sign in with your Solana account:\n%s\n\n%s\n\nURI: %s\nVersion: 1\nChain ID: %s\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		domain, address, siwsStatement, uri, chainID, nonce, issued.UTC().Format(time.RFC3339), expires.UTC().Format(time.RFC3339))
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// handleSIWSChallenge answers POST /auth/siws/challenge {"address": "..."} with the message to sign
+func (s *Server) handleSIWSChallenge(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Address string `json:"address"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid request body"})
+		return
+	}
+	address, err := solana.PublicKeyFromBase58(req.Address)
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid address"})
+		return
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		writeRelayError(w, r, fmt.Errorf("failed to generate nonce: %w", err))
+		return
+	}
+	now := time.Now()
+	challenge := siwsChallenge{address: address, expires: now.Add(siwsChallengeTTL)}
+	challenge.message = siwsMessage(r.Host, s.baseURL(r), strings.TrimSuffix(s.app.config.cluster().Name, "-beta"), address, nonce, now, challenge.expires)
+
+	s.siws.mu.Lock()
+	s.siws.prune(now)
+	full := len(s.siws.challenges) >= siwsMaxPending
+	if !full {
+		s.siws.challenges[nonce] = challenge
+	}
+	s.siws.mu.Unlock()
+	if full {
+		w.Header().Set("Retry-After", fmt.Sprint(int(siwsChallengeTTL.Seconds())))
+		writeRelayError(w, r, &apiError{http.StatusServiceUnavailable, "too many pending sign-ins, retry later"})
+		return
+	}
+	writeRelayJSON(w, r, http.StatusOK, map[string]interface{}{"message": challenge.message, "nonce": nonce, "expiresAt": challenge.expires.UTC()})
+}
+
+// handleSIWSVerify answers POST /auth/siws/verify {"nonce": "...", "signature": "<base58>"} with a
+// session token when the signature over the challenge's message is the address's
+func (s *Server) handleSIWSVerify(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Nonce     string `json:"nonce"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid request body"})
+		return
+	}
+	signature, err := base58.Decode(req.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid signature encoding"})
+		return
+	}
+
+	// A nonce is used up by the first attempt, right or wrong
+	now := time.Now()
+	s.siws.mu.Lock()
+	challenge, ok := s.siws.challenges[req.Nonce]
+	delete(s.siws.challenges, req.Nonce)
+	s.siws.mu.Unlock()
+	if !ok || now.After(challenge.expires) {
+		writeRelayError(w, r, &apiError{http.StatusUnauthorized, "unknown or expired nonce, request a new challenge"})
+		return
+	}
+	if !ed25519.Verify(ed25519.PublicKey(challenge.address[:]), []byte(challenge.message), signature) {
+		writeRelayError(w, r, &apiError{http.StatusUnauthorized, "signature does not match the address"})
+		return
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		writeRelayError(w, r, fmt.Errorf("failed to generate session token: %w", err))
+		return
+	}
+	token := siwsTokenPrefix + secret
+	session := siwsSession{address: challenge.address, expires: now.Add(siwsSessionTTL)}
+	s.siws.mu.Lock()
+	s.siws.prune(now)
+	full := len(s.siws.sessions) >= siwsMaxPending
+	if !full {
+		s.siws.sessions[token] = session
+	}
+	s.siws.mu.Unlock()
+	if full {
+		writeRelayError(w, r, &apiError{http.StatusServiceUnavailable, "too many signed-in sessions, retry later"})
+		return
+	}
+	writeRelayJSON(w, r, http.StatusOK, map[string]interface{}{"token": token, "address": session.address, "expiresAt": session.expires.UTC()})
+}
+
+// signedIn returns the wallet of the request's session token
+func (s *Server) signedIn(r *http.Request) (solana.PublicKey, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !strings.HasPrefix(token, siwsTokenPrefix) {
+		return solana.PublicKey{}, false
+	}
+	s.siws.mu.Lock()
+	defer s.siws.mu.Unlock()
+	session, ok := s.siws.sessions[token]
+	if !ok || time.Now().After(session.expires) {
+		return solana.PublicKey{}, false
+	}
+	return session.address, true
+}
+
+// meDonations is the signed-in donor's giving in a year, as `report tax` summarizes it
+type meDonations struct {
+	Wallet    solana.PublicKey `json:"wallet"`
+	Year      int              `json:"year"`
+	From      time.Time        `json:"from"`
+	To        time.Time        `json:"to"`
+	Currency  string           `json:"currency"`
+	Lamports  uint64           `json:"lamports"`
+	Value     float64          `json:"value"`    // at the time of giving, unvalued gifts left out
+	Unpriced  int              `json:"unpriced"` // gifts no price was found for
+	Campaigns []CampaignTotal  `json:"campaigns"`
+	Gifts     []TaxGift        `json:"gifts"`
+}
+
+// handleMeDonations answers GET /me/donations[?year=2024] for the wallet signed in with SIWS
+func (s *Server) handleMeDonations(w http.ResponseWriter, r *http.Request) {
+	wallet, ok := s.signedIn(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="crowdfunding"`)
+		writeRelayError(w, r, &apiError{http.StatusUnauthorized, "sign in with /auth/siws/challenge and /auth/siws/verify first"})
+		return
+	}
+	year := time.Now().In(displayLocation).Year()
+	if param := r.URL.Query().Get("year"); param != "" {
+		var err error
+		if year, err = strconv.Atoi(param); err != nil || year < 2020 || year > 9999 {
+			writeRelayError(w, r, &apiError{http.StatusBadRequest, "invalid year"})
+			return
+		}
+	}
+
+	cacheKey := fmt.Sprintf("/me/donations/%s/%d", wallet, year)
+	if body, ok := s.cache.get(cacheKey); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("X-Cache", "HIT")
+		w.Write(body)
+		return
+	}
+	period, err := parsePeriod(strconv.Itoa(year))
+	if err != nil {
+		writeRelayError(w, r, &apiError{http.StatusBadRequest, err.Error()})
+		return
+	}
+	summary, err := s.app.buildTaxSummary(r.Context(), wallet, period, func(format string, args ...interface{}) {
+		logf(r.Context(), format, args...)
+	})
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+	result := meDonations{Wallet: wallet, Year: year, From: period.From, To: period.To, Currency: summary.Currency, Campaigns: summary.ByCampaign(), Gifts: summary.Gifts}
+	result.Lamports, result.Value, result.Unpriced = summary.Totals()
+	if result.Campaigns == nil {
+		result.Campaigns, result.Gifts = []CampaignTotal{}, []TaxGift{}
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		writeRelayError(w, r, err)
+		return
+	}
+	s.cache.put(cacheKey, body, meDonationsCache)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Cache", "MISS")
+	w.Write(body)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// slackPostMessageURL is the Slack Web API method used with a bot token
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// slackTokenEnv holds the bot token when it is not in config.json
+const slackTokenEnv = "SLACK_BOT_TOKEN"
+
+// slackNotifier posts notifications to Slack. With a bot token, each campaign can have its own
+// channel and follow-ups on a thread are posted as replies; an incoming webhook posts to its
+// fixed channel without threading.
+type slackNotifier struct {
+	token    string
+	webhook  string
+	channel  string
+	channels map[string]string
+	client   *http.Client
+}
+
+// slackMessage is the chat.postMessage and incoming webhook payload
+type slackMessage struct {
+	Channel        string            `json:"channel,omitempty"`
+	Text           string            `json:"text"`
+	ThreadTS       string            `json:"thread_ts,omitempty"`
+	ReplyBroadcast bool              `json:"reply_broadcast,omitempty"`
+	Attachments    []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string `json:"color"`
+	Text   string `json:"text"`
+	Footer string `json:"footer,omitempty"`
+}
+
+func newSlackNotifier(config NotificationConfig) (Notifier, error) {
+	token := config.Token
+	if token == "" {
+		token = os.Getenv(slackTokenEnv)
+	}
+	if token == "" && config.URL == "" {
+		return nil, fmt.Errorf("token (or %s) or an incoming webhook url is required", slackTokenEnv)
+	}
+	if token != "" && config.Channel == "" && len(config.Channels) == 0 {
+		return nil, fmt.Errorf("channel is required with a bot token")
+	}
+	if token == "" && len(config.Channels) > 0 {
+		return nil, fmt.Errorf("per-campaign channels need a bot token: an incoming webhook always posts to its own channel")
+	}
+	return &slackNotifier{
+		token:    token,
+		webhook:  config.URL,
+		channel:  config.Channel,
+		channels: config.Channels,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, n Notification) error {
+	color := "#439fe0"
+	switch n.Severity {
+	case "warning":
+		color = "warning"
+	case "resolved":
+		color = "good"
+	}
+	footer := n.Campaign
+	if n.OperationID != "" {
+		footer += " · operation " + n.OperationID
+	}
+	msg := slackMessage{
+		Text:        fmt.Sprintf("*%s*", n.Title),
+		Attachments: []slackAttachment{{Color: color, Text: n.Message, Footer: footer}},
+	}
+
+	if s.token == "" {
+		_, err := s.post(ctx, s.webhook, msg)
+		return err
+	}
+
+	msg.Channel = s.channel
+	if channel, ok := s.channels[n.Campaign]; ok {
+		msg.Channel = channel
+	}
+	if msg.Channel == "" {
+		return nil // Only routed campaigns are posted when there is no default channel
+	}
+
+	// Threads are remembered in the local store, so follow-ups land in the right thread across restarts
+	var store Store
+	threadKey := "slack:" + msg.Channel + ":" + n.Thread
+	if n.Thread != "" {
+		var err error
+		if store, err = OpenStore(); err != nil {
+			return err
+		}
+		if ts, ok := store.NotificationThread(threadKey); ok {
+			msg.ThreadTS = ts
+			msg.ReplyBroadcast = n.Severity == "resolved" // Let the channel see that it is over
+		}
+	}
+
+	ts, err := s.post(ctx, slackPostMessageURL, msg)
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		if msg.ThreadTS != "" {
+			ts = msg.ThreadTS
+		}
+		return store.SetNotificationThread(threadKey, ts)
+	}
+	return nil
+}
+
+// post sends a message and returns its timestamp, which identifies it for thread replies
+func (s *slackNotifier) post(ctx context.Context, url string, msg slackMessage) (string, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("slack answered %s", resp.Status)
+	}
+	if s.token == "" {
+		return "", nil // Incoming webhooks answer a plain "ok"
+	}
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		TS    string `json:"ts"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Slack response: %w", err)
+	}
+	if !result.OK {
+		return "", fmt.Errorf("slack error: %s", result.Error)
+	}
+	return result.TS, nil
+}
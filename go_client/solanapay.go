@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/skip2/go-qrcode"
+)
+
+// lamportsToSOL formats lamports as an exact decimal SOL amount without float rounding
+func lamportsToSOL(lamports uint64) string {
+	whole := lamports / solana.LAMPORTS_PER_SOL
+	frac := lamports % solana.LAMPORTS_PER_SOL
+	if frac == 0 {
+		return strconv.FormatUint(whole, 10)
+	}
+	return strings.TrimRight(fmt.Sprintf("%d.%09d", whole, frac), "0")
+}
+
+// parseSOL parses a decimal SOL amount such as "1.5" into lamports without float rounding
+func parseSOL(value string) (uint64, error) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "SOL")
+	whole, frac, _ := strings.Cut(strings.TrimSpace(value), ".")
+	if (whole == "" && frac == "") || len(frac) > 9 {
+		return 0, fmt.Errorf("invalid SOL amount %q", value)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	w, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid SOL amount %q", value)
+	}
+	var f uint64
+	if frac != "" {
+		if f, err = strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid SOL amount %q", value)
+		}
+	}
+	if w > (^uint64(0)-f)/solana.LAMPORTS_PER_SOL {
+		return 0, fmt.Errorf("SOL amount %q is too large", value)
+	}
+	return w*solana.LAMPORTS_PER_SOL + f, nil
+}
+
+// SolanaPayURL builds a Solana Pay transfer request understood by Phantom, Backpack and Solflare
+func SolanaPayURL(recipient solana.PublicKey, lamports uint64, reference solana.PublicKey, label, message string) string {
+	query := url.Values{}
+	if lamports > 0 {
+		query.Set("amount", lamportsToSOL(lamports))
+	}
+	query.Set("reference", reference.String())
+	if label != "" {
+		query.Set("label", label)
+	}
+	if message != "" {
+		query.Set("message", message)
+	}
+
+	// Solana Pay expects %20 rather than + for spaces
+	return "solana:" + recipient.String() + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// defaultLinkAmount is the donation a transaction request link asks for when none is given:
+// wallets have no amount field for transaction requests
+const defaultLinkAmount = solana.LAMPORTS_PER_SOL / 10
+
+// parseServerURL checks that server is the http(s) URL of a `serve` instance and drops any trailing slash
+func parseServerURL(server string) (string, error) {
+	server = strings.TrimSuffix(server, "/")
+	if u, err := url.Parse(server); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid server URL %q: expected http(s)://host of a `serve` instance", server)
+	}
+	return server, nil
+}
+
+// DonationRequestURL builds a Solana Pay transaction request to the `serve` instance at server.
+// The wallet fetches a donate instruction from it, so unlike a transfer request the donation
+// counts towards amount_donated and shows up in events and feeds.
+func DonationRequestURL(server string, campaign solana.PublicKey, lamports uint64, reference solana.PublicKey, memo string) string {
+	query := url.Values{}
+	query.Set("amount", lamportsToSOL(lamports))
+	query.Set("reference", reference.String())
+	if memo != "" {
+		query.Set("memo", memo)
+	}
+	// The link's own query string must be encoded into the solana: URL
+	return "solana:" + url.QueryEscape(server+"/pay/"+campaign.String()+"?"+query.Encode())
+}
+
+// donationLink is the link posters, pages and widgets send donors to: a transaction request for
+// lamports (defaultLinkAmount if 0) when a server is given, otherwise a transfer request
+// for any amount that the amount_donated counter misses
+func donationLink(server string, campaign solana.PublicKey, name string, lamports uint64, reference solana.PublicKey) string {
+	if server == "" {
+		return SolanaPayURL(campaign, lamports, reference, name, "Donation to "+name)
+	}
+	if lamports == 0 {
+		lamports = defaultLinkAmount
+	}
+	return DonationRequestURL(server, campaign, lamports, reference, "")
+}
+
+// PrintQRCode renders a QR code in the terminal and optionally writes it to a PNG file
+func PrintQRCode(content, pngPath string) error {
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	fmt.Println(qr.ToSmallString(false))
+
+	if pngPath != "" {
+		if err := qr.WriteFile(512, pngPath); err != nil {
+			return fmt.Errorf("failed to write QR code image: %w", err)
+		}
+		fmt.Printf("🖼️  QR code saved to %s\n", pngPath)
+	}
+	return nil
+}
+
+// WaitForReference polls until a transaction mentioning the reference key lands or the timeout passes
+func (app *SolanaDApp) WaitForReference(reference solana.PublicKey, timeout time.Duration) (*rpc.TransactionSignature, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		signatures, err := app.client.GetSignaturesForAddressWithOpts(context.Background(), reference, &rpc.GetSignaturesForAddressOpts{
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err == nil && len(signatures) > 0 {
+			// The oldest signature is the payment; later ones would be replays
+			return signatures[len(signatures)-1], nil
+		}
+		time.Sleep(3 * time.Second)
+	}
+	return nil, fmt.Errorf("no transaction referencing %s within %s", reference.String(), timeout)
+}
+
+// CreateDonationLink prints a wallet-friendly donation link and QR code, then tracks whether it gets
+// paid. With server, the link is a transaction request to that `serve` instance.
+func (app *SolanaDApp) CreateDonationLink(campaignAddress solana.PublicKey, lamports uint64, server, pngPath string, timeout time.Duration) error {
+	campaign, err := app.FetchCampaign(campaignAddress)
+	if err != nil {
+		return err
+	}
+
+	// A throwaway public key lets us find the donor's transaction without knowing their wallet
+	reference := solana.NewWallet().PublicKey()
+	link := donationLink(server, campaignAddress, campaign.Name, lamports, reference)
+
+	fmt.Printf("\n📱 Donation link for '%s' (%s SOL):\n", campaign.Name, lamportsToSOL(lamports))
+	fmt.Println(link)
+	fmt.Println()
+	if err := PrintQRCode(link, pngPath); err != nil {
+		return err
+	}
+	fmt.Println("Scan with Phantom, Backpack or Solflare, or open the link on the donor's phone.")
+	if server == "" {
+		fmt.Println("ℹ️  Wallet links send a plain SOL transfer to the campaign account: the funds land, but the")
+		fmt.Println("   on-chain amount_donated counter misses them. Pass -server with the URL of a `serve`")
+		fmt.Println("   instance to have the wallet sign a real donation instead.")
+	}
+
+	fmt.Printf("\n⏳ Waiting up to %s for the donation (reference %s)...\n", timeout, reference.String())
+	signature, err := app.WaitForReference(reference, timeout)
+	if err != nil {
+		return err
+	}
+	if signature.Err != nil {
+		return fmt.Errorf("donation transaction %s failed: %v", signature.Signature.String(), signature.Err)
+	}
+
+	fmt.Printf("✅ Donation received! Transaction: %s\n", signature.Signature.String())
+	fmt.Printf("🔗 %s\n", app.TxURL(signature.Signature.String()))
+	return nil
+}
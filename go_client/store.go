@@ -0,0 +1,662 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// storeFile is the local database kept by the daemon
+const storeFile = "store.json"
+
+// databaseURLEnv selects the PostgreSQL store when config.json does not
+const databaseURLEnv = "CROWDFUNDING_DATABASE_URL"
+
+// StoreConfig selects where daemon state and history are kept in config.json
+type StoreConfig struct {
+	Postgres string `json:"postgres,omitempty"` // connection URL; store.json is used when empty
+}
+
+// Store keeps daemon state and history. LocalStore suits a single machine; PostgresStore lets
+// several daemon replicas share it.
+type Store interface {
+	AddSnapshots(snapshots ...Snapshot) error
+	Snapshots(campaign solana.PublicKey, from, to time.Time) []Snapshot
+	SnapshotAt(campaign solana.PublicKey, t time.Time) (Snapshot, bool)
+	SnapshotCampaigns() []solana.PublicKey
+
+	AlertFiring(name string) bool
+	SetAlertFiring(name string, firing bool) error
+	AddAutoWithdrawal(record AutoWithdrawal) error
+
+	Schedules() []Schedule
+	AddSchedule(schedule Schedule) (Schedule, error)
+	RemoveSchedule(id string) error
+	ClaimScheduleRun(id string, lastRun, now time.Time) (bool, error)
+	RecordScheduleRun(run ScheduleRun) error
+	ScheduleRuns() []ScheduleRun
+
+	QueuedTransactions() []QueuedTransaction
+	EnqueueTransaction(queued QueuedTransaction) (QueuedTransaction, error)
+	ClaimQueuedTransaction(id string, nextAttempt, until time.Time) (bool, error)
+	UpdateQueuedTransaction(queued QueuedTransaction) error
+	RemoveQueuedTransaction(id string) error
+
+	Mutes() map[string][]string
+	SetMuted(campaign, donor string, muted bool) error
+	DigestSentAt() time.Time
+	SetDigestSentAt(t time.Time) error
+	NotificationThread(key string) (string, bool)
+	SetNotificationThread(key, id string) error
+
+	Valuation(signature, currency string) (Valuation, bool)
+	AddValuation(valuation Valuation) error
+
+	LedgerEntries() []LedgerEntry
+	AddLedgerEntries(entries ...LedgerEntry) error
+	RemoveLedgerEntries(signatures ...string) error
+}
+
+var (
+	openStoreMu sync.Mutex
+	openedStore Store
+)
+
+// OpenStore opens the store configured in config.json (or CROWDFUNDING_DATABASE_URL), falling
+// back to store.json. The store is opened once per process and shared.
+func OpenStore() (Store, error) {
+	openStoreMu.Lock()
+	defer openStoreMu.Unlock()
+	if openedStore != nil {
+		return openedStore, nil
+	}
+
+	url := os.Getenv(databaseURLEnv)
+	if config := loadConfig(); config.Store != nil && config.Store.Postgres != "" {
+		url = config.Store.Postgres
+	}
+
+	var store Store
+	var err error
+	if url != "" {
+		store, err = OpenPostgresStore(url)
+	} else {
+		store, err = OpenLocalStore(storeFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	openedStore = store
+	return store, nil
+}
+
+// Snapshot records a campaign's balance and raised total at a point in time
+type Snapshot struct {
+	Campaign      solana.PublicKey `json:"campaign"`
+	Name          string           `json:"name"`
+	Time          time.Time        `json:"time"`
+	Lamports      uint64           `json:"lamports"`
+	AmountDonated uint64           `json:"amountDonated"`
+}
+
+// storeData is the on-disk layout of the local store
+type storeData struct {
+	Snapshots []Snapshot      `json:"snapshots"`
+	Alerts    map[string]bool `json:"alerts,omitempty"` // alert name -> currently firing
+
+	AutoWithdrawals []AutoWithdrawal `json:"autoWithdrawals,omitempty"`
+
+	Schedules    []Schedule    `json:"schedules,omitempty"`
+	ScheduleRuns []ScheduleRun `json:"scheduleRuns,omitempty"`
+
+	RetryQueue []QueuedTransaction `json:"retryQueue,omitempty"`
+
+	Mutes map[string][]string `json:"mutes,omitempty"` // campaign -> donors whose comments are hidden
+
+	DigestSentAt time.Time `json:"digestSentAt,omitempty"`
+
+	Threads map[string]NotificationThread `json:"threads,omitempty"` // backend thread key -> first message
+
+	Valuations map[string]Valuation `json:"valuations,omitempty"` // signature/currency -> SOL price at its block time
+
+	Ledger []LedgerEntry `json:"ledger,omitempty"` // by slot
+}
+
+// NotificationThread is the chat message that later notifications on the same thread reply to
+type NotificationThread struct {
+	ID      string    `json:"id"`
+	Updated time.Time `json:"updated"`
+}
+
+// threadRetention is how long an idle notification thread is remembered
+const threadRetention = 30 * 24 * time.Hour
+
+// LocalStore is a small JSON-file database for daemon state and history.
+// The daemon and one-shot commands share the file, so it is reloaded whenever it changes on disk.
+type LocalStore struct {
+	mu      sync.Mutex
+	path    string
+	data    storeData
+	modTime time.Time
+}
+
+// OpenLocalStore loads the store at path, starting empty if it does not exist
+func OpenLocalStore(path string) (*LocalStore, error) {
+	store := &LocalStore{path: path}
+	if err := store.refresh(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// refresh reloads the file if another process changed it; the caller holds the lock
+func (s *LocalStore) refresh() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+	var fresh storeData
+	if err := json.Unmarshal(data, &fresh); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	s.data = fresh
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// save writes the store atomically; the caller holds the lock
+func (s *LocalStore) save() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", s.path, err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// AddSnapshots appends snapshots and persists them
+func (s *LocalStore) AddSnapshots(snapshots ...Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	s.data.Snapshots = append(s.data.Snapshots, snapshots...)
+	return s.save()
+}
+
+// Snapshots returns a campaign's snapshots taken in [from, to], oldest first
+func (s *LocalStore) Snapshots(campaign solana.PublicKey, from, to time.Time) []Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	var result []Snapshot
+	for _, snapshot := range s.data.Snapshots {
+		if snapshot.Campaign.Equals(campaign) && !snapshot.Time.Before(from) && !snapshot.Time.After(to) {
+			result = append(result, snapshot)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result
+}
+
+// SnapshotAt returns the latest snapshot of a campaign taken at or before t
+func (s *LocalStore) SnapshotAt(campaign solana.PublicKey, t time.Time) (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	var best Snapshot
+	found := false
+	for _, snapshot := range s.data.Snapshots {
+		if snapshot.Campaign.Equals(campaign) && !snapshot.Time.After(t) && (!found || snapshot.Time.After(best.Time)) {
+			best, found = snapshot, true
+		}
+	}
+	return best, found
+}
+
+// SnapshotCampaigns lists the campaigns that have snapshots
+func (s *LocalStore) SnapshotCampaigns() []solana.PublicKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	seen := map[solana.PublicKey]bool{}
+	var campaigns []solana.PublicKey
+	for _, snapshot := range s.data.Snapshots {
+		if !seen[snapshot.Campaign] {
+			seen[snapshot.Campaign] = true
+			campaigns = append(campaigns, snapshot.Campaign)
+		}
+	}
+	return campaigns
+}
+
+// AlertFiring reports whether the named alert was firing at its last evaluation
+func (s *LocalStore) AlertFiring(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	return s.data.Alerts[name]
+}
+
+// SetAlertFiring records the named alert's state
+func (s *LocalStore) SetAlertFiring(name string, firing bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	if s.data.Alerts == nil {
+		s.data.Alerts = map[string]bool{}
+	}
+	if firing {
+		s.data.Alerts[name] = true
+	} else {
+		delete(s.data.Alerts, name)
+	}
+	return s.save()
+}
+
+// AddAutoWithdrawal appends an auto-withdraw audit record
+func (s *LocalStore) AddAutoWithdrawal(record AutoWithdrawal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	s.data.AutoWithdrawals = append(s.data.AutoWithdrawals, record)
+	return s.save()
+}
+
+// Schedules returns the persisted schedules
+func (s *LocalStore) Schedules() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	return append([]Schedule(nil), s.data.Schedules...)
+}
+
+// AddSchedule persists a new schedule, assigning its ID
+func (s *LocalStore) AddSchedule(schedule Schedule) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return schedule, err
+	}
+
+	highest := 0
+	for _, existing := range s.data.Schedules {
+		var n int
+		if _, err := fmt.Sscanf(existing.ID, "%d", &n); err == nil && n > highest {
+			highest = n
+		}
+	}
+	schedule.ID = fmt.Sprintf("%d", highest+1)
+	s.data.Schedules = append(s.data.Schedules, schedule)
+	return schedule, s.save()
+}
+
+// RemoveSchedule deletes a schedule by ID
+func (s *LocalStore) RemoveSchedule(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	for i, schedule := range s.data.Schedules {
+		if schedule.ID == id {
+			s.data.Schedules = append(s.data.Schedules[:i], s.data.Schedules[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("no schedule with ID %s", id)
+}
+
+// ClaimScheduleRun marks a schedule as run at now if it still last ran at lastRun, so that
+// only one daemon runs it
+func (s *LocalStore) ClaimScheduleRun(id string, lastRun, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return false, err
+	}
+
+	for i := range s.data.Schedules {
+		if s.data.Schedules[i].ID == id {
+			if !s.data.Schedules[i].LastRun.Equal(lastRun) {
+				return false, nil
+			}
+			s.data.Schedules[i].LastRun = now
+			return true, s.save()
+		}
+	}
+	return false, nil
+}
+
+// RecordScheduleRun appends to the execution history and marks the schedule as run
+func (s *LocalStore) RecordScheduleRun(run ScheduleRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	for i := range s.data.Schedules {
+		if s.data.Schedules[i].ID == run.ScheduleID {
+			s.data.Schedules[i].LastRun = run.Time
+		}
+	}
+	s.data.ScheduleRuns = append(s.data.ScheduleRuns, run)
+	return s.save()
+}
+
+// ScheduleRuns returns the execution history, oldest first
+func (s *LocalStore) ScheduleRuns() []ScheduleRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	return append([]ScheduleRun(nil), s.data.ScheduleRuns...)
+}
+
+// QueuedTransactions returns the retry queue, including finished entries
+func (s *LocalStore) QueuedTransactions() []QueuedTransaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	return append([]QueuedTransaction(nil), s.data.RetryQueue...)
+}
+
+// EnqueueTransaction persists a transaction to retry, assigning its ID
+func (s *LocalStore) EnqueueTransaction(queued QueuedTransaction) (QueuedTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return queued, err
+	}
+
+	highest := 0
+	for _, existing := range s.data.RetryQueue {
+		var n int
+		if _, err := fmt.Sscanf(existing.ID, "%d", &n); err == nil && n > highest {
+			highest = n
+		}
+	}
+	queued.ID = fmt.Sprintf("%d", highest+1)
+	s.data.RetryQueue = append(s.data.RetryQueue, queued)
+	return queued, s.save()
+}
+
+// ClaimQueuedTransaction postpones a pending entry's next attempt to until if it is still
+// nextAttempt, so that only one daemon retries it
+func (s *LocalStore) ClaimQueuedTransaction(id string, nextAttempt, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return false, err
+	}
+
+	for i := range s.data.RetryQueue {
+		queued := &s.data.RetryQueue[i]
+		if queued.ID == id {
+			if queued.Status != RetryPending || !queued.NextAttempt.Equal(nextAttempt) {
+				return false, nil
+			}
+			queued.NextAttempt = until
+			return true, s.save()
+		}
+	}
+	return false, nil
+}
+
+// UpdateQueuedTransaction replaces a retry queue entry by ID
+func (s *LocalStore) UpdateQueuedTransaction(queued QueuedTransaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	for i := range s.data.RetryQueue {
+		if s.data.RetryQueue[i].ID == queued.ID {
+			s.data.RetryQueue[i] = queued
+			return s.save()
+		}
+	}
+	return fmt.Errorf("no queued transaction with ID %s", queued.ID)
+}
+
+// RemoveQueuedTransaction deletes a retry queue entry by ID
+func (s *LocalStore) RemoveQueuedTransaction(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	for i, queued := range s.data.RetryQueue {
+		if queued.ID == id {
+			s.data.RetryQueue = append(s.data.RetryQueue[:i], s.data.RetryQueue[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("no queued transaction with ID %s", id)
+}
+
+// Mutes returns the muted donors of every campaign
+func (s *LocalStore) Mutes() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	mutes := map[string][]string{}
+	for campaign, donors := range s.data.Mutes {
+		mutes[campaign] = append([]string(nil), donors...)
+	}
+	return mutes
+}
+
+// SetMuted mutes or unmutes a donor's comments on a campaign
+func (s *LocalStore) SetMuted(campaign, donor string, muted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	donors := s.data.Mutes[campaign]
+	for i, existing := range donors {
+		if existing == donor {
+			if muted {
+				return nil
+			}
+			donors = append(donors[:i], donors[i+1:]...)
+			break
+		}
+	}
+	if muted {
+		donors = append(donors, donor)
+	}
+
+	if s.data.Mutes == nil {
+		s.data.Mutes = map[string][]string{}
+	}
+	if len(donors) == 0 {
+		delete(s.data.Mutes, campaign)
+	} else {
+		s.data.Mutes[campaign] = donors
+	}
+	return s.save()
+}
+
+// DigestSentAt returns when the daemon last sent the email digest, or the zero time
+func (s *LocalStore) DigestSentAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	return s.data.DigestSentAt
+}
+
+// SetDigestSentAt records when the email digest was sent
+func (s *LocalStore) SetDigestSentAt(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	s.data.DigestSentAt = t
+	return s.save()
+}
+
+// NotificationThread returns the message a notification thread started with
+func (s *LocalStore) NotificationThread(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	thread, ok := s.data.Threads[key]
+	return thread.ID, ok
+}
+
+// SetNotificationThread remembers the message a notification thread started with, forgetting idle threads
+func (s *LocalStore) SetNotificationThread(key, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if s.data.Threads == nil {
+		s.data.Threads = map[string]NotificationThread{}
+	}
+	for k, thread := range s.data.Threads {
+		if now.Sub(thread.Updated) > threadRetention {
+			delete(s.data.Threads, k)
+		}
+	}
+	s.data.Threads[key] = NotificationThread{ID: id, Updated: now}
+	return s.save()
+}
+
+// valuationKey indexes a valuation in the local store
+func valuationKey(signature, currency string) string {
+	return signature + "/" + currency
+}
+
+// Valuation returns the stored valuation of a transaction in a currency
+func (s *LocalStore) Valuation(signature, currency string) (Valuation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	valuation, ok := s.data.Valuations[valuationKey(signature, currency)]
+	return valuation, ok
+}
+
+// AddValuation stores a transaction's valuation, keeping the first one made
+func (s *LocalStore) AddValuation(valuation Valuation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	key := valuationKey(valuation.Signature, valuation.Currency)
+	if _, ok := s.data.Valuations[key]; ok {
+		return nil
+	}
+	if s.data.Valuations == nil {
+		s.data.Valuations = map[string]Valuation{}
+	}
+	s.data.Valuations[key] = valuation
+	return s.save()
+}
+
+// LedgerEntries returns the ledger in slot order
+func (s *LocalStore) LedgerEntries() []LedgerEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	return append([]LedgerEntry(nil), s.data.Ledger...)
+}
+
+// AddLedgerEntries adds entries for transactions not in the ledger yet, keeping it in slot order
+func (s *LocalStore) AddLedgerEntries(entries ...LedgerEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	recorded := map[string]bool{}
+	for _, entry := range s.data.Ledger {
+		recorded[entry.Signature] = true
+	}
+	added := false
+	for _, entry := range entries {
+		if !recorded[entry.Signature] {
+			recorded[entry.Signature] = true
+			s.data.Ledger = append(s.data.Ledger, entry)
+			added = true
+		}
+	}
+	if !added {
+		return nil
+	}
+	sort.SliceStable(s.data.Ledger, func(i, j int) bool { return s.data.Ledger[i].Slot < s.data.Ledger[j].Slot })
+	return s.save()
+}
+
+// RemoveLedgerEntries removes every entry of the transactions, including duplicates
+func (s *LocalStore) RemoveLedgerEntries(signatures ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	remove := map[string]bool{}
+	for _, signature := range signatures {
+		remove[signature] = true
+	}
+	kept := s.data.Ledger[:0]
+	for _, entry := range s.data.Ledger {
+		if !remove[entry.Signature] {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == len(s.data.Ledger) {
+		return nil
+	}
+	s.data.Ledger = kept
+	return s.save()
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TaxGift is one donation of a tax-year summary, valued at the SOL price when it was made
+type TaxGift struct {
+	CampaignActivity
+	Campaign     solana.PublicKey `json:"campaign"`
+	CampaignName string           `json:"campaignName"`
+	Recipient    string           `json:"recipient,omitempty"`    // the campaign's admin wallet
+	Organization string           `json:"organization,omitempty"` // from the verified registry, when the campaign is listed
+	Valuation    *Valuation       `json:"valuation,omitempty"`    // nil when no price could be found; the gift is left out of the total
+	Value        float64          `json:"value"`
+}
+
+// CampaignTotal is what a donor gave one campaign in a summary
+type CampaignTotal struct {
+	Campaign     solana.PublicKey `json:"campaign"`
+	Name         string           `json:"name"`
+	Organization string           `json:"organization,omitempty"`
+	Lamports     uint64           `json:"lamports"`
+	Value        float64          `json:"value"`
+	Gifts        int              `json:"gifts"`
+}
+
+// TaxSummary is a donor's gifts in one year
+type TaxSummary struct {
+	Wallet   solana.PublicKey
+	Period   reportPeriod
+	Currency string
+	Gifts    []TaxGift
+}
+
+// BuildTaxSummary finds every donation the wallet made in the year and values it at the SOL price
+// of its block time
+func (app *SolanaDApp) BuildTaxSummary(ctx context.Context, wallet solana.PublicKey, period reportPeriod) (*TaxSummary, error) {
+	return app.buildTaxSummary(ctx, wallet, period, func(format string, args ...interface{}) {
+		fmt.Printf("⚠️  "+format+"\n", args...)
+	})
+}
+
+// buildTaxSummary is BuildTaxSummary reporting gifts it can't fully describe through warn
+func (app *SolanaDApp) buildTaxSummary(ctx context.Context, wallet solana.PublicKey, period reportPeriod, warn func(format string, args ...interface{})) (*TaxSummary, error) {
+	signatures, err := app.signaturesBetween(wallet, period.From, period.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch wallet signatures: %w", err)
+	}
+	results := make([][]CampaignActivity, len(signatures))
+	err = forEachParallel(len(signatures), defaultWorkers, func(i int) error {
+		entries, err := app.transactionActivity(signatures[i])
+		results[i] = entries
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := OpenStore()
+	if err != nil {
+		return nil, err
+	}
+	valuer, err := NewValuer(app.config.Prices, store)
+	if err != nil {
+		return nil, err
+	}
+	records, _ := loadCampaignRecords()
+	registry, err := app.Registry()
+	if err != nil {
+		warn("Could not check the campaign registry: %v", err)
+	}
+	summary := &TaxSummary{Wallet: wallet, Period: period, Currency: valuer.Currency()}
+	for i := len(results) - 1; i >= 0; i-- {
+		for _, entry := range results[i] {
+			if entry.Kind != "donate" || entry.Wallet != wallet.String() {
+				continue
+			}
+			gift := TaxGift{CampaignActivity: entry, Campaign: entry.campaign, CampaignName: entry.campaign.String()}
+			if fetched, err := app.FetchCampaign(entry.campaign); err == nil {
+				gift.CampaignName, gift.Recipient = fetched.Name, fetched.Admin.String()
+			} else if record := records.get(entry.campaign); record != nil {
+				gift.CampaignName, gift.Recipient = record.Name, record.Admin.String() // closed since
+			}
+			if registry != nil {
+				if listed := registry.Entry(entry.campaign); listed != nil {
+					gift.Organization = listed.Organization
+				}
+			}
+			if entry.BlockTime == nil {
+				warn("The node has no block time for %s; it is left unvalued", entry.Signature)
+			} else if valuation, err := valuer.Value(ctx, entry.Signature, *entry.BlockTime); err == nil {
+				gift.Valuation, gift.Value = &valuation, valuation.Of(entry.Amount)
+			} else {
+				warn("%v; %s is left unvalued", err, entry.Signature)
+			}
+			summary.Gifts = append(summary.Gifts, gift)
+		}
+	}
+	return summary, nil
+}
+
+// Write saves the gifts as CSV and a printable PDF summary in dir
+func (s *TaxSummary) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	var rows [][]string
+	for _, g := range s.Gifts {
+		price, value, source := valuationColumns(g.Valuation, g.Amount)
+		rows = append(rows, []string{movementTime(g.CampaignActivity), g.Signature, g.Campaign.String(), g.CampaignName, g.Organization, g.Recipient, lamportsToSOL(g.Amount), price, value, source})
+	}
+	header := []string{"time", "signature", "campaign", "campaign_name", "organization", "recipient", "amount_sol", "sol_" + s.Currency, "value_" + s.Currency, "price_source"}
+	if err := writeCSV(filepath.Join(dir, "donations.csv"), header, rows); err != nil {
+		return err
+	}
+	return writeTextPDF(filepath.Join(dir, "summary.pdf"), "Donations "+s.Period.Name, s.lines())
+}
+
+// Totals adds up the gifts; unpriced counts those left out of value
+func (s *TaxSummary) Totals() (lamports uint64, value float64, unpriced int) {
+	for _, g := range s.Gifts {
+		lamports += g.Amount
+		value += g.Value
+		if g.Valuation == nil {
+			unpriced++
+		}
+	}
+	return lamports, value, unpriced
+}
+
+// ByCampaign groups the gifts per campaign, the most valuable first
+func (s *TaxSummary) ByCampaign() []CampaignTotal {
+	index := map[solana.PublicKey]int{}
+	var totals []CampaignTotal
+	for _, g := range s.Gifts {
+		i, ok := index[g.Campaign]
+		if !ok {
+			i = len(totals)
+			index[g.Campaign] = i
+			totals = append(totals, CampaignTotal{Campaign: g.Campaign, Name: g.CampaignName, Organization: g.Organization})
+		}
+		totals[i].Lamports += g.Amount
+		totals[i].Value += g.Value
+		totals[i].Gifts++
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].Value > totals[j].Value })
+	return totals
+}
+
+// lines is the text of the PDF summary
+func (s *TaxSummary) lines() []string {
+	lines := []string{
+		"DONATION SUMMARY FOR TAX YEAR " + s.Period.String(),
+		"Donor wallet " + s.Wallet.String(),
+		"Generated " + formatTime(time.Now()),
+		"",
+	}
+
+	totalSOL, totalValue, unpriced := s.Totals()
+	lines = append(lines, fmt.Sprintf("TOTAL: %d gift(s), %s SOL, %s at the time of giving", len(s.Gifts), lamportsToSOL(totalSOL), formatFiat(totalValue, s.Currency)))
+	if unpriced > 0 {
+		lines = append(lines, fmt.Sprintf("  %d gift(s) could not be valued and are not in the total", unpriced))
+	}
+	lines = append(lines, "", "BY CAMPAIGN")
+	for _, c := range s.ByCampaign() {
+		title := c.Name
+		if c.Organization != "" {
+			title += " (" + c.Organization + ")"
+		}
+		lines = append(lines, "  "+title, fmt.Sprintf("    %s  %d gift(s)  %s SOL  %s", c.Campaign, c.Gifts, lamportsToSOL(c.Lamports), formatFiat(c.Value, s.Currency)))
+	}
+
+	lines = append(lines, "", "GIFTS")
+	for _, g := range s.Gifts {
+		when, value := "", "not valued"
+		if g.BlockTime != nil {
+			when = formatDate(*g.BlockTime)
+		}
+		if g.Valuation != nil {
+			value = fmt.Sprintf("%s at %s/SOL", formatFiat(g.Value, s.Currency), formatFiat(g.Valuation.Price, s.Currency))
+		}
+		lines = append(lines, fmt.Sprintf("  %s  %12s SOL  %s  %s", when, lamportsToSOL(g.Amount), value, g.CampaignName), "    tx "+g.Signature)
+	}
+
+	lines = append(lines, "",
+		"Values use the SOL price at each gift's block time; donations.csv names the price source.",
+		"Recipients are campaign wallets, not necessarily registered charities: check whether a gift",
+		"is deductible where you file. donations.csv holds the full records.",
+	)
+	return lines
+}
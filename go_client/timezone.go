@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// displayLocation is the time zone timestamps are shown in: config.json's timezone, else the system's.
+// Cron schedules and withdrawal policy hours without a timezone of their own are read in it too.
+var displayLocation = time.Local
+
+// Timestamps are shown to people as ISO 8601 dates and times with the zone's abbreviation.
+// JSON output keeps RFC 3339 timestamps, which are ISO 8601 too.
+const (
+	displayTimeLayout = "2006-01-02 15:04:05 MST"
+	displayDateLayout = "2006-01-02"
+)
+
+// setTimezone shows timestamps in an IANA time zone, e.g. Europe/Berlin, or UTC
+func setTimezone(name string) error {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	displayLocation = location
+	return nil
+}
+
+// formatTime shows a timestamp in the configured time zone
+func formatTime(t time.Time) string {
+	return t.In(displayLocation).Format(displayTimeLayout)
+}
+
+// formatDate shows the date of a timestamp in the configured time zone
+func formatDate(t time.Time) string {
+	return t.In(displayLocation).Format(displayDateLayout)
+}
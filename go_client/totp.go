@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// totpFile holds the enrolled TOTP secret
+const totpFile = "totp.json"
+
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+)
+
+// TOTPEnrollment is an enrolled authenticator app (RFC 6238, SHA-1, 6 digits, 30 seconds)
+type TOTPEnrollment struct {
+	Secret   string    `json:"secret"` // base32
+	Account  string    `json:"account"`
+	Enrolled time.Time `json:"enrolled"`
+	LastStep int64     `json:"lastStep,omitempty"` // time step of the last accepted code, so a code works once
+}
+
+// NewTOTPEnrollment generates a random secret
+func NewTOTPEnrollment(account string) (*TOTPEnrollment, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return &TOTPEnrollment{
+		Secret:   base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret),
+		Account:  account,
+		Enrolled: time.Now().UTC(),
+	}, nil
+}
+
+// URI is the otpauth:// link authenticator apps scan
+func (e *TOTPEnrollment) URI() string {
+	query := url.Values{}
+	query.Set("secret", e.Secret)
+	query.Set("issuer", "Crowdfunding")
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	return "otpauth://totp/" + url.PathEscape("Crowdfunding:"+e.Account) + "?" + query.Encode()
+}
+
+// code computes the code of a time step
+func (e *TOTPEnrollment) code(step int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(strings.ToUpper(e.Secret), "="))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// Verify checks a code against the current time step and its neighbours, to allow for clock
+// drift, and refuses codes that were already used
+func (e *TOTPEnrollment) Verify(code string, now time.Time) bool {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for step := current - 1; step <= current+1; step++ {
+		if step <= e.LastStep {
+			continue
+		}
+		expected, err := e.code(step)
+		if err == nil && hmac.Equal([]byte(expected), []byte(code)) {
+			e.LastStep = step
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTOTP reads the enrollment, returning nil when there is none
+func LoadTOTP() (*TOTPEnrollment, error) {
+	data, err := os.ReadFile(totpFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", totpFile, err)
+	}
+	var enrollment TOTPEnrollment
+	if err := json.Unmarshal(data, &enrollment); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", totpFile, err)
+	}
+	return &enrollment, nil
+}
+
+// Save writes the enrollment, readable only by the owner
+func (e *TOTPEnrollment) Save() error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(totpFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", totpFile, err)
+	}
+	return nil
+}
+
+// promptTOTP asks for a code on the terminal and checks it, recording it as used
+func promptTOTP(w PolicyWithdrawal, rules []string) error {
+	enrollment, err := LoadTOTP()
+	if err != nil {
+		return err
+	}
+	if enrollment == nil {
+		return fmt.Errorf("a TOTP code is required but no authenticator is enrolled (run `totp enroll`)")
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("a TOTP code is required but there is no terminal to ask for it")
+	}
+
+	fmt.Printf("🔑 %s require an authenticator code for withdrawing %s SOL from %s\n", strings.Join(rules, ", "), lamportsToSOL(w.Lamports), w.Campaign)
+	reader := bufio.NewReader(os.Stdin)
+	for attempt := 0; attempt < 3; attempt++ {
+		fmt.Print("   Code: ")
+		code, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read code: %w", err)
+		}
+		if enrollment.Verify(code, time.Now()) {
+			return enrollment.Save()
+		}
+		fmt.Println("   ❌ Wrong or already used code")
+	}
+	return fmt.Errorf("wrong TOTP code")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfc6238Enrollment holds the SHA-1 key of RFC 6238's test vectors, "12345678901234567890"
+func rfc6238Enrollment() *TOTPEnrollment {
+	return &TOTPEnrollment{Secret: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))}
+}
+
+// TestTOTPCodeRFC6238 checks the SHA-1 vectors of RFC 6238 Appendix B. The RFC lists 8-digit
+// codes; ours are their last 6 digits.
+func TestTOTPCodeRFC6238(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string // 8 digits, as in the RFC
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	e := rfc6238Enrollment()
+	for _, v := range vectors {
+		got, err := e.code(v.unix / int64(totpPeriod.Seconds()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := v.code[2:]; got != want {
+			t.Errorf("T=%d: code %s, want %s", v.unix, got, want)
+		}
+	}
+}
+
+func TestTOTPVerifyRejectsReplay(t *testing.T) {
+	e := rfc6238Enrollment()
+	now := time.Unix(1111111111, 0)
+	if !e.Verify("050471", now) {
+		t.Fatal("current code refused")
+	}
+	if step := now.Unix() / int64(totpPeriod.Seconds()); e.LastStep != step {
+		t.Errorf("LastStep = %d, want %d", e.LastStep, step)
+	}
+	if e.Verify("050471", now) {
+		t.Error("the same code was accepted twice")
+	}
+	if e.Verify("050471", now.Add(10*time.Second)) {
+		t.Error("the same code was accepted again later in its step")
+	}
+}
+
+func TestTOTPVerifyDrift(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / int64(totpPeriod.Seconds())
+	for _, offset := range []int64{-1, 1} {
+		e := rfc6238Enrollment()
+		code, err := e.code(step + offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !e.Verify(code, now) {
+			t.Errorf("code of step %+d refused", offset)
+		}
+	}
+
+	e := rfc6238Enrollment()
+	stale, err := e.code(step - 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Verify(stale, now) {
+		t.Error("code two steps old accepted")
+	}
+
+	// Once a code is used, earlier steps' codes are refused too
+	e = rfc6238Enrollment()
+	next, _ := e.code(step + 1)
+	previous, _ := e.code(step - 1)
+	if !e.Verify(next, now) {
+		t.Fatal("next step's code refused")
+	}
+	if e.Verify(previous, now) {
+		t.Error("an older code was accepted after a newer one")
+	}
+}
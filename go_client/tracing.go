@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the client's spans; it is a no-op until setupTracing installs an exporter
+var tracer = otel.Tracer("crowdfunding-client")
+
+// setupTracing exports spans over OTLP/HTTP when an OTLP endpoint is configured through the
+// standard OTEL_EXPORTER_OTLP_* environment variables. The returned function flushes pending spans.
+func setupTracing() func() {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func() {}
+	}
+
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		log.Printf("Warning: tracing disabled, failed to create OTLP exporter: %v", err)
+		return func() {}
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName("crowdfunding-client"),
+			attribute.String("solana.cluster", clusterName(loadConfig())),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Printf("Warning: failed to flush traces: %v", err)
+		}
+	}
+}
+
+// endSpan records err on the span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
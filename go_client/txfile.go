@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"crowdfunding-client/crowdfund"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/mr-tron/base58"
+)
+
+// Transactions needing keys held on different machines (a fee payer and an admin, say) travel as
+// transaction files: one machine builds it, each signer signs it where its key lives and sends back
+// a signature file, and the signatures are merged into the transaction file before broadcast.
+
+// TxFile is a transaction waiting for its signatures
+type TxFile struct {
+	Action               string    `json:"action"`                         // donate or withdraw
+	Transaction          string    `json:"transaction"`                    // base64, with the signatures collected so far
+	LastValidBlockHeight uint64    `json:"lastValidBlockHeight,omitempty"` // 0 when a durable nonce keeps it valid
+	Created              time.Time `json:"created"`
+}
+
+// SignatureFile is one signer's signature over a transaction file's message
+type SignatureFile struct {
+	Signer    solana.PublicKey `json:"signer"`
+	Signature solana.Signature `json:"signature"`
+}
+
+// LoadTxFile reads a transaction file and decodes its transaction
+func LoadTxFile(path string) (*TxFile, *solana.Transaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read transaction file: %w", err)
+	}
+	var file TxFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	tx, err := solana.TransactionFromBase64(file.Transaction)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid transaction in %s: %w", path, err)
+	}
+	// Unsigned slots are kept as zero signatures, so each signer has its place
+	if required := int(tx.Message.Header.NumRequiredSignatures); len(tx.Signatures) < required {
+		tx.Signatures = append(tx.Signatures, make([]solana.Signature, required-len(tx.Signatures))...)
+	}
+	return &file, tx, nil
+}
+
+// Save writes the transaction file with tx and its signatures
+func (f *TxFile) Save(path string, tx *solana.Transaction) error {
+	encoded, err := tx.ToBase64()
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	f.Transaction = encoded
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadSignatureFile reads a signature file
+func LoadSignatureFile(path string) (*SignatureFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature file: %w", err)
+	}
+	var sig SignatureFile
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &sig, nil
+}
+
+// missingSigners lists the signers whose signature tx still lacks
+func missingSigners(tx *solana.Transaction) []solana.PublicKey {
+	var missing []solana.PublicKey
+	for i, key := range tx.Message.AccountKeys[:tx.Message.Header.NumRequiredSignatures] {
+		if i >= len(tx.Signatures) || tx.Signatures[i].IsZero() {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// addSignature verifies a signature over tx's message and puts it in its signer's place
+func addSignature(tx *solana.Transaction, sig SignatureFile) error {
+	i := crowdfund.SignerIndex(tx, sig.Signer)
+	if i < 0 {
+		return fmt.Errorf("%s is not a signer of this transaction", sig.Signer)
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if !sig.Signature.Verify(sig.Signer, message) {
+		return fmt.Errorf("signature of %s is not over this transaction", sig.Signer)
+	}
+	tx.Signatures[i] = sig.Signature
+	return nil
+}
+
+// encodeTransaction serializes tx in the wire format external wallets sign, as base64 or base58
+func encodeTransaction(tx *solana.Transaction, encoding string) (string, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.EncodeToString(data), nil
+	case "base58":
+		return base58.Encode(data), nil
+	}
+	return "", fmt.Errorf("unknown encoding %q: use base64 or base58", encoding)
+}
+
+// decodeTransaction parses a serialized transaction in base64 or base58, as wallets hand them back
+func decodeTransaction(blob string) (*solana.Transaction, error) {
+	blob = strings.TrimSpace(blob)
+	if data, err := base64.StdEncoding.DecodeString(blob); err == nil {
+		if tx, err := solana.TransactionFromBytes(data); err == nil {
+			return tx, nil
+		}
+	}
+	if data, err := base58.Decode(blob); err == nil {
+		if tx, err := solana.TransactionFromBytes(data); err == nil {
+			return tx, nil
+		}
+	}
+	return nil, fmt.Errorf("not a base64 or base58 serialized transaction")
+}
+
+// importSignatures copies the signatures of a transaction signed elsewhere into tx, which must
+// have the same message, and returns the signers added
+func importSignatures(tx, signed *solana.Transaction) ([]solana.PublicKey, error) {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	signedMessage, err := signed.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	// A wallet that added instructions or changed the fee payer signed a different transaction
+	if !bytes.Equal(message, signedMessage) {
+		return nil, fmt.Errorf("the imported transaction differs from the one exported; sign it without modifying it (e.g. without added priority fees)")
+	}
+
+	var added []solana.PublicKey
+	for i, sig := range signed.Signatures {
+		if sig.IsZero() || i >= len(tx.Signatures) || tx.Signatures[i].Equals(sig) {
+			continue
+		}
+		signer := tx.Message.AccountKeys[i]
+		if err := addSignature(tx, SignatureFile{Signer: signer, Signature: sig}); err != nil {
+			return nil, err
+		}
+		added = append(added, signer)
+	}
+	return added, nil
+}
+
+// parseSignerPair parses a solana-cli --sign-only "PUBKEY=SIGNATURE" pair
+func parseSignerPair(pair string) (*SignatureFile, bool) {
+	key, sig, ok := strings.Cut(strings.TrimSpace(pair), "=")
+	if !ok {
+		return nil, false
+	}
+	signer, err := solana.PublicKeyFromBase58(key)
+	if err != nil {
+		return nil, false
+	}
+	signature, err := solana.SignatureFromBase58(sig)
+	if err != nil {
+		return nil, false
+	}
+	return &SignatureFile{Signer: signer, Signature: signature}, true
+}
+
+// BuildTxFile builds an unsigned donate or withdraw transaction in which signer moves the SOL and
+// feePayer pays the fees. With a nonce account, controlled by signer, it stays valid until used;
+// otherwise it must be signed and sent within about a minute.
+func (app *SolanaDApp) BuildTxFile(ctx context.Context, action string, campaignAddress solana.PublicKey, amount uint64, signer, feePayer solana.PublicKey, nonceAccount *solana.PublicKey) (*TxFile, *solana.Transaction, error) {
+	campaign, err := app.FetchCampaign(campaignAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	instruction, err := app.BuildInstruction(action,
+		map[string]solana.PublicKey{"campaign": campaignAddress, "user": signer},
+		map[string]interface{}{"name": campaign.Name, "amount": amount},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build %s instruction: %w", action, err)
+	}
+
+	file := &TxFile{Action: action, Created: time.Now().UTC()}
+	instructions := []solana.Instruction{instruction}
+	var blockhash solana.Hash
+	if nonceAccount != nil {
+		if blockhash, err = app.nonceValue(ctx, *nonceAccount, signer); err != nil {
+			return nil, nil, err
+		}
+		// Advancing the nonce must be the first instruction of a durable nonce transaction
+		advance := system.NewAdvanceNonceAccountInstruction(*nonceAccount, solana.SysVarRecentBlockHashesPubkey, signer).Build()
+		instructions = append([]solana.Instruction{advance}, instructions...)
+	} else {
+		recent, err := app.sender.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get recent blockhash: %w", err)
+		}
+		blockhash = recent.Value.Blockhash
+		file.LastValidBlockHeight = recent.Value.LastValidBlockHeight
+	}
+
+	tx, err := solana.NewTransaction(instructions, blockhash, solana.TransactionPayer(feePayer))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	return file, tx, nil
+}
+
+// SignTxFile signs tx with the app's wallet, once screening and the withdrawal policy allow it, and records
+// it in the audit log. The signature is returned rather than added, to travel on its own.
+func (app *SolanaDApp) SignTxFile(tx *solana.Transaction) (*SignatureFile, error) {
+	if crowdfund.SignerIndex(tx, app.wallet.Address()) < 0 {
+		return nil, fmt.Errorf("%s is not a signer of this transaction", app.wallet.Address())
+	}
+	if err := app.screenTransaction(tx, app.wallet.Address()); err != nil {
+		return nil, err
+	}
+	if err := app.checkPolicy(tx); err != nil {
+		return nil, err
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	signature, err := app.wallet.SignMessage(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	sig := &SignatureFile{Signer: app.wallet.Address(), Signature: signature}
+	signed := *tx
+	signed.Signatures = append([]solana.Signature(nil), tx.Signatures...)
+	if err := addSignature(&signed, *sig); err != nil {
+		return nil, err
+	}
+	if err := app.auditSigned(&signed, app.wallet.Address()); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// SendTxFile broadcasts a fully signed transaction file and waits for its confirmation
+func (app *SolanaDApp) SendTxFile(ctx context.Context, file *TxFile, tx *solana.Transaction) (solana.Signature, error) {
+	if missing := missingSigners(tx); len(missing) > 0 {
+		return solana.Signature{}, fmt.Errorf("transaction still needs %d signature(s), from %v", len(missing), missing)
+	}
+	if err := tx.VerifySignatures(); err != nil {
+		return solana.Signature{}, fmt.Errorf("invalid signatures: %w", err)
+	}
+	if file.LastValidBlockHeight > 0 {
+		height, err := app.client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+		if err == nil && height > file.LastValidBlockHeight {
+			return solana.Signature{}, fmt.Errorf("%w: its blockhash expired at block height %d; build it again, with -nonce to give signers more time", crowdfund.ErrBlockhashExpired, file.LastValidBlockHeight)
+		}
+	}
+
+	defer app.invalidateWrittenAccounts(tx)
+	sig, err := app.send(ctx, file.Action, tx)
+	if err != nil {
+		return sig, fmt.Errorf("failed to send transaction: %w", app.idl.DecodeError(err))
+	}
+	fmt.Printf("Transaction sent: %s\n", sig)
+	fmt.Printf("🔗 %s\n", app.TxURL(sig.String()))
+
+	confirmed, err := app.awaitSignature(ctx, sig, batchConfirmTimeout)
+	if err != nil {
+		return sig, err
+	}
+	if !confirmed {
+		return sig, fmt.Errorf("transaction %s was not confirmed within %s; check it later", sig, batchConfirmTimeout)
+	}
+	return sig, nil
+}
@@ -0,0 +1,196 @@
+// Admin dashboard of `serve -ui`. API calls carry the dashboard token; withdrawals are signed by
+// the admin's Phantom wallet after the server has checked them against the withdrawal policy.
+"use strict";
+
+const LAMPORTS_PER_SOL = 1e9;
+const $ = (id) => document.getElementById(id);
+const sol = (lamports) => (lamports / LAMPORTS_PER_SOL).toLocaleString(undefined, { maximumFractionDigits: 9 });
+const short = (address) => address.slice(0, 4) + "..." + address.slice(-4);
+
+let campaigns = [];
+let selected = null;
+let events = null;
+
+async function api(path, body) {
+  const options = { headers: { Authorization: "Bearer " + dashboardToken } };
+  if (body !== undefined) {
+    options.method = "POST";
+    options.headers["Content-Type"] = "application/json";
+    options.body = JSON.stringify(body);
+  }
+  const res = await fetch("/ui/api" + path, options);
+  const data = await res.json();
+  if (!res.ok) {
+    const err = new Error(data.error || res.statusText);
+    err.status = res.status;
+    err.data = data;
+    throw err;
+  }
+  return data;
+}
+
+function link(href, text) {
+  const a = document.createElement("a");
+  a.href = href;
+  a.target = "_blank";
+  a.rel = "noopener";
+  a.textContent = text;
+  return a;
+}
+
+async function loadCampaigns() {
+  campaigns = await api("/campaigns");
+  const list = $("campaigns");
+  list.replaceChildren();
+  if (campaigns.length === 0) {
+    list.textContent = "No campaigns found: list them under daemon.campaigns in config.json or create one.";
+    return;
+  }
+  for (const c of campaigns) {
+    const row = document.createElement("button");
+    row.className = "campaign" + (selected && selected.address === c.address ? " selected" : "");
+    const name = document.createElement("span");
+    name.textContent = c.name || short(c.address);
+    const figure = document.createElement("span");
+    if (c.error) {
+      figure.className = "error";
+      figure.textContent = c.error;
+    } else {
+      figure.textContent = sol(c.amount_donated) + " SOL raised";
+    }
+    row.append(name, figure);
+    row.onclick = () => select(c.address);
+    list.append(row);
+  }
+  if (selected) {
+    showStats(campaigns.find((c) => c.address === selected.address) || selected);
+  }
+}
+
+function showStats(c) {
+  selected = c;
+  $("detail-name").replaceChildren(link(c.explorerUrl, c.name || c.address));
+  $("stat-raised").textContent = sol(c.amount_donated || 0) + (c.goal ? " / " + sol(c.goal) : "") + " SOL";
+  $("stat-balance").textContent = sol(c.lamports) + " SOL";
+  $("stat-withdrawable").textContent = sol(c.withdrawable) + " SOL";
+  $("stat-state").textContent = c.state || "active";
+  const progress = $("stat-progress");
+  progress.hidden = !c.goal;
+  if (c.goal) {
+    progress.value = Math.min(100, (100 * c.amount_donated) / c.goal);
+  }
+}
+
+async function select(address) {
+  const c = campaigns.find((c) => c.address === address);
+  if (!c || c.error) return;
+  $("detail").hidden = false;
+  showStats(c);
+  resetWithdraw();
+  $("feed").innerHTML = '<li class="muted">Waiting for activity...</li>';
+  document.querySelectorAll(".campaign").forEach((row, i) => row.classList.toggle("selected", campaigns[i].address === address));
+  subscribe(address);
+  await loadHistory(address);
+}
+
+async function loadHistory(address) {
+  const history = await api("/campaigns/" + address + "/history");
+  const body = $("history");
+  body.replaceChildren();
+  for (const a of history) {
+    const row = body.insertRow();
+    if (a.failed) row.className = "failed";
+    row.insertCell().textContent = a.blockTime ? new Date(a.blockTime).toLocaleString() : "slot " + a.slot;
+    row.insertCell().textContent = a.kind;
+    row.insertCell().textContent = short(a.wallet);
+    const amount = row.insertCell();
+    amount.className = "num";
+    amount.textContent = sol(a.amount);
+    row.insertCell().append(link(a.explorerUrl, short(a.signature)));
+  }
+  if (history.length === 0) {
+    body.insertRow().insertCell().textContent = "No donations or withdrawals yet.";
+  }
+}
+
+// subscribe follows the campaign's live activity over the public API's event stream
+function subscribe(address) {
+  if (events) events.close();
+  events = new EventSource("/campaigns/" + address + "/events");
+  const onEvent = (e) => {
+    const event = JSON.parse(e.data);
+    const feed = $("feed");
+    if (feed.querySelector(".muted")) feed.replaceChildren();
+    const item = document.createElement("li");
+    const a = event.activity || {};
+    item.textContent = new Date().toLocaleTimeString() + "  " + event.type + " " + (a.amount ? sol(a.amount) + " SOL " : "") + (a.wallet ? "by " + short(a.wallet) : "");
+    feed.prepend(item);
+    if (selected && selected.address === address) {
+      showStats(Object.assign({}, selected, { amount_donated: event.amountDonated, lamports: event.lamports }));
+    }
+    if (event.type !== "comment") {
+      loadHistory(address);
+      loadCampaigns();
+    }
+  };
+  for (const type of ["donate", "withdraw", "comment"]) {
+    events.addEventListener(type, onEvent);
+  }
+}
+
+function resetWithdraw() {
+  const form = $("withdraw");
+  form.reset();
+  $("confirm-row").hidden = true;
+  $("totp-row").hidden = true;
+  $("withdraw-status").textContent = "";
+}
+
+$("withdraw").onsubmit = async (e) => {
+  e.preventDefault();
+  const form = e.target;
+  const status = $("withdraw-status");
+  const setStatus = (text) => { status.textContent = text; };
+  const provider = (window.phantom && window.phantom.solana) || window.solana;
+  if (!provider) {
+    setStatus("No Phantom wallet found in this browser.");
+    return;
+  }
+  try {
+    setStatus("Connecting to wallet...");
+    const { publicKey } = await provider.connect();
+    setStatus("Checking the withdrawal policy...");
+    let built;
+    try {
+      built = await api("/withdrawals", {
+        campaign: selected.address,
+        amount: form.amount.value.trim(),
+        publicKey: publicKey.toString(),
+        confirmAmount: $("confirm-row").hidden ? "" : form.confirmAmount.value,
+        totpCode: $("totp-row").hidden ? "" : form.totpCode.value.trim(),
+      });
+    } catch (err) {
+      if (err.status !== 428) throw err;
+      // The policy asks for approval: show the fields it needs and let the admin submit again
+      $("confirm-row").hidden = !err.data.confirm;
+      $("totp-row").hidden = !err.data.totp;
+      setStatus("🔐 " + err.message + ". Fill in the fields above and withdraw again.");
+      return;
+    }
+    setStatus("Waiting for wallet approval...");
+    const signed = await provider.request({ method: "signTransaction", params: { message: built.message } });
+    setStatus("Broadcasting...");
+    const result = await api("/withdrawals/" + built.id + "/submit", { signature: signed.signature });
+    status.replaceChildren("✅ Withdrawn! ", link(result.url, result.signature));
+    form.amount.value = "";
+    $("confirm-row").hidden = true;
+    $("totp-row").hidden = true;
+    loadCampaigns();
+  } catch (err) {
+    setStatus("❌ " + err.message);
+  }
+};
+
+loadCampaigns().catch((err) => {
+  $("campaigns").textContent = "❌ " + err.message;
+});
@@ -0,0 +1,80 @@
+// Donation widget printed by `widget`. Every element with a data-campaign attribute becomes a
+// live progress box fed by the public API: the progress badge, the campaign JSON and its event stream.
+(function () {
+  "use strict";
+
+  const style = document.createElement("style");
+  style.textContent =
+    ".cfw { font-family: system-ui, sans-serif; max-width: 22rem; border: 1px solid #ddd; border-radius: .5rem; padding: .9rem 1rem; color: #222; background: #fff; }" +
+    ".cfw-name { font-weight: 600; margin: 0 0 .5rem; }" +
+    ".cfw-bar { height: .6rem; border-radius: .3rem; background: #e6e6e6; overflow: hidden; margin: .6rem 0; }" +
+    ".cfw-bar div { height: 100%; background: #9945ff; transition: width .6s; }" +
+    ".cfw-donate { display: inline-block; background: #9945ff; color: #fff !important; padding: .45rem 1rem; border-radius: .35rem; text-decoration: none; font-weight: 600; margin-top: .3rem; }" +
+    ".cfw-note { font-size: .75rem; color: #777; margin-top: .4rem; }";
+  document.head.appendChild(style);
+
+  function mount(el) {
+    if (el.dataset.cfwMounted) return;
+    el.dataset.cfwMounted = "1";
+    const server = el.dataset.server.replace(/\/$/, "");
+    const campaign = el.dataset.campaign;
+    const goal = Number(el.dataset.goal || 0); // lamports
+    const badgeURL = el.dataset.badge;
+
+    el.className = "cfw";
+    el.replaceChildren();
+    const name = document.createElement("p");
+    name.className = "cfw-name";
+    name.textContent = el.dataset.name || "";
+    const badge = document.createElement("img");
+    badge.src = badgeURL;
+    badge.alt = "Amount raised";
+    el.append(name, badge);
+
+    let fill = null;
+    if (goal) {
+      const bar = document.createElement("div");
+      bar.className = "cfw-bar";
+      fill = document.createElement("div");
+      fill.style.width = "0";
+      bar.append(fill);
+      el.append(bar);
+    }
+    const update = (raised) => {
+      if (fill) fill.style.width = Math.min(100, (100 * raised) / goal) + "%";
+    };
+
+    const donate = document.createElement("a");
+    donate.className = "cfw-donate";
+    donate.href = el.dataset.pay;
+    donate.textContent = "Donate with a Solana wallet";
+    const note = document.createElement("div");
+    note.className = "cfw-note";
+    note.textContent = "Opens Phantom, Backpack or Solflare";
+    el.append(document.createElement("br"), donate, note);
+
+    fetch(server + "/campaigns/" + campaign)
+      .then((res) => res.json())
+      .then((data) => update(data.amount_donated || 0))
+      .catch(() => {});
+
+    if (!window.EventSource) return;
+    const events = new EventSource(server + "/campaigns/" + campaign + "/events");
+    const onDonation = (e) => {
+      const event = JSON.parse(e.data);
+      update(event.amountDonated);
+      // A new query string fetches the badge again instead of the browser's copy
+      badge.src = badgeURL + (badgeURL.includes("?") ? "&" : "?") + "v=" + encodeURIComponent(e.lastEventId.slice(0, 16));
+    };
+    events.addEventListener("donate", onDonation);
+  }
+
+  function mountAll() {
+    document.querySelectorAll("[data-campaign][data-server]").forEach(mount);
+  }
+  if (document.readyState === "loading") {
+    document.addEventListener("DOMContentLoaded", mountAll);
+  } else {
+    mountAll();
+  }
+})();
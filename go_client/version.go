@@ -0,0 +1,77 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set at build time with:
+//
+//	go build -ldflags "-X main.Version=v1.2.0 -X main.Commit=$(git rev-parse HEAD) -X main.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Commit and BuildDate fall back to the VCS information Go embeds when they are not set.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built from a tree with uncommitted changes
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+	ProgramID string `json:"programId"`
+	Network   string `json:"network"`
+}
+
+// GetBuildInfo returns the version information embedded in the binary
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		ProgramID: ProgramID,
+		Network:   loadConfig().readEndpoint(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	return info
+}
+
+// String formats the version on one line
+func (info BuildInfo) String() string {
+	s := info.Version
+	if info.Commit != "" {
+		commit := info.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if info.Modified {
+			commit += "-dirty"
+		}
+		s += " (" + commit + ")"
+	}
+	return s
+}
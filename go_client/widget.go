@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// widgetTemplate is the snippet `widget` prints: a placeholder element and the script that fills it,
+// loaded from the server or inlined
+var widgetTemplate = template.Must(template.New("widget").Parse(`<div data-server="{{.Server}}" data-campaign="{{.Campaign}}" data-name="{{.Name}}"{{if .Goal}} data-goal="{{.Goal}}"{{end}} data-badge="{{.BadgeURL}}" data-pay="{{.PayURL}}">
+<a href="{{.PayURL}}"><img src="{{.BadgeURL}}" alt="Donate to {{.Name}}"></a>
+</div>
+{{if .Script}}<script>
+{{.Script}}</script>{{else}}<script src="{{.Server}}/widget.js" async></script>{{end}}
+`))
+
+// widgetSnippet is the data of a widget snippet
+type widgetSnippet struct {
+	Server, Campaign, Name string
+	Goal                   uint64 // lamports
+	PayURL                 template.URL
+	BadgeURL               string
+	Script                 template.JS // inlined widget script, empty to load it from the server
+}
+
+// widgetScript returns the embedded widget script
+func widgetScript() ([]byte, error) {
+	script, err := uiFiles.ReadFile("ui/widget.js")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read widget script: %w", err)
+	}
+	return script, nil
+}
+
+// WidgetSnippet builds the HTML to paste into a page to show a campaign's live progress and a
+// donate button. The widget reads the public API at server, which also builds the donation the
+// button asks for amount lamports (defaultLinkAmount if 0); selfContained inlines its script
+// instead of loading it from there.
+func (app *SolanaDApp) WidgetSnippet(server string, campaignAddress solana.PublicKey, goal, amount uint64, selfContained bool) (string, error) {
+	server, err := parseServerURL(server)
+	if err != nil {
+		return "", err
+	}
+	campaign, err := app.FetchCampaign(campaignAddress)
+	if err != nil {
+		return "", err
+	}
+	if goal == 0 {
+		records, _ := loadCampaignRecords()
+		if record := records.get(campaignAddress); record != nil {
+			goal = record.Goal
+		}
+	}
+
+	// One reference per snippet lets the widget's donations be told apart from other links
+	payURL := donationLink(server, campaignAddress, campaign.Name, amount, solana.NewWallet().PublicKey())
+	snippet := widgetSnippet{
+		Server:   server,
+		Campaign: campaignAddress.String(),
+		Name:     campaign.Name,
+		Goal:     goal,
+		PayURL:   template.URL(payURL),
+		BadgeURL: server + "/badge/" + campaignAddress.String() + ".svg",
+	}
+	if goal > 0 {
+		snippet.BadgeURL += "?goal=" + lamportsToSOL(goal)
+	}
+	if selfContained {
+		script, err := widgetScript()
+		if err != nil {
+			return "", err
+		}
+		snippet.Script = template.JS(script)
+	}
+
+	var out strings.Builder
+	if err := widgetTemplate.Execute(&out, snippet); err != nil {
+		return "", fmt.Errorf("failed to render widget: %w", err)
+	}
+	return out.String(), nil
+}
+
+// handleWidgetScript serves the script of widgets that load it from the server
+func (s *Server) handleWidgetScript(w http.ResponseWriter, r *http.Request) {
+	script, err := widgetScript()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(script)
+}
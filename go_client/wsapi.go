@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// wsMaxSubscriptions caps the campaigns one WebSocket connection may follow
+const wsMaxSubscriptions = 50
+
+// wsMessage is the envelope for every WebSocket API message in either direction.
+// Requests carry a client-chosen ID that is echoed in the matching response.
+// Errors also carry the connection's request ID for correlation with server logs.
+type wsMessage struct {
+	ID        string         `json:"id,omitempty"`
+	Type      string         `json:"type"`
+	Campaign  string         `json:"campaign,omitempty"`
+	Address   string         `json:"address,omitempty"`
+	Result    interface{}    `json:"result,omitempty"`
+	Event     *CampaignEvent `json:"event,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	RequestID string         `json:"requestId,omitempty"`
+}
+
+// handleWebSocket serves the duplex API: live campaign subscriptions plus balance and campaign queries
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Pages may connect from the same origins that may read the rest of the API
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return s.originAllowed(r.Header.Get("Origin")) }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade already replied with an HTTP error
+	}
+	defer conn.Close()
+
+	outbound := make(chan wsMessage, 32)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// gorilla/websocket allows only one concurrent writer
+	go func() {
+		ping := time.NewTicker(30 * time.Second)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+					cancel()
+					return
+				}
+			case msg := <-outbound:
+				if err := conn.WriteJSON(msg); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	send := func(msg wsMessage) {
+		if msg.Type == "error" {
+			msg.RequestID = requestIDFrom(r.Context())
+		}
+		select {
+		case outbound <- msg:
+		case <-ctx.Done():
+		}
+	}
+
+	var mu sync.Mutex
+	subscriptions := map[solana.PublicKey]func(){}
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, unsubscribe := range subscriptions {
+			unsubscribe()
+		}
+	}()
+
+	// Queries and subscriptions draw on the same buckets as the client's HTTP requests;
+	// the API key, if any, was checked when the connection was upgraded
+	apiKey, _ := s.limiter.apiKey(r)
+
+	for {
+		var req wsMessage
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		switch req.Type {
+		case "subscribe", "balance", "campaign":
+			if wait := s.takeToken(r, apiKey); wait > 0 {
+				send(wsMessage{ID: req.ID, Type: "error", Error: fmt.Sprintf("rate limit exceeded, retry in %ds", retryAfterSeconds(wait))})
+				continue
+			}
+		}
+
+		switch req.Type {
+		case "subscribe":
+			address, err := solana.PublicKeyFromBase58(req.Campaign)
+			if err != nil {
+				send(wsMessage{ID: req.ID, Type: "error", Error: "invalid campaign address"})
+				continue
+			}
+			mu.Lock()
+			_, already := subscriptions[address]
+			count := len(subscriptions)
+			mu.Unlock()
+			if already {
+				send(wsMessage{ID: req.ID, Type: "subscribed", Campaign: req.Campaign})
+				continue
+			}
+			if count >= wsMaxSubscriptions {
+				send(wsMessage{ID: req.ID, Type: "error", Error: fmt.Sprintf("too many subscriptions on this connection (max %d)", wsMaxSubscriptions)})
+				continue
+			}
+			if _, err := s.app.FetchCampaign(address); err != nil {
+				send(wsMessage{ID: req.ID, Type: "error", Error: "not a campaign account"})
+				continue
+			}
+			events, unsubscribe, err := s.events.Subscribe(address)
+			if err != nil {
+				logf(r.Context(), "WebSocket subscribe failed for %s: %v", address.String(), err)
+				send(wsMessage{ID: req.ID, Type: "error", Error: "live updates unavailable"})
+				continue
+			}
+			mu.Lock()
+			subscriptions[address] = unsubscribe
+			mu.Unlock()
+			go func() {
+				for event := range events {
+					event := event
+					send(wsMessage{Type: "event", Campaign: event.Campaign, Event: &event})
+				}
+			}()
+			send(wsMessage{ID: req.ID, Type: "subscribed", Campaign: req.Campaign})
+
+		case "unsubscribe":
+			address, err := solana.PublicKeyFromBase58(req.Campaign)
+			if err != nil {
+				send(wsMessage{ID: req.ID, Type: "error", Error: "invalid campaign address"})
+				continue
+			}
+			mu.Lock()
+			if unsubscribe, ok := subscriptions[address]; ok {
+				unsubscribe()
+				delete(subscriptions, address)
+			}
+			mu.Unlock()
+			send(wsMessage{ID: req.ID, Type: "unsubscribed", Campaign: req.Campaign})
+
+		case "balance":
+			address, err := solana.PublicKeyFromBase58(req.Address)
+			if err != nil {
+				send(wsMessage{ID: req.ID, Type: "error", Error: "invalid address"})
+				continue
+			}
+			balance, err := s.app.client.GetBalance(ctx, address, rpc.CommitmentConfirmed)
+			if err != nil {
+				send(wsMessage{ID: req.ID, Type: "error", Error: "upstream RPC request failed"})
+				continue
+			}
+			send(wsMessage{ID: req.ID, Type: "balance", Address: req.Address, Result: map[string]uint64{"lamports": balance.Value}})
+
+		case "campaign":
+			address, err := solana.PublicKeyFromBase58(req.Address)
+			if err != nil {
+				send(wsMessage{ID: req.ID, Type: "error", Error: "invalid address"})
+				continue
+			}
+			campaign, err := s.app.FetchCampaign(address)
+			if err != nil {
+				send(wsMessage{ID: req.ID, Type: "error", Error: "not a campaign account"})
+				continue
+			}
+			balance, err := s.app.client.GetBalance(ctx, address, rpc.CommitmentConfirmed)
+			if err != nil {
+				send(wsMessage{ID: req.ID, Type: "error", Error: "upstream RPC request failed"})
+				continue
+			}
+			send(wsMessage{ID: req.ID, Type: "campaign", Address: req.Address, Result: CampaignAccount{Address: address, Lamports: balance.Value, Campaign: campaign}})
+
+		default:
+			send(wsMessage{ID: req.ID, Type: "error", Error: "unknown message type"})
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// errWSResubscribed is returned once by wsSubscription.Recv after the connection dropped and the
+// subscription was renewed: notifications sent in between are lost and must be polled for
+var errWSResubscribed = errors.New("websocket reconnected; notifications may have been missed")
+
+// errWSClosed is returned by subscriptions of a closed connection
+var errWSClosed = errors.New("websocket connection closed")
+
+// wsSilenceLimit is how long a connection may go without a slot notification before it is
+// considered dead. Slots arrive a few times a second.
+var wsSilenceLimit = 30 * time.Second
+
+// wsConn is the WebSocket connection to the RPC node. When it drops, it is dialed again with an
+// exponential backoff, and subscriptions made through it renew themselves on the new connection.
+type wsConn struct {
+	endpoint string
+	ctx      context.Context // cancelled by Close
+	cancel   context.CancelFunc
+
+	mu          sync.Mutex
+	client      *ws.Client      // nil while reconnecting
+	clientCtx   context.Context // cancelled when client is dropped
+	clientClose context.CancelFunc
+	ready       chan struct{} // closed once client is connected
+}
+
+// dialWS connects to the WebSocket endpoint
+func dialWS(ctx context.Context, endpoint string) (*wsConn, error) {
+	client, err := ws.Connect(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	c := &wsConn{endpoint: endpoint, ready: make(chan struct{})}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connected(client, c.ready)
+	return c, nil
+}
+
+// connected makes client the current connection; c.mu must be held
+func (c *wsConn) connected(client *ws.Client, ready chan struct{}) {
+	c.client = client
+	c.clientCtx, c.clientClose = context.WithCancel(c.ctx)
+	close(ready)
+	go c.monitor(client, c.clientCtx)
+}
+
+// Close closes the connection and stops reconnecting
+func (c *wsConn) Close() {
+	c.cancel()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// current returns the connected client and a context cancelled when it is dropped, waiting
+// while it reconnects
+func (c *wsConn) current(ctx context.Context) (*ws.Client, context.Context, error) {
+	for {
+		c.mu.Lock()
+		client, clientCtx, ready := c.client, c.clientCtx, c.ready
+		c.mu.Unlock()
+		if client != nil {
+			return client, clientCtx, nil
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-c.ctx.Done():
+			return nil, nil, errWSClosed
+		}
+	}
+}
+
+// dropped reports that client's connection failed. The first report starts reconnecting;
+// reports about a connection that was already replaced are ignored.
+func (c *wsConn) dropped(client *ws.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client == nil || c.client != client || c.ctx.Err() != nil {
+		return
+	}
+	c.clientClose()
+	client.Close()
+	c.client, c.ready = nil, make(chan struct{})
+	go c.redial(c.ready)
+}
+
+// redial connects again, backing off from 1s up to a minute between attempts
+func (c *wsConn) redial(ready chan struct{}) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		client, err := ws.Connect(c.ctx, c.endpoint)
+		if err == nil {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.ctx.Err() != nil {
+				client.Close() // Closed while dialing
+				return
+			}
+			c.connected(client, ready)
+			log.Printf("WebSocket reconnected after %d attempt(s)", attempt)
+			return
+		}
+		if c.ctx.Err() != nil {
+			return
+		}
+		log.Printf("WebSocket reconnect failed, retrying in %s: %v", backoff, err)
+		sleepContext(c.ctx, backoff)
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+// monitor watches a connection through a slot subscription. A closed connection only fails the
+// subscriptions made before it closed, and a wedged one fails none, so without this a
+// subscription could wait forever.
+func (c *wsConn) monitor(client *ws.Client, clientCtx context.Context) {
+	sub, err := client.SlotSubscribe()
+	if err != nil {
+		log.Printf("WebSocket connection lost: %v", err)
+		c.dropped(client)
+		return
+	}
+	defer sub.Unsubscribe()
+	for {
+		ctx, cancel := context.WithTimeout(clientCtx, wsSilenceLimit)
+		_, err := sub.Recv(ctx)
+		cancel()
+		if clientCtx.Err() != nil {
+			return // Dropped or closed
+		}
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("no slot notification for %s", wsSilenceLimit)
+			}
+			log.Printf("WebSocket connection lost: %v", err)
+			c.dropped(client)
+			return
+		}
+	}
+}
+
+// wsSubscription is a subscription that survives reconnects. It subscribes on first use.
+type wsSubscription struct {
+	conn *wsConn
+	// subscribe makes the subscription on a connection, returning its receive and unsubscribe functions
+	subscribe func(client *ws.Client) (recv func(context.Context) (interface{}, error), unsubscribe func(), err error)
+
+	client      *ws.Client
+	clientCtx   context.Context
+	recv        func(context.Context) (interface{}, error)
+	unsubscribe func()
+	renewed     bool // a dropped subscription is being replaced
+}
+
+// Recv waits for the next notification, resubscribing after the connection drops. Once the
+// subscription was renewed it returns errWSResubscribed, so the caller can catch up.
+func (s *wsSubscription) Recv(ctx context.Context) (interface{}, error) {
+	backoff := time.Second
+	for {
+		if s.recv == nil {
+			client, clientCtx, err := s.conn.current(ctx)
+			if err != nil {
+				return nil, err
+			}
+			recv, unsubscribe, err := s.subscribe(client)
+			if err != nil {
+				log.Printf("WebSocket subscription failed, retrying in %s: %v", backoff, err)
+				s.conn.dropped(client)
+				sleepContext(ctx, backoff)
+				backoff = min(2*backoff, time.Minute)
+				continue
+			}
+			s.client, s.clientCtx, s.recv, s.unsubscribe = client, clientCtx, recv, unsubscribe
+			backoff = time.Second
+			if s.renewed {
+				s.renewed = false
+				return nil, errWSResubscribed
+			}
+		}
+
+		// Stop waiting when the connection is dropped, even if the subscription never learns of it
+		recvCtx, cancel := context.WithCancel(ctx)
+		stop := context.AfterFunc(s.clientCtx, cancel)
+		value, err := s.recv(recvCtx)
+		stop()
+		cancel()
+		if err == nil {
+			return value, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if s.conn.ctx.Err() != nil {
+			s.Unsubscribe()
+			return nil, errWSClosed
+		}
+		log.Printf("WebSocket subscription dropped, resubscribing: %v", err)
+		s.Unsubscribe()
+		s.conn.dropped(s.client)
+		s.renewed = true
+	}
+}
+
+// Unsubscribe ends the current subscription; a later Recv subscribes again
+func (s *wsSubscription) Unsubscribe() {
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
+	s.client, s.clientCtx, s.recv, s.unsubscribe = nil, nil, nil, nil
+}
+
+// LogsSubscribeMentions subscribes to the logs of transactions mentioning account
+func (c *wsConn) LogsSubscribeMentions(account solana.PublicKey, commitment rpc.CommitmentType) *wsSubscription {
+	return &wsSubscription{conn: c, subscribe: func(client *ws.Client) (func(context.Context) (interface{}, error), func(), error) {
+		sub, err := client.LogsSubscribeMentions(account, commitment)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) { return sub.Recv(ctx) }, sub.Unsubscribe, nil
+	}}
+}
+
+// AccountSubscribe subscribes to changes of an account
+func (c *wsConn) AccountSubscribe(account solana.PublicKey, commitment rpc.CommitmentType) *wsSubscription {
+	return &wsSubscription{conn: c, subscribe: func(client *ws.Client) (func(context.Context) (interface{}, error), func(), error) {
+		sub, err := client.AccountSubscribe(account, commitment)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) { return sub.Recv(ctx) }, sub.Unsubscribe, nil
+	}}
+}
+
+// SignatureSubscribe subscribes to the status of a transaction. A status notification sent while
+// the connection was down is lost: poll the status after errWSResubscribed.
+func (c *wsConn) SignatureSubscribe(sig solana.Signature, commitment rpc.CommitmentType) *wsSubscription {
+	return &wsSubscription{conn: c, subscribe: func(client *ws.Client) (func(context.Context) (interface{}, error), func(), error) {
+		sub, err := client.SignatureSubscribe(sig, commitment)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) { return sub.Recv(ctx) }, sub.Unsubscribe, nil
+	}}
+}
+
+// SlotSubscribe subscribes to new slots
+func (c *wsConn) SlotSubscribe() *wsSubscription {
+	return &wsSubscription{conn: c, subscribe: func(client *ws.Client) (func(context.Context) (interface{}, error), func(), error) {
+		sub, err := client.SlotSubscribe()
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) { return sub.Recv(ctx) }, sub.Unsubscribe, nil
+	}}
+}
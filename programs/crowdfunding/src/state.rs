@@ -1,5 +1,10 @@
 use anchor_lang::prelude::*;
 
+/// Prefix of every campaign address. A deployment that must keep its campaigns apart from
+/// other deployments' changes it; clients read it from the IDL.
+#[constant]
+pub const CAMPAIGN_SEED: &[u8] = b"CAMPAIGN_DEMO";
+
 #[derive(Accounts)]
 #[instruction(name: String)]
 pub struct Create<'info> {
@@ -7,7 +12,7 @@ pub struct Create<'info> {
         init,
         payer = user,
         space = 9000,
-        seeds = [b"CAMPAIGN_DEMO".as_ref(), user.key().as_ref(), name.as_ref()],
+        seeds = [CAMPAIGN_SEED, user.key().as_ref(), name.as_ref()],
         bump
     )]
     pub campaign: Account<'info, Campaign>,
@@ -21,7 +26,7 @@ pub struct Create<'info> {
 pub struct Withdraw<'info> {
     #[account(
         mut,
-        seeds = [b"CAMPAIGN_DEMO".as_ref(), campaign.admin.as_ref(), name.as_ref()],
+        seeds = [CAMPAIGN_SEED, campaign.admin.as_ref(), name.as_ref()],
         bump = campaign.bump
     )]
     pub campaign: Account<'info, Campaign>,
@@ -34,7 +39,7 @@ pub struct Withdraw<'info> {
 pub struct Donate<'info> {
     #[account(
         mut,
-        seeds = [b"CAMPAIGN_DEMO".as_ref(), campaign.admin.as_ref(), name.as_ref()],
+        seeds = [CAMPAIGN_SEED, campaign.admin.as_ref(), name.as_ref()],
         bump = campaign.bump
     )]
     pub campaign: Account<'info, Campaign>,